@@ -33,6 +33,7 @@ const (
 	AgentResponseTypeAnswer     AgentResponseType = "answer"
 	AgentResponseTypeReflection AgentResponseType = "reflection"
 	AgentResponseTypeError      AgentResponseType = "error"
+	AgentResponseTypeRefusal    AgentResponseType = "refusal"
 )
 
 // AgentStreamResponse agent streaming response
@@ -86,6 +87,26 @@ func (c *Client) AgentQAStreamWithRequest(ctx context.Context,
 	return c.processAgentSSEStream(resp.Body, callback)
 }
 
+// ResumeAgentRun resumes an agent execution interrupted by a server restart or failure
+// from its last checkpoint, streaming the remaining events like AgentQAStreamWithRequest
+func (c *Client) ResumeAgentRun(ctx context.Context,
+	sessionID, messageID string, callback AgentEventCallback,
+) error {
+	path := fmt.Sprintf("/api/v1/sessions/%s/messages/%s/resume", sessionID, messageID)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, nil, nil)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+	}
+
+	return c.processAgentSSEStream(resp.Body, callback)
+}
+
 // processAgentSSEStream processes the SSE stream and invokes callback for each event
 func (c *Client) processAgentSSEStream(reader io.Reader, callback AgentEventCallback) error {
 	scanner := bufio.NewScanner(reader)
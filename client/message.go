@@ -45,6 +45,7 @@ type Message struct {
 	RequestID           string          `json:"request_id"`
 	Content             string          `json:"content"`
 	Role                string          `json:"role"`
+	Images              []string        `json:"images,omitempty"` // Images attached to the message (only for user messages)
 	KnowledgeReferences []*SearchResult `json:"knowledge_references"`
 	AgentSteps          []AgentStep     `json:"agent_steps,omitempty"` // Agent execution steps (only for assistant messages)
 	IsCompleted         bool            `json:"is_completed"`
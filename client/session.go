@@ -7,6 +7,7 @@ package client
 import (
 	"bufio"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // SummaryConfig defines summary configuration
@@ -189,6 +191,19 @@ type KnowledgeQARequest struct {
 	WebSearchEnabled bool     `json:"web_search_enabled"` // Whether web search is enabled for this request
 	SummaryModelID   string   `json:"summary_model_id"`   // Optional summary model ID (overrides session default)
 	DisableTitle     bool     `json:"disable_title"`      // Whether to disable auto title generation
+	// Images attached to the query, as base64 data URLs (data:image/png;base64,...) or http(s) URLs
+	Images []string `json:"images,omitempty"`
+
+	// Optional JSON Schema; when set, the answer is returned as a JSON object conforming to it
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+	// Whether to stream the retrieval trace as a retrieval_debug response
+	Debug bool `json:"debug,omitempty"`
+}
+
+// ImageDataURL encodes image bytes as a data URL for KnowledgeQARequest.Images
+// mimeType is the type of the image, e.g. image/png or image/jpeg
+func ImageDataURL(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
 }
 
 // LLMToolCall represents a function/tool call from the LLM
@@ -217,6 +232,15 @@ const (
 	ResponseTypeSessionTitle ResponseType = "session_title"
 	ResponseTypeAgentQuery   ResponseType = "agent_query"
 	ResponseTypeComplete     ResponseType = "complete"
+
+	ResponseTypeRefusal          ResponseType = "refusal"
+	ResponseTypeStructuredOutput ResponseType = "structured_output"
+	ResponseTypeRetrievalDebug   ResponseType = "retrieval_debug"
+	ResponseTypeKBRouting        ResponseType = "kb_routing"
+	ResponseTypeBudgetExhausted  ResponseType = "budget_exhausted"
+	ResponseTypeApprovalRequest  ResponseType = "approval_request"
+	ResponseTypeApprovalResult   ResponseType = "approval_result"
+	ResponseTypeSubAgent         ResponseType = "sub_agent"
 )
 
 // StreamResponse streaming response
@@ -312,6 +336,67 @@ func (c *Client) KnowledgeQAStream(
 	return nil
 }
 
+// StructuredAnswer is the result of a knowledge Q&A request with a response schema
+type StructuredAnswer struct {
+	Object     json.RawMessage `json:"object"`     // JSON object returned by the model
+	Valid      bool            `json:"valid"`      // Whether the object conforms to the schema
+	Repaired   bool            `json:"repaired"`   // Whether the object was repaired by a second model call
+	Error      string          `json:"error"`      // Validation error when the object is not valid
+	References []*SearchResult `json:"references"` // Knowledge references used for the answer
+}
+
+// KnowledgeQAStructured sends a knowledge Q&A request with request.ResponseSchema set and
+// collects the structured answer. When out is not nil and the object is valid, it is
+// unmarshaled into out.
+func (c *Client) KnowledgeQAStructured(
+	ctx context.Context,
+	sessionID string,
+	request *KnowledgeQARequest,
+	out interface{},
+) (*StructuredAnswer, error) {
+	if len(request.ResponseSchema) == 0 {
+		return nil, fmt.Errorf("response schema is required")
+	}
+
+	var answer *StructuredAnswer
+	var references []*SearchResult
+	err := c.KnowledgeQAStream(ctx, sessionID, request, func(resp *StreamResponse) error {
+		switch resp.ResponseType {
+		case ResponseTypeReferences:
+			references = append(references, resp.KnowledgeReferences...)
+		case ResponseTypeStructuredOutput:
+			// Data carries the object, valid, repaired and error fields
+			raw, err := json.Marshal(resp.Data)
+			if err != nil {
+				return fmt.Errorf("failed to read structured output: %w", err)
+			}
+			answer = &StructuredAnswer{}
+			if err := json.Unmarshal(raw, answer); err != nil {
+				return fmt.Errorf("failed to parse structured output: %w", err)
+			}
+		case ResponseTypeRefusal:
+			return fmt.Errorf("request refused: %s", resp.Content)
+		case ResponseTypeError:
+			return fmt.Errorf("knowledge QA failed: %s", resp.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if answer == nil {
+		return nil, fmt.Errorf("no structured output received")
+	}
+	answer.References = references
+
+	if out != nil && answer.Valid {
+		if err := json.Unmarshal(answer.Object, out); err != nil {
+			return answer, fmt.Errorf("failed to unmarshal structured output: %w", err)
+		}
+	}
+	return answer, nil
+}
+
 // ContinueStream continues to receive an active stream for a session
 func (c *Client) ContinueStream(
 	ctx context.Context,
@@ -403,22 +488,144 @@ func (c *Client) StopSession(ctx context.Context, sessionID string, messageID st
 	return parseResponse(resp, &response)
 }
 
+// ToolApproval is an agent tool call waiting for, or decided by, a human approval
+type ToolApproval struct {
+	ID         string          `json:"id"`
+	SessionID  string          `json:"session_id"`
+	MessageID  string          `json:"message_id"`
+	ToolCallID string          `json:"tool_call_id"`
+	ToolName   string          `json:"tool_name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Status     string          `json:"status"` // pending, approved, denied or expired
+	Reason     string          `json:"reason"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// ToolApprovalResponse tool approval response
+type ToolApprovalResponse struct {
+	Success bool          `json:"success"`
+	Data    *ToolApproval `json:"data"`
+}
+
+// ToolApprovalListResponse pending tool approvals response
+type ToolApprovalListResponse struct {
+	Success bool            `json:"success"`
+	Data    []*ToolApproval `json:"data"`
+}
+
+// ListToolApprovals lists the agent tool calls of a session waiting for approval
+func (c *Client) ListToolApprovals(ctx context.Context, sessionID string) ([]*ToolApproval, error) {
+	path := fmt.Sprintf("/api/v1/sessions/%s/approvals", sessionID)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ToolApprovalListResponse
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// DecideToolApproval approves or denies a tool call waiting for approval.
+// The reason is passed to the agent when the call is denied.
+func (c *Client) DecideToolApproval(ctx context.Context,
+	sessionID, approvalID string, approved bool, reason string,
+) (*ToolApproval, error) {
+	path := fmt.Sprintf("/api/v1/sessions/%s/approvals/%s", sessionID, approvalID)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, map[string]interface{}{
+		"approved": approved,
+		"reason":   reason,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var response ToolApprovalResponse
+	if err := parseResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
 // SearchKnowledgeRequest knowledge search request
 type SearchKnowledgeRequest struct {
 	Query            string   `json:"query"`                        // Query content
 	KnowledgeBaseID  string   `json:"knowledge_base_id,omitempty"`  // Single knowledge base ID (for backward compatibility)
 	KnowledgeBaseIDs []string `json:"knowledge_base_ids,omitempty"` // Knowledge base IDs (multi-KB support)
 	KnowledgeIDs     []string `json:"knowledge_ids,omitempty"`      // Specific knowledge (file) IDs
+	Debug            bool     `json:"debug,omitempty"`              // Whether to return the retrieval trace
 }
 
 // SearchKnowledgeResponse search results response
 type SearchKnowledgeResponse struct {
 	Success bool            `json:"success"`
 	Data    []*SearchResult `json:"data"`
+	Debug   *RetrievalTrace `json:"debug,omitempty"` // Retrieval trace, only for debug requests
+}
+
+// CandidateTrace records the scores and decisions applied to a single candidate chunk
+type CandidateTrace struct {
+	ChunkID         string   `json:"chunk_id"`
+	KnowledgeID     string   `json:"knowledge_id,omitempty"`
+	KnowledgeBaseID string   `json:"knowledge_base_id,omitempty"`
+	ChunkType       string   `json:"chunk_type,omitempty"`
+	MatchType       int      `json:"match_type"`
+	Queries         []string `json:"queries,omitempty"` // Query variants that retrieved the chunk
+	VectorScore     *float64 `json:"vector_score,omitempty"`
+	VectorRank      int      `json:"vector_rank,omitempty"`
+	KeywordScore    *float64 `json:"keyword_score,omitempty"`
+	KeywordRank     int      `json:"keyword_rank,omitempty"`
+	FusionScore     *float64 `json:"fusion_score,omitempty"`
+	RerankScore     *float64 `json:"rerank_score,omitempty"`
+	CompositeScore  *float64 `json:"composite_score,omitempty"`
+	FAQBoost        float64  `json:"faq_boost,omitempty"`
+	MMRSelected     *bool    `json:"mmr_selected,omitempty"`
+	MMRRank         int      `json:"mmr_rank,omitempty"`
+	FinalScore      *float64 `json:"final_score,omitempty"`
+	Actions         []string `json:"actions,omitempty"`     // Merge/expansion actions applied to the chunk
+	MergedInto      string   `json:"merged_into,omitempty"` // Chunk that absorbed this one during merge
+	Returned        bool     `json:"returned"`
+	DropStage       string   `json:"drop_stage,omitempty"`
+	DropReason      string   `json:"drop_reason,omitempty"` // threshold, top_k, dedup, mmr, negative_question, merged
+	DropDetail      string   `json:"drop_detail,omitempty"`
+}
+
+// RetrievalTrace explains how the results of a retrieval request were produced
+type RetrievalTrace struct {
+	Query           string            `json:"query"`
+	RewriteQuery    string            `json:"rewrite_query"`
+	ExpandedQueries []string          `json:"expanded_queries,omitempty"`
+	Candidates      []*CandidateTrace `json:"candidates"`
 }
 
 // SearchKnowledge performs knowledge base search without LLM summarization
 func (c *Client) SearchKnowledge(ctx context.Context, request *SearchKnowledgeRequest) ([]*SearchResult, error) {
+	response, err := c.searchKnowledge(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// SearchKnowledgeDebug performs knowledge base search and returns the retrieval trace with the results
+func (c *Client) SearchKnowledgeDebug(
+	ctx context.Context,
+	request *SearchKnowledgeRequest,
+) ([]*SearchResult, *RetrievalTrace, error) {
+	debugRequest := *request
+	debugRequest.Debug = true
+	response, err := c.searchKnowledge(ctx, &debugRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response.Data, response.Debug, nil
+}
+
+// searchKnowledge sends the knowledge search request
+func (c *Client) searchKnowledge(ctx context.Context, request *SearchKnowledgeRequest) (*SearchKnowledgeResponse, error) {
 	fmt.Printf("Starting SearchKnowledge request, knowledge base IDs: %v, knowledge IDs: %v, query: %s\n",
 		request.KnowledgeBaseIDs, request.KnowledgeIDs, request.Query)
 
@@ -443,5 +650,5 @@ func (c *Client) SearchKnowledge(ctx context.Context, request *SearchKnowledgeRe
 	}
 
 	fmt.Printf("SearchKnowledge completed, found %d results\n", len(response.Data))
-	return response.Data, nil
+	return &response, nil
 }
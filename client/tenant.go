@@ -41,6 +41,8 @@ type Tenant struct {
 	StorageQuota int64 `yaml:"storage_quota"     json:"storage_quota"     gorm:"default:10737418240"`
 	// Storage used (Bytes)
 	StorageUsed int64 `yaml:"storage_used"      json:"storage_used"      gorm:"default:0"`
+	// Tokens the models may use per calendar month (UTC), 0 for unlimited
+	TokenQuota int64 `yaml:"token_quota"       json:"token_quota"       gorm:"default:0"`
 	// Creation timestamp
 	CreatedAt time.Time `yaml:"created_at"        json:"created_at"`
 	// Last update timestamp
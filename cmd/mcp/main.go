@@ -0,0 +1,44 @@
+// Command weknora-mcp serves the knowledge bases of a tenant to an MCP client over stdio.
+// It reads the same configuration and environment as the API server, and authenticates
+// with the tenant API key in WEKNORA_API_KEY:
+//
+//	WEKNORA_API_KEY=sk-... weknora-mcp
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Tencent/WeKnora/internal/container"
+	"github.com/Tencent/WeKnora/internal/mcpserver"
+	"github.com/Tencent/WeKnora/internal/runtime"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+func main() {
+	apiKey := os.Getenv("WEKNORA_API_KEY")
+	if apiKey == "" {
+		fmt.Fprintln(os.Stderr, "WEKNORA_API_KEY is required")
+		os.Exit(2)
+	}
+
+	// stdout carries the MCP protocol, everything else writing to it goes to stderr
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	c := container.BuildContainer(runtime.GetContainer())
+	err := c.Invoke(func(server *mcpserver.Server, cleaner interfaces.ResourceCleaner) error {
+		defer cleaner.Cleanup(context.Background())
+		return server.ServeStdio(ctx, apiKey, os.Stdin, stdout)
+	})
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "weknora-mcp: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -3,8 +3,11 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/agent/tools"
@@ -12,6 +15,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/event"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/google/uuid"
@@ -33,6 +37,10 @@ type AgentEngine struct {
 	contextManager       interfaces.ContextManager // Context manager for writing agent conversation to LLM context
 	sessionID            string                    // Session ID for context management
 	systemPromptTemplate string                    // System prompt template (optional, uses default if empty)
+	tokensUsed           int                       // Estimated LLM tokens spent by the execution
+
+	approvalService interfaces.ToolApprovalService    // Human approval of sensitive tool calls (optional)
+	checkpoints     interfaces.AgentCheckpointService // Checkpoints the execution after each round (optional)
 }
 
 // listToolNames returns tool.function names for logging
@@ -55,6 +63,8 @@ func NewAgentEngine(
 	contextManager interfaces.ContextManager,
 	sessionID string,
 	systemPromptTemplate string,
+	approvalService interfaces.ToolApprovalService,
+	checkpoints interfaces.AgentCheckpointService,
 ) *AgentEngine {
 	if eventBus == nil {
 		eventBus = event.NewEventBus()
@@ -69,6 +79,8 @@ func NewAgentEngine(
 		contextManager:       contextManager,
 		sessionID:            sessionID,
 		systemPromptTemplate: systemPromptTemplate,
+		approvalService:      approvalService,
+		checkpoints:          checkpoints,
 	}
 }
 
@@ -107,7 +119,7 @@ func (e *AgentEngine) Execute(
 		e.config.WebSearchEnabled,
 		e.selectedDocs,
 		e.systemPromptTemplate,
-	)
+	) + formatMCPResources(e.config.MCPResourceContexts) + formatScratchpad(e.config.Scratchpad)
 	logger.Debugf(ctx, "[Agent] SystemPrompt Length: %d characters", len(systemPrompt))
 	logger.Debugf(ctx, "[Agent] SystemPrompt (stream)\n----\n%s\n----", systemPrompt)
 
@@ -116,6 +128,53 @@ func (e *AgentEngine) Execute(
 	logger.Infof(ctx, "[Agent] Total messages for LLM: %d (system: 1, history: %d, user query: 1)",
 		len(messages), len(llmContext))
 
+	e.saveCheckpoint(ctx, messageID, state, messages)
+
+	return e.run(ctx, state, query, messages, sessionID, messageID)
+}
+
+// Resume continues an interrupted execution from its last checkpoint: the message history
+// and agent state saved after the last completed round, with the tool state rebuilt from its steps
+func (e *AgentEngine) Resume(
+	ctx context.Context,
+	checkpoint *types.AgentCheckpoint,
+) (*types.AgentState, error) {
+	logger.Infof(ctx, "========== Agent Execution Resumed ==========")
+	// Ensure tools are cleaned up after execution
+	defer e.toolRegistry.Cleanup(ctx)
+
+	var state types.AgentState
+	if err := json.Unmarshal(checkpoint.State, &state); err != nil {
+		return nil, fmt.Errorf("invalid agent checkpoint state: %w", err)
+	}
+	var messages []chat.Message
+	if err := json.Unmarshal(checkpoint.Messages, &messages); err != nil {
+		return nil, fmt.Errorf("invalid agent checkpoint messages: %w", err)
+	}
+	e.tokensUsed = checkpoint.TokensUsed
+	e.toolRegistry.Restore(ctx, state.RoundSteps)
+
+	logger.Infof(ctx, "[Agent] SessionID: %s, MessageID: %s, resuming at round %d with %d messages",
+		checkpoint.SessionID, checkpoint.MessageID, state.CurrentRound+1, len(messages))
+	common.PipelineInfo(ctx, "Agent", "execute_resume", map[string]interface{}{
+		"session_id": checkpoint.SessionID,
+		"message_id": checkpoint.MessageID,
+		"round":      state.CurrentRound,
+		"messages":   len(messages),
+		"attempts":   checkpoint.Attempts,
+	})
+
+	return e.run(ctx, &state, checkpoint.Query, messages, checkpoint.SessionID, checkpoint.MessageID)
+}
+
+// run executes the ReAct loop from the given state and reports the outcome
+func (e *AgentEngine) run(
+	ctx context.Context,
+	state *types.AgentState,
+	query string,
+	messages []chat.Message,
+	sessionID, messageID string,
+) (*types.AgentState, error) {
 	// Get tool definitions for function calling
 	tools := e.buildToolsForLLM()
 	toolListStr := strings.Join(listToolNames(tools), ", ")
@@ -154,6 +213,23 @@ func (e *AgentEngine) Execute(
 	return state, nil
 }
 
+// saveCheckpoint stores the progress of the execution so that it can be resumed
+// from this point; failures only cost resumability and are logged
+func (e *AgentEngine) saveCheckpoint(
+	ctx context.Context,
+	messageID string,
+	state *types.AgentState,
+	messages []chat.Message,
+) {
+	// Delegated executions share the message of the parent, whose checkpoint must not be overwritten
+	if e.checkpoints == nil || types.SubAgentDepth(ctx) > 0 {
+		return
+	}
+	if err := e.checkpoints.Save(ctx, messageID, state, messages, e.tokensUsed); err != nil {
+		logger.Warnf(ctx, "[Agent] Failed to save checkpoint at round %d: %v", state.CurrentRound, err)
+	}
+}
+
 // executeLoop executes the main ReAct loop
 // All events are emitted through EventBus with the given sessionID
 func (e *AgentEngine) executeLoop(
@@ -170,6 +246,9 @@ func (e *AgentEngine) executeLoop(
 		"max_iterations": e.config.MaxIterations,
 	})
 	for state.CurrentRound < e.config.MaxIterations {
+		if e.tokenBudgetExhausted(ctx, state.CurrentRound, sessionID) {
+			break
+		}
 		roundStart := time.Now()
 		logger.Infof(ctx, "========== Round %d/%d Started ==========", state.CurrentRound+1, e.config.MaxIterations)
 		logger.Infof(ctx, "[Agent][Round-%d] Message history size: %d messages", state.CurrentRound+1, len(messages))
@@ -198,6 +277,8 @@ func (e *AgentEngine) executeLoop(
 			return state, fmt.Errorf("LLM call failed: %w", err)
 		}
 
+		e.tokensUsed += estimateRoundTokens(tokenizer.ForModel(e.chatModel.GetModelName()), messages, response)
+
 		common.PipelineInfo(ctx, "Agent", "think_result", map[string]interface{}{
 			"iteration":     state.CurrentRound,
 			"finish_reason": response.FinishReason,
@@ -267,159 +348,18 @@ func (e *AgentEngine) executeLoop(
 				len(response.ToolCalls),
 			)
 
-			for i, tc := range response.ToolCalls {
-				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool: %s, ID: %s",
-					state.CurrentRound+1, i+1, len(response.ToolCalls), tc.Function.Name, tc.ID)
-
-				var args map[string]any
-				if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
-					logger.Errorf(ctx, "[Agent][Round-%d][Tool-%d/%d] Failed to parse tool arguments: %v",
-						state.CurrentRound+1, i+1, len(response.ToolCalls), err)
+			toolCalls := e.executeToolCalls(ctx, response.ToolCalls, state.CurrentRound, sessionID, messageID)
+			for _, toolCall := range toolCalls {
+				if toolCall == nil {
 					continue
 				}
-
-				// Log the arguments in a readable format
-				argsJSON, _ := json.MarshalIndent(args, "", "  ")
-				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Arguments:\n%s",
-					state.CurrentRound+1, i+1, len(response.ToolCalls), string(argsJSON))
-
-				toolCallStartTime := time.Now()
-				e.eventBus.Emit(ctx, event.Event{
-					ID:        tc.ID + "-tool-call",
-					Type:      event.EventAgentToolCall,
-					SessionID: sessionID,
-					Data: event.AgentToolCallData{
-						ToolCallID: tc.ID,
-						ToolName:   tc.Function.Name,
-						Arguments:  args,
-						Iteration:  state.CurrentRound,
-					},
-				})
-				logger.Debugf(ctx, "[Agent] ToolCall -> %s args=%s", tc.Function.Name, tc.Function.Arguments)
-
-				// Execute tool
-				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Executing tool: %s...",
-					state.CurrentRound+1, i+1, len(response.ToolCalls), tc.Function.Name)
-				common.PipelineInfo(ctx, "Agent", "tool_call_start", map[string]interface{}{
-					"iteration":    state.CurrentRound,
-					"round":        state.CurrentRound + 1,
-					"tool":         tc.Function.Name,
-					"tool_call_id": tc.ID,
-					"tool_index":   fmt.Sprintf("%d/%d", i+1, len(response.ToolCalls)),
-				})
-				result, err := e.toolRegistry.ExecuteTool(ctx, tc.Function.Name, json.RawMessage(tc.Function.Arguments))
-				duration := time.Since(toolCallStartTime).Milliseconds()
-				logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool execution completed in %dms",
-					state.CurrentRound+1, i+1, len(response.ToolCalls), duration)
-
-				toolCall := types.ToolCall{
-					ID:       tc.ID,
-					Name:     tc.Function.Name,
-					Args:     args,
-					Result:   result,
-					Duration: duration,
-				}
-
-				if err != nil {
-					logger.Errorf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool call failed: %s, error: %v",
-						state.CurrentRound+1, i+1, len(response.ToolCalls), tc.Function.Name, err)
-					toolCall.Result = &types.ToolResult{
-						Success: false,
-						Error:   err.Error(),
-					}
-				}
-
-				toolSuccess := toolCall.Result != nil && toolCall.Result.Success
-				pipelineFields := map[string]interface{}{
-					"iteration":    state.CurrentRound,
-					"round":        state.CurrentRound + 1,
-					"tool":         tc.Function.Name,
-					"tool_call_id": tc.ID,
-					"duration_ms":  duration,
-					"success":      toolSuccess,
-				}
-				if toolCall.Result != nil && toolCall.Result.Error != "" {
-					pipelineFields["error"] = toolCall.Result.Error
-				}
-				if err != nil {
-					common.PipelineError(ctx, "Agent", "tool_call_result", pipelineFields)
-				} else if toolSuccess {
-					common.PipelineInfo(ctx, "Agent", "tool_call_result", pipelineFields)
-				} else {
-					common.PipelineWarn(ctx, "Agent", "tool_call_result", pipelineFields)
-				}
-
-				if toolCall.Result != nil {
-					logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool result: success=%v, output_length=%d",
-						state.CurrentRound+1, i+1, len(response.ToolCalls),
-						toolCall.Result.Success, len(toolCall.Result.Output))
-					logger.Debugf(ctx, "[Agent] ToolResult <- %s success=%v len(output)=%d",
-						tc.Function.Name, toolCall.Result.Success, len(toolCall.Result.Output))
-
-					// Log the output content for debugging
-					if toolCall.Result.Output != "" {
-						// Truncate if too long for logging
-						outputPreview := toolCall.Result.Output
-						if len(outputPreview) > 500 {
-							outputPreview = outputPreview[:500] + "... (truncated)"
-						}
-						logger.Debugf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool output preview:\n%s",
-							state.CurrentRound+1, i+1, len(response.ToolCalls), outputPreview)
-					}
-
-					if toolCall.Result.Error != "" {
-						logger.Warnf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool error: %s",
-							state.CurrentRound+1, i+1, len(response.ToolCalls), toolCall.Result.Error)
-					}
-
-					// Log structured data if present
-					if toolCall.Result.Data != nil {
-						dataJSON, _ := json.MarshalIndent(toolCall.Result.Data, "", "  ")
-						logger.Debugf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool data:\n%s",
-							state.CurrentRound+1, i+1, len(response.ToolCalls), string(dataJSON))
-					}
-				}
-
 				// Store tool call (Observations are now derived from ToolCall.Result.Output)
-				step.ToolCalls = append(step.ToolCalls, toolCall)
-
-				// Emit tool result event (include structured data from tool result)
-				e.eventBus.Emit(ctx, event.Event{
-					ID:        tc.ID + "-tool-result",
-					Type:      event.EventAgentToolResult,
-					SessionID: sessionID,
-					Data: event.AgentToolResultData{
-						ToolCallID: tc.ID,
-						ToolName:   tc.Function.Name,
-						Output:     result.Output,
-						Error:      result.Error,
-						Success:    result.Success,
-						Duration:   duration,
-						Iteration:  state.CurrentRound,
-						Data:       result.Data, // Pass structured data for frontend rendering
-					},
-				})
-
-				// Emit tool execution event (for internal monitoring)
-				e.eventBus.Emit(ctx, event.Event{
-					ID:        tc.ID + "-tool-exec",
-					Type:      event.EventAgentTool,
-					SessionID: sessionID,
-					Data: event.AgentActionData{
-						Iteration:  state.CurrentRound,
-						ToolName:   tc.Function.Name,
-						ToolInput:  args,
-						ToolOutput: result.Output,
-						Success:    result.Success,
-						Error:      result.Error,
-						Duration:   duration,
-					},
-				})
+				step.ToolCalls = append(step.ToolCalls, *toolCall)
 
 				// Optional: Reflection after each tool call (streaming)
-				if e.config.ReflectionEnabled && result != nil {
+				if e.config.ReflectionEnabled && toolCall.Result != nil {
 					reflection, err := e.streamReflectionToEventBus(
-						ctx, tc.ID, tc.Function.Name, result.Output,
+						ctx, toolCall.ID, toolCall.Name, toolCall.Result.Output,
 						state.CurrentRound, sessionID,
 					)
 					if err != nil {
@@ -447,6 +387,7 @@ func (e *AgentEngine) executeLoop(
 		})
 		// 5. Check if we should continue
 		state.CurrentRound++
+		e.saveCheckpoint(ctx, messageID, state, messages)
 	}
 
 	// If loop finished without final answer, generate one
@@ -493,6 +434,389 @@ func (e *AgentEngine) executeLoop(
 	return state, nil
 }
 
+// executeToolCalls executes the tool calls of a round, concurrently at most
+// MaxParallelToolCalls at a time when the agent enables it and sequentially otherwise.
+// Results keep the order of the tool calls so that tool messages are appended
+// deterministically; unparsable calls yield nil entries and panicking tools failed results.
+func (e *AgentEngine) executeToolCalls(
+	ctx context.Context,
+	toolCalls []types.LLMToolCall,
+	round int,
+	sessionID, messageID string,
+) []*types.ToolCall {
+	results := make([]*types.ToolCall, len(toolCalls))
+	limit := e.config.MaxParallelToolCalls
+	if limit <= 1 || len(toolCalls) == 1 {
+		for i, tc := range toolCalls {
+			results[i] = e.safeExecuteToolCall(ctx, tc, i, len(toolCalls), round, sessionID, messageID)
+		}
+		return results
+	}
+
+	common.PipelineInfo(ctx, "Agent", "tool_calls_parallel", map[string]interface{}{
+		"iteration":  round,
+		"tool_calls": len(toolCalls),
+		"limit":      limit,
+	})
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i, tc := range toolCalls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, tc types.LLMToolCall) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = e.safeExecuteToolCall(ctx, tc, i, len(toolCalls), round, sessionID, messageID)
+		}(i, tc)
+	}
+	wg.Wait()
+	return results
+}
+
+// safeExecuteToolCall executes a single tool call, turning a panic of the tool into a
+// failed result so that neither the round nor the process is brought down by one call.
+func (e *AgentEngine) safeExecuteToolCall(
+	ctx context.Context,
+	tc types.LLMToolCall,
+	i, total, round int,
+	sessionID, messageID string,
+) (toolCall *types.ToolCall) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool %s panicked: %v\n%s",
+				round+1, i+1, total, tc.Function.Name, r, debug.Stack())
+			toolCall = &types.ToolCall{
+				ID:   tc.ID,
+				Name: tc.Function.Name,
+				Result: &types.ToolResult{
+					Success: false,
+					Error:   fmt.Sprintf("tool %s failed unexpectedly: %v", tc.Function.Name, r),
+				},
+			}
+		}
+	}()
+	return e.executeToolCall(ctx, tc, i, total, round, sessionID, messageID)
+}
+
+// executeToolCall executes a single tool call of a round and emits its events.
+// Returns nil when the tool arguments cannot be parsed.
+func (e *AgentEngine) executeToolCall(
+	ctx context.Context,
+	tc types.LLMToolCall,
+	i, total, round int,
+	sessionID, messageID string,
+) *types.ToolCall {
+	logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool: %s, ID: %s",
+		round+1, i+1, total, tc.Function.Name, tc.ID)
+
+	var args map[string]any
+	if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+		logger.Errorf(ctx, "[Agent][Round-%d][Tool-%d/%d] Failed to parse tool arguments: %v",
+			round+1, i+1, total, err)
+		return nil
+	}
+
+	// Log the arguments in a readable format
+	argsJSON, _ := json.MarshalIndent(args, "", "  ")
+	logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Arguments:\n%s",
+		round+1, i+1, total, string(argsJSON))
+
+	toolCallStartTime := time.Now()
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        tc.ID + "-tool-call",
+		Type:      event.EventAgentToolCall,
+		SessionID: sessionID,
+		Data: event.AgentToolCallData{
+			ToolCallID: tc.ID,
+			ToolName:   tc.Function.Name,
+			Arguments:  args,
+			Iteration:  round,
+		},
+	})
+	logger.Debugf(ctx, "[Agent] ToolCall -> %s args=%s", tc.Function.Name, tc.Function.Arguments)
+
+	// Sensitive tools run only after a human approved the call, a refusal is the tool result
+	var result *types.ToolResult
+	var err error
+	if e.config.ToolApproval.Requires(tc.Function.Name) {
+		result = e.awaitApproval(ctx, tc, args, round, sessionID, messageID)
+		toolCallStartTime = time.Now()
+	}
+
+	if result == nil {
+		// Execute tool
+		logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Executing tool: %s...",
+			round+1, i+1, total, tc.Function.Name)
+		common.PipelineInfo(ctx, "Agent", "tool_call_start", map[string]interface{}{
+			"iteration":    round,
+			"round":        round + 1,
+			"tool":         tc.Function.Name,
+			"tool_call_id": tc.ID,
+			"tool_index":   fmt.Sprintf("%d/%d", i+1, total),
+		})
+		result, err = e.toolRegistry.ExecuteTool(ctx, tc.Function.Name, json.RawMessage(tc.Function.Arguments))
+	}
+	duration := time.Since(toolCallStartTime).Milliseconds()
+	logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool execution completed in %dms",
+		round+1, i+1, total, duration)
+
+	toolCall := types.ToolCall{
+		ID:       tc.ID,
+		Name:     tc.Function.Name,
+		Args:     args,
+		Result:   result,
+		Duration: duration,
+	}
+
+	if err != nil {
+		logger.Errorf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool call failed: %s, error: %v",
+			round+1, i+1, total, tc.Function.Name, err)
+		toolCall.Result = &types.ToolResult{
+			Success: false,
+			Error:   err.Error(),
+		}
+		var budgetErr *tools.BudgetExhaustedError
+		if errors.As(err, &budgetErr) {
+			e.emitBudgetExhausted(ctx, sessionID, event.AgentBudgetExhaustedData{
+				Kind:      budgetErr.Kind,
+				ToolName:  budgetErr.ToolName,
+				Limit:     budgetErr.Limit,
+				Iteration: round,
+			})
+		}
+	}
+
+	if toolCall.Result == nil {
+		toolCall.Result = &types.ToolResult{Success: false, Error: "tool returned no result"}
+	}
+
+	toolSuccess := toolCall.Result != nil && toolCall.Result.Success
+	pipelineFields := map[string]interface{}{
+		"iteration":    round,
+		"round":        round + 1,
+		"tool":         tc.Function.Name,
+		"tool_call_id": tc.ID,
+		"duration_ms":  duration,
+		"success":      toolSuccess,
+	}
+	if toolCall.Result != nil && toolCall.Result.Error != "" {
+		pipelineFields["error"] = toolCall.Result.Error
+	}
+	if err != nil {
+		common.PipelineError(ctx, "Agent", "tool_call_result", pipelineFields)
+	} else if toolSuccess {
+		common.PipelineInfo(ctx, "Agent", "tool_call_result", pipelineFields)
+	} else {
+		common.PipelineWarn(ctx, "Agent", "tool_call_result", pipelineFields)
+	}
+
+	if toolCall.Result != nil {
+		logger.Infof(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool result: success=%v, output_length=%d",
+			round+1, i+1, total,
+			toolCall.Result.Success, len(toolCall.Result.Output))
+		logger.Debugf(ctx, "[Agent] ToolResult <- %s success=%v len(output)=%d",
+			tc.Function.Name, toolCall.Result.Success, len(toolCall.Result.Output))
+
+		// Log the output content for debugging
+		if toolCall.Result.Output != "" {
+			// Truncate if too long for logging
+			outputPreview := toolCall.Result.Output
+			if len(outputPreview) > 500 {
+				outputPreview = outputPreview[:500] + "... (truncated)"
+			}
+			logger.Debugf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool output preview:\n%s",
+				round+1, i+1, total, outputPreview)
+		}
+
+		if toolCall.Result.Error != "" {
+			logger.Warnf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool error: %s",
+				round+1, i+1, total, toolCall.Result.Error)
+		}
+
+		// Log structured data if present
+		if toolCall.Result.Data != nil {
+			dataJSON, _ := json.MarshalIndent(toolCall.Result.Data, "", "  ")
+			logger.Debugf(ctx, "[Agent][Round-%d][Tool-%d/%d] Tool data:\n%s",
+				round+1, i+1, total, string(dataJSON))
+		}
+	}
+
+	// Emit tool result event (include structured data from tool result)
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        tc.ID + "-tool-result",
+		Type:      event.EventAgentToolResult,
+		SessionID: sessionID,
+		Data: event.AgentToolResultData{
+			ToolCallID: tc.ID,
+			ToolName:   tc.Function.Name,
+			Output:     toolCall.Result.Output,
+			Error:      toolCall.Result.Error,
+			Success:    toolCall.Result.Success,
+			Duration:   duration,
+			Iteration:  round,
+			Data:       toolCall.Result.Data, // Pass structured data for frontend rendering
+		},
+	})
+
+	// Emit tool execution event (for internal monitoring)
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        tc.ID + "-tool-exec",
+		Type:      event.EventAgentTool,
+		SessionID: sessionID,
+		Data: event.AgentActionData{
+			Iteration:  round,
+			ToolName:   tc.Function.Name,
+			ToolInput:  args,
+			ToolOutput: toolCall.Result.Output,
+			Success:    toolCall.Result.Success,
+			Error:      toolCall.Result.Error,
+			Duration:   duration,
+		},
+	})
+
+	return &toolCall
+}
+
+// awaitApproval persists an approval request for the tool call, notifies the client and
+// waits for the decision. Returns nil when the call is approved, otherwise the tool result
+// explaining the refusal to the model. Calls are refused when approval is unavailable.
+func (e *AgentEngine) awaitApproval(
+	ctx context.Context,
+	tc types.LLMToolCall,
+	args map[string]any,
+	round int,
+	sessionID, messageID string,
+) *types.ToolResult {
+	refuse := func(message string) *types.ToolResult {
+		return &types.ToolResult{Success: false, Error: message}
+	}
+	if e.approvalService == nil {
+		logger.Warnf(ctx, "[Agent] Tool %s requires approval but no approval service is configured", tc.Function.Name)
+		return refuse("This tool requires human approval, which is not available. Do not call it again.")
+	}
+
+	approval := &types.ToolApproval{
+		SessionID:  sessionID,
+		MessageID:  messageID,
+		ToolCallID: tc.ID,
+		ToolName:   tc.Function.Name,
+		Arguments:  types.JSON(tc.Function.Arguments),
+		ExpiresAt:  time.Now().Add(e.config.ToolApproval.GetTimeout()),
+	}
+	if err := e.approvalService.RequestApproval(ctx, approval); err != nil {
+		logger.Errorf(ctx, "[Agent] Failed to request approval for tool %s: %v", tc.Function.Name, err)
+		return refuse("Failed to request human approval for this tool call.")
+	}
+
+	common.PipelineInfo(ctx, "Agent", "approval_requested", map[string]interface{}{
+		"iteration":   round,
+		"tool":        tc.Function.Name,
+		"approval_id": approval.ID,
+	})
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        tc.ID + "-approval-request",
+		Type:      event.EventAgentApprovalRequest,
+		SessionID: sessionID,
+		Data: event.AgentApprovalRequestData{
+			ApprovalID: approval.ID,
+			ToolCallID: tc.ID,
+			ToolName:   tc.Function.Name,
+			Arguments:  args,
+			ExpiresAt:  approval.ExpiresAt.Unix(),
+			Iteration:  round,
+		},
+	})
+
+	decided, err := e.approvalService.WaitForDecision(ctx, approval)
+	if err != nil {
+		logger.Warnf(ctx, "[Agent] Waiting for approval of tool %s failed: %v", tc.Function.Name, err)
+		return refuse("The approval of this tool call could not be obtained.")
+	}
+
+	common.PipelineInfo(ctx, "Agent", "approval_decided", map[string]interface{}{
+		"iteration":   round,
+		"tool":        tc.Function.Name,
+		"approval_id": approval.ID,
+		"status":      string(decided.Status),
+	})
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        tc.ID + "-approval-result",
+		Type:      event.EventAgentApprovalResult,
+		SessionID: sessionID,
+		Data: event.AgentApprovalResultData{
+			ApprovalID: approval.ID,
+			ToolCallID: tc.ID,
+			ToolName:   tc.Function.Name,
+			Status:     string(decided.Status),
+			Reason:     decided.Reason,
+			Iteration:  round,
+		},
+	})
+
+	switch decided.Status {
+	case types.ToolApprovalApproved:
+		return nil
+	case types.ToolApprovalExpired:
+		return refuse("The user did not approve this tool call in time, it was not executed.")
+	default:
+		message := "The user denied this tool call, it was not executed."
+		if decided.Reason != "" {
+			message += " Reason: " + decided.Reason
+		}
+		return refuse(message)
+	}
+}
+
+// tokenBudgetExhausted reports whether the execution spent its token budget,
+// in which case no more thinking rounds run and the final answer is synthesized
+func (e *AgentEngine) tokenBudgetExhausted(ctx context.Context, round int, sessionID string) bool {
+	if e.config.ToolBudget == nil || e.config.ToolBudget.MaxTokens <= 0 ||
+		e.tokensUsed < e.config.ToolBudget.MaxTokens {
+		return false
+	}
+	logger.Warnf(ctx, "[Agent] Token budget exhausted: used=%d, limit=%d", e.tokensUsed, e.config.ToolBudget.MaxTokens)
+	e.emitBudgetExhausted(ctx, sessionID, event.AgentBudgetExhaustedData{
+		Kind:      types.BudgetKindTokens,
+		Limit:     e.config.ToolBudget.MaxTokens,
+		Used:      e.tokensUsed,
+		Iteration: round,
+	})
+	return true
+}
+
+// emitBudgetExhausted notifies subscribers that a budget of the execution is exhausted
+func (e *AgentEngine) emitBudgetExhausted(ctx context.Context, sessionID string, data event.AgentBudgetExhaustedData) {
+	common.PipelineWarn(ctx, "Agent", "budget_exhausted", map[string]interface{}{
+		"iteration": data.Iteration,
+		"kind":      data.Kind,
+		"tool":      data.ToolName,
+		"limit":     data.Limit,
+	})
+	e.eventBus.Emit(ctx, event.Event{
+		ID:        generateEventID("budget-exhausted"),
+		Type:      event.EventAgentBudgetExhausted,
+		SessionID: sessionID,
+		Data:      data,
+	})
+}
+
+// estimateRoundTokens counts the tokens of a thinking round with the tokenizer of the model
+func estimateRoundTokens(tok tokenizer.Tokenizer, messages []chat.Message, response *types.ChatResponse) int {
+	total := tok.Count(response.Content)
+	for _, tc := range response.ToolCalls {
+		total += tokenizer.CountAll(tok, tc.Function.Name, tc.Function.Arguments)
+	}
+	for _, msg := range messages {
+		total += tokenizer.CountAll(tok, msg.Role, msg.Content)
+		for _, tc := range msg.ToolCalls {
+			total += tokenizer.CountAll(tok, tc.Function.Name, tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
 // buildToolsForLLM builds the tools list for LLM function calling
 func (e *AgentEngine) buildToolsForLLM() []chat.Tool {
 	functionDefs := e.toolRegistry.GetFunctionDefinitions()
@@ -608,7 +932,8 @@ func (e *AgentEngine) streamLLMToEventBus(
 	for chunk := range stream {
 		chunkCount++
 
-		if chunk.Content != "" {
+		// Thinking of models streaming it separately is shown but not part of the content
+		if chunk.Content != "" && chunk.ResponseType != types.ResponseTypeThinking {
 			fullContent += chunk.Content
 		}
 
@@ -783,7 +1108,7 @@ func (e *AgentEngine) streamFinalAnswerToEventBus(
 		e.config.WebSearchEnabled,
 		e.selectedDocs,
 		e.systemPromptTemplate,
-	)
+	) + formatMCPResources(e.config.MCPResourceContexts) + formatScratchpad(e.config.Scratchpad)
 
 	messages := []chat.Message{
 		{Role: "system", Content: systemPrompt},
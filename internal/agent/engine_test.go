@@ -0,0 +1,445 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowTool echoes its index after a short delay and records the peak number of concurrent calls
+type slowTool struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (t *slowTool) Name() string        { return "slow" }
+func (t *slowTool) Description() string { return "Echo an index slowly" }
+func (t *slowTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"index":{"type":"integer"}}}`)
+}
+
+func (t *slowTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input struct {
+		Index int `json:"index"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.running++
+	if t.running > t.peak {
+		t.peak = t.running
+	}
+	t.mu.Unlock()
+
+	// Later calls finish first, so ordering by completion would reverse the results
+	time.Sleep(time.Duration(10-input.Index) * 5 * time.Millisecond)
+
+	t.mu.Lock()
+	t.running--
+	t.mu.Unlock()
+	return &types.ToolResult{Success: true, Output: fmt.Sprint(input.Index)}, nil
+}
+
+func TestExecuteToolCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		parallel int
+		wantPeak int
+	}{
+		{name: "sequential by default", parallel: 0, wantPeak: 1},
+		{name: "sequential", parallel: 1, wantPeak: 1},
+		{name: "bounded concurrency", parallel: 3, wantPeak: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &slowTool{}
+			registry := tools.NewToolRegistry()
+			registry.RegisterTool(tool)
+			engine := NewAgentEngine(
+				&types.AgentConfig{MaxParallelToolCalls: tt.parallel},
+				nil, registry, nil, nil, nil, nil, "session-1", "", nil, nil,
+			)
+
+			calls := make([]types.LLMToolCall, 8)
+			for i := range calls {
+				calls[i].ID = fmt.Sprintf("call-%d", i)
+				calls[i].Function.Name = "slow"
+				calls[i].Function.Arguments = fmt.Sprintf(`{"index":%d}`, i)
+			}
+			results := engine.executeToolCalls(context.Background(), calls, 0, "session-1", "message-1")
+
+			require.Len(t, results, len(calls))
+			for i, result := range results {
+				require.NotNil(t, result)
+				assert.Equal(t, calls[i].ID, result.ID)
+				assert.Equal(t, fmt.Sprint(i), result.Result.Output)
+			}
+			if tt.wantPeak == 1 {
+				assert.Equal(t, 1, tool.peak)
+			} else {
+				assert.LessOrEqual(t, tool.peak, tt.wantPeak)
+				assert.Greater(t, tool.peak, 1)
+			}
+		})
+	}
+}
+
+// panicTool panics on every call
+type panicTool struct{}
+
+func (t *panicTool) Name() string        { return "panic" }
+func (t *panicTool) Description() string { return "Always panic" }
+func (t *panicTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{}}`)
+}
+
+func (t *panicTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	panic("boom")
+}
+
+func TestExecuteToolCallsRecoversPanics(t *testing.T) {
+	for _, parallel := range []int{1, 3} {
+		t.Run(fmt.Sprintf("parallel %d", parallel), func(t *testing.T) {
+			registry := tools.NewToolRegistry()
+			registry.RegisterTool(&slowTool{})
+			registry.RegisterTool(&panicTool{})
+			engine := NewAgentEngine(
+				&types.AgentConfig{MaxParallelToolCalls: parallel},
+				nil, registry, nil, nil, nil, nil, "session-1", "", nil, nil,
+			)
+
+			calls := make([]types.LLMToolCall, 3)
+			for i := range calls {
+				calls[i].ID = fmt.Sprintf("call-%d", i)
+				calls[i].Function.Name = "slow"
+				calls[i].Function.Arguments = fmt.Sprintf(`{"index":%d}`, i)
+			}
+			calls[1].Function.Name = "panic"
+			calls[1].Function.Arguments = `{}`
+			results := engine.executeToolCalls(context.Background(), calls, 0, "session-1", "message-1")
+
+			require.Len(t, results, len(calls))
+			for _, i := range []int{0, 2} {
+				require.NotNil(t, results[i])
+				assert.True(t, results[i].Result.Success)
+				assert.Equal(t, fmt.Sprint(i), results[i].Result.Output)
+			}
+			require.NotNil(t, results[1])
+			assert.Equal(t, "call-1", results[1].ID)
+			assert.False(t, results[1].Result.Success)
+			assert.Contains(t, results[1].Result.Error, "boom")
+		})
+	}
+}
+
+// blockingTool runs until its context is cancelled
+type blockingTool struct{}
+
+func (t *blockingTool) Name() string        { return "blocking" }
+func (t *blockingTool) Description() string { return "Block until cancelled" }
+func (t *blockingTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{}}`)
+}
+
+func (t *blockingTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestExecuteToolCallTimeout(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(&blockingTool{})
+	registry.SetBudget(&types.ToolBudgetConfig{DefaultTimeoutSeconds: 1})
+
+	eventBus := event.NewEventBus()
+	var toolResult *event.AgentToolResultData
+	eventBus.On(event.EventAgentToolResult, func(ctx context.Context, evt event.Event) error {
+		data := evt.Data.(event.AgentToolResultData)
+		toolResult = &data
+		return nil
+	})
+	engine := NewAgentEngine(
+		&types.AgentConfig{},
+		nil, registry, eventBus, nil, nil, nil, "session-1", "", nil, nil,
+	)
+
+	call := types.LLMToolCall{ID: "call-1"}
+	call.Function.Name = "blocking"
+	call.Function.Arguments = `{}`
+	results := engine.executeToolCalls(context.Background(), []types.LLMToolCall{call}, 0, "session-1", "message-1")
+
+	require.Len(t, results, 1)
+	require.NotNil(t, results[0])
+	assert.False(t, results[0].Result.Success)
+	assert.Contains(t, results[0].Result.Error, "timed out")
+	require.NotNil(t, toolResult)
+	assert.False(t, toolResult.Success)
+	assert.Equal(t, results[0].Result.Error, toolResult.Error)
+}
+
+// policyTool returns the refund policy of a region
+type policyTool struct {
+	regions []string
+}
+
+func (t *policyTool) Name() string        { return "lookup_policy" }
+func (t *policyTool) Description() string { return "Look up a policy" }
+func (t *policyTool) Parameters() json.RawMessage {
+	return json.RawMessage(`{"type":"object","properties":{"topic":{"type":"string"},"region":{"type":"string"}}}`)
+}
+
+func (t *policyTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input struct {
+		Region string `json:"region"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	t.regions = append(t.regions, input.Region)
+	return &types.ToolResult{Success: true, Output: "Policy: within 30 days."}, nil
+}
+
+func TestExecuteWithMockModel(t *testing.T) {
+	chatModel, err := chat.NewChat(&chat.ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "mock",
+		BaseURL:   "testdata/mock_agent.json",
+		ModelName: "mock-agent",
+	})
+	require.NoError(t, err)
+	tool := &policyTool{}
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(tool)
+
+	eventBus := event.NewEventBus()
+	var toolCalls []string
+	var complete *event.AgentCompleteData
+	eventBus.On(event.EventAgentToolCall, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentToolCallData); ok && data.Arguments != nil {
+			toolCalls = append(toolCalls, data.ToolName)
+		}
+		return nil
+	})
+	eventBus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		data := evt.Data.(event.AgentCompleteData)
+		complete = &data
+		return nil
+	})
+
+	engine := NewAgentEngine(
+		&types.AgentConfig{MaxIterations: 5},
+		chatModel, registry, eventBus, nil, nil, nil, "session-1", "", nil, nil,
+	)
+	state, err := engine.Execute(context.Background(), "session-1", "message-1",
+		"What is the refund policy for EU?", nil)
+	require.NoError(t, err)
+
+	assert.True(t, state.IsComplete)
+	assert.Equal(t, "Refunds in this region are accepted within 30 days.", state.FinalAnswer)
+	require.Len(t, state.RoundSteps, 2)
+	assert.Equal(t, "Let me look up the policy.", state.RoundSteps[0].Thought)
+	require.Len(t, state.RoundSteps[0].ToolCalls, 1)
+	assert.Equal(t, "lookup_policy", state.RoundSteps[0].ToolCalls[0].Name)
+	assert.Equal(t, []string{"EU"}, tool.regions)
+	assert.Equal(t, []string{"lookup_policy"}, toolCalls)
+	require.NotNil(t, complete)
+	assert.Equal(t, state.FinalAnswer, complete.FinalAnswer)
+	assert.Equal(t, 2, complete.TotalSteps)
+}
+
+// checkpointRecorder keeps the checkpoints saved by an execution
+type checkpointRecorder struct {
+	interfaces.AgentCheckpointService
+	saved []types.AgentCheckpoint
+}
+
+func (r *checkpointRecorder) Save(ctx context.Context, messageID string,
+	state *types.AgentState, messages []chat.Message, tokensUsed int,
+) error {
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	messagesJSON, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	r.saved = append(r.saved, types.AgentCheckpoint{
+		MessageID:  messageID,
+		Round:      state.CurrentRound,
+		State:      types.JSON(stateJSON),
+		Messages:   types.JSON(messagesJSON),
+		TokensUsed: tokensUsed,
+	})
+	return nil
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	fixture, err := chat.LoadMockFixture("testdata/mock_agent.json")
+	require.NoError(t, err)
+	// The instance running the execution goes away after the first round
+	fixture.Responses[1].Error = "instance lost"
+	failingModel, err := chat.NewMockChatWithFixture(&chat.ChatConfig{ModelName: "mock-agent"}, fixture)
+	require.NoError(t, err)
+
+	firstTool := &policyTool{}
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(firstTool)
+	recorder := &checkpointRecorder{}
+	engine := NewAgentEngine(
+		&types.AgentConfig{MaxIterations: 5},
+		failingModel, registry, nil, nil, nil, nil, "session-1", "", nil, recorder,
+	)
+	_, err = engine.Execute(context.Background(), "session-1", "message-1",
+		"What is the refund policy for EU?", nil)
+	require.Error(t, err)
+	require.NotEmpty(t, recorder.saved)
+	checkpoint := recorder.saved[len(recorder.saved)-1]
+	assert.Equal(t, 1, checkpoint.Round)
+	assert.Equal(t, []string{"EU"}, firstTool.regions)
+
+	// Another instance resumes from the checkpoint without repeating the first round
+	checkpoint.SessionID = "session-1"
+	checkpoint.Query = "What is the refund policy for EU?"
+	checkpoint.Attempts = 2
+	chatModel, err := chat.NewChat(&chat.ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "mock",
+		BaseURL:   "testdata/mock_agent.json",
+		ModelName: "mock-agent",
+	})
+	require.NoError(t, err)
+	resumedTool := &policyTool{}
+	registry = tools.NewToolRegistry()
+	registry.RegisterTool(resumedTool)
+	registry.SetBudget(&types.ToolBudgetConfig{MaxCallsPerTool: map[string]int{"lookup_policy": 1}})
+	engine = NewAgentEngine(
+		&types.AgentConfig{MaxIterations: 5},
+		chatModel, registry, nil, nil, nil, nil, "session-1", "", nil, nil,
+	)
+	state, err := engine.Resume(context.Background(), &checkpoint)
+	require.NoError(t, err)
+
+	assert.True(t, state.IsComplete)
+	assert.Equal(t, "Refunds in this region are accepted within 30 days.", state.FinalAnswer)
+	require.Len(t, state.RoundSteps, 2)
+	assert.Equal(t, "lookup_policy", state.RoundSteps[0].ToolCalls[0].Name)
+	assert.Equal(t, "Refunds in this region are accepted within 30 days.", state.RoundSteps[1].Thought)
+	assert.Empty(t, resumedTool.regions)
+
+	// The restored call counts against the budget of the resumed execution
+	call := types.LLMToolCall{ID: "call-again"}
+	call.Function.Name = "lookup_policy"
+	call.Function.Arguments = `{"region":"US"}`
+	results := engine.executeToolCalls(context.Background(), []types.LLMToolCall{call}, 2, "session-1", "message-1")
+	require.NotNil(t, results[0])
+	assert.False(t, results[0].Result.Success)
+	assert.Contains(t, results[0].Result.Error, "budget exhausted")
+}
+
+// budgetEvents collects the budget exhausted events of an execution
+func budgetEvents(eventBus *event.EventBus) *[]event.AgentBudgetExhaustedData {
+	var events []event.AgentBudgetExhaustedData
+	eventBus.On(event.EventAgentBudgetExhausted, func(ctx context.Context, evt event.Event) error {
+		events = append(events, evt.Data.(event.AgentBudgetExhaustedData))
+		return nil
+	})
+	return &events
+}
+
+func TestExecuteToolCallsBudgetExhausted(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   *types.ToolBudgetConfig
+		wantKind string
+	}{
+		{
+			name:     "total tool calls",
+			budget:   &types.ToolBudgetConfig{MaxToolCalls: 2},
+			wantKind: types.BudgetKindToolCalls,
+		},
+		{
+			name:     "calls per tool",
+			budget:   &types.ToolBudgetConfig{MaxCallsPerTool: map[string]int{"slow": 2}},
+			wantKind: types.BudgetKindToolCallsPerTool,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := tools.NewToolRegistry()
+			registry.RegisterTool(&slowTool{})
+			registry.SetBudget(tt.budget)
+			eventBus := event.NewEventBus()
+			events := budgetEvents(eventBus)
+			engine := NewAgentEngine(
+				&types.AgentConfig{ToolBudget: tt.budget},
+				nil, registry, eventBus, nil, nil, nil, "session-1", "", nil, nil,
+			)
+
+			calls := make([]types.LLMToolCall, 3)
+			for i := range calls {
+				calls[i].ID = fmt.Sprintf("call-%d", i)
+				calls[i].Function.Name = "slow"
+				calls[i].Function.Arguments = fmt.Sprintf(`{"index":%d}`, i)
+			}
+			results := engine.executeToolCalls(context.Background(), calls, 1, "session-1", "message-1")
+
+			require.Len(t, results, 3)
+			assert.True(t, results[0].Result.Success)
+			assert.True(t, results[1].Result.Success)
+			assert.False(t, results[2].Result.Success)
+			assert.Contains(t, results[2].Result.Error, "tool budget exhausted")
+			require.Len(t, *events, 1)
+			assert.Equal(t, event.AgentBudgetExhaustedData{
+				Kind: tt.wantKind, ToolName: "slow", Limit: 2, Iteration: 1,
+			}, (*events)[0])
+		})
+	}
+}
+
+func TestExecuteTokenBudgetExhausted(t *testing.T) {
+	chatModel, err := chat.NewChat(&chat.ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "mock",
+		BaseURL:   "testdata/mock_agent.json",
+		ModelName: "mock-agent",
+	})
+	require.NoError(t, err)
+	tool := &policyTool{}
+	registry := tools.NewToolRegistry()
+	registry.RegisterTool(tool)
+	eventBus := event.NewEventBus()
+	events := budgetEvents(eventBus)
+
+	engine := NewAgentEngine(
+		&types.AgentConfig{MaxIterations: 5, ToolBudget: &types.ToolBudgetConfig{MaxTokens: 1}},
+		chatModel, registry, eventBus, nil, nil, nil, "session-1", "", nil, nil,
+	)
+	state, err := engine.Execute(context.Background(), "session-1", "message-1",
+		"What is the refund policy for EU?", nil)
+	require.NoError(t, err)
+
+	// The first round spends the budget, so the answer is synthesized instead of thinking again
+	require.Len(t, state.RoundSteps, 1)
+	assert.Equal(t, []string{"EU"}, tool.regions)
+	assert.True(t, state.IsComplete)
+	require.Len(t, *events, 1)
+	data := (*events)[0]
+	assert.Equal(t, types.BudgetKindTokens, data.Kind)
+	assert.Equal(t, 1, data.Limit)
+	assert.Greater(t, data.Used, 1)
+	assert.Equal(t, 1, data.Iteration)
+}
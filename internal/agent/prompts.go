@@ -225,6 +225,61 @@ func formatSelectedDocuments(docs []*SelectedDocumentInfo) string {
 	return builder.String()
 }
 
+// formatScratchpad formats the plan and findings kept from earlier turns of the session for the prompt
+func formatScratchpad(scratchpad *types.AgentScratchpad) string {
+	if scratchpad.IsEmpty() {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n### Research Plan From Earlier Turns\n")
+	builder.WriteString("This is the plan and the key findings recorded with `todo_write` in earlier turns of this conversation. ")
+	builder.WriteString("Build on them when the user continues the same research instead of starting over, ")
+	builder.WriteString("and verify a finding again before relying on it for details it does not state.\n")
+
+	if len(scratchpad.Steps) > 0 {
+		builder.WriteString("\n")
+		if scratchpad.Task != "" {
+			builder.WriteString(fmt.Sprintf("**Task**: %s\n", scratchpad.Task))
+		}
+		builder.WriteString(fmt.Sprintf("**Steps** (%d of %d not completed):\n",
+			scratchpad.PendingSteps(), len(scratchpad.Steps)))
+		for _, step := range scratchpad.Steps {
+			builder.WriteString(fmt.Sprintf("- [%s] %s: %s\n", step.Status, step.ID, step.Description))
+		}
+	}
+
+	if len(scratchpad.Findings) > 0 {
+		builder.WriteString("\n**Findings**:\n")
+		for _, finding := range scratchpad.Findings {
+			builder.WriteString(fmt.Sprintf("- %s\n", finding.Content))
+		}
+	}
+	builder.WriteString("\n")
+
+	return builder.String()
+}
+
+// formatMCPResources formats the MCP resources attached to the agent for the prompt
+func formatMCPResources(resources []*types.MCPResourceContext) string {
+	if len(resources) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\n### Attached Resources\n")
+	builder.WriteString("These resources of MCP services are attached to this conversation as reference material. ")
+	builder.WriteString("Use them directly, and read them again with `read_mcp_resource` only if they are truncated.\n")
+	for _, resource := range resources {
+		builder.WriteString(fmt.Sprintf("\n#### %s (service: %s)\n", resource.URI, resource.ServiceName))
+		builder.WriteString(resource.Text)
+		builder.WriteString("\n")
+	}
+	builder.WriteString("\n")
+
+	return builder.String()
+}
+
 // renderPromptPlaceholdersWithStatus renders placeholders including web search status
 // Supported placeholders:
 //   - {{knowledge_bases}}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// BudgetExhaustedError is returned by ExecuteTool when a call exceeds a tool budget
+type BudgetExhaustedError struct {
+	Kind     string // types.BudgetKind*
+	ToolName string
+	Limit    int
+}
+
+// Error describes the exhausted budget so that the model can stop calling the tool
+func (e *BudgetExhaustedError) Error() string {
+	if e.Kind == types.BudgetKindToolCallsPerTool {
+		return fmt.Sprintf("tool budget exhausted: %s may be called at most %d times, "+
+			"answer with the information already collected", e.ToolName, e.Limit)
+	}
+	return fmt.Sprintf("tool budget exhausted: at most %d tool calls are allowed, "+
+		"answer with the information already collected", e.Limit)
+}
+
+// ToolTimeoutError is returned by ExecuteTool when a call exceeds its timeout
+type ToolTimeoutError struct {
+	ToolName string
+	Timeout  time.Duration
+}
+
+// Error describes the timeout
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool %s timed out after %s", e.ToolName, e.Timeout)
+}
+
+// toolBudget counts tool calls against the budget of an agent execution
+type toolBudget struct {
+	config *types.ToolBudgetConfig
+
+	mu      sync.Mutex
+	total   int
+	perTool map[string]int
+}
+
+// newToolBudget creates a budget, nil when the config sets no limit
+func newToolBudget(config *types.ToolBudgetConfig) *toolBudget {
+	if config == nil {
+		return nil
+	}
+	return &toolBudget{config: config, perTool: make(map[string]int)}
+}
+
+// acquire reserves a call of the tool, failing when a budget is exhausted
+func (b *toolBudget) acquire(name string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if limit := b.config.MaxToolCalls; limit > 0 && b.total >= limit {
+		return &BudgetExhaustedError{Kind: types.BudgetKindToolCalls, ToolName: name, Limit: limit}
+	}
+	if limit := b.config.MaxCallsPerTool[name]; limit > 0 && b.perTool[name] >= limit {
+		return &BudgetExhaustedError{Kind: types.BudgetKindToolCallsPerTool, ToolName: name, Limit: limit}
+	}
+	b.total++
+	b.perTool[name]++
+	return nil
+}
+
+// charge counts a call made before the execution was resumed, whether or not it fits the budget
+func (b *toolBudget) charge(name string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total++
+	b.perTool[name]++
+}
+
+// timeout returns the timeout of the tool, zero when calls are not bounded
+func (b *toolBudget) timeout(name string) time.Duration {
+	if b == nil {
+		return 0
+	}
+	if seconds, ok := b.config.ToolTimeoutSeconds[name]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(b.config.DefaultTimeoutSeconds) * time.Second
+}
+
+// executeWithTimeout runs the tool, giving up when the timeout expires. The tool keeps the
+// cancelled context, so a tool that ignores it finishes in the background without blocking the agent.
+// release is called once the execution returns, including when it finishes in the background.
+func executeWithTimeout(ctx context.Context, tool types.Tool,
+	args json.RawMessage, timeout time.Duration, release func(),
+) (*types.ToolResult, error) {
+	if timeout <= 0 {
+		defer release()
+		return tool.Execute(ctx, args)
+	}
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result *types.ToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer release()
+		// The agent has no frame on this goroutine, a panic of the tool becomes its error
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{nil, fmt.Errorf("tool %s panicked: %v", tool.Name(), r)}
+			}
+		}()
+		result, err := tool.Execute(ctx, args)
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		if err := parent.Err(); err != nil {
+			return nil, err
+		}
+		return nil, &ToolTimeoutError{ToolName: tool.Name(), Timeout: timeout}
+	}
+}
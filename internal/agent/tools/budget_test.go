@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stuckTool is an exclusive tool whose first call ignores its context until released
+type stuckTool struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (t *stuckTool) Name() string                { return "stuck" }
+func (t *stuckTool) Description() string         { return "A tool ignoring cancellation" }
+func (t *stuckTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+func (t *stuckTool) Exclusive() bool             { return true }
+
+func (t *stuckTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	if t.calls.Add(1) == 1 {
+		<-t.release
+	}
+	return &types.ToolResult{Success: true}, nil
+}
+
+func TestExclusiveToolLockedAfterTimeout(t *testing.T) {
+	tool := &stuckTool{release: make(chan struct{})}
+	registry := NewToolRegistry()
+	registry.RegisterTool(tool)
+	registry.SetBudget(&types.ToolBudgetConfig{DefaultTimeoutSeconds: 1})
+
+	_, err := registry.ExecuteTool(context.Background(), "stuck", nil)
+	var timeoutErr *ToolTimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "expected a timeout, got %v", err)
+
+	done := make(chan *types.ToolResult, 1)
+	go func() {
+		result, _ := registry.ExecuteTool(context.Background(), "stuck", nil)
+		done <- result
+	}()
+
+	// The timed-out call is still running, so the next call must wait for it
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), tool.calls.Load())
+
+	close(tool.release)
+	select {
+	case result := <-done:
+		require.NotNil(t, result)
+		assert.True(t, result.Success)
+	case <-time.After(2 * time.Second):
+		t.Fatal("next call did not run after the timed-out call returned")
+	}
+	assert.Equal(t, int32(2), tool.calls.Load())
+}
+
+func TestRestoreChargesBudgetAndReplaysState(t *testing.T) {
+	thinking := NewSequentialThinkingTool()
+	registry := NewToolRegistry()
+	registry.RegisterTool(thinking)
+	registry.SetBudget(&types.ToolBudgetConfig{MaxCallsPerTool: map[string]int{thinking.Name(): 2}})
+
+	registry.Restore(context.Background(), []types.AgentStep{{
+		ToolCalls: []types.ToolCall{{
+			Name: thinking.Name(),
+			Args: map[string]interface{}{"thought": "plan", "thought_number": 1, "total_thoughts": 2},
+		}},
+	}})
+	assert.Len(t, thinking.thoughtHistory, 1)
+
+	args := json.RawMessage(`{"thought":"check","thought_number":2,"total_thoughts":2}`)
+	result, err := registry.ExecuteTool(context.Background(), thinking.Name(), args)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Data["thought_history_length"])
+
+	_, err = registry.ExecuteTool(context.Background(), thinking.Name(), args)
+	var budgetErr *BudgetExhaustedError
+	assert.True(t, errors.As(err, &budgetErr), "expected the restored calls to count, got %v", err)
+}
+
+// namedTool succeeds on every call
+type namedTool struct {
+	name string
+}
+
+func (t *namedTool) Name() string                { return t.name }
+func (t *namedTool) Description() string         { return "A tool that always succeeds" }
+func (t *namedTool) Parameters() json.RawMessage { return json.RawMessage(`{"type":"object"}`) }
+
+func (t *namedTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	return &types.ToolResult{Success: true}, nil
+}
+
+func TestToolBudgetExhausted(t *testing.T) {
+	tests := []struct {
+		name      string
+		budget    *types.ToolBudgetConfig
+		calls     []string
+		wantKind  string
+		wantLimit int
+		wantTool  string
+	}{
+		{
+			name:      "total tool calls",
+			budget:    &types.ToolBudgetConfig{MaxToolCalls: 2},
+			calls:     []string{"search", "fetch", "search"},
+			wantKind:  types.BudgetKindToolCalls,
+			wantLimit: 2,
+			wantTool:  "search",
+		},
+		{
+			name:      "calls per tool",
+			budget:    &types.ToolBudgetConfig{MaxCallsPerTool: map[string]int{"search": 1}},
+			calls:     []string{"search", "fetch", "fetch", "search"},
+			wantKind:  types.BudgetKindToolCallsPerTool,
+			wantLimit: 1,
+			wantTool:  "search",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewToolRegistry()
+			registry.RegisterTool(&namedTool{name: "search"})
+			registry.RegisterTool(&namedTool{name: "fetch"})
+			registry.SetBudget(tt.budget)
+
+			last := len(tt.calls) - 1
+			for _, name := range tt.calls[:last] {
+				result, err := registry.ExecuteTool(context.Background(), name, nil)
+				require.NoError(t, err)
+				assert.True(t, result.Success)
+			}
+
+			result, err := registry.ExecuteTool(context.Background(), tt.calls[last], nil)
+			var budgetErr *BudgetExhaustedError
+			require.True(t, errors.As(err, &budgetErr), "expected an exhausted budget, got %v", err)
+			assert.Equal(t, tt.wantKind, budgetErr.Kind)
+			assert.Equal(t, tt.wantLimit, budgetErr.Limit)
+			assert.Equal(t, tt.wantTool, budgetErr.ToolName)
+			require.NotNil(t, result)
+			assert.False(t, result.Success)
+			assert.Equal(t, err.Error(), result.Error)
+			assert.Contains(t, result.Error, "tool budget exhausted")
+		})
+	}
+}
@@ -54,6 +54,11 @@ func (t *DataAnalysisTool) recordCreatedTable(tableName string) bool {
 	return true
 }
 
+// Exclusive reports that queries must not run concurrently, they share the session schema
+func (t *DataAnalysisTool) Exclusive() bool {
+	return true
+}
+
 // Cleanup cleans up the session-specific schema
 func (t *DataAnalysisTool) Cleanup(ctx context.Context) {
 	if len(t.createdTables) == 0 {
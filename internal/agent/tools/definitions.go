@@ -14,6 +14,9 @@ const (
 	ToolDataSchema          = "data_schema"
 	ToolWebSearch           = "web_search"
 	ToolWebFetch            = "web_fetch"
+	ToolDelegateAgent       = "delegate_to_agent"
+	ToolListMCPResources    = "list_mcp_resources"
+	ToolReadMCPResource     = "read_mcp_resource"
 )
 
 // AvailableTool defines a simple tool metadata used by settings APIs.
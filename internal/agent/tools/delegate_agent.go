@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// DelegateAgentInput defines the input parameters for the delegate agent tool
+type DelegateAgentInput struct {
+	AgentID string `json:"agent_id"`
+	Query   string `json:"query"`
+}
+
+// DelegateAgentTarget describes an agent that sub-tasks may be delegated to
+type DelegateAgentTarget struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// DelegateAgentTool hands a sub-task to another custom agent and returns its answer
+type DelegateAgentTool struct {
+	BaseTool
+	runner  types.SubAgentRunner
+	config  *types.SubAgentConfig
+	targets map[string]DelegateAgentTarget
+}
+
+// NewDelegateAgentTool creates a new delegate agent tool for the target agents the config allows
+func NewDelegateAgentTool(
+	runner types.SubAgentRunner,
+	config *types.SubAgentConfig,
+	targets []DelegateAgentTarget,
+) *DelegateAgentTool {
+	ids := make([]string, 0, len(targets))
+	byID := make(map[string]DelegateAgentTarget, len(targets))
+	var agents strings.Builder
+	for _, target := range targets {
+		if !config.Allows(target.ID) {
+			continue
+		}
+		ids = append(ids, target.ID)
+		byID[target.ID] = target
+		fmt.Fprintf(&agents, "- **%s** (agent_id: `%s`): %s\n", target.Name, target.ID, target.Description)
+	}
+
+	description := fmt.Sprintf(`Delegate a self-contained sub-task to a specialized agent and get its final answer.
+
+## Available Agents
+%s
+## When to Use
+✅ **Use for**:
+- Sub-tasks matching the expertise of one of the agents above (e.g. data analysis, graph exploration)
+- Questions that need the knowledge bases or tools of another agent
+
+❌ **Don't use for**:
+- Tasks you can complete with your own tools
+- Passing the whole user question unchanged
+
+## Parameters
+- **agent_id** (required): ID of the agent to delegate to
+- **query** (required): Complete, self-contained instruction for the agent. It does not see this conversation, include all needed context.
+
+## Notes
+- The agent runs with its own tools and knowledge bases and returns its final answer and references
+- Delegation depth is limited, delegated agents may not be able to delegate further`, agents.String())
+
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"agent_id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the agent to delegate to",
+				"enum":        ids,
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Complete, self-contained instruction for the agent",
+			},
+		},
+		"required": []string{"agent_id", "query"},
+	})
+
+	return &DelegateAgentTool{
+		BaseTool: NewBaseTool(ToolDelegateAgent, description, schema),
+		runner:   runner,
+		config:   config,
+		targets:  byID,
+	}
+}
+
+// Execute runs the target agent on the query at the next nesting depth
+func (t *DelegateAgentTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input DelegateAgentInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to parse args: %v", err),
+		}, err
+	}
+
+	if !t.config.Allows(input.AgentID) {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("agent %s is not allowed for delegation", input.AgentID),
+		}, fmt.Errorf("agent not allowed: %s", input.AgentID)
+	}
+	target, ok := t.targets[input.AgentID]
+	if !ok {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("agent %s is not available for delegation", input.AgentID),
+		}, fmt.Errorf("agent not available: %s", input.AgentID)
+	}
+	if strings.TrimSpace(input.Query) == "" {
+		return &types.ToolResult{
+			Success: false,
+			Error:   "query is required",
+		}, fmt.Errorf("invalid query")
+	}
+
+	depth := types.SubAgentDepth(ctx) + 1
+	if maxDepth := t.config.GetMaxDepth(); depth > maxDepth {
+		return &types.ToolResult{
+			Success: false,
+			Error: fmt.Sprintf("delegation depth limit reached (%d), answer with your own tools",
+				maxDepth),
+		}, fmt.Errorf("sub-agent depth limit reached")
+	}
+
+	result, err := t.runner.RunSubAgent(types.WithSubAgentDepth(ctx, depth), target.ID, input.Query)
+	if err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("agent %s failed: %v", target.Name, err),
+		}, err
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "=== Answer from agent %s ===\n\n%s\n", result.AgentName, result.Answer)
+	if len(result.KnowledgeRefs) > 0 {
+		output.WriteString("\n=== References ===\n")
+		for i, ref := range result.KnowledgeRefs {
+			fmt.Fprintf(&output, "%d. %s (chunk %s)\n", i+1, ref.KnowledgeTitle, ref.ID)
+		}
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  output.String(),
+		Data: map[string]interface{}{
+			"agent_id":       result.AgentID,
+			"agent_name":     result.AgentName,
+			"depth":          depth,
+			"rounds":         result.Rounds,
+			"answer":         result.Answer,
+			"knowledge_refs": result.KnowledgeRefs,
+		},
+	}, nil
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingRunner answers every delegation and records the depth it ran at
+type recordingRunner struct {
+	depths []int
+}
+
+func (r *recordingRunner) RunSubAgent(ctx context.Context, agentID, query string) (*types.SubAgentResult, error) {
+	r.depths = append(r.depths, types.SubAgentDepth(ctx))
+	return &types.SubAgentResult{AgentID: agentID, AgentName: "Analyst", Answer: "42"}, nil
+}
+
+func TestDelegateAgentTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		depth     int
+		agentID   string
+		query     string
+		wantErr   bool
+		wantDepth int
+	}{
+		{name: "top level", depth: 0, agentID: "analyst", query: "sum the sales", wantDepth: 1},
+		{name: "nested within limit", depth: 1, agentID: "analyst", query: "sum the sales", wantDepth: 2},
+		{name: "depth limit reached", depth: 2, agentID: "analyst", query: "sum the sales", wantErr: true},
+		{name: "agent not allowed", depth: 0, agentID: "admin", query: "sum the sales", wantErr: true},
+		{name: "agent no longer available", depth: 0, agentID: "writer", query: "sum the sales", wantErr: true},
+		{name: "empty query", depth: 0, agentID: "analyst", query: " ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &recordingRunner{}
+			// The writer was deleted and the admin is not in the allowlist
+			config := &types.SubAgentConfig{AllowedAgents: []string{"analyst", "writer"}, MaxDepth: 2}
+			tool := NewDelegateAgentTool(runner, config, []DelegateAgentTarget{
+				{ID: "analyst", Name: "Analyst"}, {ID: "admin", Name: "Admin"},
+			})
+
+			args, _ := json.Marshal(DelegateAgentInput{AgentID: tt.agentID, Query: tt.query})
+			ctx := types.WithSubAgentDepth(context.Background(), tt.depth)
+			result, err := tool.Execute(ctx, args)
+
+			require.NotNil(t, result)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.False(t, result.Success)
+				assert.Empty(t, runner.depths, "the sub-agent must not run")
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, result.Success)
+			assert.Equal(t, []int{tt.wantDepth}, runner.depths)
+			assert.Equal(t, tt.wantDepth, result.Data["depth"])
+		})
+	}
+}
@@ -1119,6 +1119,7 @@ func (t *KnowledgeSearchTool) formatOutput(
 		formattedResults = append(formattedResults, map[string]interface{}{
 			"result_index": i + 1,
 			"chunk_id":     result.ID,
+			"chunk_index":  result.ChunkIndex,
 			"content":      result.Content,
 			// "score":        result.Score,
 			// "relevance_level":     relevanceLevel,
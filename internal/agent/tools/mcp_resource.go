@@ -0,0 +1,297 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// MaxMCPResourceLength bounds the text of an MCP resource returned to the agent, in bytes
+const MaxMCPResourceLength = 50_000
+
+// ListMCPResourcesInput defines the input parameters for the list MCP resources tool
+type ListMCPResourcesInput struct {
+	Service string `json:"service,omitempty"`
+}
+
+// ReadMCPResourceInput defines the input parameters for the read MCP resource tool
+type ReadMCPResourceInput struct {
+	Service string `json:"service"`
+	URI     string `json:"uri"`
+}
+
+// mcpResourceServices maps the sanitized names of MCP services to the services
+type mcpResourceServices map[string]*types.MCPService
+
+// newMCPResourceServices indexes enabled MCP services by sanitized name
+func newMCPResourceServices(services []*types.MCPService) mcpResourceServices {
+	byName := make(mcpResourceServices, len(services))
+	for _, service := range services {
+		if service != nil && service.Enabled {
+			byName[sanitizeName(service.Name)] = service
+		}
+	}
+	return byName
+}
+
+// names returns the sorted service names
+func (s mcpResourceServices) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ListMCPResourcesTool lists the resources exposed by the MCP services of the agent
+type ListMCPResourcesTool struct {
+	BaseTool
+	services   mcpResourceServices
+	mcpManager *mcp.MCPManager
+}
+
+// NewListMCPResourcesTool creates a new list MCP resources tool
+func NewListMCPResourcesTool(services []*types.MCPService, mcpManager *mcp.MCPManager) *ListMCPResourcesTool {
+	byName := newMCPResourceServices(services)
+	description := fmt.Sprintf(`List the resources (files, documents, records) exposed by MCP services.
+
+## When to Use
+- Before read_mcp_resource, to find the URI of a resource
+- When the user refers to data held by an external MCP service
+
+## Parameters
+- **service** (optional): Name of the MCP service, all services when omitted. One of: %s
+
+## Returns
+The URI, name, description and MIME type of each resource, grouped by service`,
+		strings.Join(byName.names(), ", "))
+
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"service": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the MCP service, all services when omitted",
+				"enum":        byName.names(),
+			},
+		},
+	})
+
+	return &ListMCPResourcesTool{
+		BaseTool:   NewBaseTool(ToolListMCPResources, description, schema),
+		services:   byName,
+		mcpManager: mcpManager,
+	}
+}
+
+// Execute lists the resources of one or all MCP services
+func (t *ListMCPResourcesTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input ListMCPResourcesInput
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &input); err != nil {
+			return &types.ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("Failed to parse args: %v", err),
+			}, err
+		}
+	}
+
+	names := t.services.names()
+	if input.Service != "" {
+		if _, ok := t.services[input.Service]; !ok {
+			return &types.ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("Unknown MCP service %q, available: %s", input.Service, strings.Join(names, ", ")),
+			}, nil
+		}
+		names = []string{input.Service}
+	}
+
+	var builder strings.Builder
+	listed := make(map[string][]*types.MCPResource, len(names))
+	for _, name := range names {
+		resources, err := t.listResources(ctx, t.services[name])
+		if err != nil {
+			logger.GetLogger(ctx).Warnf("Failed to list resources of MCP service %s: %v", name, err)
+			builder.WriteString(fmt.Sprintf("## %s\nFailed to list resources: %v\n\n", name, err))
+			continue
+		}
+		listed[name] = resources
+		builder.WriteString(fmt.Sprintf("## %s\n", name))
+		if len(resources) == 0 {
+			builder.WriteString("No resources\n\n")
+			continue
+		}
+		for _, resource := range resources {
+			builder.WriteString(fmt.Sprintf("- `%s` %s", resource.URI, resource.Name))
+			if resource.MimeType != "" {
+				builder.WriteString(fmt.Sprintf(" (%s)", resource.MimeType))
+			}
+			if resource.Description != "" {
+				builder.WriteString(": " + resource.Description)
+			}
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  builder.String(),
+		Data:    map[string]interface{}{"resources": listed},
+	}, nil
+}
+
+// listResources lists the resources of an MCP service
+func (t *ListMCPResourcesTool) listResources(ctx context.Context, service *types.MCPService) ([]*types.MCPResource, error) {
+	client, err := t.mcpManager.GetOrCreateClientFor(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	// For stdio transport, ensure connection is released after use
+	if service.TransportType == types.MCPTransportStdio {
+		defer client.Disconnect()
+	}
+	return client.ListResources(ctx)
+}
+
+// ReadMCPResourceTool reads a resource exposed by one of the MCP services of the agent
+type ReadMCPResourceTool struct {
+	BaseTool
+	services   mcpResourceServices
+	mcpManager *mcp.MCPManager
+}
+
+// NewReadMCPResourceTool creates a new read MCP resource tool
+func NewReadMCPResourceTool(services []*types.MCPService, mcpManager *mcp.MCPManager) *ReadMCPResourceTool {
+	byName := newMCPResourceServices(services)
+	description := fmt.Sprintf(`Read the content of a resource exposed by an MCP service.
+
+## When to Use
+- To read a resource found with list_mcp_resources
+- To read a resource whose URI the user or a previous tool result gave
+
+## Parameters
+- **service** (required): Name of the MCP service. One of: %s
+- **uri** (required): URI of the resource
+
+## Notes
+- Long resources are truncated to %d bytes
+- Binary contents are not returned, only their MIME type`,
+		strings.Join(byName.names(), ", "), MaxMCPResourceLength)
+
+	schema, _ := json.Marshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"service": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the MCP service",
+				"enum":        byName.names(),
+			},
+			"uri": map[string]interface{}{
+				"type":        "string",
+				"description": "URI of the resource",
+			},
+		},
+		"required": []string{"service", "uri"},
+	})
+
+	return &ReadMCPResourceTool{
+		BaseTool:   NewBaseTool(ToolReadMCPResource, description, schema),
+		services:   byName,
+		mcpManager: mcpManager,
+	}
+}
+
+// Execute reads the resource
+func (t *ReadMCPResourceTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	var input ReadMCPResourceInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to parse args: %v", err),
+		}, err
+	}
+	service, ok := t.services[input.Service]
+	if !ok {
+		return &types.ToolResult{
+			Success: false,
+			Error: fmt.Sprintf("Unknown MCP service %q, available: %s",
+				input.Service, strings.Join(t.services.names(), ", ")),
+		}, nil
+	}
+	if input.URI == "" {
+		return &types.ToolResult{Success: false, Error: "uri is required"}, nil
+	}
+
+	resource, err := ReadMCPResource(ctx, t.mcpManager, service, input.URI)
+	if err != nil {
+		logger.GetLogger(ctx).Warnf("Failed to read MCP resource %s of service %s: %v", input.URI, service.Name, err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to read resource: %v", err),
+		}, nil
+	}
+
+	return &types.ToolResult{
+		Success: true,
+		Output:  resource.Text,
+		Data: map[string]interface{}{
+			"service":   input.Service,
+			"uri":       resource.URI,
+			"mime_type": resource.MimeType,
+		},
+	}, nil
+}
+
+// ReadMCPResource reads a resource of an MCP service as text, truncated to MaxMCPResourceLength
+func ReadMCPResource(
+	ctx context.Context,
+	mcpManager *mcp.MCPManager,
+	service *types.MCPService,
+	uri string,
+) (*types.MCPResourceContext, error) {
+	client, err := mcpManager.GetOrCreateClientFor(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MCP service: %w", err)
+	}
+	// For stdio transport, ensure connection is released after use
+	if service.TransportType == types.MCPTransportStdio {
+		defer client.Disconnect()
+	}
+
+	result, err := client.ReadResource(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	text := result.Text()
+	if len(text) > MaxMCPResourceLength {
+		text = strings.ToValidUTF8(text[:MaxMCPResourceLength], "") + "\n\n[Truncated]"
+	}
+	mimeType := ""
+	if len(result.Contents) > 0 {
+		mimeType = result.Contents[0].MimeType
+	}
+	return &types.MCPResourceContext{
+		ServiceName: service.Name,
+		URI:         uri,
+		MimeType:    mimeType,
+		Text:        text,
+	}, nil
+}
+
+// RegisterMCPResourceTools registers the tools listing and reading the resources of the given MCP services
+func RegisterMCPResourceTools(registry *ToolRegistry, services []*types.MCPService, mcpManager *mcp.MCPManager) {
+	if len(newMCPResourceServices(services)) == 0 {
+		return
+	}
+	registry.RegisterTool(NewListMCPResourcesTool(services, mcpManager))
+	registry.RegisterTool(NewReadMCPResourceTool(services, mcpManager))
+}
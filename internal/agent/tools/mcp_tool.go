@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
 	"strings"
 	"time"
 
@@ -19,6 +21,8 @@ type MCPTool struct {
 	service    *types.MCPService
 	mcpTool    *types.MCPTool
 	mcpManager *mcp.MCPManager
+	// Fixed and constrained arguments of the tool, nil when unrestricted
+	arguments map[string]types.MCPArgumentPolicy
 }
 
 // NewMCPTool creates a new MCP tool wrapper
@@ -30,6 +34,12 @@ func NewMCPTool(service *types.MCPService, mcpTool *types.MCPTool, mcpManager *m
 	}
 }
 
+// WithArgumentPolicies fixes or constrains the arguments of the tool
+func (t *MCPTool) WithArgumentPolicies(arguments map[string]types.MCPArgumentPolicy) *MCPTool {
+	t.arguments = arguments
+	return t
+}
+
 // Name returns the unique name for this tool
 // Format: mcp.{service_name}.{tool_name}
 func (t *MCPTool) Name() string {
@@ -51,7 +61,7 @@ func (t *MCPTool) Description() string {
 // Parameters returns the JSON Schema for tool parameters
 func (t *MCPTool) Parameters() json.RawMessage {
 	if len(t.mcpTool.InputSchema) > 0 {
-		return t.mcpTool.InputSchema
+		return constrainMCPSchema(t.mcpTool.InputSchema, t.arguments)
 	}
 	// Return a default schema if none provided
 	return json.RawMessage(`{
@@ -74,8 +84,16 @@ func (t *MCPTool) Execute(ctx context.Context, args json.RawMessage) (*types.Too
 		}, err
 	}
 
+	if err := applyMCPArgumentPolicies(input, t.arguments); err != nil {
+		logger.GetLogger(ctx).Warnf("MCP tool %s called with disallowed arguments: %v", t.Name(), err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
 	// Get or create MCP client
-	client, err := t.mcpManager.GetOrCreateClient(t.service)
+	client, err := t.mcpManager.GetOrCreateClientFor(ctx, t.service)
 	if err != nil {
 		logger.GetLogger(ctx).Errorf("Failed to get MCP client: %v", err)
 		return &types.ToolResult{
@@ -132,6 +150,100 @@ func (t *MCPTool) Execute(ctx context.Context, args json.RawMessage) (*types.Too
 	}, nil
 }
 
+// constrainMCPSchema hides fixed arguments from the schema of a tool and adds the allowed values
+// and patterns of constrained arguments, so that the agent knows them before calling the tool
+func constrainMCPSchema(schema json.RawMessage, arguments map[string]types.MCPArgumentPolicy) json.RawMessage {
+	if len(arguments) == 0 {
+		return schema
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(schema, &parsed); err != nil {
+		return schema
+	}
+	properties, _ := parsed["properties"].(map[string]interface{})
+	required, _ := parsed["required"].([]interface{})
+
+	for name, policy := range arguments {
+		if policy.IsFixed() {
+			delete(properties, name)
+			kept := required[:0]
+			for _, r := range required {
+				if r != name {
+					kept = append(kept, r)
+				}
+			}
+			required = kept
+			continue
+		}
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if len(policy.Enum) > 0 {
+			property["enum"] = policy.Enum
+		}
+		if policy.Pattern != "" {
+			property["pattern"] = policy.Pattern
+		}
+	}
+	if required != nil {
+		parsed["required"] = required
+	}
+
+	constrained, err := json.Marshal(parsed)
+	if err != nil {
+		return schema
+	}
+	return constrained
+}
+
+// applyMCPArgumentPolicies sets the fixed arguments of a tool call and checks its constrained arguments
+func applyMCPArgumentPolicies(input MCPInput, arguments map[string]types.MCPArgumentPolicy) error {
+	for name, policy := range arguments {
+		if policy.IsFixed() {
+			input[name] = policy.Value
+			continue
+		}
+		value, ok := input[name]
+		if !ok {
+			continue
+		}
+		if len(policy.Enum) > 0 && !containsJSONValue(policy.Enum, value) {
+			return fmt.Errorf("argument %s must be one of %v, got %v", name, policy.Enum, value)
+		}
+		if policy.Pattern != "" {
+			text, isString := value.(string)
+			pattern, err := regexp.Compile(policy.Pattern)
+			if err != nil || !isString || !pattern.MatchString(text) {
+				return fmt.Errorf("argument %s must match %s, got %v", name, policy.Pattern, value)
+			}
+		}
+	}
+	return nil
+}
+
+// containsJSONValue returns true if value is in values, comparing them as decoded JSON
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	var decoded interface{}
+	_ = json.Unmarshal(encoded, &decoded)
+	for _, v := range values {
+		encodedV, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		var decodedV interface{}
+		_ = json.Unmarshal(encodedV, &decodedV)
+		if reflect.DeepEqual(decoded, decodedV) {
+			return true
+		}
+	}
+	return false
+}
+
 // extractContentText extracts text content from MCP content items
 func extractContentText(content []mcp.ContentItem) string {
 	var textParts []string
@@ -187,12 +299,14 @@ func sanitizeName(name string) string {
 	return result.String()
 }
 
-// RegisterMCPTools registers MCP tools from given services
+// RegisterMCPTools registers MCP tools from given services.
+// Tools denied by the policy of their service are skipped, and the argument policies applied to the others.
 func RegisterMCPTools(
 	ctx context.Context,
 	registry *ToolRegistry,
 	services []*types.MCPService,
 	mcpManager *mcp.MCPManager,
+	policies map[string]*types.MCPToolPolicy,
 ) error {
 	if len(services) == 0 {
 		return nil
@@ -215,37 +329,24 @@ func RegisterMCPTools(
 			continue
 		}
 
-		// Get or create client (this may take time, but has its own timeout)
-		client, err := mcpManager.GetOrCreateClient(service)
-		if err != nil {
-			logger.GetLogger(ctx).Errorf("Failed to create MCP client for service %s: %v", service.Name, err)
-			continue
-		}
-
-		// For stdio transport, ensure connection is released after listing tools
-		isStdio := service.TransportType == types.MCPTransportStdio
-		if isStdio {
-			defer func() {
-				if err := client.Disconnect(); err != nil {
-					logger.GetLogger(ctx).Warnf("Failed to disconnect stdio MCP client after listing tools: %v", err)
-				}
-			}()
-		}
-
-		// List tools from the service with timeout
-		// Create a new context with timeout for this specific operation
+		// List tools from the service with timeout, cached by the manager between agent runs
 		listCtx, cancel := context.WithTimeout(ctx, listToolsTimeout)
-		tools, err := client.ListTools(listCtx)
-		cancel() // Cancel after ListTools completes
+		tools, err := mcpManager.ListTools(listCtx, service)
+		cancel()
 
 		if err != nil {
 			logger.GetLogger(ctx).Errorf("Failed to list tools from MCP service %s: %v", service.Name, err)
 			continue
 		}
 
-		// Register each tool
+		// Register each tool allowed by the policy of the service
+		policy := policies[service.ID]
 		for _, mcpTool := range tools {
-			tool := NewMCPTool(service, mcpTool, mcpManager)
+			if !policy.Allows(mcpTool.Name) {
+				logger.GetLogger(ctx).Infof("MCP tool %s of service %s not allowed by agent policy", mcpTool.Name, service.Name)
+				continue
+			}
+			tool := NewMCPTool(service, mcpTool, mcpManager).WithArgumentPolicies(policy.ArgumentPolicies(mcpTool.Name))
 			registry.RegisterTool(tool)
 			logger.GetLogger(ctx).Infof("Registered MCP tool: %s from service: %s", tool.Name(), service.Name)
 		}
@@ -271,12 +372,7 @@ func GetMCPToolsInfo(
 			continue
 		}
 
-		client, err := mcpManager.GetOrCreateClient(service)
-		if err != nil {
-			continue
-		}
-
-		tools, err := client.ListTools(infoCtx)
+		tools, err := mcpManager.ListTools(infoCtx, service)
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPToolArgumentPolicies(t *testing.T) {
+	policy := &types.MCPToolPolicy{
+		DeniedTools: []string{"delete_issue"},
+		Arguments: map[string]map[string]types.MCPArgumentPolicy{
+			types.MCPToolPolicyAllTools: {"project": {Value: "ABC"}},
+			"search_issues": {
+				"status": {Enum: []interface{}{"open", "closed"}},
+				"label":  {Pattern: "^team-"},
+			},
+		},
+	}
+	require.NoError(t, policy.Validate())
+	assert.True(t, policy.Allows("search_issues"))
+	assert.False(t, policy.Allows("delete_issue"))
+
+	arguments := policy.ArgumentPolicies("search_issues")
+	tool := NewMCPTool(&types.MCPService{Name: "tracker"}, &types.MCPTool{
+		Name: "search_issues",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{` +
+			`"project":{"type":"string"},"status":{"type":"string"},"label":{"type":"string"},"limit":{"type":"integer"}},` +
+			`"required":["project","status"]}`),
+	}, nil).WithArgumentPolicies(arguments)
+
+	var schema struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+		Required   []string                          `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(tool.Parameters(), &schema))
+	assert.NotContains(t, schema.Properties, "project")
+	assert.Equal(t, []interface{}{"open", "closed"}, schema.Properties["status"]["enum"])
+	assert.Equal(t, "^team-", schema.Properties["label"]["pattern"])
+	assert.Equal(t, []string{"status"}, schema.Required)
+
+	input := MCPInput{"project": "XYZ", "status": "open", "label": "team-search", "limit": float64(5)}
+	require.NoError(t, applyMCPArgumentPolicies(input, arguments))
+	assert.Equal(t, "ABC", input["project"])
+
+	assert.Error(t, applyMCPArgumentPolicies(MCPInput{"status": "merged"}, arguments))
+	assert.Error(t, applyMCPArgumentPolicies(MCPInput{"label": "bug"}, arguments))
+	assert.Error(t, applyMCPArgumentPolicies(MCPInput{"label": float64(1)}, arguments))
+
+	invalid := &types.MCPToolPolicy{Arguments: map[string]map[string]types.MCPArgumentPolicy{
+		"search_issues": {"project": {Value: "ABC", Pattern: "^A"}},
+	}}
+	assert.Error(t, invalid.Validate())
+}
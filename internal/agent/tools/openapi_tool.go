@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/openapi"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// OpenAPITool wraps an operation of an OpenAPI service to implement the Tool interface
+type OpenAPITool struct {
+	service   *types.OpenAPIService
+	operation *openapi.Operation
+	schema    json.RawMessage
+	client    *http.Client
+}
+
+// NewOpenAPITool creates a new OpenAPI operation tool
+func NewOpenAPITool(service *types.OpenAPIService, operation *openapi.Operation) (*OpenAPITool, error) {
+	schema, err := operation.InputSchema()
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPITool{
+		service:   service,
+		operation: operation,
+		schema:    schema,
+		client:    &http.Client{Timeout: service.GetTimeout()},
+	}, nil
+}
+
+// OpenAPIToolName returns the tool name of an operation
+// Format: openapi.{service_name}.{operation_id}
+func OpenAPIToolName(serviceName, operationID string) string {
+	return fmt.Sprintf("openapi.%s.%s", sanitizeName(serviceName), sanitizeName(operationID))
+}
+
+// Name returns the unique name for this tool
+func (t *OpenAPITool) Name() string {
+	return OpenAPIToolName(t.service.Name, t.operation.ID)
+}
+
+// Description returns the tool description
+func (t *OpenAPITool) Description() string {
+	description := fmt.Sprintf("[API: %s] %s %s", t.service.Name, t.operation.Method, t.operation.Path)
+	if t.operation.Summary != "" {
+		description += "\n" + t.operation.Summary
+	}
+	if t.operation.Description != "" && t.operation.Description != t.operation.Summary {
+		description += "\n" + t.operation.Description
+	}
+	return description
+}
+
+// Parameters returns the JSON Schema for tool parameters
+func (t *OpenAPITool) Parameters() json.RawMessage {
+	return t.schema
+}
+
+// Execute calls the operation
+func (t *OpenAPITool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
+	logger.Infof(ctx, "Executing OpenAPI tool: %s %s from service: %s",
+		t.operation.Method, t.operation.Path, t.service.Name)
+
+	input := make(map[string]interface{})
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &input); err != nil {
+			return &types.ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("Failed to parse args: %v", err),
+			}, err
+		}
+	}
+
+	resp, err := t.operation.Invoke(ctx, t.client, t.service.BaseURL, t.service.AuthHeaders,
+		input, t.service.MaxResponseBytes)
+	if err != nil {
+		logger.Warnf(ctx, "OpenAPI tool call failed: %v", err)
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("Tool execution failed: %v", err),
+		}, nil
+	}
+
+	output := resp.Body
+	if resp.Truncated {
+		output += fmt.Sprintf("\n\n[Response truncated to %d bytes]", len(resp.Body))
+	}
+	data := map[string]interface{}{
+		"service":      t.service.Name,
+		"operation_id": t.operation.ID,
+		"status_code":  resp.StatusCode,
+		"content_type": resp.ContentType,
+		"truncated":    resp.Truncated,
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &types.ToolResult{
+			Success: false,
+			Output:  output,
+			Error:   fmt.Sprintf("HTTP %d: %s", resp.StatusCode, output),
+			Data:    data,
+		}, nil
+	}
+	return &types.ToolResult{
+		Success: true,
+		Output:  output,
+		Data:    data,
+	}, nil
+}
+
+// RegisterOpenAPITools registers the selected operations of the given services
+func RegisterOpenAPITools(ctx context.Context, registry *ToolRegistry, services []*types.OpenAPIService) {
+	for _, service := range services {
+		spec, err := openapi.Parse([]byte(service.Spec))
+		if err != nil {
+			logger.Errorf(ctx, "Failed to parse OpenAPI document of service %s: %v", service.Name, err)
+			continue
+		}
+		for _, operationID := range service.Operations {
+			operation := spec.Operation(operationID)
+			if operation == nil {
+				logger.Warnf(ctx, "Operation %s not found in OpenAPI service %s", operationID, service.Name)
+				continue
+			}
+			tool, err := NewOpenAPITool(service, operation)
+			if err != nil {
+				logger.Warnf(ctx, "Failed to create tool for operation %s: %v", operationID, err)
+				continue
+			}
+			registry.RegisterTool(tool)
+			logger.Infof(ctx, "Registered OpenAPI tool: %s from service: %s", tool.Name(), service.Name)
+		}
+	}
+}
@@ -4,26 +4,75 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/Tencent/WeKnora/internal/common"
+	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
+// ExclusiveTool is implemented by stateful tools whose executions must not overlap
+// when the agent executes the tool calls of a round concurrently
+type ExclusiveTool interface {
+	Exclusive() bool
+}
+
+// RestorableTool is implemented by stateful tools that rebuild their state from the calls
+// of an interrupted execution when it is resumed
+type RestorableTool interface {
+	Restore(args json.RawMessage) error
+}
+
 // ToolRegistry manages the registration and retrieval of tools
 type ToolRegistry struct {
-	tools map[string]types.Tool
+	tools  map[string]types.Tool
+	locks  map[string]*sync.Mutex // Execution locks of exclusive tools
+	budget *toolBudget            // Call budgets and timeouts, nil when unlimited
 }
 
 // NewToolRegistry creates a new tool registry
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
 		tools: make(map[string]types.Tool),
+		locks: make(map[string]*sync.Mutex),
 	}
 }
 
 // RegisterTool adds a tool to the registry
 func (r *ToolRegistry) RegisterTool(tool types.Tool) {
 	r.tools[tool.Name()] = tool
+	if exclusive, ok := tool.(ExclusiveTool); ok && exclusive.Exclusive() {
+		r.locks[tool.Name()] = &sync.Mutex{}
+	} else {
+		delete(r.locks, tool.Name())
+	}
+}
+
+// SetBudget limits the calls and execution time of the registered tools.
+// Call counts are kept by the registry, which lives for a single agent execution.
+func (r *ToolRegistry) SetBudget(config *types.ToolBudgetConfig) {
+	r.budget = newToolBudget(config)
+}
+
+// Restore rebuilds the tool state of a resumed execution from the steps it already took:
+// their calls count against the budget and are replayed on the restorable tools
+func (r *ToolRegistry) Restore(ctx context.Context, steps []types.AgentStep) {
+	for _, step := range steps {
+		for _, call := range step.ToolCalls {
+			r.budget.charge(call.Name)
+			tool, ok := r.tools[call.Name].(RestorableTool)
+			if !ok {
+				continue
+			}
+			args, err := json.Marshal(call.Args)
+			if err == nil {
+				err = tool.Restore(args)
+			}
+			if err != nil {
+				logger.Warnf(ctx, "Failed to restore the state of tool %s: %v", call.Name, err)
+			}
+		}
+	}
 }
 
 // GetTool retrieves a tool by name
@@ -57,7 +106,8 @@ func (r *ToolRegistry) GetFunctionDefinitions() []types.FunctionDefinition {
 	return definitions
 }
 
-// ExecuteTool executes a tool by name with the given arguments
+// ExecuteTool executes a tool by name with the given arguments.
+// A failed call returns a failed result along with the error.
 func (r *ToolRegistry) ExecuteTool(
 	ctx context.Context,
 	name string,
@@ -79,7 +129,31 @@ func (r *ToolRegistry) ExecuteTool(
 		}, err
 	}
 
-	result, execErr := tool.Execute(ctx, args)
+	if err := r.budget.acquire(name); err != nil {
+		common.PipelineWarn(ctx, "AgentTool", "budget_exhausted", map[string]interface{}{
+			"tool":  name,
+			"error": err.Error(),
+		})
+		return &types.ToolResult{
+			Success: false,
+			Error:   err.Error(),
+		}, err
+	}
+
+	// An exclusive tool stays locked until its execution returns, even after a timeout
+	release := func() {}
+	if lock, ok := r.locks[name]; ok {
+		lock.Lock()
+		release = lock.Unlock
+	}
+	result, execErr := executeWithTimeout(ctx, tool, args, r.budget.timeout(name), release)
+	if execErr != nil && result == nil {
+		// Timed out or cancelled calls have no result, callers always get one
+		result = &types.ToolResult{
+			Success: false,
+			Error:   execErr.Error(),
+		}
+	}
 	fields := map[string]interface{}{
 		"tool": name,
 		"args": args,
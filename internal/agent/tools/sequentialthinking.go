@@ -158,6 +158,11 @@ func NewSequentialThinkingTool() *SequentialThinkingTool {
 	}
 }
 
+// Exclusive reports that thoughts must be recorded one at a time, in order
+func (t *SequentialThinkingTool) Exclusive() bool {
+	return true
+}
+
 // Execute executes the sequential thinking tool
 func (t *SequentialThinkingTool) Execute(ctx context.Context, args json.RawMessage) (*types.ToolResult, error) {
 	logger.Infof(ctx, "[Tool][SequentialThinking] Execute started")
@@ -181,21 +186,7 @@ func (t *SequentialThinkingTool) Execute(ctx context.Context, args json.RawMessa
 		}, err
 	}
 
-	// Adjust totalThoughts if thoughtNumber exceeds it
-	if input.ThoughtNumber > input.TotalThoughts {
-		input.TotalThoughts = input.ThoughtNumber
-	}
-
-	// Add to thought history
-	t.thoughtHistory = append(t.thoughtHistory, input)
-
-	// Handle branching
-	if input.BranchFromThought != nil && input.BranchID != "" {
-		if t.branches[input.BranchID] == nil {
-			t.branches[input.BranchID] = make([]SequentialThinkingInput, 0)
-		}
-		t.branches[input.BranchID] = append(t.branches[input.BranchID], input)
-	}
+	input = t.record(input)
 
 	logger.Debugf(ctx, "[Tool][SequentialThinking] %s", input.Thought)
 
@@ -238,6 +229,39 @@ func (t *SequentialThinkingTool) Execute(ctx context.Context, args json.RawMessa
 	}, nil
 }
 
+// Restore records a thought of the execution being resumed
+func (t *SequentialThinkingTool) Restore(args json.RawMessage) error {
+	var input SequentialThinkingInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return err
+	}
+	if err := t.validate(input); err != nil {
+		return err
+	}
+	t.record(input)
+	return nil
+}
+
+// record adds the thought to the history and its branch
+func (t *SequentialThinkingTool) record(input SequentialThinkingInput) SequentialThinkingInput {
+	// Adjust totalThoughts if thoughtNumber exceeds it
+	if input.ThoughtNumber > input.TotalThoughts {
+		input.TotalThoughts = input.ThoughtNumber
+	}
+
+	// Add to thought history
+	t.thoughtHistory = append(t.thoughtHistory, input)
+
+	// Handle branching
+	if input.BranchFromThought != nil && input.BranchID != "" {
+		if t.branches[input.BranchID] == nil {
+			t.branches[input.BranchID] = make([]SequentialThinkingInput, 0)
+		}
+		t.branches[input.BranchID] = append(t.branches[input.BranchID], input)
+	}
+	return input
+}
+
 // validate validates the input thought data
 func (t *SequentialThinkingTool) validate(data SequentialThinkingInput) error {
 	// Validate thought (required)
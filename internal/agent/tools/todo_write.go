@@ -129,6 +129,15 @@ The assistant did not use the todo list because this is an informational request
   - Use clear, descriptive task names focused on what to retrieve or research
   - **DO NOT include summary/synthesis tasks** - those are handled separately by the thinking tool
 
+5. **Plans Across Turns**:
+  - The plan and findings of earlier turns of the conversation are shown in the system prompt under "Research Plan From Earlier Turns"
+  - When the user continues the same research, update that plan (keep its step IDs, mark finished steps completed) instead of starting over
+  - When the user moves on to an unrelated question, write a new plan for it
+
+6. **Findings**:
+  - Use the optional findings parameter to record key facts established so far, as short statements with their source (document title or URL)
+  - Findings are kept for the following turns, so record only what will be worth remembering; do not repeat findings already listed in the system prompt
+
 **Important**: After completing all retrieval tasks in todo_write, use the thinking tool to synthesize findings and generate the final answer. The todo_write tool tracks WHAT to retrieve, while thinking tool handles HOW to synthesize and present the information.
 
 When in doubt, use this tool. Being proactive with task management demonstrates attentiveness and ensures you complete all retrieval requirements successfully.`,
@@ -145,6 +154,8 @@ type TodoWriteTool struct {
 type TodoWriteInput struct {
 	Task  string     `json:"task" jsonschema:"The complex task or question you need to create a plan for"`
 	Steps []PlanStep `json:"steps" jsonschema:"Array of research plan steps with status tracking"`
+	// Findings are persisted with the plan of the session for the following turns
+	Findings []string `json:"findings,omitempty" jsonschema:"Optional key findings established since the last update, each a short statement with its source"`
 }
 
 // PlanStep represents a single step in the research plan
@@ -181,6 +192,10 @@ func (t *TodoWriteTool) Execute(ctx context.Context, args json.RawMessage) (*typ
 
 	// Generate formatted output
 	output := generatePlanOutput(input.Task, planSteps)
+	if len(input.Findings) > 0 {
+		output += fmt.Sprintf("\n=== Findings Recorded ===\n%d findings will be kept for the following turns\n",
+			len(input.Findings))
+	}
 
 	// Prepare structured data for response
 	stepsJSON, _ := json.Marshal(planSteps)
@@ -192,6 +207,7 @@ func (t *TodoWriteTool) Execute(ctx context.Context, args json.RawMessage) (*typ
 			"task":         input.Task,
 			"steps":        planSteps,
 			"steps_json":   string(stepsJSON),
+			"findings":     input.Findings,
 			"total_steps":  len(planSteps),
 			"plan_created": true,
 			"display_type": "plan",
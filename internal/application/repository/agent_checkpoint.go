@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// agentCheckpointRepository implements the AgentCheckpointRepository interface
+type agentCheckpointRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentCheckpointRepository creates a new agent checkpoint repository
+func NewAgentCheckpointRepository(db *gorm.DB) interfaces.AgentCheckpointRepository {
+	return &agentCheckpointRepository{db: db}
+}
+
+// Save creates or replaces the checkpoint of an execution
+func (r *agentCheckpointRepository) Save(ctx context.Context, checkpoint *types.AgentCheckpoint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(checkpoint).Error
+}
+
+// GetByMessageID retrieves the checkpoint of an execution by assistant message ID and tenant ID
+func (r *agentCheckpointRepository) GetByMessageID(
+	ctx context.Context,
+	tenantID uint64,
+	messageID string,
+) (*types.AgentCheckpoint, error) {
+	var checkpoint types.AgentCheckpoint
+	err := r.db.WithContext(ctx).
+		Where("message_id = ? AND tenant_id = ?", messageID, tenantID).
+		First(&checkpoint).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// UpdateProgress stores the progress of an execution after a round
+func (r *agentCheckpointRepository) UpdateProgress(
+	ctx context.Context,
+	tenantID uint64,
+	messageID string,
+	round int,
+	messages, state types.JSON,
+	tokensUsed int,
+) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&types.AgentCheckpoint{}).
+		Where("message_id = ? AND tenant_id = ?", messageID, tenantID).
+		Updates(map[string]interface{}{
+			"round":        round,
+			"messages":     messages,
+			"state":        state,
+			"tokens_used":  tokensUsed,
+			"heartbeat_at": now,
+			"updated_at":   now,
+		}).Error
+}
+
+// UpdateStatus moves an execution owned by the instance to the given status
+func (r *agentCheckpointRepository) UpdateStatus(
+	ctx context.Context,
+	tenantID uint64,
+	messageID, owner string,
+	status types.AgentRunStatus,
+) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentCheckpoint{}).
+		Where("message_id = ? AND tenant_id = ? AND owner = ?", messageID, tenantID, owner).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Heartbeat refreshes the heartbeat of an execution owned by the instance
+func (r *agentCheckpointRepository) Heartbeat(ctx context.Context, tenantID uint64, messageID, owner string) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentCheckpoint{}).
+		Where("message_id = ? AND tenant_id = ? AND owner = ? AND status = ?",
+			messageID, tenantID, owner, types.AgentRunRunning).
+		Update("heartbeat_at", time.Now()).Error
+}
+
+// Claim takes over an execution that failed or whose heartbeat is older than staleBefore
+func (r *agentCheckpointRepository) Claim(
+	ctx context.Context,
+	tenantID uint64,
+	messageID, owner string,
+	staleBefore time.Time,
+) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&types.AgentCheckpoint{}).
+		Where("message_id = ? AND tenant_id = ?", messageID, tenantID).
+		Where("status = ? OR (status = ? AND heartbeat_at < ?)",
+			types.AgentRunFailed, types.AgentRunRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":       types.AgentRunRunning,
+			"owner":        owner,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"heartbeat_at": now,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ListStale lists running executions of all tenants whose heartbeat is older than staleBefore
+func (r *agentCheckpointRepository) ListStale(
+	ctx context.Context,
+	staleBefore time.Time,
+	limit int,
+) ([]*types.AgentCheckpoint, error) {
+	var checkpoints []*types.AgentCheckpoint
+	err := r.db.WithContext(ctx).
+		Select("message_id", "tenant_id", "session_id", "status", "owner", "attempts", "heartbeat_at").
+		Where("status = ? AND heartbeat_at < ?", types.AgentRunRunning, staleBefore).
+		Order("heartbeat_at ASC").
+		Limit(limit).
+		Find(&checkpoints).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
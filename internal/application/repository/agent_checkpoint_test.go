@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDB connects to the database named by WEKNORA_TEST_DB_DSN, skipping the test without one
+func testDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("WEKNORA_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("WEKNORA_TEST_DB_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to the test database: %v", err)
+	}
+	if err := db.AutoMigrate(&types.AgentCheckpoint{}); err != nil {
+		t.Fatalf("Failed to migrate agent checkpoints: %v", err)
+	}
+	return db
+}
+
+func TestAgentCheckpointClaim(t *testing.T) {
+	db := testDB(t)
+	repo := NewAgentCheckpointRepository(db)
+	ctx := context.Background()
+	tenantID := uint64(time.Now().UnixNano())
+	t.Cleanup(func() {
+		db.Where("tenant_id = ?", tenantID).Delete(&types.AgentCheckpoint{})
+	})
+
+	now := time.Now()
+	staleBefore := now.Add(-types.AgentCheckpointStaleAfter)
+	seed := map[types.AgentRunStatus]time.Duration{
+		types.AgentRunRunning:   -time.Hour,
+		types.AgentRunFailed:    0,
+		types.AgentRunCompleted: -time.Hour,
+	}
+	ids := map[types.AgentRunStatus]string{}
+	for status, age := range seed {
+		id := fmt.Sprintf("%d-%s", tenantID, status)
+		ids[status] = id
+		err := repo.Save(ctx, &types.AgentCheckpoint{
+			MessageID:   id,
+			TenantID:    tenantID,
+			Status:      status,
+			Owner:       "lost-instance",
+			Attempts:    1,
+			HeartbeatAt: now.Add(age),
+		})
+		if err != nil {
+			t.Fatalf("Failed to save checkpoint: %v", err)
+		}
+	}
+	fresh := fmt.Sprintf("%d-fresh", tenantID)
+	if err := repo.Save(ctx, &types.AgentCheckpoint{
+		MessageID: fresh, TenantID: tenantID, Status: types.AgentRunRunning,
+		Owner: "live-instance", Attempts: 1, HeartbeatAt: now,
+	}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	stale, err := repo.ListStale(ctx, staleBefore, 1000)
+	if err != nil {
+		t.Fatalf("ListStale failed: %v", err)
+	}
+	var listed []string
+	for _, checkpoint := range stale {
+		if checkpoint.TenantID == tenantID {
+			listed = append(listed, checkpoint.MessageID)
+		}
+	}
+	if len(listed) != 1 || listed[0] != ids[types.AgentRunRunning] {
+		t.Errorf("ListStale = %v, want only the stale running execution", listed)
+	}
+
+	// Concurrent claimers race for each execution, only one of them may win
+	for _, id := range []string{ids[types.AgentRunRunning], ids[types.AgentRunFailed]} {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var winners []string
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(owner string) {
+				defer wg.Done()
+				claimed, err := repo.Claim(ctx, tenantID, id, owner, staleBefore)
+				if err != nil {
+					t.Errorf("Claim failed: %v", err)
+					return
+				}
+				if claimed {
+					mu.Lock()
+					winners = append(winners, owner)
+					mu.Unlock()
+				}
+			}(fmt.Sprintf("instance-%d", i))
+		}
+		wg.Wait()
+		if len(winners) != 1 {
+			t.Fatalf("Claim of %s won by %v, want exactly one claimer", id, winners)
+		}
+
+		checkpoint, err := repo.GetByMessageID(ctx, tenantID, id)
+		if err != nil {
+			t.Fatalf("GetByMessageID failed: %v", err)
+		}
+		if checkpoint.Owner != winners[0] || checkpoint.Attempts != 2 || checkpoint.Status != types.AgentRunRunning {
+			t.Errorf("Claimed checkpoint = owner %s, attempts %d, status %s; want owner %s, attempts 2, running",
+				checkpoint.Owner, checkpoint.Attempts, checkpoint.Status, winners[0])
+		}
+	}
+
+	for _, id := range []string{fresh, ids[types.AgentRunCompleted]} {
+		claimed, err := repo.Claim(ctx, tenantID, id, "instance-x", staleBefore)
+		if err != nil {
+			t.Fatalf("Claim failed: %v", err)
+		}
+		if claimed {
+			t.Errorf("Claim of %s succeeded, want live and completed executions left alone", id)
+		}
+	}
+}
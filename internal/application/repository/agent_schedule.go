@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// agentScheduleRepository implements the AgentScheduleRepository interface
+type agentScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentScheduleRepository creates a new agent schedule repository
+func NewAgentScheduleRepository(db *gorm.DB) interfaces.AgentScheduleRepository {
+	return &agentScheduleRepository{db: db}
+}
+
+// Create creates a new agent schedule
+func (r *agentScheduleRepository) Create(ctx context.Context, schedule *types.AgentSchedule) error {
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+// GetByID retrieves an agent schedule by ID and tenant ID
+func (r *agentScheduleRepository) GetByID(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.AgentSchedule, error) {
+	var schedule types.AgentSchedule
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&schedule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// List retrieves all agent schedules for a tenant
+func (r *agentScheduleRepository) List(ctx context.Context, tenantID uint64) ([]*types.AgentSchedule, error) {
+	var schedules []*types.AgentSchedule
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// Update updates an agent schedule
+func (r *agentScheduleRepository) Update(ctx context.Context, schedule *types.AgentSchedule) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentSchedule{}).
+		Where("id = ? AND tenant_id = ?", schedule.ID, schedule.TenantID).
+		Updates(map[string]interface{}{
+			"name":               schedule.Name,
+			"description":        schedule.Description,
+			"enabled":            schedule.Enabled,
+			"agent_id":           schedule.AgentID,
+			"prompt":             schedule.Prompt,
+			"knowledge_base_ids": schedule.KnowledgeBaseIDs,
+			"cron_expression":    schedule.CronExpression,
+			"timezone":           schedule.Timezone,
+			"webhook_url":        schedule.WebhookURL,
+			"webhook_secret":     schedule.EncryptedWebhookSecret,
+			"max_retries":        schedule.MaxRetries,
+			"next_run_at":        schedule.NextRunAt,
+			"updated_at":         schedule.UpdatedAt,
+		}).Error
+}
+
+// Delete deletes an agent schedule (soft delete)
+func (r *agentScheduleRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Delete(&types.AgentSchedule{}).Error
+}
+
+// ListDue retrieves the enabled schedules of all tenants due at or before now
+func (r *agentScheduleRepository) ListDue(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*types.AgentSchedule, error) {
+	var schedules []*types.AgentSchedule
+	err := r.db.WithContext(ctx).
+		Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// Advance moves a due schedule to its next run time, only if it is still due at dueAt
+func (r *agentScheduleRepository) Advance(
+	ctx context.Context,
+	schedule *types.AgentSchedule,
+	dueAt time.Time,
+	next *time.Time,
+) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&types.AgentSchedule{}).
+		Where("id = ? AND tenant_id = ? AND next_run_at = ?", schedule.ID, schedule.TenantID, dueAt).
+		Updates(map[string]interface{}{
+			"next_run_at": next,
+			"last_run_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateLastRunStatus records the status of the last finished run of a schedule
+func (r *agentScheduleRepository) UpdateLastRunStatus(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	status types.AgentScheduleRunStatus,
+) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentSchedule{}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Update("last_run_status", status).Error
+}
+
+// CreateRun creates a new run of a schedule
+func (r *agentScheduleRepository) CreateRun(ctx context.Context, run *types.AgentScheduleRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// GetRun retrieves a run by ID and tenant ID
+func (r *agentScheduleRepository) GetRun(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.AgentScheduleRun, error) {
+	var run types.AgentScheduleRun
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+// UpdateRun updates the progress of a run
+func (r *agentScheduleRepository) UpdateRun(ctx context.Context, run *types.AgentScheduleRun) error {
+	return r.db.WithContext(ctx).
+		Model(&types.AgentScheduleRun{}).
+		Where("id = ? AND tenant_id = ?", run.ID, run.TenantID).
+		Updates(map[string]interface{}{
+			"status":              run.Status,
+			"attempts":            run.Attempts,
+			"session_id":          run.SessionID,
+			"message_id":          run.MessageID,
+			"error":               run.Error,
+			"webhook_status_code": run.WebhookStatusCode,
+			"delivered_at":        run.DeliveredAt,
+			"started_at":          run.StartedAt,
+			"finished_at":         run.FinishedAt,
+			"updated_at":          time.Now(),
+		}).Error
+}
+
+// ListRuns retrieves the latest runs of a schedule, newest first
+func (r *agentScheduleRepository) ListRuns(
+	ctx context.Context,
+	tenantID uint64,
+	scheduleID string,
+	limit int,
+) ([]*types.AgentScheduleRun, error) {
+	var runs []*types.AgentScheduleRun
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND schedule_id = ?", tenantID, scheduleID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
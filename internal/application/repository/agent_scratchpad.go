@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// agentScratchpadRepository implements the AgentScratchpadRepository interface
+type agentScratchpadRepository struct {
+	db *gorm.DB
+}
+
+// NewAgentScratchpadRepository creates a new agent scratchpad repository
+func NewAgentScratchpadRepository(db *gorm.DB) interfaces.AgentScratchpadRepository {
+	return &agentScratchpadRepository{db: db}
+}
+
+// Get retrieves the scratchpad of a session, nil when the session has none
+func (r *agentScratchpadRepository) Get(
+	ctx context.Context,
+	tenantID uint64,
+	sessionID string,
+) (*types.AgentScratchpad, error) {
+	var scratchpad types.AgentScratchpad
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND tenant_id = ?", sessionID, tenantID).
+		First(&scratchpad).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &scratchpad, nil
+}
+
+// Save creates or replaces the scratchpad of a session
+func (r *agentScratchpadRepository) Save(ctx context.Context, scratchpad *types.AgentScratchpad) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(scratchpad).Error
+}
+
+// Delete deletes the scratchpad of a session
+func (r *agentScratchpadRepository) Delete(ctx context.Context, tenantID uint64, sessionID string) error {
+	return r.db.WithContext(ctx).
+		Where("session_id = ? AND tenant_id = ?", sessionID, tenantID).
+		Delete(&types.AgentScratchpad{}).Error
+}
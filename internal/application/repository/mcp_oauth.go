@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mcpOAuthRepository implements the MCPOAuthRepository interface
+type mcpOAuthRepository struct {
+	db *gorm.DB
+}
+
+// NewMCPOAuthRepository creates a new MCP OAuth repository
+func NewMCPOAuthRepository(db *gorm.DB) interfaces.MCPOAuthRepository {
+	return &mcpOAuthRepository{db: db}
+}
+
+// GetToken retrieves the token of a service for a user, nil when there is none
+func (r *mcpOAuthRepository) GetToken(ctx context.Context, serviceID, userID string) (*types.MCPOAuthToken, error) {
+	var token types.MCPOAuthToken
+	err := r.db.WithContext(ctx).
+		Where("service_id = ? AND user_id = ?", serviceID, userID).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// SaveToken creates or replaces the token of a service for a user
+func (r *mcpOAuthRepository) SaveToken(ctx context.Context, token *types.MCPOAuthToken) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(token).Error
+}
+
+// DeleteToken deletes the token of a service for a user
+func (r *mcpOAuthRepository) DeleteToken(ctx context.Context, serviceID, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("service_id = ? AND user_id = ?", serviceID, userID).
+		Delete(&types.MCPOAuthToken{}).Error
+}
+
+// DeleteServiceTokens deletes the tokens of all users of a service
+func (r *mcpOAuthRepository) DeleteServiceTokens(ctx context.Context, serviceID string) error {
+	return r.db.WithContext(ctx).
+		Where("service_id = ?", serviceID).
+		Delete(&types.MCPOAuthToken{}).Error
+}
+
+// CreateState stores a pending authorization, dropping expired ones
+func (r *mcpOAuthRepository) CreateState(ctx context.Context, state *types.MCPOAuthState) error {
+	if err := r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&types.MCPOAuthState{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// ConsumeState retrieves and deletes a pending authorization, nil when unknown or expired
+func (r *mcpOAuthRepository) ConsumeState(ctx context.Context, state string) (*types.MCPOAuthState, error) {
+	var pending types.MCPOAuthState
+	result := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("state = ?", state).
+		Delete(&pending)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || pending.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return &pending, nil
+}
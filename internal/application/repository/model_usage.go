@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// modelUsageGroupColumns are the SQL expressions usage records are grouped by, for each dimension
+var modelUsageGroupColumns = map[types.ModelUsageGroupBy]string{
+	types.ModelUsageGroupByDay:    "TO_CHAR(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+	types.ModelUsageGroupByModel:  "model_id",
+	types.ModelUsageGroupByAgent:  "COALESCE(agent_id, '')",
+	types.ModelUsageGroupBySource: "source",
+}
+
+// modelUsageRepository implements the ModelUsageRepository interface
+type modelUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewModelUsageRepository creates a new model usage repository
+func NewModelUsageRepository(db *gorm.DB) interfaces.ModelUsageRepository {
+	return &modelUsageRepository{db: db}
+}
+
+// Create records a model call
+func (r *modelUsageRepository) Create(ctx context.Context, usage *types.ModelUsage) error {
+	return r.db.WithContext(ctx).Create(usage).Error
+}
+
+// SumTokens returns the tokens used by a tenant since a time
+func (r *modelUsageRepository) SumTokens(ctx context.Context, tenantID uint64, since time.Time) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).
+		Model(&types.ModelUsage{}).
+		Where("tenant_id = ? AND created_at >= ?", tenantID, since).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// Aggregate returns the usage statistics of a tenant, ordered by key
+func (r *modelUsageRepository) Aggregate(
+	ctx context.Context,
+	tenantID uint64,
+	query *types.ModelUsageQuery,
+) ([]*types.ModelUsageStat, error) {
+	groupColumn, ok := modelUsageGroupColumns[query.GroupBy]
+	if !ok {
+		groupColumn = modelUsageGroupColumns[types.ModelUsageGroupByDay]
+	}
+	modelName := "''"
+	if query.GroupBy == types.ModelUsageGroupByModel {
+		modelName = "MAX(model_name)"
+	}
+
+	db := r.db.WithContext(ctx).
+		Model(&types.ModelUsage{}).
+		Select(groupColumn+" AS \"key\", "+modelName+" AS model_name, "+
+			"COUNT(*) AS calls, "+
+			"COUNT(*) FILTER (WHERE NOT success) AS failed_calls, "+
+			"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, "+
+			"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, "+
+			"COALESCE(SUM(total_tokens), 0) AS total_tokens, "+
+			"COALESCE(AVG(latency_ms), 0) AS avg_latency_ms").
+		Where("tenant_id = ? AND created_at >= ? AND created_at < ?", tenantID, query.Start, query.End)
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+	}
+
+	var stats []*types.ModelUsageStat
+	err := db.Group(groupColumn).Order(`"key"`).Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
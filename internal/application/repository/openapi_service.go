@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// openAPIServiceRepository implements the OpenAPIServiceRepository interface
+type openAPIServiceRepository struct {
+	db *gorm.DB
+}
+
+// NewOpenAPIServiceRepository creates a new OpenAPI service repository
+func NewOpenAPIServiceRepository(db *gorm.DB) interfaces.OpenAPIServiceRepository {
+	return &openAPIServiceRepository{db: db}
+}
+
+// Create creates a new OpenAPI service
+func (r *openAPIServiceRepository) Create(ctx context.Context, service *types.OpenAPIService) error {
+	return r.db.WithContext(ctx).Create(service).Error
+}
+
+// GetByID retrieves an OpenAPI service by ID and tenant ID
+func (r *openAPIServiceRepository) GetByID(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.OpenAPIService, error) {
+	var service types.OpenAPIService
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&service).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &service, nil
+}
+
+// List retrieves all OpenAPI services for a tenant
+func (r *openAPIServiceRepository) List(ctx context.Context, tenantID uint64) ([]*types.OpenAPIService, error) {
+	var services []*types.OpenAPIService
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&services).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+// ListByIDs retrieves OpenAPI services by multiple IDs for a tenant
+func (r *openAPIServiceRepository) ListByIDs(
+	ctx context.Context,
+	tenantID uint64,
+	ids []string,
+) ([]*types.OpenAPIService, error) {
+	if len(ids) == 0 {
+		return []*types.OpenAPIService{}, nil
+	}
+
+	var services []*types.OpenAPIService
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND id IN ?", tenantID, ids).
+		Find(&services).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return services, nil
+}
+
+// Update updates an OpenAPI service
+func (r *openAPIServiceRepository) Update(ctx context.Context, service *types.OpenAPIService) error {
+	return r.db.WithContext(ctx).
+		Model(&types.OpenAPIService{}).
+		Where("id = ? AND tenant_id = ?", service.ID, service.TenantID).
+		Updates(map[string]interface{}{
+			"name":               service.Name,
+			"description":        service.Description,
+			"enabled":            service.Enabled,
+			"base_url":           service.BaseURL,
+			"spec":               service.Spec,
+			"operations":         service.Operations,
+			"auth_headers":       service.EncryptedAuthHeaders,
+			"timeout":            service.Timeout,
+			"max_response_bytes": service.MaxResponseBytes,
+			"updated_at":         service.UpdatedAt,
+		}).Error
+}
+
+// Delete deletes an OpenAPI service (soft delete)
+func (r *openAPIServiceRepository) Delete(ctx context.Context, tenantID uint64, id string) error {
+	return r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Delete(&types.OpenAPIService{}).Error
+}
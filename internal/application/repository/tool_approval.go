@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gorm.io/gorm"
+)
+
+// toolApprovalRepository implements the ToolApprovalRepository interface
+type toolApprovalRepository struct {
+	db *gorm.DB
+}
+
+// NewToolApprovalRepository creates a new tool approval repository
+func NewToolApprovalRepository(db *gorm.DB) interfaces.ToolApprovalRepository {
+	return &toolApprovalRepository{db: db}
+}
+
+// Create creates a new approval request
+func (r *toolApprovalRepository) Create(ctx context.Context, approval *types.ToolApproval) error {
+	return r.db.WithContext(ctx).Create(approval).Error
+}
+
+// GetByID retrieves an approval request by ID and tenant ID
+func (r *toolApprovalRepository) GetByID(ctx context.Context, tenantID uint64, id string) (*types.ToolApproval, error) {
+	var approval types.ToolApproval
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		First(&approval).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &approval, nil
+}
+
+// ListPendingBySession retrieves the pending approval requests of a session
+func (r *toolApprovalRepository) ListPendingBySession(
+	ctx context.Context,
+	tenantID uint64,
+	sessionID string,
+) ([]*types.ToolApproval, error) {
+	var approvals []*types.ToolApproval
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND session_id = ? AND status = ?", tenantID, sessionID, types.ToolApprovalPending).
+		Order("created_at ASC").
+		Find(&approvals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+// Decide moves a pending approval request to the given status
+func (r *toolApprovalRepository) Decide(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	status types.ToolApprovalStatus,
+	reason string,
+) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&types.ToolApproval{}).
+		Where("id = ? AND tenant_id = ? AND status = ?", id, tenantID, types.ToolApprovalPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"reason":     reason,
+			"decided_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,586 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// ErrInvalidAgentBundle is returned when a bundle cannot be imported into the current tenant
+var ErrInvalidAgentBundle = errors.New("invalid agent bundle")
+
+// agentBundlePrompts are the prompt templates of an agent config carried in the prompts section of a bundle
+var agentBundlePrompts = []struct {
+	key   string
+	field func(*types.CustomAgentConfig) *string
+}{
+	{"system_prompt", func(c *types.CustomAgentConfig) *string { return &c.SystemPrompt }},
+	{"context_template", func(c *types.CustomAgentConfig) *string { return &c.ContextTemplate }},
+	{"rewrite_prompt_system", func(c *types.CustomAgentConfig) *string { return &c.RewritePromptSystem }},
+	{"rewrite_prompt_user", func(c *types.CustomAgentConfig) *string { return &c.RewritePromptUser }},
+	{"fallback_prompt", func(c *types.CustomAgentConfig) *string { return &c.FallbackPrompt }},
+}
+
+// agentBundleService implements the AgentBundleService interface
+type agentBundleService struct {
+	customAgentService    interfaces.CustomAgentService
+	modelService          interfaces.ModelService
+	knowledgeBaseService  interfaces.KnowledgeBaseService
+	mcpServiceService     interfaces.MCPServiceService
+	openAPIServiceService interfaces.OpenAPIServiceService
+}
+
+// NewAgentBundleService creates a new agent bundle service
+func NewAgentBundleService(
+	customAgentService interfaces.CustomAgentService,
+	modelService interfaces.ModelService,
+	knowledgeBaseService interfaces.KnowledgeBaseService,
+	mcpServiceService interfaces.MCPServiceService,
+	openAPIServiceService interfaces.OpenAPIServiceService,
+) interfaces.AgentBundleService {
+	return &agentBundleService{
+		customAgentService:    customAgentService,
+		modelService:          modelService,
+		knowledgeBaseService:  knowledgeBaseService,
+		mcpServiceService:     mcpServiceService,
+		openAPIServiceService: openAPIServiceService,
+	}
+}
+
+// ExportAgent builds the bundle of an agent. MCP services are only exported when the agent
+// selects them explicitly; secrets of MCP and OpenAPI services are stripped.
+func (s *agentBundleService) ExportAgent(ctx context.Context, id string) (*types.AgentBundle, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	agent, err := s.customAgentService.GetAgentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	config := agent.Config
+	bundle := &types.AgentBundle{
+		Version:    types.AgentBundleVersion,
+		ExportedAt: time.Now(),
+		Prompts:    make(map[string]string),
+	}
+	for _, prompt := range agentBundlePrompts {
+		if value := *prompt.field(&config); value != "" {
+			bundle.Prompts[prompt.key] = value
+			*prompt.field(&config) = ""
+		}
+	}
+	bundle.Agent = types.AgentBundleAgent{
+		ID:          agent.ID,
+		Name:        agent.Name,
+		Description: agent.Description,
+		Avatar:      agent.Avatar,
+		Config:      config,
+	}
+
+	for _, modelID := range uniqueIDs(agentModelIDs(&config)) {
+		ref := types.AgentBundleRef{ID: *modelID}
+		if model, err := s.modelService.GetModelByID(ctx, *modelID); err == nil && model != nil {
+			ref.Name = model.Name
+			ref.Type = string(model.Type)
+		} else {
+			logger.Warnf(ctx, "Model %s referenced by agent %s not found, exported by ID only", *modelID, agent.ID)
+		}
+		bundle.Models = append(bundle.Models, ref)
+	}
+	for _, kbID := range config.KnowledgeBases {
+		ref := types.AgentBundleRef{ID: kbID}
+		if kb, err := s.knowledgeBaseService.GetKnowledgeBaseByID(ctx, kbID); err == nil && kb != nil {
+			ref.Name = kb.Name
+			ref.Type = kb.Type
+		} else {
+			logger.Warnf(ctx, "Knowledge base %s referenced by agent %s not found, exported by ID only", kbID, agent.ID)
+		}
+		bundle.KnowledgeBases = append(bundle.KnowledgeBases, ref)
+	}
+	if config.SubAgents != nil {
+		for _, agentID := range config.SubAgents.AllowedAgents {
+			ref := types.AgentBundleRef{ID: agentID}
+			if subAgent, err := s.customAgentService.GetAgentByID(ctx, agentID); err == nil {
+				ref.Name = subAgent.Name
+			}
+			bundle.SubAgents = append(bundle.SubAgents, ref)
+		}
+	}
+
+	if mcpServiceIDs := bundleMCPServiceIDs(&config); len(mcpServiceIDs) > 0 {
+		mcpServices, err := s.mcpServiceService.ListMCPServicesByIDs(ctx, tenantID, mcpServiceIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range mcpServices {
+			bundle.MCPServices = append(bundle.MCPServices, newBundleMCPService(svc))
+		}
+	}
+	for _, serviceID := range config.OpenAPIServices {
+		svc, err := s.openAPIServiceService.GetOpenAPIServiceByID(ctx, tenantID, serviceID)
+		if err != nil {
+			logger.Warnf(ctx, "OpenAPI service %s referenced by agent %s not exported: %v", serviceID, agent.ID, err)
+			continue
+		}
+		bundle.OpenAPIServices = append(bundle.OpenAPIServices, newBundleOpenAPIService(svc))
+	}
+
+	logger.Infof(ctx, "Agent exported, ID: %s, models: %d, knowledge bases: %d, MCP services: %d, OpenAPI services: %d",
+		agent.ID, len(bundle.Models), len(bundle.KnowledgeBases), len(bundle.MCPServices), len(bundle.OpenAPIServices))
+	return bundle, nil
+}
+
+// ImportAgent validates the references of a bundle against the current tenant, then creates
+// its tool services and the agent with new IDs. Services whose secrets were stripped are created
+// disabled. Nothing is created when a model or knowledge base cannot be resolved.
+func (s *agentBundleService) ImportAgent(
+	ctx context.Context,
+	bundle *types.AgentBundle,
+	options *types.AgentImportOptions,
+) (*types.AgentImportResult, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	if bundle.Version < 1 || bundle.Version > types.AgentBundleVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidAgentBundle, bundle.Version)
+	}
+	if strings.TrimSpace(bundle.Agent.Name) == "" {
+		return nil, fmt.Errorf("%w: agent name is required", ErrInvalidAgentBundle)
+	}
+	if options == nil {
+		options = &types.AgentImportOptions{}
+	}
+
+	config := bundle.Agent.Config
+	for _, prompt := range agentBundlePrompts {
+		if value, ok := bundle.Prompts[prompt.key]; ok {
+			*prompt.field(&config) = value
+		}
+	}
+	result := &types.AgentImportResult{IDMapping: make(map[string]string)}
+
+	// Resolve models and knowledge bases before creating anything
+	var problems []string
+	models, err := s.modelService.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, modelID := range agentModelIDs(&config) {
+		ref := findBundleRef(bundle.Models, *modelID)
+		resolved, err := resolveBundleRef(ref, options.ModelMapping, modelCandidates(models))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("model %s", err))
+			continue
+		}
+		result.IDMapping[*modelID] = resolved
+		*modelID = resolved
+	}
+	if len(config.KnowledgeBases) > 0 {
+		kbs, err := s.knowledgeBaseService.ListKnowledgeBases(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i, kbID := range config.KnowledgeBases {
+			ref := findBundleRef(bundle.KnowledgeBases, kbID)
+			resolved, err := resolveBundleRef(ref, options.KnowledgeBaseMapping, knowledgeBaseCandidates(kbs))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("knowledge base %s", err))
+				continue
+			}
+			result.IDMapping[kbID] = resolved
+			config.KnowledgeBases[i] = resolved
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAgentBundle, strings.Join(problems, "; "))
+	}
+
+	if config.SubAgents.IsActive() {
+		agents, err := s.customAgentService.ListAgents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		allowed := make([]string, 0, len(config.SubAgents.AllowedAgents))
+		for _, agentID := range config.SubAgents.AllowedAgents {
+			ref := findBundleRef(bundle.SubAgents, agentID)
+			resolved, err := resolveBundleRef(ref, nil, agentCandidates(agents))
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("sub-agent %s removed from the allowed agents", err))
+				continue
+			}
+			result.IDMapping[agentID] = resolved
+			allowed = append(allowed, resolved)
+		}
+		subAgents := *config.SubAgents
+		subAgents.AllowedAgents = allowed
+		config.SubAgents = &subAgents
+	}
+
+	if err := s.importServices(ctx, tenantID, bundle, result); err != nil {
+		s.rollbackImport(ctx, tenantID, result)
+		return nil, err
+	}
+	config.MCPServices = remapIDs(config.MCPServices, result, "MCP service")
+	config.MCPResources, config.MCPPrompt = remapMCPRefs(config.MCPResources, config.MCPPrompt, result)
+	config.MCPToolPolicies = remapMCPToolPolicies(config.MCPToolPolicies, result)
+	config.OpenAPIServices = remapIDs(config.OpenAPIServices, result, "OpenAPI service")
+
+	agent, err := s.customAgentService.CreateAgent(ctx, &types.CustomAgent{
+		Name:        bundle.Agent.Name,
+		Description: bundle.Agent.Description,
+		Avatar:      bundle.Agent.Avatar,
+		Config:      config,
+	})
+	if err != nil {
+		s.rollbackImport(ctx, tenantID, result)
+		return nil, err
+	}
+	if bundle.Agent.ID != "" {
+		result.IDMapping[bundle.Agent.ID] = agent.ID
+	}
+	result.Agent = agent
+
+	logger.Infof(ctx, "Agent imported, ID: %s, name: %s, MCP services: %d, OpenAPI services: %d, warnings: %d",
+		agent.ID, agent.Name, len(result.MCPServices), len(result.OpenAPIServices), len(result.Warnings))
+	return result, nil
+}
+
+// importServices creates the MCP and OpenAPI services of the bundle
+func (s *agentBundleService) importServices(
+	ctx context.Context,
+	tenantID uint64,
+	bundle *types.AgentBundle,
+	result *types.AgentImportResult,
+) error {
+	for _, def := range bundle.MCPServices {
+		svc := &types.MCPService{
+			TenantID:       tenantID,
+			Name:           def.Name,
+			Description:    def.Description,
+			TransportType:  def.TransportType,
+			URL:            def.URL,
+			AdvancedConfig: def.AdvancedConfig,
+			StdioConfig:    def.StdioConfig,
+			Headers:        emptyValues(def.HeaderNames),
+			EnvVars:        emptyValues(def.EnvVarNames),
+		}
+		// Stdio services run the command of the bundle on this server, so they are never
+		// enabled before an administrator has reviewed it
+		needsSecrets := def.RequiresAuth || len(def.HeaderNames) > 0 || len(def.EnvVarNames) > 0
+		runsCommand := def.TransportType == types.MCPTransportStdio
+		svc.Enabled = !needsSecrets && !runsCommand
+		if def.RequiresAuth {
+			svc.AuthConfig = &types.MCPAuthConfig{}
+		}
+		if err := s.mcpServiceService.CreateMCPService(ctx, svc); err != nil {
+			return fmt.Errorf("%w: MCP service %s: %v", ErrInvalidAgentBundle, def.Name, err)
+		}
+		result.IDMapping[def.ID] = svc.ID
+		result.MCPServices = append(result.MCPServices, svc)
+		if runsCommand {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"MCP service %s was imported disabled, review the command and arguments it runs then enable it",
+				svc.Name))
+		}
+		if needsSecrets {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"MCP service %s was imported disabled, configure its credentials, headers and environment variables then enable it",
+				svc.Name))
+		}
+	}
+
+	for _, def := range bundle.OpenAPIServices {
+		svc := &types.OpenAPIService{
+			TenantID:         tenantID,
+			Name:             def.Name,
+			Description:      def.Description,
+			Enabled:          len(def.AuthHeaderNames) == 0,
+			BaseURL:          def.BaseURL,
+			Spec:             def.Spec,
+			Operations:       def.Operations,
+			AuthHeaders:      emptyValues(def.AuthHeaderNames),
+			Timeout:          def.Timeout,
+			MaxResponseBytes: def.MaxResponseBytes,
+		}
+		if err := s.openAPIServiceService.CreateOpenAPIService(ctx, svc); err != nil {
+			return fmt.Errorf("%w: OpenAPI service %s: %v", ErrInvalidAgentBundle, def.Name, err)
+		}
+		result.IDMapping[def.ID] = svc.ID
+		result.OpenAPIServices = append(result.OpenAPIServices, svc)
+		if !svc.Enabled {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"OpenAPI service %s was imported disabled, configure its auth headers then enable it", svc.Name))
+		}
+	}
+	return nil
+}
+
+// rollbackImport deletes the services created by a failed import
+func (s *agentBundleService) rollbackImport(ctx context.Context, tenantID uint64, result *types.AgentImportResult) {
+	for _, svc := range result.MCPServices {
+		if err := s.mcpServiceService.DeleteMCPService(ctx, tenantID, svc.ID); err != nil {
+			logger.Warnf(ctx, "Failed to delete MCP service %s of a failed import: %v", svc.ID, err)
+		}
+	}
+	for _, svc := range result.OpenAPIServices {
+		if err := s.openAPIServiceService.DeleteOpenAPIService(ctx, tenantID, svc.ID); err != nil {
+			logger.Warnf(ctx, "Failed to delete OpenAPI service %s of a failed import: %v", svc.ID, err)
+		}
+	}
+}
+
+// newBundleMCPService describes an MCP service without its secrets
+func newBundleMCPService(svc *types.MCPService) *types.AgentBundleMCPService {
+	def := &types.AgentBundleMCPService{
+		ID:             svc.ID,
+		Name:           svc.Name,
+		Description:    svc.Description,
+		TransportType:  svc.TransportType,
+		URL:            svc.URL,
+		HeaderNames:    sortedKeys(svc.Headers),
+		AdvancedConfig: svc.AdvancedConfig,
+		StdioConfig:    svc.StdioConfig,
+		EnvVarNames:    sortedKeys(svc.EnvVars),
+	}
+	if auth := svc.AuthConfig; auth != nil {
+		def.RequiresAuth = auth.APIKey != "" || auth.Token != "" || len(auth.CustomHeaders) > 0
+	}
+	return def
+}
+
+// newBundleOpenAPIService describes an OpenAPI service without its auth header values
+func newBundleOpenAPIService(svc *types.OpenAPIService) *types.AgentBundleOpenAPIService {
+	return &types.AgentBundleOpenAPIService{
+		ID:               svc.ID,
+		Name:             svc.Name,
+		Description:      svc.Description,
+		BaseURL:          svc.BaseURL,
+		Spec:             svc.Spec,
+		Operations:       svc.Operations,
+		AuthHeaderNames:  sortedKeys(svc.AuthHeaders),
+		Timeout:          svc.Timeout,
+		MaxResponseBytes: svc.MaxResponseBytes,
+	}
+}
+
+// agentModelIDs returns the model references of an agent config that are set
+func agentModelIDs(config *types.CustomAgentConfig) []*string {
+	refs := []*string{&config.ModelID, &config.RerankModelID}
+	if config.KBRouting != nil {
+		refs = append(refs, &config.KBRouting.EmbeddingModelID, &config.KBRouting.ClassifierModelID)
+	}
+	if config.Guardrail != nil {
+		refs = append(refs, &config.Guardrail.TopicClassifierModelID)
+	}
+	return slices.DeleteFunc(refs, func(ref *string) bool { return *ref == "" })
+}
+
+// uniqueIDs drops the references to an ID already listed
+func uniqueIDs(refs []*string) []*string {
+	seen := make(map[string]bool)
+	return slices.DeleteFunc(refs, func(ref *string) bool {
+		if seen[*ref] {
+			return true
+		}
+		seen[*ref] = true
+		return false
+	})
+}
+
+// findBundleRef returns the description of a referenced ID, or a reference by ID only
+func findBundleRef(refs []types.AgentBundleRef, id string) types.AgentBundleRef {
+	for _, ref := range refs {
+		if ref.ID == id {
+			return ref
+		}
+	}
+	return types.AgentBundleRef{ID: id}
+}
+
+// resolveBundleRef finds the candidate matching a reference: the explicitly mapped ID,
+// then the same ID, then the same name (and type when known)
+func resolveBundleRef(
+	ref types.AgentBundleRef,
+	mapping map[string]string,
+	candidates []types.AgentBundleRef,
+) (string, error) {
+	if mapped, ok := mapping[ref.ID]; ok {
+		for _, candidate := range candidates {
+			if candidate.ID == mapped {
+				return mapped, nil
+			}
+		}
+		return "", fmt.Errorf("%s is mapped to %s, which does not exist", ref.ID, mapped)
+	}
+	for _, candidate := range candidates {
+		if candidate.ID == ref.ID {
+			return candidate.ID, nil
+		}
+	}
+	if ref.Name != "" {
+		for _, candidate := range candidates {
+			if candidate.Name == ref.Name && (ref.Type == "" || candidate.Type == ref.Type) {
+				return candidate.ID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s (%s) not found", ref.ID, ref.Name)
+}
+
+// modelCandidates describes models as bundle references
+func modelCandidates(models []*types.Model) []types.AgentBundleRef {
+	candidates := make([]types.AgentBundleRef, 0, len(models))
+	for _, model := range models {
+		if model != nil {
+			candidates = append(candidates, types.AgentBundleRef{ID: model.ID, Name: model.Name, Type: string(model.Type)})
+		}
+	}
+	return candidates
+}
+
+// knowledgeBaseCandidates describes knowledge bases as bundle references
+func knowledgeBaseCandidates(kbs []*types.KnowledgeBase) []types.AgentBundleRef {
+	candidates := make([]types.AgentBundleRef, 0, len(kbs))
+	for _, kb := range kbs {
+		if kb != nil {
+			candidates = append(candidates, types.AgentBundleRef{ID: kb.ID, Name: kb.Name, Type: kb.Type})
+		}
+	}
+	return candidates
+}
+
+// agentCandidates describes agents as bundle references
+func agentCandidates(agents []*types.CustomAgent) []types.AgentBundleRef {
+	candidates := make([]types.AgentBundleRef, 0, len(agents))
+	for _, agent := range agents {
+		if agent != nil {
+			candidates = append(candidates, types.AgentBundleRef{ID: agent.ID, Name: agent.Name})
+		}
+	}
+	return candidates
+}
+
+// remapIDs replaces the IDs of imported services, dropping the services missing from the bundle
+func remapIDs(ids []string, result *types.AgentImportResult, kind string) []string {
+	remapped := make([]string, 0, len(ids))
+	for _, id := range ids {
+		newID, ok := result.IDMapping[id]
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s %s is not in the bundle and was removed", kind, id))
+			continue
+		}
+		remapped = append(remapped, newID)
+	}
+	return remapped
+}
+
+// bundleMCPServiceIDs returns the MCP services an agent references, by selection, resource or prompt
+func bundleMCPServiceIDs(config *types.CustomAgentConfig) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if config.MCPSelectionMode == "selected" {
+		for _, id := range config.MCPServices {
+			add(id)
+		}
+	}
+	for _, ref := range config.MCPResources {
+		add(ref.ServiceID)
+	}
+	if config.MCPPrompt != nil {
+		add(config.MCPPrompt.ServiceID)
+	}
+	return ids
+}
+
+// remapMCPRefs replaces the MCP service IDs of resource and prompt references by their imported IDs,
+// removing references to services missing from the bundle
+func remapMCPRefs(
+	resources []types.MCPResourceRef,
+	prompt *types.MCPPromptRef,
+	result *types.AgentImportResult,
+) ([]types.MCPResourceRef, *types.MCPPromptRef) {
+	var remapped []types.MCPResourceRef
+	for _, ref := range resources {
+		newID, ok := result.IDMapping[ref.ServiceID]
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"MCP service %s of resource %s is not in the bundle and the resource was removed", ref.ServiceID, ref.URI))
+			continue
+		}
+		ref.ServiceID = newID
+		remapped = append(remapped, ref)
+	}
+	if prompt != nil {
+		newID, ok := result.IDMapping[prompt.ServiceID]
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"MCP service %s of prompt %s is not in the bundle and the prompt was removed", prompt.ServiceID, prompt.Name))
+			return remapped, nil
+		}
+		remappedPrompt := *prompt
+		remappedPrompt.ServiceID = newID
+		prompt = &remappedPrompt
+	}
+	return remapped, prompt
+}
+
+// remapMCPToolPolicies replaces the MCP service IDs keying tool policies by their imported IDs,
+// removing the policies of services missing from the bundle
+func remapMCPToolPolicies(
+	policies map[string]*types.MCPToolPolicy,
+	result *types.AgentImportResult,
+) map[string]*types.MCPToolPolicy {
+	if len(policies) == 0 {
+		return policies
+	}
+	remapped := make(map[string]*types.MCPToolPolicy, len(policies))
+	for _, serviceID := range sortedKeys(policies) {
+		newID, ok := result.IDMapping[serviceID]
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"MCP service %s is not in the bundle and its tool policy was removed", serviceID))
+			continue
+		}
+		remapped[newID] = policies[serviceID]
+	}
+	return remapped
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[M ~map[string]V, V any](m M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// emptyValues returns a map with the given keys and empty values, to be filled in after import
+func emptyValues(keys []string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(keys))
+	for _, key := range keys {
+		m[key] = ""
+	}
+	return m
+}
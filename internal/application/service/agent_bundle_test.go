@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveBundleRef(t *testing.T) {
+	candidates := []types.AgentBundleRef{
+		{ID: "m-1", Name: "qwen", Type: "KnowledgeQA"},
+		{ID: "m-2", Name: "bge", Type: "Embedding"},
+		{ID: "m-3", Name: "bge", Type: "Rerank"},
+	}
+	tests := []struct {
+		name    string
+		ref     types.AgentBundleRef
+		mapping map[string]string
+		want    string
+		wantErr bool
+	}{
+		{name: "same ID", ref: types.AgentBundleRef{ID: "m-1", Name: "other"}, want: "m-1"},
+		{name: "by name and type", ref: types.AgentBundleRef{ID: "x", Name: "bge", Type: "Rerank"}, want: "m-3"},
+		{name: "explicit mapping", ref: types.AgentBundleRef{ID: "m-1"}, mapping: map[string]string{"m-1": "m-2"}, want: "m-2"},
+		{name: "mapping to unknown ID", ref: types.AgentBundleRef{ID: "m-1"}, mapping: map[string]string{"m-1": "x"}, wantErr: true},
+		{name: "not found", ref: types.AgentBundleRef{ID: "x", Name: "llama"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveBundleRef(tt.ref, tt.mapping, candidates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveBundleRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveBundleRef() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgentBundleStripsSecrets(t *testing.T) {
+	url := "https://mcp.example.com/sse"
+	bundle := &types.AgentBundle{
+		Version: types.AgentBundleVersion,
+		Agent: types.AgentBundleAgent{
+			Name:   "Support",
+			Config: types.CustomAgentConfig{AgentMode: types.AgentModeSmartReasoning, MCPServices: []string{"mcp-1"}},
+		},
+		MCPServices: []*types.AgentBundleMCPService{newBundleMCPService(&types.MCPService{
+			ID:            "mcp-1",
+			Name:          "tickets",
+			TransportType: types.MCPTransportSSE,
+			URL:           &url,
+			Headers:       types.MCPHeaders{"Authorization": "Bearer header-secret"},
+			AuthConfig:    &types.MCPAuthConfig{APIKey: "api-key-secret"},
+			EnvVars:       types.MCPEnvVars{"TOKEN": "env-secret"},
+		})},
+		OpenAPIServices: []*types.AgentBundleOpenAPIService{newBundleOpenAPIService(&types.OpenAPIService{
+			ID:          "api-1",
+			Name:        "orders",
+			Spec:        "openapi: 3.0.0",
+			AuthHeaders: map[string]string{"X-Api-Key": "openapi-secret"},
+		})},
+	}
+
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("yaml.Marshal() error = %v", err)
+	}
+	for _, secret := range []string{"header-secret", "api-key-secret", "env-secret", "openapi-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("bundle contains secret %q:\n%s", secret, data)
+		}
+	}
+
+	var decoded types.AgentBundle
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("yaml.Unmarshal() error = %v", err)
+	}
+	mcp := decoded.MCPServices[0]
+	if !mcp.RequiresAuth || len(mcp.HeaderNames) != 1 || mcp.HeaderNames[0] != "Authorization" ||
+		len(mcp.EnvVarNames) != 1 || mcp.EnvVarNames[0] != "TOKEN" {
+		t.Errorf("MCP service definition not preserved: %+v", mcp)
+	}
+	if names := decoded.OpenAPIServices[0].AuthHeaderNames; len(names) != 1 || names[0] != "X-Api-Key" {
+		t.Errorf("OpenAPI auth header names = %v", names)
+	}
+	if decoded.Agent.Config.AgentMode != types.AgentModeSmartReasoning {
+		t.Errorf("agent config not preserved: %+v", decoded.Agent.Config)
+	}
+}
+
+func TestRemapMCPRefs(t *testing.T) {
+	result := &types.AgentImportResult{IDMapping: map[string]string{"mcp-1": "new-1"}}
+	resources, prompt := remapMCPRefs([]types.MCPResourceRef{
+		{ServiceID: "mcp-1", URI: "file:///guide.md"},
+		{ServiceID: "mcp-2", URI: "file:///missing.md"},
+	}, &types.MCPPromptRef{ServiceID: "mcp-1", Name: "reviewer"}, result)
+
+	if len(resources) != 1 || resources[0].ServiceID != "new-1" || resources[0].URI != "file:///guide.md" {
+		t.Errorf("remapped resources = %+v, want only file:///guide.md of new-1", resources)
+	}
+	if prompt == nil || prompt.ServiceID != "new-1" || prompt.Name != "reviewer" {
+		t.Errorf("remapped prompt = %+v, want reviewer of new-1", prompt)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one for the missing service", result.Warnings)
+	}
+
+	if _, prompt := remapMCPRefs(nil, &types.MCPPromptRef{ServiceID: "mcp-2", Name: "x"}, result); prompt != nil {
+		t.Errorf("prompt of a missing service = %+v, want removed", prompt)
+	}
+}
+
+// memoryMCPServiceService keeps the created MCP services in memory
+type memoryMCPServiceService struct {
+	interfaces.MCPServiceService
+	created []*types.MCPService
+}
+
+func (s *memoryMCPServiceService) CreateMCPService(ctx context.Context, service *types.MCPService) error {
+	service.ID = fmt.Sprintf("new-%d", len(s.created)+1)
+	s.created = append(s.created, service)
+	return nil
+}
+
+func TestImportServicesDisablesServicesNeedingReview(t *testing.T) {
+	url := "https://mcp.example.com/sse"
+	mcpServices := &memoryMCPServiceService{}
+	s := &agentBundleService{mcpServiceService: mcpServices}
+	bundle := &types.AgentBundle{MCPServices: []*types.AgentBundleMCPService{
+		{ID: "mcp-1", Name: "search", TransportType: types.MCPTransportSSE, URL: &url},
+		{ID: "mcp-2", Name: "tickets", TransportType: types.MCPTransportSSE, URL: &url, HeaderNames: []string{"Authorization"}},
+		{
+			ID:            "mcp-3",
+			Name:          "shell",
+			TransportType: types.MCPTransportStdio,
+			StdioConfig:   &types.MCPStdioConfig{Command: "npx", Args: []string{"some-server"}},
+		},
+	}}
+	result := &types.AgentImportResult{IDMapping: make(map[string]string)}
+
+	if err := s.importServices(context.Background(), 1, bundle, result); err != nil {
+		t.Fatalf("importServices() error = %v", err)
+	}
+	enabled := make(map[string]bool)
+	for _, svc := range mcpServices.created {
+		enabled[svc.Name] = svc.Enabled
+	}
+	want := map[string]bool{"search": true, "tickets": false, "shell": false}
+	for name, wantEnabled := range want {
+		if enabled[name] != wantEnabled {
+			t.Errorf("MCP service %s enabled = %v, want %v", name, enabled[name], wantEnabled)
+		}
+	}
+	if len(result.Warnings) != 2 ||
+		!strings.Contains(result.Warnings[0], "tickets") || !strings.Contains(result.Warnings[1], "shell") ||
+		!strings.Contains(result.Warnings[1], "command") {
+		t.Errorf("warnings = %v, want one for tickets and one for the command of shell", result.Warnings)
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// agentRecoveryBatchSize bounds how many interrupted executions a sweep enqueues at once
+const agentRecoveryBatchSize = 100
+
+// agentCheckpointService implements the AgentCheckpointService interface
+type agentCheckpointService struct {
+	repo        interfaces.AgentCheckpointRepository
+	asynqClient *asynq.Client
+	instanceID  string // Identifies the executions owned by this instance
+}
+
+// NewAgentCheckpointService creates a new agent checkpoint service
+func NewAgentCheckpointService(
+	repo interfaces.AgentCheckpointRepository,
+	asynqClient *asynq.Client,
+) interfaces.AgentCheckpointService {
+	hostname, _ := os.Hostname()
+	return &agentCheckpointService{
+		repo:        repo,
+		asynqClient: asynqClient,
+		instanceID:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+	}
+}
+
+// Begin records a new execution owned by this instance
+func (s *agentCheckpointService) Begin(ctx context.Context, checkpoint *types.AgentCheckpoint) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	checkpoint.TenantID = tenantID
+	checkpoint.Status = types.AgentRunRunning
+	checkpoint.Owner = s.instanceID
+	checkpoint.Attempts = 1
+	checkpoint.HeartbeatAt = time.Now()
+	return s.repo.Save(ctx, checkpoint)
+}
+
+// Save stores the progress of an execution after a round
+func (s *agentCheckpointService) Save(
+	ctx context.Context,
+	messageID string,
+	state *types.AgentState,
+	messages []chat.Message,
+	tokensUsed int,
+) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	messagesJSON, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent messages: %w", err)
+	}
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent state: %w", err)
+	}
+	return s.repo.UpdateProgress(ctx, tenantID, messageID,
+		state.CurrentRound, types.JSON(messagesJSON), types.JSON(stateJSON), tokensUsed)
+}
+
+// Finish moves an execution owned by this instance to a final status
+func (s *agentCheckpointService) Finish(ctx context.Context, messageID string, status types.AgentRunStatus) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	return s.repo.UpdateStatus(ctx, tenantID, messageID, s.instanceID, status)
+}
+
+// KeepAlive refreshes the heartbeat of an execution until the returned function is called,
+// so that long rounds (slow tools, pending approvals) are not taken for interrupted ones
+func (s *agentCheckpointService) KeepAlive(ctx context.Context, messageID string) (stop func(), err error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(types.AgentCheckpointHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.repo.Heartbeat(ctx, tenantID, messageID, s.instanceID); err != nil {
+					logger.Warnf(ctx, "Failed to refresh agent checkpoint heartbeat: %v", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }, nil
+}
+
+// Get retrieves the checkpoint of an execution, nil when the message has none
+func (s *agentCheckpointService) Get(ctx context.Context, messageID string) (*types.AgentCheckpoint, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	return s.repo.GetByMessageID(ctx, tenantID, messageID)
+}
+
+// Claim takes over an interrupted execution for this instance
+func (s *agentCheckpointService) Claim(ctx context.Context, messageID string) (bool, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return false, ErrInvalidTenantID
+	}
+	claimed, err := s.repo.Claim(ctx, tenantID, messageID, s.instanceID,
+		time.Now().Add(-types.AgentCheckpointStaleAfter))
+	if err != nil {
+		return false, err
+	}
+	if claimed {
+		logger.Infof(ctx, "Agent execution claimed for resume: message=%s, owner=%s", messageID, s.instanceID)
+	}
+	return claimed, nil
+}
+
+// RecoverInterrupted enqueues a resume task for each interrupted execution. Executions that
+// already used all their attempts are marked failed instead. Several instances may enqueue the
+// same execution; only the first to claim it resumes it.
+func (s *agentCheckpointService) RecoverInterrupted(ctx context.Context) (int, error) {
+	stale, err := s.repo.ListStale(ctx, time.Now().Add(-types.AgentCheckpointStaleAfter), agentRecoveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, checkpoint := range stale {
+		if checkpoint.Attempts >= types.AgentCheckpointMaxAttempts {
+			logger.Warnf(ctx, "Agent execution interrupted %d times, giving up: message=%s",
+				checkpoint.Attempts, checkpoint.MessageID)
+			if err := s.repo.UpdateStatus(ctx, checkpoint.TenantID, checkpoint.MessageID,
+				checkpoint.Owner, types.AgentRunFailed); err != nil {
+				logger.Warnf(ctx, "Failed to mark agent execution failed: %v", err)
+			}
+			continue
+		}
+
+		payloadBytes, err := json.Marshal(types.AgentResumePayload{
+			TenantID:  checkpoint.TenantID,
+			MessageID: checkpoint.MessageID,
+		})
+		if err != nil {
+			logger.Warnf(ctx, "Failed to marshal agent resume payload: %v", err)
+			continue
+		}
+		task := asynq.NewTask(types.TypeAgentResume, payloadBytes, asynq.Queue("critical"), asynq.MaxRetry(0))
+		info, err := s.asynqClient.Enqueue(task)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to enqueue agent resume task: %v", err)
+			continue
+		}
+		logger.Infof(ctx, "Agent resume task enqueued: %s, message ID: %s", info.ID, checkpoint.MessageID)
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+// RunRecoverySweep runs RecoverInterrupted at startup and then periodically until ctx is done.
+// Executions interrupted by a restart become stale only after AgentCheckpointStaleAfter,
+// so a single sweep at startup would miss them.
+func (s *agentCheckpointService) RunRecoverySweep(ctx context.Context) {
+	ticker := time.NewTicker(types.AgentCheckpointStaleAfter / 2)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RecoverInterrupted(ctx); err != nil {
+			logger.Warnf(ctx, "Agent recovery sweep failed: %v", err)
+		} else if n > 0 {
+			logger.Infof(ctx, "Agent recovery sweep enqueued %d interrupted executions", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
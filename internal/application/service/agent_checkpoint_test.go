@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/hibiken/asynq"
+)
+
+// staleCheckpointRepo lists fixed stale executions and records status updates
+type staleCheckpointRepo struct {
+	interfaces.AgentCheckpointRepository
+	stale   []*types.AgentCheckpoint
+	updates map[string]types.AgentRunStatus
+}
+
+func (r *staleCheckpointRepo) ListStale(ctx context.Context,
+	staleBefore time.Time, limit int,
+) ([]*types.AgentCheckpoint, error) {
+	return r.stale, nil
+}
+
+func (r *staleCheckpointRepo) UpdateStatus(ctx context.Context, tenantID uint64,
+	messageID, owner string, status types.AgentRunStatus,
+) error {
+	r.updates[messageID+"/"+owner] = status
+	return nil
+}
+
+func TestRecoverInterruptedAttemptsCap(t *testing.T) {
+	repo := &staleCheckpointRepo{
+		stale: []*types.AgentCheckpoint{
+			{MessageID: "exhausted", TenantID: 1, Owner: "lost", Attempts: types.AgentCheckpointMaxAttempts},
+			{MessageID: "retry", TenantID: 1, Owner: "lost", Attempts: types.AgentCheckpointMaxAttempts - 1},
+		},
+		updates: make(map[string]types.AgentRunStatus),
+	}
+	// Nothing listens there, so resume tasks cannot be enqueued
+	client := asynq.NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	s := &agentCheckpointService{repo: repo, asynqClient: client, instanceID: "self"}
+
+	enqueued, err := s.RecoverInterrupted(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if enqueued != 0 {
+		t.Errorf("Enqueued %d executions, want none", enqueued)
+	}
+	// Only the execution out of attempts is given up, under the owner that lost it
+	want := map[string]types.AgentRunStatus{"exhausted/lost": types.AgentRunFailed}
+	if len(repo.updates) != len(want) || repo.updates["exhausted/lost"] != types.AgentRunFailed {
+		t.Errorf("Status updates = %v, want %v", repo.updates, want)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/service/metric"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxEvaluatedAgents is the number of agents an evaluation task compares side by side
+const maxEvaluatedAgents = 2
+
+// AgentEvaluation starts a new evaluation task running the dataset through custom agents
+// datasetID: ID of the dataset to evaluate against
+// knowledgeBaseID: ID of the knowledge base whose models are used (empty for default models)
+// agentIDs: IDs of the smart-reasoning agents to evaluate, answering the same questions
+// over the same knowledge so that their results can be compared
+func (e *EvaluationService) AgentEvaluation(ctx context.Context,
+	datasetID string, knowledgeBaseID string, agentIDs []string,
+) (*types.EvaluationDetail, error) {
+	logger.Info(ctx, "Start agent evaluation")
+	logger.Infof(ctx, "Dataset ID: %s, Knowledge Base ID: %s, Agent IDs: %v", datasetID, knowledgeBaseID, agentIDs)
+
+	if len(agentIDs) == 0 || len(agentIDs) > maxEvaluatedAgents {
+		return nil, fmt.Errorf("agent evaluation requires 1 to %d agents, got %d", maxEvaluatedAgents, len(agentIDs))
+	}
+	agents := make([]*types.CustomAgent, 0, len(agentIDs))
+	for _, agentID := range agentIDs {
+		agent, err := e.customAgentService.GetAgentByID(ctx, agentID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get agent %s: %v", agentID, err)
+			return nil, err
+		}
+		if !agent.IsAgentMode() {
+			return nil, fmt.Errorf("agent %s does not run in agent mode", agent.Name)
+		}
+		// Defaults are applied once, executions of the agent then run concurrently
+		agent.EnsureDefaults()
+		agents = append(agents, agent)
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	knowledgeBaseID, err := e.prepareKnowledgeBase(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	if datasetID == "" {
+		datasetID = "default"
+		logger.Info(ctx, "Using default dataset")
+	}
+
+	taskID := uuid.New().String()
+	detail := &types.EvaluationDetail{
+		Task: &types.EvaluationTask{
+			ID:        taskID,
+			TenantID:  tenantID,
+			DatasetID: datasetID,
+			Status:    types.EvaluationStatuePending,
+			StartTime: time.Now(),
+		},
+		Agents: make([]*types.AgentEvaluationResult, 0, len(agents)),
+	}
+	for _, agent := range agents {
+		detail.Agents = append(detail.Agents, &types.AgentEvaluationResult{
+			AgentID:   agent.ID,
+			AgentName: agent.Name,
+		})
+	}
+	e.evaluationMemoryStorage.register(detail)
+
+	go func() {
+		newCtx := logger.CloneContext(ctx)
+		logger.Infof(newCtx, "Background agent evaluation started for task ID: %s", taskID)
+
+		detail.Task.Status = types.EvaluationStatueRunning
+		if err := e.evalAgents(newCtx, detail, agents, knowledgeBaseID); err != nil {
+			detail.Task.Status = types.EvaluationStatueFailed
+			detail.Task.ErrMsg = err.Error()
+			logger.Errorf(newCtx, "Agent evaluation task failed: %v, task ID: %s", err, taskID)
+			return
+		}
+
+		logger.Infof(newCtx, "Agent evaluation task completed successfully, task ID: %s", taskID)
+		detail.Task.Status = types.EvaluationStatueSuccess
+	}()
+
+	logger.Infof(ctx, "Agent evaluation task created successfully, task ID: %s", taskID)
+	return detail, nil
+}
+
+// evalAgents runs every question of the dataset through each agent and records the metrics.
+// A failed execution is recorded on its trajectory and does not stop the evaluation.
+func (e *EvaluationService) evalAgents(ctx context.Context,
+	detail *types.EvaluationDetail, agents []*types.CustomAgent, knowledgeBaseID string,
+) error {
+	// Agents search the passages right away, they must be indexed first
+	dataset, cleanup, err := e.loadDataset(ctx, detail, knowledgeBaseID, true)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
+		params.Task.Total = len(dataset) * len(agents)
+		for _, result := range params.Agents {
+			result.Trajectories = make([]*types.AgentTrajectory, len(dataset))
+		}
+	})
+
+	var finished int
+	var mu sync.Mutex
+	var g errgroup.Group
+	metricLists := make([]*MetricList, len(agents))
+	for i := range metricLists {
+		metricLists[i] = &MetricList{}
+	}
+	trajectoryMetric := metric.NewTrajectoryMetric()
+	g.SetLimit(max(runtime.GOMAXPROCS(0)-1, 1))
+
+	for a, agent := range agents {
+		for i, qaPair := range dataset {
+			g.Go(func() error {
+				logger.Infof(ctx, "Running agent %s on QA pair %d, question: %s", agent.Name, i, qaPair.Question)
+				trajectory := &types.AgentTrajectory{QID: qaPair.QID, Question: qaPair.Question}
+
+				state, err := e.sessionService.RunAgent(ctx, agent, qaPair.Question,
+					[]string{knowledgeBaseID}, event.NewEventBus())
+				var metricInput *types.MetricInput
+				if err != nil {
+					logger.Errorf(ctx, "Agent %s failed on question %d: %v", agent.Name, i, err)
+					trajectory.Error = err.Error()
+				} else {
+					trajectory.Answer = state.FinalAnswer
+					trajectory.Steps = state.RoundSteps
+					trajectory.Metric = trajectoryMetric.Compute(state, qaPair.PIDs)
+					metricInput = &types.MetricInput{
+						RetrievalGT:    [][]int{qaPair.PIDs},
+						RetrievalIDs:   metric.RetrievedChunkIndexes(state.RoundSteps),
+						GeneratedTexts: state.FinalAnswer,
+						GeneratedGT:    qaPair.Answer,
+					}
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				if metricInput != nil {
+					metricLists[a].Append(metricInput)
+				}
+				finished++
+				e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
+					result := params.Agents[a]
+					result.Trajectories[i] = trajectory
+					result.Finished++
+					if trajectory.Error != "" {
+						result.Failed++
+					}
+					result.Metric = metricLists[a].Avg()
+					result.Trajectory = averageTrajectoryMetrics(result.Trajectories)
+					params.Task.Finished = finished
+					logger.Infof(ctx, "Updated task progress: %d/%d completed", finished, params.Task.Total)
+				})
+				return nil
+			})
+		}
+	}
+
+	logger.Info(ctx, "Waiting for all agent executions to complete")
+	if err := g.Wait(); err != nil {
+		logger.Errorf(ctx, "Agent evaluation error: %v", err)
+		return err
+	}
+
+	logger.Infof(ctx, "Agent evaluation completed successfully, task ID: %s", detail.Task.ID)
+	return nil
+}
+
+// averageTrajectoryMetrics averages the trajectory metrics of the successful executions
+func averageTrajectoryMetrics(trajectories []*types.AgentTrajectory) *types.TrajectoryMetrics {
+	avg := &types.TrajectoryMetrics{}
+	count := 0
+	for _, trajectory := range trajectories {
+		if trajectory == nil || trajectory.Metric == nil {
+			continue
+		}
+		m := trajectory.Metric
+		avg.Iterations += m.Iterations
+		avg.ToolCalls += m.ToolCalls
+		avg.RedundantToolCalls += m.RedundantToolCalls
+		avg.FailedToolCalls += m.FailedToolCalls
+		avg.ExpectedRetrieved += m.ExpectedRetrieved
+		avg.Completed += m.Completed
+		count++
+	}
+	if count == 0 {
+		return avg
+	}
+	n := float64(count)
+	avg.Iterations /= n
+	avg.ToolCalls /= n
+	avg.RedundantToolCalls /= n
+	avg.FailedToolCalls /= n
+	avg.ExpectedRetrieved /= n
+	avg.Completed /= n
+	return avg
+}
@@ -0,0 +1,700 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+)
+
+// Agent schedule related errors
+var (
+	ErrAgentScheduleNotFound = errors.New("agent schedule not found")
+	ErrInvalidAgentSchedule  = errors.New("invalid agent schedule")
+)
+
+const (
+	// agentScheduleBatchSize bounds how many due schedules a sweep fires at once
+	agentScheduleBatchSize = 100
+	// agentScheduleRunHistory is the number of runs returned in the history of a schedule
+	agentScheduleRunHistory = 50
+	// agentScheduleSignatureHeader carries the HMAC-SHA256 of the webhook body when a secret is set
+	agentScheduleSignatureHeader = "X-WeKnora-Signature"
+	// agentScheduleWebhookEvent is the event name of the webhook body
+	agentScheduleWebhookEvent = "agent_schedule.run_completed"
+)
+
+// cronParser parses standard 5-field cron expressions and descriptors such as @daily or @every 1h
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// agentScheduleService implements the AgentScheduleService interface
+type agentScheduleService struct {
+	repo               interfaces.AgentScheduleRepository
+	customAgentService interfaces.CustomAgentService
+	sessionService     interfaces.SessionService
+	messageService     interfaces.MessageService
+	tenantService      interfaces.TenantService
+	asynqClient        *asynq.Client
+	client             *http.Client
+}
+
+// NewAgentScheduleService creates a new agent schedule service
+func NewAgentScheduleService(
+	repo interfaces.AgentScheduleRepository,
+	customAgentService interfaces.CustomAgentService,
+	sessionService interfaces.SessionService,
+	messageService interfaces.MessageService,
+	tenantService interfaces.TenantService,
+	asynqClient *asynq.Client,
+) interfaces.AgentScheduleService {
+	return &agentScheduleService{
+		repo:               repo,
+		customAgentService: customAgentService,
+		sessionService:     sessionService,
+		messageService:     messageService,
+		tenantService:      tenantService,
+		asynqClient:        asynqClient,
+		client:             &http.Client{Timeout: types.AgentScheduleWebhookTimeout},
+	}
+}
+
+// CreateSchedule validates and creates a new agent schedule
+func (s *agentScheduleService) CreateSchedule(ctx context.Context, schedule *types.AgentSchedule) error {
+	if err := s.validate(ctx, schedule); err != nil {
+		return err
+	}
+	if err := encryptWebhookSecret(schedule); err != nil {
+		return err
+	}
+	next, err := nextScheduleRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = next
+
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = time.Now()
+	if err := s.repo.Create(ctx, schedule); err != nil {
+		logger.Errorf(ctx, "Failed to create agent schedule: %v", err)
+		return fmt.Errorf("failed to create agent schedule: %w", err)
+	}
+
+	schedule.MaskSensitiveData()
+	logger.Infof(ctx, "Agent schedule created: %s (ID: %s), agent: %s, cron: %s",
+		secutils.SanitizeForLog(schedule.Name), schedule.ID, schedule.AgentID, schedule.CronExpression)
+	return nil
+}
+
+// GetSchedule retrieves an agent schedule by ID, with masked webhook secret
+func (s *agentScheduleService) GetSchedule(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.AgentSchedule, error) {
+	schedule, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	schedule.MaskSensitiveData()
+	return schedule, nil
+}
+
+// ListSchedules lists all agent schedules for a tenant, with masked webhook secrets
+func (s *agentScheduleService) ListSchedules(ctx context.Context, tenantID uint64) ([]*types.AgentSchedule, error) {
+	schedules, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list agent schedules: %v", err)
+		return nil, fmt.Errorf("failed to list agent schedules: %w", err)
+	}
+	for _, schedule := range schedules {
+		if err := decryptWebhookSecret(schedule); err != nil {
+			logger.Warnf(ctx, "Failed to decrypt webhook secret of agent schedule %s: %v", schedule.ID, err)
+		}
+		schedule.MaskSensitiveData()
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule updates the provided fields of an agent schedule and recomputes its next run time.
+// Blank names, agents, prompts and cron expressions are ignored, a masked webhook secret keeps the stored one.
+func (s *agentScheduleService) UpdateSchedule(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	update *types.AgentScheduleUpdate,
+) (*types.AgentSchedule, error) {
+	existing, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil && strings.TrimSpace(*update.Name) != "" {
+		existing.Name = *update.Name
+	}
+	if update.Description != nil {
+		existing.Description = *update.Description
+	}
+	if update.Enabled != nil {
+		existing.Enabled = *update.Enabled
+	}
+	if update.AgentID != nil && *update.AgentID != "" {
+		existing.AgentID = *update.AgentID
+	}
+	if update.Prompt != nil && strings.TrimSpace(*update.Prompt) != "" {
+		existing.Prompt = *update.Prompt
+	}
+	if update.KnowledgeBaseIDs != nil {
+		existing.KnowledgeBaseIDs = update.KnowledgeBaseIDs
+	}
+	if update.CronExpression != nil && strings.TrimSpace(*update.CronExpression) != "" {
+		existing.CronExpression = *update.CronExpression
+	}
+	if update.Timezone != nil {
+		existing.Timezone = *update.Timezone
+	}
+	if update.WebhookURL != nil {
+		existing.WebhookURL = *update.WebhookURL
+	}
+	if update.WebhookSecret != nil && !strings.Contains(*update.WebhookSecret, maskedMarker) {
+		existing.WebhookSecret = *update.WebhookSecret
+	}
+	if update.MaxRetries != nil {
+		existing.MaxRetries = update.MaxRetries
+	}
+
+	if err := s.validate(ctx, existing); err != nil {
+		return nil, err
+	}
+	if err := encryptWebhookSecret(existing); err != nil {
+		return nil, err
+	}
+	next, err := nextScheduleRun(existing, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	existing.NextRunAt = next
+	existing.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, existing); err != nil {
+		logger.Errorf(ctx, "Failed to update agent schedule: %v", err)
+		return nil, fmt.Errorf("failed to update agent schedule: %w", err)
+	}
+
+	existing.MaskSensitiveData()
+	logger.Infof(ctx, "Agent schedule updated: %s (ID: %s), enabled: %v, next run: %v",
+		secutils.SanitizeForLog(existing.Name), existing.ID, existing.Enabled, existing.NextRunAt)
+	return existing, nil
+}
+
+// DeleteSchedule deletes an agent schedule, its run history is kept
+func (s *agentScheduleService) DeleteSchedule(ctx context.Context, tenantID uint64, id string) error {
+	existing, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, tenantID, id); err != nil {
+		logger.Errorf(ctx, "Failed to delete agent schedule: %v", err)
+		return fmt.Errorf("failed to delete agent schedule: %w", err)
+	}
+	logger.Infof(ctx, "Agent schedule deleted: %s (ID: %s)", secutils.SanitizeForLog(existing.Name), id)
+	return nil
+}
+
+// TriggerSchedule starts a run of a schedule right away, outside its cron schedule
+func (s *agentScheduleService) TriggerSchedule(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.AgentScheduleRun, error) {
+	schedule, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.startRun(ctx, schedule, types.AgentScheduleTriggerManual, time.Now())
+}
+
+// ListRuns lists the run history of a schedule, newest first
+func (s *agentScheduleService) ListRuns(
+	ctx context.Context,
+	tenantID uint64,
+	scheduleID string,
+) ([]*types.AgentScheduleRun, error) {
+	if _, err := s.get(ctx, tenantID, scheduleID); err != nil {
+		return nil, err
+	}
+	runs, err := s.repo.ListRuns(ctx, tenantID, scheduleID, agentScheduleRunHistory)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list agent schedule runs: %v", err)
+		return nil, fmt.Errorf("failed to list agent schedule runs: %w", err)
+	}
+	return runs, nil
+}
+
+// EnqueueDue starts a run of each due schedule and moves the schedules to their next run time.
+// Several instances may find the same due schedule; only the first to advance it starts the run.
+// Runs missed while no instance was up are not caught up, the schedule fires once and moves on.
+func (s *agentScheduleService) EnqueueDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.repo.ListDue(ctx, now, agentScheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	enqueued := 0
+	for _, schedule := range due {
+		dueAt := *schedule.NextRunAt
+		next, scheduleErr := nextScheduleRun(schedule, now)
+		if scheduleErr != nil {
+			// Stop the schedule rather than firing it on every sweep, e.g. after its time zone was removed
+			logger.Warnf(ctx, "Agent schedule %s cannot be computed, stopping it: %v", schedule.ID, scheduleErr)
+		}
+		advanced, err := s.repo.Advance(ctx, schedule, dueAt, next)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to advance agent schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		if !advanced || scheduleErr != nil {
+			continue
+		}
+
+		if _, err := s.startRun(ctx, schedule, types.AgentScheduleTriggerCron, dueAt); err != nil {
+			logger.Warnf(ctx, "Failed to start run of agent schedule %s: %v", schedule.ID, err)
+			continue
+		}
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+// RunScheduler runs EnqueueDue at startup and then every AgentSchedulePollInterval until ctx is done
+func (s *agentScheduleService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(types.AgentSchedulePollInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.EnqueueDue(ctx); err != nil {
+			logger.Warnf(ctx, "Agent schedule sweep failed: %v", err)
+		} else if n > 0 {
+			logger.Infof(ctx, "Agent schedule sweep started %d runs", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessScheduleRun handles the task executing a run. The agent answer is stored once; a retry
+// after a failed webhook delivery only delivers the stored answer again.
+func (s *agentScheduleService) ProcessScheduleRun(ctx context.Context, t *asynq.Task) error {
+	var payload types.AgentScheduleRunPayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal agent schedule run payload: %v", err)
+		return err
+	}
+
+	// Set tenant context for downstream services
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenant, err := s.tenantService.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant %d for agent schedule run: %v", payload.TenantID, err)
+		return err
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	run, err := s.repo.GetRun(ctx, payload.TenantID, payload.RunID)
+	if err != nil {
+		return err
+	}
+	if run == nil || run.Status == types.AgentScheduleRunSucceeded || run.Status == types.AgentScheduleRunFailed {
+		logger.Infof(ctx, "Skip agent schedule run %s, already finished or removed", payload.RunID)
+		return nil
+	}
+
+	now := time.Now()
+	run.Attempts++
+	run.Status = types.AgentScheduleRunRunning
+	if run.StartedAt == nil {
+		run.StartedAt = &now
+	}
+	if err := s.repo.UpdateRun(ctx, run); err != nil {
+		return err
+	}
+
+	schedule, err := s.get(ctx, run.TenantID, run.ScheduleID)
+	if err == nil {
+		err = s.executeRun(ctx, schedule, run)
+	}
+	if err != nil {
+		logger.Errorf(ctx, "Agent schedule run %s attempt %d failed: %v", run.ID, run.Attempts, err)
+		run.Error = err.Error()
+		retry, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retry < maxRetry && !errors.Is(err, ErrAgentScheduleNotFound) {
+			run.Status = types.AgentScheduleRunPending
+			if updateErr := s.repo.UpdateRun(ctx, run); updateErr != nil {
+				logger.Warnf(ctx, "Failed to update agent schedule run: %v", updateErr)
+			}
+			return err
+		}
+		s.finishRun(ctx, run, types.AgentScheduleRunFailed)
+		return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+	}
+
+	run.Error = ""
+	s.finishRun(ctx, run, types.AgentScheduleRunSucceeded)
+	logger.Infof(ctx, "Agent schedule run %s completed, session: %s", run.ID, run.SessionID)
+	return nil
+}
+
+// executeRun runs the agent unless an earlier attempt already stored its answer, then delivers the answer
+func (s *agentScheduleService) executeRun(
+	ctx context.Context,
+	schedule *types.AgentSchedule,
+	run *types.AgentScheduleRun,
+) error {
+	var answer *types.Message
+	if run.MessageID != "" {
+		message, err := s.messageService.GetMessage(ctx, run.SessionID, run.MessageID)
+		if err != nil {
+			return fmt.Errorf("failed to load the answer of the run: %w", err)
+		}
+		answer = message
+	} else {
+		message, err := s.answer(ctx, schedule, run)
+		if err != nil {
+			return err
+		}
+		answer = message
+		run.SessionID = answer.SessionID
+		run.MessageID = answer.ID
+		if err := s.repo.UpdateRun(ctx, run); err != nil {
+			return fmt.Errorf("failed to record the answer of the run: %w", err)
+		}
+	}
+
+	if schedule.WebhookURL == "" {
+		return nil
+	}
+	return s.deliver(ctx, schedule, run, answer)
+}
+
+// answer runs the agent on the prompt of the schedule and stores the exchange as a new session
+func (s *agentScheduleService) answer(
+	ctx context.Context,
+	schedule *types.AgentSchedule,
+	run *types.AgentScheduleRun,
+) (*types.Message, error) {
+	customAgent, err := s.customAgentService.GetAgentByID(ctx, schedule.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent %s: %w", schedule.AgentID, err)
+	}
+
+	eventBus := event.NewEventBus()
+	refs := &subAgentRefs{seen: make(map[string]bool)}
+	eventBus.On(event.EventAgentToolResult, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentToolResultData); ok {
+			refs.collect(data.Data)
+		}
+		return nil
+	})
+	state, err := s.sessionService.RunAgent(ctx, customAgent, schedule.Prompt, schedule.KnowledgeBaseIDs, eventBus)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionService.CreateSession(ctx, &types.Session{
+		TenantID:    run.TenantID,
+		Title:       fmt.Sprintf("%s %s", schedule.Name, run.ScheduledAt.Format("2006-01-02 15:04")),
+		Description: fmt.Sprintf("Scheduled run %s of agent schedule %s", run.ID, schedule.ID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if _, err := s.messageService.CreateMessage(ctx, &types.Message{
+		SessionID:   session.ID,
+		RequestID:   run.ID,
+		Role:        "user",
+		Content:     schedule.Prompt,
+		IsCompleted: true,
+		CreatedAt:   time.Now(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create user message: %w", err)
+	}
+	message, err := s.messageService.CreateMessage(ctx, &types.Message{
+		SessionID:           session.ID,
+		RequestID:           run.ID,
+		Role:                "assistant",
+		Content:             state.FinalAnswer,
+		KnowledgeReferences: types.References(refs.list()),
+		AgentSteps:          state.RoundSteps,
+		IsCompleted:         true,
+		CreatedAt:           time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create assistant message: %w", err)
+	}
+	return message, nil
+}
+
+// deliver posts the answer and references of a run to the webhook of the schedule
+func (s *agentScheduleService) deliver(
+	ctx context.Context,
+	schedule *types.AgentSchedule,
+	run *types.AgentScheduleRun,
+	answer *types.Message,
+) error {
+	webhookEvent := types.AgentScheduleWebhookEvent{
+		Event:        agentScheduleWebhookEvent,
+		ScheduleID:   schedule.ID,
+		ScheduleName: schedule.Name,
+		AgentID:      schedule.AgentID,
+		RunID:        run.ID,
+		SessionID:    run.SessionID,
+		MessageID:    run.MessageID,
+		Prompt:       schedule.Prompt,
+		Answer:       answer.Content,
+		References:   make([]types.AgentScheduleWebhookReference, 0, len(answer.KnowledgeReferences)),
+		ScheduledAt:  run.ScheduledAt,
+		AnsweredAt:   answer.CreatedAt,
+	}
+	for _, ref := range answer.KnowledgeReferences {
+		webhookEvent.References = append(webhookEvent.References, types.AgentScheduleWebhookReference{
+			ChunkID:        ref.ID,
+			KnowledgeID:    ref.KnowledgeID,
+			KnowledgeTitle: ref.KnowledgeTitle,
+			Content:        ref.Content,
+		})
+	}
+	body, err := json.Marshal(webhookEvent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, schedule.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if schedule.WebhookSecret != "" {
+		req.Header.Set(agentScheduleSignatureHeader, signWebhookBody(schedule.WebhookSecret, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	run.WebhookStatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	now := time.Now()
+	run.DeliveredAt = &now
+	return nil
+}
+
+// startRun records a new run of the schedule and enqueues its execution,
+// retried by the task queue up to the retries of the schedule
+func (s *agentScheduleService) startRun(
+	ctx context.Context,
+	schedule *types.AgentSchedule,
+	trigger types.AgentScheduleTrigger,
+	scheduledAt time.Time,
+) (*types.AgentScheduleRun, error) {
+	run := &types.AgentScheduleRun{
+		TenantID:    schedule.TenantID,
+		ScheduleID:  schedule.ID,
+		TriggeredBy: trigger,
+		Status:      types.AgentScheduleRunPending,
+		ScheduledAt: scheduledAt,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		logger.Errorf(ctx, "Failed to create agent schedule run: %v", err)
+		return nil, fmt.Errorf("failed to create agent schedule run: %w", err)
+	}
+
+	payloadBytes, err := json.Marshal(types.AgentScheduleRunPayload{
+		TenantID: run.TenantID,
+		RunID:    run.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal agent schedule run payload: %w", err)
+	}
+	task := asynq.NewTask(types.TypeAgentScheduleRun, payloadBytes,
+		asynq.Queue("default"), asynq.MaxRetry(schedule.GetMaxRetries()))
+	info, err := s.asynqClient.Enqueue(task)
+	if err != nil {
+		run.Error = fmt.Sprintf("failed to enqueue run: %v", err)
+		s.finishRun(ctx, run, types.AgentScheduleRunFailed)
+		return nil, fmt.Errorf("failed to enqueue agent schedule run: %w", err)
+	}
+	logger.Infof(ctx, "Agent schedule run enqueued: %s, schedule: %s, run: %s", info.ID, schedule.ID, run.ID)
+	return run, nil
+}
+
+// finishRun records the final status of a run on the run and on its schedule
+func (s *agentScheduleService) finishRun(
+	ctx context.Context,
+	run *types.AgentScheduleRun,
+	status types.AgentScheduleRunStatus,
+) {
+	now := time.Now()
+	run.Status = status
+	run.FinishedAt = &now
+	if err := s.repo.UpdateRun(ctx, run); err != nil {
+		logger.Warnf(ctx, "Failed to update agent schedule run: %v", err)
+	}
+	if err := s.repo.UpdateLastRunStatus(ctx, run.TenantID, run.ScheduleID, status); err != nil {
+		logger.Warnf(ctx, "Failed to update last run status of agent schedule: %v", err)
+	}
+}
+
+// get retrieves an agent schedule with decrypted webhook secret
+func (s *agentScheduleService) get(ctx context.Context, tenantID uint64, id string) (*types.AgentSchedule, error) {
+	schedule, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get agent schedule: %v", err)
+		return nil, fmt.Errorf("failed to get agent schedule: %w", err)
+	}
+	if schedule == nil {
+		return nil, ErrAgentScheduleNotFound
+	}
+	if err := decryptWebhookSecret(schedule); err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	return schedule, nil
+}
+
+// validate checks the agent, the cron expression, the time zone, the webhook URL and the retries
+func (s *agentScheduleService) validate(ctx context.Context, schedule *types.AgentSchedule) error {
+	if strings.TrimSpace(schedule.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAgentSchedule)
+	}
+	if strings.TrimSpace(schedule.Prompt) == "" {
+		return fmt.Errorf("%w: prompt is required", ErrInvalidAgentSchedule)
+	}
+	if schedule.AgentID == "" {
+		return fmt.Errorf("%w: agent_id is required", ErrInvalidAgentSchedule)
+	}
+	customAgent, err := s.customAgentService.GetAgentByID(ctx, schedule.AgentID)
+	if err != nil {
+		return fmt.Errorf("%w: agent %s not found", ErrInvalidAgentSchedule, schedule.AgentID)
+	}
+	if !customAgent.IsAgentMode() {
+		return fmt.Errorf("%w: agent %s does not run in agent mode", ErrInvalidAgentSchedule, customAgent.Name)
+	}
+
+	if _, err := parseSchedule(schedule); err != nil {
+		return err
+	}
+	if schedule.WebhookURL != "" {
+		webhookURL, err := url.Parse(schedule.WebhookURL)
+		if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			return fmt.Errorf("%w: webhook_url must be an absolute http(s) URL", ErrInvalidAgentSchedule)
+		}
+	}
+	if retries := schedule.GetMaxRetries(); retries < 0 || retries > types.MaxAgentScheduleRetries {
+		return fmt.Errorf("%w: max_retries must be between 0 and %d", ErrInvalidAgentSchedule,
+			types.MaxAgentScheduleRetries)
+	}
+	return nil
+}
+
+// parseSchedule parses the cron expression of a schedule in its time zone
+func parseSchedule(schedule *types.AgentSchedule) (cron.Schedule, error) {
+	location := time.UTC
+	if schedule.Timezone != "" {
+		loc, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %s", ErrInvalidAgentSchedule, schedule.Timezone)
+		}
+		location = loc
+	}
+	if strings.HasPrefix(schedule.CronExpression, "CRON_TZ=") || strings.HasPrefix(schedule.CronExpression, "TZ=") {
+		return nil, fmt.Errorf("%w: set the time zone with the timezone field", ErrInvalidAgentSchedule)
+	}
+	parsed, err := cronParser.Parse(schedule.CronExpression)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cron expression: %v", ErrInvalidAgentSchedule, err)
+	}
+	if spec, ok := parsed.(*cron.SpecSchedule); ok {
+		spec.Location = location
+	}
+	if every, ok := parsed.(cron.ConstantDelaySchedule); ok && every.Delay < types.MinAgentScheduleInterval {
+		return nil, fmt.Errorf("%w: @every interval must be at least %s", ErrInvalidAgentSchedule,
+			types.MinAgentScheduleInterval)
+	}
+	return parsed, nil
+}
+
+// nextScheduleRun returns the first run time of an enabled schedule after now, nil when disabled
+func nextScheduleRun(schedule *types.AgentSchedule, now time.Time) (*time.Time, error) {
+	if !schedule.Enabled {
+		return nil, nil
+	}
+	parsed, err := parseSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	next := parsed.Next(now)
+	if next.IsZero() {
+		return nil, fmt.Errorf("%w: cron expression never fires", ErrInvalidAgentSchedule)
+	}
+	return &next, nil
+}
+
+// signWebhookBody returns the HMAC-SHA256 signature of a webhook body
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// encryptWebhookSecret stores the webhook secret encrypted
+func encryptWebhookSecret(schedule *types.AgentSchedule) error {
+	if schedule.WebhookSecret == "" {
+		schedule.EncryptedWebhookSecret = ""
+		return nil
+	}
+	encrypted, err := secutils.EncryptSecret(schedule.WebhookSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	schedule.EncryptedWebhookSecret = encrypted
+	return nil
+}
+
+// decryptWebhookSecret restores the webhook secret from its encrypted form
+func decryptWebhookSecret(schedule *types.AgentSchedule) error {
+	if schedule.EncryptedWebhookSecret == "" {
+		return nil
+	}
+	secret, err := secutils.DecryptSecret(schedule.EncryptedWebhookSecret)
+	if err != nil {
+		return err
+	}
+	schedule.WebhookSecret = secret
+	return nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+func TestNextScheduleRun(t *testing.T) {
+	now := time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC) // Tuesday
+	tests := []struct {
+		name     string
+		schedule types.AgentSchedule
+		want     *time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "weekly in UTC",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "0 9 * * 1"},
+			want:     ptrTime(time.Date(2025, 8, 18, 9, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:     "evaluated in time zone",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "0 9 * * *", Timezone: "Asia/Shanghai"},
+			want:     ptrTime(time.Date(2025, 8, 13, 1, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:     "descriptor",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "@every 1h"},
+			want:     ptrTime(now.Add(time.Hour)),
+		},
+		{
+			name:     "interval below the minimum",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "@every 1s"},
+			wantErr:  true,
+		},
+		{
+			name:     "minimum interval",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "@every 1m"},
+			want:     ptrTime(now.Add(time.Minute)),
+		},
+		{
+			name:     "disabled",
+			schedule: types.AgentSchedule{Enabled: false, CronExpression: "0 9 * * 1"},
+		},
+		{
+			name:     "invalid expression",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "every monday"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown time zone",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "@daily", Timezone: "Mars/Olympus"},
+			wantErr:  true,
+		},
+		{
+			name:     "inline time zone",
+			schedule: types.AgentSchedule{Enabled: true, CronExpression: "CRON_TZ=Asia/Tokyo 0 9 * * *"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextScheduleRun(&tt.schedule, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nextScheduleRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidAgentSchedule) {
+				t.Errorf("nextScheduleRun() error = %v, want ErrInvalidAgentSchedule", err)
+			}
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || !got.Equal(*tt.want):
+				t.Errorf("nextScheduleRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgentScheduleDeliver(t *testing.T) {
+	var received types.AgentScheduleWebhookEvent
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(agentScheduleSignatureHeader)
+		if signature != signWebhookBody("webhook-secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := &agentScheduleService{client: server.Client()}
+	schedule := &types.AgentSchedule{
+		ID:            "schedule-1",
+		Name:          "Weekly digest",
+		Prompt:        "Summarise what changed this week",
+		WebhookURL:    server.URL,
+		WebhookSecret: "webhook-secret",
+	}
+	run := &types.AgentScheduleRun{ID: "run-1", SessionID: "session-1", MessageID: "message-1"}
+	answer := &types.Message{
+		Content: "Two documents were added.",
+		KnowledgeReferences: types.References{
+			{ID: "chunk-1", KnowledgeID: "knowledge-1", KnowledgeTitle: "Release notes", Content: "..."},
+		},
+	}
+
+	if err := s.deliver(context.Background(), schedule, run, answer); err != nil {
+		t.Fatalf("deliver() error = %v", err)
+	}
+	if run.WebhookStatusCode != http.StatusNoContent || run.DeliveredAt == nil {
+		t.Errorf("run not marked delivered: status %d, delivered at %v", run.WebhookStatusCode, run.DeliveredAt)
+	}
+	if received.RunID != "run-1" || received.Answer != answer.Content ||
+		len(received.References) != 1 || received.References[0].KnowledgeTitle != "Release notes" {
+		t.Errorf("unexpected webhook body: %+v", received)
+	}
+
+	// A rejected delivery fails the attempt so that it is retried
+	schedule.WebhookSecret = "rotated-secret"
+	run.DeliveredAt = nil
+	if err := s.deliver(context.Background(), schedule, run, answer); err == nil {
+		t.Fatal("deliver() succeeded on a rejected webhook")
+	}
+	if run.WebhookStatusCode != http.StatusUnauthorized || run.DeliveredAt != nil {
+		t.Errorf("rejected delivery recorded as status %d, delivered at %v", run.WebhookStatusCode, run.DeliveredAt)
+	}
+}
+
+// memoryAgentScheduleRepo stores a single agent schedule
+type memoryAgentScheduleRepo struct {
+	interfaces.AgentScheduleRepository
+	schedule types.AgentSchedule
+}
+
+func (r *memoryAgentScheduleRepo) GetByID(ctx context.Context, tenantID uint64, id string) (*types.AgentSchedule, error) {
+	if r.schedule.TenantID != tenantID || r.schedule.ID != id {
+		return nil, nil
+	}
+	schedule := r.schedule
+	return &schedule, nil
+}
+
+func (r *memoryAgentScheduleRepo) Update(ctx context.Context, schedule *types.AgentSchedule) error {
+	r.schedule = *schedule
+	return nil
+}
+
+// agentModeAgentService serves agents running in agent mode
+type agentModeAgentService struct {
+	interfaces.CustomAgentService
+}
+
+func (agentModeAgentService) GetAgentByID(ctx context.Context, id string) (*types.CustomAgent, error) {
+	return &types.CustomAgent{ID: id, Config: types.CustomAgentConfig{AgentMode: types.AgentModeSmartReasoning}}, nil
+}
+
+func TestUpdateSchedulePartial(t *testing.T) {
+	t.Setenv("TENANT_AES_KEY", "0123456789abcdef0123456789abcdef")
+	retries := 5
+	stored := types.AgentSchedule{
+		ID:               "schedule-1",
+		TenantID:         1,
+		Name:             "Weekly digest",
+		Description:      "Sent to the team",
+		Enabled:          true,
+		AgentID:          "agent-1",
+		Prompt:           "Summarise what changed this week",
+		KnowledgeBaseIDs: types.StringArray{"kb-1"},
+		CronExpression:   "0 9 * * 1",
+		Timezone:         "Asia/Shanghai",
+		WebhookURL:       "https://hooks.example.com/weknora",
+		WebhookSecret:    "webhook-secret",
+		MaxRetries:       &retries,
+	}
+	if err := encryptWebhookSecret(&stored); err != nil {
+		t.Fatalf("Failed to encrypt webhook secret: %v", err)
+	}
+	stored.WebhookSecret = ""
+	repo := &memoryAgentScheduleRepo{schedule: stored}
+	s := &agentScheduleService{repo: repo, customAgentService: agentModeAgentService{}}
+
+	disabled := false
+	updated, err := s.UpdateSchedule(context.Background(), 1, "schedule-1",
+		&types.AgentScheduleUpdate{Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updated.Enabled || updated.NextRunAt != nil {
+		t.Errorf("Expected the schedule to be disabled without next run, got enabled %v, next run %v",
+			updated.Enabled, updated.NextRunAt)
+	}
+
+	got := repo.schedule
+	if got.Name != stored.Name || got.Description != stored.Description || got.AgentID != stored.AgentID ||
+		got.Prompt != stored.Prompt || got.CronExpression != stored.CronExpression ||
+		got.Timezone != stored.Timezone || got.WebhookURL != stored.WebhookURL ||
+		len(got.KnowledgeBaseIDs) != 1 || got.KnowledgeBaseIDs[0] != "kb-1" || got.GetMaxRetries() != 5 {
+		t.Errorf("Omitted fields changed: %+v", got)
+	}
+	if err := decryptWebhookSecret(&got); err != nil {
+		t.Fatalf("Failed to decrypt webhook secret: %v", err)
+	}
+	if got.WebhookSecret != "webhook-secret" {
+		t.Errorf("Webhook secret changed: %q", got.WebhookSecret)
+	}
+
+	// Provided fields are applied, zero values included
+	enabled := true
+	noRetries := 0
+	noWebhook := ""
+	if _, err := s.UpdateSchedule(context.Background(), 1, "schedule-1", &types.AgentScheduleUpdate{
+		Enabled:    &enabled,
+		MaxRetries: &noRetries,
+		WebhookURL: &noWebhook,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got = repo.schedule
+	if !got.Enabled || got.NextRunAt == nil || got.GetMaxRetries() != 0 || got.WebhookURL != "" ||
+		got.Timezone != stored.Timezone {
+		t.Errorf("Unexpected schedule after update: enabled %v, next run %v, retries %d, webhook %q, timezone %q",
+			got.Enabled, got.NextRunAt, got.GetMaxRetries(), got.WebhookURL, got.Timezone)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
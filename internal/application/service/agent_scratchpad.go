@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// agentScratchpadService implements the AgentScratchpadService interface
+type agentScratchpadService struct {
+	repo interfaces.AgentScratchpadRepository
+}
+
+// NewAgentScratchpadService creates a new agent scratchpad service
+func NewAgentScratchpadService(repo interfaces.AgentScratchpadRepository) interfaces.AgentScratchpadService {
+	return &agentScratchpadService{repo: repo}
+}
+
+// Get retrieves the scratchpad of a session, empty when the agent has recorded nothing yet
+func (s *agentScratchpadService) Get(ctx context.Context, sessionID string) (*types.AgentScratchpad, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	scratchpad, err := s.repo.Get(ctx, tenantID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if scratchpad == nil {
+		scratchpad = &types.AgentScratchpad{
+			SessionID: sessionID,
+			TenantID:  tenantID,
+			Steps:     types.AgentPlanSteps{},
+			Findings:  types.AgentFindings{},
+		}
+	}
+	return scratchpad, nil
+}
+
+// Update records the latest plan and the new findings written with todo_write in the steps of an execution.
+// Executions that did not call todo_write leave the scratchpad unchanged.
+func (s *agentScratchpadService) Update(
+	ctx context.Context,
+	sessionID, messageID string,
+	steps []types.AgentStep,
+) error {
+	writes := todoWrites(ctx, steps)
+	if len(writes) == 0 {
+		return nil
+	}
+
+	scratchpad, err := s.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	applyTodoWrites(scratchpad, writes, messageID, time.Now())
+	return s.repo.Save(ctx, scratchpad)
+}
+
+// Clear deletes the scratchpad of a session
+func (s *agentScratchpadService) Clear(ctx context.Context, sessionID string) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	return s.repo.Delete(ctx, tenantID, sessionID)
+}
+
+// todoWrites decodes the successful todo_write calls of an execution, in call order
+func todoWrites(ctx context.Context, steps []types.AgentStep) []tools.TodoWriteInput {
+	var writes []tools.TodoWriteInput
+	for _, step := range steps {
+		for _, tc := range step.ToolCalls {
+			if tc.Name != tools.ToolTodoWrite || tc.Result == nil || !tc.Result.Success {
+				continue
+			}
+			args, err := json.Marshal(tc.Args)
+			if err != nil {
+				continue
+			}
+			var input tools.TodoWriteInput
+			if err := json.Unmarshal(args, &input); err != nil {
+				logger.Warnf(ctx, "Failed to decode todo_write call %s: %v", tc.ID, err)
+				continue
+			}
+			writes = append(writes, input)
+		}
+	}
+	return writes
+}
+
+// applyTodoWrites replaces the plan of the scratchpad with the last plan written and appends the new
+// findings, dropping duplicates and the oldest findings beyond MaxAgentScratchpadFindings
+func applyTodoWrites(scratchpad *types.AgentScratchpad, writes []tools.TodoWriteInput, messageID string, now time.Time) {
+	known := make(map[string]bool, len(scratchpad.Findings))
+	for _, finding := range scratchpad.Findings {
+		known[normalizeFinding(finding.Content)] = true
+	}
+
+	for _, write := range writes {
+		if len(write.Steps) > 0 {
+			scratchpad.Task = write.Task
+			scratchpad.Steps = make(types.AgentPlanSteps, 0, len(write.Steps))
+			for _, step := range write.Steps {
+				scratchpad.Steps = append(scratchpad.Steps, types.AgentPlanStep{
+					ID:          step.ID,
+					Description: step.Description,
+					Status:      step.Status,
+				})
+			}
+		}
+		for _, content := range write.Findings {
+			content = strings.TrimSpace(content)
+			key := normalizeFinding(content)
+			if key == "" || known[key] {
+				continue
+			}
+			known[key] = true
+			scratchpad.Findings = append(scratchpad.Findings, types.AgentFinding{
+				Content:   content,
+				MessageID: messageID,
+				CreatedAt: now,
+			})
+		}
+	}
+
+	if overflow := len(scratchpad.Findings) - types.MaxAgentScratchpadFindings; overflow > 0 {
+		scratchpad.Findings = scratchpad.Findings[overflow:]
+	}
+	scratchpad.MessageID = messageID
+}
+
+// normalizeFinding returns the key findings are compared by
+func normalizeFinding(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
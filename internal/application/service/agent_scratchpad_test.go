@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestAgentScratchpadTodoWrites(t *testing.T) {
+	now := time.Date(2025, 8, 12, 10, 30, 0, 0, time.UTC)
+	scratchpad := &types.AgentScratchpad{
+		Task:  "Compare the 2023 and 2024 pricing",
+		Steps: types.AgentPlanSteps{{ID: "step1", Description: "Find 2023 pricing", Status: "completed"}},
+		Findings: types.AgentFindings{
+			{Content: "2023 pricing is $10/seat (Pricing 2023)", MessageID: "message-1", CreatedAt: now.Add(-time.Hour)},
+		},
+	}
+	steps := []types.AgentStep{
+		{ToolCalls: []types.ToolCall{
+			{
+				Name: tools.ToolTodoWrite,
+				Args: map[string]interface{}{
+					"task": "Compare the 2023 and 2024 pricing",
+					"steps": []interface{}{
+						map[string]interface{}{"id": "step1", "description": "Find 2023 pricing", "status": "completed"},
+						map[string]interface{}{"id": "step2", "description": "Find 2024 pricing", "status": "in_progress"},
+					},
+					"findings": []interface{}{"  2023 PRICING is $10/seat (Pricing 2023) ", "2024 pricing is $12/seat (Pricing 2024)"},
+				},
+				Result: &types.ToolResult{Success: true},
+			},
+			{Name: tools.ToolThinking, Args: map[string]interface{}{"thought": "..."}, Result: &types.ToolResult{Success: true}},
+		}},
+		{ToolCalls: []types.ToolCall{
+			{
+				// A failed call does not change the plan
+				Name:   tools.ToolTodoWrite,
+				Args:   map[string]interface{}{"task": "Something else", "steps": []interface{}{}},
+				Result: &types.ToolResult{Success: false, Error: "invalid steps"},
+			},
+			{
+				Name: tools.ToolTodoWrite,
+				Args: map[string]interface{}{
+					"task": "Compare the 2023 and 2024 pricing",
+					"steps": []interface{}{
+						map[string]interface{}{"id": "step1", "description": "Find 2023 pricing", "status": "completed"},
+						map[string]interface{}{"id": "step2", "description": "Find 2024 pricing", "status": "completed"},
+					},
+				},
+				Result: &types.ToolResult{Success: true},
+			},
+		}},
+	}
+
+	writes := todoWrites(context.Background(), steps)
+	if len(writes) != 2 {
+		t.Fatalf("todoWrites() = %d writes, want 2", len(writes))
+	}
+	applyTodoWrites(scratchpad, writes, "message-2", now)
+
+	if len(scratchpad.Steps) != 2 || scratchpad.PendingSteps() != 0 {
+		t.Errorf("plan not replaced by the last write: %+v", scratchpad.Steps)
+	}
+	if len(scratchpad.Findings) != 2 {
+		t.Fatalf("findings = %+v, want the known one and one new", scratchpad.Findings)
+	}
+	if scratchpad.Findings[0].MessageID != "message-1" {
+		t.Errorf("known finding replaced: %+v", scratchpad.Findings[0])
+	}
+	if f := scratchpad.Findings[1]; f.Content != "2024 pricing is $12/seat (Pricing 2024)" ||
+		f.MessageID != "message-2" || !f.CreatedAt.Equal(now) {
+		t.Errorf("unexpected new finding: %+v", f)
+	}
+	if scratchpad.MessageID != "message-2" {
+		t.Errorf("MessageID = %q, want message-2", scratchpad.MessageID)
+	}
+
+	// The oldest findings are dropped beyond the limit
+	findings := make([]string, types.MaxAgentScratchpadFindings)
+	for i := range findings {
+		findings[i] = fmt.Sprintf("finding %d", i)
+	}
+	applyTodoWrites(scratchpad, []tools.TodoWriteInput{{Findings: findings}}, "message-3", now)
+	if len(scratchpad.Findings) != types.MaxAgentScratchpadFindings ||
+		scratchpad.Findings[0].Content != "finding 0" || len(scratchpad.Steps) != 2 {
+		t.Errorf("findings not capped to the newest %d: first %q of %d, %d steps",
+			types.MaxAgentScratchpadFindings, scratchpad.Findings[0].Content,
+			len(scratchpad.Findings), len(scratchpad.Steps))
+	}
+}
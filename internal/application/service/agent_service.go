@@ -35,6 +35,10 @@ type agentService struct {
 	chunkService          interfaces.ChunkService
 	duckdb                *sql.DB
 	webSearchStateService interfaces.WebSearchStateService
+	toolApprovalService   interfaces.ToolApprovalService
+	checkpointService     interfaces.AgentCheckpointService
+	customAgentService    interfaces.CustomAgentService
+	openAPIService        interfaces.OpenAPIServiceService
 }
 
 // NewAgentService creates a new agent service
@@ -51,6 +55,10 @@ func NewAgentService(
 	webSearchService interfaces.WebSearchService,
 	duckdb *sql.DB,
 	webSearchStateService interfaces.WebSearchStateService,
+	toolApprovalService interfaces.ToolApprovalService,
+	checkpointService interfaces.AgentCheckpointService,
+	customAgentService interfaces.CustomAgentService,
+	openAPIService interfaces.OpenAPIServiceService,
 ) interfaces.AgentService {
 	return &agentService{
 		cfg:                   cfg,
@@ -65,6 +73,10 @@ func NewAgentService(
 		webSearchService:      webSearchService,
 		duckdb:                duckdb,
 		webSearchStateService: webSearchStateService,
+		toolApprovalService:   toolApprovalService,
+		checkpointService:     checkpointService,
+		customAgentService:    customAgentService,
+		openAPIService:        openAPIService,
 	}
 }
 
@@ -94,6 +106,7 @@ func (s *agentService) CreateAgentEngine(
 
 	// Create tool registry
 	toolRegistry := tools.NewToolRegistry()
+	toolRegistry.SetBudget(config.ToolBudget)
 
 	// Register tools
 	if err := s.registerTools(ctx, toolRegistry, config, rerankModel, chatModel, sessionID); err != nil {
@@ -146,16 +159,32 @@ func (s *agentService) CreateAgentEngine(
 
 				// Register MCP tools
 				if len(enabledServices) > 0 {
-					if err := tools.RegisterMCPTools(ctx, toolRegistry, enabledServices, s.mcpManager); err != nil {
+					if err := tools.RegisterMCPTools(ctx, toolRegistry, enabledServices, s.mcpManager, config.MCPToolPolicies); err != nil {
 						logger.Warnf(ctx, "Failed to register MCP tools: %v", err)
 					} else {
 						logger.Infof(ctx, "Registered MCP tools from %d enabled services", len(enabledServices))
 					}
+					tools.RegisterMCPResourceTools(toolRegistry, enabledServices, s.mcpManager)
 				}
 			}
 		}
 	}
 
+	// Render the MCP prompt and read the MCP resources selected for the agent
+	if tenantID > 0 && s.mcpServiceService != nil && s.mcpManager != nil {
+		s.applyMCPContext(ctx, tenantID, config)
+	}
+
+	// Register the operations of the OpenAPI services allowed for the agent
+	if tenantID > 0 && len(config.OpenAPIServices) > 0 {
+		openAPIServices, err := s.openAPIService.ListOpenAPIServicesForAgent(ctx, tenantID, config.OpenAPIServices)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to list OpenAPI services: %v", err)
+		} else {
+			tools.RegisterOpenAPITools(ctx, toolRegistry, openAPIServices)
+		}
+	}
+
 	// Get knowledge base detailed information for prompt
 	kbInfos, err := s.getKnowledgeBaseInfos(ctx, config.KnowledgeBases)
 	if err != nil {
@@ -184,6 +213,11 @@ func (s *agentService) CreateAgentEngine(
 		systemPromptTemplate = config.ResolveSystemPrompt(config.WebSearchEnabled)
 	}
 
+	checkpoints := s.checkpointService
+	if config.DisableCheckpoints {
+		checkpoints = nil
+	}
+
 	// Create engine with provided EventBus and contextManager
 	engine := agent.NewAgentEngine(
 		config,
@@ -195,11 +229,87 @@ func (s *agentService) CreateAgentEngine(
 		contextManager,
 		sessionID,
 		systemPromptTemplate,
+		s.toolApprovalService,
+		checkpoints,
 	)
 
 	return engine, nil
 }
 
+// applyMCPContext uses the MCP prompt of the agent as its system prompt and reads its attached MCP resources.
+// Failures are logged and skipped, the agent then runs with its own system prompt or without the resource.
+func (s *agentService) applyMCPContext(ctx context.Context, tenantID uint64, config *types.AgentConfig) {
+	serviceIDs := make([]string, 0, len(config.MCPResources)+1)
+	for _, ref := range config.MCPResources {
+		serviceIDs = append(serviceIDs, ref.ServiceID)
+	}
+	if config.MCPPrompt != nil {
+		serviceIDs = append(serviceIDs, config.MCPPrompt.ServiceID)
+	}
+	if len(serviceIDs) == 0 {
+		return
+	}
+	mcpServices, err := s.mcpServiceService.ListMCPServicesByIDs(ctx, tenantID, serviceIDs)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to list MCP services of agent prompt and resources: %v", err)
+		return
+	}
+	services := make(map[string]*types.MCPService, len(mcpServices))
+	for _, svc := range mcpServices {
+		if svc != nil && svc.Enabled {
+			services[svc.ID] = svc
+		}
+	}
+
+	if ref := config.MCPPrompt; ref != nil {
+		if prompt, err := s.renderMCPPrompt(ctx, services[ref.ServiceID], ref); err != nil {
+			logger.Warnf(ctx, "Failed to get MCP prompt %s of service %s: %v", ref.Name, ref.ServiceID, err)
+		} else if prompt != "" {
+			config.SystemPrompt = prompt
+			config.UseCustomSystemPrompt = true
+			logger.Infof(ctx, "Using MCP prompt %s of service %s as system prompt", ref.Name, ref.ServiceID)
+		}
+	}
+
+	for _, ref := range config.MCPResources {
+		service := services[ref.ServiceID]
+		if service == nil {
+			logger.Warnf(ctx, "MCP service %s of resource %s not found or disabled", ref.ServiceID, ref.URI)
+			continue
+		}
+		resource, err := tools.ReadMCPResource(ctx, s.mcpManager, service, ref.URI)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to read MCP resource %s of service %s: %v", ref.URI, service.Name, err)
+			continue
+		}
+		config.MCPResourceContexts = append(config.MCPResourceContexts, resource)
+	}
+}
+
+// renderMCPPrompt renders a prompt of an MCP service with the arguments configured for the agent
+func (s *agentService) renderMCPPrompt(
+	ctx context.Context,
+	service *types.MCPService,
+	ref *types.MCPPromptRef,
+) (string, error) {
+	if service == nil {
+		return "", fmt.Errorf("MCP service not found or disabled")
+	}
+	client, err := s.mcpManager.GetOrCreateClientFor(ctx, service)
+	if err != nil {
+		return "", err
+	}
+	// For stdio transport, ensure connection is released after use
+	if service.TransportType == types.MCPTransportStdio {
+		defer client.Disconnect()
+	}
+	result, err := client.GetPrompt(ctx, ref.Name, ref.Arguments)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(), nil
+}
+
 // registerTools registers tools based on the agent configuration
 func (s *agentService) registerTools(
 	ctx context.Context,
@@ -321,6 +431,27 @@ func (s *agentService) registerTools(
 		}
 	}
 
+	// Register delegation to the sub-agents the agent may invoke
+	if config.SubAgents.IsActive() && config.SubAgentRunner != nil {
+		targets := make([]tools.DelegateAgentTarget, 0, len(config.SubAgents.AllowedAgents))
+		for _, agentID := range config.SubAgents.AllowedAgents {
+			subAgent, err := s.customAgentService.GetAgentByID(ctx, agentID)
+			if err != nil {
+				logger.Warnf(ctx, "Skip sub-agent %s: %v", secutils.SanitizeForLog(agentID), err)
+				continue
+			}
+			targets = append(targets, tools.DelegateAgentTarget{
+				ID:          subAgent.ID,
+				Name:        subAgent.Name,
+				Description: subAgent.Description,
+			})
+		}
+		if len(targets) > 0 {
+			registry.RegisterTool(tools.NewDelegateAgentTool(config.SubAgentRunner, config.SubAgents, targets))
+			logger.Infof(ctx, "Registered %s tool with %d agents", tools.ToolDelegateAgent, len(targets))
+		}
+	}
+
 	logger.Infof(ctx, "Registered %d tools", len(registry.ListTools()))
 	return nil
 }
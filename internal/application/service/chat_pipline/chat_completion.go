@@ -51,6 +51,7 @@ func (p *PluginChatCompletion) OnEvent(
 		"message_count": len(chatManage.History) + 2,
 	})
 	chatMessages := prepareMessagesWithHistory(chatManage)
+	budgetCompletionTokens(ctx, chatModel, chatManage, chatMessages, opt)
 
 	// Call the chat model to generate response
 	pipelineInfo(ctx, "Completion", "model_call", map[string]interface{}{
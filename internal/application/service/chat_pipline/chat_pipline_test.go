@@ -3,8 +3,11 @@ package chatpipline
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
@@ -123,3 +126,61 @@ func TestTrigger(t *testing.T) {
 		}
 	})
 }
+
+func TestBudgetCompletionTokens(t *testing.T) {
+	chatModel, err := chat.NewChat(&chat.ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "mock",
+		ModelName: "mock-chat",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tok := tokenizer.ForModel("mock-chat")
+
+	tests := []struct {
+		name          string
+		contextTokens int
+		content       string
+		want          func(promptTokens int) int
+	}{
+		{
+			name:          "unlimited context",
+			contextTokens: 0,
+			content:       strings.Repeat("知识库", 100),
+			want:          func(int) int { return 2048 },
+		},
+		{
+			name:          "completion fits",
+			contextTokens: 100000,
+			content:       strings.Repeat("知识库", 100),
+			want:          func(int) int { return 2048 },
+		},
+		{
+			name:          "completion reduced",
+			contextTokens: 2000,
+			content:       strings.Repeat("知识库", 100),
+			want:          func(promptTokens int) int { return 2000 - promptTokens },
+		},
+		{
+			name:          "prompt overflows",
+			contextTokens: 500,
+			content:       strings.Repeat("知识库", 400),
+			want:          func(int) int { return minCompletionTokens },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := []chat.Message{{Role: "user", Content: tt.content}}
+			promptTokens := tokenizer.CountAll(tok, "user", tt.content)
+			opt := &chat.ChatOptions{MaxCompletionTokens: 2048}
+
+			budgetCompletionTokens(context.Background(), chatModel,
+				&types.ChatManage{MaxContextTokens: tt.contextTokens}, messages, opt)
+			if want := tt.want(promptTokens); opt.MaxCompletionTokens != want {
+				t.Errorf("MaxCompletionTokens = %d, want %d", opt.MaxCompletionTokens, want)
+			}
+		})
+	}
+}
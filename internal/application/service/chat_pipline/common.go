@@ -8,6 +8,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/common"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
@@ -47,11 +48,61 @@ func prepareChatModel(ctx context.Context, modelService interfaces.ModelService,
 		FrequencyPenalty:    chatManage.SummaryConfig.FrequencyPenalty,
 		PresencePenalty:     chatManage.SummaryConfig.PresencePenalty,
 		Thinking:            chatManage.SummaryConfig.Thinking,
+		Format:              chatManage.ResponseSchema,
 	}
 
 	return chatModel, opt, nil
 }
 
+// minCompletionTokens is the completion budget left to prompts overflowing the context window
+const minCompletionTokens = 256
+
+// budgetCompletionTokens lowers the completion tokens of opt so that the prompt and the
+// completion fit in the context window of chatManage
+func budgetCompletionTokens(ctx context.Context, chatModel chat.Chat,
+	chatManage *types.ChatManage, messages []chat.Message, opt *chat.ChatOptions,
+) {
+	if chatManage.MaxContextTokens <= 0 || opt.MaxCompletionTokens <= 0 {
+		return
+	}
+	tok := tokenizer.ForModel(chatModel.GetModelName())
+	promptTokens := 0
+	for _, msg := range messages {
+		promptTokens += tokenizer.CountAll(tok, msg.Role, msg.Content)
+	}
+	available := chatManage.MaxContextTokens - promptTokens
+	if available >= opt.MaxCompletionTokens {
+		return
+	}
+	if available < minCompletionTokens {
+		pipelineWarn(ctx, "Completion", "prompt_exceeds_context", map[string]interface{}{
+			"prompt_tokens":  promptTokens,
+			"context_tokens": chatManage.MaxContextTokens,
+		})
+		available = minCompletionTokens
+	}
+	pipelineInfo(ctx, "Completion", "completion_budget", map[string]interface{}{
+		"prompt_tokens":         promptTokens,
+		"max_completion_tokens": opt.MaxCompletionTokens,
+		"budget":                available,
+		"tokenizer":             tok.Name(),
+	})
+	opt.MaxCompletionTokens = available
+}
+
+// chatTokenizer returns the tokenizer of the chat model, or the default one when the
+// model cannot be loaded
+func chatTokenizer(ctx context.Context, modelService interfaces.ModelService, modelID string) tokenizer.Tokenizer {
+	if modelService == nil || modelID == "" {
+		return tokenizer.Default()
+	}
+	model, err := modelService.GetModelByID(ctx, modelID)
+	if err != nil || model == nil {
+		return tokenizer.Default()
+	}
+	return tokenizer.ForModel(model.Name)
+}
+
 // prepareMessagesWithHistory prepare complete messages including history
 func prepareMessagesWithHistory(chatManage *types.ChatManage) []chat.Message {
 	// Replace placeholders in system prompt
@@ -67,8 +118,10 @@ func prepareMessagesWithHistory(chatManage *types.ChatManage) []chat.Message {
 		chatMessages = append(chatMessages, chat.Message{Role: "assistant", Content: history.Answer})
 	}
 
-	// Add current user message
-	chatMessages = append(chatMessages, chat.Message{Role: "user", Content: chatManage.UserContent})
+	// Add current user message with the images attached to the query
+	chatMessages = append(chatMessages, chat.Message{
+		Role: "user", Content: chatManage.UserContent, Images: chatManage.Images,
+	})
 
 	return chatMessages
 }
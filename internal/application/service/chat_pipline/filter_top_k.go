@@ -2,6 +2,7 @@ package chatpipline
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Tencent/WeKnora/internal/types"
 )
@@ -40,6 +41,12 @@ func (p *PluginFilterTopK) OnEvent(ctx context.Context,
 				"before": len(searchResult),
 				"after":  topK,
 			})
+			if trace := types.RetrievalTraceFromContext(ctx); trace != nil {
+				for _, r := range searchResult[topK:] {
+					trace.Drop(r.ID, types.RetrievalStageFilterTopK, types.DropReasonTopK,
+						fmt.Sprintf("ranked below top k %d", topK))
+				}
+			}
 			searchResult = searchResult[:topK]
 		}
 		return searchResult
@@ -0,0 +1,351 @@
+package chatpipline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+// ErrGuardrailRefused is returned when a guardrail policy refuses the user query or a complete answer.
+// The refusal has already been emitted to the event bus when this error is returned.
+var ErrGuardrailRefused = &PluginError{
+	Description: "Request refused by guardrail policy",
+	ErrorType:   "guardrail_refused",
+}
+
+// PluginGuardrail checks the user query before retrieval (GUARDRAIL_INPUT) and
+// the model answer (GUARDRAIL_OUTPUT) against the guardrail policies configured
+// for the custom agent
+type PluginGuardrail struct {
+	builders []GuardrailPolicyBuilder
+}
+
+// NewPluginGuardrail creates a new guardrail plugin with the built-in policies
+// and registers it with the EventManager
+func NewPluginGuardrail(eventManager *EventManager,
+	modelService interfaces.ModelService,
+) *PluginGuardrail {
+	res := &PluginGuardrail{
+		builders: []GuardrailPolicyBuilder{
+			newDenylistPolicy,
+			newPIIPolicy,
+			newTopicPolicyBuilder(modelService),
+		},
+	}
+	eventManager.Register(res)
+	return res
+}
+
+// RegisterPolicyBuilder adds a custom policy builder evaluated after the built-in ones
+func (p *PluginGuardrail) RegisterPolicyBuilder(builder GuardrailPolicyBuilder) {
+	p.builders = append(p.builders, builder)
+}
+
+// ActivationEvents returns the event types this plugin handles
+func (p *PluginGuardrail) ActivationEvents() []types.EventType {
+	return []types.EventType{types.GUARDRAIL_INPUT, types.GUARDRAIL_OUTPUT}
+}
+
+// OnEvent handles guardrail events in the chat pipeline
+func (p *PluginGuardrail) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	cfg := chatManage.Guardrail
+	if !cfg.IsActive() {
+		return next()
+	}
+
+	switch eventType {
+	case types.GUARDRAIL_INPUT:
+		return p.checkInput(ctx, chatManage, next)
+	case types.GUARDRAIL_OUTPUT:
+		if !cfg.CheckOutput {
+			return next()
+		}
+		return p.checkOutput(ctx, chatManage, next)
+	}
+	return next()
+}
+
+// buildPolicies builds the policies configured for the request, skipping misconfigured ones
+func (p *PluginGuardrail) buildPolicies(ctx context.Context, chatManage *types.ChatManage) []GuardrailPolicy {
+	policies := make([]GuardrailPolicy, 0, len(p.builders))
+	for _, builder := range p.builders {
+		policy, err := builder(ctx, chatManage.Guardrail, chatManage)
+		if err != nil {
+			pipelineWarn(ctx, "Guardrail", "build_policy", map[string]interface{}{
+				"session_id": chatManage.SessionID,
+				"error":      err.Error(),
+			})
+			continue
+		}
+		if policy != nil {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// evaluatePolicies runs the policies in order and returns the first refusal
+func evaluatePolicies(ctx context.Context, policies []GuardrailPolicy,
+	stage GuardrailStage, text string,
+) *GuardrailVerdict {
+	for _, policy := range policies {
+		verdict, err := policy.Check(ctx, stage, text)
+		if err != nil {
+			// Policy failures must not break the conversation, the remaining policies still apply
+			pipelineWarn(ctx, "Guardrail", "policy_error", map[string]interface{}{
+				"policy": policy.Name(),
+				"stage":  string(stage),
+				"error":  err.Error(),
+			})
+			continue
+		}
+		if verdict != nil {
+			return verdict
+		}
+	}
+	return nil
+}
+
+// checkInput checks the user query and stops the pipeline on refusal
+func (p *PluginGuardrail) checkInput(ctx context.Context,
+	chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	policies := p.buildPolicies(ctx, chatManage)
+	verdict := evaluatePolicies(ctx, policies, GuardrailStageInput, chatManage.Query)
+	if verdict == nil {
+		pipelineInfo(ctx, "Guardrail", "input_allowed", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"policies":   len(policies),
+		})
+		return next()
+	}
+
+	pipelineWarn(ctx, "Guardrail", "input_refused", map[string]interface{}{
+		"session_id": chatManage.SessionID,
+		"policy":     verdict.Policy,
+		"reason":     verdict.Reason,
+	})
+	message := chatManage.Guardrail.GetRefusalMessage()
+	chatManage.ChatResponse = &types.ChatResponse{Content: message}
+	emitGuardrailRefusal(ctx, chatManage.EventBus, chatManage.SessionID, GuardrailStageInput, verdict, message)
+	return ErrGuardrailRefused
+}
+
+// checkOutput checks the model answer. For non-streaming pipelines the completed
+// response is checked directly; for streaming pipelines the event bus is wrapped
+// before CHAT_COMPLETION_STREAM so that answer chunks are checked as they arrive,
+// in the same way STREAM_FILTER intercepts answer events.
+func (p *PluginGuardrail) checkOutput(ctx context.Context,
+	chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	policies := p.buildPolicies(ctx, chatManage)
+	if len(policies) == 0 {
+		return next()
+	}
+
+	if chatManage.ChatResponse != nil {
+		verdict := evaluatePolicies(ctx, policies, GuardrailStageOutput, chatManage.ChatResponse.Content)
+		if verdict != nil {
+			pipelineWarn(ctx, "Guardrail", "output_refused", map[string]interface{}{
+				"session_id": chatManage.SessionID,
+				"policy":     verdict.Policy,
+				"reason":     verdict.Reason,
+			})
+			message := chatManage.Guardrail.GetRefusalMessage()
+			chatManage.ChatResponse.Content = message
+			emitGuardrailRefusal(ctx, chatManage.EventBus, chatManage.SessionID, GuardrailStageOutput, verdict, message)
+			return ErrGuardrailRefused
+		}
+		return next()
+	}
+
+	if chatManage.EventBus == nil {
+		pipelineWarn(ctx, "Guardrail", "eventbus_missing", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+		})
+		return next()
+	}
+
+	pipelineInfo(ctx, "Guardrail", "install_output_guard", map[string]interface{}{
+		"session_id": chatManage.SessionID,
+		"policies":   len(policies),
+	})
+	chatManage.EventBus = &guardrailEventBus{
+		ctx:        ctx,
+		inner:      chatManage.EventBus,
+		policies:   policies,
+		chatManage: chatManage,
+		tails:      make(map[string]string),
+	}
+	return next()
+}
+
+// guardrailOverlap is the length, in bytes, of the end of the checked answer that is checked
+// again with each new chunk, so that matches spanning chunks are still detected
+const guardrailOverlap = 256
+
+// guardrailEventBus wraps the request event bus and checks answer chunks before forwarding them.
+// In agent mode the answer streams as the thoughts of the last round, which is only known to be the
+// last once it ends, so all thought chunks are checked, as are the answers of delegated agents.
+// Each chunk is checked together with the end of the previous ones, so the cost stays linear
+// in the answer length. Once an answer is refused, the remaining chunks are dropped and a
+// refusal is emitted instead.
+type guardrailEventBus struct {
+	ctx        context.Context
+	inner      types.EventBusInterface
+	policies   []GuardrailPolicy
+	chatManage *types.ChatManage
+
+	mu      sync.Mutex
+	tails   map[string]string // End of the checked answer per event ID
+	refused bool
+}
+
+// On registers an event handler on the wrapped event bus
+func (b *guardrailEventBus) On(eventType types.EventType, handler types.EventHandler) {
+	b.inner.On(eventType, handler)
+}
+
+// Emit checks answer events and forwards the allowed ones
+func (b *guardrailEventBus) Emit(ctx context.Context, evt types.Event) error {
+	if evt.Type == types.EventType(event.EventAgentComplete) {
+		return b.inner.Emit(ctx, b.redactComplete(evt))
+	}
+	content, ok := guardedContent(evt)
+	if !ok {
+		return b.inner.Emit(ctx, evt)
+	}
+
+	b.mu.Lock()
+	if b.refused {
+		b.mu.Unlock()
+		return nil
+	}
+	text := b.tails[evt.ID] + content
+	verdict := evaluatePolicies(b.ctx, b.policies, GuardrailStageOutput, text)
+	if verdict != nil {
+		b.refused = true
+	}
+	b.tails[evt.ID] = guardrailTail(text)
+	b.mu.Unlock()
+
+	if verdict == nil {
+		return b.inner.Emit(ctx, evt)
+	}
+
+	pipelineWarn(ctx, "Guardrail", "output_refused", map[string]interface{}{
+		"session_id": b.chatManage.SessionID,
+		"policy":     verdict.Policy,
+		"reason":     verdict.Reason,
+	})
+	emitGuardrailRefusal(ctx, b.inner, b.chatManage.SessionID,
+		GuardrailStageOutput, verdict, b.chatManage.Guardrail.GetRefusalMessage())
+	return nil
+}
+
+// redactComplete removes a refused answer from the completion event of an agent execution,
+// whose final answer and step thoughts are stored with the message
+func (b *guardrailEventBus) redactComplete(evt types.Event) types.Event {
+	b.mu.Lock()
+	refused := b.refused
+	b.mu.Unlock()
+	data, ok := evt.Data.(event.AgentCompleteData)
+	if !refused || !ok {
+		return evt
+	}
+	data.FinalAnswer = b.chatManage.Guardrail.GetRefusalMessage()
+	if steps, ok := data.AgentSteps.([]types.AgentStep); ok {
+		redacted := make([]types.AgentStep, len(steps))
+		for i, step := range steps {
+			step.Thought = ""
+			redacted[i] = step
+		}
+		data.AgentSteps = redacted
+	}
+	evt.Data = data
+	return evt
+}
+
+// guardedContent returns the text an event shows to the user which output policies check:
+// final answer and thought chunks, including those forwarded from delegated agents
+func guardedContent(evt types.Event) (string, bool) {
+	switch evt.Type {
+	case types.EventType(event.EventAgentFinalAnswer), types.EventType(event.EventAgentThought):
+	case types.EventType(event.EventAgentSubAgent):
+		data, ok := evt.Data.(event.SubAgentEventData)
+		if !ok {
+			return "", false
+		}
+		return guardedContent(types.Event{Type: types.EventType(data.EventType), Data: data.Data})
+	default:
+		return "", false
+	}
+	switch data := evt.Data.(type) {
+	case event.AgentFinalAnswerData:
+		return data.Content, true
+	case event.AgentThoughtData:
+		return data.Content, true
+	}
+	return "", false
+}
+
+// guardrailTail returns the last guardrailOverlap bytes of text, without splitting a rune
+func guardrailTail(text string) string {
+	if len(text) <= guardrailOverlap {
+		return text
+	}
+	start := len(text) - guardrailOverlap
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return text[start:]
+}
+
+// emitGuardrailRefusal emits the refusal event followed by a final answer carrying the
+// refusal message, so that clients unaware of refusal events still finish the answer
+func emitGuardrailRefusal(ctx context.Context, eventBus types.EventBusInterface, sessionID string,
+	stage GuardrailStage, verdict *GuardrailVerdict, message string,
+) {
+	if eventBus == nil {
+		return
+	}
+	prefix := uuid.New().String()[:8]
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-refusal", prefix),
+		Type:      types.EventType(event.EventGuardrailRefusal),
+		SessionID: sessionID,
+		Data: event.GuardrailRefusalData{
+			Stage:   string(stage),
+			Policy:  verdict.Policy,
+			Reason:  verdict.Reason,
+			Message: message,
+		},
+	}); err != nil {
+		pipelineError(ctx, "Guardrail", "emit_refusal", map[string]interface{}{
+			"session_id": sessionID,
+			"error":      err.Error(),
+		})
+	}
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-answer", prefix),
+		Type:      types.EventType(event.EventAgentFinalAnswer),
+		SessionID: sessionID,
+		Data: event.AgentFinalAnswerData{
+			Content: message,
+			Done:    true,
+		},
+	}); err != nil {
+		pipelineError(ctx, "Guardrail", "emit_answer", map[string]interface{}{
+			"session_id": sessionID,
+			"error":      err.Error(),
+		})
+	}
+}
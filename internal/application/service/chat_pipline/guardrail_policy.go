@@ -0,0 +1,273 @@
+package chatpipline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// GuardrailStage identifies where a guardrail policy is evaluated
+type GuardrailStage string
+
+const (
+	// GuardrailStageInput checks the user query before retrieval
+	GuardrailStageInput GuardrailStage = "input"
+	// GuardrailStageOutput checks the (streamed) model answer
+	GuardrailStageOutput GuardrailStage = "output"
+)
+
+// GuardrailVerdict describes why a policy refused a text
+type GuardrailVerdict struct {
+	Policy string // Name of the refusing policy
+	Reason string // Internal reason, logged and sent in the refusal event data
+}
+
+// GuardrailPolicy is a single check applied by the guardrail stage.
+// Check returns nil when the text is allowed.
+type GuardrailPolicy interface {
+	// Name returns the policy identifier
+	Name() string
+	// Check evaluates text at the given stage
+	Check(ctx context.Context, stage GuardrailStage, text string) (*GuardrailVerdict, error)
+}
+
+// GuardrailPolicyBuilder builds a policy from the guardrail config of a request.
+// It returns nil when the policy is not configured.
+type GuardrailPolicyBuilder func(
+	ctx context.Context, cfg *types.GuardrailConfig, chatManage *types.ChatManage,
+) (GuardrailPolicy, error)
+
+// denylistPolicy refuses texts matching denied regular expressions or keywords
+type denylistPolicy struct {
+	patterns []*regexp.Regexp
+	keywords []string
+}
+
+// newDenylistPolicy builds the regex/keyword denylist policy
+func newDenylistPolicy(_ context.Context,
+	cfg *types.GuardrailConfig, _ *types.ChatManage,
+) (GuardrailPolicy, error) {
+	if len(cfg.DenyPatterns) == 0 && len(cfg.DenyKeywords) == 0 {
+		return nil, nil
+	}
+	policy := &denylistPolicy{}
+	for _, pattern := range cfg.DenyPatterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+		policy.patterns = append(policy.patterns, re)
+	}
+	for _, keyword := range cfg.DenyKeywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			policy.keywords = append(policy.keywords, strings.ToLower(keyword))
+		}
+	}
+	return policy, nil
+}
+
+// Name returns the policy identifier
+func (p *denylistPolicy) Name() string {
+	return "denylist"
+}
+
+// Check refuses the text if any denied pattern or keyword is present
+func (p *denylistPolicy) Check(_ context.Context, _ GuardrailStage, text string) (*GuardrailVerdict, error) {
+	for _, re := range p.patterns {
+		if re.MatchString(text) {
+			return &GuardrailVerdict{Policy: p.Name(), Reason: fmt.Sprintf("matched pattern %q", re.String())}, nil
+		}
+	}
+	lower := strings.ToLower(text)
+	for _, keyword := range p.keywords {
+		if strings.Contains(lower, keyword) {
+			return &GuardrailVerdict{Policy: p.Name(), Reason: fmt.Sprintf("matched keyword %q", keyword)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// piiDetectors maps PII types to their detection patterns
+var piiDetectors = map[string]*regexp.Regexp{
+	types.PIITypeEmail:      regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	types.PIITypePhone:      regexp.MustCompile(`(?:\+\d{1,3}[\s\-]?)?(?:1[3-9]\d{9}|\(?\d{3}\)?[\s\-]\d{3}[\s\-]\d{4})`),
+	types.PIITypeIDCard:     regexp.MustCompile(`\b[1-9]\d{5}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`),
+	types.PIITypeCreditCard: regexp.MustCompile(`\b(?:\d[ \-]?){13,19}\b`),
+	types.PIITypeIPAddress:  regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+}
+
+// piiTypeOrder keeps PII detection deterministic
+var piiTypeOrder = []string{
+	types.PIITypeEmail, types.PIITypeIDCard, types.PIITypeCreditCard, types.PIITypePhone, types.PIITypeIPAddress,
+}
+
+// piiPolicy refuses texts containing personally identifiable information
+type piiPolicy struct {
+	piiTypes []string
+}
+
+// newPIIPolicy builds the PII detection policy
+func newPIIPolicy(_ context.Context,
+	cfg *types.GuardrailConfig, _ *types.ChatManage,
+) (GuardrailPolicy, error) {
+	if !cfg.PIIDetection {
+		return nil, nil
+	}
+	if len(cfg.PIITypes) == 0 {
+		return &piiPolicy{piiTypes: piiTypeOrder}, nil
+	}
+	enabled := make(map[string]bool, len(cfg.PIITypes))
+	for _, t := range cfg.PIITypes {
+		if _, ok := piiDetectors[t]; !ok {
+			return nil, fmt.Errorf("unsupported pii type: %s", t)
+		}
+		enabled[t] = true
+	}
+	policy := &piiPolicy{}
+	for _, t := range piiTypeOrder {
+		if enabled[t] {
+			policy.piiTypes = append(policy.piiTypes, t)
+		}
+	}
+	return policy, nil
+}
+
+// Name returns the policy identifier
+func (p *piiPolicy) Name() string {
+	return "pii"
+}
+
+// Check refuses the text if any enabled PII type is detected
+func (p *piiPolicy) Check(_ context.Context, _ GuardrailStage, text string) (*GuardrailVerdict, error) {
+	for _, t := range p.piiTypes {
+		for _, match := range piiDetectors[t].FindAllString(text, -1) {
+			if t == types.PIITypeCreditCard && !luhnValid(match) {
+				continue
+			}
+			return &GuardrailVerdict{Policy: p.Name(), Reason: fmt.Sprintf("detected %s", t)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// luhnValid checks a candidate card number with the Luhn algorithm
+func luhnValid(number string) bool {
+	sum, count := 0, 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		count++
+	}
+	return count >= 13 && sum%10 == 0
+}
+
+// topicClassifierPrompt asks the model to classify a query against allowed topics
+const topicClassifierPrompt = `You are a strict topic classifier. Decide whether the user query belongs to at least one of the allowed topics.
+Allowed topics:
+%s
+
+Respond with JSON only, in the form {"allowed": true|false, "topic": "<matched topic or empty>"}.`
+
+// topicPolicy refuses queries classified outside the allowed topics by an LLM
+type topicPolicy struct {
+	modelService interfaces.ModelService
+	modelID      string
+	topics       []string
+}
+
+// newTopicPolicyBuilder returns a builder for the topic allowlist policy
+func newTopicPolicyBuilder(modelService interfaces.ModelService) GuardrailPolicyBuilder {
+	return func(_ context.Context, cfg *types.GuardrailConfig, chatManage *types.ChatManage) (GuardrailPolicy, error) {
+		if len(cfg.AllowedTopics) == 0 {
+			return nil, nil
+		}
+		modelID := cfg.TopicClassifierModelID
+		if modelID == "" {
+			modelID = chatManage.ChatModelID
+		}
+		if modelID == "" {
+			return nil, fmt.Errorf("no model available for topic classification")
+		}
+		return &topicPolicy{modelService: modelService, modelID: modelID, topics: cfg.AllowedTopics}, nil
+	}
+}
+
+// Name returns the policy identifier
+func (p *topicPolicy) Name() string {
+	return "topic_allowlist"
+}
+
+// Check classifies the user query, answers are not checked
+func (p *topicPolicy) Check(ctx context.Context, stage GuardrailStage, text string) (*GuardrailVerdict, error) {
+	if stage != GuardrailStageInput {
+		return nil, nil
+	}
+	chatModel, err := p.modelService.GetChatModel(ctx, p.modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{Role: "system", Content: fmt.Sprintf(topicClassifierPrompt, "- "+strings.Join(p.topics, "\n- "))},
+		{Role: "user", Content: text},
+	}, &chat.ChatOptions{
+		Temperature:         0,
+		MaxCompletionTokens: 64,
+		Thinking:            &thinking,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, topic, err := parseTopicClassification(response.Content)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return &GuardrailVerdict{Policy: p.Name(), Reason: "query is outside the allowed topics"}, nil
+	}
+	if topic != "" {
+		pipelineInfo(ctx, "Guardrail", "topic_match", map[string]interface{}{"topic": topic})
+	}
+	return nil, nil
+}
+
+// parseTopicClassification extracts the classifier decision from the model output
+func parseTopicClassification(content string) (bool, string, error) {
+	content = strings.TrimSpace(content)
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return false, "", fmt.Errorf("unexpected classifier output: %s", content)
+	}
+	var result struct {
+		Allowed bool   `json:"allowed"`
+		Topic   string `json:"topic"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return false, "", fmt.Errorf("failed to parse classifier output: %w", err)
+	}
+	return result.Allowed, result.Topic, nil
+}
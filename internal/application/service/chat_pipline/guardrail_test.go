@@ -0,0 +1,222 @@
+package chatpipline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestGuardrailPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Denylist", func(t *testing.T) {
+		policy, err := newDenylistPolicy(ctx, &types.GuardrailConfig{
+			DenyPatterns: []string{`(?i)drop\s+table`},
+			DenyKeywords: []string{"Password"},
+		}, &types.ChatManage{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for text, refused := range map[string]bool{
+			"how do I DROP  TABLE users": true,
+			"what is my password":        true,
+			"how do I create a table":    false,
+		} {
+			verdict, _ := policy.Check(ctx, GuardrailStageInput, text)
+			if (verdict != nil) != refused {
+				t.Errorf("Check(%q) refused=%v, want %v", text, verdict != nil, refused)
+			}
+		}
+	})
+
+	t.Run("PII", func(t *testing.T) {
+		policy, err := newPIIPolicy(ctx, &types.GuardrailConfig{PIIDetection: true}, &types.ChatManage{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for text, refused := range map[string]bool{
+			"contact me at alice@example.com": true,
+			"my card is 4111 1111 1111 1111":  true,
+			"order number 1234567890123":      false,
+			"server 192.168.1.10 is down":     true,
+			"the answer is 42":                false,
+		} {
+			verdict, _ := policy.Check(ctx, GuardrailStageOutput, text)
+			if (verdict != nil) != refused {
+				t.Errorf("Check(%q) refused=%v, want %v", text, verdict != nil, refused)
+			}
+		}
+
+		if _, err := newPIIPolicy(ctx, &types.GuardrailConfig{
+			PIIDetection: true, PIITypes: []string{"passport"},
+		}, &types.ChatManage{}); err == nil {
+			t.Error("Expected error for unsupported pii type")
+		}
+	})
+
+	t.Run("TopicClassification", func(t *testing.T) {
+		allowed, topic, err := parseTopicClassification("```json\n{\"allowed\": true, \"topic\": \"billing\"}\n```")
+		if err != nil || !allowed || topic != "billing" {
+			t.Errorf("Unexpected classification: allowed=%v topic=%q err=%v", allowed, topic, err)
+		}
+		if _, _, err := parseTopicClassification("yes"); err == nil {
+			t.Error("Expected error for non-JSON output")
+		}
+	})
+}
+
+func TestGuardrailStreamOutput(t *testing.T) {
+	ctx := context.Background()
+	manager := &EventManager{}
+	NewPluginGuardrail(manager, nil)
+
+	bus := event.NewEventBus()
+	var answers, refusals []string
+	bus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		answers = append(answers, evt.Data.(event.AgentFinalAnswerData).Content)
+		return nil
+	})
+	bus.On(event.EventGuardrailRefusal, func(ctx context.Context, evt event.Event) error {
+		refusals = append(refusals, evt.Data.(event.GuardrailRefusalData).Policy)
+		return nil
+	})
+
+	chatManage := &types.ChatManage{
+		EventBus: bus.AsEventBusInterface(),
+		Guardrail: &types.GuardrailConfig{
+			Enabled:        true,
+			DenyKeywords:   []string{"secret"},
+			CheckOutput:    true,
+			RefusalMessage: "refused",
+		},
+	}
+	if err := manager.Trigger(ctx, types.GUARDRAIL_OUTPUT, chatManage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, chunk := range []string{"the ", "sec", "ret is", " out"} {
+		_ = chatManage.EventBus.Emit(ctx, types.Event{
+			ID:   "answer",
+			Type: types.EventType(event.EventAgentFinalAnswer),
+			Data: event.AgentFinalAnswerData{Content: chunk},
+		})
+	}
+
+	if len(refusals) != 1 || refusals[0] != "denylist" {
+		t.Fatalf("Expected one denylist refusal, got %v", refusals)
+	}
+	expected := []string{"the ", "sec", "refused"}
+	if len(answers) != len(expected) {
+		t.Fatalf("Expected answers %v, got %v", expected, answers)
+	}
+	for i := range expected {
+		if answers[i] != expected[i] {
+			t.Errorf("Answer %d: expected %q, got %q", i, expected[i], answers[i])
+		}
+	}
+
+	// Input refusal stops the pipeline
+	chatManage.Query = "tell me the secret"
+	if err := manager.Trigger(ctx, types.GUARDRAIL_INPUT, chatManage); err != ErrGuardrailRefused {
+		t.Errorf("Expected ErrGuardrailRefused, got %v", err)
+	}
+}
+
+// lengthPolicy records the length of the longest text it checked
+type lengthPolicy struct {
+	longest int
+}
+
+func (p *lengthPolicy) Name() string { return "length" }
+
+func (p *lengthPolicy) Check(_ context.Context, _ GuardrailStage, text string) (*GuardrailVerdict, error) {
+	p.longest = max(p.longest, len(text))
+	return nil, nil
+}
+
+func TestGuardrailStreamOutputWindow(t *testing.T) {
+	ctx := context.Background()
+	manager := &EventManager{}
+	plugin := NewPluginGuardrail(manager, nil)
+	policy := &lengthPolicy{}
+	plugin.RegisterPolicyBuilder(func(context.Context, *types.GuardrailConfig, *types.ChatManage) (GuardrailPolicy, error) {
+		return policy, nil
+	})
+
+	chatManage := &types.ChatManage{
+		EventBus:  event.NewEventBus().AsEventBusInterface(),
+		Guardrail: &types.GuardrailConfig{Enabled: true, CheckOutput: true},
+	}
+	if err := manager.Trigger(ctx, types.GUARDRAIL_OUTPUT, chatManage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chunk := "0123456789"
+	for i := 0; i < 1000; i++ {
+		_ = chatManage.EventBus.Emit(ctx, types.Event{
+			ID:   "answer",
+			Type: types.EventType(event.EventAgentFinalAnswer),
+			Data: event.AgentFinalAnswerData{Content: chunk},
+		})
+	}
+	if policy.longest > guardrailOverlap+len(chunk) {
+		t.Errorf("Expected checks bounded by the overlap window, checked %d bytes", policy.longest)
+	}
+
+	if tail := guardrailTail(strings.Repeat("答", 200)); len(tail) > guardrailOverlap || !utf8.ValidString(tail) {
+		t.Errorf("Expected a valid tail within the overlap window, got %d bytes", len(tail))
+	}
+}
+
+func TestGuardrailOutputRefusal(t *testing.T) {
+	ctx := context.Background()
+	manager := &EventManager{}
+	NewPluginGuardrail(manager, nil)
+	guardrail := &types.GuardrailConfig{
+		Enabled:        true,
+		DenyKeywords:   []string{"secret"},
+		CheckOutput:    true,
+		RefusalMessage: "refused",
+	}
+
+	// A refused complete answer stops the pipeline
+	chatManage := &types.ChatManage{
+		Guardrail:    guardrail,
+		ChatResponse: &types.ChatResponse{Content: "the secret is out"},
+	}
+	if err := manager.Trigger(ctx, types.GUARDRAIL_OUTPUT, chatManage); err != ErrGuardrailRefused {
+		t.Errorf("Expected ErrGuardrailRefused, got %v", err)
+	}
+	if chatManage.ChatResponse.Content != "refused" {
+		t.Errorf("Expected the refusal message, got %q", chatManage.ChatResponse.Content)
+	}
+
+	// Agent mode intercepts the events emitted on the request bus
+	bus := event.NewEventBus()
+	var answer string
+	bus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		answer += evt.Data.(event.AgentFinalAnswerData).Content
+		return nil
+	})
+	chatManage = &types.ChatManage{EventBus: bus.AsDispatcher(), Guardrail: guardrail}
+	if err := manager.Trigger(ctx, types.GUARDRAIL_OUTPUT, chatManage); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	guarded := chatManage.EventBus
+	bus.Intercept(func(ctx context.Context, evt event.Event) error {
+		return guarded.Emit(ctx, types.Event{ID: evt.ID, Type: types.EventType(evt.Type), Data: evt.Data})
+	})
+	for _, chunk := range []string{"the ", "secret", " is out"} {
+		_ = bus.Emit(ctx, event.Event{
+			ID:   "answer",
+			Type: event.EventAgentFinalAnswer,
+			Data: event.AgentFinalAnswerData{Content: chunk},
+		})
+	}
+	if answer != "the refused" {
+		t.Errorf("Expected the refused answer to be replaced, got %q", answer)
+	}
+}
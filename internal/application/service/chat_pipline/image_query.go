@@ -0,0 +1,96 @@
+package chatpipline
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// imageQueryPrompt asks the vision model for a searchable description of the attached images
+const imageQueryPrompt = `Describe the images so that the description can be used to search a knowledge base.
+Transcribe all visible text (OCR) exactly as written, including error messages, code, table cells and labels.
+Then briefly describe what the images show in relation to the user's question.
+Answer in the language of the question, in plain text without any preamble.`
+
+// imageQueryMaxTokens caps the length of the image description
+const imageQueryMaxTokens = 512
+
+// PluginImageQuery describes the images attached to the query with the vision chat model.
+// The description is searched in addition to the query.
+type PluginImageQuery struct {
+	modelService interfaces.ModelService // Model service for calling the vision model
+}
+
+// NewPluginImageQuery creates a new image query plugin instance
+// Also registers the plugin with the event manager
+func NewPluginImageQuery(eventManager *EventManager, modelService interfaces.ModelService) *PluginImageQuery {
+	res := &PluginImageQuery{modelService: modelService}
+	eventManager.Register(res)
+	return res
+}
+
+// ActivationEvents returns the list of event types this plugin responds to
+func (p *PluginImageQuery) ActivationEvents() []types.EventType {
+	return []types.EventType{types.IMAGE_QUERY}
+}
+
+// OnEvent describes the attached images into chatManage.ImageQuery.
+// Failures only leave the images out of retrieval; the answer model still reads them.
+func (p *PluginImageQuery) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	chatManage.ImageQuery = ""
+	if len(chatManage.Images) == 0 {
+		return next()
+	}
+	if !chatManage.EnableImageQuery {
+		pipelineInfo(ctx, "ImageQuery", "skip", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"reason":     "image_query_disabled",
+		})
+		return next()
+	}
+
+	pipelineInfo(ctx, "ImageQuery", "input", map[string]interface{}{
+		"session_id":    chatManage.SessionID,
+		"chat_model_id": chatManage.ChatModelID,
+		"images":        len(chatManage.Images),
+	})
+
+	visionModel, err := p.modelService.GetChatModel(ctx, chatManage.ChatModelID)
+	if err != nil {
+		pipelineWarn(ctx, "ImageQuery", "get_model", map[string]interface{}{
+			"session_id":    chatManage.SessionID,
+			"chat_model_id": chatManage.ChatModelID,
+			"error":         err.Error(),
+		})
+		return next()
+	}
+
+	thinking := false
+	response, err := visionModel.Chat(ctx, []chat.Message{
+		{Role: "system", Content: imageQueryPrompt},
+		{Role: "user", Content: chatManage.Query, Images: chatManage.Images},
+	}, &chat.ChatOptions{
+		Temperature:         0.1,
+		MaxCompletionTokens: imageQueryMaxTokens,
+		Thinking:            &thinking,
+	})
+	if err != nil {
+		pipelineWarn(ctx, "ImageQuery", "model_call", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"error":      err.Error(),
+		})
+		return next()
+	}
+
+	chatManage.ImageQuery = strings.TrimSpace(reg.ReplaceAllString(response.Content, ""))
+	pipelineInfo(ctx, "ImageQuery", "output", map[string]interface{}{
+		"session_id":  chatManage.SessionID,
+		"image_query": chatManage.ImageQuery,
+	})
+	return next()
+}
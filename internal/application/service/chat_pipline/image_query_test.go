@@ -0,0 +1,121 @@
+package chatpipline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// recordingChat answers with a fixed response and records the messages it received
+type recordingChat struct {
+	content  string
+	err      error
+	messages []chat.Message
+}
+
+func (c *recordingChat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	c.messages = messages
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &types.ChatResponse{Content: c.content}, nil
+}
+
+func (c *recordingChat) ChatStream(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *recordingChat) GetModelName() string { return "mock-vlm" }
+
+func (c *recordingChat) GetModelID() string { return "vlm-1" }
+
+func TestPluginImageQuery(t *testing.T) {
+	image := "data:image/png;base64,aGVsbG8="
+	tests := []struct {
+		name    string
+		enabled bool
+		images  []string
+		model   *recordingChat
+		want    string
+		called  bool
+	}{
+		{
+			name:    "describes images",
+			enabled: true,
+			images:  []string{image},
+			model:   &recordingChat{content: "<think>reading</think> Error: connection refused on port 5432"},
+			want:    "Error: connection refused on port 5432",
+			called:  true,
+		},
+		{
+			name:    "disabled",
+			enabled: false,
+			images:  []string{image},
+			model:   &recordingChat{content: "unused"},
+		},
+		{
+			name:    "no images",
+			enabled: true,
+			model:   &recordingChat{content: "unused"},
+		},
+		{
+			name:    "model failure",
+			enabled: true,
+			images:  []string{image},
+			model:   &recordingChat{err: errors.New("vision model unavailable")},
+			called:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewEventManager()
+			NewPluginImageQuery(manager, &mockModelService{chat: tt.model})
+			chatManage := &types.ChatManage{
+				Query:            "why does the app fail to start?",
+				Images:           tt.images,
+				EnableImageQuery: tt.enabled,
+				ChatModelID:      "vlm-1",
+			}
+
+			if err := manager.Trigger(context.Background(), types.IMAGE_QUERY, chatManage); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if chatManage.ImageQuery != tt.want {
+				t.Errorf("ImageQuery = %q, want %q", chatManage.ImageQuery, tt.want)
+			}
+			if called := tt.model.messages != nil; called != tt.called {
+				t.Fatalf("Model called = %v, want %v", called, tt.called)
+			}
+			if tt.called {
+				last := tt.model.messages[len(tt.model.messages)-1]
+				if len(last.Images) != 1 || last.Images[0] != image || last.Content != chatManage.Query {
+					t.Errorf("Unexpected image query message: %+v", last)
+				}
+			}
+		})
+	}
+}
+
+func TestPrepareMessagesWithImages(t *testing.T) {
+	image := "https://example.com/screenshot.png"
+	messages := prepareMessagesWithHistory(&types.ChatManage{
+		UserContent: "What does the screenshot show?",
+		Images:      []string{image},
+		History:     []*types.History{{Query: "hi", Answer: "hello"}},
+	})
+
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(messages))
+	}
+	for _, message := range messages[:3] {
+		if len(message.Images) != 0 {
+			t.Errorf("Only the current user message should carry images, got %+v", message)
+		}
+	}
+	if last := messages[3]; last.Role != "user" || len(last.Images) != 1 || last.Images[0] != image {
+		t.Errorf("Unexpected current user message: %+v", last)
+	}
+}
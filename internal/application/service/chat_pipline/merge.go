@@ -3,22 +3,28 @@ package chatpipline
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
 
 // PluginMerge handles merging of search result chunks
 type PluginMerge struct {
-	chunkRepo interfaces.ChunkRepository
+	chunkRepo    interfaces.ChunkRepository
+	modelService interfaces.ModelService // Resolves the chat model whose tokenizer sizes the context
 }
 
 // NewPluginMerge creates and registers a new PluginMerge instance
-func NewPluginMerge(eventManager *EventManager, chunkRepo interfaces.ChunkRepository) *PluginMerge {
+func NewPluginMerge(eventManager *EventManager,
+	chunkRepo interfaces.ChunkRepository, modelService interfaces.ModelService,
+) *PluginMerge {
 	res := &PluginMerge{
-		chunkRepo: chunkRepo,
+		chunkRepo:    chunkRepo,
+		modelService: modelService,
 	}
 	eventManager.Register(res)
 	return res
@@ -73,6 +79,7 @@ func (p *PluginMerge) OnEvent(ctx context.Context,
 		"knowledge_cnt": len(knowledgeGroup),
 	})
 
+	trace := types.RetrievalTraceFromContext(ctx)
 	mergedChunks := []*types.SearchResult{}
 	// Process each knowledge source separately
 	for knowledgeID, chunkGroup := range knowledgeGroup {
@@ -117,6 +124,14 @@ func (p *PluginMerge) OnEvent(ctx context.Context,
 				if chunks[i].Score > lastChunk.Score {
 					lastChunk.Score = chunks[i].Score
 				}
+				if trace != nil {
+					trace.AddAction(lastChunk.ID, types.RetrievalActionMerged)
+					trace.Update(chunks[i].ID, func(c *types.CandidateTrace) {
+						c.MergedInto = lastChunk.ID
+					})
+					trace.Drop(chunks[i].ID, types.RetrievalStageMerge, types.DropReasonMerged,
+						fmt.Sprintf("overlaps chunk %s", lastChunk.ID))
+				}
 			}
 
 			pipelineInfo(ctx, "Merge", "group_output", map[string]interface{}{
@@ -286,6 +301,7 @@ func (p *PluginMerge) populateFAQAnswers(
 				continue
 			}
 			r.Content = content
+			types.RetrievalTraceFromContext(ctx).AddAction(r.ID, types.RetrievalActionFAQAnswer)
 			updated++
 		}
 	}
@@ -341,9 +357,10 @@ func (p *PluginMerge) expandShortContextWithNeighbors(
 	chatManage *types.ChatManage,
 	results []*types.SearchResult,
 ) []*types.SearchResult {
+	// Sizes of the expanded context in tokens of the chat model
 	const (
-		minLen = 350
-		maxLen = 850
+		minTokens = 256
+		maxTokens = 640
 	)
 
 	if len(results) == 0 || p.chunkRepo == nil {
@@ -360,6 +377,11 @@ func (p *PluginMerge) expandShortContextWithNeighbors(
 		})
 		return results
 	}
+	chatModelID := ""
+	if chatManage != nil {
+		chatModelID = chatManage.ChatModelID
+	}
+	tok := chatTokenizer(ctx, p.modelService, chatModelID)
 
 	type targetInfo struct {
 		result *types.SearchResult
@@ -375,7 +397,7 @@ func (p *PluginMerge) expandShortContextWithNeighbors(
 		if r.ChunkType != string(types.ChunkTypeText) {
 			continue
 		}
-		if runeLen(r.Content) >= minLen {
+		if tok.Count(r.Content) >= minTokens {
 			continue
 		}
 		targets = append(targets, targetInfo{result: r})
@@ -489,11 +511,11 @@ func (p *PluginMerge) expandShortContextWithNeighbors(
 
 		var merged string
 		for {
-			merged = mergeOrderedContent(prevContent, baseChunk.Content, nextContent, maxLen)
+			merged = mergeOrderedContent(tok, prevContent, baseChunk.Content, nextContent, maxTokens)
 			if merged == "" {
 				break
 			}
-			if runeLen(merged) >= minLen {
+			if tok.Count(merged) >= minTokens {
 				break
 			}
 			if prevCursor == "" && nextCursor == "" {
@@ -514,8 +536,8 @@ func (p *PluginMerge) expandShortContextWithNeighbors(
 				}
 			}
 
-			merged = mergeOrderedContent(prevContent, baseChunk.Content, nextContent, maxLen)
-			if runeLen(merged) >= minLen {
+			merged = mergeOrderedContent(tok, prevContent, baseChunk.Content, nextContent, maxTokens)
+			if tok.Count(merged) >= minTokens {
 				break
 			}
 
@@ -543,6 +565,7 @@ func (p *PluginMerge) expandShortContextWithNeighbors(
 
 		beforeLen := runeLen(res.Content)
 		res.Content = merged
+		types.RetrievalTraceFromContext(ctx).AddAction(res.ID, types.RetrievalActionNeighborExpand)
 
 		for _, id := range prevIDs {
 			if id != "" && !containsID(res.SubChunkID, id) {
@@ -585,8 +608,8 @@ func runeLen(s string) int {
 	return len([]rune(s))
 }
 
-// mergeOrderedContent merges ordered content
-func mergeOrderedContent(prev, base, next string, maxLen int) string {
+// mergeOrderedContent merges ordered content, truncated to maxTokens
+func mergeOrderedContent(tok tokenizer.Tokenizer, prev, base, next string, maxTokens int) string {
 	content := base
 	if prev != "" {
 		content = concatNoOverlap(prev, content)
@@ -594,11 +617,7 @@ func mergeOrderedContent(prev, base, next string, maxLen int) string {
 	if next != "" {
 		content = concatNoOverlap(content, next)
 	}
-	runes := []rune(content)
-	if len(runes) > maxLen {
-		return string(runes[:maxLen])
-	}
-	return content
+	return tok.Truncate(content, maxTokens)
 }
 
 // concatNoOverlap concatenates two strings, removing potential overlapping prefix/suffix
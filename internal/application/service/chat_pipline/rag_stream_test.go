@@ -0,0 +1,127 @@
+package chatpipline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// mockModelService serves the mock models whatever the model ID
+type mockModelService struct {
+	interfaces.ModelService
+	chat     chat.Chat
+	reranker rerank.Reranker
+}
+
+func (s *mockModelService) GetChatModel(ctx context.Context, modelID string) (chat.Chat, error) {
+	return s.chat, nil
+}
+
+func (s *mockModelService) GetModelByID(ctx context.Context, modelID string) (*types.Model, error) {
+	return &types.Model{ID: modelID, Name: s.chat.GetModelName()}, nil
+}
+
+func (s *mockModelService) GetRerankModel(ctx context.Context, modelID string) (rerank.Reranker, error) {
+	return s.reranker, nil
+}
+
+// emptyChunkRepository has no chunks beyond the search results
+type emptyChunkRepository struct {
+	interfaces.ChunkRepository
+}
+
+func (emptyChunkRepository) ListChunksByID(ctx context.Context, tenantID uint64, ids []string) ([]*types.Chunk, error) {
+	return nil, nil
+}
+
+func TestRAGStreamWithMockModels(t *testing.T) {
+	chatModel, err := chat.NewChat(&chat.ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "mock",
+		BaseURL:   "testdata/mock_rag.json",
+		ModelName: "mock-chat",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reranker, err := rerank.NewReranker(&rerank.RerankerConfig{Provider: "mock", ModelName: "mock-rerank"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	modelService := &mockModelService{chat: chatModel, reranker: reranker}
+
+	// Retrieval needs knowledge bases, so the search results are given and the search plugins left out
+	manager := NewEventManager()
+	NewPluginGuardrail(manager, modelService)
+	NewPluginRerank(manager, modelService)
+	NewPluginMerge(manager, emptyChunkRepository{}, modelService)
+	NewPluginFilterTopK(manager)
+	NewPluginDataAnalysis(manager, modelService, nil, nil, nil)
+	NewPluginIntoChatMessage(manager)
+	NewPluginChatCompletionStream(manager, modelService)
+	NewPluginStreamFilter(manager)
+
+	eventBus := event.NewEventBus()
+	answers := make(chan event.AgentFinalAnswerData, 100)
+	eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		answers <- evt.Data.(event.AgentFinalAnswerData)
+		return nil
+	})
+
+	query := "refund processing days"
+	chatManage := &types.ChatManage{
+		SessionID:       "session-1",
+		Query:           query,
+		RewriteQuery:    query,
+		RerankModelID:   "rerank-1",
+		RerankTopK:      2,
+		RerankThreshold: 0.5,
+		ChatModelID:     "chat-1",
+		SummaryConfig: types.SummaryConfig{
+			Prompt:          "Answer from the documents.",
+			ContextTemplate: "{{contexts}}\nQuestion: {{query}}",
+		},
+		SearchResult: []*types.SearchResult{
+			{ID: "chunk-1", KnowledgeID: "knowledge-1", Content: "Shipping is free for orders over $50.", Score: 0.9},
+			{ID: "chunk-2", KnowledgeID: "knowledge-2", Content: "Refund processing takes 5 business days.", Score: 0.6},
+			{ID: "chunk-3", KnowledgeID: "knowledge-3", Content: "Refunds go back to the original payment method.", Score: 0.7},
+		},
+		EventBus: eventBus.AsEventBusInterface(),
+	}
+
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	for _, eventType := range types.Pipline["rag_stream"] {
+		if err := manager.Trigger(ctx, eventType, chatManage); err != nil {
+			t.Fatalf("Event %s failed: %v", eventType, err)
+		}
+	}
+
+	if len(chatManage.MergeResult) != 1 || chatManage.MergeResult[0].ID != "chunk-2" {
+		t.Fatalf("Expected only the relevant chunk to be kept, got %+v", chatManage.MergeResult)
+	}
+	if !strings.HasPrefix(chatManage.UserContent, "[1] Refund processing takes 5 business days.") {
+		t.Errorf("Unexpected user content: %q", chatManage.UserContent)
+	}
+
+	var answer strings.Builder
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case data := <-answers:
+			answer.WriteString(data.Content)
+			done = data.Done
+		case <-timeout:
+			t.Fatalf("Timed out waiting for the answer, got %q", answer.String())
+		}
+	}
+	if got, want := answer.String(), "Based on the documents: Refund processing takes 5 business days."; got != want {
+		t.Errorf("Answer = %q, want %q", got, want)
+	}
+}
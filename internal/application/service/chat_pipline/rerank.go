@@ -92,12 +92,19 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 	})
 
 	var rerankResp []rerank.RankResult
+	trace := types.RetrievalTraceFromContext(ctx)
 
 	// Only call rerank model if there are candidates
 	if len(candidatesToRerank) > 0 {
 		// Single rerank call with RewriteQuery, use threshold degradation if no results
+		// Candidates retrieved for the attached images are scored against their description too
+		rerankQuery := chatManage.RewriteQuery
+		if chatManage.ImageQuery != "" {
+			rerankQuery += "\n" + chatManage.ImageQuery
+		}
 		originalThreshold := chatManage.RerankThreshold
-		rerankResp = p.rerank(ctx, chatManage, rerankModel, chatManage.RewriteQuery, passages, candidatesToRerank)
+		appliedThreshold := originalThreshold
+		rerankResp = p.rerank(ctx, chatManage, rerankModel, rerankQuery, passages, candidatesToRerank)
 
 		// If no results and threshold is high enough, try with lower threshold
 		if len(rerankResp) == 0 && originalThreshold > 0.3 {
@@ -110,10 +117,15 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 				"degraded": degradedThreshold,
 			})
 			chatManage.RerankThreshold = degradedThreshold
-			rerankResp = p.rerank(ctx, chatManage, rerankModel, chatManage.RewriteQuery, passages, candidatesToRerank)
+			appliedThreshold = degradedThreshold
+			rerankResp = p.rerank(ctx, chatManage, rerankModel, rerankQuery, passages, candidatesToRerank)
 			// Restore original threshold
 			chatManage.RerankThreshold = originalThreshold
+			for _, rr := range rerankResp {
+				trace.AddAction(candidatesToRerank[rr.Index].ID, types.RetrievalActionThresholdLower)
+			}
 		}
+		traceRerankDrops(trace, candidatesToRerank, rerankResp, appliedThreshold)
 	}
 
 	pipelineInfo(ctx, "Rerank", "model_response", map[string]interface{}{
@@ -145,6 +157,9 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 		sr.Metadata["base_score"] = fmt.Sprintf("%.4f", base)
 		modelScore := rr.RelevanceScore
 		sr.Score = compositeScore(sr, modelScore, base)
+		trace.Update(sr.ID, func(c *types.CandidateTrace) {
+			c.CompositeScore = types.Float64Ptr(sr.Score)
+		})
 
 		// Apply FAQ score boost if enabled
 		if chatManage.FAQPriorityEnabled && chatManage.FAQScoreBoost > 1.0 &&
@@ -153,6 +168,9 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 			sr.Score = math.Min(sr.Score*chatManage.FAQScoreBoost, 1.0)
 			sr.Metadata["faq_boosted"] = "true"
 			sr.Metadata["faq_original_score"] = fmt.Sprintf("%.4f", originalScore)
+			trace.Update(sr.ID, func(c *types.CandidateTrace) {
+				c.FAQBoost = chatManage.FAQScoreBoost
+			})
 			pipelineInfo(ctx, "Rerank", "faq_boost", map[string]interface{}{
 				"chunk_id":       sr.ID,
 				"original_score": fmt.Sprintf("%.4f", originalScore),
@@ -178,6 +196,9 @@ func (p *PluginRerank) OnEvent(ctx context.Context,
 		// Assign high model score for direct load items
 		modelScore := 1.0
 		sr.Score = compositeScore(sr, modelScore, base)
+		trace.Update(sr.ID, func(c *types.CandidateTrace) {
+			c.CompositeScore = types.Float64Ptr(sr.Score)
+		})
 		pipelineInfo(ctx, "Rerank", "composite_calc_direct", map[string]interface{}{
 			"chunk_id":    sr.ID,
 			"base_score":  fmt.Sprintf("%.4f", base),
@@ -231,6 +252,16 @@ func (p *PluginRerank) rerank(ctx context.Context,
 		})
 		return nil
 	}
+	if trace := types.RetrievalTraceFromContext(ctx); trace != nil {
+		for _, rr := range rerankResp {
+			if rr.Index < len(candidates) {
+				score := rr.RelevanceScore
+				trace.Update(candidates[rr.Index].ID, func(c *types.CandidateTrace) {
+					c.RerankScore = &score
+				})
+			}
+		}
+	}
 
 	// Log top scores for debugging
 	pipelineInfo(ctx, "Rerank", "threshold", map[string]interface{}{
@@ -267,6 +298,35 @@ func (p *PluginRerank) rerank(ctx context.Context,
 	return rankFilter
 }
 
+// traceRerankDrops marks the candidates filtered out by the rerank threshold as dropped
+func traceRerankDrops(trace *types.RetrievalTrace,
+	candidates []*types.SearchResult, kept []rerank.RankResult, threshold float64,
+) {
+	if trace == nil {
+		return
+	}
+	keptIdx := make(map[int]struct{}, len(kept))
+	for _, rr := range kept {
+		keptIdx[rr.Index] = struct{}{}
+	}
+	for i, sr := range candidates {
+		if _, ok := keptIdx[i]; ok {
+			continue
+		}
+		trace.Update(sr.ID, func(c *types.CandidateTrace) {
+			if c.DropReason != "" {
+				return
+			}
+			c.DropStage, c.DropReason = types.RetrievalStageRerank, types.DropReasonThreshold
+			if c.RerankScore == nil {
+				c.DropDetail = "no rerank score, the rerank model call failed"
+			} else {
+				c.DropDetail = fmt.Sprintf("rerank score not above threshold %.2f", threshold)
+			}
+		})
+	}
+}
+
 // ensureMetadata ensures the metadata is not nil
 func ensureMetadata(m map[string]string) map[string]string {
 	if m == nil {
@@ -376,6 +436,23 @@ func applyMMR(
 			avgRed /= float64(pairs)
 		}
 	}
+	if trace := types.RetrievalTraceFromContext(ctx); trace != nil {
+		for i, r := range results {
+			_, isSelected := selectedIndices[i]
+			trace.Update(r.ID, func(c *types.CandidateTrace) {
+				c.MMRSelected = &isSelected
+			})
+			if !isSelected {
+				trace.Drop(r.ID, types.RetrievalStageRerank, types.DropReasonMMR,
+					fmt.Sprintf("not among the %d results selected by MMR (lambda %.2f)", k, lambda))
+			}
+		}
+		for rank, r := range selected {
+			trace.Update(r.ID, func(c *types.CandidateTrace) {
+				c.MMRRank = rank + 1
+			})
+		}
+	}
 	pipelineInfo(ctx, "Rerank", "mmr_done", map[string]interface{}{
 		"selected":       len(selected),
 		"avg_redundancy": fmt.Sprintf("%.4f", avgRed),
@@ -125,6 +125,8 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 		})
 	}
 
+	trace := types.RetrievalTraceFromContext(ctx)
+
 	// If recall is low, attempt query expansion with keyword-focused search
 	if chatManage.EnableQueryExpansion && len(chatManage.SearchResult) < max(1, chatManage.EmbeddingTopK/2) {
 		pipelineInfo(ctx, "Search", "recall_low", map[string]interface{}{
@@ -132,6 +134,7 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 			"threshold": chatManage.EmbeddingTopK / 2,
 		})
 		expansions := p.expandQueries(ctx, chatManage)
+		trace.AddExpandedQueries(expansions...)
 		if len(expansions) > 0 {
 			pipelineInfo(ctx, "Search", "expansion_start", map[string]interface{}{
 				"variants": len(expansions),
@@ -188,6 +191,9 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 								"query": q,
 								"hits":  len(res),
 							})
+							for _, r := range res {
+								trace.AddAction(r.ID, types.RetrievalActionQueryExpansion)
+							}
 							muExp.Lock()
 							expResults = append(expResults, res...)
 							muExp.Unlock()
@@ -213,12 +219,18 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 			"session_id":   chatManage.SessionID,
 			"history_hits": len(historyResult),
 		})
+		for _, r := range historyResult {
+			trace.AddAction(r.ID, types.RetrievalActionHistory)
+		}
 		chatManage.SearchResult = append(chatManage.SearchResult, historyResult...)
 	}
 
 	// Remove duplicate results
 	before := len(chatManage.SearchResult)
-	chatManage.SearchResult = removeDuplicateResults(chatManage.SearchResult)
+	deduped := removeDuplicateResults(chatManage.SearchResult)
+	traceDroppedResults(trace, types.RetrievalStageSearch, chatManage.SearchResult, deduped,
+		types.DropReasonDedup, "same chunk, parent chunk or content as a higher ranked result")
+	chatManage.SearchResult = deduped
 	pipelineInfo(ctx, "Search", "dedup_summary", map[string]interface{}{
 		"before": before,
 		"after":  len(chatManage.SearchResult),
@@ -226,6 +238,7 @@ func (p *PluginSearch) OnEvent(ctx context.Context,
 
 	// Log final scores after all processing
 	for i, r := range chatManage.SearchResult {
+		trace.RecordResult(r)
 		pipelineInfo(ctx, "Search", "final_score", map[string]interface{}{
 			"index":      i,
 			"chunk_id":   r.ID,
@@ -305,6 +318,24 @@ func removeDuplicateResults(results []*types.SearchResult) []*types.SearchResult
 	return uniqueResults
 }
 
+// traceDroppedResults marks the results of before that are missing from after as dropped
+func traceDroppedResults(trace *types.RetrievalTrace, stage string,
+	before, after []*types.SearchResult, reason, detail string,
+) {
+	if trace == nil {
+		return
+	}
+	kept := make(map[string]struct{}, len(after))
+	for _, r := range after {
+		kept[r.ID] = struct{}{}
+	}
+	for _, r := range before {
+		if _, ok := kept[r.ID]; !ok {
+			trace.Drop(r.ID, stage, reason, detail)
+		}
+	}
+}
+
 func buildContentSignature(content string) string {
 	return searchutil.BuildContentSignature(content)
 }
@@ -338,6 +369,9 @@ func (p *PluginSearch) searchByTargets(
 				directResults, skippedIDs := p.tryDirectChunkLoading(ctx, chatManage.TenantID, t.KnowledgeIDs)
 
 				if len(directResults) > 0 {
+					for _, r := range directResults {
+						types.RetrievalTraceFromContext(ctx).AddAction(r.ID, types.RetrievalActionDirectLoad)
+					}
 					pipelineInfo(ctx, "Search", "direct_load", map[string]interface{}{
 						"kb_id":        t.KnowledgeBaseID,
 						"loaded_count": len(directResults),
@@ -362,35 +396,47 @@ func (p *PluginSearch) searchByTargets(
 				return
 			}
 
-			// Build params for rewrite query
-			params := types.SearchParams{
-				QueryText:        strings.TrimSpace(chatManage.RewriteQuery),
-				VectorThreshold:  chatManage.VectorThreshold,
-				KeywordThreshold: chatManage.KeywordThreshold,
-				MatchCount:       chatManage.EmbeddingTopK,
-			}
-			// Apply knowledge ID filter if this is a partial KB search
-			if t.Type == types.SearchTargetTypeKnowledge {
-				params.KnowledgeIDs = searchKnowledgeIDs
+			// Search with the rewritten query, and with the description of the attached images
+			queries := []string{strings.TrimSpace(chatManage.RewriteQuery)}
+			if chatManage.ImageQuery != "" {
+				queries = append(queries, chatManage.ImageQuery)
 			}
-			res, err := p.knowledgeBaseService.HybridSearch(ctx, t.KnowledgeBaseID, params)
-			if err != nil {
-				pipelineWarn(ctx, "Search", "kb_search_error", map[string]interface{}{
+			for i, query := range queries {
+				params := types.SearchParams{
+					QueryText:        query,
+					VectorThreshold:  chatManage.VectorThreshold,
+					KeywordThreshold: chatManage.KeywordThreshold,
+					MatchCount:       chatManage.EmbeddingTopK,
+				}
+				// Apply knowledge ID filter if this is a partial KB search
+				if t.Type == types.SearchTargetTypeKnowledge {
+					params.KnowledgeIDs = searchKnowledgeIDs
+				}
+				res, err := p.knowledgeBaseService.HybridSearch(ctx, t.KnowledgeBaseID, params)
+				if err != nil {
+					pipelineWarn(ctx, "Search", "kb_search_error", map[string]interface{}{
+						"kb_id":       t.KnowledgeBaseID,
+						"target_type": t.Type,
+						"query":       params.QueryText,
+						"error":       err.Error(),
+					})
+					continue
+				}
+				pipelineInfo(ctx, "Search", "kb_result", map[string]interface{}{
 					"kb_id":       t.KnowledgeBaseID,
 					"target_type": t.Type,
-					"query":       params.QueryText,
-					"error":       err.Error(),
+					"image_query": i > 0,
+					"hit_count":   len(res),
 				})
-				return
+				if i > 0 {
+					for _, r := range res {
+						types.RetrievalTraceFromContext(ctx).AddAction(r.ID, types.RetrievalActionImageQuery)
+					}
+				}
+				mu.Lock()
+				results = append(results, res...)
+				mu.Unlock()
 			}
-			pipelineInfo(ctx, "Search", "kb_result", map[string]interface{}{
-				"kb_id":       t.KnowledgeBaseID,
-				"target_type": t.Type,
-				"hit_count":   len(res),
-			})
-			mu.Lock()
-			results = append(results, res...)
-			mu.Unlock()
 		}(target)
 	}
 
@@ -153,8 +153,15 @@ func (p *PluginSearchParallel) OnEvent(ctx context.Context,
 	wg.Wait()
 
 	// Merge results from both searches (no concurrent access now)
-	chatManage.SearchResult = append(chunkChatManage.SearchResult, entityChatManage.SearchResult...)
-	chatManage.SearchResult = removeDuplicateResults(chatManage.SearchResult)
+	combined := append(chunkChatManage.SearchResult, entityChatManage.SearchResult...)
+	chatManage.SearchResult = removeDuplicateResults(combined)
+	if trace := types.RetrievalTraceFromContext(ctx); trace != nil {
+		traceDroppedResults(trace, types.RetrievalStageSearch, combined, chatManage.SearchResult,
+			types.DropReasonDedup, "duplicate of a chunk or graph search result")
+		for _, r := range chatManage.SearchResult {
+			trace.RecordResult(r)
+		}
+	}
 
 	// Log any errors but don't fail the pipeline if at least one search succeeded
 	if chunkSearchErr != nil {
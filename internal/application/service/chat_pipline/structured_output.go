@@ -0,0 +1,178 @@
+package chatpipline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/Tencent/WeKnora/internal/utils"
+	"github.com/google/uuid"
+)
+
+// structuredOutputRepairPrompt asks the model to fix a JSON answer that failed validation
+const structuredOutputRepairPrompt = `You repair JSON documents so that they conform to a JSON Schema.
+Keep the information of the original document, fix only what the validation error reports.
+Output only the corrected JSON document, without explanations or markdown.`
+
+// PluginStructuredOutput validates the model answer against the requested JSON Schema,
+// repairs it with one extra model call when needed, and emits the parsed object
+type PluginStructuredOutput struct {
+	modelService interfaces.ModelService
+}
+
+// NewPluginStructuredOutput creates a new structured output plugin and registers it with the EventManager
+func NewPluginStructuredOutput(eventManager *EventManager,
+	modelService interfaces.ModelService,
+) *PluginStructuredOutput {
+	res := &PluginStructuredOutput{
+		modelService: modelService,
+	}
+	eventManager.Register(res)
+	return res
+}
+
+// ActivationEvents returns the event types this plugin handles
+func (p *PluginStructuredOutput) ActivationEvents() []types.EventType {
+	return []types.EventType{types.STRUCTURED_OUTPUT}
+}
+
+// OnEvent handles structured output events in the chat pipeline
+func (p *PluginStructuredOutput) OnEvent(ctx context.Context,
+	eventType types.EventType, chatManage *types.ChatManage, next func() *PluginError,
+) *PluginError {
+	if len(chatManage.ResponseSchema) == 0 || chatManage.ChatResponse == nil {
+		return next()
+	}
+
+	content := chatManage.ChatResponse.Content
+	output := parseStructuredOutput(chatManage.ResponseSchema, content)
+	if !output.Valid {
+		pipelineWarn(ctx, "StructuredOutput", "invalid_output", map[string]interface{}{
+			"session_id": chatManage.SessionID,
+			"error":      output.Error,
+		})
+		if repaired, err := p.repair(ctx, chatManage, content, output.Error); err != nil {
+			pipelineError(ctx, "StructuredOutput", "repair", map[string]interface{}{
+				"session_id": chatManage.SessionID,
+				"error":      err.Error(),
+			})
+		} else {
+			if repairedOutput := parseStructuredOutput(chatManage.ResponseSchema, repaired); repairedOutput.Valid {
+				repairedOutput.Repaired = true
+				output = repairedOutput
+			}
+		}
+	}
+
+	pipelineInfo(ctx, "StructuredOutput", "output", map[string]interface{}{
+		"session_id": chatManage.SessionID,
+		"valid":      output.Valid,
+		"repaired":   output.Repaired,
+	})
+	chatManage.StructuredOutput = output
+	chatManage.ChatResponse.Content = string(output.Object)
+
+	p.emit(ctx, chatManage)
+	return next()
+}
+
+// parseStructuredOutput extracts the JSON document from the model answer and validates it
+func parseStructuredOutput(schema json.RawMessage, content string) *types.StructuredOutput {
+	raw := utils.ExtractJSON(content)
+	if _, err := utils.ValidateJSONSchema(schema, []byte(raw)); err != nil {
+		output := &types.StructuredOutput{Error: err.Error()}
+		if json.Valid([]byte(raw)) {
+			output.Object = json.RawMessage(raw)
+		} else {
+			// Keep the raw answer as a JSON string so the object is always valid JSON
+			output.Object, _ = json.Marshal(content)
+		}
+		return output
+	}
+	return &types.StructuredOutput{Object: json.RawMessage(raw), Valid: true}
+}
+
+// repair asks the chat model to fix the answer according to the validation error
+func (p *PluginStructuredOutput) repair(ctx context.Context,
+	chatManage *types.ChatManage, content string, validationError string,
+) (string, error) {
+	chatModel, err := p.modelService.GetChatModel(ctx, chatManage.ChatModelID)
+	if err != nil {
+		return "", err
+	}
+
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{Role: "system", Content: structuredOutputRepairPrompt},
+		{Role: "user", Content: fmt.Sprintf("JSON Schema:\n%s\n\nDocument:\n%s\n\nValidation error: %s",
+			chatManage.ResponseSchema, content, validationError)},
+	}, &chat.ChatOptions{
+		Temperature: 0,
+		Thinking:    &thinking,
+		Format:      chatManage.ResponseSchema,
+	})
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// emit sends references, the structured output and the JSON answer to the event bus.
+// References are emitted here so that they arrive before the final answer completes the stream.
+func (p *PluginStructuredOutput) emit(ctx context.Context, chatManage *types.ChatManage) {
+	eventBus := chatManage.EventBus
+	if eventBus == nil {
+		return
+	}
+	prefix := uuid.New().String()[:8]
+
+	if len(chatManage.MergeResult) > 0 {
+		if err := eventBus.Emit(ctx, types.Event{
+			ID:        fmt.Sprintf("%s-references", prefix),
+			Type:      types.EventType(event.EventAgentReferences),
+			SessionID: chatManage.SessionID,
+			Data: event.AgentReferencesData{
+				References: chatManage.MergeResult,
+			},
+		}); err != nil {
+			pipelineError(ctx, "StructuredOutput", "emit_references", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	output := chatManage.StructuredOutput
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-structured", prefix),
+		Type:      types.EventType(event.EventStructuredOutput),
+		SessionID: chatManage.SessionID,
+		Data: event.StructuredOutputData{
+			Object:   output.Object,
+			Valid:    output.Valid,
+			Repaired: output.Repaired,
+			Error:    output.Error,
+		},
+	}); err != nil {
+		pipelineError(ctx, "StructuredOutput", "emit_output", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if err := eventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-answer", prefix),
+		Type:      types.EventType(event.EventAgentFinalAnswer),
+		SessionID: chatManage.SessionID,
+		Data: event.AgentFinalAnswerData{
+			Content: string(output.Object),
+			Done:    true,
+		},
+	}); err != nil {
+		pipelineError(ctx, "StructuredOutput", "emit_answer", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package chatpipline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func TestParseStructuredOutput(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {"name": {"type": "string"}, "age": {"type": "integer"}},
+		"required": ["name", "age"]
+	}`)
+
+	output := parseStructuredOutput(schema, "```json\n{\"name\": \"Alice\", \"age\": 30}\n```")
+	if !output.Valid {
+		t.Fatalf("Expected valid output, got error %q", output.Error)
+	}
+	if string(output.Object) != `{"name": "Alice", "age": 30}` {
+		t.Errorf("Unexpected object: %s", output.Object)
+	}
+
+	output = parseStructuredOutput(schema, `Here you go: {"name": "Alice"}`)
+	if output.Valid || output.Error == "" {
+		t.Errorf("Expected validation error for missing field, got %+v", output)
+	}
+	if string(output.Object) != `{"name": "Alice"}` {
+		t.Errorf("Expected invalid object to be kept, got %s", output.Object)
+	}
+
+	output = parseStructuredOutput(schema, "not json")
+	if output.Valid || !json.Valid(output.Object) {
+		t.Errorf("Expected invalid output with JSON string object, got %+v", output)
+	}
+}
+
+func TestPluginStructuredOutput(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"properties": {"name": {"type": "string"}, "age": {"type": "integer"}},
+		"required": ["name", "age"]
+	}`)
+
+	tests := []struct {
+		name         string
+		answer       string
+		repair       *recordingChat
+		wantRepair   bool
+		wantValid    bool
+		wantRepaired bool
+		wantContent  string
+	}{
+		{
+			name:        "valid answer",
+			answer:      "```json\n{\"name\": \"Alice\", \"age\": 30}\n```",
+			repair:      &recordingChat{},
+			wantValid:   true,
+			wantContent: `{"name": "Alice", "age": 30}`,
+		},
+		{
+			name:         "repaired answer",
+			answer:       `{"name": "Alice", "age": "thirty"}`,
+			repair:       &recordingChat{content: `{"name": "Alice", "age": 30}`},
+			wantRepair:   true,
+			wantValid:    true,
+			wantRepaired: true,
+			wantContent:  `{"name": "Alice", "age": 30}`,
+		},
+		{
+			name:        "repair still invalid",
+			answer:      `{"name": "Alice", "age": "thirty"}`,
+			repair:      &recordingChat{content: `{"name": "Alice"}`},
+			wantRepair:  true,
+			wantContent: `{"name": "Alice", "age": "thirty"}`,
+		},
+		{
+			name:        "repair failed",
+			answer:      `{"name": "Alice", "age": "thirty"}`,
+			repair:      &recordingChat{err: errors.New("model unavailable")},
+			wantRepair:  true,
+			wantContent: `{"name": "Alice", "age": "thirty"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewEventManager()
+			NewPluginStructuredOutput(manager, &mockModelService{chat: tt.repair})
+
+			bus := event.NewEventBus()
+			var outputs []event.StructuredOutputData
+			var answers []string
+			bus.On(event.EventStructuredOutput, func(ctx context.Context, evt event.Event) error {
+				outputs = append(outputs, evt.Data.(event.StructuredOutputData))
+				return nil
+			})
+			bus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+				answers = append(answers, evt.Data.(event.AgentFinalAnswerData).Content)
+				return nil
+			})
+
+			chatManage := &types.ChatManage{
+				ResponseSchema: schema,
+				ChatResponse:   &types.ChatResponse{Content: tt.answer},
+				EventBus:       bus.AsEventBusInterface(),
+			}
+			if err := manager.Trigger(context.Background(), types.STRUCTURED_OUTPUT, chatManage); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if repaired := tt.repair.messages != nil; repaired != tt.wantRepair {
+				t.Errorf("Repair called = %v, want %v", repaired, tt.wantRepair)
+			}
+			if tt.wantRepair && !strings.Contains(tt.repair.messages[1].Content, "Validation error") {
+				t.Errorf("Expected the validation error in the repair prompt, got %q", tt.repair.messages[1].Content)
+			}
+			output := chatManage.StructuredOutput
+			if output == nil {
+				t.Fatal("Expected a structured output")
+			}
+			if output.Valid != tt.wantValid || output.Repaired != tt.wantRepaired {
+				t.Errorf("Valid = %v, Repaired = %v, want %v, %v", output.Valid, output.Repaired, tt.wantValid, tt.wantRepaired)
+			}
+			if !tt.wantValid && output.Error == "" {
+				t.Error("Expected the validation error of an invalid output")
+			}
+			if chatManage.ChatResponse.Content != tt.wantContent {
+				t.Errorf("Content = %s, want %s", chatManage.ChatResponse.Content, tt.wantContent)
+			}
+			if len(outputs) != 1 || outputs[0].Valid != tt.wantValid {
+				t.Errorf("Expected one structured output event with valid=%v, got %+v", tt.wantValid, outputs)
+			}
+			if len(answers) != 1 || answers[0] != tt.wantContent {
+				t.Errorf("Expected the JSON answer to complete the stream, got %v", answers)
+			}
+		})
+	}
+}
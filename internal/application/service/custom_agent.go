@@ -10,6 +10,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +20,10 @@ var (
 	ErrCannotModifyBuiltin = errors.New("cannot modify built-in agent basic info")
 	ErrCannotDeleteBuiltin = errors.New("cannot delete built-in agent")
 	ErrAgentNameRequired   = errors.New("agent name is required")
+	ErrInvalidGuardrail    = errors.New("invalid guardrail configuration")
+	ErrInvalidToolBudget   = errors.New("invalid tool budget configuration")
+	ErrInvalidMCPPolicy    = errors.New("invalid MCP tool policy")
+	ErrInvalidSubAgents    = errors.New("invalid sub-agent configuration")
 )
 
 // customAgentService implements the CustomAgentService interface
@@ -39,6 +44,20 @@ func (s *customAgentService) CreateAgent(ctx context.Context, agent *types.Custo
 	if strings.TrimSpace(agent.Name) == "" {
 		return nil, ErrAgentNameRequired
 	}
+	if err := agent.Config.Guardrail.Validate(); err != nil {
+		logger.Warnf(ctx, "Invalid guardrail configuration: %v", err)
+		return nil, ErrInvalidGuardrail
+	}
+	if err := agent.Config.ToolBudget.Validate(); err != nil {
+		logger.Warnf(ctx, "Invalid tool budget configuration: %v", err)
+		return nil, ErrInvalidToolBudget
+	}
+	for serviceID, policy := range agent.Config.MCPToolPolicies {
+		if err := policy.Validate(); err != nil {
+			logger.Warnf(ctx, "Invalid tool policy of MCP service %s: %v", serviceID, err)
+			return nil, ErrInvalidMCPPolicy
+		}
+	}
 
 	// Generate UUID and set creation timestamps
 	if agent.ID == "" {
@@ -52,6 +71,10 @@ func (s *customAgentService) CreateAgent(ctx context.Context, agent *types.Custo
 	}
 	agent.TenantID = tenantID
 
+	if err := s.validateSubAgents(ctx, agent.ID, tenantID, agent.Config.SubAgents); err != nil {
+		return nil, err
+	}
+
 	// Set timestamps
 	agent.CreatedAt = time.Now()
 	agent.UpdatedAt = time.Now()
@@ -193,6 +216,25 @@ func (s *customAgentService) UpdateAgent(ctx context.Context, agent *types.Custo
 		return nil, ErrInvalidTenantID
 	}
 
+	if err := agent.Config.Guardrail.Validate(); err != nil {
+		logger.Warnf(ctx, "Invalid guardrail configuration: %v", err)
+		return nil, ErrInvalidGuardrail
+	}
+	if err := agent.Config.ToolBudget.Validate(); err != nil {
+		logger.Warnf(ctx, "Invalid tool budget configuration: %v", err)
+		return nil, ErrInvalidToolBudget
+	}
+	for serviceID, policy := range agent.Config.MCPToolPolicies {
+		if err := policy.Validate(); err != nil {
+			logger.Warnf(ctx, "Invalid tool policy of MCP service %s: %v", serviceID, err)
+			return nil, ErrInvalidMCPPolicy
+		}
+	}
+
+	if err := s.validateSubAgents(ctx, agent.ID, tenantID, agent.Config.SubAgents); err != nil {
+		return nil, err
+	}
+
 	// Handle built-in agents specially using registry
 	if types.IsBuiltinAgentID(agent.ID) {
 		return s.updateBuiltinAgent(ctx, agent, tenantID)
@@ -240,6 +282,37 @@ func (s *customAgentService) UpdateAgent(ctx context.Context, agent *types.Custo
 	return existingAgent, nil
 }
 
+// validateSubAgents checks that the agents an agent delegates to are other agents of its tenant
+func (s *customAgentService) validateSubAgents(
+	ctx context.Context,
+	agentID string,
+	tenantID uint64,
+	config *types.SubAgentConfig,
+) error {
+	if !config.IsActive() {
+		return nil
+	}
+	for _, id := range config.AllowedAgents {
+		if id == agentID {
+			logger.Warnf(ctx, "Agent %s cannot delegate to itself", secutils.SanitizeForLog(agentID))
+			return ErrInvalidSubAgents
+		}
+		if types.IsBuiltinAgentID(id) && types.GetBuiltinAgent(id, tenantID) != nil {
+			continue
+		}
+		// Agents are looked up within the tenant, those of other tenants are unknown
+		if _, err := s.repo.GetAgentByID(ctx, id, tenantID); err != nil {
+			if errors.Is(err, repository.ErrCustomAgentNotFound) {
+				logger.Warnf(ctx, "Unknown sub-agent %s of agent %s",
+					secutils.SanitizeForLog(id), secutils.SanitizeForLog(agentID))
+				return ErrInvalidSubAgents
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // updateBuiltinAgent updates a built-in agent's configuration (but not basic info)
 func (s *customAgentService) updateBuiltinAgent(ctx context.Context, agent *types.CustomAgent, tenantID uint64) (*types.CustomAgent, error) {
 	// Get the default built-in agent from registry
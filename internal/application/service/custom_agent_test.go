@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/application/repository"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// memoryCustomAgentRepo stores agents in memory, isolated by tenant
+type memoryCustomAgentRepo struct {
+	interfaces.CustomAgentRepository
+	agents map[string]*types.CustomAgent
+}
+
+func (r *memoryCustomAgentRepo) CreateAgent(ctx context.Context, agent *types.CustomAgent) error {
+	r.agents[agent.ID] = agent
+	return nil
+}
+
+func (r *memoryCustomAgentRepo) UpdateAgent(ctx context.Context, agent *types.CustomAgent) error {
+	r.agents[agent.ID] = agent
+	return nil
+}
+
+func (r *memoryCustomAgentRepo) GetAgentByID(ctx context.Context,
+	id string, tenantID uint64,
+) (*types.CustomAgent, error) {
+	agent, ok := r.agents[id]
+	if !ok || agent.TenantID != tenantID {
+		return nil, repository.ErrCustomAgentNotFound
+	}
+	return agent, nil
+}
+
+func TestCustomAgentSubAgentValidation(t *testing.T) {
+	repo := &memoryCustomAgentRepo{agents: map[string]*types.CustomAgent{
+		"parent":  {ID: "parent", Name: "Parent", TenantID: 1},
+		"analyst": {ID: "analyst", Name: "Analyst", TenantID: 1},
+		"foreign": {ID: "foreign", Name: "Foreign", TenantID: 2},
+	}}
+	s := NewCustomAgentService(repo)
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	// selfID stands for the ID of the agent being saved
+	const selfID = "$self"
+	tests := []struct {
+		name    string
+		allowed []string
+		wantErr bool
+	}{
+		{name: "agents of the tenant", allowed: []string{"analyst", types.BuiltinDataAnalystID}},
+		{name: "unknown agent", allowed: []string{"analyst", "missing"}, wantErr: true},
+		{name: "agent of another tenant", allowed: []string{"foreign"}, wantErr: true},
+		{name: "self reference", allowed: []string{selfID}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFor := func(id string) types.CustomAgentConfig {
+				allowed := make([]string, len(tt.allowed))
+				for i, agentID := range tt.allowed {
+					if agentID == selfID {
+						agentID = id
+					}
+					allowed[i] = agentID
+				}
+				return types.CustomAgentConfig{SubAgents: &types.SubAgentConfig{AllowedAgents: allowed}}
+			}
+
+			_, err := s.UpdateAgent(ctx, &types.CustomAgent{ID: "parent", Name: "Parent", Config: configFor("parent")})
+			if gotErr := errors.Is(err, ErrInvalidSubAgents); gotErr != tt.wantErr {
+				t.Errorf("UpdateAgent error = %v, want invalid sub-agents %v", err, tt.wantErr)
+			}
+			_, err = s.CreateAgent(ctx, &types.CustomAgent{ID: "child", Name: "Child", Config: configFor("child")})
+			if gotErr := errors.Is(err, ErrInvalidSubAgents); gotErr != tt.wantErr {
+				t.Errorf("CreateAgent error = %v, want invalid sub-agents %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -32,6 +32,7 @@ type EvaluationService struct {
 	knowledgeService     interfaces.KnowledgeService     // Service for knowledge operations
 	sessionService       interfaces.SessionService       // Service for chat sessions
 	modelService         interfaces.ModelService         // Service for model operations
+	customAgentService   interfaces.CustomAgentService   // Service for custom agents evaluated in agent mode
 
 	evaluationMemoryStorage *evaluationMemoryStorage // In-memory storage for evaluation tasks
 }
@@ -43,6 +44,7 @@ func NewEvaluationService(
 	knowledgeService interfaces.KnowledgeService,
 	sessionService interfaces.SessionService,
 	modelService interfaces.ModelService,
+	customAgentService interfaces.CustomAgentService,
 ) interfaces.EvaluationService {
 	evaluationMemoryStorage := newEvaluationMemoryStorage()
 	return &EvaluationService{
@@ -52,6 +54,7 @@ func NewEvaluationService(
 		knowledgeService:        knowledgeService,
 		sessionService:          sessionService,
 		modelService:            modelService,
+		customAgentService:      customAgentService,
 		evaluationMemoryStorage: evaluationMemoryStorage,
 	}
 }
@@ -141,67 +144,10 @@ func (e *EvaluationService) Evaluation(ctx context.Context,
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
 	logger.Infof(ctx, "Tenant ID: %d", tenantID)
 
-	// Handle knowledge base creation if not provided
-	if knowledgeBaseID == "" {
-		logger.Info(ctx, "No knowledge base ID provided, creating new knowledge base")
-		// Create new knowledge base with default evaluation settings
-		// 获取默认的嵌入模型和LLM模型
-		models, err := e.modelService.ListModels(ctx)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to list models: %v", err)
-			return nil, err
-		}
-
-		var embeddingModelID, llmModelID string
-		for _, model := range models {
-			if model == nil {
-				continue
-			}
-			if model.Type == types.ModelTypeEmbedding {
-				embeddingModelID = model.ID
-			}
-			if model.Type == types.ModelTypeKnowledgeQA {
-				llmModelID = model.ID
-			}
-		}
-
-		if embeddingModelID == "" || llmModelID == "" {
-			return nil, fmt.Errorf("no default models found for evaluation")
-		}
-
-		kb, err := e.knowledgeBaseService.CreateKnowledgeBase(ctx, &types.KnowledgeBase{
-			Name:             "evaluation",
-			Description:      "evaluation",
-			EmbeddingModelID: embeddingModelID,
-			SummaryModelID:   llmModelID,
-		})
-		if err != nil {
-			logger.Errorf(ctx, "Failed to create knowledge base: %v", err)
-			return nil, err
-		}
-		knowledgeBaseID = kb.ID
-		logger.Infof(ctx, "Created new knowledge base with ID: %s", knowledgeBaseID)
-	} else {
-		logger.Infof(ctx, "Using existing knowledge base ID: %s", knowledgeBaseID)
-		// Create evaluation-specific knowledge base based on existing one
-		kb, err := e.knowledgeBaseService.GetKnowledgeBaseByID(ctx, knowledgeBaseID)
-		if err != nil {
-			logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
-			return nil, err
-		}
-
-		kb, err = e.knowledgeBaseService.CreateKnowledgeBase(ctx, &types.KnowledgeBase{
-			Name:             "evaluation",
-			Description:      "evaluation",
-			EmbeddingModelID: kb.EmbeddingModelID,
-			SummaryModelID:   kb.SummaryModelID,
-		})
-		if err != nil {
-			logger.Errorf(ctx, "Failed to create knowledge base: %v", err)
-			return nil, err
-		}
-		knowledgeBaseID = kb.ID
-		logger.Infof(ctx, "Created new knowledge base with ID: %s based on existing one", knowledgeBaseID)
+	// Create the knowledge base the dataset passages are loaded into
+	knowledgeBaseID, err := e.prepareKnowledgeBase(ctx, knowledgeBaseID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Set default values for optional parameters
@@ -326,54 +272,84 @@ func (e *EvaluationService) Evaluation(ctx context.Context,
 	return detail, nil
 }
 
+// prepareKnowledgeBase creates the evaluation knowledge base, with the models of the given
+// knowledge base or the default models when none is given, and returns its ID
+func (e *EvaluationService) prepareKnowledgeBase(ctx context.Context, knowledgeBaseID string) (string, error) {
+	if knowledgeBaseID == "" {
+		logger.Info(ctx, "No knowledge base ID provided, creating new knowledge base")
+		// Create new knowledge base with default evaluation settings
+		// 获取默认的嵌入模型和LLM模型
+		models, err := e.modelService.ListModels(ctx)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to list models: %v", err)
+			return "", err
+		}
+
+		var embeddingModelID, llmModelID string
+		for _, model := range models {
+			if model == nil {
+				continue
+			}
+			if model.Type == types.ModelTypeEmbedding {
+				embeddingModelID = model.ID
+			}
+			if model.Type == types.ModelTypeKnowledgeQA {
+				llmModelID = model.ID
+			}
+		}
+
+		if embeddingModelID == "" || llmModelID == "" {
+			return "", fmt.Errorf("no default models found for evaluation")
+		}
+
+		kb, err := e.knowledgeBaseService.CreateKnowledgeBase(ctx, &types.KnowledgeBase{
+			Name:             "evaluation",
+			Description:      "evaluation",
+			EmbeddingModelID: embeddingModelID,
+			SummaryModelID:   llmModelID,
+		})
+		if err != nil {
+			logger.Errorf(ctx, "Failed to create knowledge base: %v", err)
+			return "", err
+		}
+		knowledgeBaseID = kb.ID
+		logger.Infof(ctx, "Created new knowledge base with ID: %s", knowledgeBaseID)
+	} else {
+		logger.Infof(ctx, "Using existing knowledge base ID: %s", knowledgeBaseID)
+		// Create evaluation-specific knowledge base based on existing one
+		kb, err := e.knowledgeBaseService.GetKnowledgeBaseByID(ctx, knowledgeBaseID)
+		if err != nil {
+			logger.Errorf(ctx, "Failed to get knowledge base: %v", err)
+			return "", err
+		}
+
+		kb, err = e.knowledgeBaseService.CreateKnowledgeBase(ctx, &types.KnowledgeBase{
+			Name:             "evaluation",
+			Description:      "evaluation",
+			EmbeddingModelID: kb.EmbeddingModelID,
+			SummaryModelID:   kb.SummaryModelID,
+		})
+		if err != nil {
+			logger.Errorf(ctx, "Failed to create knowledge base: %v", err)
+			return "", err
+		}
+		knowledgeBaseID = kb.ID
+		logger.Infof(ctx, "Created new knowledge base with ID: %s based on existing one", knowledgeBaseID)
+	}
+	return knowledgeBaseID, nil
+}
+
 // EvalDataset performs the actual evaluation of a dataset
 // Processes each QA pair in parallel and records metrics
 func (e *EvaluationService) EvalDataset(ctx context.Context, detail *types.EvaluationDetail, knowledgeBaseID string) error {
 	logger.Info(ctx, "Start evaluating dataset")
 	logger.Infof(ctx, "Task ID: %s, Dataset ID: %s", detail.Task.ID, detail.Task.DatasetID)
 
-	// Retrieve dataset from storage
-	dataset, err := e.dataset.GetDatasetByID(ctx, detail.Task.DatasetID)
-	if err != nil {
-		logger.Errorf(ctx, "Failed to get dataset: %v", err)
-		return err
-	}
-	logger.Infof(ctx, "Dataset retrieved successfully with %d QA pairs", len(dataset))
-
-	// Update total QA pairs count in task details
-	e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
-		params.Task.Total = len(dataset)
-		logger.Infof(ctx, "Updated task total to %d QA pairs", params.Task.Total)
-	})
-
-	// Extract and organize passages from dataset
-	passages := getPassageList(dataset)
-	logger.Infof(ctx, "Creating knowledge from %d passages", len(passages))
-
-	// Create knowledge base from passages
-	knowledge, err := e.knowledgeService.CreateKnowledgeFromPassage(ctx, knowledgeBaseID, passages)
+	dataset, cleanup, err := e.loadDataset(ctx, detail, knowledgeBaseID, false)
 	if err != nil {
-		logger.Errorf(ctx, "Failed to create knowledge from passages: %v", err)
 		return err
 	}
-	logger.Infof(ctx, "Knowledge created successfully, ID: %s", knowledge.ID)
-
-	// Setup cleanup of temporary resources
-	defer func() {
-		logger.Infof(ctx, "Cleaning up resources - deleting knowledge: %s", knowledge.ID)
-		if err := e.knowledgeService.DeleteKnowledge(ctx, knowledge.ID); err != nil {
-			logger.Errorf(ctx, "Failed to delete knowledge: %v, knowledge ID: %s", err, knowledge.ID)
-		}
-
-		logger.Infof(ctx, "Cleaning up resources - deleting knowledge base: %s", knowledgeBaseID)
-		if err := e.knowledgeBaseService.DeleteKnowledgeBase(ctx, knowledgeBaseID); err != nil {
-			logger.Errorf(
-				ctx,
-				"Failed to delete knowledge base: %v, knowledge base ID: %s",
-				err, knowledgeBaseID,
-			)
-		}
-	}()
+	defer cleanup()
 
 	// Initialize parallel evaluation metrics
 	var finished int
@@ -453,6 +429,61 @@ func (e *EvaluationService) EvalDataset(ctx context.Context, detail *types.Evalu
 	return nil
 }
 
+// loadDataset loads the dataset of the task and creates knowledge from its passages in the
+// evaluation knowledge base. The returned cleanup deletes the knowledge and the knowledge base.
+// waitIndexed waits until the passages are indexed, so that they can be searched right away.
+func (e *EvaluationService) loadDataset(ctx context.Context,
+	detail *types.EvaluationDetail, knowledgeBaseID string, waitIndexed bool,
+) ([]*types.QAPair, func(), error) {
+	// Retrieve dataset from storage
+	dataset, err := e.dataset.GetDatasetByID(ctx, detail.Task.DatasetID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get dataset: %v", err)
+		return nil, nil, err
+	}
+	logger.Infof(ctx, "Dataset retrieved successfully with %d QA pairs", len(dataset))
+
+	// Update total QA pairs count in task details
+	e.evaluationMemoryStorage.update(detail.Task.ID, func(params *types.EvaluationDetail) {
+		params.Task.Total = len(dataset)
+		logger.Infof(ctx, "Updated task total to %d QA pairs", params.Task.Total)
+	})
+
+	// Extract and organize passages from dataset
+	passages := getPassageList(dataset)
+	logger.Infof(ctx, "Creating knowledge from %d passages", len(passages))
+
+	// Create knowledge base from passages
+	createKnowledge := e.knowledgeService.CreateKnowledgeFromPassage
+	if waitIndexed {
+		createKnowledge = e.knowledgeService.CreateKnowledgeFromPassageSync
+	}
+	knowledge, err := createKnowledge(ctx, knowledgeBaseID, passages)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to create knowledge from passages: %v", err)
+		return nil, nil, err
+	}
+	logger.Infof(ctx, "Knowledge created successfully, ID: %s", knowledge.ID)
+
+	// Cleanup of temporary resources
+	cleanup := func() {
+		logger.Infof(ctx, "Cleaning up resources - deleting knowledge: %s", knowledge.ID)
+		if err := e.knowledgeService.DeleteKnowledge(ctx, knowledge.ID); err != nil {
+			logger.Errorf(ctx, "Failed to delete knowledge: %v, knowledge ID: %s", err, knowledge.ID)
+		}
+
+		logger.Infof(ctx, "Cleaning up resources - deleting knowledge base: %s", knowledgeBaseID)
+		if err := e.knowledgeBaseService.DeleteKnowledgeBase(ctx, knowledgeBaseID); err != nil {
+			logger.Errorf(
+				ctx,
+				"Failed to delete knowledge base: %v, knowledge base ID: %s",
+				err, knowledgeBaseID,
+			)
+		}
+	}
+	return dataset, cleanup, nil
+}
+
 // getPassageList extracts and organizes passages from QA pairs
 // Returns a slice of passages indexed by their passage IDs
 func getPassageList(dataset []*types.QAPair) []string {
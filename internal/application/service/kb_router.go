@@ -0,0 +1,351 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const (
+	// maxKBProfileChars bounds the knowledge base description embedded for routing
+	maxKBProfileChars = 2000
+	// maxKBProfiles bounds the number of cached profile embeddings
+	maxKBProfiles = 4096
+)
+
+const kbRoutingClassifierPrompt = `You route user questions to knowledge bases.
+Given a question and a numbered list of knowledge bases with their descriptions, pick the knowledge bases
+most likely to contain the answer, most relevant first, at most %d of them.
+Respond with JSON only, in the form {"selected": [<number>, ...]}. Respond with {"selected": []} if none is relevant.`
+
+// kbRouter picks the knowledge bases most relevant to a query, so that tenants with
+// many knowledge bases do not search every one of them for every query
+type kbRouter struct {
+	modelService interfaces.ModelService
+
+	mu          sync.Mutex
+	profiles    map[string]*list.Element // Profile embedding per knowledge base and model
+	recent      *list.List               // Cached profiles, most recently used first
+	maxProfiles int
+}
+
+// kbProfile is a cached profile embedding, valid while the knowledge base is not updated
+type kbProfile struct {
+	key     string
+	version int64
+	vector  []float32
+}
+
+// newKBRouter creates a knowledge base router
+func newKBRouter(modelService interfaces.ModelService) *kbRouter {
+	return &kbRouter{
+		modelService: modelService,
+		profiles:     make(map[string]*list.Element),
+		recent:       list.New(),
+		maxProfiles:  maxKBProfiles,
+	}
+}
+
+// Route scores the knowledge bases against the query and selects the top ones.
+// chatModelID is the classifier fallback when the config does not name one.
+// When no knowledge base passes the minimum score or the classifier picks none,
+// the top ones by embedding score are searched anyway.
+func (r *kbRouter) Route(ctx context.Context, cfg *types.KBRoutingConfig,
+	query string, kbs []*types.KnowledgeBase, chatModelID string,
+) (*types.KBRoutingDecision, error) {
+	candidates, err := r.scoreByEmbedding(ctx, cfg, query, kbs)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	topN := cfg.GetTopN()
+	eligible := make([]*types.KBRouteCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Similarity >= cfg.MinScore {
+			eligible = append(eligible, c)
+		}
+	}
+
+	decision := &types.KBRoutingDecision{
+		Query:      query,
+		Method:     types.KBRoutingMethodEmbedding,
+		Candidates: candidates,
+	}
+	selected := eligible
+	if len(selected) > topN {
+		selected = selected[:topN]
+	}
+
+	classifierModelID := cfg.ClassifierModelID
+	if classifierModelID == "" {
+		classifierModelID = chatModelID
+	}
+	if cfg.LLMClassifier && classifierModelID != "" && len(eligible) > 0 {
+		// The classifier chooses among twice as many candidates as it may select
+		pool := eligible
+		if len(pool) > topN*2 {
+			pool = pool[:topN*2]
+		}
+		picked, err := r.classify(ctx, classifierModelID, query, pool, kbs, topN)
+		if err != nil {
+			logger.Warnf(ctx, "KB routing classifier failed, keeping embedding ranking: %v", err)
+		} else {
+			decision.Method = types.KBRoutingMethodLLM
+			selected = picked
+		}
+	}
+	if len(selected) == 0 {
+		selected = candidates
+		if len(selected) > topN {
+			selected = selected[:topN]
+		}
+		decision.Method = types.KBRoutingMethodEmbedding
+		decision.Fallback = "no knowledge base selected, using the embedding top N"
+	}
+
+	decision.Selected = make([]string, 0, len(selected))
+	for _, c := range selected {
+		c.Selected = true
+		decision.Selected = append(decision.Selected, c.KnowledgeBaseID)
+	}
+	return decision, nil
+}
+
+// scoreByEmbedding computes the cosine similarity between the query and each knowledge base profile.
+// The query is embedded once per embedding model; knowledge bases that cannot be embedded score zero.
+// Similarities of different embedding models are not comparable, so when several models are used
+// each similarity is scaled by the best one of its model to rank the knowledge bases.
+func (r *kbRouter) scoreByEmbedding(ctx context.Context, cfg *types.KBRoutingConfig,
+	query string, kbs []*types.KnowledgeBase,
+) ([]*types.KBRouteCandidate, error) {
+	byModel := make(map[string][]*types.KnowledgeBase)
+	for _, kb := range kbs {
+		modelID := cfg.EmbeddingModelID
+		if modelID == "" {
+			modelID = kb.EmbeddingModelID
+		}
+		byModel[modelID] = append(byModel[modelID], kb)
+	}
+
+	similarities := make(map[string]float64, len(kbs))
+	scores := make(map[string]float64, len(kbs))
+	models := make(map[string]string, len(kbs))
+	embedded := 0
+	for modelID, group := range byModel {
+		if modelID == "" {
+			continue
+		}
+		embedder, err := r.modelService.GetEmbeddingModel(ctx, modelID)
+		if err != nil {
+			logger.Warnf(ctx, "KB routing: failed to get embedding model %s: %v", modelID, err)
+			continue
+		}
+		queryVector, err := embedder.Embed(ctx, query)
+		if err != nil {
+			logger.Warnf(ctx, "KB routing: failed to embed query with model %s: %v", modelID, err)
+			continue
+		}
+
+		// Embed the profiles missing from the cache in one batch
+		vectors := make([][]float32, len(group))
+		var missing []int
+		var texts []string
+		for i, kb := range group {
+			if vector, ok := r.loadProfile(kb, modelID); ok {
+				vectors[i] = vector
+			} else {
+				missing = append(missing, i)
+				texts = append(texts, kbProfileText(kb))
+			}
+		}
+		if len(texts) > 0 {
+			batch, err := embedder.BatchEmbed(ctx, texts)
+			if err != nil || len(batch) != len(texts) {
+				logger.Warnf(ctx, "KB routing: failed to embed %d knowledge base profiles: %v", len(texts), err)
+				continue
+			}
+			for j, i := range missing {
+				vectors[i] = batch[j]
+				r.storeProfile(group[i], modelID, batch[j])
+			}
+		}
+
+		best := 0.0
+		for i, kb := range group {
+			similarities[kb.ID] = cosineSimilarity(queryVector, vectors[i])
+			models[kb.ID] = modelID
+			best = math.Max(best, similarities[kb.ID])
+		}
+		for _, kb := range group {
+			scores[kb.ID] = similarities[kb.ID]
+			if len(byModel) > 1 && best > 0 {
+				scores[kb.ID] = similarities[kb.ID] / best
+			}
+		}
+		embedded++
+	}
+	if embedded == 0 {
+		return nil, fmt.Errorf("no embedding model available for knowledge base routing")
+	}
+
+	candidates := make([]*types.KBRouteCandidate, 0, len(kbs))
+	for _, kb := range kbs {
+		candidates = append(candidates, &types.KBRouteCandidate{
+			KnowledgeBaseID:  kb.ID,
+			Name:             kb.Name,
+			EmbeddingModelID: models[kb.ID],
+			Similarity:       similarities[kb.ID],
+			Score:            scores[kb.ID],
+		})
+	}
+	return candidates, nil
+}
+
+// loadProfile returns the cached profile embedding of a knowledge base, unless it was updated since
+func (r *kbRouter) loadProfile(kb *types.KnowledgeBase, modelID string) ([]float32, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	elem, ok := r.profiles[kbProfileKey(kb, modelID)]
+	if !ok {
+		return nil, false
+	}
+	profile := elem.Value.(*kbProfile)
+	if profile.version != kb.UpdatedAt.UnixNano() {
+		return nil, false
+	}
+	r.recent.MoveToFront(elem)
+	return profile.vector, true
+}
+
+// storeProfile caches a profile embedding, evicting the least recently used ones when the cache is full
+func (r *kbRouter) storeProfile(kb *types.KnowledgeBase, modelID string, vector []float32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := kbProfileKey(kb, modelID)
+	profile := &kbProfile{key: key, version: kb.UpdatedAt.UnixNano(), vector: vector}
+	if elem, ok := r.profiles[key]; ok {
+		elem.Value = profile
+		r.recent.MoveToFront(elem)
+		return
+	}
+	for r.recent.Len() >= r.maxProfiles {
+		oldest := r.recent.Back()
+		r.recent.Remove(oldest)
+		delete(r.profiles, oldest.Value.(*kbProfile).key)
+	}
+	r.profiles[key] = r.recent.PushFront(profile)
+}
+
+// classify asks a chat model to pick the knowledge bases among the candidates
+func (r *kbRouter) classify(ctx context.Context, modelID string, query string,
+	pool []*types.KBRouteCandidate, kbs []*types.KnowledgeBase, topN int,
+) ([]*types.KBRouteCandidate, error) {
+	chatModel, err := r.modelService.GetChatModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make(map[string]string, len(kbs))
+	for _, kb := range kbs {
+		descriptions[kb.ID] = kb.Description
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Question: %s\n\nKnowledge bases:\n", query)
+	for i, c := range pool {
+		fmt.Fprintf(&sb, "[%d] %s: %s\n", i, c.Name, truncateRunes(descriptions[c.KnowledgeBaseID], 300))
+	}
+
+	thinking := false
+	response, err := chatModel.Chat(ctx, []chat.Message{
+		{Role: "system", Content: fmt.Sprintf(kbRoutingClassifierPrompt, topN)},
+		{Role: "user", Content: sb.String()},
+	}, &chat.ChatOptions{Temperature: 0, Thinking: &thinking})
+	if err != nil {
+		return nil, err
+	}
+	indices, err := parseKBRoutingSelection(response.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := make([]*types.KBRouteCandidate, 0, topN)
+	seen := make(map[int]bool)
+	for _, idx := range indices {
+		if idx < 0 || idx >= len(pool) || seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		picked = append(picked, pool[idx])
+		if len(picked) == topN {
+			break
+		}
+	}
+	return picked, nil
+}
+
+// parseKBRoutingSelection extracts the selected indices from the classifier output
+func parseKBRoutingSelection(content string) ([]int, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("unexpected KB routing output: %s", content)
+	}
+	var result struct {
+		Selected []int `json:"selected"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse KB routing output: %w", err)
+	}
+	return result.Selected, nil
+}
+
+// kbProfileKey identifies the profile embedding of a knowledge base for a model
+func kbProfileKey(kb *types.KnowledgeBase, modelID string) string {
+	return kb.ID + "|" + modelID
+}
+
+// kbProfileText is the text embedded to represent a knowledge base
+func kbProfileText(kb *types.KnowledgeBase) string {
+	if kb.Description == "" {
+		return kb.Name
+	}
+	return truncateRunes(kb.Name+"\n"+kb.Description, maxKBProfileChars)
+}
+
+// truncateRunes truncates s to at most n characters
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, zero when they are not comparable
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
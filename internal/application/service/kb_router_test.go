@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// routingEmbedder embeds texts as fixed vectors; unknown texts embed as zero vectors
+type routingEmbedder struct {
+	embedding.Embedder
+	vectors map[string][]float32
+	batches int
+}
+
+func (e *routingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	return e.vectors[text], nil
+}
+
+func (e *routingEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.batches++
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i] = e.vectors[text]
+	}
+	return result, nil
+}
+
+// routingChat answers the classifier with a fixed response
+type routingChat struct {
+	content string
+	err     error
+}
+
+func (c *routingChat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &types.ChatResponse{Content: c.content}, nil
+}
+
+func (c *routingChat) ChatStream(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *routingChat) GetModelName() string { return "classifier" }
+
+func (c *routingChat) GetModelID() string { return "classifier" }
+
+// routingModelService serves the embedders by model ID and the classifier
+type routingModelService struct {
+	interfaces.ModelService
+	embedders map[string]*routingEmbedder
+	chat      chat.Chat
+}
+
+func (s *routingModelService) GetEmbeddingModel(ctx context.Context, modelID string) (embedding.Embedder, error) {
+	if e, ok := s.embedders[modelID]; ok {
+		return e, nil
+	}
+	return nil, errors.New("model not found")
+}
+
+func (s *routingModelService) GetChatModel(ctx context.Context, modelID string) (chat.Chat, error) {
+	if s.chat == nil {
+		return nil, errors.New("model not found")
+	}
+	return s.chat, nil
+}
+
+// similarTo returns a unit vector whose cosine similarity with [1, 0] is cos
+func similarTo(cos float64) []float32 {
+	return []float32{float32(cos), float32(math.Sqrt(1 - cos*cos))}
+}
+
+func routingKB(id, modelID string) *types.KnowledgeBase {
+	return &types.KnowledgeBase{ID: id, Name: id, EmbeddingModelID: modelID}
+}
+
+func TestKBRouterRoute(t *testing.T) {
+	query := "how do I get a refund"
+	embedders := func() map[string]*routingEmbedder {
+		return map[string]*routingEmbedder{
+			"model-a": {vectors: map[string][]float32{
+				query:     {1, 0},
+				"billing": similarTo(0.9),
+				"refunds": similarTo(0.8),
+				"hr":      similarTo(0.2),
+			}},
+			// Model B scores everything lower; its best match is still relevant
+			"model-b": {vectors: map[string][]float32{
+				query:    {1, 0},
+				"orders": similarTo(0.5),
+				"it":     similarTo(0.1),
+			}},
+		}
+	}
+	singleModel := []*types.KnowledgeBase{
+		routingKB("hr", "model-a"), routingKB("billing", "model-a"), routingKB("refunds", "model-a"),
+	}
+	mixedModels := []*types.KnowledgeBase{
+		routingKB("billing", "model-a"), routingKB("refunds", "model-a"),
+		routingKB("it", "model-b"), routingKB("orders", "model-b"),
+	}
+
+	tests := []struct {
+		name         string
+		cfg          *types.KBRoutingConfig
+		kbs          []*types.KnowledgeBase
+		chat         chat.Chat
+		wantSelected []string
+		wantMethod   string
+		wantFallback bool
+	}{
+		{
+			name:         "embedding ranking",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 2},
+			kbs:          singleModel,
+			wantSelected: []string{"billing", "refunds"},
+			wantMethod:   types.KBRoutingMethodEmbedding,
+		},
+		{
+			name:         "min score",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 2, MinScore: 0.85},
+			kbs:          singleModel,
+			wantSelected: []string{"billing"},
+			wantMethod:   types.KBRoutingMethodEmbedding,
+		},
+		{
+			name:         "nothing passes the min score",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 2, MinScore: 0.95},
+			kbs:          singleModel,
+			wantSelected: []string{"billing", "refunds"},
+			wantMethod:   types.KBRoutingMethodEmbedding,
+			wantFallback: true,
+		},
+		{
+			name:         "similarities normalized per embedding model",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 2},
+			kbs:          mixedModels,
+			wantSelected: []string{"billing", "orders"},
+			wantMethod:   types.KBRoutingMethodEmbedding,
+		},
+		{
+			name:         "classifier picks",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 1, LLMClassifier: true},
+			kbs:          singleModel,
+			chat:         &routingChat{content: "```json\n{\"selected\": [1]}\n```"},
+			wantSelected: []string{"refunds"},
+			wantMethod:   types.KBRoutingMethodLLM,
+		},
+		{
+			name:         "classifier failure keeps the embedding ranking",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 1, LLMClassifier: true},
+			kbs:          singleModel,
+			chat:         &routingChat{err: errors.New("timeout")},
+			wantSelected: []string{"billing"},
+			wantMethod:   types.KBRoutingMethodEmbedding,
+		},
+		{
+			name:         "classifier picks nothing",
+			cfg:          &types.KBRoutingConfig{Enabled: true, TopN: 1, LLMClassifier: true},
+			kbs:          singleModel,
+			chat:         &routingChat{content: `{"selected": [7]}`},
+			wantSelected: []string{"billing"},
+			wantMethod:   types.KBRoutingMethodEmbedding,
+			wantFallback: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newKBRouter(&routingModelService{embedders: embedders(), chat: tt.chat})
+			decision, err := router.Route(context.Background(), tt.cfg, query, tt.kbs, "chat-1")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(decision.Selected, tt.wantSelected) {
+				t.Errorf("Selected = %v, want %v", decision.Selected, tt.wantSelected)
+			}
+			if decision.Method != tt.wantMethod {
+				t.Errorf("Method = %s, want %s", decision.Method, tt.wantMethod)
+			}
+			if (decision.Fallback != "") != tt.wantFallback {
+				t.Errorf("Fallback = %q, want fallback %v", decision.Fallback, tt.wantFallback)
+			}
+		})
+	}
+}
+
+func TestRouteKnowledgeBasesFallback(t *testing.T) {
+	kbs := []*types.KnowledgeBase{routingKB("a", "missing"), routingKB("b", "missing")}
+	kbIDs := []string{"a", "b"}
+	agent := &types.CustomAgent{Config: types.CustomAgentConfig{
+		KBRouting: &types.KBRoutingConfig{Enabled: true, TopN: 1},
+	}}
+	s := &sessionService{kbRouter: newKBRouter(&routingModelService{})}
+
+	// Without an embedding model every knowledge base is searched
+	selected, decision := s.routeKnowledgeBases(context.Background(), agent, "query", kbs, kbIDs)
+	if !reflect.DeepEqual(selected, kbIDs) {
+		t.Errorf("Selected = %v, want all knowledge bases", selected)
+	}
+	if decision == nil || decision.Fallback == "" {
+		t.Errorf("Expected a fallback decision, got %+v", decision)
+	}
+
+	// Routing is skipped when there are no more knowledge bases than selected ones
+	agent.Config.KBRouting.TopN = 2
+	selected, decision = s.routeKnowledgeBases(context.Background(), agent, "query", kbs, kbIDs)
+	if !reflect.DeepEqual(selected, kbIDs) || decision != nil {
+		t.Errorf("Expected routing to be skipped, got %v, %+v", selected, decision)
+	}
+}
+
+func TestKBRouterProfileCache(t *testing.T) {
+	embedder := &routingEmbedder{vectors: map[string][]float32{}}
+	router := newKBRouter(&routingModelService{embedders: map[string]*routingEmbedder{"m": embedder}})
+	router.maxProfiles = 2
+	cfg := &types.KBRoutingConfig{Enabled: true, TopN: 1}
+	route := func(ids ...string) {
+		kbs := make([]*types.KnowledgeBase, len(ids))
+		for i, id := range ids {
+			kbs[i] = routingKB(id, "m")
+		}
+		if _, err := router.Route(context.Background(), cfg, "q", kbs, ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	route("a", "b")
+	route("a") // a becomes the most recently used profile
+	route("c") // evicts b
+	embedder.batches = 0
+	route("a", "c")
+	if embedder.batches != 0 {
+		t.Errorf("Expected a and c to be cached, got %d embedding batches", embedder.batches)
+	}
+	route("b")
+	if embedder.batches != 1 {
+		t.Errorf("Expected b to be evicted, got %d embedding batches", embedder.batches)
+	}
+
+	// Updating a knowledge base invalidates its profile
+	embedder.batches = 0
+	updated := routingKB("a", "m")
+	updated.UpdatedAt = time.Now()
+	if _, err := router.Route(context.Background(), cfg, "q", []*types.KnowledgeBase{updated}, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if embedder.batches != 1 {
+		t.Errorf("Expected the updated profile to be embedded again, got %d embedding batches", embedder.batches)
+	}
+}
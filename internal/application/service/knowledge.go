@@ -25,6 +25,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/tracing"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -1436,6 +1437,12 @@ func (s *knowledgeService) processChunks(ctx context.Context,
 	logger.GetLogger(ctx).Infof("processChunks successfully")
 }
 
+// Token budgets of summary generation
+const (
+	summaryInputTokens = 3072 // Content given to the model
+	summaryMinTokens   = 100  // Content shorter than this is used as the summary
+)
+
 // GetSummary generates a summary for knowledge content using an AI model
 func (s *knowledgeService) getSummary(ctx context.Context,
 	summaryModel chat.Chat, knowledge *types.Knowledge, chunks []*types.Chunk,
@@ -1456,9 +1463,10 @@ func (s *knowledgeService) getSummary(ctx context.Context,
 		return sortedChunks[i].StartAt < sortedChunks[j].StartAt
 	})
 
-	// concat chunk contents and collect image infos
+	// concat chunk contents and collect image infos, up to the input budget in tokens of the model
+	tok := tokenizer.ForModel(summaryModel.GetModelName())
 	for _, chunk := range sortedChunks {
-		if chunk.EndAt > 4096 {
+		if tok.Count(chunkContents) >= summaryInputTokens {
 			break
 		}
 		chunkContents = string([]rune(chunkContents)[:chunk.StartAt]) + chunk.Content
@@ -1488,8 +1496,10 @@ func (s *knowledgeService) getSummary(ctx context.Context,
 		// concat chunk contents and image annotations
 		chunkContents = chunkContents + imageAnnotations
 	}
+	chunkContents = tok.Truncate(chunkContents, summaryInputTokens)
 
-	if len(chunkContents) < 300 {
+	// Short content is its own summary
+	if tok.Count(chunkContents) < summaryMinTokens {
 		return chunkContents, nil
 	}
 
@@ -1598,6 +1608,7 @@ func (s *knowledgeService) ProcessSummaryGeneration(ctx context.Context, t *asyn
 
 	// Set tenant context
 	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{Source: types.ModelUsageSourceSummary})
 
 	// Get knowledge base
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
@@ -1777,6 +1788,7 @@ func (s *knowledgeService) ProcessQuestionGeneration(ctx context.Context, t *asy
 
 	// Set tenant context
 	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{Source: types.ModelUsageSourceQuestionGeneration})
 
 	// Get knowledge base
 	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, payload.KnowledgeBaseID)
@@ -2265,7 +2277,7 @@ func (s *knowledgeService) CloneKnowledgeBase(ctx context.Context, srcID, dstID
 		g.Go(func() error {
 			err := s.DeleteKnowledgeList(gctx, ids)
 			if err != nil {
-				logger.Errorf(gctx, "delete partial knowledge %v: %w", ids, err)
+				logger.Errorf(gctx, "delete partial knowledge %v: %v", ids, err)
 				return err
 			}
 			return nil
@@ -2284,12 +2296,12 @@ func (s *knowledgeService) CloneKnowledgeBase(ctx context.Context, srcID, dstID
 		g.Go(func() error {
 			srcKn, err := s.repo.GetKnowledgeByID(gctx, srcKB.TenantID, knowledge)
 			if err != nil {
-				logger.Errorf(gctx, "get knowledge %s: %w", knowledge, err)
+				logger.Errorf(gctx, "get knowledge %s: %v", knowledge, err)
 				return err
 			}
 			err = s.cloneKnowledge(gctx, srcKn, dstKB)
 			if err != nil {
-				logger.Errorf(gctx, "clone knowledge %s: %w", knowledge, err)
+				logger.Errorf(gctx, "clone knowledge %s: %v", knowledge, err)
 				return err
 			}
 			return nil
@@ -5106,6 +5118,7 @@ func (s *knowledgeService) ProcessDocument(ctx context.Context, t *asynq.Task) e
 	ctx = logger.WithRequestID(ctx, payload.RequestId)
 	ctx = logger.WithField(ctx, "document_process", payload.KnowledgeID)
 	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{Source: types.ModelUsageSourceIndexing})
 
 	// 获取任务重试信息，用于判断是否是最后一次重试
 	retryCount, _ := asynq.GetRetryCount(ctx)
@@ -5354,6 +5367,7 @@ func (s *knowledgeService) ProcessFAQImport(ctx context.Context, t *asynq.Task)
 	ctx = logger.WithRequestID(ctx, uuid.New().String())
 	ctx = logger.WithField(ctx, "faq_import", payload.TaskID)
 	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{Source: types.ModelUsageSourceIndexing})
 
 	// 获取任务重试信息，用于判断是否是最后一次重试
 	retryCount, _ := asynq.GetRetryCount(ctx)
@@ -5560,6 +5574,7 @@ func (s *knowledgeService) ProcessKBClone(ctx context.Context, t *asynq.Task) er
 
 	// Add tenant ID to context
 	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{Source: types.ModelUsageSourceIndexing})
 
 	// Get tenant info and add to context
 	tenantInfo, err := s.tenantRepo.GetTenantByID(ctx, payload.TenantID)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"slices"
 	"strings"
 	"time"
@@ -612,6 +613,10 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 	}
 	logger.Infof(ctx, "Result count before fusion: vector=%d, keyword=%d", len(vectorResults), len(keywordResults))
 
+	// Record per-retriever scores before fusion overwrites them
+	trace := types.RetrievalTraceFromContext(ctx)
+	traceRetrieverResults(trace, params.QueryText, vectorResults, keywordResults)
+
 	var deduplicatedChunks []*types.IndexWithScore
 
 	// If only vector results (no keyword results), keep original embedding scores
@@ -717,6 +722,8 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 		}
 	}
 
+	traceFusionScores(trace, deduplicatedChunks)
+
 	kb.EnsureDefaults()
 
 	// Check if we need iterative retrieval for FAQ with separate indexing
@@ -733,20 +740,87 @@ func (s *knowledgeBaseService) HybridSearch(ctx context.Context,
 			params.MatchCount,
 			params.QueryText,
 		)
+		traceFusionScores(trace, deduplicatedChunks)
 	} else if kb.Type == types.KnowledgeBaseTypeFAQ {
 		// Filter by negative questions if not using iterative retrieval
-		deduplicatedChunks = s.filterByNegativeQuestions(ctx, deduplicatedChunks, params.QueryText)
+		filtered := s.filterByNegativeQuestions(ctx, deduplicatedChunks, params.QueryText)
+		traceDroppedChunks(trace, deduplicatedChunks, filtered, types.DropReasonNegativeQuestion,
+			"query matches a negative question of the FAQ entry")
+		deduplicatedChunks = filtered
 		logger.Infof(ctx, "Result count after negative question filtering: %d", len(deduplicatedChunks))
 	}
 
 	// Limit to MatchCount
 	if len(deduplicatedChunks) > params.MatchCount {
+		traceDroppedChunks(trace, deduplicatedChunks, deduplicatedChunks[:params.MatchCount], types.DropReasonTopK,
+			fmt.Sprintf("ranked below match count %d", params.MatchCount))
 		deduplicatedChunks = deduplicatedChunks[:params.MatchCount]
 	}
 
 	return s.processSearchResults(ctx, deduplicatedChunks)
 }
 
+// traceRetrieverResults records the vector and keyword scores and ranks of a debug request.
+// Results are sorted by score, so the first occurrence of a chunk carries its best rank.
+func traceRetrieverResults(trace *types.RetrievalTrace, query string,
+	vectorResults, keywordResults []*types.IndexWithScore,
+) {
+	if trace == nil {
+		return
+	}
+	record := func(results []*types.IndexWithScore, vector bool) {
+		for i, r := range results {
+			rank, score := i+1, r.Score
+			trace.Update(r.ChunkID, func(c *types.CandidateTrace) {
+				c.KnowledgeID = r.KnowledgeID
+				c.KnowledgeBaseID = r.KnowledgeBaseID
+				c.MatchType = r.MatchType
+				if !slices.Contains(c.Queries, query) {
+					c.Queries = append(c.Queries, query)
+				}
+				if vector && (c.VectorScore == nil || score > *c.VectorScore) {
+					c.VectorScore, c.VectorRank = &score, rank
+				}
+				if !vector && (c.KeywordScore == nil || score > *c.KeywordScore) {
+					c.KeywordScore, c.KeywordRank = &score, rank
+				}
+			})
+		}
+	}
+	record(vectorResults, true)
+	record(keywordResults, false)
+}
+
+// traceFusionScores records the fused scores of a debug request, keeping the best across query variants
+func traceFusionScores(trace *types.RetrievalTrace, chunks []*types.IndexWithScore) {
+	for _, chunk := range chunks {
+		score := chunk.Score
+		trace.Update(chunk.ChunkID, func(c *types.CandidateTrace) {
+			if c.FusionScore == nil || score > *c.FusionScore {
+				c.FusionScore = &score
+			}
+		})
+	}
+}
+
+// traceDroppedChunks marks the chunks of before that are missing from after as dropped
+func traceDroppedChunks(trace *types.RetrievalTrace,
+	before, after []*types.IndexWithScore, reason, detail string,
+) {
+	if trace == nil {
+		return
+	}
+	kept := make(map[string]struct{}, len(after))
+	for _, chunk := range after {
+		kept[chunk.ChunkID] = struct{}{}
+	}
+	for _, chunk := range before {
+		if _, ok := kept[chunk.ChunkID]; !ok {
+			trace.Drop(chunk.ChunkID, types.RetrievalStageHybridSearch, reason, detail)
+		}
+	}
+}
+
 // iterativeRetrieveWithDeduplication performs iterative retrieval until enough unique chunks are found
 // This is used for FAQ knowledge bases with separate indexing mode
 // Negative question filtering is applied after each iteration with chunk data caching
@@ -7,32 +7,49 @@ import (
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
 
+// messageOverheadTokens approximates the tokens chat templates add around every message
+const messageOverheadTokens = 4
+
+// estimateTokens counts the tokens of messages with the tokenizer of the model
+func estimateTokens(tok tokenizer.Tokenizer, messages []chat.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageOverheadTokens + tokenizer.CountAll(tok, msg.Role, msg.Content)
+		// Account for tool calls if present
+		for _, tc := range msg.ToolCalls {
+			total += tokenizer.CountAll(tok, tc.Function.Name, tc.Function.Arguments)
+		}
+	}
+	return total
+}
+
 // slidingWindowStrategy implements CompressionStrategy using sliding window
 type slidingWindowStrategy struct {
 	recentMessageCount int
+	tokenizer          tokenizer.Tokenizer
 }
 
-// NewSlidingWindowStrategy creates a new sliding window compression strategy
-func NewSlidingWindowStrategy(recentMessageCount int) interfaces.CompressionStrategy {
+// NewSlidingWindowStrategy creates a new sliding window compression strategy counting
+// tokens with the given tokenizer
+func NewSlidingWindowStrategy(recentMessageCount int, tok tokenizer.Tokenizer) interfaces.CompressionStrategy {
 	return &slidingWindowStrategy{
 		recentMessageCount: recentMessageCount,
+		tokenizer:          tok,
 	}
 }
 
 // Compress implements the sliding window compression
-// Keeps system messages and the most recent N messages
+// Keeps system messages and the most recent N messages, dropping more of the oldest
+// ones while the context still exceeds maxTokens
 func (s *slidingWindowStrategy) Compress(
 	ctx context.Context,
 	messages []chat.Message,
 	maxTokens int,
 ) ([]chat.Message, error) {
-	if len(messages) <= s.recentMessageCount {
-		return messages, nil
-	}
-
 	// Separate system messages from regular messages
 	var systemMessages []chat.Message
 	var regularMessages []chat.Message
@@ -52,6 +69,13 @@ func (s *slidingWindowStrategy) Compress(
 	} else {
 		keptMessages = regularMessages
 	}
+	// Drop the oldest messages while over budget, along with tool results whose call was
+	// dropped. The latest message is always kept.
+	tokens := s.EstimateTokens(systemMessages) + s.EstimateTokens(keptMessages)
+	for len(keptMessages) > 1 && (tokens > maxTokens || keptMessages[0].Role == "tool") {
+		tokens -= s.EstimateTokens(keptMessages[:1])
+		keptMessages = keptMessages[1:]
+	}
 
 	// Combine: system messages first, then recent messages
 	result := make([]chat.Message, 0, len(systemMessages)+len(keptMessages))
@@ -64,19 +88,9 @@ func (s *slidingWindowStrategy) Compress(
 	return result, nil
 }
 
-// EstimateTokens estimates token count (rough approximation: 4 characters ≈ 1 token)
+// EstimateTokens counts the tokens of messages
 func (s *slidingWindowStrategy) EstimateTokens(messages []chat.Message) int {
-	totalChars := 0
-	for _, msg := range messages {
-		totalChars += len(msg.Role) + len(msg.Content)
-		// Account for tool calls if present
-		if len(msg.ToolCalls) > 0 {
-			for _, tc := range msg.ToolCalls {
-				totalChars += len(tc.Function.Name) + len(tc.Function.Arguments)
-			}
-		}
-	}
-	return totalChars / 4 // Rough approximation
+	return estimateTokens(s.tokenizer, messages)
 }
 
 // smartCompressionStrategy implements CompressionStrategy using LLM summarization
@@ -84,18 +98,22 @@ type smartCompressionStrategy struct {
 	recentMessageCount int
 	chatModel          chat.Chat
 	summarizeThreshold int // Minimum messages before summarization
+	tokenizer          tokenizer.Tokenizer
 }
 
-// NewSmartCompressionStrategy creates a new smart compression strategy
+// NewSmartCompressionStrategy creates a new smart compression strategy counting tokens
+// with the given tokenizer
 func NewSmartCompressionStrategy(
 	recentMessageCount int,
 	chatModel chat.Chat,
 	summarizeThreshold int,
+	tok tokenizer.Tokenizer,
 ) interfaces.CompressionStrategy {
 	return &smartCompressionStrategy{
 		recentMessageCount: recentMessageCount,
 		chatModel:          chatModel,
 		summarizeThreshold: summarizeThreshold,
+		tokenizer:          tok,
 	}
 }
 
@@ -226,17 +244,7 @@ func (s *smartCompressionStrategy) summarizeMessages(ctx context.Context, messag
 	return summary, nil
 }
 
-// EstimateTokens estimates token count (rough approximation: 4 characters ≈ 1 token)
+// EstimateTokens counts the tokens of messages
 func (s *smartCompressionStrategy) EstimateTokens(messages []chat.Message) int {
-	totalChars := 0
-	for _, msg := range messages {
-		totalChars += len(msg.Role) + len(msg.Content)
-		// Account for tool calls if present
-		if len(msg.ToolCalls) > 0 {
-			for _, tc := range msg.ToolCalls {
-				totalChars += len(tc.Function.Name) + len(tc.Function.Arguments)
-			}
-		}
-	}
-	return totalChars / 4 // Rough approximation
+	return estimateTokens(s.tokenizer, messages)
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 )
@@ -21,18 +22,26 @@ const (
 	DefaultCompressionStrategy = "sliding_window"
 )
 
-// NewContextManagerFromConfig creates a ContextManager based on configuration
+// NewContextManagerFromConfig creates a ContextManager based on configuration.
+// Tokens are counted with the tokenizer of chatModel, and reservedTokens (the completion
+// budget of the model) are kept free of context.
 func NewContextManagerFromConfig(
 	contextCfg *types.ContextConfig,
 	storage ContextStorage,
 	chatModel chat.Chat,
+	reservedTokens int,
 ) interfaces.ContextManager {
+	tok := tokenizer.Default()
+	if chatModel != nil {
+		tok = tokenizer.ForModel(chatModel.GetModelName())
+	}
+
 	// Use default values if config is nil
 	if contextCfg == nil {
 		logger.Info(context.TODO(), "ContextManager config not found, using default memory-based context manager")
-		strategy := NewSlidingWindowStrategy(DefaultRecentMessageCount)
+		strategy := NewSlidingWindowStrategy(DefaultRecentMessageCount, tok)
 		storage := NewMemoryStorage()
-		return NewContextManager(storage, strategy, DefaultMaxTokens)
+		return NewContextManager(storage, strategy, contextBudget(DefaultMaxTokens, reservedTokens))
 	}
 
 	// Set default values if not specified
@@ -40,6 +49,7 @@ func NewContextManagerFromConfig(
 	if maxTokens == 0 {
 		maxTokens = DefaultMaxTokens
 	}
+	maxTokens = contextBudget(maxTokens, reservedTokens)
 
 	recentMessageCount := contextCfg.RecentMessageCount
 	if recentMessageCount == 0 {
@@ -60,19 +70,28 @@ func NewContextManagerFromConfig(
 	var strategy interfaces.CompressionStrategy
 	switch compressionStrategy {
 	case "sliding_window":
-		strategy = NewSlidingWindowStrategy(recentMessageCount)
+		strategy = NewSlidingWindowStrategy(recentMessageCount, tok)
 	case "smart":
 		if chatModel != nil {
-			strategy = NewSmartCompressionStrategy(recentMessageCount, chatModel, summarizeThreshold)
+			strategy = NewSmartCompressionStrategy(recentMessageCount, chatModel, summarizeThreshold, tok)
 		} else {
 			logger.Warn(context.TODO(), "Smart compression requested but no chat model provided, falling back to sliding window")
-			strategy = NewSlidingWindowStrategy(recentMessageCount)
+			strategy = NewSlidingWindowStrategy(recentMessageCount, tok)
 		}
 	default:
 		logger.Warnf(context.TODO(), "Unknown compression strategy '%s', using sliding window", compressionStrategy)
-		strategy = NewSlidingWindowStrategy(recentMessageCount)
+		strategy = NewSlidingWindowStrategy(recentMessageCount, tok)
 	}
 
 	// Create context manager with storage and strategy
 	return NewContextManager(storage, strategy, maxTokens)
 }
+
+// contextBudget returns the tokens left for context once the completion is reserved.
+// Reservations taking more than half of the window are capped there.
+func contextBudget(maxTokens, reservedTokens int) int {
+	if reservedTokens <= 0 {
+		return maxTokens
+	}
+	return maxTokens - min(reservedTokens, maxTokens/2)
+}
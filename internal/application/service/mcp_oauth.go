@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+)
+
+// mcpOAuthClientName is the client name used when registering with an authorization server dynamically
+const mcpOAuthClientName = "WeKnora"
+
+var (
+	// ErrMCPServiceNotFound is returned when the MCP service does not exist in the tenant
+	ErrMCPServiceNotFound = errors.New("MCP service not found")
+	// ErrMCPOAuthNotConfigured is returned when the MCP service does not use OAuth
+	ErrMCPOAuthNotConfigured = errors.New("MCP service does not use OAuth")
+	// ErrMCPOAuthUserRequired is returned when a per-user authorization is requested without a signed-in user
+	ErrMCPOAuthUserRequired = errors.New("MCP service is authorized per user, sign in to authorize it")
+	// ErrInvalidOAuthState is returned when the callback state is unknown or expired
+	ErrInvalidOAuthState = errors.New("OAuth authorization is unknown or expired, start it again")
+	// ErrOAuthBrowserMismatch is returned when the callback does not come from the browser which
+	// started the authorization, such as a link to an authorization started by someone else
+	ErrOAuthBrowserMismatch = errors.New("OAuth authorization was started in another browser, start it again")
+	// ErrInvalidReturnURL is returned when the return URL is not on the API host
+	ErrInvalidReturnURL = errors.New("return URL must be a path or on the API host")
+)
+
+// mcpOAuthService implements MCPOAuthService interface
+type mcpOAuthService struct {
+	mcpServiceRepo interfaces.MCPServiceRepository
+	oauthRepo      interfaces.MCPOAuthRepository
+	mcpManager     *mcp.MCPManager
+}
+
+// NewMCPOAuthService creates a new MCP OAuth service
+func NewMCPOAuthService(
+	mcpServiceRepo interfaces.MCPServiceRepository,
+	oauthRepo interfaces.MCPOAuthRepository,
+	mcpManager *mcp.MCPManager,
+) interfaces.MCPOAuthService {
+	return &mcpOAuthService{
+		mcpServiceRepo: mcpServiceRepo,
+		oauthRepo:      oauthRepo,
+		mcpManager:     mcpManager,
+	}
+}
+
+// Authorize starts the authorization of a service and returns the URL of the authorization server,
+// and the nonce the browser must present on the callback
+func (s *mcpOAuthService) Authorize(
+	ctx context.Context,
+	serviceID, redirectURI, returnURL string,
+) (string, string, error) {
+	service, err := s.getOAuthService(ctx, serviceID)
+	if err != nil {
+		return "", "", err
+	}
+	oauth := service.OAuthConfig()
+	userID := mcp.OAuthUserID(ctx, service)
+	if oauth.PerUser && userID == "" {
+		return "", "", ErrMCPOAuthUserRequired
+	}
+	if oauth.RedirectURI != "" {
+		redirectURI = oauth.RedirectURI
+	}
+	if err := validateReturnURL(returnURL, redirectURI); err != nil {
+		return "", "", err
+	}
+
+	handler := mcp.NewOAuthHandler(service, "", "", redirectURI, nil)
+	var clientID, clientSecret string
+	if oauth.ClientID == "" {
+		if err := handler.RegisterClient(ctx, mcpOAuthClientName); err != nil {
+			logger.GetLogger(ctx).Errorf("Failed to register OAuth client for MCP service %s: %v", serviceID, err)
+			return "", "", fmt.Errorf("failed to register OAuth client: %w", err)
+		}
+		clientID, clientSecret = handler.GetClientID(), handler.GetClientSecret()
+	}
+
+	verifier, err := mcpclient.GenerateCodeVerifier()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	state, err := mcpclient.GenerateState()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := mcpclient.GenerateState()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate browser nonce: %w", err)
+	}
+	authURL, err := handler.GetAuthorizationURL(ctx, state, mcpclient.GenerateCodeChallenge(verifier))
+	if err != nil {
+		logger.GetLogger(ctx).Errorf("Failed to build OAuth authorization URL for MCP service %s: %v", serviceID, err)
+		return "", "", fmt.Errorf("failed to build authorization URL: %w", err)
+	}
+
+	encryptedVerifier, err := secutils.EncryptSecret(verifier)
+	if err != nil {
+		return "", "", err
+	}
+	encryptedSecret := ""
+	if clientSecret != "" {
+		if encryptedSecret, err = secutils.EncryptSecret(clientSecret); err != nil {
+			return "", "", err
+		}
+	}
+	now := time.Now()
+	if err := s.oauthRepo.CreateState(ctx, &types.MCPOAuthState{
+		State:        state,
+		ServiceID:    service.ID,
+		UserID:       userID,
+		TenantID:     service.TenantID,
+		CodeVerifier: encryptedVerifier,
+		RedirectURI:  redirectURI,
+		ClientID:     clientID,
+		ClientSecret: encryptedSecret,
+		ReturnURL:    returnURL,
+		BrowserNonce: hashOAuthNonce(nonce),
+		ExpiresAt:    now.Add(types.MCPOAuthStateTTL),
+		CreatedAt:    now,
+	}); err != nil {
+		return "", "", fmt.Errorf("failed to save OAuth state: %w", err)
+	}
+
+	logger.GetLogger(ctx).Infof("Started OAuth authorization of MCP service %s for user %q", serviceID, userID)
+	return authURL, nonce, nil
+}
+
+// HandleCallback exchanges the authorization code for a token and returns the URL to return to.
+// The state is consumed even when the nonce does not match, so that a leaked state cannot be retried.
+func (s *mcpOAuthService) HandleCallback(ctx context.Context, state, nonce, code, authErr string) (string, error) {
+	pending, err := s.oauthRepo.ConsumeState(ctx, state)
+	if err != nil {
+		return "", fmt.Errorf("failed to get OAuth state: %w", err)
+	}
+	if pending == nil {
+		return "", ErrInvalidOAuthState
+	}
+	if nonce == "" ||
+		subtle.ConstantTimeCompare([]byte(hashOAuthNonce(nonce)), []byte(pending.BrowserNonce)) != 1 {
+		logger.GetLogger(ctx).Warnf("OAuth callback of MCP service %s came from another browser", pending.ServiceID)
+		return "", ErrOAuthBrowserMismatch
+	}
+	if authErr != "" {
+		return pending.ReturnURL, fmt.Errorf("authorization denied: %s", authErr)
+	}
+
+	service, err := s.mcpServiceRepo.GetByID(ctx, pending.TenantID, pending.ServiceID)
+	if err != nil {
+		return pending.ReturnURL, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return pending.ReturnURL, ErrMCPServiceNotFound
+	}
+	if service.OAuthConfig() == nil {
+		return pending.ReturnURL, ErrMCPOAuthNotConfigured
+	}
+
+	verifier, err := secutils.DecryptSecret(pending.CodeVerifier)
+	if err != nil {
+		return pending.ReturnURL, fmt.Errorf("failed to decrypt code verifier: %w", err)
+	}
+	clientSecret := ""
+	if pending.ClientSecret != "" {
+		if clientSecret, err = secutils.DecryptSecret(pending.ClientSecret); err != nil {
+			return pending.ReturnURL, fmt.Errorf("failed to decrypt client secret: %w", err)
+		}
+	}
+
+	store := mcp.NewOAuthTokenStore(s.oauthRepo, service, pending.UserID, pending.ClientID, clientSecret)
+	handler := mcp.NewOAuthHandler(service, pending.ClientID, clientSecret, pending.RedirectURI, store)
+	handler.SetExpectedState(state)
+	if err := handler.ProcessAuthorizationResponse(ctx, code, state, verifier); err != nil {
+		logger.GetLogger(ctx).Errorf("Failed to exchange OAuth code for MCP service %s: %v", service.ID, err)
+		return pending.ReturnURL, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	// Reconnect clients which failed to initialize without a token
+	s.mcpManager.CloseClient(service.ID)
+	logger.GetLogger(ctx).Infof("Authorized MCP service %s for user %q", service.ID, pending.UserID)
+	return pending.ReturnURL, nil
+}
+
+// GetStatus returns the authorization status of a service for the calling user
+func (s *mcpOAuthService) GetStatus(ctx context.Context, serviceID string) (*types.MCPOAuthStatus, error) {
+	service, err := s.getService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	oauth := service.OAuthConfig()
+	if oauth == nil {
+		return &types.MCPOAuthStatus{}, nil
+	}
+	status := &types.MCPOAuthStatus{Enabled: true, PerUser: oauth.PerUser}
+	userID := mcp.OAuthUserID(ctx, service)
+	if oauth.PerUser && userID == "" {
+		return status, nil
+	}
+
+	token, err := s.oauthRepo.GetToken(ctx, service.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OAuth token: %w", err)
+	}
+	if token != nil {
+		status.Authorized = true
+		status.Scope = token.Scope
+		status.UpdatedAt = &token.UpdatedAt
+		if !token.ExpiresAt.IsZero() {
+			status.ExpiresAt = &token.ExpiresAt
+		}
+	}
+	return status, nil
+}
+
+// Revoke deletes the token of a service for the calling user
+func (s *mcpOAuthService) Revoke(ctx context.Context, serviceID string) error {
+	service, err := s.getOAuthService(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	userID := mcp.OAuthUserID(ctx, service)
+	if service.OAuthConfig().PerUser && userID == "" {
+		return ErrMCPOAuthUserRequired
+	}
+	if err := s.oauthRepo.DeleteToken(ctx, service.ID, userID); err != nil {
+		return fmt.Errorf("failed to delete OAuth token: %w", err)
+	}
+	s.mcpManager.CloseClient(service.ID)
+	logger.GetLogger(ctx).Infof("Revoked OAuth token of MCP service %s for user %q", serviceID, userID)
+	return nil
+}
+
+// getService returns a service of the tenant of the context
+func (s *mcpOAuthService) getService(ctx context.Context, serviceID string) (*types.MCPService, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, ErrMCPServiceNotFound
+	}
+	return service, nil
+}
+
+// getOAuthService returns a service of the tenant of the context which uses OAuth
+func (s *mcpOAuthService) getOAuthService(ctx context.Context, serviceID string) (*types.MCPService, error) {
+	service, err := s.getService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if service.OAuthConfig() == nil {
+		return nil, ErrMCPOAuthNotConfigured
+	}
+	return service, nil
+}
+
+// validateReturnURL only accepts paths and URLs on the host of the redirect URI, so that the
+// callback cannot be used to redirect users to other sites
+func validateReturnURL(returnURL, redirectURI string) error {
+	if returnURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(returnURL)
+	if err != nil {
+		return ErrInvalidReturnURL
+	}
+	if parsed.Scheme == "" && parsed.Host == "" {
+		if !strings.HasPrefix(returnURL, "/") || strings.HasPrefix(returnURL, "//") {
+			return ErrInvalidReturnURL
+		}
+		return nil
+	}
+	redirect, err := url.Parse(redirectURI)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host != redirect.Host {
+		return ErrInvalidReturnURL
+	}
+	return nil
+}
+
+// hashOAuthNonce hashes the browser nonce of an authorization, only the hash is stored
+func hashOAuthNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	mcpclient "github.com/mark3labs/mcp-go/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuthServer is an OAuth authorization server supporting dynamic registration, PKCE and refresh
+type fakeAuthServer struct {
+	mu         sync.Mutex
+	challenges map[string]string // code -> code challenge
+	refreshes  int
+}
+
+func newFakeAuthServer(t *testing.T) *httptest.Server {
+	fake := &fakeAuthServer{challenges: map[string]string{"good-code": ""}}
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"registration_endpoint":  server.URL + "/register",
+		})
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"client_id": "registered", "client_secret": "s3cret"})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if r.Form.Get("client_id") != "registered" || r.Form.Get("client_secret") != "s3cret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			challenge, ok := fake.challenges[r.Form.Get("code")]
+			if !ok || challenge != mcpclient.GenerateCodeChallenge(r.Form.Get("code_verifier")) {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access-1", "refresh_token": "refresh-1", "token_type": "Bearer", "expires_in": 3600,
+			})
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "refresh-1" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			fake.refreshes++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access-2", "token_type": "Bearer", "expires_in": 3600,
+			})
+		}
+	})
+	// Record the code challenge of each authorization so that the token endpoint can verify PKCE
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		fake.challenges["good-code"] = r.URL.Query().Get("code_challenge")
+		fake.mu.Unlock()
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+// fakeMCPServiceRepo serves a single MCP service
+type fakeMCPServiceRepo struct {
+	interfaces.MCPServiceRepository
+	service *types.MCPService
+}
+
+func (r *fakeMCPServiceRepo) GetByID(ctx context.Context, tenantID uint64, id string) (*types.MCPService, error) {
+	if r.service.TenantID != tenantID || r.service.ID != id {
+		return nil, nil
+	}
+	return r.service, nil
+}
+
+// fakeMCPOAuthRepo keeps tokens and states in memory
+type fakeMCPOAuthRepo struct {
+	tokens map[string]*types.MCPOAuthToken
+	states map[string]*types.MCPOAuthState
+}
+
+func newFakeMCPOAuthRepo() *fakeMCPOAuthRepo {
+	return &fakeMCPOAuthRepo{
+		tokens: make(map[string]*types.MCPOAuthToken),
+		states: make(map[string]*types.MCPOAuthState),
+	}
+}
+
+func (r *fakeMCPOAuthRepo) GetToken(ctx context.Context, serviceID, userID string) (*types.MCPOAuthToken, error) {
+	return r.tokens[serviceID+"/"+userID], nil
+}
+
+func (r *fakeMCPOAuthRepo) SaveToken(ctx context.Context, token *types.MCPOAuthToken) error {
+	r.tokens[token.ServiceID+"/"+token.UserID] = token
+	return nil
+}
+
+func (r *fakeMCPOAuthRepo) DeleteToken(ctx context.Context, serviceID, userID string) error {
+	delete(r.tokens, serviceID+"/"+userID)
+	return nil
+}
+
+func (r *fakeMCPOAuthRepo) DeleteServiceTokens(ctx context.Context, serviceID string) error {
+	return nil
+}
+
+func (r *fakeMCPOAuthRepo) CreateState(ctx context.Context, state *types.MCPOAuthState) error {
+	r.states[state.State] = state
+	return nil
+}
+
+func (r *fakeMCPOAuthRepo) ConsumeState(ctx context.Context, state string) (*types.MCPOAuthState, error) {
+	pending := r.states[state]
+	delete(r.states, state)
+	if pending == nil || pending.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+	return pending, nil
+}
+
+func TestMCPOAuthFlow(t *testing.T) {
+	t.Setenv("TENANT_AES_KEY", "0123456789abcdef0123456789abcdef")
+	authServer := newFakeAuthServer(t)
+	defer authServer.Close()
+
+	serviceURL := authServer.URL + "/mcp"
+	service := &types.MCPService{
+		ID:            "svc",
+		TenantID:      1,
+		TransportType: types.MCPTransportHTTPStreamable,
+		URL:           &serviceURL,
+		AuthConfig:    &types.MCPAuthConfig{OAuth: &types.MCPOAuthConfig{PerUser: true}},
+	}
+	oauthRepo := newFakeMCPOAuthRepo()
+	manager := mcp.NewMCPManager(oauthRepo)
+	defer manager.Shutdown()
+	oauthService := NewMCPOAuthService(&fakeMCPServiceRepo{service: service}, oauthRepo, manager)
+
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	_, _, err := oauthService.Authorize(ctx, "svc", "http://api.local/callback", "")
+	assert.ErrorIs(t, err, ErrMCPOAuthUserRequired)
+
+	ctx = context.WithValue(ctx, types.UserContextKey, &types.User{ID: "alice", TenantID: 1})
+	_, _, err = oauthService.Authorize(ctx, "svc", "http://api.local/callback", "https://evil.example/")
+	assert.ErrorIs(t, err, ErrInvalidReturnURL)
+
+	authURL, nonce, err := oauthService.Authorize(ctx, "svc", "http://api.local/callback", "/agents")
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, "registered", query.Get("client_id"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	state := query.Get("state")
+	require.NotEmpty(t, state)
+	require.NotEmpty(t, nonce)
+	assert.NotEqual(t, nonce, oauthRepo.states[state].BrowserNonce, "only the hash of the nonce is stored")
+
+	// The user consents at the authorization server, which redirects to the callback
+	resp, err := http.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	_, err = oauthService.HandleCallback(ctx, "unknown", nonce, "good-code", "")
+	assert.ErrorIs(t, err, ErrInvalidOAuthState)
+
+	returnURL, err := oauthService.HandleCallback(context.Background(), state, nonce, "good-code", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/agents", returnURL)
+
+	stored := oauthRepo.tokens["svc/alice"]
+	require.NotNil(t, stored)
+	assert.NotEqual(t, "access-1", stored.AccessToken)
+	accessToken, err := secutils.DecryptSecret(stored.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", accessToken)
+	assert.Equal(t, "registered", stored.ClientID)
+
+	status, err := oauthService.GetStatus(ctx, "svc")
+	require.NoError(t, err)
+	assert.True(t, status.Authorized)
+	assert.True(t, status.PerUser)
+
+	// Another user has not authorized the service
+	bobCtx := context.WithValue(ctx, types.UserContextKey, &types.User{ID: "bob", TenantID: 1})
+	_, err = manager.NewClientConfig(bobCtx, service)
+	assert.ErrorIs(t, err, mcp.ErrOAuthAuthorizationRequired)
+
+	// Expired tokens are refreshed and saved back
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	clientSecret, err := secutils.DecryptSecret(stored.ClientSecret)
+	require.NoError(t, err)
+	store := mcp.NewOAuthTokenStore(oauthRepo, service, "alice", stored.ClientID, clientSecret)
+	handler := mcp.NewOAuthHandler(service, stored.ClientID, clientSecret, "", store)
+	header, err := handler.GetAuthorizationHeader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer access-2", header)
+	token, err := store.GetToken(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "access-2", token.AccessToken)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+
+	require.NoError(t, oauthService.Revoke(ctx, "svc"))
+	status, err = oauthService.GetStatus(ctx, "svc")
+	require.NoError(t, err)
+	assert.False(t, status.Authorized)
+}
+
+func TestMCPOAuthCallbackFromAnotherBrowser(t *testing.T) {
+	t.Setenv("TENANT_AES_KEY", "0123456789abcdef0123456789abcdef")
+	authServer := newFakeAuthServer(t)
+	defer authServer.Close()
+
+	serviceURL := authServer.URL + "/mcp"
+	service := &types.MCPService{
+		ID:            "svc",
+		TenantID:      1,
+		TransportType: types.MCPTransportHTTPStreamable,
+		URL:           &serviceURL,
+		AuthConfig:    &types.MCPAuthConfig{OAuth: &types.MCPOAuthConfig{PerUser: true}},
+	}
+	oauthRepo := newFakeMCPOAuthRepo()
+	manager := mcp.NewMCPManager(oauthRepo)
+	defer manager.Shutdown()
+	oauthService := NewMCPOAuthService(&fakeMCPServiceRepo{service: service}, oauthRepo, manager)
+
+	// An attacker starts authorizations and sends their links to a victim, whose browser
+	// has no nonce or the one of its own authorization
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.UserContextKey, &types.User{ID: "mallory", TenantID: 1})
+	for _, victimNonce := range []string{"", "victim-nonce"} {
+		authURL, nonce, err := oauthService.Authorize(ctx, "svc", "http://api.local/callback", "/agents")
+		require.NoError(t, err)
+		parsed, err := url.Parse(authURL)
+		require.NoError(t, err)
+		state := parsed.Query().Get("state")
+
+		returnURL, err := oauthService.HandleCallback(context.Background(), state, victimNonce, "good-code", "")
+		assert.ErrorIs(t, err, ErrOAuthBrowserMismatch)
+		assert.Empty(t, returnURL)
+
+		// The rejected callback consumed the state
+		_, err = oauthService.HandleCallback(context.Background(), state, nonce, "good-code", "")
+		assert.ErrorIs(t, err, ErrInvalidOAuthState)
+	}
+	assert.Empty(t, oauthRepo.tokens)
+}
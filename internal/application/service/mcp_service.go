@@ -16,16 +16,19 @@ import (
 type mcpServiceService struct {
 	mcpServiceRepo interfaces.MCPServiceRepository
 	mcpManager     *mcp.MCPManager
+	oauthRepo      interfaces.MCPOAuthRepository
 }
 
 // NewMCPServiceService creates a new MCP service service
 func NewMCPServiceService(
 	mcpServiceRepo interfaces.MCPServiceRepository,
 	mcpManager *mcp.MCPManager,
+	oauthRepo interfaces.MCPOAuthRepository,
 ) interfaces.MCPServiceService {
 	return &mcpServiceService{
 		mcpServiceRepo: mcpServiceRepo,
 		mcpManager:     mcpManager,
+		oauthRepo:      oauthRepo,
 	}
 }
 
@@ -266,6 +269,9 @@ func (s *mcpServiceService) DeleteMCPService(ctx context.Context, tenantID uint6
 		logger.GetLogger(ctx).Errorf("Failed to delete MCP service: %v", err)
 		return fmt.Errorf("failed to delete MCP service: %w", err)
 	}
+	if err := s.oauthRepo.DeleteServiceTokens(ctx, id); err != nil {
+		logger.GetLogger(ctx).Warnf("Failed to delete OAuth tokens of MCP service %s: %v", id, err)
+	}
 
 	logger.GetLogger(ctx).Infof("MCP service deleted: %s (ID: %s)", secutils.SanitizeForLog(existing.Name), id)
 	return nil
@@ -287,8 +293,12 @@ func (s *mcpServiceService) TestMCPService(
 	}
 
 	// Create temporary client for testing
-	config := &mcp.ClientConfig{
-		Service: service,
+	config, err := s.mcpManager.NewClientConfig(ctx, service)
+	if err != nil {
+		return &types.MCPTestResult{
+			Success: false,
+			Message: fmt.Sprintf("Failed to create client: %v", err),
+		}, nil
 	}
 
 	client, err := mcp.NewMCPClient(config)
@@ -320,12 +330,13 @@ func (s *mcpServiceService) TestMCPService(
 		}, nil
 	}
 
-	// List tools
+	// List tools, and drop the cached tool list so that agents pick up the tested one
 	tools, err := client.ListTools(testCtx)
 	if err != nil {
 		logger.GetLogger(ctx).Warnf("Failed to list tools: %v", err)
 		tools = []*types.MCPTool{}
 	}
+	s.mcpManager.InvalidateTools(id)
 
 	// List resources
 	resources, err := client.ListResources(testCtx)
@@ -334,6 +345,13 @@ func (s *mcpServiceService) TestMCPService(
 		resources = []*types.MCPResource{}
 	}
 
+	// List prompts
+	prompts, err := client.ListPrompts(testCtx)
+	if err != nil {
+		logger.GetLogger(ctx).Warnf("Failed to list prompts: %v", err)
+		prompts = []*types.MCPPrompt{}
+	}
+
 	return &types.MCPTestResult{
 		Success: true,
 		Message: fmt.Sprintf(
@@ -343,6 +361,7 @@ func (s *mcpServiceService) TestMCPService(
 		),
 		Tools:     tools,
 		Resources: resources,
+		Prompts:   prompts,
 	}, nil
 }
 
@@ -361,14 +380,8 @@ func (s *mcpServiceService) GetMCPServiceTools(
 		return nil, fmt.Errorf("MCP service not found")
 	}
 
-	// Get or create client
-	client, err := s.mcpManager.GetOrCreateClient(service)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get MCP client: %w", err)
-	}
-
-	// List tools
-	tools, err := client.ListTools(ctx)
+	// List tools, cached until the service notifies that they changed
+	tools, err := s.mcpManager.ListTools(ctx, service)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tools: %w", err)
 	}
@@ -392,7 +405,7 @@ func (s *mcpServiceService) GetMCPServiceResources(
 	}
 
 	// Get or create client
-	client, err := s.mcpManager.GetOrCreateClient(service)
+	client, err := s.mcpManager.GetOrCreateClientFor(ctx, service)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get MCP client: %w", err)
 	}
@@ -406,6 +419,36 @@ func (s *mcpServiceService) GetMCPServiceResources(
 	return resources, nil
 }
 
+// GetMCPServicePrompts retrieves the list of prompts from an MCP service
+func (s *mcpServiceService) GetMCPServicePrompts(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) ([]*types.MCPPrompt, error) {
+	// Get service
+	service, err := s.mcpServiceRepo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP service: %w", err)
+	}
+	if service == nil {
+		return nil, fmt.Errorf("MCP service not found")
+	}
+
+	// Get or create client
+	client, err := s.mcpManager.GetOrCreateClientFor(ctx, service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP client: %w", err)
+	}
+
+	// List prompts
+	prompts, err := client.ListPrompts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	return prompts, nil
+}
+
 // equalStringSlices compares two string slices for equality
 func equalStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
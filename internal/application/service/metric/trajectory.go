@@ -0,0 +1,75 @@
+package metric
+
+import (
+	"encoding/json"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// TrajectoryMetric scores the tool-use trajectory of an agent execution
+type TrajectoryMetric struct{}
+
+// NewTrajectoryMetric creates a new TrajectoryMetric instance
+func NewTrajectoryMetric() *TrajectoryMetric {
+	return &TrajectoryMetric{}
+}
+
+// Compute scores the steps of an execution against the passages expected to be retrieved
+func (m *TrajectoryMetric) Compute(state *types.AgentState, expected []int) *types.TrajectoryMetrics {
+	result := &types.TrajectoryMetrics{
+		Iterations: float64(state.CurrentRound),
+	}
+	if state.IsComplete {
+		result.Completed = 1
+	}
+
+	// A call is redundant when the same tool was already called with identical arguments
+	seen := make(map[string]struct{})
+	for _, step := range state.RoundSteps {
+		for _, call := range step.ToolCalls {
+			result.ToolCalls++
+			if call.Result == nil || !call.Result.Success {
+				result.FailedToolCalls++
+			}
+			args, _ := json.Marshal(call.Args)
+			key := call.Name + ":" + string(args)
+			if _, ok := seen[key]; ok {
+				result.RedundantToolCalls++
+			}
+			seen[key] = struct{}{}
+		}
+	}
+
+	retrieved := ToSet(RetrievedChunkIndexes(state.RoundSteps))
+	if len(expected) > 0 && Hit(expected, retrieved) == len(expected) {
+		result.ExpectedRetrieved = 1
+	}
+	return result
+}
+
+// RetrievedChunkIndexes returns the indexes of the chunks returned by the knowledge
+// search tool calls of the steps, in retrieval order and without duplicates
+func RetrievedChunkIndexes(steps []types.AgentStep) []int {
+	indexes := make([]int, 0)
+	seen := make(map[int]struct{})
+	for _, step := range steps {
+		for _, call := range step.ToolCalls {
+			if call.Result == nil || call.Result.Data["display_type"] != "search_results" {
+				continue
+			}
+			results, _ := call.Result.Data["results"].([]map[string]interface{})
+			for _, r := range results {
+				index, ok := r["chunk_index"].(int)
+				if !ok {
+					continue
+				}
+				if _, ok := seen[index]; ok {
+					continue
+				}
+				seen[index] = struct{}{}
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	return indexes
+}
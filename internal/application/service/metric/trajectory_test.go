@@ -0,0 +1,93 @@
+package metric
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+func searchCall(query string, chunkIndexes ...int) types.ToolCall {
+	results := make([]map[string]interface{}, 0, len(chunkIndexes))
+	for _, index := range chunkIndexes {
+		results = append(results, map[string]interface{}{"chunk_index": index})
+	}
+	return types.ToolCall{
+		Name: "knowledge_search",
+		Args: map[string]interface{}{"query": query},
+		Result: &types.ToolResult{
+			Success: true,
+			Data:    map[string]interface{}{"display_type": "search_results", "results": results},
+		},
+	}
+}
+
+func TestTrajectoryMetric_Compute(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    *types.AgentState
+		expected []int
+		want     *types.TrajectoryMetrics
+	}{
+		{
+			name: "expected passages retrieved without redundant calls",
+			state: &types.AgentState{
+				CurrentRound: 2,
+				IsComplete:   true,
+				RoundSteps: []types.AgentStep{
+					{ToolCalls: []types.ToolCall{searchCall("rag", 1, 2)}},
+					{ToolCalls: []types.ToolCall{searchCall("retrieval", 3)}},
+				},
+			},
+			expected: []int{1, 3},
+			want: &types.TrajectoryMetrics{
+				Iterations: 2, ToolCalls: 2, ExpectedRetrieved: 1, Completed: 1,
+			},
+		},
+		{
+			name: "repeated and failed calls",
+			state: &types.AgentState{
+				CurrentRound: 3,
+				RoundSteps: []types.AgentStep{
+					{ToolCalls: []types.ToolCall{searchCall("rag", 1)}},
+					{ToolCalls: []types.ToolCall{
+						searchCall("rag", 1),
+						{Name: "web_search", Result: &types.ToolResult{Success: false}},
+					}},
+				},
+			},
+			expected: []int{1, 2},
+			want: &types.TrajectoryMetrics{
+				Iterations: 3, ToolCalls: 3, RedundantToolCalls: 1, FailedToolCalls: 1,
+			},
+		},
+		{
+			name:     "no tool calls",
+			state:    &types.AgentState{CurrentRound: 1, IsComplete: true},
+			expected: []int{1},
+			want:     &types.TrajectoryMetrics{Iterations: 1, Completed: 1},
+		},
+	}
+
+	tm := NewTrajectoryMetric()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tm.Compute(tt.state, tt.expected)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrievedChunkIndexes(t *testing.T) {
+	steps := []types.AgentStep{
+		{ToolCalls: []types.ToolCall{searchCall("a", 3, 1), {Name: "thinking"}}},
+		{ToolCalls: []types.ToolCall{searchCall("b", 1, 2)}},
+	}
+	got := RetrievedChunkIndexes(steps)
+	if want := []int{3, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RetrievedChunkIndexes() = %v, want %v", got, want)
+	}
+}
@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/chat"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/models/metering"
 	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/models/routing"
 	"github.com/Tencent/WeKnora/internal/models/utils/ollama"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
@@ -16,17 +19,27 @@ import (
 // ErrModelNotFound is returned when a model cannot be found in the repository
 var ErrModelNotFound = errors.New("model not found")
 
+// ErrInvalidModelRoute is returned when a fallback cannot stand in for the primary model of a route
+var ErrInvalidModelRoute = errors.New("invalid model route")
+
 // modelService implements the model service interface
 type modelService struct {
 	repo          interfaces.ModelRepository
 	ollamaService *ollama.OllamaService
+	usageService  interfaces.ModelUsageService
+	// breakers are shared by the tenants, as models are only shared through builtin models
+	breakers *routing.Breakers
 }
 
 // NewModelService creates a new model service instance
-func NewModelService(repo interfaces.ModelRepository, ollamaService *ollama.OllamaService) interfaces.ModelService {
+func NewModelService(repo interfaces.ModelRepository, ollamaService *ollama.OllamaService,
+	usageService interfaces.ModelUsageService,
+) interfaces.ModelService {
 	return &modelService{
 		repo:          repo,
 		ollamaService: ollamaService,
+		usageService:  usageService,
+		breakers:      routing.NewBreakers(),
 	}
 }
 
@@ -243,6 +256,35 @@ func (s *modelService) GetEmbeddingModel(ctx context.Context, modelId string) (e
 
 	logger.Infof(ctx, "Getting embedding model: %s, source: %s", model.Name, model.Source)
 
+	embedder, err := s.newEmbedder(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	router, fallbacks := s.route(ctx, model)
+	if router == nil {
+		logger.Info(ctx, "Embedding model initialized successfully")
+		return embedder, nil
+	}
+	fallbackEmbedders := make([]embedding.Embedder, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		if fallback.Parameters.EmbeddingParameters.Dimension != model.Parameters.EmbeddingParameters.Dimension {
+			logger.Warnf(ctx, "Skipping fallback embedding model %s of another dimension", fallback.ID)
+			continue
+		}
+		fallbackEmbedder, err := s.newEmbedder(ctx, fallback)
+		if err != nil {
+			continue
+		}
+		fallbackEmbedders = append(fallbackEmbedders, fallbackEmbedder)
+	}
+
+	logger.Infof(ctx, "Embedding model initialized successfully with %d fallbacks", len(fallbackEmbedders))
+	return routing.NewEmbedder(router, embedder, fallbackEmbedders...), nil
+}
+
+// newEmbedder initializes the embedder of a model
+func (s *modelService) newEmbedder(ctx context.Context, model *types.Model) (embedding.Embedder, error) {
 	// Initialize the embedder with model configuration
 	embedder, err := embedding.NewEmbedder(embedding.Config{
 		Source:               model.Source,
@@ -261,9 +303,7 @@ func (s *modelService) GetEmbeddingModel(ctx context.Context, modelId string) (e
 		})
 		return nil, err
 	}
-
-	logger.Info(ctx, "Embedding model initialized successfully")
-	return embedder, nil
+	return metering.NewEmbedder(embedder, model, s.usageService), nil
 }
 
 // GetRerankModel retrieves and initializes a reranking model instance
@@ -280,6 +320,37 @@ func (s *modelService) GetRerankModel(ctx context.Context, modelId string) (rera
 
 	logger.Infof(ctx, "Getting rerank model: %s, source: %s", model.Name, model.Source)
 
+	reranker, err := s.newReranker(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	router, fallbacks := s.route(ctx, model)
+	if router == nil {
+		logger.Info(ctx, "Rerank model initialized successfully")
+		return reranker, nil
+	}
+	fallbackRerankers := make([]rerank.Reranker, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		fallbackReranker, err := s.newReranker(ctx, fallback)
+		if err != nil {
+			continue
+		}
+		fallbackRerankers = append(fallbackRerankers, fallbackReranker)
+	}
+
+	logger.Infof(ctx, "Rerank model initialized successfully with %d fallbacks", len(fallbackRerankers))
+	return routing.NewReranker(router, reranker, fallbackRerankers...), nil
+}
+
+// newReranker initializes the reranker of a model
+func (s *modelService) newReranker(ctx context.Context, model *types.Model) (rerank.Reranker, error) {
+	// Rerank models configured with a chat model are served by the LLM reranker,
+	// whose usage is recorded by the chat model
+	if chatModelID := model.Parameters.ExtraConfig[rerank.LLMRerankChatModelKey]; chatModelID != "" {
+		return s.getLLMRerankModel(ctx, model, chatModelID)
+	}
+
 	// Initialize the reranker with model configuration
 	reranker, err := rerank.NewReranker(&rerank.RerankerConfig{
 		ModelID:   model.ID,
@@ -287,6 +358,7 @@ func (s *modelService) GetRerankModel(ctx context.Context, modelId string) (rera
 		BaseURL:   model.Parameters.BaseURL,
 		ModelName: model.Name,
 		Source:    model.Source,
+		Provider:  model.Parameters.Provider,
 	})
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
@@ -295,8 +367,29 @@ func (s *modelService) GetRerankModel(ctx context.Context, modelId string) (rera
 		})
 		return nil, err
 	}
+	return metering.NewReranker(reranker, model, s.usageService), nil
+}
+
+// getLLMRerankModel initializes a reranker that ranks documents with a chat model
+func (s *modelService) getLLMRerankModel(ctx context.Context,
+	model *types.Model, chatModelID string,
+) (rerank.Reranker, error) {
+	chatModel, err := s.GetChatModel(ctx, chatModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	reranker, err := rerank.NewLLMReranker(chatModel,
+		rerank.NewLLMRerankerConfig(model.ID, model.Name, model.Parameters.ExtraConfig))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"model_id":      model.ID,
+			"chat_model_id": chatModelID,
+		})
+		return nil, err
+	}
 
-	logger.Info(ctx, "Rerank model initialized successfully")
+	logger.Infof(ctx, "LLM rerank model initialized successfully, chat model: %s", chatModelID)
 	return reranker, nil
 }
 
@@ -328,6 +421,30 @@ func (s *modelService) GetChatModel(ctx context.Context, modelId string) (chat.C
 
 	logger.Infof(ctx, "Getting chat model: %s, source: %s", model.Name, model.Source)
 
+	chatModel, err := s.newChat(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	router, fallbacks := s.route(ctx, model)
+	if router == nil {
+		return chatModel, nil
+	}
+	fallbackChats := make([]chat.Chat, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		fallbackChat, err := s.newChat(ctx, fallback)
+		if err != nil {
+			continue
+		}
+		fallbackChats = append(fallbackChats, fallbackChat)
+	}
+
+	logger.Infof(ctx, "Chat model initialized with %d fallbacks", len(fallbackChats))
+	return routing.NewChat(router, chatModel, fallbackChats...), nil
+}
+
+// newChat initializes the chat model of a model
+func (s *modelService) newChat(ctx context.Context, model *types.Model) (chat.Chat, error) {
 	// Initialize the chat model with model configuration
 	chatModel, err := chat.NewChat(&chat.ChatConfig{
 		ModelID:   model.ID,
@@ -335,6 +452,7 @@ func (s *modelService) GetChatModel(ctx context.Context, modelId string) (chat.C
 		BaseURL:   model.Parameters.BaseURL,
 		ModelName: model.Name,
 		Source:    model.Source,
+		Provider:  model.Parameters.Provider,
 	})
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, map[string]interface{}{
@@ -343,8 +461,76 @@ func (s *modelService) GetChatModel(ctx context.Context, modelId string) (chat.C
 		})
 		return nil, err
 	}
+	return metering.NewChat(chatModel, model, s.usageService), nil
+}
+
+// route returns the router of the tenant in the context and the fallbacks of a model, or a nil router
+// when the tenant has no model routing. Models without fallbacks are still retried and circuit broken.
+// Fallbacks which are missing, inactive or of another type are skipped.
+func (s *modelService) route(ctx context.Context, model *types.Model) (*routing.Router, []*types.Model) {
+	tenant, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if !ok || tenant == nil || tenant.ModelRoutingConfig == nil {
+		return nil, nil
+	}
+	router := routing.NewRouter(s.breakers, tenant.ModelRoutingConfig)
+	route := tenant.ModelRoutingConfig.Route(model.ID)
+	if route == nil {
+		return router, nil
+	}
+
+	fallbacks := make([]*types.Model, 0, len(route.Fallbacks))
+	for _, fallbackID := range route.Fallbacks {
+		fallback, err := s.repo.GetByID(ctx, tenant.ID, fallbackID)
+		if err != nil || fallback == nil {
+			logger.Warnf(ctx, "Skipping missing fallback model %s of model %s", fallbackID, model.ID)
+			continue
+		}
+		if fallback.Type != model.Type || fallback.Status != types.ModelStatusActive {
+			logger.Warnf(ctx, "Skipping fallback model %s of type %s, status %s", fallbackID, fallback.Type, fallback.Status)
+			continue
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+	return router, fallbacks
+}
+
+// ValidateModelRouting checks that the models of the routes exist, that fallbacks have the type of
+// their primary model and that fallback embedding models have its dimension
+func (s *modelService) ValidateModelRouting(ctx context.Context, config *types.ModelRoutingConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidModelRoute, err)
+	}
 
-	return chatModel, nil
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	for _, route := range config.Routes {
+		primary, err := s.repo.GetByID(ctx, tenantID, route.ModelID)
+		if err != nil {
+			return err
+		}
+		if primary == nil {
+			return fmt.Errorf("%w: %s", ErrModelNotFound, route.ModelID)
+		}
+		for _, fallbackID := range route.Fallbacks {
+			fallback, err := s.repo.GetByID(ctx, tenantID, fallbackID)
+			if err != nil {
+				return err
+			}
+			if fallback == nil {
+				return fmt.Errorf("%w: %s", ErrModelNotFound, fallbackID)
+			}
+			if fallback.Type != primary.Type {
+				return fmt.Errorf("%w: fallback %s is a %s model, model %s is a %s model",
+					ErrInvalidModelRoute, fallbackID, fallback.Type, route.ModelID, primary.Type)
+			}
+			if primary.Type == types.ModelTypeEmbedding &&
+				fallback.Parameters.EmbeddingParameters.Dimension != primary.Parameters.EmbeddingParameters.Dimension {
+				return fmt.Errorf("%w: fallback %s has dimension %d, model %s has dimension %d",
+					ErrInvalidModelRoute, fallbackID, fallback.Parameters.EmbeddingParameters.Dimension,
+					route.ModelID, primary.Parameters.EmbeddingParameters.Dimension)
+			}
+		}
+	}
+	return nil
 }
 
 // Note: default model selection logic has been removed; models no longer
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// maxUsageStatsRange bounds the period usage statistics are aggregated over
+const maxUsageStatsRange = 366 * 24 * time.Hour
+
+// quotaCacheTTL bounds how long the cached token usage of a tenant is trusted before it is summed again,
+// which takes the usage recorded by other instances into account
+const quotaCacheTTL = 30 * time.Second
+
+// ErrInvalidUsageQuery is returned for usage statistics queries with an invalid dimension or period
+var ErrInvalidUsageQuery = errors.New("invalid usage query")
+
+// modelUsageService implements the ModelUsageService interface
+type modelUsageService struct {
+	usageRepo  interfaces.ModelUsageRepository
+	tenantRepo interfaces.TenantRepository
+
+	mu     sync.Mutex
+	usages map[uint64]*tenantTokenUsage // Token usage of the current period by tenant ID
+}
+
+// tenantTokenUsage is the cached token usage of a tenant in a quota period
+type tenantTokenUsage struct {
+	quota    int64     // Token quota of the tenant when loaded
+	used     int64     // Tokens used since the period start, including the calls recorded since loaded
+	period   time.Time // Start of the quota period
+	loadedAt time.Time
+}
+
+// NewModelUsageService creates a new model usage service
+func NewModelUsageService(
+	usageRepo interfaces.ModelUsageRepository,
+	tenantRepo interfaces.TenantRepository,
+) interfaces.ModelUsageService {
+	return &modelUsageService{
+		usageRepo:  usageRepo,
+		tenantRepo: tenantRepo,
+		usages:     make(map[uint64]*tenantTokenUsage),
+	}
+}
+
+// Record records a model call for the tenant, user and scope in the context.
+// Failures are logged, as accounting must not fail the call.
+func (s *modelUsageService) Record(ctx context.Context, usage *types.ModelUsage) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok || tenantID == 0 {
+		logger.Warnf(ctx, "Model usage of %s without tenant is not recorded", usage.ModelID)
+		return
+	}
+	usage.TenantID = tenantID
+	if user, ok := ctx.Value(types.UserContextKey).(*types.User); ok && user != nil {
+		usage.UserID = user.ID
+	}
+	scope := types.ModelUsageScopeFromContext(ctx)
+	usage.Source = scope.Source
+	usage.SessionID = scope.SessionID
+	usage.AgentID = scope.AgentID
+	s.addTokens(tenantID, int64(usage.TotalTokens))
+
+	// The usage is recorded even when the request was cancelled once the model answered
+	if err := s.usageRepo.Create(context.WithoutCancel(ctx), usage); err != nil {
+		logger.Warnf(ctx, "Failed to record model usage of %s: %v", usage.ModelID, err)
+	}
+}
+
+// CheckQuota returns a *types.TokenQuotaExceededError when the tenant in the context used its monthly token quota.
+// The usage is summed at most once per quotaCacheTTL, the calls recorded in between being counted in memory.
+func (s *modelUsageService) CheckQuota(ctx context.Context) error {
+	// Background tasks may only carry the tenant ID
+	tenantID, _ := ctx.Value(types.TenantIDContextKey).(uint64)
+	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant != nil {
+		if tenant.TokenQuota <= 0 {
+			return nil
+		}
+		tenantID = tenant.ID
+	}
+	if tenantID == 0 {
+		return nil
+	}
+
+	start, _ := types.TokenQuotaPeriod(time.Now())
+	usage, ok := s.cachedUsage(tenantID, start)
+	if !ok {
+		var err error
+		if usage, err = s.loadUsage(ctx, tenantID, tenant, start); err != nil {
+			// An unavailable usage table must not take the models down with it
+			logger.Warnf(ctx, "Failed to check token quota of tenant %d: %v", tenantID, err)
+			return nil
+		}
+	}
+	quota := usage.quota
+	if tenant != nil {
+		quota = tenant.TokenQuota
+	}
+	if quota <= 0 || usage.used < quota {
+		return nil
+	}
+	logger.Warnf(ctx, "Tenant %d exceeded its monthly token quota: %d of %d", tenantID, usage.used, quota)
+	return types.NewTokenQuotaExceededError(usage.used, quota)
+}
+
+// cachedUsage returns the cached token usage of a tenant unless it expired or belongs to another period
+func (s *modelUsageService) cachedUsage(tenantID uint64, period time.Time) (tenantTokenUsage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage, ok := s.usages[tenantID]
+	if !ok || !usage.period.Equal(period) || time.Since(usage.loadedAt) > quotaCacheTTL {
+		return tenantTokenUsage{}, false
+	}
+	return *usage, true
+}
+
+// loadUsage sums the token usage of a tenant since the period start and caches it.
+// The tenant is loaded for its quota when not given.
+func (s *modelUsageService) loadUsage(
+	ctx context.Context,
+	tenantID uint64,
+	tenant *types.Tenant,
+	period time.Time,
+) (tenantTokenUsage, error) {
+	if tenant == nil {
+		var err error
+		if tenant, err = s.tenantRepo.GetTenantByID(ctx, tenantID); err != nil {
+			return tenantTokenUsage{}, err
+		}
+		if tenant == nil {
+			return tenantTokenUsage{}, fmt.Errorf("tenant %d not found", tenantID)
+		}
+	}
+	used, err := s.usageRepo.SumTokens(ctx, tenantID, period)
+	if err != nil {
+		return tenantTokenUsage{}, err
+	}
+	usage := tenantTokenUsage{quota: tenant.TokenQuota, used: used, period: period, loadedAt: time.Now()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usages[tenantID] = &usage
+	return usage, nil
+}
+
+// addTokens counts tokens used by a tenant in its cached usage
+func (s *modelUsageService) addTokens(tenantID uint64, tokens int64) {
+	period, _ := types.TokenQuotaPeriod(time.Now())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if usage, ok := s.usages[tenantID]; ok && usage.period.Equal(period) {
+		usage.used += tokens
+	}
+}
+
+// GetQuotaStatus returns the token usage of the tenant in the context in the current month
+func (s *modelUsageService) GetQuotaStatus(ctx context.Context) (*types.TokenQuotaStatus, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	tenant, err := s.tenantRepo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	start, end := types.TokenQuotaPeriod(time.Now())
+	used, err := s.usageRepo.SumTokens(ctx, tenantID, start)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TokenQuotaStatus{
+		TokenQuota:  tenant.TokenQuota,
+		TokenUsed:   used,
+		PeriodStart: start,
+		PeriodEnd:   end,
+	}, nil
+}
+
+// GetUsageStats returns the usage statistics of the tenant in the context
+func (s *modelUsageService) GetUsageStats(
+	ctx context.Context,
+	query *types.ModelUsageQuery,
+) ([]*types.ModelUsageStat, error) {
+	switch query.GroupBy {
+	case types.ModelUsageGroupByDay, types.ModelUsageGroupByModel,
+		types.ModelUsageGroupByAgent, types.ModelUsageGroupBySource:
+	default:
+		return nil, fmt.Errorf("%w: group_by must be day, model, agent or source", ErrInvalidUsageQuery)
+	}
+	if !query.Start.Before(query.End) {
+		return nil, fmt.Errorf("%w: start must be before end", ErrInvalidUsageQuery)
+	}
+	if query.End.Sub(query.Start) > maxUsageStatsRange {
+		return nil, fmt.Errorf("%w: period must not exceed one year", ErrInvalidUsageQuery)
+	}
+
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	stats, err := s.usageRepo.Aggregate(ctx, tenantID, query)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"tenant_id": tenantID,
+			"group_by":  query.GroupBy,
+		})
+		return nil, err
+	}
+	return stats, nil
+}
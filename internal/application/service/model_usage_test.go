@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// countingUsageRepo keeps usage records in memory and counts the sums
+type countingUsageRepo struct {
+	interfaces.ModelUsageRepository
+	records []*types.ModelUsage
+	sums    int
+}
+
+func (r *countingUsageRepo) Create(ctx context.Context, usage *types.ModelUsage) error {
+	r.records = append(r.records, usage)
+	return nil
+}
+
+func (r *countingUsageRepo) SumTokens(ctx context.Context, tenantID uint64, since time.Time) (int64, error) {
+	r.sums++
+	var total int64
+	for _, usage := range r.records {
+		if usage.TenantID == tenantID {
+			total += int64(usage.TotalTokens)
+		}
+	}
+	return total, nil
+}
+
+// countingTenantRepo serves a single tenant and counts the loads
+type countingTenantRepo struct {
+	interfaces.TenantRepository
+	tenant *types.Tenant
+	loads  int
+}
+
+func (r *countingTenantRepo) GetTenantByID(ctx context.Context, id uint64) (*types.Tenant, error) {
+	r.loads++
+	return r.tenant, nil
+}
+
+func TestCheckQuotaCachesUsage(t *testing.T) {
+	tenant := &types.Tenant{ID: 1, TokenQuota: 100}
+	usageRepo := &countingUsageRepo{records: []*types.ModelUsage{{TenantID: 1, TotalTokens: 60}}}
+	s := NewModelUsageService(usageRepo, &countingTenantRepo{tenant: tenant})
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, tenant.ID)
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	for i := 0; i < 3; i++ {
+		if err := s.CheckQuota(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if usageRepo.sums != 1 {
+		t.Errorf("Expected the usage to be summed once, got %d sums", usageRepo.sums)
+	}
+
+	// Recorded calls count against the quota without summing again
+	s.Record(ctx, &types.ModelUsage{ModelID: "m", TotalTokens: 40})
+	var quotaErr *types.TokenQuotaExceededError
+	if err := s.CheckQuota(ctx); !errors.As(err, &quotaErr) {
+		t.Fatalf("Expected a quota error, got %v", err)
+	}
+	if usageRepo.sums != 1 {
+		t.Errorf("Expected the cached usage to be used, got %d sums", usageRepo.sums)
+	}
+
+	// Expired usage is summed again
+	s.(*modelUsageService).usages[tenant.ID].loadedAt = time.Now().Add(-2 * quotaCacheTTL)
+	if err := s.CheckQuota(ctx); !errors.As(err, &quotaErr) {
+		t.Fatalf("Expected a quota error, got %v", err)
+	}
+	if usageRepo.sums != 2 {
+		t.Errorf("Expected the expired usage to be summed again, got %d sums", usageRepo.sums)
+	}
+}
+
+func TestCheckQuotaLoadsTenantOfBackgroundTasks(t *testing.T) {
+	tenantRepo := &countingTenantRepo{tenant: &types.Tenant{ID: 1, TokenQuota: 100}}
+	s := NewModelUsageService(&countingUsageRepo{}, tenantRepo)
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+
+	for i := 0; i < 3; i++ {
+		if err := s.CheckQuota(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if tenantRepo.loads != 1 {
+		t.Errorf("Expected the tenant to be loaded once, got %d loads", tenantRepo.loads)
+	}
+}
+
+func TestRecordUser(t *testing.T) {
+	usageRepo := &countingUsageRepo{}
+	s := NewModelUsageService(usageRepo, &countingTenantRepo{})
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	ctx = context.WithValue(ctx, types.UserContextKey, &types.User{ID: "user-1"})
+
+	s.Record(ctx, &types.ModelUsage{ModelID: "m", TotalTokens: 10})
+	if len(usageRepo.records) != 1 || usageRepo.records[0].UserID != "user-1" {
+		t.Errorf("Expected the usage to be recorded for user-1, got %+v", usageRepo.records)
+	}
+}
@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/openapi"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+)
+
+// OpenAPI service related errors
+var (
+	ErrOpenAPIServiceNotFound = errors.New("OpenAPI service not found")
+	ErrInvalidOpenAPIService  = errors.New("invalid OpenAPI service")
+)
+
+// maskedMarker is contained in masked secrets returned to clients
+const maskedMarker = "****"
+
+// openAPIServiceService implements OpenAPIServiceService interface
+type openAPIServiceService struct {
+	repo interfaces.OpenAPIServiceRepository
+}
+
+// NewOpenAPIServiceService creates a new OpenAPI service service
+func NewOpenAPIServiceService(repo interfaces.OpenAPIServiceRepository) interfaces.OpenAPIServiceService {
+	return &openAPIServiceService{repo: repo}
+}
+
+// CreateOpenAPIService validates the document and creates a new OpenAPI service
+func (s *openAPIServiceService) CreateOpenAPIService(ctx context.Context, service *types.OpenAPIService) error {
+	if strings.TrimSpace(service.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidOpenAPIService)
+	}
+	if err := validateOpenAPIService(service); err != nil {
+		return err
+	}
+	if err := encryptAuthHeaders(service); err != nil {
+		return err
+	}
+
+	service.CreatedAt = time.Now()
+	service.UpdatedAt = time.Now()
+	if err := s.repo.Create(ctx, service); err != nil {
+		logger.Errorf(ctx, "Failed to create OpenAPI service: %v", err)
+		return fmt.Errorf("failed to create OpenAPI service: %w", err)
+	}
+
+	service.MaskSensitiveData()
+	logger.Infof(ctx, "OpenAPI service created: %s (ID: %s), operations: %v",
+		secutils.SanitizeForLog(service.Name), service.ID, service.Operations)
+	return nil
+}
+
+// GetOpenAPIServiceByID retrieves an OpenAPI service by ID, with masked auth headers
+func (s *openAPIServiceService) GetOpenAPIServiceByID(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) (*types.OpenAPIService, error) {
+	service, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	service.MaskSensitiveData()
+	return service, nil
+}
+
+// ListOpenAPIServices lists all OpenAPI services for a tenant, with masked auth headers
+func (s *openAPIServiceService) ListOpenAPIServices(
+	ctx context.Context,
+	tenantID uint64,
+) ([]*types.OpenAPIService, error) {
+	services, err := s.repo.List(ctx, tenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list OpenAPI services: %v", err)
+		return nil, fmt.Errorf("failed to list OpenAPI services: %w", err)
+	}
+	for _, service := range services {
+		if err := decryptAuthHeaders(service); err != nil {
+			logger.Warnf(ctx, "Failed to decrypt auth headers of OpenAPI service %s: %v", service.ID, err)
+		}
+		service.MaskSensitiveData()
+	}
+	return services, nil
+}
+
+// ListOpenAPIServicesForAgent retrieves the enabled services among the IDs, with decrypted auth headers
+func (s *openAPIServiceService) ListOpenAPIServicesForAgent(
+	ctx context.Context,
+	tenantID uint64,
+	ids []string,
+) ([]*types.OpenAPIService, error) {
+	services, err := s.repo.ListByIDs(ctx, tenantID, ids)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to list OpenAPI services by IDs: %v", err)
+		return nil, fmt.Errorf("failed to list OpenAPI services by IDs: %w", err)
+	}
+
+	enabled := make([]*types.OpenAPIService, 0, len(services))
+	for _, service := range services {
+		if !service.Enabled {
+			continue
+		}
+		if err := decryptAuthHeaders(service); err != nil {
+			// Calling the API without its credentials would fail anyway
+			logger.Warnf(ctx, "Skip OpenAPI service %s, failed to decrypt auth headers: %v", service.ID, err)
+			continue
+		}
+		enabled = append(enabled, service)
+	}
+	return enabled, nil
+}
+
+// UpdateOpenAPIService updates the provided fields of an OpenAPI service. Blank names and documents
+// are ignored, masked header values keep their stored value.
+func (s *openAPIServiceService) UpdateOpenAPIService(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+	update *types.OpenAPIServiceUpdate,
+) (*types.OpenAPIService, error) {
+	existing, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil && strings.TrimSpace(*update.Name) != "" {
+		existing.Name = *update.Name
+	}
+	if update.Description != nil {
+		existing.Description = *update.Description
+	}
+	if update.Enabled != nil {
+		existing.Enabled = *update.Enabled
+	}
+	if update.BaseURL != nil {
+		existing.BaseURL = *update.BaseURL
+	}
+	if update.Spec != nil && strings.TrimSpace(*update.Spec) != "" {
+		existing.Spec = *update.Spec
+	}
+	if update.Operations != nil {
+		existing.Operations = update.Operations
+	}
+	if update.AuthHeaders != nil {
+		headers := make(map[string]string, len(update.AuthHeaders))
+		for name, value := range update.AuthHeaders {
+			if strings.Contains(value, maskedMarker) {
+				value = existing.AuthHeaders[name]
+			}
+			headers[name] = value
+		}
+		existing.AuthHeaders = headers
+	}
+	if update.Timeout != nil {
+		existing.Timeout = *update.Timeout
+	}
+	if update.MaxResponseBytes != nil {
+		existing.MaxResponseBytes = *update.MaxResponseBytes
+	}
+
+	if err := validateOpenAPIService(existing); err != nil {
+		return nil, err
+	}
+	if err := encryptAuthHeaders(existing); err != nil {
+		return nil, err
+	}
+	existing.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, existing); err != nil {
+		logger.Errorf(ctx, "Failed to update OpenAPI service: %v", err)
+		return nil, fmt.Errorf("failed to update OpenAPI service: %w", err)
+	}
+
+	existing.MaskSensitiveData()
+	logger.Infof(ctx, "OpenAPI service updated: %s (ID: %s), enabled: %v",
+		secutils.SanitizeForLog(existing.Name), existing.ID, existing.Enabled)
+	return existing, nil
+}
+
+// DeleteOpenAPIService deletes an OpenAPI service
+func (s *openAPIServiceService) DeleteOpenAPIService(ctx context.Context, tenantID uint64, id string) error {
+	existing, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, tenantID, id); err != nil {
+		logger.Errorf(ctx, "Failed to delete OpenAPI service: %v", err)
+		return fmt.Errorf("failed to delete OpenAPI service: %w", err)
+	}
+	logger.Infof(ctx, "OpenAPI service deleted: %s (ID: %s)", secutils.SanitizeForLog(existing.Name), id)
+	return nil
+}
+
+// ListOpenAPIOperations lists the operations of the document of an OpenAPI service
+func (s *openAPIServiceService) ListOpenAPIOperations(
+	ctx context.Context,
+	tenantID uint64,
+	id string,
+) ([]*types.OpenAPIOperation, error) {
+	service, err := s.get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := openapi.Parse([]byte(service.Spec))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOpenAPIService, err)
+	}
+
+	selected := make(map[string]bool, len(service.Operations))
+	for _, operationID := range service.Operations {
+		selected[operationID] = true
+	}
+	operations := make([]*types.OpenAPIOperation, 0, len(spec.Operations))
+	for _, op := range spec.Operations {
+		operations = append(operations, &types.OpenAPIOperation{
+			OperationID: op.ID,
+			Method:      op.Method,
+			Path:        op.Path,
+			Summary:     op.Summary,
+			ToolName:    tools.OpenAPIToolName(service.Name, op.ID),
+			Selected:    selected[op.ID],
+		})
+	}
+	return operations, nil
+}
+
+// get retrieves an OpenAPI service with decrypted auth headers
+func (s *openAPIServiceService) get(ctx context.Context, tenantID uint64, id string) (*types.OpenAPIService, error) {
+	service, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get OpenAPI service: %v", err)
+		return nil, fmt.Errorf("failed to get OpenAPI service: %w", err)
+	}
+	if service == nil {
+		return nil, ErrOpenAPIServiceNotFound
+	}
+	if err := decryptAuthHeaders(service); err != nil {
+		return nil, fmt.Errorf("failed to decrypt auth headers: %w", err)
+	}
+	return service, nil
+}
+
+// validateOpenAPIService checks the document, the selected operations and the base URL,
+// which defaults to the first server of the document
+func validateOpenAPIService(service *types.OpenAPIService) error {
+	spec, err := openapi.Parse([]byte(service.Spec))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOpenAPIService, err)
+	}
+	for _, operationID := range service.Operations {
+		if spec.Operation(operationID) == nil {
+			return fmt.Errorf("%w: operation %s not found in the document", ErrInvalidOpenAPIService, operationID)
+		}
+	}
+
+	if strings.TrimSpace(service.BaseURL) == "" && len(spec.Servers) > 0 {
+		service.BaseURL = spec.Servers[0]
+	}
+	baseURL, err := url.Parse(service.BaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return fmt.Errorf("%w: base_url must be an absolute http(s) URL", ErrInvalidOpenAPIService)
+	}
+	if service.Timeout < 0 || service.MaxResponseBytes < 0 {
+		return fmt.Errorf("%w: timeout and max_response_bytes cannot be negative", ErrInvalidOpenAPIService)
+	}
+	return nil
+}
+
+// encryptAuthHeaders stores the auth headers encrypted
+func encryptAuthHeaders(service *types.OpenAPIService) error {
+	if len(service.AuthHeaders) == 0 {
+		service.EncryptedAuthHeaders = ""
+		return nil
+	}
+	data, err := json.Marshal(service.AuthHeaders)
+	if err != nil {
+		return err
+	}
+	encrypted, err := secutils.EncryptSecret(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt auth headers: %w", err)
+	}
+	service.EncryptedAuthHeaders = encrypted
+	return nil
+}
+
+// decryptAuthHeaders restores the auth headers from their encrypted form
+func decryptAuthHeaders(service *types.OpenAPIService) error {
+	if service.EncryptedAuthHeaders == "" {
+		return nil
+	}
+	data, err := secutils.DecryptSecret(service.EncryptedAuthHeaders)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), &service.AuthHeaders)
+}
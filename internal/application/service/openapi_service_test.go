@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+const updateTestSpec = `
+openapi: 3.0.3
+info:
+  title: Orders
+  version: 1.0.0
+servers:
+  - url: https://orders.example.com
+paths:
+  /orders/{id}:
+    get:
+      operationId: getOrder
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        200:
+          description: An order
+`
+
+// memoryOpenAPIServiceRepo stores a single OpenAPI service
+type memoryOpenAPIServiceRepo struct {
+	interfaces.OpenAPIServiceRepository
+	service types.OpenAPIService
+}
+
+func (r *memoryOpenAPIServiceRepo) GetByID(ctx context.Context, tenantID uint64, id string) (*types.OpenAPIService, error) {
+	if r.service.TenantID != tenantID || r.service.ID != id {
+		return nil, nil
+	}
+	service := r.service
+	return &service, nil
+}
+
+func (r *memoryOpenAPIServiceRepo) Update(ctx context.Context, service *types.OpenAPIService) error {
+	r.service = *service
+	return nil
+}
+
+func TestUpdateOpenAPIServicePartial(t *testing.T) {
+	t.Setenv("TENANT_AES_KEY", "0123456789abcdef0123456789abcdef")
+	stored := types.OpenAPIService{
+		ID:               "svc-1",
+		TenantID:         1,
+		Name:             "orders",
+		Description:      "Order lookup",
+		Enabled:          true,
+		BaseURL:          "https://internal.example.com",
+		Spec:             updateTestSpec,
+		Operations:       types.StringArray{"getOrder"},
+		AuthHeaders:      map[string]string{"Authorization": "Bearer secret-token"},
+		Timeout:          15,
+		MaxResponseBytes: 4096,
+	}
+	if err := encryptAuthHeaders(&stored); err != nil {
+		t.Fatalf("Failed to encrypt auth headers: %v", err)
+	}
+	stored.AuthHeaders = nil
+	repo := &memoryOpenAPIServiceRepo{service: stored}
+	s := NewOpenAPIServiceService(repo)
+
+	disabled := false
+	updated, err := s.UpdateOpenAPIService(context.Background(), 1, "svc-1",
+		&types.OpenAPIServiceUpdate{Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updated.Enabled {
+		t.Error("Expected the service to be disabled")
+	}
+
+	got := repo.service
+	if got.Name != stored.Name || got.Description != stored.Description || got.BaseURL != stored.BaseURL ||
+		got.Spec != stored.Spec || got.Timeout != stored.Timeout || got.MaxResponseBytes != stored.MaxResponseBytes ||
+		len(got.Operations) != 1 || got.Operations[0] != "getOrder" {
+		t.Errorf("Omitted fields changed: %+v", got)
+	}
+	if err := decryptAuthHeaders(&got); err != nil {
+		t.Fatalf("Failed to decrypt auth headers: %v", err)
+	}
+	if got.AuthHeaders["Authorization"] != "Bearer secret-token" {
+		t.Errorf("Auth headers changed: %v", got.AuthHeaders)
+	}
+
+	// Provided fields are applied, zero values included
+	timeout := 0
+	baseURL := "https://orders.example.com/v2"
+	if _, err := s.UpdateOpenAPIService(context.Background(), 1, "svc-1",
+		&types.OpenAPIServiceUpdate{Timeout: &timeout, BaseURL: &baseURL}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if repo.service.Timeout != 0 || repo.service.BaseURL != baseURL || repo.service.Enabled {
+		t.Errorf("Unexpected service after update: timeout %d, base URL %s, enabled %v",
+			repo.service.Timeout, repo.service.BaseURL, repo.service.Enabled)
+	}
+}
@@ -42,6 +42,11 @@ type sessionService struct {
 	knowledgeService     interfaces.KnowledgeService      // Service for knowledge operations
 	chunkService         interfaces.ChunkService          // Service for chunk operations
 	webSearchStateRepo   interfaces.WebSearchStateService // Service for web search state
+	kbRouter             *kbRouter                        // Router selecting knowledge bases per query
+
+	checkpointService  interfaces.AgentCheckpointService // Checkpoints agent executions for resume
+	customAgentService interfaces.CustomAgentService     // Loads the agents sub-tasks are delegated to
+	scratchpadService  interfaces.AgentScratchpadService // Keeps the agent plan and findings across turns
 }
 
 // NewSessionService creates a new session service instance with all required dependencies
@@ -57,6 +62,9 @@ func NewSessionService(cfg *config.Config,
 	agentService interfaces.AgentService,
 	sessionStorage llmcontext.ContextStorage,
 	webSearchStateRepo interfaces.WebSearchStateService,
+	checkpointService interfaces.AgentCheckpointService,
+	customAgentService interfaces.CustomAgentService,
+	scratchpadService interfaces.AgentScratchpadService,
 ) interfaces.SessionService {
 	return &sessionService{
 		cfg:                  cfg,
@@ -71,6 +79,10 @@ func NewSessionService(cfg *config.Config,
 		agentService:         agentService,
 		sessionStorage:       sessionStorage,
 		webSearchStateRepo:   webSearchStateRepo,
+		kbRouter:             newKBRouter(modelService),
+		checkpointService:    checkpointService,
+		customAgentService:   customAgentService,
+		scratchpadService:    scratchpadService,
 	}
 }
 
@@ -202,6 +214,9 @@ func (s *sessionService) DeleteSession(ctx context.Context, id string) error {
 	if err := s.webSearchStateRepo.DeleteWebSearchTempKBState(ctx, id); err != nil {
 		logger.Warnf(ctx, "Failed to cleanup temporary KB for session %s: %v", id, err)
 	}
+	if err := s.scratchpadService.Clear(ctx, id); err != nil {
+		logger.Warnf(ctx, "Failed to cleanup agent scratchpad for session %s: %v", id, err)
+	}
 
 	// Delete session from repository
 	err := s.sessionRepo.Delete(ctx, tenantID, id)
@@ -230,6 +245,10 @@ func (s *sessionService) GenerateTitle(ctx context.Context,
 	if session.Title != "" {
 		return session.Title, nil
 	}
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{
+		Source:    types.ModelUsageSourceTitle,
+		SessionID: session.ID,
+	})
 	var err error
 	// Get the first user message, either from provided messages or repository
 	var message *types.Message
@@ -395,6 +414,7 @@ func (s *sessionService) KnowledgeQA(
 	ctx context.Context,
 	session *types.Session,
 	query string,
+	images []string,
 	knowledgeBaseIDs []string,
 	knowledgeIDs []string,
 	assistantMessageID string,
@@ -402,19 +422,28 @@ func (s *sessionService) KnowledgeQA(
 	webSearchEnabled bool,
 	eventBus *event.EventBus,
 	customAgent *types.CustomAgent,
+	responseSchema json.RawMessage,
 ) error {
 	logger.Infof(
 		ctx,
-		"Knowledge base question answering parameters, session ID: %s, query: %s, webSearchEnabled: %v",
+		"Knowledge base question answering parameters, session ID: %s, query: %s, images: %d, webSearchEnabled: %v",
 		session.ID,
 		query,
+		len(images),
 		webSearchEnabled,
 	)
+	usageScope := types.ModelUsageScope{Source: types.ModelUsageSourceQA, SessionID: session.ID}
+	if customAgent != nil {
+		usageScope.AgentID = customAgent.ID
+	}
+	ctx = types.WithModelUsageScope(ctx, usageScope)
 
 	// Use custom agent's knowledge bases only if request didn't specify any
 	// When user explicitly @mentions a knowledge base or document, only search those
 	if len(knowledgeBaseIDs) == 0 && len(knowledgeIDs) == 0 {
-		knowledgeBaseIDs = s.resolveKnowledgeBasesFromAgent(ctx, customAgent)
+		var routing *types.KBRoutingDecision
+		knowledgeBaseIDs, routing = s.resolveKnowledgeBasesFromAgent(ctx, customAgent, query)
+		s.emitKBRouting(ctx, eventBus, session.ID, routing)
 	} else {
 		logger.Infof(ctx, "Using request-specified targets (ignoring agent config): kbs=%v, docs=%v", knowledgeBaseIDs, knowledgeIDs)
 	}
@@ -439,6 +468,7 @@ func (s *sessionService) KnowledgeQA(
 	fallbackPrompt := s.cfg.Conversation.FallbackPrompt
 	enableRewrite := s.cfg.Conversation.EnableRewrite
 	enableQueryExpansion := s.cfg.Conversation.EnableQueryExpansion
+	enableImageQuery := s.cfg.Conversation.EnableImageQuery
 	rerankModelID := ""
 
 	summaryConfig := types.SummaryConfig{
@@ -509,6 +539,7 @@ func (s *sessionService) KnowledgeQA(
 		// Override rewrite settings
 		enableRewrite = customAgent.Config.EnableRewrite
 		enableQueryExpansion = customAgent.Config.EnableQueryExpansion
+		enableImageQuery = customAgent.Config.EnableImageQuery
 		if customAgent.Config.RewritePromptSystem != "" {
 			rewritePromptSystem = customAgent.Config.RewritePromptSystem
 		}
@@ -557,6 +588,14 @@ func (s *sessionService) KnowledgeQA(
 		logger.Warnf(ctx, "Failed to build search targets: %v", err)
 	}
 
+	// Images can only be answered by a model that reads them
+	if len(images) > 0 {
+		chatModelID, err = s.selectVisionModelID(ctx, chatModelID, searchTargets)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create chat management object with session settings
 	logger.Infof(
 		ctx,
@@ -569,6 +608,7 @@ func (s *sessionService) KnowledgeQA(
 	chatManage := &types.ChatManage{
 		Query:                query,
 		RewriteQuery:         query,
+		Images:               images,
 		SessionID:            session.ID,
 		MessageID:            assistantMessageID, // NEW: For event emission in pipeline
 		KnowledgeBaseIDs:     knowledgeBaseIDs,   // Multi-KB support
@@ -593,11 +633,22 @@ func (s *sessionService) KnowledgeQA(
 		RewritePromptUser:    rewritePromptUser,
 		EnableRewrite:        enableRewrite,
 		EnableQueryExpansion: enableQueryExpansion,
+		EnableImageQuery:     enableImageQuery,
 		// FAQ Strategy Settings
 		FAQPriorityEnabled:       faqPriorityEnabled,
 		FAQDirectAnswerThreshold: faqDirectAnswerThreshold,
 		FAQScoreBoost:            faqScoreBoost,
 	}
+	if customAgent != nil {
+		chatManage.Guardrail = customAgent.Config.Guardrail
+	}
+	if tenant, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant); ok && tenant.ContextConfig != nil {
+		chatManage.MaxContextTokens = tenant.ContextConfig.MaxTokens
+	}
+	structured := len(responseSchema) > 0
+	if structured {
+		chatManage.ResponseSchema = responseSchema
+	}
 
 	// Determine pipeline based on knowledge bases availability and web search setting
 	// If no knowledge bases are selected AND web search is disabled, use pure chat pipeline
@@ -616,6 +667,15 @@ func (s *sessionService) KnowledgeQA(
 			logger.Info(ctx, "Multi-turn disabled, using chat_stream pipeline")
 			pipeline = types.Pipline["chat_stream"]
 		}
+		// Structured answers are generated in one call so they can be validated before emission
+		if structured {
+			if maxRounds > 0 {
+				pipeline = types.Pipline["chat_history_structured"]
+			} else {
+				pipeline = types.Pipline["chat_structured"]
+			}
+			logger.Info(ctx, "Response schema provided, using structured chat pipeline")
+		}
 	} else {
 		if webSearchEnabled && len(knowledgeBaseIDs) == 0 && len(knowledgeIDs) == 0 {
 			logger.Info(ctx, "Web search enabled without knowledge bases, using rag_stream pipeline for web search only")
@@ -623,6 +683,10 @@ func (s *sessionService) KnowledgeQA(
 			logger.Info(ctx, "Knowledge bases selected, using rag_stream pipeline")
 		}
 		pipeline = types.Pipline["rag_stream"]
+		if structured {
+			logger.Info(ctx, "Response schema provided, using rag_structured pipeline")
+			pipeline = types.Pipline["rag_structured"]
+		}
 	}
 
 	// Start knowledge QA event processing
@@ -636,7 +700,8 @@ func (s *sessionService) KnowledgeQA(
 	}
 
 	// Emit references event if we have search results
-	if len(chatManage.MergeResult) > 0 {
+	// (structured answers emit references themselves, before the final answer)
+	if len(chatManage.MergeResult) > 0 && !structured {
 		logger.Infof(ctx, "Emitting references event with %d results", len(chatManage.MergeResult))
 		if err := eventBus.Emit(ctx, event.Event{
 			ID:        generateEventID("references"),
@@ -694,6 +759,44 @@ func (s *sessionService) selectChatModelIDWithOverride(
 	return s.selectChatModelID(ctx, session, knowledgeBaseIDs, knowledgeIDs)
 }
 
+// selectVisionModelID returns the model answering a query with images: the chat model when it
+// reads images, otherwise the VLM of the first searched knowledge base that has one
+func (s *sessionService) selectVisionModelID(
+	ctx context.Context,
+	chatModelID string,
+	searchTargets types.SearchTargets,
+) (string, error) {
+	if chatModelID != "" {
+		model, err := s.modelService.GetModelByID(ctx, chatModelID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get chat model %s: %v", chatModelID, err)
+		} else if model != nil && model.SupportsVision() {
+			return chatModelID, nil
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range searchTargets {
+		if target == nil || seen[target.KnowledgeBaseID] {
+			continue
+		}
+		seen[target.KnowledgeBaseID] = true
+		kb, err := s.knowledgeBaseService.GetKnowledgeBaseByID(ctx, target.KnowledgeBaseID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get knowledge base: %v", err)
+			continue
+		}
+		if kb != nil && kb.VLMConfig.Enabled && kb.VLMConfig.ModelID != "" {
+			logger.Infof(ctx, "Chat model %s does not read images, using VLM model %s of knowledge base %s",
+				chatModelID, kb.VLMConfig.ModelID, kb.ID)
+			return kb.VLMConfig.ModelID, nil
+		}
+	}
+
+	return "", errors.New("no vision capable chat model is configured for images; " +
+		"select a VLM model or enable supports_vision on the chat model")
+}
+
 // selectChatModelID selects the appropriate chat model ID with priority for Remote models
 // Priority order:
 // 1. Session's SummaryModelID if it's a Remote model
@@ -781,16 +884,19 @@ func (s *sessionService) selectChatModelID(
 
 // resolveKnowledgeBasesFromAgent resolves knowledge base IDs based on agent's KBSelectionMode
 // Returns the resolved knowledge base IDs based on the selection mode:
-//   - "all": fetches all knowledge bases for the tenant
+//   - "all": fetches all knowledge bases for the tenant, routed to the most relevant ones when KBRouting is enabled
 //   - "selected": uses the explicitly configured knowledge bases
 //   - "none": returns empty slice
 //   - default: falls back to configured knowledge bases for backward compatibility
+//
+// The routing decision is nil unless the query was routed.
 func (s *sessionService) resolveKnowledgeBasesFromAgent(
 	ctx context.Context,
 	customAgent *types.CustomAgent,
-) []string {
+	query string,
+) ([]string, *types.KBRoutingDecision) {
 	if customAgent == nil {
-		return nil
+		return nil, nil
 	}
 
 	switch customAgent.Config.KBSelectionMode {
@@ -798,26 +904,71 @@ func (s *sessionService) resolveKnowledgeBasesFromAgent(
 		allKBs, err := s.knowledgeBaseService.ListKnowledgeBases(ctx)
 		if err != nil {
 			logger.Warnf(ctx, "Failed to list all knowledge bases: %v", err)
-			return nil
+			return nil, nil
 		}
 		kbIDs := make([]string, 0, len(allKBs))
 		for _, kb := range allKBs {
 			kbIDs = append(kbIDs, kb.ID)
 		}
 		logger.Infof(ctx, "KBSelectionMode=all: loaded %d knowledge bases", len(kbIDs))
-		return kbIDs
+		return s.routeKnowledgeBases(ctx, customAgent, query, allKBs, kbIDs)
 	case "selected":
 		logger.Infof(ctx, "KBSelectionMode=selected: using %d configured knowledge bases", len(customAgent.Config.KnowledgeBases))
-		return customAgent.Config.KnowledgeBases
+		return customAgent.Config.KnowledgeBases, nil
 	case "none":
 		logger.Infof(ctx, "KBSelectionMode=none: no knowledge bases configured")
-		return nil
+		return nil, nil
 	default:
 		// Default to "selected" behavior for backward compatibility
 		if len(customAgent.Config.KnowledgeBases) > 0 {
 			logger.Infof(ctx, "KBSelectionMode not set: using %d configured knowledge bases", len(customAgent.Config.KnowledgeBases))
 		}
-		return customAgent.Config.KnowledgeBases
+		return customAgent.Config.KnowledgeBases, nil
+	}
+}
+
+// routeKnowledgeBases limits the search to the knowledge bases most relevant to the query.
+// Every knowledge base is kept when routing is disabled, not needed or fails.
+func (s *sessionService) routeKnowledgeBases(
+	ctx context.Context,
+	customAgent *types.CustomAgent,
+	query string,
+	kbs []*types.KnowledgeBase,
+	kbIDs []string,
+) ([]string, *types.KBRoutingDecision) {
+	cfg := customAgent.Config.KBRouting
+	if !cfg.IsActive() || query == "" || len(kbs) <= cfg.GetTopN() {
+		return kbIDs, nil
+	}
+
+	decision, err := s.kbRouter.Route(ctx, cfg, query, kbs, customAgent.Config.ModelID)
+	if err != nil {
+		logger.Warnf(ctx, "KB routing failed, searching all %d knowledge bases: %v", len(kbIDs), err)
+		return kbIDs, &types.KBRoutingDecision{
+			Query:    query,
+			Selected: kbIDs,
+			Fallback: err.Error(),
+		}
+	}
+	logger.Infof(ctx, "KB routing (%s) selected %d of %d knowledge bases: %v",
+		decision.Method, len(decision.Selected), len(kbIDs), decision.Selected)
+	return decision.Selected, decision
+}
+
+// emitKBRouting reports the routing decision in the response stream
+func (s *sessionService) emitKBRouting(ctx context.Context,
+	eventBus *event.EventBus, sessionID string, decision *types.KBRoutingDecision,
+) {
+	if decision == nil || eventBus == nil {
+		return
+	}
+	if err := eventBus.Emit(ctx, event.Event{
+		ID:        generateEventID("kb-routing"),
+		Type:      event.EventKBRouting,
+		SessionID: sessionID,
+		Data:      event.KBRoutingData{Decision: decision},
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit kb routing event: %v", err)
 	}
 }
 
@@ -909,6 +1060,11 @@ func (s *sessionService) KnowledgeQAByEvent(ctx context.Context,
 
 	// Process each event in sequence
 	for _, eventType := range eventList {
+		// Retrieval is complete once the results are turned into the chat message
+		if eventType == types.INTO_CHAT_MESSAGE {
+			s.emitRetrievalTrace(ctx, chatManage)
+		}
+
 		logger.Infof(ctx, "Starting to trigger event: %v", eventType)
 		err := s.eventManager.Trigger(ctx, eventType, chatManage)
 
@@ -920,10 +1076,21 @@ func (s *sessionService) KnowledgeQAByEvent(ctx context.Context,
 				eventType,
 				chatManage.FallbackStrategy,
 			)
+			s.emitRetrievalTrace(ctx, chatManage)
+			if len(chatManage.ResponseSchema) > 0 {
+				s.handleStructuredFallback(ctx, chatManage)
+				return nil
+			}
 			s.handleFallbackResponse(ctx, chatManage)
 			return nil
 		}
 
+		// Refusal has already been emitted by the guardrail plugin
+		if err == chatpipline.ErrGuardrailRefused {
+			logger.Warnf(ctx, "Event %v triggered, request refused by guardrail", eventType)
+			return nil
+		}
+
 		// Handle other errors
 		if err != nil {
 			logger.Errorf(ctx, "Event triggering failed, event: %v, error type: %s, description: %s, error: %v",
@@ -940,6 +1107,23 @@ func (s *sessionService) KnowledgeQAByEvent(ctx context.Context,
 	return nil
 }
 
+// emitRetrievalTrace emits the retrieval trace of a debug request to the event bus
+func (s *sessionService) emitRetrievalTrace(ctx context.Context, chatManage *types.ChatManage) {
+	trace := types.RetrievalTraceFromContext(ctx)
+	if trace == nil || chatManage.EventBus == nil {
+		return
+	}
+	trace.Finish(chatManage.RewriteQuery, chatManage.MergeResult)
+	if err := chatManage.EventBus.Emit(ctx, types.Event{
+		ID:        fmt.Sprintf("%s-retrieval-debug", chatManage.MessageID),
+		Type:      types.EventType(event.EventRetrievalDebug),
+		SessionID: chatManage.SessionID,
+		Data:      event.RetrievalDebugData{Trace: trace},
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit retrieval trace: %v", err)
+	}
+}
+
 // SearchKnowledge performs knowledge base search without LLM summarization
 // knowledgeBaseIDs: list of knowledge base IDs to search (supports multi-KB)
 // knowledgeIDs: list of specific knowledge (file) IDs to search
@@ -1035,6 +1219,7 @@ func (s *sessionService) SearchKnowledge(ctx context.Context,
 		// Handle case where search returns no results
 		if err == chatpipline.ErrSearchNothing {
 			logger.Warnf(ctx, "Event %v triggered, search result is empty", event)
+			types.RetrievalTraceFromContext(ctx).Finish(chatManage.RewriteQuery, nil)
 			return []*types.SearchResult{}, nil
 		}
 
@@ -1051,6 +1236,7 @@ func (s *sessionService) SearchKnowledge(ctx context.Context,
 	}
 
 	logger.Infof(ctx, "Knowledge base search completed, found %d results", len(chatManage.MergeResult))
+	types.RetrievalTraceFromContext(ctx).Finish(chatManage.RewriteQuery, chatManage.MergeResult)
 	return chatManage.MergeResult, nil
 }
 
@@ -1067,6 +1253,92 @@ func (s *sessionService) AgentQA(
 	customAgent *types.CustomAgent,
 	knowledgeBaseIDs []string,
 	knowledgeIDs []string,
+) error {
+	return s.runAgentQA(ctx, session, query, assistantMessageID, summaryModelID,
+		eventBus, customAgent, knowledgeBaseIDs, knowledgeIDs, nil)
+}
+
+// ResumeAgentQA resumes an interrupted agent execution from its checkpoint.
+// The knowledge bases resolved by the interrupted execution are reused as is.
+func (s *sessionService) ResumeAgentQA(
+	ctx context.Context,
+	session *types.Session,
+	checkpoint *types.AgentCheckpoint,
+	eventBus *event.EventBus,
+	customAgent *types.CustomAgent,
+) error {
+	logger.Infof(ctx, "Resume agent execution, session ID: %s, message ID: %s, round: %d, attempt: %d",
+		session.ID, checkpoint.MessageID, checkpoint.Round, checkpoint.Attempts)
+	return s.runAgentQA(ctx, session, checkpoint.Query, checkpoint.MessageID, checkpoint.SummaryModelID,
+		eventBus, customAgent, checkpoint.KnowledgeBaseIDs, checkpoint.KnowledgeIDs, checkpoint)
+}
+
+// RunAgent runs a custom agent on a single query over the given knowledge bases outside any session.
+// Features bound to a session or a waiting user are disabled: conversation history,
+// checkpoints, human approval and delegation to other agents.
+// The knowledge bases of the agent are used when none are given.
+func (s *sessionService) RunAgent(
+	ctx context.Context,
+	customAgent *types.CustomAgent,
+	query string,
+	knowledgeBaseIDs []string,
+	eventBus *event.EventBus,
+) (*types.AgentState, error) {
+	if !customAgent.IsAgentMode() {
+		return nil, fmt.Errorf("agent %s does not run in agent mode", customAgent.Name)
+	}
+	if customAgent.Config.ModelID == "" {
+		return nil, errors.New("summary model (model_id) is not configured in custom agent settings")
+	}
+
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{
+		Source:  types.ModelUsageSourceAgent,
+		AgentID: customAgent.ID,
+	})
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	agentConfig := s.newAgentConfig(ctx, customAgent, tenantInfo)
+	agentConfig.MultiTurnEnabled = false
+	agentConfig.ToolApproval = nil
+	agentConfig.SubAgents = nil
+	agentConfig.DisableCheckpoints = true
+	if len(knowledgeBaseIDs) == 0 {
+		knowledgeBaseIDs, _ = s.resolveKnowledgeBasesFromAgent(ctx, customAgent, query)
+	}
+	agentConfig.KnowledgeBases = knowledgeBaseIDs
+
+	searchTargets, err := s.buildSearchTargets(ctx, tenantInfo.ID, knowledgeBaseIDs, nil)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to build search targets for agent: %v", err)
+	}
+	agentConfig.SearchTargets = searchTargets
+
+	chatModel, rerankModel, err := s.loadAgentModels(ctx, customAgent, agentConfig, customAgent.Config.ModelID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := uuid.New().String()
+	engine, err := s.agentService.CreateAgentEngine(
+		ctx, agentConfig, chatModel, rerankModel, eventBus, nil, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent engine: %w", err)
+	}
+	return engine.Execute(ctx, sessionID, uuid.New().String(), query, nil)
+}
+
+// runAgentQA builds the agent engine and runs it, from scratch or from the given checkpoint
+func (s *sessionService) runAgentQA(
+	ctx context.Context,
+	session *types.Session,
+	query string,
+	assistantMessageID string,
+	summaryModelID string,
+	eventBus *event.EventBus,
+	customAgent *types.CustomAgent,
+	knowledgeBaseIDs []string,
+	knowledgeIDs []string,
+	resume *types.AgentCheckpoint,
 ) error {
 	sessionID := session.ID
 	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
@@ -1088,26 +1360,17 @@ func (s *sessionService) AgentQA(
 		logger.Warnf(ctx, "Custom agent not provided for session: %s", sessionID)
 		return errors.New("custom agent configuration is required for agent QA")
 	}
+	ctx = types.WithModelUsageScope(ctx, types.ModelUsageScope{
+		Source:    types.ModelUsageSourceAgent,
+		SessionID: sessionID,
+		AgentID:   customAgent.ID,
+	})
 
-	// Ensure defaults are set
-	customAgent.EnsureDefaults()
+	agentConfig := s.newAgentConfig(ctx, customAgent, tenantInfo)
 
-	// Create runtime AgentConfig from customAgent
-	// Note: tenantInfo.AgentConfig is deprecated, all config comes from customAgent now
-	agentConfig := &types.AgentConfig{
-		MaxIterations:       customAgent.Config.MaxIterations,
-		ReflectionEnabled:   customAgent.Config.ReflectionEnabled,
-		Temperature:         customAgent.Config.Temperature,
-		WebSearchEnabled:    customAgent.Config.WebSearchEnabled,
-		WebSearchMaxResults: customAgent.Config.WebSearchMaxResults,
-		MultiTurnEnabled:    customAgent.Config.MultiTurnEnabled,
-		HistoryTurns:        customAgent.Config.HistoryTurns,
-		MCPSelectionMode:    customAgent.Config.MCPSelectionMode,
-		MCPServices:         customAgent.Config.MCPServices,
-	}
-
-	// Resolve knowledge bases: request-level @ mentions take priority over agent config
-	if len(knowledgeBaseIDs) > 0 || len(knowledgeIDs) > 0 {
+	// Resolve knowledge bases: request-level @ mentions take priority over agent config.
+	// A resumed execution keeps the knowledge bases resolved when it started.
+	if resume != nil || len(knowledgeBaseIDs) > 0 || len(knowledgeIDs) > 0 {
 		// User explicitly specified via @ mention
 		if len(knowledgeBaseIDs) > 0 {
 			agentConfig.KnowledgeBases = knowledgeBaseIDs
@@ -1119,31 +1382,9 @@ func (s *sessionService) AgentQA(
 		}
 	} else {
 		// Use agent's configured knowledge bases based on KBSelectionMode
-		agentConfig.KnowledgeBases = s.resolveKnowledgeBasesFromAgent(ctx, customAgent)
-	}
-
-	// Use custom agent's allowed tools if specified, otherwise use defaults
-	if len(customAgent.Config.AllowedTools) > 0 {
-		agentConfig.AllowedTools = customAgent.Config.AllowedTools
-	} else {
-		agentConfig.AllowedTools = tools.DefaultAllowedTools()
-	}
-
-	// Use custom agent's system prompt if specified
-	if customAgent.Config.SystemPrompt != "" {
-		agentConfig.UseCustomSystemPrompt = true
-		agentConfig.SystemPrompt = customAgent.Config.SystemPrompt
-	}
-
-	logger.Infof(ctx, "Custom agent config applied: MaxIterations=%d, Temperature=%.2f, AllowedTools=%v, WebSearchEnabled=%v",
-		agentConfig.MaxIterations, agentConfig.Temperature, agentConfig.AllowedTools, agentConfig.WebSearchEnabled)
-
-	// Set web search max results from tenant config if not set (default: 5)
-	if agentConfig.WebSearchMaxResults == 0 {
-		agentConfig.WebSearchMaxResults = 5
-		if tenantInfo.WebSearchConfig != nil && tenantInfo.WebSearchConfig.MaxResults > 0 {
-			agentConfig.WebSearchMaxResults = tenantInfo.WebSearchConfig.MaxResults
-		}
+		var routing *types.KBRoutingDecision
+		agentConfig.KnowledgeBases, routing = s.resolveKnowledgeBasesFromAgent(ctx, customAgent, query)
+		s.emitKBRouting(ctx, eventBus, sessionID, routing)
 	}
 
 	logger.Infof(ctx, "Merged agent config from tenant %d and session %s", tenantInfo.ID, sessionID)
@@ -1181,34 +1422,29 @@ func (s *sessionService) AgentQA(
 	} else {
 		logger.Infof(ctx, "Using custom agent's model_id: %s", effectiveModelID)
 	}
-
-	summaryModel, err := s.modelService.GetChatModel(ctx, effectiveModelID)
-	if err != nil {
-		logger.Warnf(ctx, "Failed to get chat model: %v", err)
-		return fmt.Errorf("failed to get chat model: %w", err)
+	if customAgent.Config.SubAgents.IsActive() {
+		agentConfig.SubAgentRunner = s.newSubAgentRunner(session, assistantMessageID, effectiveModelID,
+			eventBus, customAgent.Config.SubAgents.GetMaxDepth())
 	}
 
-	// Get rerank model from custom agent config (only required when knowledge bases are configured)
-	var rerankModel rerank.Reranker
-	hasKnowledge := len(agentConfig.KnowledgeBases) > 0 || len(agentConfig.KnowledgeIDs) > 0
-	if hasKnowledge {
-		rerankModelID := customAgent.Config.RerankModelID
-		if rerankModelID == "" {
-			logger.Warnf(ctx, "No rerank model configured for custom agent %s, but knowledge bases are specified", customAgent.ID)
-			return errors.New("rerank model (rerank_model_id) is not configured in custom agent settings")
+	// Check the query against the agent's guardrail policies before running any tool.
+	// A resumed execution is checked again, the policies may have changed since it started.
+	if s.refuseByInputGuardrail(ctx, session, query, assistantMessageID, effectiveModelID, eventBus, customAgent) {
+		if resume != nil {
+			s.finishAgentCheckpoint(ctx, assistantMessageID, nil)
 		}
+		return nil
+	}
+	// Check the streamed answer against the agent's output policies
+	s.guardAgentOutput(ctx, session, assistantMessageID, effectiveModelID, eventBus, customAgent)
 
-		rerankModel, err = s.modelService.GetRerankModel(ctx, rerankModelID)
-		if err != nil {
-			logger.Warnf(ctx, "Failed to get rerank model: %v", err)
-			return fmt.Errorf("failed to get rerank model: %w", err)
-		}
-	} else {
-		logger.Infof(ctx, "No knowledge bases configured, skipping rerank model initialization")
+	summaryModel, rerankModel, err := s.loadAgentModels(ctx, customAgent, agentConfig, effectiveModelID)
+	if err != nil {
+		return err
 	}
 
 	// Get or create contextManager for this session
-	contextManager := s.getContextManagerForSession(ctx, session, summaryModel)
+	contextManager := s.getContextManagerForSession(ctx, session, summaryModel, customAgent.Config.MaxCompletionTokens)
 
 	// Set system prompt for the current agent in context manager
 	// This ensures the context uses the correct system prompt when switching agents
@@ -1236,6 +1472,14 @@ func (s *sessionService) AgentQA(
 		// Multi-turn disabled, clear history
 		logger.Infof(ctx, "Multi-turn disabled for this agent, clearing history context")
 		llmContext = []chat.Message{}
+	} else if scratchpad, err := s.scratchpadService.Get(ctx, sessionID); err != nil {
+		logger.Warnf(ctx, "Failed to load agent scratchpad, continuing without it: %v", err)
+	} else if !scratchpad.IsEmpty() {
+		// The plan and findings of earlier turns are kept apart from the LLM context,
+		// so that they survive its compression
+		agentConfig.Scratchpad = scratchpad
+		logger.Infof(ctx, "Loaded agent scratchpad: %d plan steps, %d findings",
+			len(scratchpad.Steps), len(scratchpad.Findings))
 	}
 
 	// Create agent engine with EventBus and ContextManager
@@ -1254,10 +1498,42 @@ func (s *sessionService) AgentQA(
 		return err
 	}
 
+	// Checkpoint the execution after each round so that it can be resumed if this instance goes away
+	if resume == nil {
+		if err := s.checkpointService.Begin(ctx, &types.AgentCheckpoint{
+			MessageID:        assistantMessageID,
+			SessionID:        sessionID,
+			CustomAgentID:    customAgent.ID,
+			Query:            query,
+			SummaryModelID:   summaryModelID,
+			KnowledgeBaseIDs: agentConfig.KnowledgeBases,
+			KnowledgeIDs:     agentConfig.KnowledgeIDs,
+		}); err != nil {
+			logger.Warnf(ctx, "Failed to create agent checkpoint, execution will not be resumable: %v", err)
+		}
+	}
+	stopKeepAlive, err := s.checkpointService.KeepAlive(ctx, assistantMessageID)
+	if err != nil {
+		return err
+	}
+	defer stopKeepAlive()
+
 	// Execute agent with streaming (asynchronously)
 	// Events will be emitted to EventBus and handled by the Handler layer
 	logger.Info(ctx, "Executing agent with streaming")
-	if _, err := engine.Execute(ctx, sessionID, assistantMessageID, query, llmContext); err != nil {
+	var state *types.AgentState
+	if resume != nil {
+		state, err = engine.Resume(ctx, resume)
+	} else {
+		state, err = engine.Execute(ctx, sessionID, assistantMessageID, query, llmContext)
+	}
+	s.finishAgentCheckpoint(ctx, assistantMessageID, err)
+	if state != nil && agentConfig.MultiTurnEnabled {
+		if err := s.scratchpadService.Update(ctx, sessionID, assistantMessageID, state.RoundSteps); err != nil {
+			logger.Warnf(ctx, "Failed to update agent scratchpad: %v", err)
+		}
+	}
+	if err != nil {
 		logger.Errorf(ctx, "Agent execution failed: %v", err)
 		// Emit error event to the EventBus used by this agent
 		eventBus.Emit(ctx, event.Event{
@@ -1274,12 +1550,123 @@ func (s *sessionService) AgentQA(
 	return nil
 }
 
+// newAgentConfig creates the runtime agent configuration of a custom agent.
+// Knowledge bases and search targets are resolved by the caller.
+func (s *sessionService) newAgentConfig(
+	ctx context.Context,
+	customAgent *types.CustomAgent,
+	tenantInfo *types.Tenant,
+) *types.AgentConfig {
+	// Ensure defaults are set
+	customAgent.EnsureDefaults()
+
+	// Create runtime AgentConfig from customAgent
+	// Note: tenantInfo.AgentConfig is deprecated, all config comes from customAgent now
+	agentConfig := &types.AgentConfig{
+		MaxIterations:       customAgent.Config.MaxIterations,
+		ReflectionEnabled:   customAgent.Config.ReflectionEnabled,
+		Temperature:         customAgent.Config.Temperature,
+		WebSearchEnabled:    customAgent.Config.WebSearchEnabled,
+		WebSearchMaxResults: customAgent.Config.WebSearchMaxResults,
+		MultiTurnEnabled:    customAgent.Config.MultiTurnEnabled,
+		HistoryTurns:        customAgent.Config.HistoryTurns,
+		MCPSelectionMode:    customAgent.Config.MCPSelectionMode,
+		MCPServices:         customAgent.Config.MCPServices,
+		MCPToolPolicies:     customAgent.Config.MCPToolPolicies,
+		MCPResources:        customAgent.Config.MCPResources,
+		MCPPrompt:           customAgent.Config.MCPPrompt,
+
+		MaxParallelToolCalls: customAgent.Config.MaxParallelToolCalls,
+		ToolBudget:           customAgent.Config.ToolBudget,
+		ToolApproval:         customAgent.Config.ToolApproval,
+		SubAgents:            customAgent.Config.SubAgents,
+		OpenAPIServices:      customAgent.Config.OpenAPIServices,
+	}
+
+	// Use custom agent's allowed tools if specified, otherwise use defaults
+	if len(customAgent.Config.AllowedTools) > 0 {
+		agentConfig.AllowedTools = customAgent.Config.AllowedTools
+	} else {
+		agentConfig.AllowedTools = tools.DefaultAllowedTools()
+	}
+
+	// Use custom agent's system prompt if specified
+	if customAgent.Config.SystemPrompt != "" {
+		agentConfig.UseCustomSystemPrompt = true
+		agentConfig.SystemPrompt = customAgent.Config.SystemPrompt
+	}
+
+	logger.Infof(ctx, "Custom agent config applied: MaxIterations=%d, Temperature=%.2f, AllowedTools=%v, WebSearchEnabled=%v",
+		agentConfig.MaxIterations, agentConfig.Temperature, agentConfig.AllowedTools, agentConfig.WebSearchEnabled)
+
+	// Set web search max results from tenant config if not set (default: 5)
+	if agentConfig.WebSearchMaxResults == 0 {
+		agentConfig.WebSearchMaxResults = 5
+		if tenantInfo.WebSearchConfig != nil && tenantInfo.WebSearchConfig.MaxResults > 0 {
+			agentConfig.WebSearchMaxResults = tenantInfo.WebSearchConfig.MaxResults
+		}
+	}
+	return agentConfig
+}
+
+// loadAgentModels loads the chat model and, when knowledge bases are configured, the rerank model of an agent
+func (s *sessionService) loadAgentModels(
+	ctx context.Context,
+	customAgent *types.CustomAgent,
+	agentConfig *types.AgentConfig,
+	modelID string,
+) (chat.Chat, rerank.Reranker, error) {
+	chatModel, err := s.modelService.GetChatModel(ctx, modelID)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to get chat model: %v", err)
+		return nil, nil, fmt.Errorf("failed to get chat model: %w", err)
+	}
+
+	// Get rerank model from custom agent config (only required when knowledge bases are configured)
+	var rerankModel rerank.Reranker
+	hasKnowledge := len(agentConfig.KnowledgeBases) > 0 || len(agentConfig.KnowledgeIDs) > 0
+	if hasKnowledge {
+		rerankModelID := customAgent.Config.RerankModelID
+		if rerankModelID == "" {
+			logger.Warnf(ctx, "No rerank model configured for custom agent %s, but knowledge bases are specified", customAgent.ID)
+			return nil, nil, errors.New("rerank model (rerank_model_id) is not configured in custom agent settings")
+		}
+
+		rerankModel, err = s.modelService.GetRerankModel(ctx, rerankModelID)
+		if err != nil {
+			logger.Warnf(ctx, "Failed to get rerank model: %v", err)
+			return nil, nil, fmt.Errorf("failed to get rerank model: %w", err)
+		}
+	} else {
+		logger.Infof(ctx, "No knowledge bases configured, skipping rerank model initialization")
+	}
+	return chatModel, rerankModel, nil
+}
+
+// finishAgentCheckpoint records the outcome of an agent execution on its checkpoint.
+// Failed executions stay resumable; stopped ones are not resumed.
+func (s *sessionService) finishAgentCheckpoint(ctx context.Context, messageID string, execErr error) {
+	status := types.AgentRunCompleted
+	if execErr != nil {
+		status = types.AgentRunFailed
+		if ctx.Err() != nil {
+			status = types.AgentRunStopped
+		}
+	}
+	// The execution context may be cancelled already, the outcome must be stored anyway
+	if err := s.checkpointService.Finish(context.WithoutCancel(ctx), messageID, status); err != nil {
+		logger.Warnf(ctx, "Failed to update agent checkpoint status: %v", err)
+	}
+}
+
 // getContextManagerForSession creates a context manager for the session based on configuration
-// Returns the configured context manager (tenant-level or session-level) or default
+// Returns the configured context manager (tenant-level or session-level) or default, leaving
+// room in the context for maxCompletionTokens
 func (s *sessionService) getContextManagerForSession(
 	ctx context.Context,
 	session *types.Session,
 	chatModel chat.Chat,
+	maxCompletionTokens int,
 ) interfaces.ContextManager {
 	// Get tenant to access global context configuration
 	tenant, _ := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
@@ -1299,7 +1686,7 @@ func (s *sessionService) getContextManagerForSession(
 			SummarizeThreshold:  llmcontext.DefaultSummarizeThreshold,
 		}
 	}
-	return llmcontext.NewContextManagerFromConfig(contextConfig, s.sessionStorage, chatModel)
+	return llmcontext.NewContextManagerFromConfig(contextConfig, s.sessionStorage, chatModel, maxCompletionTokens)
 }
 
 // getContextForSession retrieves LLM context for a session
@@ -1330,6 +1717,93 @@ func (s *sessionService) ClearContext(ctx context.Context, sessionID string) err
 	return s.sessionStorage.Delete(ctx, sessionID)
 }
 
+// refuseByInputGuardrail runs the GUARDRAIL_INPUT stage for agent mode.
+// It returns true when the query was refused; the refusal and the completion
+// events have been emitted in that case.
+func (s *sessionService) refuseByInputGuardrail(
+	ctx context.Context,
+	session *types.Session,
+	query string,
+	assistantMessageID string,
+	chatModelID string,
+	eventBus *event.EventBus,
+	customAgent *types.CustomAgent,
+) bool {
+	if !customAgent.Config.Guardrail.IsActive() {
+		return false
+	}
+
+	chatManage := &types.ChatManage{
+		Query:       query,
+		SessionID:   session.ID,
+		MessageID:   assistantMessageID,
+		ChatModelID: chatModelID,
+		TenantID:    session.TenantID,
+		EventBus:    eventBus.AsEventBusInterface(),
+		Guardrail:   customAgent.Config.Guardrail,
+	}
+	if err := s.eventManager.Trigger(ctx, types.GUARDRAIL_INPUT, chatManage); err != chatpipline.ErrGuardrailRefused {
+		return false
+	}
+
+	logger.Warnf(ctx, "Agent query refused by guardrail, session ID: %s", session.ID)
+	finalAnswer := customAgent.Config.Guardrail.GetRefusalMessage()
+	if err := eventBus.Emit(ctx, event.Event{
+		ID:        generateEventID("complete"),
+		Type:      event.EventAgentComplete,
+		SessionID: session.ID,
+		Data: event.AgentCompleteData{
+			SessionID:   session.ID,
+			FinalAnswer: finalAnswer,
+			MessageID:   assistantMessageID,
+		},
+	}); err != nil {
+		logger.Errorf(ctx, "Failed to emit agent complete event: %v", err)
+	}
+	return true
+}
+
+// guardAgentOutput checks the answer and thought events emitted on the agent event bus, including
+// those of delegated agents, against the output policies of the agent guardrail; a refused answer
+// is replaced with the refusal message
+func (s *sessionService) guardAgentOutput(
+	ctx context.Context,
+	session *types.Session,
+	assistantMessageID string,
+	chatModelID string,
+	eventBus *event.EventBus,
+	customAgent *types.CustomAgent,
+) {
+	if !customAgent.Config.Guardrail.IsActive() || !customAgent.Config.Guardrail.CheckOutput {
+		return
+	}
+
+	// The guard forwards the allowed events past the interceptor installed below
+	chatManage := &types.ChatManage{
+		SessionID:   session.ID,
+		MessageID:   assistantMessageID,
+		ChatModelID: chatModelID,
+		TenantID:    session.TenantID,
+		EventBus:    eventBus.AsDispatcher(),
+		Guardrail:   customAgent.Config.Guardrail,
+	}
+	if err := s.eventManager.Trigger(ctx, types.GUARDRAIL_OUTPUT, chatManage); err != nil {
+		logger.Warnf(ctx, "Failed to install agent output guardrail: %v", err)
+		return
+	}
+	guarded := chatManage.EventBus
+	eventBus.Intercept(func(ctx context.Context, evt event.Event) error {
+		return guarded.Emit(ctx, types.Event{
+			ID:        evt.ID,
+			Type:      types.EventType(evt.Type),
+			SessionID: evt.SessionID,
+			Data:      evt.Data,
+			Metadata:  evt.Metadata,
+			RequestID: evt.RequestID,
+		})
+	})
+}
+
 // handleFallbackResponse handles fallback response based on strategy
 func (s *sessionService) handleFallbackResponse(ctx context.Context, chatManage *types.ChatManage) {
 	if chatManage.FallbackStrategy == types.FallbackStrategyModel {
@@ -1339,6 +1813,29 @@ func (s *sessionService) handleFallbackResponse(ctx context.Context, chatManage
 	}
 }
 
+// handleStructuredFallback answers a structured request without search results with an explicit
+// empty structured output, followed by the fixed fallback response
+func (s *sessionService) handleStructuredFallback(ctx context.Context, chatManage *types.ChatManage) {
+	chatManage.StructuredOutput = &types.StructuredOutput{
+		Object: json.RawMessage("null"),
+		Error:  chatpipline.ErrSearchNothing.Description,
+	}
+	if chatManage.EventBus != nil {
+		if err := chatManage.EventBus.Emit(ctx, types.Event{
+			ID:        generateEventID("structured"),
+			Type:      types.EventType(event.EventStructuredOutput),
+			SessionID: chatManage.SessionID,
+			Data: event.StructuredOutputData{
+				Object: chatManage.StructuredOutput.Object,
+				Error:  chatManage.StructuredOutput.Error,
+			},
+		}); err != nil {
+			logger.Errorf(ctx, "Failed to emit structured output event: %v", err)
+		}
+	}
+	s.handleFixedFallback(ctx, chatManage)
+}
+
 // handleFixedFallback handles fixed fallback response
 func (s *sessionService) handleFixedFallback(ctx context.Context, chatManage *types.ChatManage) {
 	fallbackContent := chatManage.FallbackResponse
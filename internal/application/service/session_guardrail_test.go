@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/agent"
+	"github.com/Tencent/WeKnora/internal/agent/tools"
+	chatpipline "github.com/Tencent/WeKnora/internal/application/service/chat_pipline"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// guardrailEvents collects the text shown to the user and the refusals
+type guardrailEvents struct {
+	mu       sync.Mutex
+	shown    strings.Builder
+	refusals []string
+}
+
+func (e *guardrailEvents) subscribe(bus *event.EventBus) {
+	show := func(ctx context.Context, evt event.Event) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		switch data := evt.Data.(type) {
+		case event.AgentThoughtData:
+			e.shown.WriteString(data.Content)
+		case event.AgentFinalAnswerData:
+			e.shown.WriteString(data.Content)
+		case event.SubAgentEventData:
+			if thought, ok := data.Data.(event.AgentThoughtData); ok {
+				e.shown.WriteString(thought.Content)
+			}
+		}
+		return nil
+	}
+	bus.On(event.EventAgentThought, show)
+	bus.On(event.EventAgentFinalAnswer, show)
+	bus.On(event.EventAgentSubAgent, show)
+	bus.On(event.EventGuardrailRefusal, func(ctx context.Context, evt event.Event) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.refusals = append(e.refusals, evt.Data.(event.GuardrailRefusalData).Policy)
+		return nil
+	})
+}
+
+func newGuardedAgentBus(t *testing.T, guardrail *types.GuardrailConfig) (*event.EventBus, *guardrailEvents) {
+	manager := chatpipline.NewEventManager()
+	chatpipline.NewPluginGuardrail(manager, nil)
+	s := &sessionService{eventManager: manager}
+
+	bus := event.NewEventBus()
+	events := &guardrailEvents{}
+	events.subscribe(bus)
+	s.guardAgentOutput(context.Background(), &types.Session{ID: "session-1"}, "message-1", "",
+		bus, &types.CustomAgent{Config: types.CustomAgentConfig{Guardrail: guardrail}})
+	return bus, events
+}
+
+func TestGuardAgentOutputChecksStreamedAnswer(t *testing.T) {
+	bus, events := newGuardedAgentBus(t, &types.GuardrailConfig{
+		Enabled:        true,
+		PIIDetection:   true,
+		CheckOutput:    true,
+		RefusalMessage: "I can't share that.",
+	})
+
+	// The answer of the last round streams as thought chunks
+	chatModel, err := chat.NewMockChatWithFixture(&chat.ChatConfig{ModelName: "mock"}, &chat.MockFixture{
+		Default: &chat.MockResponse{Content: "Write to alice@example.com for a refund."},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine := agent.NewAgentEngine(&types.AgentConfig{MaxIterations: 3}, chatModel, tools.NewToolRegistry(),
+		bus, nil, nil, nil, "session-1", "", nil, nil)
+	var complete event.AgentCompleteData
+	bus.On(event.EventAgentComplete, func(ctx context.Context, evt event.Event) error {
+		complete = evt.Data.(event.AgentCompleteData)
+		return nil
+	})
+	if _, err := engine.Execute(context.Background(), "session-1", "message-1", "How do I get a refund?", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(events.refusals) != 1 || events.refusals[0] != "pii" {
+		t.Fatalf("Expected one pii refusal, got %v", events.refusals)
+	}
+	if shown := events.shown.String(); strings.Contains(shown, "alice@example.com") ||
+		!strings.HasSuffix(shown, "I can't share that.") {
+		t.Errorf("Expected the answer to be replaced by the refusal, got %q", shown)
+	}
+	// The stored answer and steps do not keep the refused content
+	if complete.FinalAnswer != "I can't share that." {
+		t.Errorf("Expected the refusal as final answer, got %q", complete.FinalAnswer)
+	}
+	for _, step := range complete.AgentSteps.([]types.AgentStep) {
+		if strings.Contains(step.Thought, "alice@example.com") {
+			t.Errorf("Expected the refused thought to be removed, got %q", step.Thought)
+		}
+	}
+}
+
+func TestGuardAgentOutputChecksSubAgentAnswers(t *testing.T) {
+	bus, events := newGuardedAgentBus(t, &types.GuardrailConfig{
+		Enabled:        true,
+		DenyKeywords:   []string{"internal roadmap"},
+		CheckOutput:    true,
+		RefusalMessage: "refused",
+	})
+
+	for _, chunk := range []string{"The internal ", "roadmap says ", "we launch in May."} {
+		_ = bus.Emit(context.Background(), event.Event{
+			ID:   "thinking-1-d1",
+			Type: event.EventAgentSubAgent,
+			Data: event.SubAgentEventData{
+				AgentID:   "researcher",
+				Depth:     1,
+				EventID:   "thinking-1",
+				EventType: event.EventAgentThought,
+				Data:      event.AgentThoughtData{Content: chunk},
+			},
+		})
+	}
+
+	if len(events.refusals) != 1 || events.refusals[0] != "denylist" {
+		t.Fatalf("Expected one denylist refusal, got %v", events.refusals)
+	}
+	if shown := events.shown.String(); strings.Contains(shown, "May") {
+		t.Errorf("Expected the sub-agent answer to be cut off, got %q", shown)
+	}
+}
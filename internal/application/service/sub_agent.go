@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// subAgentForwardedEvents are the events of a delegated agent streamed to the parent execution.
+// Completion events are not forwarded, they would end the parent stream.
+var subAgentForwardedEvents = []event.EventType{
+	event.EventAgentThought,
+	event.EventAgentToolCall,
+	event.EventAgentToolResult,
+	event.EventAgentReflection,
+	event.EventAgentFinalAnswer,
+	event.EventAgentApprovalRequest,
+	event.EventAgentApprovalResult,
+	event.EventAgentBudgetExhausted,
+	event.EventError,
+}
+
+// subAgentRunner runs the custom agents a parent agent delegates sub-tasks to,
+// within the session and assistant message of the parent execution
+type subAgentRunner struct {
+	s         *sessionService
+	session   *types.Session
+	messageID string
+	modelID   string          // Model of the top-level agent, used by sub-agents without one
+	eventBus  *event.EventBus // Event bus of the parent execution
+	maxDepth  int             // Nesting depth allowed by the top-level agent
+}
+
+// newSubAgentRunner creates the sub-agent runner of an execution
+func (s *sessionService) newSubAgentRunner(
+	session *types.Session,
+	messageID string,
+	modelID string,
+	eventBus *event.EventBus,
+	maxDepth int,
+) *subAgentRunner {
+	return &subAgentRunner{
+		s:         s,
+		session:   session,
+		messageID: messageID,
+		modelID:   modelID,
+		eventBus:  eventBus,
+		maxDepth:  maxDepth,
+	}
+}
+
+// RunSubAgent runs the agent with its own tools and knowledge bases on the query
+func (r *subAgentRunner) RunSubAgent(ctx context.Context, agentID, query string) (*types.SubAgentResult, error) {
+	depth := types.SubAgentDepth(ctx)
+	if depth > r.maxDepth {
+		return nil, fmt.Errorf("sub-agent depth %d exceeds the limit of %d", depth, r.maxDepth)
+	}
+
+	customAgent, err := r.s.customAgentService.GetAgentByID(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agent %s: %w", agentID, err)
+	}
+	if !customAgent.IsAgentMode() {
+		return nil, fmt.Errorf("agent %s does not run in agent mode", customAgent.Name)
+	}
+	logger.Infof(ctx, "Delegating to sub-agent %s (%s) at depth %d, query: %s",
+		customAgent.Name, agentID, depth, query)
+
+	tenantInfo := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	agentConfig := r.s.newAgentConfig(ctx, customAgent, tenantInfo)
+	agentConfig.MultiTurnEnabled = false
+	if customAgent.Config.SubAgents.IsActive() && depth < r.maxDepth {
+		// Nested delegation stays within the depth allowed by the top-level agent
+		nested := *customAgent.Config.SubAgents
+		nested.MaxDepth = min(nested.GetMaxDepth(), r.maxDepth)
+		agentConfig.SubAgents = &nested
+		agentConfig.SubAgentRunner = r
+	} else {
+		agentConfig.SubAgents = nil
+	}
+
+	agentConfig.KnowledgeBases, _ = r.s.resolveKnowledgeBasesFromAgent(ctx, customAgent, query)
+	searchTargets, err := r.s.buildSearchTargets(ctx, tenantInfo.ID, agentConfig.KnowledgeBases, nil)
+	if err != nil {
+		logger.Warnf(ctx, "Failed to build search targets for sub-agent: %v", err)
+	}
+	agentConfig.SearchTargets = searchTargets
+
+	modelID := customAgent.Config.ModelID
+	if modelID == "" {
+		modelID = r.modelID
+	}
+	chatModel, rerankModel, err := r.s.loadAgentModels(ctx, customAgent, agentConfig, modelID)
+	if err != nil {
+		return nil, err
+	}
+
+	subBus := event.NewEventBus()
+	refs := r.forwardEvents(subBus, customAgent, depth)
+
+	// The sub-agent does not share the LLM context of the session
+	engine, err := r.s.agentService.CreateAgentEngine(
+		ctx, agentConfig, chatModel, rerankModel, subBus, nil, r.session.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sub-agent engine: %w", err)
+	}
+	state, err := engine.Execute(ctx, r.session.ID, r.messageID, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.SubAgentResult{
+		AgentID:       customAgent.ID,
+		AgentName:     customAgent.Name,
+		Answer:        state.FinalAnswer,
+		KnowledgeRefs: refs.list(),
+		Rounds:        state.CurrentRound,
+	}
+	if len(result.KnowledgeRefs) > 0 {
+		r.eventBus.Emit(ctx, event.Event{
+			ID:        generateEventID("sub-agent-references"),
+			Type:      event.EventAgentReferences,
+			SessionID: r.session.ID,
+			Data:      event.AgentReferencesData{References: result.KnowledgeRefs},
+		})
+	}
+	return result, nil
+}
+
+// forwardEvents streams the events of the sub-agent to the parent execution tagged with the
+// sub-agent and its depth, and collects the knowledge the sub-agent retrieved
+func (r *subAgentRunner) forwardEvents(
+	subBus *event.EventBus,
+	customAgent *types.CustomAgent,
+	depth int,
+) *subAgentRefs {
+	refs := &subAgentRefs{seen: make(map[string]bool)}
+	for _, eventType := range subAgentForwardedEvents {
+		subBus.On(eventType, func(ctx context.Context, evt event.Event) error {
+			if data, ok := evt.Data.(event.AgentToolResultData); ok {
+				refs.collect(data.Data)
+			}
+			return r.eventBus.Emit(ctx, event.Event{
+				ID:        fmt.Sprintf("%s-d%d", evt.ID, depth),
+				Type:      event.EventAgentSubAgent,
+				SessionID: r.session.ID,
+				Data: event.SubAgentEventData{
+					AgentID:   customAgent.ID,
+					AgentName: customAgent.Name,
+					Depth:     depth,
+					EventID:   evt.ID,
+					EventType: evt.Type,
+					Data:      evt.Data,
+				},
+			})
+		})
+	}
+	// Events of deeper sub-agents are already tagged
+	subBus.On(event.EventAgentSubAgent, func(ctx context.Context, evt event.Event) error {
+		return r.eventBus.Emit(ctx, evt)
+	})
+	return refs
+}
+
+// subAgentRefs collects the knowledge retrieved by a sub-agent from its tool results
+type subAgentRefs struct {
+	mu   sync.Mutex
+	seen map[string]bool
+	refs []*types.SearchResult
+}
+
+// collect adds the search results of a tool result, and the references returned by nested sub-agents
+func (c *subAgentRefs) collect(data map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if nested, ok := data["knowledge_refs"].([]*types.SearchResult); ok {
+		for _, ref := range nested {
+			c.add(ref)
+		}
+	}
+	if data["display_type"] != "search_results" {
+		return
+	}
+	results, _ := data["results"].([]map[string]interface{})
+	for _, result := range results {
+		chunkID, _ := result["chunk_id"].(string)
+		if chunkID == "" {
+			continue
+		}
+		ref := &types.SearchResult{ID: chunkID}
+		ref.Content, _ = result["content"].(string)
+		ref.KnowledgeID, _ = result["knowledge_id"].(string)
+		ref.KnowledgeTitle, _ = result["knowledge_title"].(string)
+		c.add(ref)
+	}
+}
+
+// add appends a reference once
+func (c *subAgentRefs) add(ref *types.SearchResult) {
+	if ref == nil || c.seen[ref.ID] {
+		return
+	}
+	c.seen[ref.ID] = true
+	c.refs = append(c.refs, ref)
+}
+
+// list returns the collected references
+func (c *subAgentRefs) list() []*types.SearchResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refs
+}
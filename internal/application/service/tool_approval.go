@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/google/uuid"
+)
+
+// Tool approval related errors
+var (
+	ErrToolApprovalNotFound   = errors.New("tool approval not found")
+	ErrToolApprovalNotPending = errors.New("tool approval is no longer pending")
+)
+
+// toolApprovalPollInterval is how often a waiting agent checks the stored decision,
+// so that decisions taken on another instance are seen as well
+const toolApprovalPollInterval = 500 * time.Millisecond
+
+// toolApprovalService implements the ToolApprovalService interface
+type toolApprovalService struct {
+	repo interfaces.ToolApprovalRepository
+
+	mu      sync.Mutex
+	waiters map[string]chan struct{} // Wakes agents of this instance waiting on a request
+}
+
+// NewToolApprovalService creates a new tool approval service
+func NewToolApprovalService(repo interfaces.ToolApprovalRepository) interfaces.ToolApprovalService {
+	return &toolApprovalService{
+		repo:    repo,
+		waiters: make(map[string]chan struct{}),
+	}
+}
+
+// RequestApproval persists a pending approval request for a tool call
+func (s *toolApprovalService) RequestApproval(ctx context.Context, approval *types.ToolApproval) error {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return ErrInvalidTenantID
+	}
+	if approval.ID == "" {
+		approval.ID = uuid.New().String()
+	}
+	approval.TenantID = tenantID
+	approval.Status = types.ToolApprovalPending
+	if approval.ExpiresAt.IsZero() {
+		approval.ExpiresAt = time.Now().Add(types.DefaultToolApprovalTimeout)
+	}
+
+	s.mu.Lock()
+	s.waiters[approval.ID] = make(chan struct{})
+	s.mu.Unlock()
+
+	if err := s.repo.Create(ctx, approval); err != nil {
+		s.removeWaiter(approval.ID)
+		return err
+	}
+	logger.Infof(ctx, "Tool approval requested: id=%s, tool=%s, session=%s",
+		approval.ID, approval.ToolName, approval.SessionID)
+	return nil
+}
+
+// WaitForDecision blocks until the request is decided, expires or ctx is done
+func (s *toolApprovalService) WaitForDecision(
+	ctx context.Context,
+	approval *types.ToolApproval,
+) (*types.ToolApproval, error) {
+	defer s.removeWaiter(approval.ID)
+
+	s.mu.Lock()
+	wake := s.waiters[approval.ID]
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(toolApprovalPollInterval)
+	defer ticker.Stop()
+	expiry := time.NewTimer(time.Until(approval.ExpiresAt))
+	defer expiry.Stop()
+
+	for {
+		// Checked first, the request may have been decided before the wait started
+		current, err := s.repo.GetByID(ctx, approval.TenantID, approval.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, ErrToolApprovalNotFound
+		}
+		if current.Status != types.ToolApprovalPending {
+			return current, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wake:
+			wake = nil
+		case <-ticker.C:
+		case <-expiry.C:
+			expired, err := s.repo.Decide(ctx, approval.TenantID, approval.ID,
+				types.ToolApprovalExpired, "approval timed out")
+			if err != nil {
+				return nil, err
+			}
+			if expired {
+				logger.Warnf(ctx, "Tool approval expired: id=%s, tool=%s", approval.ID, approval.ToolName)
+			}
+		}
+	}
+}
+
+// Decide approves or denies a pending request of the session
+func (s *toolApprovalService) Decide(
+	ctx context.Context,
+	sessionID, id string,
+	approved bool,
+	reason string,
+) (*types.ToolApproval, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	approval, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil || approval.SessionID != sessionID {
+		return nil, ErrToolApprovalNotFound
+	}
+
+	status := types.ToolApprovalDenied
+	if approved {
+		status = types.ToolApprovalApproved
+	}
+	decided, err := s.repo.Decide(ctx, tenantID, id, status, reason)
+	if err != nil {
+		return nil, err
+	}
+	if !decided {
+		return nil, ErrToolApprovalNotPending
+	}
+	logger.Infof(ctx, "Tool approval decided: id=%s, tool=%s, status=%s", id, approval.ToolName, status)
+
+	s.mu.Lock()
+	if wake, ok := s.waiters[id]; ok {
+		close(wake)
+		delete(s.waiters, id)
+	}
+	s.mu.Unlock()
+
+	approval.Status = status
+	approval.Reason = reason
+	return approval, nil
+}
+
+// ListPending lists the pending approval requests of the session
+func (s *toolApprovalService) ListPending(ctx context.Context, sessionID string) ([]*types.ToolApproval, error) {
+	tenantID, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok {
+		return nil, ErrInvalidTenantID
+	}
+	return s.repo.ListPendingBySession(ctx, tenantID, sessionID)
+}
+
+// removeWaiter forgets the wake channel of a request
+func (s *toolApprovalService) removeWaiter(id string) {
+	s.mu.Lock()
+	delete(s.waiters, id)
+	s.mu.Unlock()
+}
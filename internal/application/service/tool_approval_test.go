@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// memoryToolApprovalRepo is an in-memory ToolApprovalRepository
+type memoryToolApprovalRepo struct {
+	mu        sync.Mutex
+	approvals map[string]types.ToolApproval
+}
+
+func newMemoryToolApprovalRepo() *memoryToolApprovalRepo {
+	return &memoryToolApprovalRepo{approvals: make(map[string]types.ToolApproval)}
+}
+
+func (r *memoryToolApprovalRepo) Create(ctx context.Context, approval *types.ToolApproval) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approvals[approval.ID] = *approval
+	return nil
+}
+
+func (r *memoryToolApprovalRepo) GetByID(ctx context.Context, tenantID uint64, id string) (*types.ToolApproval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	approval, ok := r.approvals[id]
+	if !ok || approval.TenantID != tenantID {
+		return nil, nil
+	}
+	return &approval, nil
+}
+
+func (r *memoryToolApprovalRepo) ListPendingBySession(ctx context.Context,
+	tenantID uint64, sessionID string,
+) ([]*types.ToolApproval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []*types.ToolApproval
+	for _, approval := range r.approvals {
+		if approval.TenantID == tenantID && approval.SessionID == sessionID &&
+			approval.Status == types.ToolApprovalPending {
+			approval := approval
+			pending = append(pending, &approval)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memoryToolApprovalRepo) Decide(ctx context.Context, tenantID uint64, id string,
+	status types.ToolApprovalStatus, reason string,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	approval, ok := r.approvals[id]
+	if !ok || approval.TenantID != tenantID || approval.Status != types.ToolApprovalPending {
+		return false, nil
+	}
+	approval.Status = status
+	approval.Reason = reason
+	r.approvals[id] = approval
+	return true, nil
+}
+
+func TestToolApprovalDecideWakesWaiter(t *testing.T) {
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	svc := NewToolApprovalService(newMemoryToolApprovalRepo())
+
+	approval := &types.ToolApproval{SessionID: "session-1", ToolName: "mcp.jira.create_issue"}
+	if err := svc.RequestApproval(ctx, approval); err != nil {
+		t.Fatalf("RequestApproval: %v", err)
+	}
+	if approval.ID == "" || approval.Status != types.ToolApprovalPending || approval.TenantID != 1 {
+		t.Fatalf("unexpected request: %+v", approval)
+	}
+
+	pending, err := svc.ListPending(ctx, "session-1")
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected 1 pending request, got %d (%v)", len(pending), err)
+	}
+
+	decided := make(chan *types.ToolApproval, 1)
+	go func() {
+		result, err := svc.WaitForDecision(ctx, approval)
+		if err != nil {
+			t.Errorf("WaitForDecision: %v", err)
+		}
+		decided <- result
+	}()
+
+	if _, err := svc.Decide(ctx, "session-2", approval.ID, true, ""); !errors.Is(err, ErrToolApprovalNotFound) {
+		t.Errorf("expected ErrToolApprovalNotFound for another session, got %v", err)
+	}
+	start := time.Now()
+	if _, err := svc.Decide(ctx, "session-1", approval.ID, false, "not now"); err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+
+	select {
+	case result := <-decided:
+		if result == nil || result.Status != types.ToolApprovalDenied || result.Reason != "not now" {
+			t.Errorf("unexpected decision: %+v", result)
+		}
+		if elapsed := time.Since(start); elapsed >= toolApprovalPollInterval {
+			t.Errorf("expected the decision to wake the waiter, took %s", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiter did not see the decision")
+	}
+
+	if _, err := svc.Decide(ctx, "session-1", approval.ID, true, ""); !errors.Is(err, ErrToolApprovalNotPending) {
+		t.Errorf("expected ErrToolApprovalNotPending on a second decision, got %v", err)
+	}
+}
+
+func TestToolApprovalExpires(t *testing.T) {
+	ctx := context.WithValue(context.Background(), types.TenantIDContextKey, uint64(1))
+	svc := NewToolApprovalService(newMemoryToolApprovalRepo())
+
+	approval := &types.ToolApproval{
+		SessionID: "session-1",
+		ToolName:  "send_email",
+		ExpiresAt: time.Now().Add(50 * time.Millisecond),
+	}
+	if err := svc.RequestApproval(ctx, approval); err != nil {
+		t.Fatalf("RequestApproval: %v", err)
+	}
+	result, err := svc.WaitForDecision(ctx, approval)
+	if err != nil {
+		t.Fatalf("WaitForDecision: %v", err)
+	}
+	if result.Status != types.ToolApprovalExpired {
+		t.Errorf("expected expired status, got %s", result.Status)
+	}
+}
+
+func TestToolApprovalRequiresTenant(t *testing.T) {
+	svc := NewToolApprovalService(newMemoryToolApprovalRepo())
+	err := svc.RequestApproval(context.Background(), &types.ToolApproval{SessionID: "session-1"})
+	if !errors.Is(err, ErrInvalidTenantID) {
+		t.Errorf("expected ErrInvalidTenantID, got %v", err)
+	}
+}
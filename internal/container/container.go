@@ -43,7 +43,9 @@ import (
 	"github.com/Tencent/WeKnora/internal/handler/session"
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/mcpserver"
 	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
 	"github.com/Tencent/WeKnora/internal/models/utils/ollama"
 	"github.com/Tencent/WeKnora/internal/router"
 	"github.com/Tencent/WeKnora/internal/stream"
@@ -76,6 +78,9 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	// Register goroutine pool cleanup handler
 	must(container.Invoke(registerPoolCleanup))
 
+	// Token counts fall back to estimates without the tokenizer vocabularies
+	tokenizer.WarnMissing(context.Background())
+
 	// Initialize retrieval engine registry for search capabilities
 	must(container.Provide(initRetrieveEngineRegistry))
 
@@ -99,7 +104,14 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(repository.NewAuthTokenRepository))
 	must(container.Provide(neo4jRepo.NewNeo4jRepository))
 	must(container.Provide(repository.NewMCPServiceRepository))
+	must(container.Provide(repository.NewMCPOAuthRepository))
+	must(container.Provide(repository.NewOpenAPIServiceRepository))
 	must(container.Provide(repository.NewCustomAgentRepository))
+	must(container.Provide(repository.NewToolApprovalRepository))
+	must(container.Provide(repository.NewAgentCheckpointRepository))
+	must(container.Provide(repository.NewAgentScheduleRepository))
+	must(container.Provide(repository.NewAgentScratchpadRepository))
+	must(container.Provide(repository.NewModelUsageRepository))
 	must(container.Provide(service.NewWebSearchStateService))
 
 	// MCP manager for managing MCP client connections
@@ -112,6 +124,7 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(service.NewChunkService))
 	must(container.Provide(service.NewKnowledgeTagService))
 	must(container.Provide(embedding.NewBatchEmbedder))
+	must(container.Provide(service.NewModelUsageService))
 	must(container.Provide(service.NewModelService))
 	must(container.Provide(service.NewDatasetService))
 	must(container.Provide(service.NewEvaluationService))
@@ -123,7 +136,10 @@ func BuildContainer(container *dig.Container) *dig.Container {
 
 	must(container.Provide(service.NewMessageService))
 	must(container.Provide(service.NewMCPServiceService))
+	must(container.Provide(service.NewMCPOAuthService))
+	must(container.Provide(service.NewOpenAPIServiceService))
 	must(container.Provide(service.NewCustomAgentService))
+	must(container.Provide(service.NewAgentBundleService))
 
 	// Web search service (needed by AgentService)
 	must(container.Provide(service.NewWebSearchService))
@@ -131,11 +147,15 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	// Agent service layer (requires event bus, web search service)
 	// SessionService is passed as parameter to CreateAgentEngine method when creating AgentService
 	must(container.Provide(event.NewEventBus))
+	must(container.Provide(service.NewToolApprovalService))
+	must(container.Provide(service.NewAgentCheckpointService))
 	must(container.Provide(service.NewAgentService))
 
 	// Session service (depends on agent service)
 	// SessionService is created after AgentService and passes itself to AgentService.CreateAgentEngine when needed
 	must(container.Provide(service.NewSessionService))
+	must(container.Provide(service.NewAgentScheduleService))
+	must(container.Provide(service.NewAgentScratchpadService))
 
 	must(container.Provide(router.NewAsyncqClient))
 	must(container.Provide(router.NewAsynqServer))
@@ -151,8 +171,11 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Invoke(chatpipline.NewPluginChatCompletion))
 	must(container.Invoke(chatpipline.NewPluginChatCompletionStream))
 	must(container.Invoke(chatpipline.NewPluginStreamFilter))
+	must(container.Invoke(chatpipline.NewPluginGuardrail))
+	must(container.Invoke(chatpipline.NewPluginStructuredOutput))
 	must(container.Invoke(chatpipline.NewPluginFilterTopK))
 	must(container.Invoke(chatpipline.NewPluginRewrite))
+	must(container.Invoke(chatpipline.NewPluginImageQuery))
 	must(container.Invoke(chatpipline.NewPluginLoadHistory))
 	must(container.Invoke(chatpipline.NewPluginExtractEntity))
 	must(container.Invoke(chatpipline.NewPluginSearchEntity))
@@ -168,13 +191,20 @@ func BuildContainer(container *dig.Container) *dig.Container {
 	must(container.Provide(session.NewHandler))
 	must(container.Provide(handler.NewMessageHandler))
 	must(container.Provide(handler.NewModelHandler))
+	must(container.Provide(handler.NewModelUsageHandler))
 	must(container.Provide(handler.NewEvaluationHandler))
 	must(container.Provide(handler.NewInitializationHandler))
 	must(container.Provide(handler.NewAuthHandler))
 	must(container.Provide(handler.NewSystemHandler))
 	must(container.Provide(handler.NewMCPServiceHandler))
+	must(container.Provide(handler.NewMCPOAuthHandler))
+	must(container.Provide(handler.NewOpenAPIServiceHandler))
 	must(container.Provide(handler.NewWebSearchHandler))
 	must(container.Provide(handler.NewCustomAgentHandler))
+	must(container.Provide(handler.NewAgentScheduleHandler))
+
+	// MCP server exposing the knowledge bases to MCP clients
+	must(container.Provide(mcpserver.NewServer))
 
 	// Router configuration
 	must(container.Provide(router.NewRouter))
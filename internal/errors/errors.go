@@ -36,6 +36,9 @@ const (
 	ErrAgentInvalidMaxIterations ErrorCode = 2102
 	ErrAgentInvalidTemperature   ErrorCode = 2103
 
+	// Usage related error codes (2200-2299)
+	ErrTokenQuotaExceeded ErrorCode = 2200
+
 	// Add more error codes here
 )
 
@@ -184,6 +187,15 @@ func NewAgentInvalidTemperatureError() *AppError {
 	}
 }
 
+// Usage related errors
+func NewTokenQuotaExceededError(message string) *AppError {
+	return &AppError{
+		Code:     ErrTokenQuotaExceeded,
+		Message:  message,
+		HTTPCode: http.StatusTooManyRequests,
+	}
+}
+
 // IsAppError checks if the error is an AppError type
 func IsAppError(err error) (*AppError, bool) {
 	appErr, ok := err.(*AppError)
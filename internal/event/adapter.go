@@ -9,7 +9,8 @@ import (
 // EventBusAdapter adapts *EventBus to types.EventBusInterface
 // This allows EventBus to be used through the interface without circular dependencies
 type EventBusAdapter struct {
-	bus *EventBus
+	bus      *EventBus
+	dispatch bool // Emit bypasses the interceptor of the bus
 }
 
 // NewEventBusAdapter creates a new adapter for EventBus
@@ -50,6 +51,9 @@ func (a *EventBusAdapter) Emit(ctx context.Context, evt types.Event) error {
 		Metadata:  evt.Metadata,
 		RequestID: evt.RequestID,
 	}
+	if a.dispatch {
+		return a.bus.Dispatch(ctx, eventEvt)
+	}
 	return a.bus.Emit(ctx, eventEvt)
 }
 
@@ -57,3 +61,8 @@ func (a *EventBusAdapter) Emit(ctx context.Context, evt types.Event) error {
 func (eb *EventBus) AsEventBusInterface() types.EventBusInterface {
 	return NewEventBusAdapter(eb)
 }
+
+// AsDispatcher converts *EventBus to types.EventBusInterface, emitting events past its interceptor
+func (eb *EventBus) AsDispatcher() types.EventBusInterface {
+	return &EventBusAdapter{bus: eb, dispatch: true}
+}
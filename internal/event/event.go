@@ -54,6 +54,28 @@ const (
 	EventAgentReferences  EventType = "references"   // 知识引用
 	EventAgentFinalAnswer EventType = "final_answer" // 最终答案
 
+	// Agent approval events
+	EventAgentApprovalRequest EventType = "approval_request" // 工具调用等待人工审批
+	EventAgentApprovalResult  EventType = "approval_result"  // 工具调用审批结果
+
+	// Agent budget events
+	EventAgentBudgetExhausted EventType = "budget_exhausted" // Agent 工具调用或 token 预算耗尽
+
+	// Sub-agent events
+	EventAgentSubAgent EventType = "sub_agent" // 被委派的子智能体产生的事件
+
+	// Structured output events
+	EventStructuredOutput EventType = "structured_output" // 结构化 JSON 答案
+
+	// Guardrail events
+	EventGuardrailRefusal EventType = "guardrail_refusal" // 护栏策略拒绝请求
+
+	// Debug events
+	EventRetrievalDebug EventType = "retrieval_debug" // 检索过程调试信息
+
+	// Routing events
+	EventKBRouting EventType = "kb_routing" // 知识库路由结果
+
 	// Error events
 	EventError EventType = "error" // 错误事件
 
@@ -79,9 +101,10 @@ type EventHandler func(ctx context.Context, event Event) error
 
 // EventBus manages event publishing and subscription
 type EventBus struct {
-	mu        sync.RWMutex
-	handlers  map[EventType][]EventHandler
-	asyncMode bool // 是否异步处理事件
+	mu          sync.RWMutex
+	handlers    map[EventType][]EventHandler
+	asyncMode   bool         // 是否异步处理事件
+	interceptor EventHandler // 拦截发布的事件，由其决定是否投递给处理器
 }
 
 // NewEventBus creates a new EventBus instance
@@ -117,6 +140,15 @@ func (eb *EventBus) Off(eventType EventType) {
 	delete(eb.handlers, eventType)
 }
 
+// Intercept routes the events emitted on the bus to interceptor instead of the handlers.
+// The interceptor delivers the events it lets through with Dispatch.
+func (eb *EventBus) Intercept(interceptor EventHandler) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.interceptor = interceptor
+}
+
 // Emit publishes an event to all registered handlers
 // Returns error if any handler fails (in sync mode)
 // Automatically generates an ID for the event if not provided (from source)
@@ -126,6 +158,21 @@ func (eb *EventBus) Emit(ctx context.Context, event Event) error {
 		event.ID = uuid.New().String()
 	}
 
+	eb.mu.RLock()
+	interceptor := eb.interceptor
+	eb.mu.RUnlock()
+	if interceptor != nil {
+		return interceptor(ctx, event)
+	}
+	return eb.Dispatch(ctx, event)
+}
+
+// Dispatch publishes an event to all registered handlers, bypassing the interceptor
+func (eb *EventBus) Dispatch(ctx context.Context, event Event) error {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+
 	eb.mu.RLock()
 	handlers, exists := eb.handlers[event.Type]
 	eb.mu.RUnlock()
@@ -1,5 +1,7 @@
 package event
 
+import "encoding/json"
+
 // EventData contains common event data structures for different stages
 
 // QueryData represents query-related event data
@@ -175,6 +177,35 @@ type AgentToolResultData struct {
 	Data       map[string]interface{} `json:"data,omitempty"` // Structured data from tool result (e.g., display_type, formatted results)
 }
 
+// AgentApprovalRequestData represents a tool call waiting for human approval
+type AgentApprovalRequestData struct {
+	ApprovalID string                 `json:"approval_id"`
+	ToolCallID string                 `json:"tool_call_id"`
+	ToolName   string                 `json:"tool_name"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	ExpiresAt  int64                  `json:"expires_at"` // Unix timestamp after which the call is refused
+	Iteration  int                    `json:"iteration"`
+}
+
+// AgentApprovalResultData represents the decision on a tool call approval
+type AgentApprovalResultData struct {
+	ApprovalID string `json:"approval_id"`
+	ToolCallID string `json:"tool_call_id"`
+	ToolName   string `json:"tool_name"`
+	Status     string `json:"status"` // approved, denied or expired
+	Reason     string `json:"reason,omitempty"`
+	Iteration  int    `json:"iteration"`
+}
+
+// AgentBudgetExhaustedData represents a tool call or token budget exhausted by the agent
+type AgentBudgetExhaustedData struct {
+	Kind      string `json:"kind"`                // tool_calls, tool_calls_per_tool or tokens
+	ToolName  string `json:"tool_name,omitempty"` // Tool whose call was refused
+	Limit     int    `json:"limit"`
+	Used      int    `json:"used,omitempty"` // Estimated tokens used, for token budgets
+	Iteration int    `json:"iteration"`
+}
+
 // AgentReferencesData represents knowledge references data
 type AgentReferencesData struct {
 	References interface{} `json:"references"` // []*types.SearchResult
@@ -195,6 +226,42 @@ type AgentReflectionData struct {
 	Done       bool   `json:"done"` // Whether streaming is complete
 }
 
+// StructuredOutputData represents a JSON answer validated against the requested schema
+type StructuredOutputData struct {
+	Object   json.RawMessage `json:"object"`
+	Valid    bool            `json:"valid"`
+	Repaired bool            `json:"repaired"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// GuardrailRefusalData represents a request or answer refused by a guardrail policy
+type GuardrailRefusalData struct {
+	Stage   string `json:"stage"`  // input or output
+	Policy  string `json:"policy"` // Name of the policy that refused
+	Reason  string `json:"reason"` // Why the policy refused (not shown to end users)
+	Message string `json:"message"`
+}
+
+// RetrievalDebugData carries the retrieval trace of a debug request
+type RetrievalDebugData struct {
+	Trace interface{} `json:"trace"` // *types.RetrievalTrace
+}
+
+// SubAgentEventData wraps an event of a delegated agent forwarded to the parent execution
+type SubAgentEventData struct {
+	AgentID   string      `json:"agent_id"`
+	AgentName string      `json:"agent_name"`
+	Depth     int         `json:"depth"`      // 1 for an agent delegated by the top-level agent
+	EventID   string      `json:"event_id"`   // ID of the wrapped event
+	EventType EventType   `json:"event_type"` // Type of the wrapped event
+	Data      interface{} `json:"data"`       // Data of the wrapped event
+}
+
+// KBRoutingData carries the knowledge bases selected for a query
+type KBRoutingData struct {
+	Decision interface{} `json:"decision"` // *types.KBRoutingDecision
+}
+
 // SessionTitleData represents session title update data
 type SessionTitleData struct {
 	SessionID string `json:"session_id"`
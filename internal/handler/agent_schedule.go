@@ -0,0 +1,306 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AgentScheduleHandler handles agent schedule related HTTP requests
+type AgentScheduleHandler struct {
+	scheduleService interfaces.AgentScheduleService
+}
+
+// NewAgentScheduleHandler creates a new agent schedule handler
+func NewAgentScheduleHandler(scheduleService interfaces.AgentScheduleService) *AgentScheduleHandler {
+	return &AgentScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+// CreateAgentSchedule godoc
+// @Summary      Create Agent Schedule
+// @Description  Run a custom agent with a fixed prompt on a cron schedule, optionally posting each answer to a webhook
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.AgentSchedule     true  "Agent schedule configuration"
+// @Success      200      {object}  map[string]interface{}  "Created agent schedule"
+// @Failure      400      {object}  errors.AppError         "Invalid schedule"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules [post]
+func (h *AgentScheduleHandler) CreateAgentSchedule(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var schedule types.AgentSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		logger.Error(ctx, "Failed to parse agent schedule request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+	schedule.ID = ""
+	schedule.TenantID = tenantID
+
+	if err := h.scheduleService.CreateSchedule(ctx, &schedule); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"schedule_name": secutils.SanitizeForLog(schedule.Name),
+		})
+		c.Error(agentScheduleError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// ListAgentSchedules godoc
+// @Summary      Get Agent Schedule List
+// @Description  Get all agent schedules for current tenant
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Agent schedule list"
+// @Failure      400  {object}  errors.AppError         "Invalid request parameters"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules [get]
+func (h *AgentScheduleHandler) ListAgentSchedules(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	schedules, err := h.scheduleService.ListSchedules(ctx, tenantID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"tenant_id": tenantID})
+		c.Error(errors.NewInternalServerError("Failed to list agent schedules: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedules,
+	})
+}
+
+// GetAgentSchedule godoc
+// @Summary      Get Agent Schedule Details
+// @Description  Get agent schedule details by ID, the webhook secret is masked
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Agent Schedule ID"
+// @Success      200  {object}  map[string]interface{}  "Agent schedule details"
+// @Failure      404  {object}  errors.AppError         "Schedule not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules/{id} [get]
+func (h *AgentScheduleHandler) GetAgentSchedule(c *gin.Context) {
+	ctx := c.Request.Context()
+	scheduleID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	schedule, err := h.scheduleService.GetSchedule(ctx, tenantID, scheduleID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"schedule_id": scheduleID})
+		c.Error(agentScheduleError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// UpdateAgentSchedule godoc
+// @Summary      Update Agent Schedule
+// @Description  Update the provided fields of an agent schedule and recompute its next run time,
+// @Description  omitted fields keep their value. A masked webhook secret keeps the stored one.
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                     true  "Agent Schedule ID"
+// @Param        request  body      types.AgentScheduleUpdate  true  "Fields to update"
+// @Success      200      {object}  map[string]interface{}     "Updated agent schedule"
+// @Failure      400      {object}  errors.AppError            "Invalid schedule"
+// @Failure      404      {object}  errors.AppError            "Schedule not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules/{id} [put]
+func (h *AgentScheduleHandler) UpdateAgentSchedule(c *gin.Context) {
+	ctx := c.Request.Context()
+	scheduleID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	var update types.AgentScheduleUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error(ctx, "Failed to parse agent schedule update request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	schedule, err := h.scheduleService.UpdateSchedule(ctx, tenantID, scheduleID, &update)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"schedule_id": scheduleID})
+		c.Error(agentScheduleError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// DeleteAgentSchedule godoc
+// @Summary      Delete Agent Schedule
+// @Description  Delete agent schedule, its run history and sessions are kept
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Agent Schedule ID"
+// @Success      200  {object}  map[string]interface{}  "Deleted successfully"
+// @Failure      404  {object}  errors.AppError         "Schedule not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules/{id} [delete]
+func (h *AgentScheduleHandler) DeleteAgentSchedule(c *gin.Context) {
+	ctx := c.Request.Context()
+	scheduleID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	if err := h.scheduleService.DeleteSchedule(ctx, tenantID, scheduleID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"schedule_id": scheduleID})
+		c.Error(agentScheduleError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Agent schedule deleted successfully",
+	})
+}
+
+// TriggerAgentSchedule godoc
+// @Summary      Run Agent Schedule Now
+// @Description  Start a run of the schedule right away, outside its cron schedule
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Agent Schedule ID"
+// @Success      200  {object}  map[string]interface{}  "Started run"
+// @Failure      404  {object}  errors.AppError         "Schedule not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules/{id}/run [post]
+func (h *AgentScheduleHandler) TriggerAgentSchedule(c *gin.Context) {
+	ctx := c.Request.Context()
+	scheduleID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	run, err := h.scheduleService.TriggerSchedule(ctx, tenantID, scheduleID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"schedule_id": scheduleID})
+		c.Error(agentScheduleError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// ListAgentScheduleRuns godoc
+// @Summary      Get Agent Schedule Run History
+// @Description  Get the latest runs of an agent schedule, newest first
+// @Tags         Agent Schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Agent Schedule ID"
+// @Success      200  {object}  map[string]interface{}  "Run history"
+// @Failure      404  {object}  errors.AppError         "Schedule not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agent-schedules/{id}/runs [get]
+func (h *AgentScheduleHandler) ListAgentScheduleRuns(c *gin.Context) {
+	ctx := c.Request.Context()
+	scheduleID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	runs, err := h.scheduleService.ListRuns(ctx, tenantID, scheduleID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"schedule_id": scheduleID})
+		c.Error(agentScheduleError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    runs,
+	})
+}
+
+// agentScheduleError maps agent schedule errors to HTTP errors
+func agentScheduleError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrAgentScheduleNotFound):
+		return errors.NewNotFoundError(err.Error())
+	case stderrors.Is(err, service.ErrInvalidAgentSchedule):
+		return errors.NewBadRequestError(err.Error())
+	default:
+		return errors.NewInternalServerError(err.Error())
+	}
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	stderrors "errors"
+	"fmt"
 	"net/http"
 
 	"github.com/Tencent/WeKnora/internal/application/service"
@@ -10,17 +12,23 @@ import (
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	secutils "github.com/Tencent/WeKnora/internal/utils"
 	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
 )
 
 // CustomAgentHandler defines the HTTP handler for custom agent operations
 type CustomAgentHandler struct {
-	service interfaces.CustomAgentService
+	service       interfaces.CustomAgentService
+	bundleService interfaces.AgentBundleService
 }
 
 // NewCustomAgentHandler creates a new custom agent handler instance
-func NewCustomAgentHandler(service interfaces.CustomAgentService) *CustomAgentHandler {
+func NewCustomAgentHandler(
+	service interfaces.CustomAgentService,
+	bundleService interfaces.AgentBundleService,
+) *CustomAgentHandler {
 	return &CustomAgentHandler{
-		service: service,
+		service:       service,
+		bundleService: bundleService,
 	}
 }
 
@@ -80,7 +88,9 @@ func (h *CustomAgentHandler) CreateAgent(c *gin.Context) {
 	createdAgent, err := h.service.CreateAgent(ctx, agent)
 	if err != nil {
 		logger.ErrorWithFields(ctx, err, nil)
-		if err == service.ErrAgentNameRequired {
+		if err == service.ErrAgentNameRequired || err == service.ErrInvalidGuardrail ||
+			err == service.ErrInvalidToolBudget || err == service.ErrInvalidMCPPolicy ||
+			err == service.ErrInvalidSubAgents {
 			c.Error(errors.NewBadRequestError(err.Error()))
 			return
 		}
@@ -225,7 +235,8 @@ func (h *CustomAgentHandler) UpdateAgent(c *gin.Context) {
 			c.Error(errors.NewNotFoundError("Agent not found"))
 		case service.ErrCannotModifyBuiltin:
 			c.Error(errors.NewForbiddenError("Cannot modify built-in agent"))
-		case service.ErrAgentNameRequired:
+		case service.ErrAgentNameRequired, service.ErrInvalidGuardrail, service.ErrInvalidToolBudget,
+			service.ErrInvalidMCPPolicy, service.ErrInvalidSubAgents:
 			c.Error(errors.NewBadRequestError(err.Error()))
 		default:
 			c.Error(errors.NewInternalServerError(err.Error()))
@@ -344,6 +355,114 @@ func (h *CustomAgentHandler) CopyAgent(c *gin.Context) {
 	})
 }
 
+// ImportAgentRequest defines the request body for importing an agent bundle
+type ImportAgentRequest struct {
+	// YAML bundle produced by the export endpoint
+	Bundle string `json:"bundle" binding:"required"`
+	types.AgentImportOptions
+}
+
+// ExportAgent godoc
+// @Summary      Export Agent
+// @Description  Export an agent as a portable YAML bundle, with its prompts, referenced models and
+// @Description  knowledge bases, and MCP/OpenAPI service definitions without secrets
+// @Tags         Agent
+// @Produce      application/x-yaml
+// @Param        id   path      string  true  "Agent ID"
+// @Success      200  {string}  string                  "YAML bundle"
+// @Failure      404  {object}  errors.AppError         "Agent not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/{id}/export [get]
+func (h *CustomAgentHandler) ExportAgent(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := secutils.SanitizeForLog(c.Param("id"))
+	if id == "" {
+		logger.Error(ctx, "Agent ID is empty")
+		c.Error(errors.NewBadRequestError("Agent ID cannot be empty"))
+		return
+	}
+
+	logger.Infof(ctx, "Exporting custom agent, ID: %s", id)
+
+	bundle, err := h.bundleService.ExportAgent(ctx, id)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"agent_id": id,
+		})
+		if err == service.ErrAgentNotFound {
+			c.Error(errors.NewNotFoundError("Agent not found"))
+			return
+		}
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"agent-%s.yaml\"", id))
+	c.Data(http.StatusOK, "application/x-yaml", data)
+}
+
+// ImportAgent godoc
+// @Summary      Import Agent
+// @Description  Import an agent bundle into the current tenant. Referenced models and knowledge bases
+// @Description  are matched by mapping, ID or name; MCP/OpenAPI services and the agent get new IDs
+// @Tags         Agent
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ImportAgentRequest      true  "Bundle and reference mappings"
+// @Success      201      {object}  map[string]interface{}  "Imported agent, services and ID mapping"
+// @Failure      400      {object}  errors.AppError         "Invalid bundle or unresolved references"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /agents/import [post]
+func (h *CustomAgentHandler) ImportAgent(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	logger.Info(ctx, "Start importing custom agent")
+
+	var req ImportAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	var bundle types.AgentBundle
+	if err := yaml.Unmarshal([]byte(req.Bundle), &bundle); err != nil {
+		logger.Error(ctx, "Failed to parse agent bundle", err)
+		c.Error(errors.NewBadRequestError("Invalid agent bundle").WithDetails(err.Error()))
+		return
+	}
+
+	result, err := h.bundleService.ImportAgent(ctx, &bundle, &req.AgentImportOptions)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		if stderrors.Is(err, service.ErrInvalidAgentBundle) || err == service.ErrAgentNameRequired ||
+			err == service.ErrInvalidGuardrail || err == service.ErrInvalidToolBudget ||
+			err == service.ErrInvalidMCPPolicy || err == service.ErrInvalidSubAgents {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Custom agent imported successfully, ID: %s, name: %s",
+		secutils.SanitizeForLog(result.Agent.ID), secutils.SanitizeForLog(result.Agent.Name))
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
 // GetPlaceholders godoc
 // @Summary      Get Placeholder Definitions
 // @Description  Get all available prompt placeholder definitions, grouped by field type
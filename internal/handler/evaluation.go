@@ -87,6 +87,65 @@ func (e *EvaluationHandler) Evaluation(c *gin.Context) {
 	})
 }
 
+// AgentEvaluationRequest contains parameters for agent evaluation request
+type AgentEvaluationRequest struct {
+	DatasetID       string   `json:"dataset_id"`                   // ID of dataset to evaluate
+	KnowledgeBaseID string   `json:"knowledge_base_id"`            // ID of knowledge base whose models are used
+	AgentIDs        []string `json:"agent_ids" binding:"required"` // IDs of custom agents to compare
+}
+
+// AgentEvaluation godoc
+// @Summary      Execute Agent Evaluation
+// @Description  Evaluate smart-reasoning agents on a dataset, scoring final answers and tool-use trajectories
+// @Tags         Evaluation
+// @Accept       json
+// @Produce      json
+// @Param        request  body      AgentEvaluationRequest  true  "Agent evaluation request parameters"
+// @Success      200      {object}  map[string]interface{}  "Evaluation task"
+// @Failure      400      {object}  errors.AppError         "Invalid request parameters"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /evaluation/agent [post]
+func (e *EvaluationHandler) AgentEvaluation(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	logger.Info(ctx, "Start processing agent evaluation request")
+
+	var request AgentEvaluationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewBadRequestError("Invalid request parameters").WithDetails(err.Error()))
+		return
+	}
+
+	agentIDs := make([]string, 0, len(request.AgentIDs))
+	for _, agentID := range request.AgentIDs {
+		agentIDs = append(agentIDs, secutils.SanitizeForLog(agentID))
+	}
+	logger.Infof(ctx, "Executing agent evaluation, dataset: %s, knowledge_base: %s, agents: %v",
+		secutils.SanitizeForLog(request.DatasetID),
+		secutils.SanitizeForLog(request.KnowledgeBaseID),
+		agentIDs,
+	)
+
+	task, err := e.evaluationService.AgentEvaluation(ctx,
+		secutils.SanitizeForLog(request.DatasetID),
+		secutils.SanitizeForLog(request.KnowledgeBaseID),
+		agentIDs,
+	)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	logger.Infof(ctx, "Agent evaluation task created successfully")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    task,
+	})
+}
+
 // GetEvaluationRequest contains parameters for getting evaluation result
 type GetEvaluationRequest struct {
 	TaskID string `form:"task_id" binding:"required"` // ID of evaluation task
@@ -1585,6 +1585,7 @@ func (h *InitializationHandler) checkRemoteModelConnection(ctx context.Context,
 		ModelName: model.Name,
 		APIKey:    model.Parameters.APIKey,
 		ModelID:   model.Name,
+		Provider:  model.Parameters.Provider,
 	}
 
 	// 创建聊天实例
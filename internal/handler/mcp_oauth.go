@@ -0,0 +1,218 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// mcpOAuthCallbackPath is the path of the OAuth callback, used to derive the default redirect URI
+const mcpOAuthCallbackPath = "/api/v1/mcp-oauth/callback"
+
+// mcpOAuthNonceCookie holds the nonce binding a pending authorization to the browser which started it
+const mcpOAuthNonceCookie = "weknora_mcp_oauth_nonce"
+
+// MCPOAuthHandler handles the OAuth authorization of MCP services
+type MCPOAuthHandler struct {
+	oauthService interfaces.MCPOAuthService
+}
+
+// NewMCPOAuthHandler creates a new MCP OAuth handler
+func NewMCPOAuthHandler(oauthService interfaces.MCPOAuthService) *MCPOAuthHandler {
+	return &MCPOAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+// AuthorizeMCPService godoc
+// @Summary      Start MCP Service Authorization
+// @Description  Start the OAuth authorization of an MCP service and return the URL of the authorization server.
+// @Description  Sets an HttpOnly cookie binding the authorization to the browser, checked by the callback.
+// @Tags         MCP Service
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string  true   "MCP Service ID"
+// @Param        return_url  query     string  false  "Path or URL on the API host to return to after the callback"
+// @Success      200  {object}  map[string]interface{}  "Authorization URL"
+// @Failure      400  {object}  errors.AppError         "Service does not use OAuth"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/oauth/authorize [get]
+func (h *MCPOAuthHandler) AuthorizeMCPService(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	authURL, nonce, err := h.oauthService.Authorize(ctx, serviceID, callbackURL(c), c.Query("return_url"))
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(mcpOAuthError(err))
+		return
+	}
+	setOAuthNonceCookie(c, nonce, int(types.MCPOAuthStateTTL.Seconds()))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"authorization_url": authURL},
+	})
+}
+
+// MCPOAuthCallback godoc
+// @Summary      MCP Service Authorization Callback
+// @Description  Receive the authorization code of an MCP service from the authorization server, then redirect to the return URL
+// @Tags         MCP Service
+// @Produce      json
+// @Param        state  query  string  true   "State of the authorization"
+// @Param        code   query  string  false  "Authorization code"
+// @Param        error  query  string  false  "Error reported by the authorization server"
+// @Success      200  {object}  map[string]interface{}  "Authorized"
+// @Success      302  "Redirect to the return URL"
+// @Failure      400  {object}  errors.AppError  "Unknown or expired state, or started in another browser"
+// @Router       /mcp-oauth/callback [get]
+func (h *MCPOAuthHandler) MCPOAuthCallback(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// The cookie is only sent back to the browser which started the authorization
+	nonce, _ := c.Cookie(mcpOAuthNonceCookie)
+	setOAuthNonceCookie(c, "", -1)
+	returnURL, err := h.oauthService.HandleCallback(ctx,
+		c.Query("state"), nonce, c.Query("code"), c.Query("error"))
+	if err != nil {
+		logger.Error(ctx, "Failed to handle MCP OAuth callback", err)
+	}
+	if returnURL != "" {
+		c.Redirect(http.StatusFound, withOAuthResult(returnURL, err))
+		return
+	}
+	if err != nil {
+		c.Error(mcpOAuthError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "MCP service authorized",
+	})
+}
+
+// GetMCPOAuthStatus godoc
+// @Summary      Get MCP Service Authorization Status
+// @Description  Get whether the calling user (or the tenant) has authorized an MCP service
+// @Tags         MCP Service
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP Service ID"
+// @Success      200  {object}  map[string]interface{}  "Authorization status"
+// @Failure      404  {object}  errors.AppError         "Service not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/oauth [get]
+func (h *MCPOAuthHandler) GetMCPOAuthStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	status, err := h.oauthService.GetStatus(ctx, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(mcpOAuthError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// RevokeMCPOAuth godoc
+// @Summary      Revoke MCP Service Authorization
+// @Description  Delete the OAuth token of an MCP service for the calling user (or the tenant)
+// @Tags         MCP Service
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP Service ID"
+// @Success      200  {object}  map[string]interface{}  "Revoked"
+// @Failure      400  {object}  errors.AppError         "Service does not use OAuth"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/oauth [delete]
+func (h *MCPOAuthHandler) RevokeMCPOAuth(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	if err := h.oauthService.Revoke(ctx, serviceID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(mcpOAuthError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "MCP service authorization revoked",
+	})
+}
+
+// callbackURL derives the OAuth callback URL from the address the API was reached at
+func callbackURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host + mcpOAuthCallbackPath
+}
+
+// setOAuthNonceCookie stores the browser nonce of an authorization for the callback, a negative
+// maxAge deletes it. The cookie must survive the top-level redirect back from the authorization server.
+func setOAuthNonceCookie(c *gin.Context, nonce string, maxAge int) {
+	secure := strings.HasPrefix(callbackURL(c), "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(mcpOAuthNonceCookie, nonce, maxAge, mcpOAuthCallbackPath, "", secure, true)
+}
+
+// withOAuthResult adds the result of the authorization to the query of the return URL
+func withOAuthResult(returnURL string, err error) string {
+	parsed, parseErr := url.Parse(returnURL)
+	if parseErr != nil {
+		return returnURL
+	}
+	query := parsed.Query()
+	if err != nil {
+		query.Set("mcp_oauth", "error")
+		query.Set("mcp_oauth_error", err.Error())
+	} else {
+		query.Set("mcp_oauth", "success")
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func mcpOAuthError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrMCPServiceNotFound):
+		return errors.NewNotFoundError(err.Error())
+	case stderrors.Is(err, service.ErrMCPOAuthNotConfigured),
+		stderrors.Is(err, service.ErrMCPOAuthUserRequired),
+		stderrors.Is(err, service.ErrInvalidOAuthState),
+		stderrors.Is(err, service.ErrOAuthBrowserMismatch),
+		stderrors.Is(err, service.ErrInvalidReturnURL),
+		stderrors.Is(err, service.ErrInvalidTenantID):
+		return errors.NewBadRequestError(err.Error())
+	default:
+		return errors.NewInternalServerError(err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// nonceOAuthService accepts the callback of its single authorization with the nonce it issued
+type nonceOAuthService struct {
+	interfaces.MCPOAuthService
+}
+
+func (nonceOAuthService) Authorize(ctx context.Context, serviceID, redirectURI, returnURL string) (string, string, error) {
+	return "https://auth.example.com/authorize?state=state-1", "nonce-1", nil
+}
+
+func (nonceOAuthService) HandleCallback(ctx context.Context, state, nonce, code, authErr string) (string, error) {
+	if nonce != "nonce-1" {
+		return "", service.ErrOAuthBrowserMismatch
+	}
+	return "/agents", nil
+}
+
+func TestMCPOAuthNonceCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewMCPOAuthHandler(nonceOAuthService{})
+	router := gin.New()
+	router.GET("/api/v1/mcp-services/:id/oauth/authorize", h.AuthorizeMCPService)
+	router.GET(mcpOAuthCallbackPath, h.MCPOAuthCallback)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/mcp-services/svc/oauth/authorize", nil))
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == mcpOAuthNonceCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value != "nonce-1" || !cookie.HttpOnly || cookie.Path != mcpOAuthCallbackPath {
+		t.Fatalf("Expected an HttpOnly nonce cookie on the callback path, got %+v", cookie)
+	}
+
+	// The browser which started the authorization is redirected back
+	req := httptest.NewRequest(http.MethodGet, mcpOAuthCallbackPath+"?state=state-1&code=code", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/agents?mcp_oauth=success" {
+		t.Errorf("Expected a redirect to the return URL, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+
+	// Another browser is rejected
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, mcpOAuthCallbackPath+"?state=state-1&code=code", nil)
+	h.MCPOAuthCallback(c)
+	var appErr *errors.AppError
+	if len(c.Errors) != 1 || !stderrors.As(c.Errors[0].Err, &appErr) || appErr.HTTPCode != http.StatusBadRequest ||
+		appErr.Message != service.ErrOAuthBrowserMismatch.Error() {
+		t.Errorf("Expected the callback to be rejected, got %v", c.Errors)
+	}
+}
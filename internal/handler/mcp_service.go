@@ -419,3 +419,39 @@ func (h *MCPServiceHandler) GetMCPServiceResources(c *gin.Context) {
 		"data":    resources,
 	})
 }
+
+// GetMCPServicePrompts godoc
+// @Summary      Get MCP Service Prompts List
+// @Description  Get list of prompt templates provided by MCP service
+// @Tags         MCP Service
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "MCP Service ID"
+// @Success      200  {object}  map[string]interface{}  "Prompts list"
+// @Failure      500  {object}  errors.AppError         "Server error"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /mcp-services/{id}/prompts [get]
+func (h *MCPServiceHandler) GetMCPServicePrompts(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	prompts, err := h.mcpServiceService.GetMCPServicePrompts(ctx, tenantID, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": secutils.SanitizeForLog(serviceID)})
+		c.Error(errors.NewInternalServerError("Failed to get MCP service prompts: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    prompts,
+	})
+}
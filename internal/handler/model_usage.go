@@ -0,0 +1,119 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// usageDateLayout is the layout of the dates of usage statistics queries
+const usageDateLayout = "2006-01-02"
+
+// ModelUsageHandler handles model usage and token quota related HTTP requests
+type ModelUsageHandler struct {
+	usageService interfaces.ModelUsageService
+}
+
+// NewModelUsageHandler creates a new model usage handler
+func NewModelUsageHandler(usageService interfaces.ModelUsageService) *ModelUsageHandler {
+	return &ModelUsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetUsageStats godoc
+// @Summary      Get Model Usage Statistics
+// @Description  Aggregate the token usage of chat, embedding and rerank calls by day, model, agent or source
+// @Tags         Usage
+// @Accept       json
+// @Produce      json
+// @Param        group_by    query     string  false  "day, model, agent or source (default day)"
+// @Param        start_date  query     string  false  "First day (YYYY-MM-DD, UTC), the first day of the month by default"
+// @Param        end_date    query     string  false  "Last day (YYYY-MM-DD, UTC), today by default"
+// @Param        source      query     string  false  "Only calls of this source"
+// @Success      200         {object}  map[string]interface{}  "Usage statistics"
+// @Failure      400         {object}  errors.AppError         "Invalid query"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /usage/stats [get]
+func (h *ModelUsageHandler) GetUsageStats(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	now := time.Now().UTC()
+	start, _ := types.TokenQuotaPeriod(now)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var err error
+	if value := c.Query("start_date"); value != "" {
+		if start, err = time.Parse(usageDateLayout, value); err != nil {
+			c.Error(errors.NewBadRequestError("start_date must be a date (YYYY-MM-DD)"))
+			return
+		}
+	}
+	if value := c.Query("end_date"); value != "" {
+		if end, err = time.Parse(usageDateLayout, value); err != nil {
+			c.Error(errors.NewBadRequestError("end_date must be a date (YYYY-MM-DD)"))
+			return
+		}
+	}
+
+	query := &types.ModelUsageQuery{
+		GroupBy: types.ModelUsageGroupBy(c.DefaultQuery("group_by", string(types.ModelUsageGroupByDay))),
+		Start:   start,
+		// The last day is included
+		End:    end.AddDate(0, 0, 1),
+		Source: types.ModelUsageSource(c.Query("source")),
+	}
+	stats, err := h.usageService.GetUsageStats(ctx, query)
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidUsageQuery) {
+			c.Error(errors.NewBadRequestError(err.Error()))
+			return
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to get usage statistics").WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"group_by":   query.GroupBy,
+			"start_date": query.Start.Format(usageDateLayout),
+			"end_date":   end.Format(usageDateLayout),
+			"items":      stats,
+		},
+	})
+}
+
+// GetTokenQuota godoc
+// @Summary      Get Token Quota
+// @Description  Get the monthly token quota of the tenant and the tokens used this month
+// @Tags         Usage
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Token quota status"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /usage/quota [get]
+func (h *ModelUsageHandler) GetTokenQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	status, err := h.usageService.GetQuotaStatus(ctx)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError("Failed to get token quota").WithDetails(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
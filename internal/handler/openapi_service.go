@@ -0,0 +1,270 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIServiceHandler handles OpenAPI service related HTTP requests
+type OpenAPIServiceHandler struct {
+	openAPIService interfaces.OpenAPIServiceService
+}
+
+// NewOpenAPIServiceHandler creates a new OpenAPI service handler
+func NewOpenAPIServiceHandler(openAPIService interfaces.OpenAPIServiceService) *OpenAPIServiceHandler {
+	return &OpenAPIServiceHandler{
+		openAPIService: openAPIService,
+	}
+}
+
+// CreateOpenAPIService godoc
+// @Summary      Create OpenAPI Service
+// @Description  Register an HTTP API from an OpenAPI 3 document, its selected operations become agent tools
+// @Tags         OpenAPI Service
+// @Accept       json
+// @Produce      json
+// @Param        request  body      types.OpenAPIService    true  "OpenAPI service configuration"
+// @Success      200      {object}  map[string]interface{}  "Created OpenAPI service"
+// @Failure      400      {object}  errors.AppError         "Invalid document or configuration"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openapi-services [post]
+func (h *OpenAPIServiceHandler) CreateOpenAPIService(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var openAPIService types.OpenAPIService
+	if err := c.ShouldBindJSON(&openAPIService); err != nil {
+		logger.Error(ctx, "Failed to parse OpenAPI service request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+	openAPIService.ID = ""
+	openAPIService.TenantID = tenantID
+
+	if err := h.openAPIService.CreateOpenAPIService(ctx, &openAPIService); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"service_name": secutils.SanitizeForLog(openAPIService.Name),
+		})
+		c.Error(openAPIServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    openAPIService,
+	})
+}
+
+// ListOpenAPIServices godoc
+// @Summary      Get OpenAPI Service List
+// @Description  Get all OpenAPI services for current tenant
+// @Tags         OpenAPI Service
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "OpenAPI service list"
+// @Failure      400  {object}  errors.AppError         "Invalid request parameters"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openapi-services [get]
+func (h *OpenAPIServiceHandler) ListOpenAPIServices(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	services, err := h.openAPIService.ListOpenAPIServices(ctx, tenantID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"tenant_id": tenantID})
+		c.Error(errors.NewInternalServerError("Failed to list OpenAPI services: " + err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    services,
+	})
+}
+
+// GetOpenAPIService godoc
+// @Summary      Get OpenAPI Service Details
+// @Description  Get OpenAPI service details by ID, auth header values are masked
+// @Tags         OpenAPI Service
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "OpenAPI Service ID"
+// @Success      200  {object}  map[string]interface{}  "OpenAPI service details"
+// @Failure      404  {object}  errors.AppError         "Service not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openapi-services/{id} [get]
+func (h *OpenAPIServiceHandler) GetOpenAPIService(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	openAPIService, err := h.openAPIService.GetOpenAPIServiceByID(ctx, tenantID, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(openAPIServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    openAPIService,
+	})
+}
+
+// UpdateOpenAPIService godoc
+// @Summary      Update OpenAPI Service
+// @Description  Update the provided fields of an OpenAPI service, omitted fields keep their value.
+// @Description  Masked auth header values keep their stored value.
+// @Tags         OpenAPI Service
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                      true  "OpenAPI Service ID"
+// @Param        request  body      types.OpenAPIServiceUpdate  true  "Fields to update"
+// @Success      200      {object}  map[string]interface{}      "Updated OpenAPI service"
+// @Failure      400      {object}  errors.AppError             "Invalid document or configuration"
+// @Failure      404      {object}  errors.AppError             "Service not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openapi-services/{id} [put]
+func (h *OpenAPIServiceHandler) UpdateOpenAPIService(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	var update types.OpenAPIServiceUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		logger.Error(ctx, "Failed to parse OpenAPI service update request", err)
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	openAPIService, err := h.openAPIService.UpdateOpenAPIService(ctx, tenantID, serviceID, &update)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(openAPIServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    openAPIService,
+	})
+}
+
+// DeleteOpenAPIService godoc
+// @Summary      Delete OpenAPI Service
+// @Description  Delete OpenAPI service configuration
+// @Tags         OpenAPI Service
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "OpenAPI Service ID"
+// @Success      200  {object}  map[string]interface{}  "Deleted successfully"
+// @Failure      404  {object}  errors.AppError         "Service not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openapi-services/{id} [delete]
+func (h *OpenAPIServiceHandler) DeleteOpenAPIService(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	if err := h.openAPIService.DeleteOpenAPIService(ctx, tenantID, serviceID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(openAPIServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "OpenAPI service deleted successfully",
+	})
+}
+
+// ListOpenAPIOperations godoc
+// @Summary      Get OpenAPI Service Operations
+// @Description  List the operations of the document of an OpenAPI service, with their tool names
+// @Tags         OpenAPI Service
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "OpenAPI Service ID"
+// @Success      200  {object}  map[string]interface{}  "Operation list"
+// @Failure      404  {object}  errors.AppError         "Service not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /openapi-services/{id}/operations [get]
+func (h *OpenAPIServiceHandler) ListOpenAPIOperations(c *gin.Context) {
+	ctx := c.Request.Context()
+	serviceID := secutils.SanitizeForLog(c.Param("id"))
+
+	tenantID := c.GetUint64(types.TenantIDContextKey.String())
+	if tenantID == 0 {
+		logger.Error(ctx, "Tenant ID is empty")
+		c.Error(errors.NewBadRequestError("Tenant ID cannot be empty"))
+		return
+	}
+
+	operations, err := h.openAPIService.ListOpenAPIOperations(ctx, tenantID, serviceID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{"service_id": serviceID})
+		c.Error(openAPIServiceError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    operations,
+	})
+}
+
+// openAPIServiceError maps OpenAPI service errors to HTTP errors
+func openAPIServiceError(err error) *errors.AppError {
+	switch {
+	case stderrors.Is(err, service.ErrOpenAPIServiceNotFound):
+		return errors.NewNotFoundError(err.Error())
+	case stderrors.Is(err, service.ErrInvalidOpenAPIService):
+		return errors.NewBadRequestError(err.Error())
+	default:
+		return errors.NewInternalServerError(err.Error())
+	}
+}
@@ -64,6 +64,14 @@ func (h *AgentStreamHandler) Subscribe() {
 	h.eventBus.On(event.EventAgentFinalAnswer, h.handleFinalAnswer)
 	h.eventBus.On(event.EventAgentReflection, h.handleReflection)
 	h.eventBus.On(event.EventError, h.handleError)
+	h.eventBus.On(event.EventGuardrailRefusal, h.handleGuardrailRefusal)
+	h.eventBus.On(event.EventStructuredOutput, h.handleStructuredOutput)
+	h.eventBus.On(event.EventRetrievalDebug, h.handleRetrievalDebug)
+	h.eventBus.On(event.EventKBRouting, h.handleKBRouting)
+	h.eventBus.On(event.EventAgentBudgetExhausted, h.handleBudgetExhausted)
+	h.eventBus.On(event.EventAgentApprovalRequest, h.handleApprovalRequest)
+	h.eventBus.On(event.EventAgentApprovalResult, h.handleApprovalResult)
+	h.eventBus.On(event.EventAgentSubAgent, h.handleSubAgent)
 	h.eventBus.On(event.EventSessionTitle, h.handleSessionTitle)
 	h.eventBus.On(event.EventAgentComplete, h.handleComplete)
 }
@@ -373,6 +381,227 @@ func (h *AgentStreamHandler) handleError(ctx context.Context, evt event.Event) e
 	return nil
 }
 
+// handleStructuredOutput handles JSON answers validated against the requested schema
+func (h *AgentStreamHandler) handleStructuredOutput(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.StructuredOutputData)
+	if !ok {
+		return nil
+	}
+
+	// Append structured output event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeStructuredOutput,
+		Content:   string(data.Object),
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"object":   data.Object,
+			"valid":    data.Valid,
+			"repaired": data.Repaired,
+			"error":    data.Error,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append structured output event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleRetrievalDebug handles the retrieval trace of debug requests
+func (h *AgentStreamHandler) handleRetrievalDebug(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.RetrievalDebugData)
+	if !ok {
+		return nil
+	}
+
+	// Append retrieval debug event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeRetrievalDebug,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"trace": data.Trace,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append retrieval debug event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleKBRouting handles the knowledge bases selected for the query
+func (h *AgentStreamHandler) handleKBRouting(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.KBRoutingData)
+	if !ok {
+		return nil
+	}
+
+	// Append routing event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeKBRouting,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"decision": data.Decision,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append kb routing event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleApprovalRequest handles tool calls waiting for human approval
+func (h *AgentStreamHandler) handleApprovalRequest(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.AgentApprovalRequestData)
+	if !ok {
+		return nil
+	}
+
+	// Append approval request event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeApprovalRequest,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"approval_id":  data.ApprovalID,
+			"tool_call_id": data.ToolCallID,
+			"tool_name":    data.ToolName,
+			"arguments":    data.Arguments,
+			"expires_at":   data.ExpiresAt,
+			"iteration":    data.Iteration,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append approval request event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleApprovalResult handles decisions on tool call approvals
+func (h *AgentStreamHandler) handleApprovalResult(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.AgentApprovalResultData)
+	if !ok {
+		return nil
+	}
+
+	// Append approval result event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeApprovalResult,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"approval_id":  data.ApprovalID,
+			"tool_call_id": data.ToolCallID,
+			"tool_name":    data.ToolName,
+			"status":       data.Status,
+			"reason":       data.Reason,
+			"iteration":    data.Iteration,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append approval result event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleSubAgent handles events of the agents the current agent delegated to
+func (h *AgentStreamHandler) handleSubAgent(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.SubAgentEventData)
+	if !ok {
+		return nil
+	}
+
+	// Append sub-agent event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeSubAgent,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"agent_id":   data.AgentID,
+			"agent_name": data.AgentName,
+			"depth":      data.Depth,
+			"event_id":   data.EventID,
+			"event_type": data.EventType,
+			"data":       data.Data,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append sub-agent event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleBudgetExhausted handles tool call and token budgets exhausted by the agent
+func (h *AgentStreamHandler) handleBudgetExhausted(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.AgentBudgetExhaustedData)
+	if !ok {
+		return nil
+	}
+
+	// Append budget event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeBudgetExhausted,
+		Content:   "",
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"kind":      data.Kind,
+			"tool_name": data.ToolName,
+			"limit":     data.Limit,
+			"used":      data.Used,
+			"iteration": data.Iteration,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append budget exhausted event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
+// handleGuardrailRefusal handles requests or answers refused by a guardrail policy
+func (h *AgentStreamHandler) handleGuardrailRefusal(ctx context.Context, evt event.Event) error {
+	data, ok := evt.Data.(event.GuardrailRefusalData)
+	if !ok {
+		return nil
+	}
+
+	// Refused content must not be kept in the stored answer
+	h.mu.Lock()
+	h.finalAnswer = ""
+	h.mu.Unlock()
+
+	// Append refusal event to stream
+	if err := h.streamManager.AppendEvent(h.ctx, h.sessionID, h.assistantMessageID, interfaces.StreamEvent{
+		ID:        evt.ID,
+		Type:      types.ResponseTypeRefusal,
+		Content:   data.Message,
+		Done:      false,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"stage":  data.Stage,
+			"policy": data.Policy,
+			"reason": data.Reason,
+		},
+	}); err != nil {
+		logger.GetLogger(h.ctx).Error("Append guardrail refusal event to stream failed", "error", err)
+	}
+
+	return nil
+}
+
 // handleSessionTitle handles session title update events
 func (h *AgentStreamHandler) handleSessionTitle(ctx context.Context, evt event.Event) error {
 	data, ok := evt.Data.(event.SessionTitleData)
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/application/service"
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ListToolApprovals godoc
+// @Summary      List Pending Tool Approvals
+// @Description  List agent tool calls of the session waiting for human approval
+// @Tags         Q&A
+// @Produce      json
+// @Param        session_id  path      string  true  "Session ID"
+// @Success      200         {object}  map[string]interface{}  "Pending approvals"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{session_id}/approvals [get]
+func (h *Handler) ListToolApprovals(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	if sessionID == "" {
+		c.Error(errors.NewBadRequestError(errors.ErrInvalidSessionID.Error()))
+		return
+	}
+
+	approvals, err := h.toolApprovalService.ListPending(ctx, sessionID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    approvals,
+	})
+}
+
+// DecideToolApproval godoc
+// @Summary      Approve or Deny a Tool Call
+// @Description  Approve or deny an agent tool call waiting for human approval, the agent resumes with the decision
+// @Tags         Q&A
+// @Accept       json
+// @Produce      json
+// @Param        session_id   path      string                     true  "Session ID"
+// @Param        approval_id  path      string                     true  "Approval ID"
+// @Param        request      body      DecideToolApprovalRequest  true  "Decision"
+// @Success      200          {object}  map[string]interface{}     "Decided approval"
+// @Failure      404          {object}  errors.AppError            "Approval not found"
+// @Failure      409          {object}  errors.AppError            "Approval already decided or expired"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{session_id}/approvals/{approval_id} [post]
+func (h *Handler) DecideToolApproval(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	approvalID := secutils.SanitizeForLog(c.Param("approval_id"))
+	if sessionID == "" || approvalID == "" {
+		c.Error(errors.NewBadRequestError("session ID and approval ID are required"))
+		return
+	}
+
+	var request DecideToolApprovalRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.Error(errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	approval, err := h.toolApprovalService.Decide(ctx, sessionID, approvalID, request.Approved, request.Reason)
+	if err != nil {
+		switch err {
+		case service.ErrToolApprovalNotFound:
+			c.Error(errors.NewNotFoundError(err.Error()))
+		case service.ErrToolApprovalNotPending:
+			c.Error(errors.NewConflictError(err.Error()))
+		default:
+			logger.ErrorWithFields(ctx, err, map[string]interface{}{
+				"session_id":  sessionID,
+				"approval_id": approvalID,
+			})
+			c.Error(errors.NewInternalServerError(err.Error()))
+		}
+		return
+	}
+
+	logger.Infof(ctx, "Tool approval %s decided: %s", approvalID, approval.Status)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    approval,
+	})
+}
@@ -20,6 +20,12 @@ type Handler struct {
 	config               *config.Config                  // Application configuration
 	knowledgebaseService interfaces.KnowledgeBaseService // Service for managing knowledge bases
 	customAgentService   interfaces.CustomAgentService   // Service for managing custom agents
+	toolApprovalService  interfaces.ToolApprovalService  // Service for approving agent tool calls
+
+	checkpointService interfaces.AgentCheckpointService // Service for resuming interrupted agent executions
+	tenantService     interfaces.TenantService          // Service for loading tenants of resume tasks
+	scratchpadService interfaces.AgentScratchpadService // Service for the agent plan kept across turns
+	usageService      interfaces.ModelUsageService      // Service for checking token quotas before answering
 }
 
 // NewHandler creates a new instance of Handler with all necessary dependencies
@@ -30,6 +36,11 @@ func NewHandler(
 	config *config.Config,
 	knowledgebaseService interfaces.KnowledgeBaseService,
 	customAgentService interfaces.CustomAgentService,
+	toolApprovalService interfaces.ToolApprovalService,
+	checkpointService interfaces.AgentCheckpointService,
+	tenantService interfaces.TenantService,
+	scratchpadService interfaces.AgentScratchpadService,
+	usageService interfaces.ModelUsageService,
 ) *Handler {
 	return &Handler{
 		sessionService:       sessionService,
@@ -38,6 +49,11 @@ func NewHandler(
 		config:               config,
 		knowledgebaseService: knowledgebaseService,
 		customAgentService:   customAgentService,
+		toolApprovalService:  toolApprovalService,
+		checkpointService:    checkpointService,
+		tenantService:        tenantService,
+		scratchpadService:    scratchpadService,
+		usageService:         usageService,
 	}
 }
 
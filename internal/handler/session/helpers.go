@@ -122,7 +122,9 @@ func createAgentQueryEvent(sessionID, assistantMessageID string) interfaces.Stre
 }
 
 // createUserMessage creates a user message
-func (h *Handler) createUserMessage(ctx context.Context, sessionID, query, requestID string, mentionedItems types.MentionedItems) error {
+func (h *Handler) createUserMessage(
+	ctx context.Context, sessionID, query, requestID string, mentionedItems types.MentionedItems, images types.MessageImages,
+) error {
 	_, err := h.messageService.CreateMessage(ctx, &types.Message{
 		SessionID:      sessionID,
 		Role:           "user",
@@ -131,6 +133,7 @@ func (h *Handler) createUserMessage(ctx context.Context, sessionID, query, reque
 		CreatedAt:      time.Now(),
 		IsCompleted:    true,
 		MentionedItems: mentionedItems,
+		Images:         images,
 	})
 	return err
 }
@@ -31,6 +31,9 @@ type qaRequestContext struct {
 	summaryModelID   string
 	webSearchEnabled bool
 	mentionedItems   types.MentionedItems
+	images           types.MessageImages
+	responseSchema   json.RawMessage
+	debug            bool
 }
 
 // parseQARequest parses and validates a QA request, returns the request context
@@ -58,11 +61,36 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 		return nil, nil, errors.NewBadRequestError("Query content cannot be empty")
 	}
 
-	// Log request details
-	if requestJSON, err := json.Marshal(request); err == nil {
+	// Validate response schema
+	if len(request.ResponseSchema) > 0 {
+		if _, err := secutils.CompileJSONSchema(request.ResponseSchema); err != nil {
+			logger.Error(ctx, "Invalid response schema", err)
+			return nil, nil, errors.NewBadRequestError(err.Error())
+		}
+	}
+
+	// Validate attached images
+	images := types.MessageImages(request.Images)
+	if err := images.Validate(); err != nil {
+		logger.Error(ctx, "Invalid images", err)
+		return nil, nil, errors.NewBadRequestError(err.Error())
+	}
+
+	// Refuse the question before streaming once the tenant used its monthly token quota
+	if err := h.usageService.CheckQuota(ctx); err != nil {
+		return nil, nil, errors.NewTokenQuotaExceededError(err.Error())
+	}
+
+	// Log request details, leaving out the image data
+	logged := request
+	logged.Images = nil
+	if requestJSON, err := json.Marshal(logged); err == nil {
 		logger.Infof(ctx, "[%s] Request: session_id=%s, request=%s",
 			logPrefix, sessionID, secutils.SanitizeForLog(string(requestJSON)))
 	}
+	if len(images) > 0 {
+		logger.Infof(ctx, "[%s] Request has %d images", logPrefix, len(images))
+	}
 
 	// Get session
 	session, err := h.sessionService.GetSession(ctx, sessionID)
@@ -106,6 +134,9 @@ func (h *Handler) parseQARequest(c *gin.Context, logPrefix string) (*qaRequestCo
 		summaryModelID:   secutils.SanitizeForLog(request.SummaryModelID),
 		webSearchEnabled: request.WebSearchEnabled,
 		mentionedItems:   convertMentionedItems(request.MentionedItems),
+		images:           images,
+		responseSchema:   request.ResponseSchema,
+		debug:            request.Debug,
 	}
 
 	return reqCtx, &request, nil
@@ -220,6 +251,13 @@ func (h *Handler) SearchKnowledge(c *gin.Context) {
 		secutils.SanitizeForLog(request.Query),
 	)
 
+	// Collect per-candidate scores and drop reasons for debug requests
+	var trace *types.RetrievalTrace
+	if request.Debug {
+		trace = types.NewRetrievalTrace(request.Query)
+		ctx = types.WithRetrievalTrace(ctx, trace)
+	}
+
 	// Directly call knowledge retrieval service without LLM summarization
 	searchResults, err := h.sessionService.SearchKnowledge(ctx, knowledgeBaseIDs, request.KnowledgeIDs, request.Query)
 	if err != nil {
@@ -229,10 +267,14 @@ func (h *Handler) SearchKnowledge(c *gin.Context) {
 	}
 
 	logger.Infof(ctx, "Knowledge search completed, found %d results", len(searchResults))
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
 		"data":    searchResults,
-	})
+	}
+	if trace != nil {
+		response["debug"] = trace
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // KnowledgeQA godoc
@@ -292,6 +334,10 @@ func (h *Handler) AgentQA(c *gin.Context) {
 
 	// Route to appropriate handler based on agent mode
 	if agentModeEnabled {
+		if len(reqCtx.images) > 0 {
+			c.Error(errors.NewBadRequestError("Images are not supported in agent mode"))
+			return
+		}
 		h.executeAgentModeQA(reqCtx)
 	} else {
 		logger.Infof(reqCtx.ctx, "Agent mode disabled, delegating to normal mode for session: %s", reqCtx.sessionID)
@@ -302,11 +348,15 @@ func (h *Handler) AgentQA(c *gin.Context) {
 
 // executeNormalModeQA executes the normal (KnowledgeQA) mode
 func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bool) {
+	// The retrieval trace is streamed as a retrieval_debug event once retrieval completes
+	if reqCtx.debug {
+		reqCtx.ctx = types.WithRetrievalTrace(reqCtx.ctx, types.NewRetrievalTrace(reqCtx.query))
+	}
 	ctx := reqCtx.ctx
 	sessionID := reqCtx.sessionID
 
 	// Create user message
-	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
+	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems, reqCtx.images); err != nil {
 		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
@@ -322,13 +372,27 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 	// Setup SSE stream
 	streamCtx := h.setupSSEStream(reqCtx, generateTitle)
 
+	// Replace the stored answer with the refusal message when a guardrail refuses
+	refused := false
+	streamCtx.eventBus.On(event.EventGuardrailRefusal, func(ctx context.Context, evt event.Event) error {
+		data, ok := evt.Data.(event.GuardrailRefusalData)
+		if !ok {
+			return nil
+		}
+		refused = true
+		streamCtx.assistantMessage.Content = data.Message
+		return nil
+	})
+
 	// Setup completion handler for normal mode
 	streamCtx.eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
 		data, ok := evt.Data.(event.AgentFinalAnswerData)
 		if !ok {
 			return nil
 		}
-		streamCtx.assistantMessage.Content += data.Content
+		if !refused {
+			streamCtx.assistantMessage.Content += data.Content
+		}
 		if data.Done {
 			logger.Infof(streamCtx.asyncCtx, "Knowledge QA service completed for session: %s", sessionID)
 			h.completeAssistantMessage(streamCtx.asyncCtx, streamCtx.assistantMessage)
@@ -357,6 +421,7 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 			streamCtx.asyncCtx,
 			reqCtx.session,
 			reqCtx.query,
+			reqCtx.images,
 			reqCtx.knowledgeBaseIDs,
 			reqCtx.knowledgeIDs,
 			reqCtx.assistantMessage.ID,
@@ -364,6 +429,7 @@ func (h *Handler) executeNormalModeQA(reqCtx *qaRequestContext, generateTitle bo
 			reqCtx.webSearchEnabled,
 			streamCtx.eventBus,
 			reqCtx.customAgent,
+			reqCtx.responseSchema,
 		)
 		if err != nil {
 			logger.ErrorWithFields(streamCtx.asyncCtx, err, nil)
@@ -406,7 +472,7 @@ func (h *Handler) executeAgentModeQA(reqCtx *qaRequestContext) {
 	}
 
 	// Create user message
-	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems); err != nil {
+	if err := h.createUserMessage(ctx, sessionID, reqCtx.query, reqCtx.requestID, reqCtx.mentionedItems, reqCtx.images); err != nil {
 		reqCtx.c.Error(errors.NewInternalServerError(err.Error()))
 		return
 	}
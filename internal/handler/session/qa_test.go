@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/mcp"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	"github.com/gin-gonic/gin"
+)
+
+// userRecordingSessionService reports the user seen by the agent
+type userRecordingSessionService struct {
+	interfaces.SessionService
+	userIDs chan string
+}
+
+func (s *userRecordingSessionService) GetSession(ctx context.Context, id string) (*types.Session, error) {
+	return &types.Session{ID: id, Title: "session"}, nil
+}
+
+func (s *userRecordingSessionService) AgentQA(
+	ctx context.Context,
+	session *types.Session,
+	query string,
+	assistantMessageID string,
+	summaryModelID string,
+	eventBus *event.EventBus,
+	customAgent *types.CustomAgent,
+	knowledgeBaseIDs []string,
+	knowledgeIDs []string,
+) error {
+	s.userIDs <- mcp.UserIDFromContext(ctx)
+	return nil
+}
+
+type memoryMessageService struct {
+	interfaces.MessageService
+}
+
+func (s *memoryMessageService) CreateMessage(ctx context.Context, message *types.Message) (*types.Message, error) {
+	message.ID = "message-" + message.Role
+	return message, nil
+}
+
+func (s *memoryMessageService) UpdateMessage(ctx context.Context, message *types.Message) error {
+	return nil
+}
+
+type discardStreamManager struct{}
+
+func (discardStreamManager) AppendEvent(ctx context.Context, sessionID, messageID string, event interfaces.StreamEvent) error {
+	return nil
+}
+
+func (discardStreamManager) GetEvents(
+	ctx context.Context, sessionID, messageID string, fromOffset int,
+) ([]interfaces.StreamEvent, int, error) {
+	return nil, fromOffset, nil
+}
+
+type unlimitedUsageService struct {
+	interfaces.ModelUsageService
+}
+
+func (unlimitedUsageService) CheckQuota(ctx context.Context) error {
+	return nil
+}
+
+func TestAgentQAKeepsUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sessions := &userRecordingSessionService{userIDs: make(chan string, 1)}
+	h := &Handler{
+		sessionService: sessions,
+		messageService: &memoryMessageService{},
+		streamManager:  discardStreamManager{},
+		usageService:   unlimitedUsageService{},
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		// Stores the user the way the auth middleware does
+		c.Request = c.Request.WithContext(
+			context.WithValue(c.Request.Context(), types.UserContextKey, &types.User{ID: "user-1"}),
+		)
+	})
+	router.POST("/agent-chat/:session_id", h.AgentQA)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/agent-chat/session-1",
+		strings.NewReader(`{"query": "hello", "agent_enabled": true}`)).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	select {
+	case userID := <-sessions.userIDs:
+		if userID != "user-1" {
+			t.Errorf("Agent ran for user %q, want user-1", userID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Agent was not called")
+	}
+	// Disconnect the client to end the SSE stream
+	cancel()
+	<-done
+}
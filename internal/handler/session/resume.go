@@ -0,0 +1,203 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+)
+
+// agentResumeTarget is everything needed to resume an interrupted agent execution
+type agentResumeTarget struct {
+	session     *types.Session
+	message     *types.Message
+	checkpoint  *types.AgentCheckpoint
+	customAgent *types.CustomAgent
+}
+
+// ResumeAgentRun godoc
+// @Summary      Resume Agent Execution
+// @Description  Resume an agent execution interrupted by a server restart or failure from its last checkpoint, SSE streaming response
+// @Tags         Q&A
+// @Produce      text/event-stream
+// @Param        session_id  path      string  true  "Session ID"
+// @Param        message_id  path      string  true  "Assistant message ID"
+// @Success      200         {object}  map[string]interface{}  "Resumed execution (SSE stream)"
+// @Failure      404         {object}  errors.AppError         "No checkpoint for the message"
+// @Failure      409         {object}  errors.AppError         "Execution is still running or already finished"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{session_id}/messages/{message_id}/resume [post]
+func (h *Handler) ResumeAgentRun(c *gin.Context) {
+	ctx := logger.CloneContext(c.Request.Context())
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	messageID := secutils.SanitizeForLog(c.Param("message_id"))
+	if sessionID == "" || messageID == "" {
+		c.Error(errors.NewBadRequestError("session ID and message ID are required"))
+		return
+	}
+
+	target, err := h.claimAgentResume(ctx, sessionID, messageID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setSSEHeaders(c)
+	asyncCtx, cancel := context.WithCancel(logger.CloneContext(ctx))
+	eventBus, _ := h.startAgentResume(asyncCtx, cancel, target)
+
+	// Handle SSE events (blocking)
+	h.handleAgentEventsForSSE(ctx, c, sessionID, messageID, target.message.RequestID, eventBus, false)
+}
+
+// ProcessAgentResume handles the agent resume task enqueued by the recovery sweep.
+// Events of the resumed execution go to the stream manager, clients follow them with ContinueStream.
+// The task ends once the execution is claimed, the execution itself does not hold a worker.
+func (h *Handler) ProcessAgentResume(ctx context.Context, t *asynq.Task) error {
+	var payload types.AgentResumePayload
+	if err := json.Unmarshal(t.Payload(), &payload); err != nil {
+		logger.Errorf(ctx, "Failed to unmarshal agent resume payload: %v", err)
+		return err
+	}
+
+	// Set tenant context for downstream services
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, payload.TenantID)
+	tenant, err := h.tenantService.GetTenantByID(ctx, payload.TenantID)
+	if err != nil {
+		logger.Errorf(ctx, "Failed to get tenant %d for agent resume: %v", payload.TenantID, err)
+		return err
+	}
+	ctx = context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+
+	checkpoint, err := h.checkpointService.Get(ctx, payload.MessageID)
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil {
+		logger.Warnf(ctx, "Agent checkpoint not found, message ID: %s", payload.MessageID)
+		return nil
+	}
+
+	target, err := h.claimAgentResume(ctx, checkpoint.SessionID, checkpoint.MessageID)
+	if err != nil {
+		// Another instance resumed the execution first, or it is no longer resumable
+		logger.Infof(ctx, "Skip agent resume, message ID: %s: %v", checkpoint.MessageID, err)
+		return nil
+	}
+
+	asyncCtx, cancel := context.WithCancel(logger.CloneContext(ctx))
+	_, done := h.startAgentResume(asyncCtx, cancel, target)
+	go func() {
+		<-done
+		cancel()
+	}()
+	return nil
+}
+
+// claimAgentResume loads the interrupted execution of the message and claims it for this instance
+func (h *Handler) claimAgentResume(ctx context.Context, sessionID, messageID string) (*agentResumeTarget, error) {
+	checkpoint, err := h.checkpointService.Get(ctx, messageID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, nil)
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if checkpoint == nil || checkpoint.SessionID != sessionID {
+		return nil, errors.NewNotFoundError("No agent checkpoint found for the message")
+	}
+	if !checkpoint.IsResumable(time.Now()) {
+		return nil, errors.NewConflictError(
+			fmt.Sprintf("Agent execution is %s and cannot be resumed", checkpoint.Status))
+	}
+
+	session, err := h.sessionService.GetSession(ctx, sessionID)
+	if err != nil {
+		if err == errors.ErrSessionNotFound {
+			return nil, errors.NewNotFoundError(err.Error())
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	message, err := h.messageService.GetMessage(ctx, sessionID, messageID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if message == nil {
+		return nil, errors.NewNotFoundError("Message not found")
+	}
+	customAgent, err := h.customAgentService.GetAgentByID(ctx, checkpoint.CustomAgentID)
+	if err != nil {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("Agent %s not found: %v", checkpoint.CustomAgentID, err))
+	}
+
+	claimed, err := h.checkpointService.Claim(ctx, messageID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if !claimed {
+		return nil, errors.NewConflictError("Agent execution was resumed by another request")
+	}
+
+	// The resumed execution rewrites the answer of the message
+	message.IsCompleted = false
+	return &agentResumeTarget{
+		session:     session,
+		message:     message,
+		checkpoint:  checkpoint,
+		customAgent: customAgent,
+	}, nil
+}
+
+// startAgentResume resumes the claimed execution asynchronously, its events are written to the stream manager.
+// The returned channel is closed once the execution ends.
+func (h *Handler) startAgentResume(
+	asyncCtx context.Context,
+	cancel context.CancelFunc,
+	target *agentResumeTarget,
+) (*event.EventBus, <-chan struct{}) {
+	sessionID := target.session.ID
+	eventBus := event.NewEventBus()
+	h.setupStopEventHandler(eventBus, sessionID, target.message, cancel)
+	h.setupStreamHandler(asyncCtx, sessionID, target.message.ID,
+		target.message.RequestID, target.message, eventBus)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				buf := make([]byte, 1024)
+				runtime.Stack(buf, true)
+				logger.ErrorWithFields(asyncCtx,
+					errors.NewInternalServerError(fmt.Sprintf("Agent resume panicked: %v\n%s", r, string(buf))),
+					map[string]interface{}{"session_id": sessionID})
+			}
+			h.completeAssistantMessage(asyncCtx, target.message)
+			logger.Infof(asyncCtx, "Agent resume completed for session: %s", sessionID)
+		}()
+
+		err := h.sessionService.ResumeAgentQA(asyncCtx, target.session, target.checkpoint,
+			eventBus, target.customAgent)
+		if err != nil {
+			logger.ErrorWithFields(asyncCtx, err, nil)
+			eventBus.Emit(asyncCtx, event.Event{
+				Type:      event.EventError,
+				SessionID: sessionID,
+				Data: event.ErrorData{
+					Error:     err.Error(),
+					Stage:     "agent_execution",
+					SessionID: sessionID,
+				},
+			})
+		}
+	}()
+	return eventBus, done
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/Tencent/WeKnora/internal/errors"
+	"github.com/Tencent/WeKnora/internal/logger"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAgentScratchpad godoc
+// @Summary      Get Agent Scratchpad
+// @Description  Get the research plan and key findings the agent keeps across turns of the session
+// @Tags         Q&A
+// @Produce      json
+// @Param        session_id  path      string  true  "Session ID"
+// @Success      200         {object}  map[string]interface{}  "Plan and findings"
+// @Failure      404         {object}  errors.AppError         "Session not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{session_id}/scratchpad [get]
+func (h *Handler) GetAgentScratchpad(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	if !h.checkScratchpadSession(c, sessionID) {
+		return
+	}
+
+	scratchpad, err := h.scratchpadService.Get(ctx, sessionID)
+	if err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    scratchpad,
+	})
+}
+
+// ClearAgentScratchpad godoc
+// @Summary      Clear Agent Scratchpad
+// @Description  Clear the plan and findings of the session, the next turn starts a new plan
+// @Tags         Q&A
+// @Produce      json
+// @Param        session_id  path      string  true  "Session ID"
+// @Success      200         {object}  map[string]interface{}  "Cleared successfully"
+// @Failure      404         {object}  errors.AppError         "Session not found"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /sessions/{session_id}/scratchpad [delete]
+func (h *Handler) ClearAgentScratchpad(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := secutils.SanitizeForLog(c.Param("session_id"))
+	if !h.checkScratchpadSession(c, sessionID) {
+		return
+	}
+
+	if err := h.scratchpadService.Clear(ctx, sessionID); err != nil {
+		logger.ErrorWithFields(ctx, err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Agent scratchpad cleared successfully",
+	})
+}
+
+// checkScratchpadSession reports whether the session exists for the tenant, writing the error otherwise
+func (h *Handler) checkScratchpadSession(c *gin.Context, sessionID string) bool {
+	ctx := c.Request.Context()
+	if sessionID == "" {
+		c.Error(errors.NewBadRequestError(errors.ErrInvalidSessionID.Error()))
+		return false
+	}
+
+	if _, err := h.sessionService.GetSession(ctx, sessionID); err != nil {
+		if err == errors.ErrSessionNotFound {
+			c.Error(errors.NewNotFoundError(err.Error()))
+			return false
+		}
+		logger.ErrorWithFields(ctx, err, nil)
+		c.Error(errors.NewInternalServerError(err.Error()))
+		return false
+	}
+	return true
+}
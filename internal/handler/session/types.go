@@ -1,6 +1,8 @@
 package session
 
 import (
+	"encoding/json"
+
 	"github.com/Tencent/WeKnora/internal/types"
 )
 
@@ -38,6 +40,12 @@ type CreateKnowledgeQARequest struct {
 	SummaryModelID   string                 `json:"summary_model_id"`                      // Optional summary model ID for this request (overrides session default)
 	MentionedItems   []MentionedItemRequest `json:"mentioned_items"`                       // @mentioned knowledge bases and files
 	DisableTitle     bool                   `json:"disable_title"`                         // Whether to disable auto title generation
+	Images           []string               `json:"images"`                                // Images attached to the query (base64 data URLs or http(s) URLs)
+
+	// Optional JSON Schema; when set, the answer is returned as a JSON object conforming to it
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty" swaggertype:"object"`
+	// Whether to stream the retrieval trace (per-candidate scores and drop reasons)
+	Debug bool `json:"debug"`
 }
 
 // SearchKnowledgeRequest defines the request structure for searching knowledge without LLM summarization
@@ -46,6 +54,13 @@ type SearchKnowledgeRequest struct {
 	KnowledgeBaseID  string   `json:"knowledge_base_id"`                     // Single knowledge base ID (for backward compatibility)
 	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`                    // IDs of knowledge bases to search (multi-KB support)
 	KnowledgeIDs     []string `json:"knowledge_ids"`                         // IDs of specific knowledge (files) to search
+	Debug            bool     `json:"debug"`                                 // Whether to return the retrieval trace
+}
+
+// DecideToolApprovalRequest represents the decision on a tool call waiting for approval
+type DecideToolApprovalRequest struct {
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"` // Optional, passed to the model when the call is denied
 }
 
 // StopSessionRequest represents the stop session request
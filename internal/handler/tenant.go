@@ -1,6 +1,7 @@
 package handler
 
 import (
+	stderrors "errors"
 	"net/http"
 	"strconv"
 
@@ -8,6 +9,7 @@ import (
 
 	"github.com/Tencent/WeKnora/internal/agent"
 	agenttools "github.com/Tencent/WeKnora/internal/agent/tools"
+	"github.com/Tencent/WeKnora/internal/application/service"
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/errors"
 	"github.com/Tencent/WeKnora/internal/logger"
@@ -20,23 +22,28 @@ import (
 // Provides functionality for creating, retrieving, updating, and deleting tenants
 // through the REST API endpoints
 type TenantHandler struct {
-	service     interfaces.TenantService
-	userService interfaces.UserService
-	config      *config.Config
+	service      interfaces.TenantService
+	userService  interfaces.UserService
+	modelService interfaces.ModelService
+	config       *config.Config
 }
 
 // NewTenantHandler creates a new tenant handler instance with the provided service
 // Parameters:
 //   - service: An implementation of the TenantService interface for business logic
 //   - userService: An implementation of the UserService interface for user operations
+//   - modelService: An implementation of the ModelService interface for validating model routes
 //   - config: Application configuration
 //
 // Returns a pointer to the newly created TenantHandler
-func NewTenantHandler(service interfaces.TenantService, userService interfaces.UserService, config *config.Config) *TenantHandler {
+func NewTenantHandler(service interfaces.TenantService, userService interfaces.UserService,
+	modelService interfaces.ModelService, config *config.Config,
+) *TenantHandler {
 	return &TenantHandler{
-		service:     service,
-		userService: userService,
-		config:      config,
+		service:      service,
+		userService:  userService,
+		modelService: modelService,
+		config:       config,
 	}
 }
 
@@ -572,7 +579,7 @@ func (h *TenantHandler) updateTenantAgentConfigInternal(c *gin.Context) {
 
 // GetTenantKV godoc
 // @Summary      Get Tenant KV Configuration
-// @Description  Get tenant-level KV configuration (supports agent-config, web-search-config, conversation-config)
+// @Description  Get tenant-level KV configuration (supports agent-config, web-search-config, conversation-config, model-routing-config)
 // @Tags         Tenant Management
 // @Accept       json
 // @Produce      json
@@ -596,6 +603,9 @@ func (h *TenantHandler) GetTenantKV(c *gin.Context) {
 	case "conversation-config":
 		h.GetTenantConversationConfig(c)
 		return
+	case "model-routing-config":
+		h.GetTenantModelRoutingConfig(c)
+		return
 	case "prompt-templates":
 		h.GetPromptTemplates(c)
 		return
@@ -608,7 +618,7 @@ func (h *TenantHandler) GetTenantKV(c *gin.Context) {
 
 // UpdateTenantKV godoc
 // @Summary      Update Tenant KV Configuration
-// @Description  Update tenant-level KV configuration (supports agent-config, web-search-config, conversation-config)
+// @Description  Update tenant-level KV configuration (supports agent-config, web-search-config, conversation-config, model-routing-config)
 // @Tags         Tenant Management
 // @Accept       json
 // @Produce      json
@@ -633,6 +643,9 @@ func (h *TenantHandler) UpdateTenantKV(c *gin.Context) {
 	case "conversation-config":
 		h.updateTenantConversationInternal(c)
 		return
+	case "model-routing-config":
+		h.updateTenantModelRoutingInternal(c)
+		return
 	default:
 		logger.Info(ctx, "KV key not supported", "key", key)
 		c.Error(errors.NewBadRequestError("unsupported key"))
@@ -848,6 +861,85 @@ func (h *TenantHandler) updateTenantConversationInternal(c *gin.Context) {
 	})
 }
 
+// GetTenantModelRoutingConfig godoc
+// @Summary      Get Tenant Model Routing Configuration
+// @Description  Get tenant's model fallback routes, retry and circuit breaker policies
+// @Tags         Tenant Management
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}  "Model routing configuration"
+// @Failure      400  {object}  errors.AppError         "Invalid request parameters"
+// @Security     Bearer
+// @Security     ApiKeyAuth
+// @Router       /tenants/kv/model-routing-config [get]
+func (h *TenantHandler) GetTenantModelRoutingConfig(c *gin.Context) {
+	ctx := c.Request.Context()
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	cfg := tenant.ModelRoutingConfig
+	if cfg == nil {
+		cfg = &types.ModelRoutingConfig{Routes: []types.ModelRoute{}}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cfg,
+	})
+}
+
+// updateTenantModelRoutingInternal updates the model routing configuration of a tenant
+func (h *TenantHandler) updateTenantModelRoutingInternal(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cfg types.ModelRoutingConfig
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		logger.Error(ctx, "Failed to parse request parameters", err)
+		c.Error(errors.NewValidationError("Invalid request data").WithDetails(err.Error()))
+		return
+	}
+
+	if err := h.modelService.ValidateModelRouting(ctx, &cfg); err != nil {
+		if stderrors.Is(err, service.ErrModelNotFound) || stderrors.Is(err, service.ErrInvalidModelRoute) {
+			c.Error(errors.NewBadRequestError(err.Error()))
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to validate model routes").WithDetails(err.Error()))
+		}
+		return
+	}
+
+	tenant := ctx.Value(types.TenantInfoContextKey).(*types.Tenant)
+	if tenant == nil {
+		logger.Error(ctx, "Tenant is empty")
+		c.Error(errors.NewBadRequestError("Tenant is empty"))
+		return
+	}
+
+	tenant.ModelRoutingConfig = &cfg
+	updatedTenant, err := h.service.UpdateTenant(ctx, tenant)
+	if err != nil {
+		if appErr, ok := errors.IsAppError(err); ok {
+			logger.Error(ctx, "Failed to update tenant: application error", appErr)
+			c.Error(appErr)
+		} else {
+			logger.ErrorWithFields(ctx, err, nil)
+			c.Error(errors.NewInternalServerError("Failed to update tenant model routing config").WithDetails(err.Error()))
+		}
+		return
+	}
+
+	logger.Infof(ctx, "Tenant model routing config updated successfully, Tenant ID: %d", tenant.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    updatedTenant.ModelRoutingConfig,
+		"message": "Model routing configuration updated successfully",
+	})
+}
+
 // GetPromptTemplates godoc
 // @Summary      Get Prompt Templates
 // @Description  Get system-configured prompt template list
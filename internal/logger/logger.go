@@ -260,6 +260,7 @@ func CloneContext(ctx context.Context) context.Context {
 		types.TenantIDContextKey,
 		types.RequestIDContextKey,
 		types.TenantInfoContextKey,
+		types.UserContextKey,
 	} {
 		if v := ctx.Value(k); v != nil {
 			newCtx = context.WithValue(newCtx, k, v)
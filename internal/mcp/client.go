@@ -38,6 +38,12 @@ type MCPClient interface {
 	// ReadResource reads a resource from the MCP service
 	ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error)
 
+	// ListPrompts retrieves the list of available prompts from the MCP service
+	ListPrompts(ctx context.Context) ([]*types.MCPPrompt, error)
+
+	// GetPrompt renders a prompt of the MCP service with the given arguments
+	GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error)
+
 	// IsConnected returns true if the client is connected
 	IsConnected() bool
 
@@ -48,6 +54,10 @@ type MCPClient interface {
 // ClientConfig represents configuration for creating an MCP client
 type ClientConfig struct {
 	Service *types.MCPService
+	// OnToolsListChanged is called when the service notifies that its tool list changed
+	OnToolsListChanged func()
+	// OAuth authorizes the requests of SSE and HTTP Streamable clients, nil for services without OAuth
+	OAuth *transport.OAuthConfig
 }
 
 // mcpGoClient wraps mark3labs/mcp-go client to implement our MCPClient interface
@@ -99,10 +109,17 @@ func NewMCPClient(config *ClientConfig) (MCPClient, error) {
 		if config.Service.URL == nil || *config.Service.URL == "" {
 			return nil, fmt.Errorf("URL is required for SSE transport")
 		}
-		mcpClient, err = client.NewSSEMCPClient(*config.Service.URL,
-			client.WithHTTPClient(httpClient),
-			client.WithHeaders(headers),
-		)
+		if config.OAuth != nil {
+			mcpClient, err = client.NewOAuthSSEClient(*config.Service.URL, *config.OAuth,
+				client.WithHTTPClient(httpClient),
+				client.WithHeaders(headers),
+			)
+		} else {
+			mcpClient, err = client.NewSSEMCPClient(*config.Service.URL,
+				client.WithHTTPClient(httpClient),
+				client.WithHeaders(headers),
+			)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to create SSE client: %w", err)
 		}
@@ -111,10 +128,17 @@ func NewMCPClient(config *ClientConfig) (MCPClient, error) {
 			return nil, fmt.Errorf("URL is required for HTTP Streamable transport")
 		}
 		// For HTTP streamable, we need to use transport options
-		mcpClient, err = client.NewStreamableHttpClient(*config.Service.URL,
-			transport.WithHTTPBasicClient(httpClient),
-			transport.WithHTTPHeaders(headers),
-		)
+		if config.OAuth != nil {
+			mcpClient, err = client.NewOAuthStreamableHttpClient(*config.Service.URL, *config.OAuth,
+				transport.WithHTTPBasicClient(httpClient),
+				transport.WithHTTPHeaders(headers),
+			)
+		} else {
+			mcpClient, err = client.NewStreamableHttpClient(*config.Service.URL,
+				transport.WithHTTPBasicClient(httpClient),
+				transport.WithHTTPHeaders(headers),
+			)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to create HTTP streamable client: %w", err)
 		}
@@ -153,6 +177,15 @@ func NewMCPClient(config *ClientConfig) (MCPClient, error) {
 		return nil, ErrUnsupportedTransport
 	}
 
+	if config.OnToolsListChanged != nil {
+		onToolsListChanged := config.OnToolsListChanged
+		mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+			if notification.Method == mcp.MethodNotificationToolsListChanged {
+				onToolsListChanged()
+			}
+		})
+	}
+
 	return &mcpGoClient{
 		service: config.Service,
 		client:  mcpClient,
@@ -363,6 +396,80 @@ func (c *mcpGoClient) ReadResource(ctx context.Context, uri string) (*ReadResour
 	}, nil
 }
 
+// ListPrompts retrieves the list of available prompts
+func (c *mcpGoClient) ListPrompts(ctx context.Context) ([]*types.MCPPrompt, error) {
+	if !c.initialized {
+		return nil, ErrNotConnected
+	}
+
+	req := mcp.ListPromptsRequest{}
+	result, err := c.client.ListPrompts(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	// Convert to our types
+	prompts := make([]*types.MCPPrompt, len(result.Prompts))
+	for i, prompt := range result.Prompts {
+		arguments := make([]types.MCPPromptArgument, len(prompt.Arguments))
+		for j, argument := range prompt.Arguments {
+			arguments[j] = types.MCPPromptArgument{
+				Name:        argument.Name,
+				Description: argument.Description,
+				Required:    argument.Required,
+			}
+		}
+		prompts[i] = &types.MCPPrompt{
+			Name:        prompt.Name,
+			Description: prompt.Description,
+			Arguments:   arguments,
+		}
+	}
+
+	return prompts, nil
+}
+
+// GetPrompt renders a prompt with the given arguments
+func (c *mcpGoClient) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	if !c.initialized {
+		return nil, ErrNotConnected
+	}
+
+	req := mcp.GetPromptRequest{
+		Params: mcp.GetPromptParams{
+			Name:      name,
+			Arguments: args,
+		},
+	}
+
+	result, err := c.client.GetPrompt(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	// Convert to our types, keeping the text of each message
+	messages := make([]PromptMessage, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		item := PromptMessage{Role: string(message.Role)}
+		if textContent, ok := mcp.AsTextContent(message.Content); ok {
+			item.Text = textContent.Text
+		} else if resource, ok := mcp.AsEmbeddedResource(message.Content); ok {
+			if textContent, ok := mcp.AsTextResourceContents(resource.Resource); ok {
+				item.Text = textContent.Text
+			}
+		}
+		if item.Text == "" {
+			continue
+		}
+		messages = append(messages, item)
+	}
+
+	return &GetPromptResult{
+		Description: result.Description,
+		Messages:    messages,
+	}, nil
+}
+
 // IsConnected returns true if the client is connected
 func (c *mcpGoClient) IsConnected() bool {
 	return c.connected
@@ -30,3 +30,6 @@ var (
 	// ErrConnectionClosed is returned when connection is closed unexpectedly
 	ErrConnectionClosed = errors.New("connection closed")
 )
+
+// ErrOAuthAuthorizationRequired is returned when an OAuth service has no valid token for the caller
+var ErrOAuthAuthorizationRequired = errors.New("MCP service requires OAuth authorization")
@@ -3,29 +3,38 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	mcpclient "github.com/mark3labs/mcp-go/client"
 )
 
 // MCPManager manages MCP client connections
 type MCPManager struct {
-	clients   map[string]MCPClient // serviceID -> client
+	clients   map[string]MCPClient // serviceID, or serviceID/userID for per-user OAuth -> client
 	clientsMu sync.RWMutex
+	oauthRepo interfaces.MCPOAuthRepository
+	tools     map[string]*cachedTools // client key -> tool list
+	toolsMu   sync.RWMutex
 	ctx       context.Context
 	cancel    context.CancelFunc
 }
 
 // NewMCPManager creates a new MCP manager
-func NewMCPManager() *MCPManager {
+func NewMCPManager(oauthRepo interfaces.MCPOAuthRepository) *MCPManager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	manager := &MCPManager{
-		clients: make(map[string]MCPClient),
-		ctx:     ctx,
-		cancel:  cancel,
+		clients:   make(map[string]MCPClient),
+		oauthRepo: oauthRepo,
+		tools:     make(map[string]*cachedTools),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
 	// Start cleanup goroutine
@@ -37,7 +46,19 @@ func NewMCPManager() *MCPManager {
 // GetOrCreateClient gets an existing client or creates a new one
 // For stdio transport, always creates a new client (not cached)
 // For SSE/HTTP Streamable, caches and reuses existing connections
+// OAuth services use the token shared by the tenant, see GetOrCreateClientFor for per-user tokens
 func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, error) {
+	return m.getOrCreateClient(service, "")
+}
+
+// GetOrCreateClientFor gets or creates a client calling the service on behalf of the user of ctx,
+// with their own token when the service uses per-user OAuth authorization
+func (m *MCPManager) GetOrCreateClientFor(ctx context.Context, service *types.MCPService) (MCPClient, error) {
+	return m.getOrCreateClient(service, OAuthUserID(ctx, service))
+}
+
+// getOrCreateClient gets or creates the client of a service for a user, empty for the tenant
+func (m *MCPManager) getOrCreateClient(service *types.MCPService, userID string) (MCPClient, error) {
 	// Check if service is enabled
 	if !service.Enabled {
 		return nil, fmt.Errorf("MCP service %s is not enabled", service.Name)
@@ -49,8 +70,9 @@ func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, er
 	}
 
 	// For SSE/HTTP Streamable, check if client already exists and reuse
+	key := clientKey(service.ID, userID)
 	m.clientsMu.RLock()
-	client, exists := m.clients[service.ID]
+	client, exists := m.clients[key]
 	m.clientsMu.RUnlock()
 
 	if exists && client.IsConnected() {
@@ -62,17 +84,24 @@ func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, er
 	defer m.clientsMu.Unlock()
 
 	// Double check after acquiring write lock
-	client, exists = m.clients[service.ID]
+	client, exists = m.clients[key]
 	if exists && client.IsConnected() {
 		return client, nil
 	}
 
-	// Create new client
-	config := &ClientConfig{
-		Service: service,
+	// Create new client, notifications sent while no client was connected are lost
+	config, err := m.newClientConfig(service, userID)
+	if err != nil {
+		return nil, err
+	}
+	serviceID := service.ID
+	m.InvalidateTools(serviceID)
+	config.OnToolsListChanged = func() {
+		logger.GetLogger(m.ctx).Infof("MCP service %s tool list changed", serviceID)
+		m.InvalidateTools(serviceID)
 	}
 
-	client, err := NewMCPClient(config)
+	client, err = NewMCPClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MCP client: %w", err)
 	}
@@ -89,12 +118,66 @@ func (m *MCPManager) GetOrCreateClient(service *types.MCPService) (MCPClient, er
 	}
 
 	// Store client (only for non-stdio transports)
-	m.clients[service.ID] = client
+	m.clients[key] = client
 
 	logger.GetLogger(m.ctx).Infof("MCP client created and initialized for service: %s", service.Name)
 	return client, nil
 }
 
+// NewClientConfig returns the configuration of a new client calling the service on behalf of the user of ctx,
+// for clients not managed by the manager
+func (m *MCPManager) NewClientConfig(ctx context.Context, service *types.MCPService) (*ClientConfig, error) {
+	return m.newClientConfig(service, OAuthUserID(ctx, service))
+}
+
+// newClientConfig returns the configuration of a client of a service for a user, with the stored
+// OAuth token of the user when the service uses OAuth. The token is refreshed by the client when it expires.
+func (m *MCPManager) newClientConfig(service *types.MCPService, userID string) (*ClientConfig, error) {
+	config := &ClientConfig{Service: service}
+	oauth := service.OAuthConfig()
+	if oauth == nil {
+		return config, nil
+	}
+	if oauth.PerUser && userID == "" {
+		return nil, fmt.Errorf("%w: service %s is authorized per user, call it as a signed-in user",
+			ErrOAuthAuthorizationRequired, service.Name)
+	}
+	if m.oauthRepo == nil {
+		return nil, ErrOAuthAuthorizationRequired
+	}
+
+	token, err := m.oauthRepo.GetToken(m.ctx, service.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OAuth token: %w", err)
+	}
+	if token == nil || (token.RefreshToken == "" && !token.ExpiresAt.IsZero() && token.ExpiresAt.Before(time.Now())) {
+		return nil, fmt.Errorf("%w: service %s", ErrOAuthAuthorizationRequired, service.Name)
+	}
+	clientSecret := ""
+	if token.ClientSecret != "" {
+		if clientSecret, err = secutils.DecryptSecret(token.ClientSecret); err != nil {
+			return nil, fmt.Errorf("failed to decrypt OAuth client secret: %w", err)
+		}
+	}
+	clientID := token.ClientID
+	if oauth.ClientID != "" {
+		clientID, clientSecret = oauth.ClientID, oauth.ClientSecret
+	}
+
+	store := NewOAuthTokenStore(m.oauthRepo, service, userID, token.ClientID, clientSecret)
+	oauthConfig := oauthConfig(service, clientID, clientSecret, oauth.RedirectURI, store)
+	config.OAuth = &oauthConfig
+	return config, nil
+}
+
+// clientKey returns the key of the cached client of a service for a user
+func clientKey(serviceID, userID string) string {
+	if userID == "" {
+		return serviceID
+	}
+	return serviceID + "/" + userID
+}
+
 // createStdioClient creates a new stdio client (not cached)
 func (m *MCPManager) createStdioClient(service *types.MCPService) (MCPClient, error) {
 	// Create new client
@@ -139,12 +222,67 @@ func (m *MCPManager) initializeClient(service *types.MCPService, client MCPClien
 		if errPrefix == "" {
 			errPrefix = "failed to initialize MCP client"
 		}
+		if mcpclient.IsOAuthAuthorizationRequiredError(err) {
+			return fmt.Errorf("%s: %w: %v", errPrefix, ErrOAuthAuthorizationRequired, err)
+		}
 		return fmt.Errorf("%s: %w", errPrefix, err)
 	}
 
 	return nil
 }
 
+// cachedTools is the tool list of a service, valid until the service changes
+type cachedTools struct {
+	updatedAt time.Time
+	tools     []*types.MCPTool
+}
+
+// ListTools returns the tools of a service. Tool lists are cached until the service notifies
+// that its tool list changed, its client reconnects or the service is updated.
+func (m *MCPManager) ListTools(ctx context.Context, service *types.MCPService) ([]*types.MCPTool, error) {
+	key := clientKey(service.ID, OAuthUserID(ctx, service))
+	m.toolsMu.RLock()
+	cached, ok := m.tools[key]
+	m.toolsMu.RUnlock()
+	if ok && cached.updatedAt.Equal(service.UpdatedAt) {
+		return cached.tools, nil
+	}
+
+	client, err := m.GetOrCreateClientFor(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	// For stdio transport, ensure connection is released after listing tools
+	if service.TransportType == types.MCPTransportStdio {
+		defer func() {
+			if err := client.Disconnect(); err != nil {
+				logger.GetLogger(ctx).Warnf("Failed to disconnect stdio MCP client after listing tools: %v", err)
+			}
+		}()
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.toolsMu.Lock()
+	m.tools[key] = &cachedTools{updatedAt: service.UpdatedAt, tools: tools}
+	m.toolsMu.Unlock()
+	return tools, nil
+}
+
+// InvalidateTools drops the cached tool lists of a service, of the tenant and of every user
+func (m *MCPManager) InvalidateTools(serviceID string) {
+	m.toolsMu.Lock()
+	defer m.toolsMu.Unlock()
+	for key := range m.tools {
+		if key == serviceID || strings.HasPrefix(key, serviceID+"/") {
+			delete(m.tools, key)
+		}
+	}
+}
+
 // GetClient gets an existing client
 func (m *MCPManager) GetClient(serviceID string) (MCPClient, bool) {
 	m.clientsMu.RLock()
@@ -154,22 +292,22 @@ func (m *MCPManager) GetClient(serviceID string) (MCPClient, bool) {
 	return client, exists
 }
 
-// CloseClient closes and removes a specific client
+// CloseClient closes and removes the clients of a service, of the tenant and of every user
 func (m *MCPManager) CloseClient(serviceID string) error {
 	m.clientsMu.Lock()
 	defer m.clientsMu.Unlock()
 
-	client, exists := m.clients[serviceID]
-	if !exists {
-		return nil
-	}
-
-	if err := client.Disconnect(); err != nil {
-		logger.GetLogger(m.ctx).Errorf("Failed to disconnect MCP client %s: %v", serviceID, err)
+	m.InvalidateTools(serviceID)
+	for key, client := range m.clients {
+		if key != serviceID && !strings.HasPrefix(key, serviceID+"/") {
+			continue
+		}
+		if err := client.Disconnect(); err != nil {
+			logger.GetLogger(m.ctx).Errorf("Failed to disconnect MCP client %s: %v", key, err)
+		}
+		delete(m.clients, key)
+		logger.GetLogger(m.ctx).Infof("MCP client closed: %s", key)
 	}
-
-	delete(m.clients, serviceID)
-	logger.GetLogger(m.ctx).Infof("MCP client closed: %s", serviceID)
 	return nil
 }
 
@@ -185,6 +323,9 @@ func (m *MCPManager) CloseAll() {
 	}
 
 	m.clients = make(map[string]MCPClient)
+	m.toolsMu.Lock()
+	m.tools = make(map[string]*cachedTools)
+	m.toolsMu.Unlock()
 	logger.GetLogger(m.ctx).Info("All MCP clients closed")
 }
 
@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+	secutils "github.com/Tencent/WeKnora/internal/utils"
+	"github.com/mark3labs/mcp-go/client/transport"
+)
+
+// UserIDFromContext returns the ID of the user a request runs on behalf of, empty when authenticated by API key
+func UserIDFromContext(ctx context.Context) string {
+	if user, ok := ctx.Value(types.UserContextKey).(*types.User); ok && user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// OAuthUserID returns the user whose token is used for a service: the calling user for per-user
+// authorization, empty for the token shared by the tenant
+func OAuthUserID(ctx context.Context, service *types.MCPService) string {
+	if oauth := service.OAuthConfig(); oauth != nil && oauth.PerUser {
+		return UserIDFromContext(ctx)
+	}
+	return ""
+}
+
+// NewOAuthHandler creates the OAuth handler of a service, using the dynamically registered client
+// of the token or pending authorization when the service has no client configured
+func NewOAuthHandler(
+	service *types.MCPService,
+	clientID, clientSecret, redirectURI string,
+	store transport.TokenStore,
+) *transport.OAuthHandler {
+	oauth := service.OAuthConfig()
+	if oauth.ClientID != "" {
+		clientID, clientSecret = oauth.ClientID, oauth.ClientSecret
+	}
+	handler := transport.NewOAuthHandler(oauthConfig(service, clientID, clientSecret, redirectURI, store))
+	if service.URL != nil {
+		if baseURL, err := serviceBaseURL(*service.URL); err == nil {
+			handler.SetBaseURL(baseURL)
+		}
+	}
+	return handler
+}
+
+// oauthConfig returns the OAuth configuration of the transport of a service
+func oauthConfig(
+	service *types.MCPService,
+	clientID, clientSecret, redirectURI string,
+	store transport.TokenStore,
+) transport.OAuthConfig {
+	oauth := service.OAuthConfig()
+	return transport.OAuthConfig{
+		ClientID:              clientID,
+		ClientSecret:          clientSecret,
+		RedirectURI:           redirectURI,
+		Scopes:                oauth.Scopes,
+		TokenStore:            store,
+		AuthServerMetadataURL: oauth.AuthServerMetadataURL,
+		PKCEEnabled:           true,
+		HTTPClient:            &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// OAuthTokenStore stores the OAuth token of a service for a user in the database, encrypted.
+// Tokens refreshed by the transport are saved back through it.
+type OAuthTokenStore struct {
+	repo      interfaces.MCPOAuthRepository
+	tenantID  uint64
+	serviceID string
+	userID    string
+	// Client registered dynamically, kept with the token for refreshes
+	clientID     string
+	clientSecret string
+}
+
+// NewOAuthTokenStore creates the token store of a service for a user (empty for the tenant)
+func NewOAuthTokenStore(
+	repo interfaces.MCPOAuthRepository,
+	service *types.MCPService,
+	userID, clientID, clientSecret string,
+) *OAuthTokenStore {
+	return &OAuthTokenStore{
+		repo:         repo,
+		tenantID:     service.TenantID,
+		serviceID:    service.ID,
+		userID:       userID,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+// GetToken returns the stored token, transport.ErrNoToken when there is none
+func (s *OAuthTokenStore) GetToken(ctx context.Context) (*transport.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stored, err := s.repo.GetToken(ctx, s.serviceID, s.userID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, transport.ErrNoToken
+	}
+	accessToken, err := secutils.DecryptSecret(stored.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+	refreshToken := ""
+	if stored.RefreshToken != "" {
+		if refreshToken, err = secutils.DecryptSecret(stored.RefreshToken); err != nil {
+			return nil, fmt.Errorf("failed to decrypt refresh token: %w", err)
+		}
+	}
+	return &transport.Token{
+		AccessToken:  accessToken,
+		TokenType:    stored.TokenType,
+		RefreshToken: refreshToken,
+		Scope:        stored.Scope,
+		ExpiresAt:    stored.ExpiresAt,
+	}, nil
+}
+
+// SaveToken encrypts and stores a token
+func (s *OAuthTokenStore) SaveToken(ctx context.Context, token *transport.Token) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	accessToken, err := secutils.EncryptSecret(token.AccessToken)
+	if err != nil {
+		return err
+	}
+	refreshToken := ""
+	if token.RefreshToken != "" {
+		if refreshToken, err = secutils.EncryptSecret(token.RefreshToken); err != nil {
+			return err
+		}
+	}
+	clientSecret := ""
+	if s.clientSecret != "" {
+		if clientSecret, err = secutils.EncryptSecret(s.clientSecret); err != nil {
+			return err
+		}
+	}
+	return s.repo.SaveToken(ctx, &types.MCPOAuthToken{
+		ServiceID:    s.serviceID,
+		UserID:       s.userID,
+		TenantID:     s.tenantID,
+		ClientID:     s.clientID,
+		ClientSecret: clientSecret,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    token.TokenType,
+		Scope:        token.Scope,
+		ExpiresAt:    token.ExpiresAt,
+		UpdatedAt:    time.Now(),
+	})
+}
+
+// serviceBaseURL returns the scheme and host of the URL of a service, where OAuth metadata is discovered
+func serviceBaseURL(serviceURL string) (string, error) {
+	parsed, err := url.Parse(serviceURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("invalid service URL %q", serviceURL)
+	}
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
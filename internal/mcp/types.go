@@ -1,5 +1,10 @@
 package mcp
 
+import (
+	"fmt"
+	"strings"
+)
+
 // InitializeResult represents the result of initialize request
 type InitializeResult struct {
 	ProtocolVersion string             `json:"protocolVersion"`
@@ -64,3 +69,42 @@ type ResourceContent struct {
 	Text     string `json:"text,omitempty"`
 	Blob     string `json:"blob,omitempty"` // Base64 encoded
 }
+
+// GetPromptResult represents the result of prompts/get request
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// PromptMessage represents a message of a rendered prompt
+type PromptMessage struct {
+	Role string `json:"role"` // "user" or "assistant"
+	Text string `json:"text"`
+}
+
+// Text returns the text of the resource contents, with binary contents replaced by a placeholder
+func (r *ReadResourceResult) Text() string {
+	parts := make([]string, 0, len(r.Contents))
+	for _, content := range r.Contents {
+		if content.Text != "" {
+			parts = append(parts, content.Text)
+		} else if content.Blob != "" {
+			parts = append(parts, fmt.Sprintf("[Binary content: %s]", content.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Text returns the messages of the prompt joined as a single instruction.
+// Assistant messages are labelled, so that examples in the prompt keep their roles.
+func (r *GetPromptResult) Text() string {
+	parts := make([]string, 0, len(r.Messages))
+	for _, message := range r.Messages {
+		if message.Role == "assistant" {
+			parts = append(parts, "Assistant: "+message.Text)
+			continue
+		}
+		parts = append(parts, message.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,175 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Resource URIs
+const (
+	knowledgeBasesURI         = "weknora://knowledge-bases"
+	knowledgeBaseURITemplate  = "weknora://knowledge-bases/{id}"
+	knowledgeURITemplate      = "weknora://knowledge/{id}"
+	knowledgeBaseURIPrefix    = "weknora://knowledge-bases/"
+	knowledgeURIPrefix        = "weknora://knowledge/"
+	maxKnowledgeBaseResources = 100
+	// maxKnowledgeResourceLength bounds the content of a knowledge resource, in bytes
+	maxKnowledgeResourceLength = 200_000
+)
+
+// knowledgeSummary is a knowledge (document or FAQ import) as listed to MCP clients
+type knowledgeSummary struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	FileName    string `json:"file_name,omitempty"`
+	FileType    string `json:"file_type,omitempty"`
+	ParseStatus string `json:"parse_status"`
+	URI         string `json:"uri"`
+}
+
+// knowledgeBaseURI returns the resource URI of a knowledge base
+func knowledgeBaseURI(id string) string {
+	return knowledgeBaseURIPrefix + id
+}
+
+// knowledgeURI returns the resource URI of a knowledge
+func knowledgeURI(id string) string {
+	return knowledgeURIPrefix + id
+}
+
+// registerResources registers the resources of the server
+func (s *Server) registerResources() {
+	s.mcp.AddResource(mcp.NewResource(knowledgeBasesURI, "Knowledge bases",
+		mcp.WithResourceDescription("The knowledge bases of the tenant"),
+		mcp.WithMIMEType("application/json"),
+	), s.readKnowledgeBases)
+
+	s.mcp.AddResourceTemplate(mcp.NewResourceTemplate(knowledgeBaseURITemplate, "Knowledge base",
+		mcp.WithTemplateDescription("A knowledge base and its latest knowledge"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), s.readKnowledgeBase)
+
+	s.mcp.AddResourceTemplate(mcp.NewResourceTemplate(knowledgeURITemplate, "Knowledge",
+		mcp.WithTemplateDescription("The parsed text of a document"),
+		mcp.WithTemplateMIMEType("text/markdown"),
+	), s.readKnowledge)
+}
+
+// readKnowledgeBases reads the list of knowledge bases of the tenant
+func (s *Server) readKnowledgeBases(
+	ctx context.Context,
+	request mcp.ReadResourceRequest,
+) ([]mcp.ResourceContents, error) {
+	result, err := s.listKnowledgeBases(ctx, mcp.CallToolRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("%s", resultText(result))
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: "application/json",
+		Text:     resultText(result),
+	}}, nil
+}
+
+// readKnowledgeBase reads a knowledge base and its latest knowledge
+func (s *Server) readKnowledgeBase(
+	ctx context.Context,
+	request mcp.ReadResourceRequest,
+) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(request.Params.URI, knowledgeBaseURIPrefix)
+	kb, err := s.knowledgeBase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.knowledgeService.ListPagedKnowledgeByKnowledgeBaseID(ctx, id,
+		&types.Pagination{Page: 1, PageSize: maxKnowledgeBaseResources}, "", "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list knowledge of knowledge base %s: %w", id, err)
+	}
+	knowledges, _ := page.Data.([]*types.Knowledge)
+	summaries := make([]knowledgeSummary, 0, len(knowledges))
+	for _, knowledge := range knowledges {
+		summaries = append(summaries, knowledgeSummary{
+			ID:          knowledge.ID,
+			Title:       knowledge.Title,
+			Description: knowledge.Description,
+			FileName:    knowledge.FileName,
+			FileType:    knowledge.FileType,
+			ParseStatus: knowledge.ParseStatus,
+			URI:         knowledgeURI(knowledge.ID),
+		})
+	}
+
+	content, err := json.Marshal(map[string]interface{}{
+		"id":              kb.ID,
+		"name":            kb.Name,
+		"type":            kb.Type,
+		"description":     kb.Description,
+		"knowledge_count": page.Total,
+		"knowledge":       summaries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: "application/json",
+		Text:     string(content),
+	}}, nil
+}
+
+// readKnowledge reads the parsed text of a knowledge from its text chunks
+func (s *Server) readKnowledge(
+	ctx context.Context,
+	request mcp.ReadResourceRequest,
+) ([]mcp.ResourceContents, error) {
+	if _, err := requestTenantID(ctx); err != nil {
+		return nil, err
+	}
+	id := strings.TrimPrefix(request.Params.URI, knowledgeURIPrefix)
+	knowledge, err := s.knowledgeService.GetKnowledgeByID(ctx, id)
+	if err != nil || knowledge == nil {
+		return nil, fmt.Errorf("knowledge %s not found", id)
+	}
+	chunks, err := s.chunkService.ListChunksByKnowledgeID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks of knowledge %s: %w", id, err)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("# %s\n\n", knowledge.Title))
+	for _, chunk := range chunks {
+		if builder.Len()+len(chunk.Content) > maxKnowledgeResourceLength {
+			builder.WriteString("\n\n[Truncated, search the knowledge base for the rest of the document]\n")
+			break
+		}
+		builder.WriteString(chunk.Content)
+		builder.WriteString("\n\n")
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      request.Params.URI,
+		MIMEType: "text/markdown",
+		Text:     builder.String(),
+	}}, nil
+}
+
+// resultText returns the text content of a tool result
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+// Package mcpserver serves the knowledge bases of a tenant over the Model Context Protocol,
+// so that MCP clients such as IDE assistants can search and query them directly.
+// It is served over streamable HTTP by the API server and over stdio by the weknora-mcp command.
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/handler"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// ServerName is the name the server reports to MCP clients
+const ServerName = "weknora"
+
+// ErrInvalidAPIKey is returned when the API key does not belong to a tenant
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// Server exposes the knowledge bases of the calling tenant as MCP tools and resources.
+// Every request runs on behalf of the tenant in its context: the tenant authenticated by the
+// API middleware over HTTP, or the tenant of the API key given to ServeStdio.
+type Server struct {
+	cfg              *config.Config
+	tenantService    interfaces.TenantService
+	kbService        interfaces.KnowledgeBaseService
+	knowledgeService interfaces.KnowledgeService
+	chunkService     interfaces.ChunkService
+	sessionService   interfaces.SessionService
+
+	mcp  *server.MCPServer
+	http *server.StreamableHTTPServer
+}
+
+// NewServer creates the MCP server with its tools and resources registered
+func NewServer(
+	cfg *config.Config,
+	tenantService interfaces.TenantService,
+	kbService interfaces.KnowledgeBaseService,
+	knowledgeService interfaces.KnowledgeService,
+	chunkService interfaces.ChunkService,
+	sessionService interfaces.SessionService,
+) *Server {
+	s := &Server{
+		cfg:              cfg,
+		tenantService:    tenantService,
+		kbService:        kbService,
+		knowledgeService: knowledgeService,
+		chunkService:     chunkService,
+		sessionService:   sessionService,
+	}
+	s.mcp = server.NewMCPServer(ServerName, handler.Version,
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+		server.WithRecovery(),
+		server.WithInstructions("Search and ask questions about the knowledge bases of your WeKnora tenant. "+
+			"Call list_knowledge_bases first to find the knowledge base IDs the other tools take."),
+	)
+	s.registerTools()
+	s.registerResources()
+
+	// Stateless, so that any instance behind a load balancer can serve any request
+	s.http = server.NewStreamableHTTPServer(s.mcp, server.WithStateLess(true))
+	return s
+}
+
+// Handler returns the streamable HTTP transport of the server.
+// Requests must carry the tenant set by the authentication middleware in their context.
+func (s *Server) Handler() http.Handler {
+	return s.http
+}
+
+// ServeStdio serves a single MCP client over stdin and stdout on behalf of the tenant owning apiKey,
+// until ctx is done or stdin is closed
+func (s *Server) ServeStdio(ctx context.Context, apiKey string, stdin io.Reader, stdout io.Writer) error {
+	tenant, err := s.authenticate(ctx, apiKey)
+	if err != nil {
+		return err
+	}
+
+	stdio := server.NewStdioServer(s.mcp)
+	stdio.SetContextFunc(func(ctx context.Context) context.Context {
+		return withTenant(ctx, tenant)
+	})
+	return stdio.Listen(ctx, stdin, stdout)
+}
+
+// authenticate returns the tenant owning the API key, the same check as the API middleware
+func (s *Server) authenticate(ctx context.Context, apiKey string) (*types.Tenant, error) {
+	if apiKey == "" {
+		return nil, ErrInvalidAPIKey
+	}
+	tenantID, err := s.tenantService.ExtractTenantIDFromAPIKey(apiKey)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	tenant, err := s.tenantService.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant %d: %w", tenantID, err)
+	}
+	if tenant == nil || tenant.APIKey != apiKey {
+		return nil, ErrInvalidAPIKey
+	}
+	return tenant, nil
+}
+
+// withTenant sets the tenant in ctx the way the API middleware does
+func withTenant(ctx context.Context, tenant *types.Tenant) context.Context {
+	ctx = context.WithValue(ctx, types.TenantIDContextKey, tenant.ID)
+	return context.WithValue(ctx, types.TenantInfoContextKey, tenant)
+}
+
+// requestTenantID returns the tenant the request runs on behalf of
+func requestTenantID(ctx context.Context) (uint64, error) {
+	id, ok := ctx.Value(types.TenantIDContextKey).(uint64)
+	if !ok || id == 0 {
+		return 0, errors.New("unauthorized: missing tenant")
+	}
+	if _, ok := ctx.Value(types.TenantInfoContextKey).(*types.Tenant); !ok {
+		return 0, errors.New("unauthorized: missing tenant")
+	}
+	return id, nil
+}
+
+// knowledgeBase returns a knowledge base of the calling tenant
+func (s *Server) knowledgeBase(ctx context.Context, id string) (*types.KnowledgeBase, error) {
+	tenantID, err := requestTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kb, err := s.kbService.GetKnowledgeBaseByID(ctx, id)
+	if err != nil || kb == nil || kb.TenantID != tenantID {
+		return nil, fmt.Errorf("knowledge base %s not found", id)
+	}
+	return kb, nil
+}
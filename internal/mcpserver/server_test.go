@@ -0,0 +1,108 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/Tencent/WeKnora/internal/config"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/Tencent/WeKnora/internal/types/interfaces"
+)
+
+// fakeKnowledgeBaseService serves a fixed set of knowledge bases of all tenants
+type fakeKnowledgeBaseService struct {
+	interfaces.KnowledgeBaseService
+	kbs []*types.KnowledgeBase
+}
+
+func (f *fakeKnowledgeBaseService) ListKnowledgeBases(ctx context.Context) ([]*types.KnowledgeBase, error) {
+	tenantID := ctx.Value(types.TenantIDContextKey).(uint64)
+	var kbs []*types.KnowledgeBase
+	for _, kb := range f.kbs {
+		if kb.TenantID == tenantID {
+			kbs = append(kbs, kb)
+		}
+	}
+	return kbs, nil
+}
+
+func (f *fakeKnowledgeBaseService) GetKnowledgeBaseByID(ctx context.Context, id string) (*types.KnowledgeBase, error) {
+	for _, kb := range f.kbs {
+		if kb.ID == id {
+			return kb, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestServerTools(t *testing.T) {
+	kbService := &fakeKnowledgeBaseService{kbs: []*types.KnowledgeBase{
+		{ID: "kb-docs", TenantID: 1, Name: "Product docs", Type: types.KnowledgeBaseTypeDocument},
+		{ID: "kb-temp", TenantID: 1, Name: "Web search", Type: types.KnowledgeBaseTypeDocument, IsTemporary: true},
+		{ID: "kb-other", TenantID: 2, Name: "Other tenant", Type: types.KnowledgeBaseTypeFAQ},
+	}}
+	s := NewServer(&config.Config{Conversation: &config.ConversationConfig{}}, nil, kbService, nil, nil, nil)
+
+	c, err := client.NewInProcessClient(s.mcp)
+	if err != nil {
+		t.Fatalf("NewInProcessClient() error = %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Initialize(context.Background(), mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	tools, err := c.ListTools(context.Background(), mcp.ListToolsRequest{})
+	if err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+	if len(tools.Tools) != 4 {
+		t.Errorf("ListTools() = %d tools, want 4", len(tools.Tools))
+	}
+
+	call := func(ctx context.Context, name string, args map[string]interface{}) *mcp.CallToolResult {
+		t.Helper()
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		request.Params.Arguments = args
+		result, err := c.CallTool(ctx, request)
+		if err != nil {
+			t.Fatalf("CallTool(%s) error = %v", name, err)
+		}
+		return result
+	}
+
+	// Requests without a tenant are rejected
+	if result := call(context.Background(), ToolListKnowledgeBases, nil); !result.IsError {
+		t.Errorf("%s without tenant succeeded: %s", ToolListKnowledgeBases, resultText(result))
+	}
+
+	ctx := withTenant(context.Background(), &types.Tenant{ID: 1})
+	result := call(ctx, ToolListKnowledgeBases, nil)
+	var listed struct {
+		KnowledgeBases []knowledgeBaseSummary `json:"knowledge_bases"`
+	}
+	if err := json.Unmarshal([]byte(resultText(result)), &listed); err != nil {
+		t.Fatalf("unexpected %s result %q: %v", ToolListKnowledgeBases, resultText(result), err)
+	}
+	if len(listed.KnowledgeBases) != 1 || listed.KnowledgeBases[0].ID != "kb-docs" ||
+		listed.KnowledgeBases[0].URI != "weknora://knowledge-bases/kb-docs" {
+		t.Errorf("%s = %+v, want only kb-docs", ToolListKnowledgeBases, listed.KnowledgeBases)
+	}
+
+	// Knowledge bases of other tenants are not found
+	result = call(ctx, ToolHybridSearch, map[string]interface{}{"knowledge_base_id": "kb-other", "query": "pricing"})
+	if !result.IsError || !strings.Contains(resultText(result), "not found") {
+		t.Errorf("%s in another tenant's knowledge base = %q, want not found", ToolHybridSearch, resultText(result))
+	}
+
+	result = call(ctx, ToolFAQSearch, map[string]interface{}{"knowledge_base_id": "kb-docs", "query": "pricing"})
+	if !result.IsError || !strings.Contains(resultText(result), "not an FAQ knowledge base") {
+		t.Errorf("%s in a document knowledge base = %q, want rejected", ToolFAQSearch, resultText(result))
+	}
+}
@@ -0,0 +1,302 @@
+package mcpserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/Tencent/WeKnora/internal/event"
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Tool names
+const (
+	ToolListKnowledgeBases = "list_knowledge_bases"
+	ToolHybridSearch       = "hybrid_search"
+	ToolKnowledgeQA        = "knowledge_qa"
+	ToolFAQSearch          = "faq_search"
+)
+
+const (
+	// defaultFAQMatchCount is the number of FAQ entries returned when the client sets none
+	defaultFAQMatchCount = 5
+	// maxMatchCount bounds the results a client may request
+	maxMatchCount = 50
+	// knowledgeQATimeout bounds how long a question waits for its answer
+	knowledgeQATimeout = 5 * time.Minute
+)
+
+// knowledgeBaseSummary is a knowledge base as listed to MCP clients
+type knowledgeBaseSummary struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Description    string `json:"description"`
+	KnowledgeCount int64  `json:"knowledge_count"`
+	URI            string `json:"uri"`
+}
+
+// searchHit is a chunk matched by a search or cited by an answer
+type searchHit struct {
+	ChunkID        string  `json:"chunk_id"`
+	KnowledgeID    string  `json:"knowledge_id"`
+	KnowledgeTitle string  `json:"knowledge_title"`
+	Content        string  `json:"content"`
+	Score          float64 `json:"score"`
+}
+
+// faqHit is an FAQ entry matched by a search
+type faqHit struct {
+	ID               string   `json:"id"`
+	StandardQuestion string   `json:"standard_question"`
+	SimilarQuestions []string `json:"similar_questions,omitempty"`
+	Answers          []string `json:"answers"`
+	TagName          string   `json:"tag_name,omitempty"`
+	Score            float64  `json:"score"`
+}
+
+// registerTools registers the tools of the server
+func (s *Server) registerTools() {
+	s.mcp.AddTool(mcp.NewTool(ToolListKnowledgeBases,
+		mcp.WithDescription("List the knowledge bases of the tenant with their IDs, types and descriptions. "+
+			"Type \"document\" holds documents, type \"faq\" holds question-answer entries."),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.listKnowledgeBases)
+
+	s.mcp.AddTool(mcp.NewTool(ToolHybridSearch,
+		mcp.WithDescription("Search a knowledge base with vector and keyword retrieval combined. "+
+			"Returns the matching chunks with the documents they come from, best match first."),
+		mcp.WithString("knowledge_base_id", mcp.Required(), mcp.Description("ID of the knowledge base to search")),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Text to search for")),
+		mcp.WithNumber("match_count", mcp.Description("Maximum number of chunks to return"), mcp.Min(1), mcp.Max(maxMatchCount)),
+		mcp.WithNumber("vector_threshold", mcp.Description("Minimum vector similarity of a chunk, 0 to 1"), mcp.Min(0), mcp.Max(1)),
+		mcp.WithNumber("keyword_threshold", mcp.Description("Minimum keyword score of a chunk, 0 to 1"), mcp.Min(0), mcp.Max(1)),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.hybridSearch)
+
+	s.mcp.AddTool(mcp.NewTool(ToolKnowledgeQA,
+		mcp.WithDescription("Answer a question from the content of knowledge bases. "+
+			"Retrieves, reranks and summarizes the relevant chunks, and returns the answer with the chunks it cites."),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Question to answer")),
+		mcp.WithArray("knowledge_base_ids", mcp.Required(), mcp.WithStringItems(), mcp.MinItems(1),
+			mcp.Description("IDs of the knowledge bases to answer from")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.knowledgeQA)
+
+	s.mcp.AddTool(mcp.NewTool(ToolFAQSearch,
+		mcp.WithDescription("Search the entries of an FAQ knowledge base by question. "+
+			"Returns the matching entries with their standard question and answers, best match first."),
+		mcp.WithString("knowledge_base_id", mcp.Required(), mcp.Description("ID of the FAQ knowledge base to search")),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Question to search for")),
+		mcp.WithNumber("match_count", mcp.Description("Maximum number of entries to return"), mcp.Min(1), mcp.Max(maxMatchCount)),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), s.faqSearch)
+}
+
+// listKnowledgeBases lists the knowledge bases of the tenant
+func (s *Server) listKnowledgeBases(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if _, err := requestTenantID(ctx); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	kbs, err := s.kbService.ListKnowledgeBases(ctx)
+	if err != nil {
+		logger.Errorf(ctx, "MCP: failed to list knowledge bases: %v", err)
+		return mcp.NewToolResultErrorFromErr("failed to list knowledge bases", err), nil
+	}
+
+	summaries := make([]knowledgeBaseSummary, 0, len(kbs))
+	for _, kb := range kbs {
+		if kb.IsTemporary {
+			continue
+		}
+		summaries = append(summaries, knowledgeBaseSummary{
+			ID:             kb.ID,
+			Name:           kb.Name,
+			Type:           kb.Type,
+			Description:    kb.Description,
+			KnowledgeCount: kb.KnowledgeCount,
+			URI:            knowledgeBaseURI(kb.ID),
+		})
+	}
+	return mcp.NewToolResultJSON(map[string]interface{}{"knowledge_bases": summaries})
+}
+
+// hybridSearch searches a knowledge base, with the conversation defaults for unset parameters
+func (s *Server) hybridSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kbID, err := request.RequireString("knowledge_base_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if _, err := s.knowledgeBase(ctx, kbID); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	params := types.SearchParams{
+		QueryText:        query,
+		MatchCount:       min(request.GetInt("match_count", s.cfg.Conversation.EmbeddingTopK), maxMatchCount),
+		VectorThreshold:  request.GetFloat("vector_threshold", s.cfg.Conversation.VectorThreshold),
+		KeywordThreshold: request.GetFloat("keyword_threshold", s.cfg.Conversation.KeywordThreshold),
+	}
+	results, err := s.kbService.HybridSearch(ctx, kbID, params)
+	if err != nil {
+		logger.Errorf(ctx, "MCP: hybrid search in knowledge base %s failed: %v", kbID, err)
+		return mcp.NewToolResultErrorFromErr("search failed", err), nil
+	}
+	return mcp.NewToolResultJSON(map[string]interface{}{"results": searchHits(results)})
+}
+
+// knowledgeQA answers a question from knowledge bases with the knowledge QA pipeline
+func (s *Server) knowledgeQA(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	kbIDs, err := request.RequireStringSlice("knowledge_base_ids")
+	if err != nil || len(kbIDs) == 0 {
+		return mcp.NewToolResultError("knowledge_base_ids must list at least one knowledge base"), nil
+	}
+	for _, kbID := range kbIDs {
+		if _, err := s.knowledgeBase(ctx, kbID); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	tenantID, err := requestTenantID(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, knowledgeQATimeout)
+	defer cancel()
+
+	// The answer is streamed through the event bus, possibly after KnowledgeQA returns
+	var (
+		mu      sync.Mutex
+		answer  string
+		refs    []*types.SearchResult
+		failure string
+		once    sync.Once
+	)
+	done := make(chan struct{})
+	finish := func() { once.Do(func() { close(done) }) }
+	eventBus := event.NewEventBus()
+	eventBus.On(event.EventAgentFinalAnswer, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentFinalAnswerData); ok {
+			mu.Lock()
+			answer += data.Content
+			mu.Unlock()
+			if data.Done {
+				finish()
+			}
+		}
+		return nil
+	})
+	eventBus.On(event.EventAgentReferences, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.AgentReferencesData); ok {
+			if results, ok := data.References.([]*types.SearchResult); ok {
+				mu.Lock()
+				refs = results
+				mu.Unlock()
+			}
+		}
+		return nil
+	})
+	eventBus.On(event.EventError, func(ctx context.Context, evt event.Event) error {
+		if data, ok := evt.Data.(event.ErrorData); ok {
+			mu.Lock()
+			failure = data.Error
+			mu.Unlock()
+			finish()
+		}
+		return nil
+	})
+
+	// Questions are answered outside any stored session, without conversation history
+	session := &types.Session{ID: uuid.New().String(), TenantID: tenantID}
+	if err := s.sessionService.KnowledgeQA(ctx, session, query, nil, kbIDs, nil,
+		uuid.New().String(), "", false, eventBus, nil, nil); err != nil {
+		logger.Errorf(ctx, "MCP: knowledge QA failed: %v", err)
+		return mcp.NewToolResultErrorFromErr("failed to answer the question", err), nil
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return mcp.NewToolResultError(fmt.Sprintf("failed to answer the question: %v", ctx.Err())), nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if failure != "" {
+		return mcp.NewToolResultError("failed to answer the question: " + failure), nil
+	}
+	return mcp.NewToolResultJSON(map[string]interface{}{
+		"answer":     answer,
+		"references": searchHits(refs),
+	})
+}
+
+// faqSearch searches the entries of an FAQ knowledge base
+func (s *Server) faqSearch(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	kbID, err := request.RequireString("knowledge_base_id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	query, err := request.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	kb, err := s.knowledgeBase(ctx, kbID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if kb.Type != types.KnowledgeBaseTypeFAQ {
+		return mcp.NewToolResultError(fmt.Sprintf("knowledge base %s is not an FAQ knowledge base, use %s",
+			kbID, ToolHybridSearch)), nil
+	}
+
+	entries, err := s.knowledgeService.SearchFAQEntries(ctx, kbID, &types.FAQSearchRequest{
+		QueryText:       query,
+		VectorThreshold: s.cfg.Conversation.VectorThreshold,
+		MatchCount:      min(request.GetInt("match_count", defaultFAQMatchCount), maxMatchCount),
+	})
+	if err != nil {
+		logger.Errorf(ctx, "MCP: FAQ search in knowledge base %s failed: %v", kbID, err)
+		return mcp.NewToolResultErrorFromErr("search failed", err), nil
+	}
+
+	hits := make([]faqHit, 0, len(entries))
+	for _, entry := range entries {
+		hits = append(hits, faqHit{
+			ID:               entry.ID,
+			StandardQuestion: entry.StandardQuestion,
+			SimilarQuestions: entry.SimilarQuestions,
+			Answers:          entry.Answers,
+			TagName:          entry.TagName,
+			Score:            entry.Score,
+		})
+	}
+	return mcp.NewToolResultJSON(map[string]interface{}{"results": hits})
+}
+
+// searchHits converts search results to the hits returned to clients
+func searchHits(results []*types.SearchResult) []searchHit {
+	hits := make([]searchHit, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, searchHit{
+			ChunkID:        result.ID,
+			KnowledgeID:    result.KnowledgeID,
+			KnowledgeTitle: result.KnowledgeTitle,
+			Content:        result.Content,
+			Score:          result.Score,
+		})
+	}
+	return hits
+}
@@ -21,6 +21,8 @@ var noAuthAPI = map[string][]string{
 	"/api/v1/auth/register": {"POST"},
 	"/api/v1/auth/login":    {"POST"},
 	"/api/v1/auth/refresh":  {"POST"},
+	// Callback of MCP service authorization servers, authenticated by its state
+	"/api/v1/mcp-oauth/callback": {"GET"},
 }
 
 // Check if request is in the list of APIs that do not require authentication
@@ -130,14 +132,14 @@ func Auth(
 				// 存储用户和租户信息到上下文
 				c.Set(types.TenantIDContextKey.String(), targetTenantID)
 				c.Set(types.TenantInfoContextKey.String(), tenant)
-				c.Set("user", user)
+				c.Set(types.UserContextKey.String(), user)
 				c.Request = c.Request.WithContext(
 					context.WithValue(
 						context.WithValue(
 							context.WithValue(c.Request.Context(), types.TenantIDContextKey, targetTenantID),
 							types.TenantInfoContextKey, tenant,
 						),
-						"user", user,
+						types.UserContextKey, user,
 					),
 				)
 				c.Next()
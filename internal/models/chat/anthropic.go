@@ -0,0 +1,579 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/models/provider"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	// anthropicVersion is the version of the Messages API
+	anthropicVersion = "2023-06-01"
+	// anthropicDefaultMaxTokens is used when the options do not limit the completion, as the API requires a limit
+	anthropicDefaultMaxTokens = 4096
+	// anthropicThinkingBudget is the token budget of extended thinking, counted in the completion limit
+	anthropicThinkingBudget = 2048
+	// anthropicMaxToolNameLength is the maximum length of tool names
+	anthropicMaxToolNameLength = 64
+)
+
+// anthropicInvalidToolNameChars matches the characters tool names may not contain
+var anthropicInvalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// AnthropicChat implements chat with the native Anthropic Messages API
+type AnthropicChat struct {
+	modelName string
+	modelID   string
+	baseURL   string
+	apiKey    string
+	client    *http.Client
+}
+
+// anthropicRequest is the request body of the Messages API
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature *float64             `json:"temperature,omitempty"`
+	TopP        *float64             `json:"top_p,omitempty"`
+	Stream      bool                 `json:"stream,omitempty"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Thinking    *anthropicThinking   `json:"thinking,omitempty"`
+
+	toolNames *anthropicToolNames // Names of the tools in the request
+}
+
+// anthropicToolNames maps tool names to names accepted by the API, which only allows letters, digits,
+// underscores and hyphens, and back. Names such as mcp.<service>.<tool> are sent as mcp_<service>_<tool>.
+type anthropicToolNames struct {
+	apiNames map[string]string // API name by tool name
+	names    map[string]string // Tool name by API name
+}
+
+func newAnthropicToolNames() *anthropicToolNames {
+	return &anthropicToolNames{apiNames: make(map[string]string), names: make(map[string]string)}
+}
+
+// apiName returns the name a tool is sent as, numbering the names which collide once sanitized
+func (n *anthropicToolNames) apiName(name string) string {
+	if apiName, ok := n.apiNames[name]; ok {
+		return apiName
+	}
+	base := anthropicInvalidToolNameChars.ReplaceAllString(name, "_")
+	if base == "" {
+		base = "tool"
+	}
+	apiName := truncateToolName(base, "")
+	for i := 2; ; i++ {
+		if _, taken := n.names[apiName]; !taken {
+			break
+		}
+		apiName = truncateToolName(base, fmt.Sprintf("_%d", i))
+	}
+	n.apiNames[name] = apiName
+	n.names[apiName] = name
+	return apiName
+}
+
+// name returns the tool name of a name used by the model
+func (n *anthropicToolNames) name(apiName string) string {
+	if name, ok := n.names[apiName]; ok {
+		return name
+	}
+	return apiName
+}
+
+// truncateToolName truncates a sanitized name so that it fits the maximum length with a suffix
+func truncateToolName(name, suffix string) string {
+	if len(name)+len(suffix) > anthropicMaxToolNameLength {
+		name = name[:anthropicMaxToolNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// anthropicMessage is a message of the conversation, made of content blocks
+type anthropicMessage struct {
+	Role    string             `json:"role"`
+	Content []anthropicContent `json:"content"`
+}
+
+// anthropicContent is a content block: text, image, thinking, tool_use or tool_result
+type anthropicContent struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Thinking  string                `json:"thinking,omitempty"`
+	Signature string                `json:"signature,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+}
+
+// anthropicImageSource is the source of an image block, base64 data or a URL
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicTool is a tool definition
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// anthropicToolChoice tells the model how to use tools
+type anthropicToolChoice struct {
+	Type string `json:"type"` // "auto", "any", "none" or "tool"
+	Name string `json:"name,omitempty"`
+}
+
+// anthropicThinking enables extended thinking
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// anthropicResponse is the response body of the Messages API
+type anthropicResponse struct {
+	ID         string             `json:"id"`
+	Content    []anthropicContent `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Usage      anthropicUsage     `json:"usage"`
+}
+
+// anthropicUsage is the token usage of a request
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicError is an error returned by the API, in an error response or stream event
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicStreamEvent is a server-sent event of a streaming response
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Message      *anthropicResponse `json:"message,omitempty"`
+	Index        int                `json:"index"`
+	ContentBlock *anthropicContent  `json:"content_block,omitempty"`
+	Delta        *anthropicDelta    `json:"delta,omitempty"`
+	Usage        *anthropicUsage    `json:"usage,omitempty"`
+	Error        *anthropicError    `json:"error,omitempty"`
+}
+
+// anthropicDelta is the increment of a content block or of the message in a stream event
+type anthropicDelta struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+// NewAnthropicChat creates an Anthropic Messages API chat instance
+func NewAnthropicChat(chatConfig *ChatConfig) (*AnthropicChat, error) {
+	baseURL := strings.TrimSuffix(chatConfig.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = provider.AnthropicBaseURL
+	}
+	return &AnthropicChat{
+		modelName: chatConfig.ModelName,
+		modelID:   chatConfig.ModelID,
+		baseURL:   baseURL,
+		apiKey:    chatConfig.APIKey,
+		client:    &http.Client{},
+	}, nil
+}
+
+// convertMessages converts messages to Anthropic format. System messages are joined into the
+// system prompt, tool results become tool_result blocks of user messages, and consecutive
+// messages of the same role are merged as the API requires alternating roles.
+func (c *AnthropicChat) convertMessages(messages []Message, toolNames *anthropicToolNames) (string, []anthropicMessage) {
+	var system []string
+	result := make([]anthropicMessage, 0, len(messages))
+	appendBlocks := func(role string, blocks []anthropicContent) {
+		if len(blocks) == 0 {
+			return
+		}
+		if last := len(result) - 1; last >= 0 && result[last].Role == role {
+			result[last].Content = append(result[last].Content, blocks...)
+			return
+		}
+		result = append(result, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if msg.Content != "" {
+				system = append(system, msg.Content)
+			}
+		case "tool":
+			appendBlocks("user", []anthropicContent{{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			}})
+		case "assistant":
+			blocks := make([]anthropicContent, 0, len(msg.ToolCalls)+1)
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContent{Type: "text", Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicContent{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  toolNames.apiName(tc.Function.Name),
+					Input: input,
+				})
+			}
+			appendBlocks("assistant", blocks)
+		default:
+			blocks := make([]anthropicContent, 0, len(msg.Images)+1)
+			for _, image := range msg.Images {
+				blocks = append(blocks, anthropicContent{Type: "image", Source: anthropicImage(image)})
+			}
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContent{Type: "text", Text: msg.Content})
+			}
+			appendBlocks("user", blocks)
+		}
+	}
+	return strings.Join(system, "\n\n"), result
+}
+
+// anthropicImage converts an image URL or base64 data URL to an image source
+func anthropicImage(image string) *anthropicImageSource {
+	if strings.HasPrefix(image, "data:") {
+		header, data, found := strings.Cut(strings.TrimPrefix(image, "data:"), ",")
+		if found && strings.HasSuffix(header, ";base64") {
+			return &anthropicImageSource{
+				Type:      "base64",
+				MediaType: strings.TrimSuffix(header, ";base64"),
+				Data:      data,
+			}
+		}
+	}
+	return &anthropicImageSource{Type: "url", URL: image}
+}
+
+// buildRequest builds the request body
+func (c *AnthropicChat) buildRequest(messages []Message, opts *ChatOptions, isStream bool) *anthropicRequest {
+	// The tools offered are named first, so that their names do not depend on the conversation
+	toolNames := newAnthropicToolNames()
+	if opts != nil {
+		for _, tool := range opts.Tools {
+			toolNames.apiName(tool.Function.Name)
+		}
+	}
+	system, converted := c.convertMessages(messages, toolNames)
+	req := &anthropicRequest{
+		Model:     c.modelName,
+		System:    system,
+		Messages:  converted,
+		MaxTokens: anthropicDefaultMaxTokens,
+		Stream:    isStream,
+		toolNames: toolNames,
+	}
+	if opts == nil {
+		return req
+	}
+
+	if opts.MaxCompletionTokens > 0 {
+		req.MaxTokens = opts.MaxCompletionTokens
+	} else if opts.MaxTokens > 0 {
+		req.MaxTokens = opts.MaxTokens
+	}
+
+	// Thinking blocks must be sent back with the tool calls they led to, with their signature.
+	// Messages do not keep them, so thinking is only enabled outside of tool call loops.
+	if opts.Thinking != nil && *opts.Thinking && !hasToolCalls(messages) {
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: anthropicThinkingBudget}
+		if req.MaxTokens <= anthropicThinkingBudget {
+			req.MaxTokens += anthropicThinkingBudget
+		}
+	} else if opts.Temperature > 0 {
+		// Sampling parameters are not accepted with thinking, and recent models only take one of them
+		temperature := opts.Temperature
+		req.Temperature = &temperature
+	} else if opts.TopP > 0 {
+		topP := opts.TopP
+		req.TopP = &topP
+	}
+
+	if len(opts.Tools) > 0 {
+		req.Tools = make([]anthropicTool, 0, len(opts.Tools))
+		for _, tool := range opts.Tools {
+			schema := tool.Function.Parameters
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type":"object","properties":{}}`)
+			}
+			req.Tools = append(req.Tools, anthropicTool{
+				Name:        toolNames.apiName(tool.Function.Name),
+				Description: tool.Function.Description,
+				InputSchema: schema,
+			})
+		}
+		switch opts.ToolChoice {
+		case "":
+		case "auto", "none":
+			req.ToolChoice = &anthropicToolChoice{Type: opts.ToolChoice}
+		case "required":
+			req.ToolChoice = &anthropicToolChoice{Type: "any"}
+		default:
+			req.ToolChoice = &anthropicToolChoice{Type: "tool", Name: toolNames.apiName(opts.ToolChoice)}
+		}
+	}
+
+	// The Messages API has no response format, ask for the schema in the last message
+	if len(opts.Format) > 0 && len(req.Messages) > 0 {
+		last := &req.Messages[len(req.Messages)-1]
+		last.Content = append(last.Content, anthropicContent{
+			Type: "text",
+			Text: fmt.Sprintf("Use this JSON schema: %s", opts.Format),
+		})
+	}
+	return req
+}
+
+// hasToolCalls reports whether an assistant message of the conversation called tools
+func hasToolCalls(messages []Message) bool {
+	for _, msg := range messages {
+		if len(msg.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// send posts a request to the Messages API and returns the response once its status is OK
+func (c *AnthropicChat) send(ctx context.Context, req *anthropicRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	logger.Infof(ctx, "[LLM Request] model=%s, stream=%v, messages=%d, tools=%d",
+		c.modelName, req.Stream, len(req.Messages), len(req.Tools))
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		var errResp struct {
+			Error anthropicError `json:"error"`
+		}
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error.Message != "" {
+			return nil, fmt.Errorf("API request failed with status %d: %s: %s",
+				resp.StatusCode, errResp.Error.Type, errResp.Error.Message)
+		}
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(data))
+	}
+	return resp, nil
+}
+
+// Chat performs non-streaming chat
+func (c *AnthropicChat) Chat(ctx context.Context, messages []Message, opts *ChatOptions) (*types.ChatResponse, error) {
+	req := c.buildRequest(messages, opts, false)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var message anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	response := &types.ChatResponse{FinishReason: anthropicFinishReason(message.StopReason)}
+	response.Usage.PromptTokens = message.Usage.InputTokens
+	response.Usage.CompletionTokens = message.Usage.OutputTokens
+	response.Usage.TotalTokens = message.Usage.InputTokens + message.Usage.OutputTokens
+
+	var content strings.Builder
+	for _, block := range message.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			response.ToolCalls = append(response.ToolCalls, types.LLMToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: types.FunctionCall{
+					Name:      req.toolNames.name(block.Name),
+					Arguments: string(block.Input),
+				},
+			})
+		}
+	}
+	response.Content = content.String()
+	return response, nil
+}
+
+// ChatStream performs streaming chat. Thinking is streamed as thinking responses, separately from the answer.
+func (c *AnthropicChat) ChatStream(ctx context.Context,
+	messages []Message, opts *ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	req := c.buildRequest(messages, opts, true)
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	streamChan := make(chan types.StreamResponse)
+	go func() {
+		defer close(streamChan)
+		defer resp.Body.Close()
+
+		var toolCalls []types.LLMToolCall
+		// Index of the tool call of each tool_use content block
+		toolCallIndex := make(map[int]int)
+		sendError := func(err error) {
+			logger.GetLogger(ctx).Errorf("Anthropic stream failed: %v", err)
+			streamChan <- types.StreamResponse{
+				ResponseType: types.ResponseTypeError,
+				Content:      err.Error(),
+				Done:         true,
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var ev anthropicStreamEvent
+			if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+				sendError(fmt.Errorf("decode stream event: %w", err))
+				return
+			}
+
+			switch ev.Type {
+			case "content_block_start":
+				if ev.ContentBlock == nil || ev.ContentBlock.Type != "tool_use" {
+					continue
+				}
+				name := req.toolNames.name(ev.ContentBlock.Name)
+				toolCallIndex[ev.Index] = len(toolCalls)
+				toolCalls = append(toolCalls, types.LLMToolCall{
+					ID:       ev.ContentBlock.ID,
+					Type:     "function",
+					Function: types.FunctionCall{Name: name},
+				})
+				streamChan <- types.StreamResponse{
+					ResponseType: types.ResponseTypeToolCall,
+					Data: map[string]interface{}{
+						"tool_name":    name,
+						"tool_call_id": ev.ContentBlock.ID,
+					},
+				}
+			case "content_block_delta":
+				if ev.Delta == nil {
+					continue
+				}
+				switch ev.Delta.Type {
+				case "text_delta":
+					streamChan <- types.StreamResponse{
+						ResponseType: types.ResponseTypeAnswer,
+						Content:      ev.Delta.Text,
+					}
+				case "thinking_delta":
+					streamChan <- types.StreamResponse{
+						ResponseType: types.ResponseTypeThinking,
+						Content:      ev.Delta.Thinking,
+					}
+				case "input_json_delta":
+					if i, ok := toolCallIndex[ev.Index]; ok {
+						toolCalls[i].Function.Arguments += ev.Delta.PartialJSON
+					}
+				}
+			case "content_block_stop":
+				if i, ok := toolCallIndex[ev.Index]; ok && toolCalls[i].Function.Arguments == "" {
+					toolCalls[i].Function.Arguments = "{}"
+				}
+			case "message_stop":
+				streamChan <- types.StreamResponse{
+					ResponseType: types.ResponseTypeAnswer,
+					Done:         true,
+					ToolCalls:    toolCalls,
+				}
+				return
+			case "error":
+				if ev.Error != nil {
+					sendError(fmt.Errorf("%s: %s", ev.Error.Type, ev.Error.Message))
+				} else {
+					sendError(fmt.Errorf("unknown stream error"))
+				}
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			sendError(err)
+			return
+		}
+		sendError(fmt.Errorf("stream ended before message_stop"))
+	}()
+
+	return streamChan, nil
+}
+
+// anthropicFinishReason maps a stop reason to the OpenAI finish reason used by the other chat models
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return stopReason
+	}
+}
+
+// GetModelName gets model name
+func (c *AnthropicChat) GetModelName() string {
+	return c.modelName
+}
+
+// GetModelID gets model ID
+func (c *AnthropicChat) GetModelID() string {
+	return c.modelID
+}
@@ -0,0 +1,207 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAnthropicStub serves a recorded response and records the request it received
+func newAnthropicStub(t *testing.T, recording, contentType string, received *anthropicRequest) *httptest.Server {
+	body, err := os.ReadFile(recording)
+	require.NoError(t, err)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "sk-ant-test", r.Header.Get("x-api-key"))
+		assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(received))
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(body)
+	}))
+}
+
+func TestAnthropicChat(t *testing.T) {
+	var received anthropicRequest
+	server := newAnthropicStub(t, "testdata/anthropic_message.json", "application/json", &received)
+	defer server.Close()
+
+	chatModel, err := NewChat(&ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "anthropic",
+		BaseURL:   server.URL + "/v1",
+		ModelName: "claude-sonnet-4-5",
+		APIKey:    "sk-ant-test",
+	})
+	require.NoError(t, err)
+	require.IsType(t, &AnthropicChat{}, chatModel)
+
+	messages := []Message{
+		{Role: "system", Content: "You are a support agent."},
+		{Role: "user", Content: "What is in this screenshot?", Images: []string{"data:image/png;base64,iVBORw0KGgo="}},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionCall{Name: "ocr", Arguments: `{"page":1}`}},
+			{ID: "call_2", Type: "function", Function: FunctionCall{Name: "get_current_time"}},
+		}},
+		{Role: "tool", ToolCallID: "call_1", Name: "ocr", Content: "Refund request #42"},
+		{Role: "tool", ToolCallID: "call_2", Name: "get_current_time", Content: "2025-08-12"},
+	}
+	resp, err := chatModel.Chat(context.Background(), messages, &ChatOptions{
+		Temperature: 0.3,
+		TopP:        0.9,
+		ToolChoice:  "required",
+		Tools: []Tool{{Type: "function", Function: FunctionDef{
+			Name:       "knowledge_search",
+			Parameters: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string"}}}`),
+		}}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "You are a support agent.", received.System)
+	assert.Equal(t, anthropicDefaultMaxTokens, received.MaxTokens)
+	require.NotNil(t, received.Temperature)
+	assert.Nil(t, received.TopP)
+	assert.Equal(t, &anthropicToolChoice{Type: "any"}, received.ToolChoice)
+	require.Len(t, received.Messages, 3)
+	assert.Equal(t, "user", received.Messages[0].Role)
+	assert.Equal(t, &anthropicImageSource{Type: "base64", MediaType: "image/png", Data: "iVBORw0KGgo="},
+		received.Messages[0].Content[0].Source)
+	assert.JSONEq(t, `{"page":1}`, string(received.Messages[1].Content[0].Input))
+	assert.JSONEq(t, `{}`, string(received.Messages[1].Content[1].Input))
+	// Consecutive tool results are merged into one user message
+	assert.Equal(t, "user", received.Messages[2].Role)
+	require.Len(t, received.Messages[2].Content, 2)
+	assert.Equal(t, "call_2", received.Messages[2].Content[1].ToolUseID)
+
+	assert.Equal(t, "I'll search the knowledge base for the refund policy.", resp.Content)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	assert.Equal(t, 490, resp.Usage.TotalTokens)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "knowledge_search", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"query":"refund policy","top_k":5}`, resp.ToolCalls[0].Function.Arguments)
+}
+
+func TestAnthropicChatStream(t *testing.T) {
+	var received anthropicRequest
+	server := newAnthropicStub(t, "testdata/anthropic_stream.sse", "text/event-stream", &received)
+	defer server.Close()
+
+	chatModel, err := NewAnthropicChat(&ChatConfig{
+		BaseURL:   server.URL + "/v1",
+		ModelName: "claude-sonnet-4-5",
+		APIKey:    "sk-ant-test",
+	})
+	require.NoError(t, err)
+
+	thinking := true
+	stream, err := chatModel.ChatStream(context.Background(), []Message{
+		{Role: "user", Content: "Can I get a refund?"},
+	}, &ChatOptions{Thinking: &thinking, Temperature: 0.7, MaxTokens: 1024})
+	require.NoError(t, err)
+
+	var answer, thought string
+	var toolCallEvents []string
+	var last types.StreamResponse
+	for chunk := range stream {
+		switch chunk.ResponseType {
+		case types.ResponseTypeAnswer:
+			answer += chunk.Content
+		case types.ResponseTypeThinking:
+			thought += chunk.Content
+		case types.ResponseTypeToolCall:
+			toolCallEvents = append(toolCallEvents, chunk.Data["tool_name"].(string))
+		case types.ResponseTypeError:
+			t.Fatalf("unexpected stream error: %s", chunk.Content)
+		}
+		last = chunk
+	}
+
+	require.NotNil(t, received.Thinking)
+	assert.Equal(t, anthropicThinkingBudget, received.Thinking.BudgetTokens)
+	assert.Greater(t, received.MaxTokens, anthropicThinkingBudget)
+	assert.Nil(t, received.Temperature)
+	assert.True(t, received.Stream)
+
+	assert.Equal(t, "Let me check the policy.", answer)
+	assert.Equal(t, "The user asks about refunds, so I should search first.", thought)
+	assert.Equal(t, []string{"knowledge_search", "get_current_time"}, toolCallEvents)
+	assert.True(t, last.Done)
+	require.Len(t, last.ToolCalls, 2)
+	assert.Equal(t, "toolu_01T1x1fJ34qAmk2tNTrN7Up6", last.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"query":"refund policy"}`, last.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "{}", last.ToolCalls[1].Function.Arguments)
+}
+
+func TestAnthropicChatError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"Rate limited"}}`))
+	}))
+	defer server.Close()
+
+	chatModel, err := NewAnthropicChat(&ChatConfig{BaseURL: server.URL, ModelName: "claude-haiku-4-5"})
+	require.NoError(t, err)
+	_, err = chatModel.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+	assert.Contains(t, err.Error(), "rate_limit_error")
+}
+
+func TestAnthropicChatToolNames(t *testing.T) {
+	var received anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","stop_reason":"tool_use","content":[` +
+			`{"type":"tool_use","id":"toolu_1","name":"mcp_github_search_issues","input":{"q":"bug"}},` +
+			`{"type":"tool_use","id":"toolu_2","name":"mcp_github_search_issues_2","input":{}}]}`))
+	}))
+	defer server.Close()
+
+	chatModel, err := NewAnthropicChat(&ChatConfig{BaseURL: server.URL + "/v1", ModelName: "claude-sonnet-4-5"})
+	require.NoError(t, err)
+	resp, err := chatModel.Chat(context.Background(), []Message{
+		{Role: "user", Content: "Find the open bugs"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "toolu_0", Type: "function", Function: FunctionCall{Name: "openapi.crm.get-customer"}},
+		}},
+		{Role: "tool", ToolCallID: "toolu_0", Content: "{}"},
+	}, &ChatOptions{
+		ToolChoice: "mcp.github.search_issues",
+		Tools: []Tool{
+			{Type: "function", Function: FunctionDef{Name: "mcp.github.search_issues"}},
+			{Type: "function", Function: FunctionDef{Name: "mcp_github.search_issues"}},
+		},
+	})
+	require.NoError(t, err)
+
+	// Names are sent with the characters the API accepts, colliding names being numbered
+	require.Len(t, received.Tools, 2)
+	assert.Equal(t, "mcp_github_search_issues", received.Tools[0].Name)
+	assert.Equal(t, "mcp_github_search_issues_2", received.Tools[1].Name)
+	assert.Equal(t, &anthropicToolChoice{Type: "tool", Name: "mcp_github_search_issues"}, received.ToolChoice)
+	assert.Equal(t, "openapi_crm_get-customer", received.Messages[1].Content[0].Name)
+
+	// Tool calls carry the original names
+	require.Len(t, resp.ToolCalls, 2)
+	assert.Equal(t, "mcp.github.search_issues", resp.ToolCalls[0].Function.Name)
+	assert.Equal(t, "mcp_github.search_issues", resp.ToolCalls[1].Function.Name)
+}
+
+func TestAnthropicToolNameLength(t *testing.T) {
+	names := newAnthropicToolNames()
+	long := "mcp." + strings.Repeat("a", 70)
+	first := names.apiName(long)
+	second := names.apiName(long + "b")
+	assert.Len(t, first, anthropicMaxToolNameLength)
+	assert.Len(t, second, anthropicMaxToolNameLength)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, long+"b", names.name(second))
+}
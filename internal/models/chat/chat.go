@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Tencent/WeKnora/internal/models/provider"
 	"github.com/Tencent/WeKnora/internal/models/utils/ollama"
 	"github.com/Tencent/WeKnora/internal/runtime"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -46,6 +47,7 @@ type Message struct {
 	Name       string     `json:"name,omitempty"`         // Function/tool name (for tool role)
 	ToolCallID string     `json:"tool_call_id,omitempty"` // Tool call ID (for tool role)
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Tool calls (for assistant role)
+	Images     []string   `json:"images,omitempty"`       // Image URLs or base64 data URLs (for user role)
 }
 
 // ToolCall represents a tool call in a message
@@ -100,7 +102,18 @@ func NewChat(config *ChatConfig) (Chat, error) {
 		}
 		return chat, nil
 	case string(types.ModelSourceRemote):
-		return NewRemoteAPIChat(config)
+		providerName := provider.ProviderName(config.Provider)
+		if providerName == "" {
+			providerName = provider.DetectProvider(config.BaseURL)
+		}
+		switch providerName {
+		case provider.ProviderAnthropic:
+			return NewAnthropicChat(config)
+		case provider.ProviderMock:
+			return NewMockChat(config)
+		default:
+			return NewRemoteAPIChat(config)
+		}
 	default:
 		return nil, fmt.Errorf("unsupported chat model source: %s", config.Source)
 	}
@@ -0,0 +1,305 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// MockFixture scripts the responses of a mock chat model
+type MockFixture struct {
+	// Responses are tried in order, the first one matching the last message answers
+	Responses []*MockResponse `json:"responses"`
+	// Default answers when no response matches; the last message is echoed without it
+	Default *MockResponse `json:"default,omitempty"`
+}
+
+// MockResponse is a scripted response
+type MockResponse struct {
+	// Match is a regular expression matched against the content of the last message
+	Match string `json:"match"`
+	// Role restricts the response to last messages of this role (user, tool, ...)
+	Role string `json:"role,omitempty"`
+	// Content of the answer, which may reference submatches as $1 or ${name}
+	Content string `json:"content,omitempty"`
+	// Thinking streamed before the answer
+	Thinking string `json:"thinking,omitempty"`
+	// Tool calls requested by the model
+	ToolCalls []MockToolCall `json:"tool_calls,omitempty"`
+	// Error makes the call fail with this message
+	Error string `json:"error,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// MockToolCall is a scripted tool call
+type MockToolCall struct {
+	Name string `json:"name"`
+	// Arguments object, whose string values may reference submatches like the content
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// ErrInvalidMockFixture is returned for fixture paths outside of the fixture directory or which cannot be read
+var ErrInvalidMockFixture = errors.New("invalid mock fixture")
+
+// mockFixtureDir returns the directory fixtures of mock chat models are read from,
+// the working directory unless MOCK_FIXTURE_DIR is set
+func mockFixtureDir() string {
+	if dir := os.Getenv("MOCK_FIXTURE_DIR"); dir != "" {
+		return dir
+	}
+	return "."
+}
+
+// LoadMockFixture reads a fixture file
+func LoadMockFixture(path string) (*MockFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mock fixture: %w", err)
+	}
+	var fixture MockFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("parse mock fixture: %w", err)
+	}
+	return &fixture, nil
+}
+
+// loadMockFixtureFromDir reads a fixture file by its path relative to the fixture directory.
+// The path is configured by users, so it may not leave the directory and is not reported in errors.
+func loadMockFixtureFromDir(path string) (*MockFixture, error) {
+	path = filepath.Clean(path)
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("%w: path must be relative to the fixture directory", ErrInvalidMockFixture)
+	}
+	root, err := os.OpenRoot(mockFixtureDir())
+	if err != nil {
+		return nil, fmt.Errorf("%w: fixture directory is not available", ErrInvalidMockFixture)
+	}
+	defer root.Close()
+	file, err := root.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: file cannot be read", ErrInvalidMockFixture)
+	}
+	defer file.Close()
+	var fixture MockFixture
+	if err := json.NewDecoder(file).Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("%w: file is not a valid fixture", ErrInvalidMockFixture)
+	}
+	return &fixture, nil
+}
+
+// compile compiles the patterns of the responses
+func (f *MockFixture) compile() error {
+	for i, response := range f.Responses {
+		pattern, err := regexp.Compile(response.Match)
+		if err != nil {
+			return fmt.Errorf("response %d: invalid match: %w", i, err)
+		}
+		response.pattern = pattern
+	}
+	return nil
+}
+
+// MockChat implements deterministic offline chat, answering from a fixture
+type MockChat struct {
+	modelName string
+	modelID   string
+	fixture   *MockFixture
+}
+
+// NewMockChat creates a mock chat model. The base URL is the path of the fixture file relative to
+// the fixture directory (optionally prefixed with file://); without one, the last message is echoed.
+func NewMockChat(config *ChatConfig) (*MockChat, error) {
+	fixture := &MockFixture{}
+	if path := strings.TrimPrefix(config.BaseURL, "file://"); path != "" {
+		var err error
+		if fixture, err = loadMockFixtureFromDir(path); err != nil {
+			return nil, err
+		}
+	}
+	return NewMockChatWithFixture(config, fixture)
+}
+
+// NewMockChatWithFixture creates a mock chat model answering from a fixture
+func NewMockChatWithFixture(config *ChatConfig, fixture *MockFixture) (*MockChat, error) {
+	if err := fixture.compile(); err != nil {
+		return nil, fmt.Errorf("mock fixture: %w", err)
+	}
+	return &MockChat{
+		modelName: config.ModelName,
+		modelID:   config.ModelID,
+		fixture:   fixture,
+	}, nil
+}
+
+// respond builds the scripted response to messages
+func (c *MockChat) respond(messages []Message) (*types.ChatResponse, string, error) {
+	var last Message
+	if len(messages) > 0 {
+		last = messages[len(messages)-1]
+	}
+
+	script, submatches := c.match(last)
+	if script == nil {
+		return &types.ChatResponse{Content: last.Content, FinishReason: "stop"}, "", nil
+	}
+	if script.Error != "" {
+		return nil, "", errors.New(script.Error)
+	}
+
+	expand := func(template string) string {
+		if script.pattern == nil {
+			return template
+		}
+		return string(script.pattern.ExpandString(nil, template, last.Content, submatches))
+	}
+	resp := &types.ChatResponse{Content: expand(script.Content), FinishReason: "stop"}
+	for i, call := range script.ToolCalls {
+		arguments, err := expandMockArguments(call.Arguments, expand)
+		if err != nil {
+			return nil, "", err
+		}
+		resp.ToolCalls = append(resp.ToolCalls, types.LLMToolCall{
+			// IDs are unique within a conversation as each round adds messages
+			ID:   fmt.Sprintf("call_%d_%d", len(messages), i),
+			Type: "function",
+			Function: types.FunctionCall{
+				Name:      call.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+	if len(resp.ToolCalls) > 0 {
+		resp.FinishReason = "tool_calls"
+	}
+	return resp, expand(script.Thinking), nil
+}
+
+// match returns the first response matching a message and the submatch indexes
+func (c *MockChat) match(message Message) (*MockResponse, []int) {
+	for _, response := range c.fixture.Responses {
+		if response.Role != "" && response.Role != message.Role {
+			continue
+		}
+		if submatches := response.pattern.FindStringSubmatchIndex(message.Content); submatches != nil {
+			return response, submatches
+		}
+	}
+	return c.fixture.Default, nil
+}
+
+// expandMockArguments expands the string values of tool call arguments
+func expandMockArguments(arguments json.RawMessage, expand func(string) string) (string, error) {
+	if len(arguments) == 0 {
+		return "{}", nil
+	}
+	var value any
+	if err := json.Unmarshal(arguments, &value); err != nil {
+		return "", fmt.Errorf("invalid arguments of mock tool call: %w", err)
+	}
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return expand(v)
+		case map[string]any:
+			for key, item := range v {
+				v[key] = walk(item)
+			}
+		case []any:
+			for i, item := range v {
+				v[i] = walk(item)
+			}
+		}
+		return v
+	}
+	expanded, err := json.Marshal(walk(value))
+	if err != nil {
+		return "", err
+	}
+	return string(expanded), nil
+}
+
+// Chat returns the scripted response to the last message
+func (c *MockChat) Chat(ctx context.Context, messages []Message, opts *ChatOptions) (*types.ChatResponse, error) {
+	resp, _, err := c.respond(messages)
+	if err != nil {
+		return nil, err
+	}
+	tok := tokenizer.ForModel(c.modelName)
+	for _, msg := range messages {
+		resp.Usage.PromptTokens += tokenizer.CountAll(tok, msg.Role, msg.Content)
+	}
+	resp.Usage.CompletionTokens = tok.Count(resp.Content)
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	return resp, nil
+}
+
+// ChatStream streams the scripted response word by word, the way remote models stream:
+// thinking, then the answer, then a tool call notice per tool call and a final response
+// carrying all tool calls
+func (c *MockChat) ChatStream(ctx context.Context,
+	messages []Message, opts *ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	resp, thinking, err := c.respond(messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []types.StreamResponse
+	for _, word := range strings.SplitAfter(thinking, " ") {
+		if word != "" {
+			chunks = append(chunks, types.StreamResponse{ResponseType: types.ResponseTypeThinking, Content: word})
+		}
+	}
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if word != "" {
+			chunks = append(chunks, types.StreamResponse{ResponseType: types.ResponseTypeAnswer, Content: word})
+		}
+	}
+	for _, tc := range resp.ToolCalls {
+		chunks = append(chunks, types.StreamResponse{
+			ResponseType: types.ResponseTypeToolCall,
+			Data: map[string]interface{}{
+				"tool_name":    tc.Function.Name,
+				"tool_call_id": tc.ID,
+			},
+		})
+	}
+	chunks = append(chunks, types.StreamResponse{
+		ResponseType: types.ResponseTypeAnswer,
+		Done:         true,
+		ToolCalls:    resp.ToolCalls,
+	})
+
+	streamChan := make(chan types.StreamResponse)
+	go func() {
+		defer close(streamChan)
+		for _, chunk := range chunks {
+			select {
+			case streamChan <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return streamChan, nil
+}
+
+// GetModelName returns the model name
+func (c *MockChat) GetModelName() string {
+	return c.modelName
+}
+
+// GetModelID returns the model ID
+func (c *MockChat) GetModelID() string {
+	return c.modelID
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockChatForTest(t *testing.T, baseURL string) Chat {
+	chatModel, err := NewChat(&ChatConfig{
+		Source:    types.ModelSourceRemote,
+		Provider:  "mock",
+		BaseURL:   baseURL,
+		ModelName: "mock-chat",
+		ModelID:   "model-1",
+	})
+	require.NoError(t, err)
+	require.IsType(t, &MockChat{}, chatModel)
+	return chatModel
+}
+
+func TestMockChat(t *testing.T) {
+	chatModel := newMockChatForTest(t, "file://testdata/mock_chat.json")
+	ctx := context.Background()
+
+	resp, err := chatModel.Chat(ctx, []Message{
+		{Role: "system", Content: "You are a support agent."},
+		{Role: "user", Content: "How long do refunds take?"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "call_2_0", resp.ToolCalls[0].ID)
+	assert.Equal(t, "knowledge_search", resp.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"queries":["refunds duration"],"top_k":3}`, resp.ToolCalls[0].Function.Arguments)
+
+	resp, err = chatModel.Chat(ctx, []Message{
+		{Role: "tool", Content: "Found: refunds take 5 days."},
+	}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, "According to the knowledge base, refunds take 5 days.", resp.Content)
+	assert.Positive(t, resp.Usage.TotalTokens)
+
+	// The role restricts the responses: a user message falls through to the default
+	resp, err = chatModel.Chat(ctx, []Message{{Role: "user", Content: "Found: nothing"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "I don't know.", resp.Content)
+
+	_, err = chatModel.Chat(ctx, []Message{{Role: "user", Content: "Is there an outage?"}}, nil)
+	require.EqualError(t, err, "status 503: service unavailable")
+	_, err = chatModel.ChatStream(ctx, []Message{{Role: "user", Content: "Is there an outage?"}}, nil)
+	require.Error(t, err)
+}
+
+func TestMockChatEchoesWithoutFixture(t *testing.T) {
+	chatModel := newMockChatForTest(t, "")
+
+	resp, err := chatModel.Chat(context.Background(), []Message{{Role: "user", Content: "ping"}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", resp.Content)
+}
+
+func TestMockChatInvalidFixture(t *testing.T) {
+	_, err := NewMockChatWithFixture(&ChatConfig{}, &MockFixture{Responses: []*MockResponse{{Match: "("}}})
+	require.Error(t, err)
+
+	_, err = NewChat(&ChatConfig{Source: types.ModelSourceRemote, Provider: "mock", BaseURL: "testdata/missing.json"})
+	require.ErrorIs(t, err, ErrInvalidMockFixture)
+	assert.NotContains(t, err.Error(), "missing.json")
+}
+
+func TestMockChatFixtureDir(t *testing.T) {
+	// Fixtures may not be read from outside of the fixture directory
+	for _, path := range []string{"/etc/passwd", "file:///etc/passwd", "../chat/testdata/mock_chat.json", "testdata/../../x"} {
+		_, err := NewChat(&ChatConfig{Source: types.ModelSourceRemote, Provider: "mock", BaseURL: path})
+		require.ErrorIs(t, err, ErrInvalidMockFixture, path)
+		assert.NotContains(t, err.Error(), "passwd", path)
+	}
+
+	// Fixtures are read relative to the configured directory
+	t.Setenv("MOCK_FIXTURE_DIR", "testdata")
+	newMockChatForTest(t, "mock_chat.json")
+	_, err := NewChat(&ChatConfig{Source: types.ModelSourceRemote, Provider: "mock", BaseURL: "testdata/mock_chat.json"})
+	require.ErrorIs(t, err, ErrInvalidMockFixture)
+}
+
+func TestMockChatStream(t *testing.T) {
+	chatModel := newMockChatForTest(t, "testdata/mock_chat.json")
+
+	stream, err := chatModel.ChatStream(context.Background(), []Message{
+		{Role: "user", Content: "How long do refunds take?"},
+	}, nil)
+	require.NoError(t, err)
+	var responses []types.StreamResponse
+	for response := range stream {
+		responses = append(responses, response)
+	}
+
+	var thinking string
+	for _, response := range responses {
+		if response.ResponseType == types.ResponseTypeThinking {
+			thinking += response.Content
+		}
+	}
+	assert.Equal(t, "The user asks about refunds.", thinking)
+
+	require.GreaterOrEqual(t, len(responses), 2)
+	notice := responses[len(responses)-2]
+	assert.Equal(t, types.ResponseTypeToolCall, notice.ResponseType)
+	assert.Equal(t, "knowledge_search", notice.Data["tool_name"])
+	last := responses[len(responses)-1]
+	assert.True(t, last.Done)
+	require.Len(t, last.ToolCalls, 1)
+	assert.Equal(t, notice.Data["tool_call_id"], last.ToolCalls[0].ID)
+}
@@ -2,9 +2,11 @@ package chat
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/Tencent/WeKnora/internal/logger"
 	"github.com/Tencent/WeKnora/internal/models/utils/ollama"
@@ -29,7 +31,7 @@ func NewOllamaChat(config *ChatConfig, ollamaService *ollama.OllamaService) (*Ol
 }
 
 // convertMessages converts message format to Ollama API format
-func (c *OllamaChat) convertMessages(messages []Message) []ollamaapi.Message {
+func (c *OllamaChat) convertMessages(ctx context.Context, messages []Message) []ollamaapi.Message {
 	ollamaMessages := make([]ollamaapi.Message, 0, len(messages))
 	for _, msg := range messages {
 		msgOllama := ollamaapi.Message{
@@ -37,6 +39,14 @@ func (c *OllamaChat) convertMessages(messages []Message) []ollamaapi.Message {
 			Content:   msg.Content,
 			ToolCalls: c.toolCallFrom(msg.ToolCalls),
 		}
+		for _, image := range msg.Images {
+			data, err := ollamaImage(image)
+			if err != nil {
+				logger.GetLogger(ctx).Warnf("Skipping image for model %s: %v", c.modelName, err)
+				continue
+			}
+			msgOllama.Images = append(msgOllama.Images, data)
+		}
 		if msg.Role == "tool" {
 			msgOllama.ToolName = msg.Name
 		}
@@ -45,15 +55,33 @@ func (c *OllamaChat) convertMessages(messages []Message) []ollamaapi.Message {
 	return ollamaMessages
 }
 
+// ollamaImage decodes a base64 data URL; Ollama only accepts raw image bytes
+func ollamaImage(image string) (ollamaapi.ImageData, error) {
+	if !strings.HasPrefix(image, "data:") {
+		return nil, fmt.Errorf("ollama only accepts inline images, not URLs")
+	}
+	_, data, found := strings.Cut(image, ",")
+	if !found {
+		return nil, fmt.Errorf("invalid data URL")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image data: %w", err)
+	}
+	return decoded, nil
+}
+
 // buildChatRequest builds chat request parameters
-func (c *OllamaChat) buildChatRequest(messages []Message, opts *ChatOptions, isStream bool) *ollamaapi.ChatRequest {
+func (c *OllamaChat) buildChatRequest(
+	ctx context.Context, messages []Message, opts *ChatOptions, isStream bool,
+) *ollamaapi.ChatRequest {
 	// Set streaming flag
 	streamFlag := isStream
 
 	// Build request parameters
 	chatReq := &ollamaapi.ChatRequest{
 		Model:    c.modelName,
-		Messages: c.convertMessages(messages),
+		Messages: c.convertMessages(ctx, messages),
 		Stream:   &streamFlag,
 		Options:  make(map[string]interface{}),
 	}
@@ -93,7 +121,7 @@ func (c *OllamaChat) Chat(ctx context.Context, messages []Message, opts *ChatOpt
 	}
 
 	// Build request parameters
-	chatReq := c.buildChatRequest(messages, opts, false)
+	chatReq := c.buildChatRequest(ctx, messages, opts, false)
 
 	// Log request
 	logger.GetLogger(ctx).Infof("Sending chat request to model %s", c.modelName)
@@ -147,7 +175,7 @@ func (c *OllamaChat) ChatStream(
 	}
 
 	// Build request parameters
-	chatReq := c.buildChatRequest(messages, opts, true)
+	chatReq := c.buildChatRequest(ctx, messages, opts, true)
 
 	// Log request
 	logger.GetLogger(ctx).Infof("Sending streaming chat request to model %s", c.modelName)
@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaConvertMessagesImages(t *testing.T) {
+	c := &OllamaChat{modelName: "llava"}
+
+	messages := c.convertMessages(context.Background(), []Message{
+		{Role: "system", Content: "You are helpful"},
+		{
+			Role:    "user",
+			Content: "What does the screenshot say?",
+			Images: []string{
+				"data:image/png;base64,aGVsbG8=",
+				"https://example.com/screenshot.png", // Ollama only reads inline images
+				"data:image/png;base64,!!!",
+			},
+		},
+	})
+
+	require.Len(t, messages, 2)
+	assert.Empty(t, messages[0].Images)
+	require.Len(t, messages[1].Images, 1)
+	assert.Equal(t, "hello", string(messages[1].Images[0]))
+}
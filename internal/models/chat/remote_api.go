@@ -62,7 +62,22 @@ func (c *RemoteAPIChat) convertMessages(messages []Message) []openai.ChatComplet
 		}
 
 		// Handle content: for assistant role, content may be empty (when there are tool_calls)
-		if msg.Content != "" {
+		if len(msg.Images) > 0 {
+			// Images are sent as content parts, which exclude the plain content
+			openaiMsg.MultiContent = make([]openai.ChatMessagePart, 0, len(msg.Images)+1)
+			for _, image := range msg.Images {
+				openaiMsg.MultiContent = append(openaiMsg.MultiContent, openai.ChatMessagePart{
+					Type:     openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{URL: image},
+				})
+			}
+			if msg.Content != "" {
+				openaiMsg.MultiContent = append(openaiMsg.MultiContent, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeText,
+					Text: msg.Content,
+				})
+			}
+		} else if msg.Content != "" {
 			openaiMsg.Content = msg.Content
 		}
 
@@ -98,6 +113,11 @@ func (c *RemoteAPIChat) isAliyunQwen3Model() bool {
 	return c.provider == provider.ProviderAliyun && provider.IsQwen3Model(c.modelName)
 }
 
+// supportsJSONSchema reports whether the provider accepts response_format of type json_schema
+func (c *RemoteAPIChat) supportsJSONSchema() bool {
+	return c.provider == provider.ProviderOpenAI || c.provider == provider.ProviderOpenRouter
+}
+
 // isDeepSeekModel checks if it's a DeepSeek model
 func (c *RemoteAPIChat) isDeepSeekModel() bool {
 	return provider.IsDeepSeekModel(c.modelName)
@@ -204,10 +224,29 @@ func (c *RemoteAPIChat) buildChatCompletionRequest(messages []Message,
 		}
 
 		if len(opts.Format) > 0 {
-			req.ResponseFormat = &openai.ChatCompletionResponseFormat{
-				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			if c.supportsJSONSchema() {
+				// Native structured output: the provider enforces the schema
+				req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+					Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+					JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+						Name:   "response",
+						Schema: opts.Format,
+					},
+				}
+			} else {
+				req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+					Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+				}
+				instruction := fmt.Sprintf("\nUse this JSON schema: %s", opts.Format)
+				if last := &req.Messages[len(req.Messages)-1]; len(last.MultiContent) > 0 {
+					last.MultiContent = append(last.MultiContent, openai.ChatMessagePart{
+						Type: openai.ChatMessagePartTypeText,
+						Text: instruction,
+					})
+				} else {
+					last.Content += instruction
+				}
 			}
-			req.Messages[len(req.Messages)-1].Content += fmt.Sprintf("\nUse this JSON schema: %s", opts.Format)
 		}
 	}
 
@@ -70,6 +70,8 @@ func NewEmbedder(config Config) (Embedder, error) {
 
 		// Route to provider-specific embedders
 		switch providerName {
+		case provider.ProviderMock:
+			return NewMockEmbedder(config.ModelName, config.Dimensions, config.ModelID), nil
 		case provider.ProviderAliyun:
 			// Check if it's a multimodal embedding model
 			// Multimodal models: tongyi-embedding-vision-*, multimodal-embedding-*
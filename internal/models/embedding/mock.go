@@ -0,0 +1,93 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	"github.com/Tencent/WeKnora/internal/models/utils"
+)
+
+// defaultMockDimensions is the vector dimension of mock embedders configured without one
+const defaultMockDimensions = 256
+
+// MockEmbedder implements deterministic offline text vectorization: every term of the text
+// is hashed into one dimension of the vector, so texts sharing terms have similar vectors
+type MockEmbedder struct {
+	modelName  string
+	modelID    string
+	dimensions int
+}
+
+// NewMockEmbedder creates a new mock embedder
+func NewMockEmbedder(modelName string, dimensions int, modelID string) *MockEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultMockDimensions
+	}
+	return &MockEmbedder{
+		modelName:  modelName,
+		modelID:    modelID,
+		dimensions: dimensions,
+	}
+}
+
+// Embed converts text to a normalized vector of hashed term counts
+func (e *MockEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dimensions)
+	for _, term := range utils.Terms(text) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(term))
+		sum := h.Sum64()
+		// The top bit signs the term so that unrelated terms cancel out rather than add up
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		vector[sum%uint64(e.dimensions)] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / norm)
+		}
+	}
+	return vector, nil
+}
+
+// BatchEmbed converts multiple texts to vectors in batch
+func (e *MockEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector, err := e.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
+
+// BatchEmbedWithPool embeds texts with the given model directly, as mock embedding needs no pool
+func (e *MockEmbedder) BatchEmbedWithPool(ctx context.Context, model Embedder, texts []string) ([][]float32, error) {
+	return model.BatchEmbed(ctx, texts)
+}
+
+// GetModelName returns the model name
+func (e *MockEmbedder) GetModelName() string {
+	return e.modelName
+}
+
+// GetDimensions returns the vector dimensions
+func (e *MockEmbedder) GetDimensions() int {
+	return e.dimensions
+}
+
+// GetModelID returns the model ID
+func (e *MockEmbedder) GetModelID() string {
+	return e.modelID
+}
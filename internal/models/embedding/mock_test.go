@@ -0,0 +1,56 @@
+package embedding
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestMockEmbedder(t *testing.T) {
+	embedder, err := NewEmbedder(Config{
+		Source:     types.ModelSourceRemote,
+		Provider:   "mock",
+		ModelName:  "mock-embedding",
+		ModelID:    "embedding-1",
+		Dimensions: 64,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 64, embedder.GetDimensions())
+	ctx := context.Background()
+
+	vectors, err := embedder.BatchEmbedWithPool(ctx, embedder, []string{
+		"How long do refunds take?",
+		"Refunds take 5 days",
+		"Shipping is free over $50",
+		"how long do REFUNDS take",
+	})
+	require.NoError(t, err)
+	require.Len(t, vectors, 4)
+	for _, vector := range vectors {
+		require.Len(t, vector, 64)
+		assert.InDelta(t, 1.0, cosine(vector, vector), 1e-5)
+	}
+	// Embeddings are deterministic and case insensitive
+	assert.Equal(t, vectors[0], vectors[3])
+	assert.Greater(t, cosine(vectors[0], vectors[1]), cosine(vectors[0], vectors[2]))
+
+	empty, err := embedder.Embed(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, empty, 64)
+	assert.Zero(t, cosine(empty, empty))
+
+	embedder, err = NewEmbedder(Config{Source: types.ModelSourceRemote, Provider: "mock"})
+	require.NoError(t, err)
+	assert.Equal(t, defaultMockDimensions, embedder.GetDimensions())
+}
@@ -0,0 +1,239 @@
+// Package metering records the token usage of model calls and refuses calls once the token quota
+// of the tenant is used.
+package metering
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// Recorder checks token quotas and records model calls
+type Recorder interface {
+	// CheckQuota returns an error when the tenant in the context used its token quota
+	CheckQuota(ctx context.Context) error
+	// Record records a model call for the tenant in the context
+	Record(ctx context.Context, usage *types.ModelUsage)
+}
+
+// meter describes the model whose calls are recorded
+type meter struct {
+	recorder Recorder
+	model    *types.Model
+}
+
+// estimateTokens estimates the tokens of texts with the tokenizer of the model
+func (m *meter) estimateTokens(texts ...string) int {
+	return tokenizer.CountAll(tokenizer.ForModel(m.model.Name), texts...)
+}
+
+// estimateMessageTokens estimates the tokens of chat messages
+func (m *meter) estimateMessageTokens(messages []chat.Message) int {
+	texts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		texts = append(texts, msg.Role, msg.Content)
+		for _, tc := range msg.ToolCalls {
+			texts = append(texts, tc.Function.Name, tc.Function.Arguments)
+		}
+	}
+	return m.estimateTokens(texts...)
+}
+
+// newUsage creates the usage record of a call which started at a time
+func (m *meter) newUsage(start time.Time, err error) *types.ModelUsage {
+	return &types.ModelUsage{
+		ModelID:   m.model.ID,
+		ModelName: m.model.Name,
+		ModelType: m.model.Type,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Success:   err == nil,
+	}
+}
+
+// estimate sets the tokens of a usage record from estimates
+func estimate(usage *types.ModelUsage, promptTokens, completionTokens int) {
+	usage.PromptTokens = promptTokens
+	usage.CompletionTokens = completionTokens
+	usage.TotalTokens = promptTokens + completionTokens
+	usage.Estimated = true
+}
+
+// Chat is a chat model recording its calls
+type Chat struct {
+	meter
+	chat chat.Chat
+}
+
+// NewChat creates a chat model recording the calls of a model
+func NewChat(model chat.Chat, config *types.Model, recorder Recorder) *Chat {
+	return &Chat{meter: meter{recorder: recorder, model: config}, chat: model}
+}
+
+// Chat performs non-streaming chat, with the token usage reported by the provider when available
+func (c *Chat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	if err := c.recorder.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := c.chat.Chat(ctx, messages, opts)
+	usage := c.newUsage(start, err)
+	switch {
+	case resp != nil && resp.Usage.TotalTokens > 0:
+		usage.PromptTokens = resp.Usage.PromptTokens
+		usage.CompletionTokens = resp.Usage.CompletionTokens
+		usage.TotalTokens = resp.Usage.TotalTokens
+	case resp != nil:
+		estimate(usage, c.estimateMessageTokens(messages), c.estimateTokens(resp.Content))
+	}
+	c.recorder.Record(ctx, usage)
+	return resp, err
+}
+
+// ChatStream performs streaming chat. Streams report no token usage, which is estimated from the text.
+func (c *Chat) ChatStream(ctx context.Context,
+	messages []chat.Message, opts *chat.ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	if err := c.recorder.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	stream, err := c.chat.ChatStream(ctx, messages, opts)
+	if err != nil {
+		c.recorder.Record(ctx, c.newUsage(start, err))
+		return nil, err
+	}
+
+	metered := make(chan types.StreamResponse)
+	go func() {
+		defer close(metered)
+		var texts []string
+		var streamErr error
+		for response := range stream {
+			if response.ResponseType == types.ResponseTypeError {
+				streamErr = errors.New(response.Content)
+			} else {
+				texts = append(texts, response.Content)
+			}
+			// The last response carries the complete tool calls
+			if response.Done {
+				for _, tc := range response.ToolCalls {
+					texts = append(texts, tc.Function.Name, tc.Function.Arguments)
+				}
+			}
+			metered <- response
+		}
+		usage := c.newUsage(start, streamErr)
+		estimate(usage, c.estimateMessageTokens(messages), c.estimateTokens(texts...))
+		c.recorder.Record(ctx, usage)
+	}()
+	return metered, nil
+}
+
+// GetModelName returns the model name
+func (c *Chat) GetModelName() string {
+	return c.chat.GetModelName()
+}
+
+// GetModelID returns the model ID
+func (c *Chat) GetModelID() string {
+	return c.chat.GetModelID()
+}
+
+// Embedder is an embedding model recording its calls
+type Embedder struct {
+	meter
+	embedder embedding.Embedder
+}
+
+// NewEmbedder creates an embedding model recording the calls of a model
+func NewEmbedder(model embedding.Embedder, config *types.Model, recorder Recorder) *Embedder {
+	return &Embedder{meter: meter{recorder: recorder, model: config}, embedder: model}
+}
+
+// Embed converts text to vector. Embedding APIs report no token usage, which is estimated from the text.
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if err := e.recorder.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	vector, err := e.embedder.Embed(ctx, text)
+	usage := e.newUsage(start, err)
+	estimate(usage, e.estimateTokens(text), 0)
+	e.recorder.Record(ctx, usage)
+	return vector, err
+}
+
+// BatchEmbed converts multiple texts to vectors in batch
+func (e *Embedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := e.recorder.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	vectors, err := e.embedder.BatchEmbed(ctx, texts)
+	usage := e.newUsage(start, err)
+	estimate(usage, e.estimateTokens(texts...), 0)
+	e.recorder.Record(ctx, usage)
+	return vectors, err
+}
+
+// BatchEmbedWithPool embeds texts in batches with the pool of the model, each batch being recorded
+// when the model passed is this embedder
+func (e *Embedder) BatchEmbedWithPool(ctx context.Context, model embedding.Embedder, texts []string) ([][]float32, error) {
+	return e.embedder.BatchEmbedWithPool(ctx, model, texts)
+}
+
+// GetModelName returns the model name
+func (e *Embedder) GetModelName() string {
+	return e.embedder.GetModelName()
+}
+
+// GetDimensions returns the vector dimensions
+func (e *Embedder) GetDimensions() int {
+	return e.embedder.GetDimensions()
+}
+
+// GetModelID returns the model ID
+func (e *Embedder) GetModelID() string {
+	return e.embedder.GetModelID()
+}
+
+// Reranker is a rerank model recording its calls
+type Reranker struct {
+	meter
+	reranker rerank.Reranker
+}
+
+// NewReranker creates a rerank model recording the calls of a model
+func NewReranker(model rerank.Reranker, config *types.Model, recorder Recorder) *Reranker {
+	return &Reranker{meter: meter{recorder: recorder, model: config}, reranker: model}
+}
+
+// Rerank reranks documents. Rerank APIs report no token usage, which is estimated from the query and documents.
+func (r *Reranker) Rerank(ctx context.Context, query string, documents []string) ([]rerank.RankResult, error) {
+	if err := r.recorder.CheckQuota(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	results, err := r.reranker.Rerank(ctx, query, documents)
+	usage := r.newUsage(start, err)
+	// The query is scored against each document
+	estimate(usage, r.estimateTokens(documents...)+len(documents)*r.estimateTokens(query), 0)
+	r.recorder.Record(ctx, usage)
+	return results, err
+}
+
+// GetModelName returns the model name
+func (r *Reranker) GetModelName() string {
+	return r.reranker.GetModelName()
+}
+
+// GetModelID returns the model ID
+func (r *Reranker) GetModelID() string {
+	return r.reranker.GetModelID()
+}
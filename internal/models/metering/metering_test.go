@@ -0,0 +1,161 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/models/tokenizer"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRecorder keeps the recorded usage and refuses calls once its quota error is set
+type fakeRecorder struct {
+	quotaErr error
+	usages   []*types.ModelUsage
+}
+
+func (r *fakeRecorder) CheckQuota(ctx context.Context) error { return r.quotaErr }
+
+func (r *fakeRecorder) Record(ctx context.Context, usage *types.ModelUsage) {
+	r.usages = append(r.usages, usage)
+}
+
+// fakeChat answers with a fixed response and stream
+type fakeChat struct {
+	resp   *types.ChatResponse
+	err    error
+	stream []types.StreamResponse
+	calls  int
+}
+
+func (f *fakeChat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	f.calls++
+	return f.resp, f.err
+}
+
+func (f *fakeChat) ChatStream(ctx context.Context,
+	messages []chat.Message, opts *chat.ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	f.calls++
+	stream := make(chan types.StreamResponse, len(f.stream))
+	for _, response := range f.stream {
+		stream <- response
+	}
+	close(stream)
+	return stream, nil
+}
+
+func (f *fakeChat) GetModelName() string { return "qwen-plus" }
+func (f *fakeChat) GetModelID() string   { return "model-1" }
+
+var chatModel = &types.Model{ID: "model-1", Name: "qwen-plus", Type: types.ModelTypeKnowledgeQA}
+
+func TestChatRecordsReportedUsage(t *testing.T) {
+	recorder := &fakeRecorder{}
+	resp := &types.ChatResponse{Content: "Refunds take 5 days."}
+	resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.TotalTokens = 120, 8, 128
+	model := NewChat(&fakeChat{resp: resp}, chatModel, recorder)
+
+	_, err := model.Chat(context.Background(), []chat.Message{{Role: "user", Content: "How long do refunds take?"}}, nil)
+	require.NoError(t, err)
+	require.Len(t, recorder.usages, 1)
+	usage := recorder.usages[0]
+	assert.Equal(t, "model-1", usage.ModelID)
+	assert.Equal(t, types.ModelTypeKnowledgeQA, usage.ModelType)
+	assert.Equal(t, 128, usage.TotalTokens)
+	assert.False(t, usage.Estimated)
+	assert.True(t, usage.Success)
+}
+
+func TestChatEstimatesMissingUsage(t *testing.T) {
+	recorder := &fakeRecorder{}
+	model := NewChat(&fakeChat{resp: &types.ChatResponse{Content: strings.Repeat("a", 40)}}, chatModel, recorder)
+
+	_, err := model.Chat(context.Background(), []chat.Message{{Role: "user", Content: strings.Repeat("q", 36)}}, nil)
+	require.NoError(t, err)
+	usage := recorder.usages[0]
+	tok := tokenizer.ForModel(chatModel.Name)
+	assert.True(t, usage.Estimated)
+	assert.Equal(t, tokenizer.CountAll(tok, "user", strings.Repeat("q", 36)), usage.PromptTokens)
+	assert.Equal(t, tok.Count(strings.Repeat("a", 40)), usage.CompletionTokens)
+	assert.Equal(t, usage.PromptTokens+usage.CompletionTokens, usage.TotalTokens)
+}
+
+func TestChatRecordsFailures(t *testing.T) {
+	recorder := &fakeRecorder{}
+	model := NewChat(&fakeChat{err: errors.New("status 503")}, chatModel, recorder)
+
+	_, err := model.Chat(context.Background(), nil, nil)
+	require.Error(t, err)
+	require.Len(t, recorder.usages, 1)
+	assert.False(t, recorder.usages[0].Success)
+	assert.Zero(t, recorder.usages[0].TotalTokens)
+}
+
+func TestChatRefusedOverQuota(t *testing.T) {
+	quotaErr := types.NewTokenQuotaExceededError(1000, 1000)
+	recorder := &fakeRecorder{quotaErr: quotaErr}
+	inner := &fakeChat{resp: &types.ChatResponse{}}
+	model := NewChat(inner, chatModel, recorder)
+
+	_, err := model.Chat(context.Background(), nil, nil)
+	require.ErrorIs(t, err, quotaErr)
+	_, err = model.ChatStream(context.Background(), nil, nil)
+	require.ErrorIs(t, err, quotaErr)
+	assert.Zero(t, inner.calls)
+	assert.Empty(t, recorder.usages)
+}
+
+func TestChatStreamRecordsWhenDone(t *testing.T) {
+	recorder := &fakeRecorder{}
+	model := NewChat(&fakeChat{stream: []types.StreamResponse{
+		{ResponseType: types.ResponseTypeThinking, Content: strings.Repeat("t", 8)},
+		{ResponseType: types.ResponseTypeAnswer, Content: strings.Repeat("a", 8)},
+		{ResponseType: types.ResponseTypeAnswer, Done: true, ToolCalls: []types.LLMToolCall{
+			{Function: types.FunctionCall{Name: "search", Arguments: "{}"}},
+		}},
+	}}, chatModel, recorder)
+
+	stream, err := model.ChatStream(context.Background(), []chat.Message{{Role: "user", Content: "hi"}}, nil)
+	require.NoError(t, err)
+	var responses int
+	for range stream {
+		responses++
+	}
+	assert.Equal(t, 3, responses)
+	require.Len(t, recorder.usages, 1)
+	usage := recorder.usages[0]
+	assert.True(t, usage.Success)
+	tok := tokenizer.ForModel(chatModel.Name)
+	assert.True(t, usage.Estimated)
+	assert.Equal(t, tokenizer.CountAll(tok, "user", "hi"), usage.PromptTokens)
+	// Thinking, answer and tool calls
+	assert.Equal(t, tokenizer.CountAll(tok, strings.Repeat("t", 8), strings.Repeat("a", 8), "search", "{}"),
+		usage.CompletionTokens)
+}
+
+// fakeReranker scores every document the same
+type fakeReranker struct{}
+
+func (fakeReranker) Rerank(ctx context.Context, query string, documents []string) ([]rerank.RankResult, error) {
+	return make([]rerank.RankResult, len(documents)), nil
+}
+func (fakeReranker) GetModelName() string { return "bge-reranker" }
+func (fakeReranker) GetModelID() string   { return "rerank-1" }
+
+func TestRerankerEstimatesQueryPerDocument(t *testing.T) {
+	recorder := &fakeRecorder{}
+	model := NewReranker(fakeReranker{}, &types.Model{ID: "rerank-1", Type: types.ModelTypeRerank}, recorder)
+
+	_, err := model.Rerank(context.Background(), strings.Repeat("q", 8), []string{strings.Repeat("d", 8), strings.Repeat("d", 8)})
+	require.NoError(t, err)
+	tok := tokenizer.Default()
+	assert.Equal(t, 2*tok.Count(strings.Repeat("d", 8))+2*tok.Count(strings.Repeat("q", 8)), recorder.usages[0].TotalTokens)
+	assert.Equal(t, "rerank-1", model.GetModelID())
+}
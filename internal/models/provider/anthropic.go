@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+const (
+	AnthropicBaseURL = "https://api.anthropic.com/v1"
+)
+
+// AnthropicProvider implements Anthropic Provider interface
+type AnthropicProvider struct{}
+
+func init() {
+	Register(&AnthropicProvider{})
+}
+
+// Info returns Anthropic provider metadata
+func (p *AnthropicProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		Name:        ProviderAnthropic,
+		DisplayName: "Anthropic",
+		Description: "claude-sonnet-4-5, claude-haiku-4-5, etc. (Messages API)",
+		DefaultURLs: map[types.ModelType]string{
+			types.ModelTypeKnowledgeQA: AnthropicBaseURL,
+			types.ModelTypeVLLM:        AnthropicBaseURL,
+		},
+		ModelTypes: []types.ModelType{
+			types.ModelTypeKnowledgeQA,
+			types.ModelTypeVLLM,
+		},
+		RequiresAuth: true,
+	}
+}
+
+// ValidateConfig validates Anthropic provider configuration
+func (p *AnthropicProvider) ValidateConfig(config *Config) error {
+	if config.APIKey == "" {
+		return fmt.Errorf("API key is required for Anthropic provider")
+	}
+	if config.ModelName == "" {
+		return fmt.Errorf("model name is required")
+	}
+	return nil
+}
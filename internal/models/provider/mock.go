@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// MockProvider implements the Provider interface for deterministic offline models,
+// which answer from a local fixture file instead of calling a model service
+type MockProvider struct{}
+
+func init() {
+	Register(&MockProvider{})
+}
+
+// Info returns mock provider metadata
+func (p *MockProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		Name:        ProviderMock,
+		DisplayName: "Mock (Offline Testing)",
+		Description: "Scripted chat, hash-based embedding and lexical rerank, no network required",
+		DefaultURLs: map[types.ModelType]string{}, // Base URL is the path of the chat fixture file in the fixture directory, if any
+		ModelTypes: []types.ModelType{
+			types.ModelTypeKnowledgeQA,
+			types.ModelTypeEmbedding,
+			types.ModelTypeRerank,
+		},
+		RequiresAuth: false,
+	}
+}
+
+// ValidateConfig validates mock provider configuration
+func (p *MockProvider) ValidateConfig(config *Config) error {
+	if config.ModelName == "" {
+		return fmt.Errorf("model name is required")
+	}
+	return nil
+}
@@ -39,6 +39,10 @@ const (
 	ProviderMiniMax ProviderName = "minimax"
 	// Xiaomi Mimo
 	ProviderMimo ProviderName = "mimo"
+	// Anthropic (native Messages API)
+	ProviderAnthropic ProviderName = "anthropic"
+	// Deterministic offline models for testing
+	ProviderMock ProviderName = "mock"
 )
 
 // AllProviders returns all registered provider names
@@ -53,10 +57,12 @@ func AllProviders() []ProviderName {
 		ProviderDeepSeek,
 		ProviderMiniMax,
 		ProviderOpenAI,
+		ProviderAnthropic,
 		ProviderGemini,
 		ProviderOpenRouter,
 		ProviderJina,
 		ProviderMimo,
+		ProviderMock,
 	}
 }
 
@@ -208,6 +214,8 @@ func DetectProvider(baseURL string) ProviderName {
 		return ProviderMiniMax
 	case containsAny(baseURL, "xiaomimimo.com"):
 		return ProviderMimo
+	case containsAny(baseURL, "api.anthropic.com"):
+		return ProviderAnthropic
 	default:
 		return ProviderGeneric
 	}
@@ -46,6 +46,7 @@ func TestDetectProvider(t *testing.T) {
 		{"https://api.minimaxi.com/v1", ProviderMiniMax},
 		{"https://api.minimax.io/v1", ProviderMiniMax},
 		{"https://api.xiaomimimo.com/v1", ProviderMimo},
+		{"https://api.anthropic.com/v1", ProviderAnthropic},
 		{"https://custom-endpoint.example.com/v1", ProviderGeneric},
 		{"http://localhost:11434/v1", ProviderGeneric},
 	}
@@ -0,0 +1,356 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"golang.org/x/sync/errgroup"
+)
+
+// LLMRerankMode selects how the chat model ranks documents
+type LLMRerankMode string
+
+const (
+	// LLMRerankPointwise scores each document independently, several documents per call
+	LLMRerankPointwise LLMRerankMode = "pointwise"
+	// LLMRerankListwise orders documents with a sliding window moving from the tail to the head
+	LLMRerankListwise LLMRerankMode = "listwise"
+)
+
+// Extra config keys of a rerank model backed by a chat model.
+// A rerank model is served by the LLM reranker when LLMRerankChatModelKey is set.
+const (
+	LLMRerankChatModelKey     = "llm_chat_model_id"      // ID of the chat model used for reranking
+	LLMRerankModeKey          = "llm_rerank_mode"        // pointwise (default) or listwise
+	LLMRerankBatchSizeKey     = "llm_rerank_batch_size"  // Documents scored per call in pointwise mode
+	LLMRerankWindowSizeKey    = "llm_rerank_window_size" // Window size in listwise mode
+	LLMRerankStepKey          = "llm_rerank_step"        // Window step in listwise mode
+	LLMRerankMaxCandidatesKey = "llm_rerank_max_candidates"
+	LLMRerankMaxDocCharsKey   = "llm_rerank_max_doc_chars"
+)
+
+// Default limits bounding the cost of a single Rerank call
+const (
+	defaultLLMRerankBatchSize     = 10
+	defaultLLMRerankWindowSize    = 10
+	defaultLLMRerankMaxCandidates = 30
+	defaultLLMRerankMaxDocChars   = 1000
+	defaultLLMRerankConcurrency   = 4
+)
+
+const llmPointwisePrompt = `You are a search relevance judge. For each passage, rate how relevant it is to the query
+on a scale from 0 (irrelevant) to 10 (directly answers the query).
+Respond with JSON only, in the form {"scores": [<score of passage 0>, <score of passage 1>, ...]},
+with exactly one score per passage, in passage order.`
+
+const llmListwisePrompt = `You are a search relevance judge. Rank the passages by their relevance to the query,
+most relevant first, and rate how relevant each passage is on a scale from 0 (irrelevant) to 10 (directly
+answers the query). Respond with the passage identifiers and ratings only, in the form [2] (9) > [0] (6) > [1] (0),
+including every passage exactly once.`
+
+// LLMRerankerConfig configures the LLM reranker
+type LLMRerankerConfig struct {
+	ModelID          string        // ID of the rerank model
+	ModelName        string        // Name of the rerank model
+	Mode             LLMRerankMode // Ranking mode
+	BatchSize        int           // Documents scored per call in pointwise mode
+	WindowSize       int           // Window size in listwise mode
+	Step             int           // Window step in listwise mode, defaults to half the window
+	MaxCandidates    int           // Documents beyond this limit are not sent to the model and get a zero score
+	MaxDocumentChars int           // Documents are truncated to this many characters in prompts
+}
+
+// LLMReranker ranks documents with a chat model, for tenants without a dedicated rerank model
+type LLMReranker struct {
+	chatModel chat.Chat
+	config    LLMRerankerConfig
+}
+
+// NewLLMRerankerConfig builds the LLM reranker config from the extra config of a rerank model
+func NewLLMRerankerConfig(modelID, modelName string, extra map[string]string) LLMRerankerConfig {
+	atoi := func(key string) int {
+		v, _ := strconv.Atoi(extra[key])
+		return v
+	}
+	return LLMRerankerConfig{
+		ModelID:          modelID,
+		ModelName:        modelName,
+		Mode:             LLMRerankMode(extra[LLMRerankModeKey]),
+		BatchSize:        atoi(LLMRerankBatchSizeKey),
+		WindowSize:       atoi(LLMRerankWindowSizeKey),
+		Step:             atoi(LLMRerankStepKey),
+		MaxCandidates:    atoi(LLMRerankMaxCandidatesKey),
+		MaxDocumentChars: atoi(LLMRerankMaxDocCharsKey),
+	}
+}
+
+// NewLLMReranker creates a reranker backed by the given chat model
+func NewLLMReranker(chatModel chat.Chat, config LLMRerankerConfig) (*LLMReranker, error) {
+	if chatModel == nil {
+		return nil, fmt.Errorf("chat model is required for LLM reranker")
+	}
+	switch config.Mode {
+	case "":
+		config.Mode = LLMRerankPointwise
+	case LLMRerankPointwise, LLMRerankListwise:
+	default:
+		return nil, fmt.Errorf("unsupported LLM rerank mode: %s", config.Mode)
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultLLMRerankBatchSize
+	}
+	if config.WindowSize <= 1 {
+		config.WindowSize = defaultLLMRerankWindowSize
+	}
+	if config.Step <= 0 || config.Step >= config.WindowSize {
+		config.Step = config.WindowSize / 2
+	}
+	if config.MaxCandidates <= 0 {
+		config.MaxCandidates = defaultLLMRerankMaxCandidates
+	}
+	if config.MaxDocumentChars <= 0 {
+		config.MaxDocumentChars = defaultLLMRerankMaxDocChars
+	}
+	return &LLMReranker{chatModel: chatModel, config: config}, nil
+}
+
+// Rerank ranks documents by relevance to the query. Only the first MaxCandidates
+// documents are ranked, the remaining ones are returned after them with a zero score.
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []string) ([]RankResult, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	candidates := len(documents)
+	if candidates > r.config.MaxCandidates {
+		candidates = r.config.MaxCandidates
+	}
+
+	var order []int
+	var scores []float64
+	var err error
+	switch r.config.Mode {
+	case LLMRerankListwise:
+		order, scores, err = r.rankListwise(ctx, query, documents[:candidates])
+	default:
+		scores, err = r.scorePointwise(ctx, query, documents[:candidates])
+		order = make([]int, candidates)
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return scores[order[i]] > scores[order[j]]
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RankResult, 0, len(documents))
+	for _, i := range order {
+		results = append(results, RankResult{
+			Index:          i,
+			Document:       DocumentInfo{Text: documents[i]},
+			RelevanceScore: scores[i],
+		})
+	}
+	for i := candidates; i < len(documents); i++ {
+		results = append(results, RankResult{Index: i, Document: DocumentInfo{Text: documents[i]}})
+	}
+	return results, nil
+}
+
+// scorePointwise scores documents in batches and normalizes the scores to [0, 1]
+func (r *LLMReranker) scorePointwise(ctx context.Context, query string, documents []string) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(defaultLLMRerankConcurrency)
+	for start := 0; start < len(documents); start += r.config.BatchSize {
+		start := start
+		end := start + r.config.BatchSize
+		if end > len(documents) {
+			end = len(documents)
+		}
+		g.Go(func() error {
+			content, err := r.chat(gctx, llmPointwisePrompt, r.buildPassages(query, documents[start:end]))
+			if err != nil {
+				return err
+			}
+			batch, err := parsePointwiseScores(content, end-start)
+			if err != nil {
+				return err
+			}
+			copy(scores[start:end], batch)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// rankListwise orders documents with a sliding window from the tail to the head of the list,
+// so that relevant documents bubble up to the front. The score of a document is the rating of the
+// last window it was ranked in, capped by the score of the document ranked above it, so that
+// scores measure relevance rather than position and still follow the ranking.
+func (r *LLMReranker) rankListwise(ctx context.Context, query string, documents []string) ([]int, []float64, error) {
+	order := make([]int, len(documents))
+	ratings := make([]float64, len(documents))
+	for i := range order {
+		order[i] = i
+		ratings[i] = -1
+	}
+
+	end := len(order)
+	for {
+		start := end - r.config.WindowSize
+		if start < 0 {
+			start = 0
+		}
+		window := order[start:end]
+		passages := make([]string, len(window))
+		for i, idx := range window {
+			passages[i] = documents[idx]
+		}
+
+		content, err := r.chat(ctx, llmListwisePrompt, r.buildPassages(query, passages))
+		if err != nil {
+			return nil, nil, err
+		}
+		permutation, windowRatings := parseListwiseRanking(content, len(window))
+		reordered := make([]int, len(window))
+		for i, p := range permutation {
+			reordered[i] = window[p]
+			if windowRatings[p] >= 0 {
+				ratings[window[p]] = windowRatings[p]
+			}
+		}
+		copy(window, reordered)
+
+		if start == 0 {
+			break
+		}
+		end -= r.config.Step
+	}
+
+	// Documents left unrated take the score of the document ranked above them
+	scores := make([]float64, len(documents))
+	previous := 1.0
+	for _, idx := range order {
+		if ratings[idx] >= 0 && ratings[idx] < previous {
+			previous = ratings[idx]
+		}
+		scores[idx] = previous
+	}
+	return order, scores, nil
+}
+
+// buildPassages formats the query and the numbered passages for the prompt
+func (r *LLMReranker) buildPassages(query string, documents []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Query: %s\n\n", query)
+	for i, doc := range documents {
+		if runes := []rune(doc); len(runes) > r.config.MaxDocumentChars {
+			doc = string(runes[:r.config.MaxDocumentChars])
+		}
+		fmt.Fprintf(&sb, "[%d] %s\n\n", i, doc)
+	}
+	return sb.String()
+}
+
+// chat sends a single deterministic ranking request to the chat model
+func (r *LLMReranker) chat(ctx context.Context, systemPrompt, userContent string) (string, error) {
+	thinking := false
+	response, err := r.chatModel.Chat(ctx, []chat.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userContent},
+	}, &chat.ChatOptions{
+		Temperature: 0,
+		Thinking:    &thinking,
+	})
+	if err != nil {
+		return "", fmt.Errorf("LLM rerank request failed: %w", err)
+	}
+	return response.Content, nil
+}
+
+// parsePointwiseScores extracts the per-passage scores and normalizes them to [0, 1]
+func parsePointwiseScores(content string, expected int) ([]float64, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("unexpected LLM rerank output: %s", content)
+	}
+	var result struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM rerank output: %w", err)
+	}
+	if len(result.Scores) != expected {
+		return nil, fmt.Errorf("LLM rerank returned %d scores for %d passages", len(result.Scores), expected)
+	}
+	scores := make([]float64, expected)
+	for i, s := range result.Scores {
+		scores[i] = normalizeRating(s)
+	}
+	return scores, nil
+}
+
+// normalizeRating maps a 0-10 relevance rating to [0, 1]
+func normalizeRating(rating float64) float64 {
+	switch {
+	case rating < 0:
+		rating = 0
+	case rating > 10:
+		rating = 10
+	}
+	return rating / 10
+}
+
+var listwiseIdentifierPattern = regexp.MustCompile(`\[(\d+)\](?:\s*\((\d+(?:\.\d+)?)\))?`)
+
+// parseListwiseRanking extracts a permutation of [0, n) and the normalized rating of each passage
+// from the model output, -1 for passages without rating. Unknown and duplicate identifiers are
+// ignored, missing ones keep their original order at the end.
+func parseListwiseRanking(content string, n int) ([]int, []float64) {
+	seen := make([]bool, n)
+	permutation := make([]int, 0, n)
+	ratings := make([]float64, n)
+	for i := range ratings {
+		ratings[i] = -1
+	}
+	for _, match := range listwiseIdentifierPattern.FindAllStringSubmatch(content, -1) {
+		idx, err := strconv.Atoi(match[1])
+		if err != nil || idx >= n || seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		permutation = append(permutation, idx)
+		if rating, err := strconv.ParseFloat(match[2], 64); err == nil {
+			ratings[idx] = normalizeRating(rating)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !seen[i] {
+			permutation = append(permutation, i)
+		}
+	}
+	return permutation, ratings
+}
+
+// GetModelName returns the model name
+func (r *LLMReranker) GetModelName() string {
+	return r.config.ModelName
+}
+
+// GetModelID returns the model ID
+func (r *LLMReranker) GetModelID() string {
+	return r.config.ModelID
+}
@@ -0,0 +1,146 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// fakeChat answers ranking prompts with a fixed function of the user message
+type fakeChat struct {
+	mu      sync.Mutex
+	calls   int
+	respond func(user string) string
+}
+
+func (f *fakeChat) Chat(_ context.Context, messages []chat.Message, _ *chat.ChatOptions) (*types.ChatResponse, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	return &types.ChatResponse{Content: f.respond(messages[len(messages)-1].Content)}, nil
+}
+
+func (f *fakeChat) ChatStream(context.Context, []chat.Message, *chat.ChatOptions) (<-chan types.StreamResponse, error) {
+	return nil, nil
+}
+
+func (f *fakeChat) GetModelName() string { return "fake" }
+
+func (f *fakeChat) GetModelID() string { return "fake" }
+
+func TestLLMRerankerPointwise(t *testing.T) {
+	model := &fakeChat{respond: func(user string) string {
+		// Score passages mentioning "go" highly
+		var scores []string
+		for _, line := range strings.Split(user, "\n") {
+			if strings.HasPrefix(line, "[") {
+				if strings.Contains(line, "go") {
+					scores = append(scores, "9")
+				} else {
+					scores = append(scores, "1")
+				}
+			}
+		}
+		return `{"scores": [` + strings.Join(scores, ",") + `]}`
+	}}
+	reranker, err := NewLLMReranker(model, LLMRerankerConfig{BatchSize: 2, MaxCandidates: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results, err := reranker.Rerank(context.Background(), "golang",
+		[]string{"python", "go routines", "rust", "go modules", "go channels"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if model.calls != 2 {
+		t.Errorf("Expected 2 batched calls, got %d", model.calls)
+	}
+	if len(results) != 5 || results[0].Index != 1 || results[1].Index != 3 {
+		t.Fatalf("Unexpected ranking: %+v", results)
+	}
+	if results[0].RelevanceScore != 0.9 || results[4].Index != 4 || results[4].RelevanceScore != 0 {
+		t.Errorf("Unexpected scores: %+v", results)
+	}
+}
+
+func TestLLMRerankerListwise(t *testing.T) {
+	// The model always ranks the last passage of the window first
+	model := &fakeChat{respond: func(user string) string {
+		n := strings.Count(user, "\n[")
+		return "[" + string(rune('0'+n-1)) + "]"
+	}}
+	reranker, err := NewLLMReranker(model, LLMRerankerConfig{Mode: LLMRerankListwise, WindowSize: 3, Step: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results, err := reranker.Rerank(context.Background(), "q", []string{"a", "b", "c", "d", "e"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if model.calls != 2 {
+		t.Errorf("Expected 2 window calls, got %d", model.calls)
+	}
+	// Window [c d e] moves e to the front, then window [a b e] moves e to the front again
+	expected := []int{4, 0, 1, 2, 3}
+	for i, idx := range expected {
+		if results[i].Index != idx {
+			t.Fatalf("Expected order %v, got %+v", expected, results)
+		}
+	}
+	if results[0].RelevanceScore != 1 {
+		t.Errorf("Expected top score 1, got %v", results[0].RelevanceScore)
+	}
+}
+
+func TestLLMRerankerListwiseAllRelevant(t *testing.T) {
+	// The model reverses each window and rates every passage as relevant
+	model := &fakeChat{respond: func(user string) string {
+		n := strings.Count(user, "\n[")
+		ranking := make([]string, 0, n)
+		for i := n - 1; i >= 0; i-- {
+			ranking = append(ranking, fmt.Sprintf("[%d] (%d)", i, 7+i%3))
+		}
+		return strings.Join(ranking, " > ")
+	}}
+	reranker, err := NewLLMReranker(model, LLMRerankerConfig{Mode: LLMRerankListwise, WindowSize: 4, Step: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results, err := reranker.Rerank(context.Background(), "q", []string{"a", "b", "c", "d", "e", "f", "g", "h"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Scores follow the ratings, so no relevant document falls below the default rerank threshold
+	for i, result := range results {
+		if result.RelevanceScore < 0.7 {
+			t.Errorf("Expected every document to keep a relevant score, got %+v", result)
+		}
+		if i > 0 && result.RelevanceScore > results[i-1].RelevanceScore {
+			t.Errorf("Expected scores to follow the ranking, got %+v", results)
+		}
+	}
+}
+
+func TestParseListwiseRanking(t *testing.T) {
+	got, ratings := parseListwiseRanking("[2] (8) > [2] > [7] > [0] (12)", 4)
+	expected := []int{2, 0, 1, 3}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+	expectedRatings := []float64{1, -1, 0.8, -1}
+	for i := range expectedRatings {
+		if ratings[i] != expectedRatings[i] {
+			t.Fatalf("Expected ratings %v, got %v", expectedRatings, ratings)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package rerank
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Tencent/WeKnora/internal/models/utils"
+)
+
+// MockReranker implements deterministic offline reranking, scoring each document by the
+// share of distinct query terms it contains
+type MockReranker struct {
+	modelName string // Name of the model used for reranking
+	modelID   string // Unique identifier of the model
+}
+
+// NewMockReranker creates a new instance of mock reranker with the provided configuration
+func NewMockReranker(config *RerankerConfig) *MockReranker {
+	return &MockReranker{
+		modelName: config.ModelName,
+		modelID:   config.ModelID,
+	}
+}
+
+// Rerank scores all documents by lexical overlap with the query, best first
+func (r *MockReranker) Rerank(ctx context.Context, query string, documents []string) ([]RankResult, error) {
+	queryTerms := make(map[string]struct{})
+	for _, term := range utils.Terms(query) {
+		queryTerms[term] = struct{}{}
+	}
+
+	results := make([]RankResult, 0, len(documents))
+	for i, document := range documents {
+		var score float64
+		if len(queryTerms) > 0 {
+			matched := make(map[string]struct{})
+			for _, term := range utils.Terms(document) {
+				if _, ok := queryTerms[term]; ok {
+					matched[term] = struct{}{}
+				}
+			}
+			score = float64(len(matched)) / float64(len(queryTerms))
+		}
+		results = append(results, RankResult{
+			Index:          i,
+			Document:       DocumentInfo{Text: document},
+			RelevanceScore: score,
+		})
+	}
+	// Ties keep the order of the documents
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+	return results, nil
+}
+
+// GetModelName returns the name of the reranking model
+func (r *MockReranker) GetModelName() string {
+	return r.modelName
+}
+
+// GetModelID returns the unique identifier of the reranking model
+func (r *MockReranker) GetModelID() string {
+	return r.modelID
+}
@@ -0,0 +1,34 @@
+package rerank
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockReranker(t *testing.T) {
+	reranker, err := NewReranker(&RerankerConfig{Provider: "mock", ModelName: "mock-rerank", ModelID: "rerank-1"})
+	require.NoError(t, err)
+	require.IsType(t, &MockReranker{}, reranker)
+
+	results, err := reranker.Rerank(context.Background(), "Refund processing days", []string{
+		"Shipping is free for orders over $50.",
+		"Refunds: processing takes 5 days.",
+		"Refund processing takes 5 business days.",
+		"退款处理需要5个工作日。",
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.Equal(t, 2, results[0].Index)
+	assert.InDelta(t, 1.0, results[0].RelevanceScore, 1e-9)
+	// Terms match exactly: "refunds" is not "refund"
+	assert.Equal(t, 1, results[1].Index)
+	assert.InDelta(t, 2.0/3, results[1].RelevanceScore, 1e-9)
+	// Ties keep the order of the documents
+	assert.Equal(t, 0, results[2].Index)
+	assert.Equal(t, 3, results[3].Index)
+	assert.Zero(t, results[3].RelevanceScore)
+	assert.Equal(t, "Refund processing takes 5 business days.", results[0].Document.Text)
+}
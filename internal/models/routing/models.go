@@ -0,0 +1,184 @@
+package routing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/models/rerank"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// modelIDs returns the IDs of models
+func modelIDs[M interface{ GetModelID() string }](models []M) []string {
+	ids := make([]string, len(models))
+	for i, model := range models {
+		ids[i] = model.GetModelID()
+	}
+	return ids
+}
+
+// Chat is a chat model failing over to the fallbacks of the primary model
+type Chat struct {
+	router *Router
+	models []chat.Chat
+	ids    []string
+}
+
+// NewChat creates a chat model routing requests to the primary model then to the fallbacks
+func NewChat(router *Router, primary chat.Chat, fallbacks ...chat.Chat) *Chat {
+	models := append([]chat.Chat{primary}, fallbacks...)
+	return &Chat{router: router, models: models, ids: modelIDs(models)}
+}
+
+// Chat performs non-streaming chat
+func (c *Chat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	var response *types.ChatResponse
+	err := c.router.Do(ctx, c.ids, func(i int) error {
+		var err error
+		response, err = c.models[i].Chat(ctx, messages, opts)
+		return err
+	})
+	return response, err
+}
+
+// ChatStream performs streaming chat. A model fails over until its stream yields a first response,
+// as responses cannot be taken back once streamed.
+func (c *Chat) ChatStream(ctx context.Context,
+	messages []chat.Message, opts *chat.ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	var stream <-chan types.StreamResponse
+	err := c.router.Do(ctx, c.ids, func(i int) error {
+		modelStream, err := c.models[i].ChatStream(ctx, messages, opts)
+		if err != nil {
+			return err
+		}
+		first, ok := <-modelStream
+		if !ok {
+			return errors.New("stream closed without response")
+		}
+		if first.ResponseType == types.ResponseTypeError && first.Done {
+			return errors.New(first.Content)
+		}
+		stream = prepend(first, modelStream)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// prepend returns a stream of a response followed by the responses of a stream
+func prepend(first types.StreamResponse, rest <-chan types.StreamResponse) <-chan types.StreamResponse {
+	stream := make(chan types.StreamResponse)
+	go func() {
+		defer close(stream)
+		stream <- first
+		for response := range rest {
+			stream <- response
+		}
+	}()
+	return stream
+}
+
+// GetModelName returns the name of the primary model
+func (c *Chat) GetModelName() string {
+	return c.models[0].GetModelName()
+}
+
+// GetModelID returns the ID of the primary model
+func (c *Chat) GetModelID() string {
+	return c.models[0].GetModelID()
+}
+
+// Embedder is an embedding model failing over to fallbacks of the same dimension
+type Embedder struct {
+	router *Router
+	models []embedding.Embedder
+	ids    []string
+}
+
+// NewEmbedder creates an embedding model routing requests to the primary model then to the fallbacks,
+// which must have the dimension of the primary model
+func NewEmbedder(router *Router, primary embedding.Embedder, fallbacks ...embedding.Embedder) *Embedder {
+	models := append([]embedding.Embedder{primary}, fallbacks...)
+	return &Embedder{router: router, models: models, ids: modelIDs(models)}
+}
+
+// Embed converts text to vector
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var vector []float32
+	err := e.router.Do(ctx, e.ids, func(i int) error {
+		var err error
+		vector, err = e.models[i].Embed(ctx, text)
+		return err
+	})
+	return vector, err
+}
+
+// BatchEmbed converts multiple texts to vectors in batch
+func (e *Embedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := e.router.Do(ctx, e.ids, func(i int) error {
+		var err error
+		vectors, err = e.models[i].BatchEmbed(ctx, texts)
+		return err
+	})
+	return vectors, err
+}
+
+// BatchEmbedWithPool embeds texts in batches with the pool of the primary model
+func (e *Embedder) BatchEmbedWithPool(ctx context.Context, model embedding.Embedder, texts []string) ([][]float32, error) {
+	return e.models[0].BatchEmbedWithPool(ctx, model, texts)
+}
+
+// GetModelName returns the name of the primary model
+func (e *Embedder) GetModelName() string {
+	return e.models[0].GetModelName()
+}
+
+// GetDimensions returns the dimension shared by the models
+func (e *Embedder) GetDimensions() int {
+	return e.models[0].GetDimensions()
+}
+
+// GetModelID returns the ID of the primary model, which the vectors are stored under
+func (e *Embedder) GetModelID() string {
+	return e.models[0].GetModelID()
+}
+
+// Reranker is a rerank model failing over to the fallbacks of the primary model
+type Reranker struct {
+	router *Router
+	models []rerank.Reranker
+	ids    []string
+}
+
+// NewReranker creates a rerank model routing requests to the primary model then to the fallbacks
+func NewReranker(router *Router, primary rerank.Reranker, fallbacks ...rerank.Reranker) *Reranker {
+	models := append([]rerank.Reranker{primary}, fallbacks...)
+	return &Reranker{router: router, models: models, ids: modelIDs(models)}
+}
+
+// Rerank reranks documents based on relevance to the query
+func (r *Reranker) Rerank(ctx context.Context, query string, documents []string) ([]rerank.RankResult, error) {
+	var results []rerank.RankResult
+	err := r.router.Do(ctx, r.ids, func(i int) error {
+		var err error
+		results, err = r.models[i].Rerank(ctx, query, documents)
+		return err
+	})
+	return results, err
+}
+
+// GetModelName returns the name of the primary model
+func (r *Reranker) GetModelName() string {
+	return r.models[0].GetModelName()
+}
+
+// GetModelID returns the ID of the primary model
+func (r *Reranker) GetModelID() string {
+	return r.models[0].GetModelID()
+}
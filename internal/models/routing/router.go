@@ -0,0 +1,200 @@
+// Package routing retries model requests, breaks the circuit of failing models and fails over to
+// the fallback models of a route.
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrCircuitOpen is returned for a model skipped because it failed repeatedly
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrAllModelsFailed is returned when every model of a route failed
+var ErrAllModelsFailed = errors.New("all models of the route failed")
+
+// Breakers holds the circuit breaker of each model. It is shared by all requests so that the
+// failures of a model are counted across sessions.
+type Breakers struct {
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// breaker counts the consecutive failures of a model
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// NewBreakers creates an empty set of circuit breakers
+func NewBreakers() *Breakers {
+	return &Breakers{breakers: make(map[string]*breaker)}
+}
+
+// Allow reports whether a request may be sent to a model. Once the circuit has been open for
+// its duration, requests are let through again and a single failure reopens it.
+func (b *Breakers) Allow(modelID string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.breakers[modelID]
+	return !ok || !now.Before(state.openUntil)
+}
+
+// Success closes the circuit of a model
+func (b *Breakers) Success(modelID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.breakers, modelID)
+}
+
+// Failure counts a failure of a model and opens its circuit once the threshold is reached
+func (b *Breakers) Failure(modelID string, policy types.ModelCircuitBreakerPolicy, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.breakers[modelID]
+	if !ok {
+		state = &breaker{}
+		b.breakers[modelID] = state
+	}
+	state.failures++
+	if state.failures >= policy.Threshold() {
+		state.openUntil = now.Add(policy.OpenDuration())
+	}
+}
+
+// Router sends a request to the models of a route in order, retrying each one
+type Router struct {
+	breakers *Breakers
+	config   *types.ModelRoutingConfig
+	// sleep waits between retries, replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRouter creates a router applying the policies of a routing configuration
+func NewRouter(breakers *Breakers, config *types.ModelRoutingConfig) *Router {
+	return &Router{breakers: breakers, config: config, sleep: sleep}
+}
+
+// Do calls the models in order until one succeeds. Retryable errors are retried with backoff
+// before failing over, and count towards opening the circuit of the model.
+func (r *Router) Do(ctx context.Context, modelIDs []string, call func(i int) error) error {
+	var errs []error
+	for i, modelID := range modelIDs {
+		if !r.breakers.Allow(modelID, time.Now()) {
+			errs = append(errs, fmt.Errorf("model %s: %w", modelID, ErrCircuitOpen))
+			continue
+		}
+		err := r.retry(ctx, modelID, func() error { return call(i) })
+		if err == nil {
+			r.breakers.Success(modelID)
+			if i > 0 {
+				logger.Infof(ctx, "[ModelRouting] Served by fallback model %s", modelID)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if IsRetryable(err) {
+			r.breakers.Failure(modelID, r.config.CircuitBreaker, time.Now())
+		}
+		logger.Warnf(ctx, "[ModelRouting] Model %s failed: %v", modelID, err)
+		errs = append(errs, fmt.Errorf("model %s: %w", modelID, err))
+	}
+	return fmt.Errorf("%w: %w", ErrAllModelsFailed, errors.Join(errs...))
+}
+
+// retry calls a model until it succeeds, fails with an error which is not retryable, or runs out of retries
+func (r *Router) retry(ctx context.Context, modelID string, call func() error) error {
+	retries := r.config.Retry.Retries()
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= retries || !IsRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		backoff := r.config.Retry.Backoff(attempt + 1)
+		logger.Infof(ctx, "[ModelRouting] Retrying model %s (%d/%d) in %v: %v", modelID, attempt+1, retries, backoff, err)
+		if err := r.sleep(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for a duration unless the context is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// statusPattern finds the HTTP status in the messages of errors returned by model clients
+var statusPattern = regexp.MustCompile(`(?i)status(?: code)?\s*[:=]?\s*(\d{3})\b`)
+
+// transientMessages are fragments of the messages of errors worth retrying
+var transientMessages = []string{
+	"timeout", "timed out", "connection reset", "connection refused", "broken pipe",
+	"unexpected eof", "overloaded", "rate limit", "too many requests", "server error",
+}
+
+// IsRetryable reports whether a request failing with an error may succeed if sent again:
+// network errors, timeouts, rate limits and server errors
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode != 0 {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) && requestErr.HTTPStatusCode != 0 {
+		return retryableStatus(requestErr.HTTPStatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	message := err.Error()
+	if match := statusPattern.FindStringSubmatch(message); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		return retryableStatus(status)
+	}
+	message = strings.ToLower(message)
+	for _, fragment := range transientMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableStatus reports whether a request failing with an HTTP status may succeed if sent again
+func retryableStatus(status int) bool {
+	switch status {
+	case 408, 409, 425, 429:
+		return true
+	default:
+		return status >= 500
+	}
+}
@@ -0,0 +1,232 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/models/embedding"
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRouter creates a router which records its waits instead of sleeping
+func newTestRouter(breakers *Breakers, config *types.ModelRoutingConfig, waits *[]time.Duration) *Router {
+	router := NewRouter(breakers, config)
+	router.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return ctx.Err()
+	}
+	return router
+}
+
+// fakeChat fails with its errors in turn before answering
+type fakeChat struct {
+	id     string
+	errs   []error
+	calls  int
+	stream []types.StreamResponse
+}
+
+func (f *fakeChat) Chat(ctx context.Context, messages []chat.Message, opts *chat.ChatOptions) (*types.ChatResponse, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &types.ChatResponse{Content: "answer from " + f.id}, nil
+}
+
+func (f *fakeChat) ChatStream(ctx context.Context,
+	messages []chat.Message, opts *chat.ChatOptions,
+) (<-chan types.StreamResponse, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	stream := make(chan types.StreamResponse, len(f.stream))
+	for _, response := range f.stream {
+		stream <- response
+	}
+	close(stream)
+	return stream, nil
+}
+
+func (f *fakeChat) GetModelName() string { return f.id }
+func (f *fakeChat) GetModelID() string   { return f.id }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{&openai.APIError{HTTPStatusCode: 429}, true},
+		{&openai.APIError{HTTPStatusCode: 503}, true},
+		{fmt.Errorf("chat: %w", &openai.APIError{HTTPStatusCode: 401}), false},
+		{&openai.RequestError{HTTPStatusCode: 502}, true},
+		{errors.New("anthropic API error (status 529): overloaded_error"), true},
+		{errors.New("API request failed with status 400: invalid model"), false},
+		{errors.New("dial tcp 10.0.0.1:443: connect: connection refused"), true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("invalid API key"), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.retryable, IsRetryable(tt.err), tt.err.Error())
+	}
+}
+
+func TestRouterRetriesThenFailsOver(t *testing.T) {
+	var waits []time.Duration
+	router := newTestRouter(NewBreakers(), &types.ModelRoutingConfig{
+		Retry: types.ModelRetryPolicy{MaxRetries: 2, InitialBackoffMs: 100},
+	}, &waits)
+
+	overloaded := &openai.APIError{HTTPStatusCode: 503}
+	primary := &fakeChat{id: "primary", errs: []error{overloaded, overloaded, overloaded}}
+	fallback := &fakeChat{id: "fallback"}
+	model := NewChat(router, primary, fallback)
+
+	resp, err := model.Chat(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "answer from fallback", resp.Content)
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, waits)
+	assert.Equal(t, "primary", model.GetModelID())
+}
+
+func TestRouterFailsOverWithoutRetryingPermanentErrors(t *testing.T) {
+	var waits []time.Duration
+	breakers := NewBreakers()
+	router := newTestRouter(breakers, &types.ModelRoutingConfig{}, &waits)
+
+	primary := &fakeChat{id: "primary", errs: []error{&openai.APIError{HTTPStatusCode: 400}}}
+	fallback := &fakeChat{id: "fallback", errs: []error{errors.New("invalid API key")}}
+	_, err := NewChat(router, primary, fallback).Chat(context.Background(), nil, nil)
+	require.ErrorIs(t, err, ErrAllModelsFailed)
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 1, fallback.calls)
+	assert.Empty(t, waits)
+	// Errors which are not retryable are not the fault of the provider
+	assert.True(t, breakers.Allow("primary", time.Now()))
+}
+
+func TestRouterOpensCircuit(t *testing.T) {
+	var waits []time.Duration
+	breakers := NewBreakers()
+	router := newTestRouter(breakers, &types.ModelRoutingConfig{
+		Retry:          types.ModelRetryPolicy{MaxRetries: -1},
+		CircuitBreaker: types.ModelCircuitBreakerPolicy{FailureThreshold: 2, OpenSeconds: 60},
+	}, &waits)
+
+	rateLimited := &openai.APIError{HTTPStatusCode: 429}
+	primary := &fakeChat{id: "primary", errs: []error{rateLimited, rateLimited, rateLimited}}
+	fallback := &fakeChat{id: "fallback"}
+	model := NewChat(router, primary, fallback)
+
+	for i := 0; i < 3; i++ {
+		resp, err := model.Chat(context.Background(), nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "answer from fallback", resp.Content)
+	}
+	// The third request skipped the primary model
+	assert.Equal(t, 2, primary.calls)
+	assert.False(t, breakers.Allow("primary", time.Now()))
+	assert.True(t, breakers.Allow("primary", time.Now().Add(time.Minute)))
+
+	breakers.Success("primary")
+	assert.True(t, breakers.Allow("primary", time.Now()))
+}
+
+func TestRouterStopsWhenContextIsDone(t *testing.T) {
+	var waits []time.Duration
+	router := newTestRouter(NewBreakers(), &types.ModelRoutingConfig{}, &waits)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	primary := &fakeChat{id: "primary", errs: []error{&openai.APIError{HTTPStatusCode: 500}}}
+	fallback := &fakeChat{id: "fallback"}
+	_, err := NewChat(router, primary, fallback).Chat(ctx, nil, nil)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrAllModelsFailed)
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 0, fallback.calls)
+}
+
+func TestChatStreamFailsOverBeforeFirstResponse(t *testing.T) {
+	var waits []time.Duration
+	router := newTestRouter(NewBreakers(), &types.ModelRoutingConfig{
+		Retry: types.ModelRetryPolicy{MaxRetries: -1},
+	}, &waits)
+
+	primary := &fakeChat{id: "primary", stream: []types.StreamResponse{
+		{ResponseType: types.ResponseTypeError, Content: "stream error: status 502", Done: true},
+	}}
+	fallback := &fakeChat{id: "fallback", stream: []types.StreamResponse{
+		{ResponseType: types.ResponseTypeAnswer, Content: "Hello"},
+		{ResponseType: types.ResponseTypeAnswer, Content: " world", Done: true},
+	}}
+	stream, err := NewChat(router, primary, fallback).ChatStream(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	var answer string
+	for response := range stream {
+		answer += response.Content
+	}
+	assert.Equal(t, "Hello world", answer)
+}
+
+// fakeEmbedder embeds every text as the same vector
+type fakeEmbedder struct {
+	embedding.EmbedderPooler
+	id    string
+	err   error
+	calls int
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []float32{1, 0}, nil
+}
+
+func (f *fakeEmbedder) BatchEmbed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := f.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (f *fakeEmbedder) GetModelName() string { return f.id }
+func (f *fakeEmbedder) GetDimensions() int   { return 2 }
+func (f *fakeEmbedder) GetModelID() string   { return f.id }
+
+func TestEmbedderFailsOver(t *testing.T) {
+	var waits []time.Duration
+	router := newTestRouter(NewBreakers(), &types.ModelRoutingConfig{
+		Retry: types.ModelRetryPolicy{MaxRetries: 1},
+	}, &waits)
+
+	primary := &fakeEmbedder{id: "primary", err: errors.New("request timed out")}
+	fallback := &fakeEmbedder{id: "fallback"}
+	embedder := NewEmbedder(router, primary, fallback)
+
+	vectors, err := embedder.BatchEmbed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Len(t, vectors, 2)
+	assert.Equal(t, 2, primary.calls)
+	// Vectors are stored under the primary model whichever model embedded them
+	assert.Equal(t, "primary", embedder.GetModelID())
+	assert.Equal(t, 2, embedder.GetDimensions())
+}
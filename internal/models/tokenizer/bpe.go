@@ -0,0 +1,215 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pre-tokenization patterns of the encodings. Go regexps have no lookahead, so the
+// `\s+(?!\S)` alternative of the original patterns is emulated by split.
+const (
+	cl100kPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|` +
+		` ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+	o200kPattern = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|` +
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|` +
+		`\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`
+	qwenPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}|` +
+		` ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+)
+
+// noRank marks byte sequences that are not tokens
+const noRank = math.MaxInt
+
+// BPE is a byte-level byte pair encoding tokenizer reading tiktoken vocabularies
+type BPE struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// LoadBPE loads a tiktoken vocabulary file
+func LoadBPE(name, path, pattern string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewBPE(name, f, pattern)
+}
+
+// NewBPE reads a tiktoken vocabulary: one token per line, base64 encoded and followed
+// by its rank. pattern splits text into the pieces that are encoded separately.
+func NewBPE(name string, r io.Reader, pattern string) (*BPE, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern of tokenizer %s: %w", name, err)
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("tokenizer %s line %d: expected token and rank", name, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s line %d: %w", name, line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer %s line %d: %w", name, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read tokenizer %s: %w", name, err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("tokenizer %s has no tokens", name)
+	}
+	return &BPE{name: name, ranks: ranks, pattern: re}, nil
+}
+
+// Name returns the name of the encoding
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count returns the number of tokens in text
+func (b *BPE) Count(text string) int {
+	count := 0
+	for _, piece := range b.split(text) {
+		count += len(b.merge(text[piece[0]:piece[1]])) - 1
+	}
+	return count
+}
+
+// Truncate returns the longest prefix of text of at most maxTokens tokens, cut at a
+// character boundary
+func (b *BPE) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	count := 0
+	for _, piece := range b.split(text) {
+		boundaries := b.merge(text[piece[0]:piece[1]])
+		if count+len(boundaries)-1 <= maxTokens {
+			count += len(boundaries) - 1
+			continue
+		}
+		cut := piece[0] + boundaries[maxTokens-count]
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		return text[:cut]
+	}
+	return text
+}
+
+// split returns the byte ranges of the pieces of text
+func (b *BPE) split(text string) [][2]int {
+	var pieces [][2]int
+	for pos := 0; pos < len(text); {
+		loc := b.pattern.FindStringIndex(text[pos:])
+		if loc == nil {
+			pieces = append(pieces, [2]int{pos, len(text)})
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if start > pos {
+			// Every character is matched by the patterns, but keep unmatched text counted
+			pieces = append(pieces, [2]int{pos, start})
+		}
+		// `\s+(?!\S)`: whitespace followed by text leaves its last character to that text,
+		// unless it ends a line
+		if end < len(text) && isSpaces(text[start:end]) {
+			last, size := utf8.DecodeLastRuneInString(text[start:end])
+			if last != '\r' && last != '\n' && end-size > start {
+				end -= size
+			}
+		}
+		if end == start {
+			end = start + 1
+		}
+		pieces = append(pieces, [2]int{start, end})
+		pos = end
+	}
+	return pieces
+}
+
+// merge encodes a piece and returns the boundaries of its tokens
+func (b *BPE) merge(piece string) []int {
+	if _, ok := b.ranks[piece]; ok {
+		return []int{0, len(piece)}
+	}
+
+	type part struct {
+		start int
+		rank  int
+	}
+	parts := make([]part, 0, len(piece)+1)
+	for i := 0; i <= len(piece); i++ {
+		parts = append(parts, part{start: i, rank: noRank})
+	}
+	// rank returns the rank of the token merging parts i and i+1
+	rank := func(i int) int {
+		if i+2 < len(parts) {
+			if r, ok := b.ranks[piece[parts[i].start:parts[i+2].start]]; ok {
+				return r
+			}
+		}
+		return noRank
+	}
+	for i := 0; i < len(parts)-2; i++ {
+		parts[i].rank = rank(i)
+	}
+
+	// Merge the pair of lowest rank until no pair is a token
+	for {
+		best, minRank := -1, noRank
+		for i := 0; i < len(parts)-1; i++ {
+			if parts[i].rank < minRank {
+				best, minRank = i, parts[i].rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+		parts[best].rank = rank(best)
+		if best > 0 {
+			parts[best-1].rank = rank(best - 1)
+		}
+	}
+
+	boundaries := make([]int, len(parts))
+	for i, p := range parts {
+		boundaries[i] = p.start
+	}
+	return boundaries
+}
+
+// isSpaces reports whether text only contains whitespace
+func isSpaces(text string) bool {
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
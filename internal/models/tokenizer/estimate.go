@@ -0,0 +1,57 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// estimator approximates BPE encodings without a vocabulary: every CJK character is a
+// token of its own, while other text averages 4 bytes per token
+type estimator struct{}
+
+// Estimator returns the tokenizer used when no vocabulary is available
+func Estimator() Tokenizer {
+	return estimator{}
+}
+
+// Name returns the name of the estimator
+func (estimator) Name() string {
+	return "estimate"
+}
+
+// Count estimates the number of tokens in text
+func (estimator) Count(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// Truncate returns the longest prefix of text estimated at most maxTokens tokens
+func (estimator) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	cjk, other := 0, 0
+	for i, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+		if cjk+(other+3)/4 > maxTokens {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// isCJK reports whether r is a Han, Hiragana, Katakana or Hangul character
+func isCJK(r rune) bool {
+	return r >= 0x2E80 && unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,159 @@
+// Package tokenizer counts and truncates text in the tokens of the models that read it
+package tokenizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Tencent/WeKnora/internal/logger"
+)
+
+// Tokenizer counts text in model tokens
+type Tokenizer interface {
+	// Name returns the name of the encoding
+	Name() string
+	// Count returns the number of tokens in text
+	Count(text string) int
+	// Truncate returns the longest prefix of text of at most maxTokens tokens
+	Truncate(text string, maxTokens int) string
+}
+
+// Encoding names
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+	EncodingQwen   = "qwen"
+)
+
+// defaultDir is where vocabularies are looked up when TOKENIZER_DIR is not set
+const defaultDir = "config/tokenizers"
+
+// encoding is a BPE vocabulary loaded from <dir>/<name>.tiktoken on first use
+type encoding struct {
+	name    string
+	pattern string
+
+	once      sync.Once
+	tokenizer Tokenizer
+}
+
+var encodings = map[string]*encoding{
+	EncodingCL100K: {name: EncodingCL100K, pattern: cl100kPattern},
+	EncodingO200K:  {name: EncodingO200K, pattern: o200kPattern},
+	EncodingQwen:   {name: EncodingQwen, pattern: qwenPattern},
+}
+
+// modelEncodings maps model name prefixes to encodings, checked in order
+var modelEncodings = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", EncodingO200K},
+	{"chatgpt-4o", EncodingO200K},
+	{"gpt-4.1", EncodingO200K},
+	{"gpt-4.5", EncodingO200K},
+	{"gpt-5", EncodingO200K},
+	{"o1", EncodingO200K},
+	{"o3", EncodingO200K},
+	{"o4", EncodingO200K},
+	{"gpt-4", EncodingCL100K},
+	{"gpt-3.5", EncodingCL100K},
+	{"text-embedding-", EncodingCL100K},
+	// Chinese model families tokenize Chinese far closer to Qwen than to OpenAI encodings
+	{"qwen", EncodingQwen},
+	{"qwq", EncodingQwen},
+	{"deepseek", EncodingQwen},
+	{"glm", EncodingQwen},
+	{"chatglm", EncodingQwen},
+	{"hunyuan", EncodingQwen},
+	{"moonshot", EncodingQwen},
+	{"kimi", EncodingQwen},
+	{"yi-", EncodingQwen},
+	{"baichuan", EncodingQwen},
+	{"internlm", EncodingQwen},
+}
+
+// Dir returns the directory vocabularies are loaded from
+func Dir() string {
+	if dir := os.Getenv("TOKENIZER_DIR"); dir != "" {
+		return dir
+	}
+	return defaultDir
+}
+
+// EncodingForModel returns the name of the encoding used for a model
+func EncodingForModel(modelName string) string {
+	name := strings.ToLower(strings.TrimSpace(modelName))
+	// Drop the organization of names like Qwen/Qwen2.5-7B-Instruct
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, m := range modelEncodings {
+		if strings.HasPrefix(name, m.prefix) {
+			return m.encoding
+		}
+	}
+	return EncodingCL100K
+}
+
+// ForModel returns the tokenizer of a model; models of unknown families use cl100k_base.
+// When the vocabulary is not installed, token counts are estimated.
+func ForModel(modelName string) Tokenizer {
+	return encodings[EncodingForModel(modelName)].load()
+}
+
+// Default returns the tokenizer used when the model is not known
+func Default() Tokenizer {
+	return encodings[EncodingCL100K].load()
+}
+
+// load loads the vocabulary of the encoding, falling back to estimation when it is missing
+func (e *encoding) load() Tokenizer {
+	e.once.Do(func() {
+		path := filepath.Join(Dir(), e.name+".tiktoken")
+		bpe, err := LoadBPE(e.name, path, e.pattern)
+		if err != nil {
+			logger.Warnf(context.Background(),
+				"Tokenizer %s not available (%v), token counts are estimated and context limits may be exceeded; "+
+					"run scripts/download_tokenizers.sh to install it", e.name, err)
+			e.tokenizer = Estimator()
+			return
+		}
+		e.tokenizer = bpe
+	})
+	return e.tokenizer
+}
+
+// Missing returns the encodings whose vocabulary is not installed, sorted by name
+func Missing() []string {
+	var missing []string
+	for name := range encodings {
+		if info, err := os.Stat(filepath.Join(Dir(), name+".tiktoken")); err != nil || info.Size() == 0 {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// WarnMissing logs a warning when vocabularies are not installed, so that deployments relying on
+// estimated token counts are noticed at startup rather than at the first request
+func WarnMissing(ctx context.Context) {
+	if missing := Missing(); len(missing) > 0 {
+		logger.Warnf(ctx, "Tokenizer vocabularies %s not found in %s, token counts will be estimated; "+
+			"run scripts/download_tokenizers.sh to install them", strings.Join(missing, ", "), Dir())
+	}
+}
+
+// CountAll returns the total number of tokens in texts
+func CountAll(t Tokenizer, texts ...string) int {
+	total := 0
+	for _, text := range texts {
+		total += t.Count(text)
+	}
+	return total
+}
@@ -0,0 +1,108 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTiny(t *testing.T) *BPE {
+	t.Helper()
+	bpe, err := LoadBPE("tiny", "testdata/tiny.tiktoken", cl100kPattern)
+	require.NoError(t, err)
+	return bpe
+}
+
+func TestBPECount(t *testing.T) {
+	bpe := loadTiny(t)
+
+	assert.Equal(t, 0, bpe.Count(""))
+	// "hello" and " world" are tokens of their own
+	assert.Equal(t, 2, bpe.Count("hello world"))
+	// "he" + "ll" merge into "hello" through "llo"; "help" has no merge past "he"
+	assert.Equal(t, 3, bpe.Count("help"))
+	// 你 is a single token, 好 is encoded as its three bytes
+	assert.Equal(t, 4, bpe.Count("你好"))
+}
+
+func TestBPESplit(t *testing.T) {
+	bpe := loadTiny(t)
+
+	pieces := func(text string) []string {
+		var result []string
+		for _, p := range bpe.split(text) {
+			result = append(result, text[p[0]:p[1]])
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"hello", " world"}, pieces("hello world"))
+	// Whitespace before a word leaves its last space to the word
+	assert.Equal(t, []string{"hello", "  ", " world"}, pieces("hello   world"))
+	assert.Equal(t, []string{"a", "\n\n", "b"}, pieces("a\n\nb"))
+	assert.Equal(t, []string{"a", "  "}, pieces("a  "))
+	assert.Equal(t, []string{"123", "4", ",", " it", "'s"}, pieces("1234, it's"))
+}
+
+func TestBPETruncate(t *testing.T) {
+	bpe := loadTiny(t)
+
+	assert.Equal(t, "hello", bpe.Truncate("hello world", 1))
+	assert.Equal(t, "hello world", bpe.Truncate("hello world", 2))
+	assert.Equal(t, "", bpe.Truncate("hello world", 0))
+	// Cutting inside the bytes of a character drops the whole character
+	assert.Equal(t, "你", bpe.Truncate("你好", 2))
+	assert.Equal(t, "你", bpe.Truncate("你好", 3))
+	assert.Equal(t, "你好", bpe.Truncate("你好", 4))
+}
+
+func TestEstimator(t *testing.T) {
+	e := Estimator()
+
+	assert.Equal(t, 4, e.Count("你好世界"))
+	assert.Equal(t, 2, e.Count("abcdefgh"))
+	assert.Equal(t, 3, e.Count("abcdefghi"))
+	assert.Equal(t, 3, e.Count("你好abc"))
+
+	assert.Equal(t, "你好", e.Truncate("你好世界", 2))
+	assert.Equal(t, "abcdefgh", e.Truncate("abcdefghijkl", 2))
+	assert.Equal(t, "你好abcd", e.Truncate("你好abcdefgh", 3))
+}
+
+func TestEncodingForModel(t *testing.T) {
+	assert.Equal(t, EncodingO200K, EncodingForModel("gpt-4o-mini"))
+	assert.Equal(t, EncodingO200K, EncodingForModel("o3-mini"))
+	assert.Equal(t, EncodingCL100K, EncodingForModel("gpt-4-turbo"))
+	assert.Equal(t, EncodingCL100K, EncodingForModel("text-embedding-3-small"))
+	assert.Equal(t, EncodingQwen, EncodingForModel("Qwen/Qwen2.5-7B-Instruct"))
+	assert.Equal(t, EncodingQwen, EncodingForModel("qwen3:8b"))
+	assert.Equal(t, EncodingQwen, EncodingForModel("deepseek-chat"))
+	assert.Equal(t, EncodingCL100K, EncodingForModel("llama3.1:8b"))
+}
+
+func TestForModelWithoutVocabulary(t *testing.T) {
+	t.Setenv("TOKENIZER_DIR", t.TempDir())
+	e := &encoding{name: EncodingCL100K, pattern: cl100kPattern}
+	assert.Equal(t, "estimate", e.load().Name())
+}
+
+func TestForModelWithVocabulary(t *testing.T) {
+	t.Setenv("TOKENIZER_DIR", "testdata")
+	e := &encoding{name: "tiny", pattern: cl100kPattern}
+	tok := e.load()
+	assert.Equal(t, "tiny", tok.Name())
+	assert.Equal(t, 2, tok.Count("hello world"))
+}
+
+func TestMissing(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TOKENIZER_DIR", dir)
+	assert.Equal(t, []string{EncodingCL100K, EncodingO200K, EncodingQwen}, Missing())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, EncodingO200K+".tiktoken"), []byte("aGk= 0\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, EncodingQwen+".tiktoken"), nil, 0o644))
+	assert.Equal(t, []string{EncodingCL100K, EncodingQwen}, Missing())
+}
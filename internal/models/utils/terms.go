@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Terms splits text into lowercase terms: runs of letters and digits, with each
+// Han, Hiragana, Katakana or Hangul character being a term of its own
+func Terms(text string) []string {
+	var terms []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			terms = append(terms, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
@@ -0,0 +1,141 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BodyArgument is the tool argument holding the JSON request body of an operation
+const BodyArgument = "body"
+
+// DefaultMaxResponseBytes bounds the response body returned to the agent when no limit is configured
+const DefaultMaxResponseBytes = 16 * 1024
+
+// Response is the outcome of an operation call
+type Response struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+	// Whether Body was cut at the response size limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Invoke calls the operation on baseURL with the tool arguments. Headers (e.g. authentication)
+// are added to each request. The response body is truncated to maxResponseBytes.
+func (o *Operation) Invoke(
+	ctx context.Context,
+	client *http.Client,
+	baseURL string,
+	headers map[string]string,
+	args map[string]interface{},
+	maxResponseBytes int,
+) (*Response, error) {
+	req, err := o.newRequest(ctx, baseURL, args)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s %s failed: %w", o.Method, o.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if maxResponseBytes <= 0 {
+		maxResponseBytes = DefaultMaxResponseBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxResponseBytes)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response of %s %s: %w", o.Method, o.Path, err)
+	}
+	result := &Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if len(body) > maxResponseBytes {
+		body = body[:maxResponseBytes]
+		result.Truncated = true
+	}
+	result.Body = strings.ToValidUTF8(string(body), "")
+	return result, nil
+}
+
+// newRequest builds the HTTP request of the operation from the tool arguments
+func (o *Operation) newRequest(ctx context.Context, baseURL string, args map[string]interface{}) (*http.Request, error) {
+	path := o.Path
+	query := url.Values{}
+	header := http.Header{}
+	for _, param := range o.Parameters {
+		value, ok := args[param.Name]
+		if !ok || value == nil {
+			if param.Required || param.In == "path" {
+				return nil, fmt.Errorf("missing required parameter %s", param.Name)
+			}
+			continue
+		}
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(formatValue(value)))
+		case "query":
+			if items, ok := value.([]interface{}); ok {
+				for _, item := range items {
+					query.Add(param.Name, formatValue(item))
+				}
+			} else {
+				query.Set(param.Name, formatValue(value))
+			}
+		case "header":
+			header.Set(param.Name, formatValue(value))
+		}
+	}
+
+	target := strings.TrimRight(baseURL, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if value, ok := args[BodyArgument]; ok && len(o.Body) > 0 {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	} else if o.BodyRequired {
+		return nil, fmt.Errorf("missing required argument %s", BodyArgument)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, o.Method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request %s %s: %w", o.Method, o.Path, err)
+	}
+	req.Header = header
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// formatValue formats a parameter value for a path, query string or header
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool, json.Number:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
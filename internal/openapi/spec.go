@@ -0,0 +1,322 @@
+// Package openapi parses OpenAPI 3 documents and invokes their operations,
+// so that HTTP APIs can be exposed to agents as tools
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	jsonschema "github.com/google/jsonschema-go/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// maxRefDepth bounds the inlining of nested $ref, deeper (or recursive) references become empty schemas
+const maxRefDepth = 8
+
+// httpMethods are the operations of a path item, in the order they are listed
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// invalidIDChars matches characters not allowed in generated operation IDs
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// Spec is a parsed OpenAPI 3 document
+type Spec struct {
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Version     string       `json:"version"`
+	Servers     []string     `json:"servers,omitempty"`
+	Operations  []*Operation `json:"operations"`
+}
+
+// Operation is an HTTP operation of the document
+type Operation struct {
+	ID          string      `json:"operation_id"`
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Summary     string      `json:"summary,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Deprecated  bool        `json:"deprecated,omitempty"`
+	Parameters  []Parameter `json:"parameters,omitempty"`
+	// JSON request body schema, nil when the operation takes no JSON body
+	Body         json.RawMessage `json:"body,omitempty"`
+	BodyRequired bool            `json:"body_required,omitempty"`
+}
+
+// Parameter is a path, query or header parameter of an operation
+type Parameter struct {
+	Name        string          `json:"name"`
+	In          string          `json:"in"`
+	Description string          `json:"description,omitempty"`
+	Required    bool            `json:"required"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+}
+
+// document mirrors the parts of an OpenAPI 3 document used to build operations
+type document struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Version     string `json:"version"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// operationObject is an operation of a path item
+type operationObject struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	Deprecated  bool        `json:"deprecated"`
+	Parameters  []Parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema json.RawMessage `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+// Parse parses an OpenAPI 3 document in JSON or YAML. Local references ("#/components/...")
+// are inlined so that each operation is self-contained.
+func Parse(data []byte) (*Spec, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	root, ok := normalize(raw).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid OpenAPI document: not an object")
+	}
+	resolved, err := json.Marshal(inlineRefs(root, root, 0))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	var doc document
+	if err := json.Unmarshal(resolved, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, only 3.x is supported", doc.OpenAPI)
+	}
+
+	spec := &Spec{
+		Title:       doc.Info.Title,
+		Description: doc.Info.Description,
+		Version:     doc.Info.Version,
+	}
+	for _, server := range doc.Servers {
+		spec.Servers = append(spec.Servers, server.URL)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		item := doc.Paths[path]
+		// Parameters declared on the path item apply to all its operations
+		var shared []Parameter
+		if rawParams, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(rawParams, &shared); err != nil {
+				return nil, fmt.Errorf("invalid parameters of path %s: %w", path, err)
+			}
+		}
+		for _, method := range httpMethods {
+			rawOp, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op operationObject
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("invalid operation %s %s: %w", strings.ToUpper(method), path, err)
+			}
+			operation := newOperation(method, path, &op, shared)
+			if seen[operation.ID] {
+				return nil, fmt.Errorf("duplicate operation ID %s", operation.ID)
+			}
+			seen[operation.ID] = true
+			spec.Operations = append(spec.Operations, operation)
+		}
+	}
+	return spec, nil
+}
+
+// Operation returns the operation with the given ID, nil if the document has none
+func (s *Spec) Operation(id string) *Operation {
+	for _, op := range s.Operations {
+		if op.ID == id {
+			return op
+		}
+	}
+	return nil
+}
+
+// newOperation builds an operation, merging the path item parameters it does not override
+func newOperation(method, path string, op *operationObject, shared []Parameter) *Operation {
+	id := op.OperationID
+	if id == "" {
+		id = method + "_" + strings.Trim(invalidIDChars.ReplaceAllString(path, "_"), "_")
+	}
+	operation := &Operation{
+		ID:          id,
+		Method:      strings.ToUpper(method),
+		Path:        path,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+	}
+
+	for _, param := range op.Parameters {
+		if param.In != "cookie" {
+			operation.Parameters = append(operation.Parameters, param)
+		}
+	}
+	for _, param := range shared {
+		if param.In != "cookie" && !containsParam(operation.Parameters, param) {
+			operation.Parameters = append(operation.Parameters, param)
+		}
+	}
+
+	if op.RequestBody != nil {
+		for contentType, media := range op.RequestBody.Content {
+			if strings.Contains(contentType, "json") {
+				operation.Body = media.Schema
+				operation.BodyRequired = op.RequestBody.Required
+				break
+			}
+		}
+	}
+	return operation
+}
+
+// containsParam reports whether the parameter is already in the list
+func containsParam(params []Parameter, param Parameter) bool {
+	for _, p := range params {
+		if p.In == param.In && p.Name == param.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// InputSchema returns the JSON schema of the tool arguments of the operation: one property per
+// parameter, and a "body" property holding the JSON request body
+func (o *Operation) InputSchema() (json.RawMessage, error) {
+	schema := &jsonschema.Schema{
+		Type:       "object",
+		Properties: make(map[string]*jsonschema.Schema),
+	}
+	for _, param := range o.Parameters {
+		schema.Properties[param.Name] = toSchema(param.Schema, param.Description)
+		if param.Required || param.In == "path" {
+			schema.Required = append(schema.Required, param.Name)
+		}
+	}
+	if len(o.Body) > 0 {
+		schema.Properties[BodyArgument] = toSchema(o.Body, "JSON request body")
+		if o.BodyRequired {
+			schema.Required = append(schema.Required, BodyArgument)
+		}
+	}
+	return json.Marshal(schema)
+}
+
+// toSchema converts an OpenAPI schema object to a JSON schema. Schemas using OpenAPI 3.0
+// keywords that are not valid JSON schema fall back to an unconstrained schema.
+func toSchema(raw json.RawMessage, description string) *jsonschema.Schema {
+	schema := &jsonschema.Schema{}
+	if len(raw) == 0 {
+		schema.Type = "string"
+	} else if err := json.Unmarshal(raw, schema); err != nil {
+		schema = &jsonschema.Schema{}
+	}
+	if schema.Description == "" {
+		schema.Description = description
+	}
+	return schema
+}
+
+// normalize converts YAML maps with non-string keys (e.g. response codes) to JSON compatible maps
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalize(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// inlineRefs replaces local references by a copy of the referenced object
+func inlineRefs(value interface{}, root map[string]interface{}, depth int) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if depth >= maxRefDepth {
+				return map[string]interface{}{}
+			}
+			target, ok := lookupRef(root, ref)
+			if !ok {
+				return map[string]interface{}{}
+			}
+			return inlineRefs(target, root, depth+1)
+		}
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = inlineRefs(item, root, depth)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = inlineRefs(item, root, depth)
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+// lookupRef resolves a local JSON pointer such as "#/components/schemas/Pet"
+func lookupRef(root map[string]interface{}, ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var node interface{} = root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[token]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
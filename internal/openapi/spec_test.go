@@ -0,0 +1,168 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const petStore = `
+openapi: 3.0.3
+info:
+  title: Pet Store
+  version: 1.0.0
+servers:
+  - url: https://pets.example.com/v1
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      operationId: getPet
+      summary: Get a pet
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+      responses:
+        200:
+          description: A pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+  /pets:
+    post:
+      summary: Create a pet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        201:
+          description: Created
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        tag:
+          type: string
+`
+
+func TestParse(t *testing.T) {
+	spec, err := Parse([]byte(petStore))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if spec.Title != "Pet Store" || len(spec.Servers) != 1 || spec.Servers[0] != "https://pets.example.com/v1" {
+		t.Errorf("unexpected document info: %+v", spec)
+	}
+	if len(spec.Operations) != 2 {
+		t.Fatalf("got %d operations, want 2", len(spec.Operations))
+	}
+
+	getPet := spec.Operation("getPet")
+	if getPet == nil {
+		t.Fatal("operation getPet not found")
+	}
+	if len(getPet.Parameters) != 2 {
+		t.Errorf("getPet has %d parameters, want 2 (including path item parameter)", len(getPet.Parameters))
+	}
+
+	// Operations without operationId get one from the method and path
+	createPet := spec.Operation("post_pets")
+	if createPet == nil {
+		t.Fatal("operation post_pets not found")
+	}
+	schema, err := createPet.InputSchema()
+	if err != nil {
+		t.Fatalf("InputSchema() error = %v", err)
+	}
+	var decoded struct {
+		Required   []string `json:"required"`
+		Properties map[string]struct {
+			Required []string `json:"required"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &decoded); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	if len(decoded.Required) != 1 || decoded.Required[0] != BodyArgument {
+		t.Errorf("required = %v, want [body]", decoded.Required)
+	}
+	if body := decoded.Properties[BodyArgument]; len(body.Required) != 1 || body.Required[0] != "name" {
+		t.Errorf("body schema not resolved from $ref: %s", schema)
+	}
+}
+
+func TestParseRejectsSwagger2(t *testing.T) {
+	if _, err := Parse([]byte(`{"swagger": "2.0", "paths": {}}`)); err == nil {
+		t.Error("Parse() should reject Swagger 2.0 documents")
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/pets/42":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"Rex","fields":"` + r.URL.Query().Get("fields") + `"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/pets":
+			body, _ := io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(strings.Repeat(string(body), 10)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	spec, err := Parse([]byte(petStore))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	headers := map[string]string{"Authorization": "Bearer secret"}
+	ctx := context.Background()
+
+	resp, err := spec.Operation("getPet").Invoke(ctx, server.Client(), server.URL+"/v1", headers,
+		map[string]interface{}{"petId": float64(42), "fields": "name"}, 0)
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Body != `{"name":"Rex","fields":"name"}` {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	resp, err = spec.Operation("post_pets").Invoke(ctx, server.Client(), server.URL+"/v1", headers,
+		map[string]interface{}{BodyArgument: map[string]interface{}{"name": "Rex"}}, 20)
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated || !resp.Truncated || len(resp.Body) != 20 {
+		t.Errorf("response should be truncated to 20 bytes: %+v", resp)
+	}
+
+	if _, err := spec.Operation("getPet").Invoke(ctx, server.Client(), server.URL+"/v1", headers,
+		map[string]interface{}{}, 0); err == nil {
+		t.Error("Invoke() should fail without the path parameter")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/Tencent/WeKnora/internal/config"
 	"github.com/Tencent/WeKnora/internal/handler"
 	"github.com/Tencent/WeKnora/internal/handler/session"
+	"github.com/Tencent/WeKnora/internal/mcpserver"
 	"github.com/Tencent/WeKnora/internal/middleware"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 
@@ -39,15 +40,20 @@ type RouterParams struct {
 	SessionHandler        *session.Handler
 	MessageHandler        *handler.MessageHandler
 	ModelHandler          *handler.ModelHandler
+	ModelUsageHandler     *handler.ModelUsageHandler
 	EvaluationHandler     *handler.EvaluationHandler
 	AuthHandler           *handler.AuthHandler
 	InitializationHandler *handler.InitializationHandler
 	SystemHandler         *handler.SystemHandler
 	MCPServiceHandler     *handler.MCPServiceHandler
+	MCPOAuthHandler       *handler.MCPOAuthHandler
+	OpenAPIServiceHandler *handler.OpenAPIServiceHandler
 	WebSearchHandler      *handler.WebSearchHandler
 	FAQHandler            *handler.FAQHandler
 	TagHandler            *handler.TagHandler
 	CustomAgentHandler    *handler.CustomAgentHandler
+	AgentScheduleHandler  *handler.AgentScheduleHandler
+	MCPServer             *mcpserver.Server
 }
 
 // NewRouter creates a new router
@@ -106,12 +112,17 @@ func NewRouter(params RouterParams) *gin.Engine {
 		RegisterChatRoutes(v1, params.SessionHandler)
 		RegisterMessageRoutes(v1, params.MessageHandler)
 		RegisterModelRoutes(v1, params.ModelHandler)
+		RegisterModelUsageRoutes(v1, params.ModelUsageHandler)
 		RegisterEvaluationRoutes(v1, params.EvaluationHandler)
 		RegisterInitializationRoutes(v1, params.InitializationHandler)
 		RegisterSystemRoutes(v1, params.SystemHandler)
 		RegisterMCPServiceRoutes(v1, params.MCPServiceHandler)
+		RegisterMCPOAuthRoutes(v1, params.MCPOAuthHandler)
+		RegisterOpenAPIServiceRoutes(v1, params.OpenAPIServiceHandler)
 		RegisterWebSearchRoutes(v1, params.WebSearchHandler)
 		RegisterCustomAgentRoutes(v1, params.CustomAgentHandler)
+		RegisterAgentScheduleRoutes(v1, params.AgentScheduleHandler)
+		RegisterMCPServerRoutes(v1, params.MCPServer)
 	}
 
 	return r
@@ -263,6 +274,14 @@ func RegisterSessionRoutes(r *gin.RouterGroup, handler *session.Handler) {
 		sessions.DELETE("/:id", handler.DeleteSession)
 		sessions.POST("/:session_id/generate_title", handler.GenerateTitle)
 		sessions.POST("/:session_id/stop", handler.StopSession)
+		// 工具调用人工审批
+		sessions.GET("/:session_id/approvals", handler.ListToolApprovals)
+		sessions.POST("/:session_id/approvals/:approval_id", handler.DecideToolApproval)
+		// 智能体跨轮次保留的研究计划与关键发现
+		sessions.GET("/:session_id/scratchpad", handler.GetAgentScratchpad)
+		sessions.DELETE("/:session_id/scratchpad", handler.ClearAgentScratchpad)
+		// 恢复中断的智能体执行
+		sessions.POST("/:session_id/messages/:message_id/resume", handler.ResumeAgentRun)
 		// 继续接收活跃流
 		sessions.GET("/continue-stream/:session_id", handler.ContinueStream)
 	}
@@ -330,11 +349,23 @@ func RegisterModelRoutes(r *gin.RouterGroup, handler *handler.ModelHandler) {
 	}
 }
 
+// RegisterModelUsageRoutes registers the model usage and token quota routes
+func RegisterModelUsageRoutes(r *gin.RouterGroup, handler *handler.ModelUsageHandler) {
+	usage := r.Group("/usage")
+	{
+		// Usage statistics by day, model, agent or source
+		usage.GET("/stats", handler.GetUsageStats)
+		// Monthly token quota and tokens used this month
+		usage.GET("/quota", handler.GetTokenQuota)
+	}
+}
+
 func RegisterEvaluationRoutes(r *gin.RouterGroup, handler *handler.EvaluationHandler) {
 	evaluationRoutes := r.Group("/evaluation")
 	{
 		evaluationRoutes.POST("/", handler.Evaluation)
 		evaluationRoutes.GET("/", handler.GetEvaluationResult)
+		evaluationRoutes.POST("/agent", handler.AgentEvaluation)
 	}
 }
 
@@ -403,6 +434,63 @@ func RegisterMCPServiceRoutes(r *gin.RouterGroup, handler *handler.MCPServiceHan
 		mcpServices.GET("/:id/tools", handler.GetMCPServiceTools)
 		// Get MCP service resources
 		mcpServices.GET("/:id/resources", handler.GetMCPServiceResources)
+		// Get MCP service prompts
+		mcpServices.GET("/:id/prompts", handler.GetMCPServicePrompts)
+	}
+}
+
+// RegisterMCPOAuthRoutes registers MCP service OAuth authorization routes
+func RegisterMCPOAuthRoutes(r *gin.RouterGroup, handler *handler.MCPOAuthHandler) {
+	mcpServices := r.Group("/mcp-services")
+	{
+		// Start the authorization of an MCP service
+		mcpServices.GET("/:id/oauth/authorize", handler.AuthorizeMCPService)
+		// Get the authorization status of an MCP service
+		mcpServices.GET("/:id/oauth", handler.GetMCPOAuthStatus)
+		// Revoke the authorization of an MCP service
+		mcpServices.DELETE("/:id/oauth", handler.RevokeMCPOAuth)
+	}
+	// Callback of the authorization server, reached without credentials
+	r.GET("/mcp-oauth/callback", handler.MCPOAuthCallback)
+}
+
+// RegisterOpenAPIServiceRoutes registers OpenAPI service routes
+func RegisterOpenAPIServiceRoutes(r *gin.RouterGroup, handler *handler.OpenAPIServiceHandler) {
+	openAPIServices := r.Group("/openapi-services")
+	{
+		// Create OpenAPI service from a document
+		openAPIServices.POST("", handler.CreateOpenAPIService)
+		// List OpenAPI services
+		openAPIServices.GET("", handler.ListOpenAPIServices)
+		// Get OpenAPI service by ID
+		openAPIServices.GET("/:id", handler.GetOpenAPIService)
+		// Update OpenAPI service
+		openAPIServices.PUT("/:id", handler.UpdateOpenAPIService)
+		// Delete OpenAPI service
+		openAPIServices.DELETE("/:id", handler.DeleteOpenAPIService)
+		// List the operations of the document
+		openAPIServices.GET("/:id/operations", handler.ListOpenAPIOperations)
+	}
+}
+
+// RegisterAgentScheduleRoutes registers agent schedule routes
+func RegisterAgentScheduleRoutes(r *gin.RouterGroup, handler *handler.AgentScheduleHandler) {
+	schedules := r.Group("/agent-schedules")
+	{
+		// Create agent schedule
+		schedules.POST("", handler.CreateAgentSchedule)
+		// List agent schedules
+		schedules.GET("", handler.ListAgentSchedules)
+		// Get agent schedule by ID
+		schedules.GET("/:id", handler.GetAgentSchedule)
+		// Update agent schedule
+		schedules.PUT("/:id", handler.UpdateAgentSchedule)
+		// Delete agent schedule
+		schedules.DELETE("/:id", handler.DeleteAgentSchedule)
+		// Start a run right away
+		schedules.POST("/:id/run", handler.TriggerAgentSchedule)
+		// Get run history
+		schedules.GET("/:id/runs", handler.ListAgentScheduleRuns)
 	}
 }
 
@@ -434,5 +522,17 @@ func RegisterCustomAgentRoutes(r *gin.RouterGroup, agentHandler *handler.CustomA
 		agents.DELETE("/:id", agentHandler.DeleteAgent)
 		// Copy agent
 		agents.POST("/:id/copy", agentHandler.CopyAgent)
+		// Export agent as a YAML bundle
+		agents.GET("/:id/export", agentHandler.ExportAgent)
+		// Import agent from a YAML bundle
+		agents.POST("/import", agentHandler.ImportAgent)
 	}
 }
+
+// RegisterMCPServerRoutes serves the knowledge bases of the tenant to MCP clients over streamable HTTP
+func RegisterMCPServerRoutes(r *gin.RouterGroup, server *mcpserver.Server) {
+	mcp := gin.WrapH(server.Handler())
+	r.POST("/mcp", mcp)
+	r.GET("/mcp", mcp)
+	r.DELETE("/mcp", mcp)
+}
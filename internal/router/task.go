@@ -1,11 +1,13 @@
 package router
 
 import (
+	"context"
 	"log"
 	"os"
 	"strconv"
 	"time"
 
+	"github.com/Tencent/WeKnora/internal/handler/session"
 	"github.com/Tencent/WeKnora/internal/types"
 	"github.com/Tencent/WeKnora/internal/types/interfaces"
 	"github.com/hibiken/asynq"
@@ -21,6 +23,11 @@ type AsynqTaskParams struct {
 	TagService           interfaces.KnowledgeTagService
 	ChunkExtracter       interfaces.TaskHandler `name:"chunkExtracter"`
 	DataTableSummary     interfaces.TaskHandler `name:"dataTableSummary"`
+
+	SessionHandler         *session.Handler
+	AgentCheckpointService interfaces.AgentCheckpointService
+	AgentScheduleService   interfaces.AgentScheduleService
+	Cleaner                interfaces.ResourceCleaner
 }
 
 func getAsynqRedisClientOpt() *asynq.RedisClientOpt {
@@ -94,11 +101,30 @@ func RunAsynqServer(params AsynqTaskParams) *asynq.ServeMux {
 	// Register KB delete handler
 	mux.HandleFunc(types.TypeKBDelete, params.KnowledgeBaseService.ProcessKBDelete)
 
+	// Register agent resume handler
+	mux.HandleFunc(types.TypeAgentResume, params.SessionHandler.ProcessAgentResume)
+
+	// Register scheduled agent run handler
+	mux.HandleFunc(types.TypeAgentScheduleRun, params.AgentScheduleService.ProcessScheduleRun)
+
 	go func() {
 		// Start the server
 		if err := params.Server.Run(mux); err != nil {
 			log.Fatalf("could not run server: %v", err)
 		}
 	}()
+
+	// Background loops stop when the application shuts down
+	loopCtx, stopLoops := context.WithCancel(context.Background())
+	params.Cleaner.RegisterWithName("AsynqBackgroundLoops", func() error {
+		stopLoops()
+		return nil
+	})
+
+	// Resume agent executions interrupted by a restart of this or another instance
+	go params.AgentCheckpointService.RunRecoverySweep(loopCtx)
+
+	// Start the runs of due agent schedules
+	go params.AgentScheduleService.RunScheduler(loopCtx)
 	return mux
 }
@@ -29,6 +29,29 @@ type AgentConfig struct {
 	// MCP service selection
 	MCPSelectionMode string   `json:"mcp_selection_mode"` // MCP selection mode: "all", "selected", "none"
 	MCPServices      []string `json:"mcp_services"`       // Selected MCP service IDs (when mode is "selected")
+	// Tool allowlists, denylists and argument policies by MCP service ID
+	MCPToolPolicies map[string]*MCPToolPolicy `json:"mcp_tool_policies,omitempty"`
+	// MCP resources attached as context and MCP prompt used as the system prompt
+	MCPResources []MCPResourceRef `json:"mcp_resources,omitempty"`
+	MCPPrompt    *MCPPromptRef    `json:"mcp_prompt,omitempty"`
+	// Content of the attached MCP resources (runtime only)
+	MCPResourceContexts []*MCPResourceContext `json:"-"`
+	// OpenAPI services whose selected operations are registered as tools
+	OpenAPIServices []string `json:"openapi_services"`
+	// Maximum tool calls of a round executed concurrently (0 or 1 executes them sequentially)
+	MaxParallelToolCalls int `json:"max_parallel_tool_calls"`
+	// Tool timeouts, call budgets and token budget of an execution (nil means unlimited)
+	ToolBudget *ToolBudgetConfig `json:"tool_budget,omitempty"`
+	// Tools requiring human approval before they run (nil means none)
+	ToolApproval *ToolApprovalConfig `json:"tool_approval,omitempty"`
+	// Custom agents this agent may delegate sub-tasks to (nil means none)
+	SubAgents *SubAgentConfig `json:"sub_agents,omitempty"`
+	// Runs delegated agents, set when SubAgents is active (runtime only)
+	SubAgentRunner SubAgentRunner `json:"-"`
+	// Plan and findings kept from earlier turns of the session (runtime only)
+	Scratchpad *AgentScratchpad `json:"-"`
+	// Skips checkpoints of executions that are never resumed (runtime only)
+	DisableCheckpoints bool `json:"-"`
 }
 
 // SessionAgentConfig represents session-level agent configuration
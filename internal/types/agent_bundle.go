@@ -0,0 +1,95 @@
+package types
+
+import "time"
+
+// AgentBundleVersion is the format version of the agent bundles written by this release
+const AgentBundleVersion = 1
+
+// AgentBundle is a portable YAML export of a custom agent, moved between tenants or environments.
+// Referenced models and knowledge bases are described so that they can be matched on import,
+// tool services are carried along without their secrets.
+type AgentBundle struct {
+	Version    int       `yaml:"version"     json:"version"`
+	ExportedAt time.Time `yaml:"exported_at" json:"exported_at"`
+
+	Agent AgentBundleAgent `yaml:"agent" json:"agent"`
+	// Prompt templates of the agent, keyed by config field (system_prompt, context_template, ...)
+	Prompts map[string]string `yaml:"prompts,omitempty" json:"prompts,omitempty"`
+
+	// Models referenced by the agent config
+	Models []AgentBundleRef `yaml:"models,omitempty" json:"models,omitempty"`
+	// Knowledge bases referenced by the agent config
+	KnowledgeBases []AgentBundleRef `yaml:"knowledge_bases,omitempty" json:"knowledge_bases,omitempty"`
+	// Agents the agent delegates sub-tasks to, only kept on import when they exist in the target tenant
+	SubAgents []AgentBundleRef `yaml:"sub_agents,omitempty" json:"sub_agents,omitempty"`
+
+	// MCP services selected by the agent, with secrets stripped
+	MCPServices []*AgentBundleMCPService `yaml:"mcp_services,omitempty" json:"mcp_services,omitempty"`
+	// OpenAPI services allowed for the agent, with auth headers stripped
+	OpenAPIServices []*AgentBundleOpenAPIService `yaml:"openapi_services,omitempty" json:"openapi_services,omitempty"`
+}
+
+// AgentBundleAgent holds the custom agent of a bundle
+type AgentBundleAgent struct {
+	ID          string            `yaml:"id"          json:"id"`
+	Name        string            `yaml:"name"        json:"name"`
+	Description string            `yaml:"description" json:"description"`
+	Avatar      string            `yaml:"avatar"      json:"avatar"`
+	Config      CustomAgentConfig `yaml:"config"      json:"config"`
+}
+
+// AgentBundleRef describes a resource referenced by ID in the agent config
+type AgentBundleRef struct {
+	ID   string `yaml:"id"             json:"id"`
+	Name string `yaml:"name"           json:"name"`
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+}
+
+// AgentBundleMCPService is an MCP service definition of a bundle.
+// Header and environment variable values and auth credentials are removed.
+type AgentBundleMCPService struct {
+	ID             string             `yaml:"id"                        json:"id"`
+	Name           string             `yaml:"name"                      json:"name"`
+	Description    string             `yaml:"description"               json:"description"`
+	TransportType  MCPTransportType   `yaml:"transport_type"            json:"transport_type"`
+	URL            *string            `yaml:"url,omitempty"             json:"url,omitempty"`
+	HeaderNames    []string           `yaml:"header_names,omitempty"    json:"header_names,omitempty"`
+	AdvancedConfig *MCPAdvancedConfig `yaml:"advanced_config,omitempty" json:"advanced_config,omitempty"`
+	StdioConfig    *MCPStdioConfig    `yaml:"stdio_config,omitempty"    json:"stdio_config,omitempty"`
+	EnvVarNames    []string           `yaml:"env_var_names,omitempty"   json:"env_var_names,omitempty"`
+	// Whether the service used auth credentials, which must be configured again after import
+	RequiresAuth bool `yaml:"requires_auth" json:"requires_auth"`
+}
+
+// AgentBundleOpenAPIService is an OpenAPI service definition of a bundle, without auth header values
+type AgentBundleOpenAPIService struct {
+	ID               string   `yaml:"id"                          json:"id"`
+	Name             string   `yaml:"name"                        json:"name"`
+	Description      string   `yaml:"description"                 json:"description"`
+	BaseURL          string   `yaml:"base_url"                    json:"base_url"`
+	Spec             string   `yaml:"spec"                        json:"spec"`
+	Operations       []string `yaml:"operations"                  json:"operations"`
+	AuthHeaderNames  []string `yaml:"auth_header_names,omitempty" json:"auth_header_names,omitempty"`
+	Timeout          int      `yaml:"timeout"                     json:"timeout"`
+	MaxResponseBytes int      `yaml:"max_response_bytes"          json:"max_response_bytes"`
+}
+
+// AgentImportOptions maps the references of a bundle to resources of the target tenant.
+// References without mapping are matched by ID, then by name.
+type AgentImportOptions struct {
+	// Bundle model ID -> model ID in the target tenant
+	ModelMapping map[string]string `json:"model_mapping"`
+	// Bundle knowledge base ID -> knowledge base ID in the target tenant
+	KnowledgeBaseMapping map[string]string `json:"knowledge_base_mapping"`
+}
+
+// AgentImportResult is the outcome of an agent import
+type AgentImportResult struct {
+	Agent           *CustomAgent      `json:"agent"`
+	MCPServices     []*MCPService     `json:"mcp_services,omitempty"`
+	OpenAPIServices []*OpenAPIService `json:"openapi_services,omitempty"`
+	// ID of each bundle resource -> ID of the resource it was imported as or mapped to
+	IDMapping map[string]string `json:"id_mapping"`
+	// Follow-up actions, e.g. credentials to configure on the imported services
+	Warnings []string `json:"warnings,omitempty"`
+}
@@ -0,0 +1,76 @@
+package types
+
+import "time"
+
+const (
+	// AgentCheckpointHeartbeatInterval is how often a running agent execution refreshes its checkpoint
+	AgentCheckpointHeartbeatInterval = 30 * time.Second
+	// AgentCheckpointStaleAfter is how long a running checkpoint may go without heartbeat
+	// before its execution is considered interrupted and may be resumed elsewhere
+	AgentCheckpointStaleAfter = 2 * time.Minute
+	// AgentCheckpointMaxAttempts bounds how many times an execution is started, so that an
+	// execution crashing its instance is not resumed forever
+	AgentCheckpointMaxAttempts = 3
+)
+
+// AgentRunStatus is the state of a checkpointed agent execution
+type AgentRunStatus string
+
+const (
+	AgentRunRunning   AgentRunStatus = "running"
+	AgentRunCompleted AgentRunStatus = "completed"
+	AgentRunFailed    AgentRunStatus = "failed"
+	AgentRunStopped   AgentRunStatus = "stopped"
+)
+
+// AgentCheckpoint is the persisted state of an agent execution, saved after each round so that
+// the execution can be resumed on another instance when the one running it goes away
+type AgentCheckpoint struct {
+	// Assistant message the execution answers, one checkpoint per message
+	MessageID string `json:"message_id" gorm:"type:varchar(36);primaryKey"`
+	TenantID  uint64 `json:"tenant_id"  gorm:"index"`
+	SessionID string `json:"session_id" gorm:"type:varchar(36);index"`
+
+	// Inputs needed to rebuild the agent engine
+	CustomAgentID    string      `json:"custom_agent_id"    gorm:"type:varchar(36)"`
+	Query            string      `json:"query"              gorm:"type:text"`
+	SummaryModelID   string      `json:"summary_model_id"   gorm:"type:varchar(64)"`
+	KnowledgeBaseIDs StringArray `json:"knowledge_base_ids" gorm:"type:json"`
+	KnowledgeIDs     StringArray `json:"knowledge_ids"      gorm:"type:json"`
+
+	// Progress of the execution: the LLM message history and the agent state after the last round
+	Round      int  `json:"round"`
+	Messages   JSON `json:"messages"    gorm:"type:json"`
+	State      JSON `json:"state"       gorm:"type:json"`
+	TokensUsed int  `json:"tokens_used"`
+
+	Status      AgentRunStatus `json:"status"       gorm:"type:varchar(16);index"`
+	Owner       string         `json:"owner"        gorm:"type:varchar(255)"` // Instance running the execution
+	Attempts    int            `json:"attempts"`
+	HeartbeatAt time.Time      `json:"heartbeat_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentCheckpoint
+func (AgentCheckpoint) TableName() string {
+	return "agent_checkpoints"
+}
+
+// IsResumable reports whether the execution stopped before completing and may be resumed
+func (c *AgentCheckpoint) IsResumable(now time.Time) bool {
+	switch c.Status {
+	case AgentRunFailed:
+		return true
+	case AgentRunRunning:
+		return now.Sub(c.HeartbeatAt) > AgentCheckpointStaleAfter
+	default:
+		return false
+	}
+}
+
+// AgentResumePayload represents the agent resume task payload
+type AgentResumePayload struct {
+	TenantID  uint64 `json:"tenant_id"`
+	MessageID string `json:"message_id"`
+}
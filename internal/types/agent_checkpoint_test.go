@@ -0,0 +1,28 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgentCheckpointIsResumable(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		status      AgentRunStatus
+		heartbeatAt time.Time
+		want        bool
+	}{
+		{AgentRunRunning, now.Add(-AgentCheckpointStaleAfter / 2), false},
+		{AgentRunRunning, now.Add(-AgentCheckpointStaleAfter - time.Second), true},
+		{AgentRunFailed, now, true},
+		{AgentRunCompleted, now.Add(-time.Hour), false},
+		{AgentRunStopped, now.Add(-time.Hour), false},
+	}
+	for _, tt := range tests {
+		checkpoint := &AgentCheckpoint{Status: tt.status, HeartbeatAt: tt.heartbeatAt}
+		if got := checkpoint.IsResumable(now); got != tt.want {
+			t.Errorf("IsResumable(%s, heartbeat %s ago) = %v, want %v",
+				tt.status, now.Sub(tt.heartbeatAt), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultAgentScheduleMaxRetries is the number of retries of a failed run when none is configured
+	DefaultAgentScheduleMaxRetries = 3
+	// MaxAgentScheduleRetries bounds the retries a schedule can configure
+	MaxAgentScheduleRetries = 10
+	// MinAgentScheduleInterval is the shortest delay of an @every schedule, the resolution of cron expressions
+	MinAgentScheduleInterval = time.Minute
+	// AgentSchedulePollInterval is how often due schedules are looked up
+	AgentSchedulePollInterval = 30 * time.Second
+	// AgentScheduleWebhookTimeout bounds the delivery of a run result to the webhook
+	AgentScheduleWebhookTimeout = 30 * time.Second
+)
+
+// AgentScheduleRunStatus is the status of a scheduled agent run
+type AgentScheduleRunStatus string
+
+const (
+	// AgentScheduleRunPending means the run is queued, or waiting for a retry after a failed attempt
+	AgentScheduleRunPending AgentScheduleRunStatus = "pending"
+	// AgentScheduleRunRunning means an attempt of the run is in progress
+	AgentScheduleRunRunning AgentScheduleRunStatus = "running"
+	// AgentScheduleRunSucceeded means the answer was stored and delivered
+	AgentScheduleRunSucceeded AgentScheduleRunStatus = "success"
+	// AgentScheduleRunFailed means every attempt of the run failed
+	AgentScheduleRunFailed AgentScheduleRunStatus = "failed"
+)
+
+// AgentScheduleTrigger is what started a scheduled agent run
+type AgentScheduleTrigger string
+
+const (
+	// AgentScheduleTriggerCron marks runs started by the cron expression of the schedule
+	AgentScheduleTriggerCron AgentScheduleTrigger = "cron"
+	// AgentScheduleTriggerManual marks runs started through the API
+	AgentScheduleTriggerManual AgentScheduleTrigger = "manual"
+)
+
+// AgentSchedule runs a custom agent with a fixed prompt on a cron schedule, without a user session.
+// Each run stores its answer as a new session, and optionally posts it to a webhook.
+type AgentSchedule struct {
+	ID          string `json:"id"          gorm:"type:varchar(36);primaryKey"`
+	TenantID    uint64 `json:"tenant_id"   gorm:"index"`
+	Name        string `json:"name"        gorm:"type:varchar(255);not null"`
+	Description string `json:"description" gorm:"type:text"`
+	Enabled     bool   `json:"enabled"`
+	// Custom agent to run, must run in agent mode
+	AgentID string `json:"agent_id" gorm:"type:varchar(36);not null;index"`
+	// Prompt sent to the agent on each run
+	Prompt string `json:"prompt" gorm:"type:text;not null"`
+	// Knowledge bases searched by the agent, the agent's own knowledge bases when empty
+	KnowledgeBaseIDs StringArray `json:"knowledge_base_ids" gorm:"type:json"`
+	// Standard 5-field cron expression or descriptor (@daily, @every 1h, ...)
+	CronExpression string `json:"cron_expression" gorm:"type:varchar(128);not null"`
+	// IANA time zone the cron expression is evaluated in, UTC when empty
+	Timezone string `json:"timezone" gorm:"type:varchar(64)"`
+	// URL the answer and references of each run are posted to, optional
+	WebhookURL string `json:"webhook_url" gorm:"type:varchar(512)"`
+	// Key signing the webhook body, never returned unmasked
+	WebhookSecret string `json:"webhook_secret,omitempty" gorm:"-"`
+	// WebhookSecret encrypted at rest
+	EncryptedWebhookSecret string `json:"-" gorm:"column:webhook_secret;type:text"`
+	// Retries of a failed run, DefaultAgentScheduleMaxRetries when nil
+	MaxRetries *int `json:"max_retries"`
+	// Next time the schedule fires, nil while disabled
+	NextRunAt *time.Time `json:"next_run_at" gorm:"index"`
+	// Last time the schedule fired
+	LastRunAt *time.Time `json:"last_run_at"`
+	// Status of the last finished run
+	LastRunStatus AgentScheduleRunStatus `json:"last_run_status" gorm:"type:varchar(16)"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt         `json:"deleted_at"  gorm:"index"`
+}
+
+// TableName returns the table name for AgentSchedule
+func (AgentSchedule) TableName() string {
+	return "agent_schedules"
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new agent schedule
+func (s *AgentSchedule) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// GetMaxRetries returns the retries of a failed run, DefaultAgentScheduleMaxRetries when not configured
+func (s *AgentSchedule) GetMaxRetries() int {
+	if s.MaxRetries == nil {
+		return DefaultAgentScheduleMaxRetries
+	}
+	return *s.MaxRetries
+}
+
+// MaskSensitiveData masks the webhook secret for display
+func (s *AgentSchedule) MaskSensitiveData() {
+	if s.WebhookSecret != "" {
+		s.WebhookSecret = maskString(s.WebhookSecret)
+	}
+}
+
+// AgentScheduleUpdate holds the fields of an agent schedule update, omitted fields keep their value
+type AgentScheduleUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+	AgentID     *string `json:"agent_id,omitempty"`
+	Prompt      *string `json:"prompt,omitempty"`
+	// An empty list searches the agent's own knowledge bases
+	KnowledgeBaseIDs StringArray `json:"knowledge_base_ids,omitempty"`
+	CronExpression   *string     `json:"cron_expression,omitempty"`
+	Timezone         *string     `json:"timezone,omitempty"`
+	WebhookURL       *string     `json:"webhook_url,omitempty"`
+	// A masked secret keeps the stored one, an empty string removes it
+	WebhookSecret *string `json:"webhook_secret,omitempty"`
+	MaxRetries    *int    `json:"max_retries,omitempty"`
+}
+
+// AgentScheduleRun is a run of an agent schedule, kept as its run history
+type AgentScheduleRun struct {
+	ID          string               `json:"id"           gorm:"type:varchar(36);primaryKey"`
+	TenantID    uint64               `json:"tenant_id"    gorm:"index"`
+	ScheduleID  string               `json:"schedule_id"  gorm:"type:varchar(36);not null;index"`
+	TriggeredBy AgentScheduleTrigger `json:"triggered_by" gorm:"type:varchar(16)"`
+	// Status of the run, pending between a failed attempt and its retry
+	Status AgentScheduleRunStatus `json:"status" gorm:"type:varchar(16);not null"`
+	// Number of attempts so far
+	Attempts int `json:"attempts"`
+	// Session and assistant message holding the answer, set once the agent answered
+	SessionID string `json:"session_id" gorm:"type:varchar(36)"`
+	MessageID string `json:"message_id" gorm:"type:varchar(36)"`
+	// Error of the last failed attempt
+	Error string `json:"error" gorm:"type:text"`
+	// HTTP status returned by the webhook on the last delivery
+	WebhookStatusCode int `json:"webhook_status_code"`
+	// When the result was delivered to the webhook
+	DeliveredAt *time.Time `json:"delivered_at"`
+	// When the run was due
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentScheduleRun
+func (AgentScheduleRun) TableName() string {
+	return "agent_schedule_runs"
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new agent schedule run
+func (r *AgentScheduleRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// AgentScheduleRunPayload is the payload of the task executing a scheduled agent run
+type AgentScheduleRunPayload struct {
+	TenantID uint64 `json:"tenant_id"`
+	RunID    string `json:"run_id"`
+}
+
+// AgentScheduleWebhookEvent is the body posted to the webhook of a schedule after a run
+type AgentScheduleWebhookEvent struct {
+	Event        string                          `json:"event"`
+	ScheduleID   string                          `json:"schedule_id"`
+	ScheduleName string                          `json:"schedule_name"`
+	AgentID      string                          `json:"agent_id"`
+	RunID        string                          `json:"run_id"`
+	SessionID    string                          `json:"session_id"`
+	MessageID    string                          `json:"message_id"`
+	Prompt       string                          `json:"prompt"`
+	Answer       string                          `json:"answer"`
+	References   []AgentScheduleWebhookReference `json:"references"`
+	ScheduledAt  time.Time                       `json:"scheduled_at"`
+	AnsweredAt   time.Time                       `json:"answered_at"`
+}
+
+// AgentScheduleWebhookReference is a knowledge chunk the answer of a run is based on
+type AgentScheduleWebhookReference struct {
+	ChunkID        string `json:"chunk_id"`
+	KnowledgeID    string `json:"knowledge_id"`
+	KnowledgeTitle string `json:"knowledge_title"`
+	Content        string `json:"content"`
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// MaxAgentScratchpadFindings bounds the findings kept for a session, the oldest are dropped first
+const MaxAgentScratchpadFindings = 30
+
+// AgentPlanStep is a step of the research plan written by the agent with todo_write
+type AgentPlanStep struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      string `json:"status"` // pending, in_progress, completed
+}
+
+// AgentPlanSteps is a list of plan steps stored as JSON
+type AgentPlanSteps []AgentPlanStep
+
+// Value implements the driver.Valuer interface for database serialization
+func (s AgentPlanSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return json.Marshal([]AgentPlanStep{})
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements the sql.Scanner interface for database deserialization
+func (s *AgentPlanSteps) Scan(value interface{}) error {
+	if value == nil {
+		*s = make(AgentPlanSteps, 0)
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		*s = make(AgentPlanSteps, 0)
+		return nil
+	}
+	return json.Unmarshal(b, s)
+}
+
+// AgentFinding is a key finding the agent recorded, kept for the following turns
+type AgentFinding struct {
+	Content string `json:"content"`
+	// Assistant message whose execution recorded the finding
+	MessageID string    `json:"message_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AgentFindings is a list of findings stored as JSON
+type AgentFindings []AgentFinding
+
+// Value implements the driver.Valuer interface for database serialization
+func (f AgentFindings) Value() (driver.Value, error) {
+	if f == nil {
+		return json.Marshal([]AgentFinding{})
+	}
+	return json.Marshal(f)
+}
+
+// Scan implements the sql.Scanner interface for database deserialization
+func (f *AgentFindings) Scan(value interface{}) error {
+	if value == nil {
+		*f = make(AgentFindings, 0)
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		*f = make(AgentFindings, 0)
+		return nil
+	}
+	return json.Unmarshal(b, f)
+}
+
+// AgentScratchpad is the research plan and key findings of the agent in a session.
+// Unlike the LLM context, it survives across turns as structured state: it is injected into the
+// system prompt of the next turn and shown by clients as the progress of the plan.
+type AgentScratchpad struct {
+	SessionID string `json:"session_id" gorm:"type:varchar(36);primaryKey"`
+	TenantID  uint64 `json:"tenant_id"  gorm:"index"`
+	// Task the current plan was made for
+	Task string `json:"task" gorm:"type:text"`
+	// Steps of the latest plan
+	Steps AgentPlanSteps `json:"steps" gorm:"type:json"`
+	// Findings recorded so far, oldest first
+	Findings AgentFindings `json:"findings" gorm:"type:json"`
+	// Assistant message whose execution last updated the scratchpad
+	MessageID string    `json:"message_id" gorm:"type:varchar(36)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for AgentScratchpad
+func (AgentScratchpad) TableName() string {
+	return "agent_scratchpads"
+}
+
+// IsEmpty reports whether the scratchpad holds neither a plan nor findings
+func (s *AgentScratchpad) IsEmpty() bool {
+	return s == nil || (len(s.Steps) == 0 && len(s.Findings) == 0)
+}
+
+// PendingSteps returns the number of plan steps not completed yet
+func (s *AgentScratchpad) PendingSteps() int {
+	pending := 0
+	for _, step := range s.Steps {
+		if step.Status != "completed" {
+			pending++
+		}
+	}
+	return pending
+}
@@ -36,6 +36,18 @@ type ChatResponse struct {
 	} `json:"usage"`
 }
 
+// StructuredOutput is a JSON answer validated against the requested schema
+type StructuredOutput struct {
+	// Parsed JSON object (raw model output when it could not be parsed)
+	Object json.RawMessage `json:"object"`
+	// Whether the object conforms to the schema
+	Valid bool `json:"valid"`
+	// Whether the object was repaired after failing validation
+	Repaired bool `json:"repaired"`
+	// Validation error when the object is invalid
+	Error string `json:"error,omitempty"`
+}
+
 // Response type
 type ResponseType string
 
@@ -60,6 +72,22 @@ const (
 	ResponseTypeAgentQuery ResponseType = "agent_query"
 	// Complete response type (agent complete)
 	ResponseTypeComplete ResponseType = "complete"
+	// Refusal response type (request or answer blocked by a guardrail)
+	ResponseTypeRefusal ResponseType = "refusal"
+	// Structured output response type (JSON answer validated against a schema)
+	ResponseTypeStructuredOutput ResponseType = "structured_output"
+	// Retrieval debug response type (per-candidate scores and decisions)
+	ResponseTypeRetrievalDebug ResponseType = "retrieval_debug"
+	// Knowledge base routing response type (knowledge bases selected for the query)
+	ResponseTypeKBRouting ResponseType = "kb_routing"
+	// Budget exhausted response type (agent tool call or token budget reached)
+	ResponseTypeBudgetExhausted ResponseType = "budget_exhausted"
+	// Approval request response type (tool call waiting for human approval)
+	ResponseTypeApprovalRequest ResponseType = "approval_request"
+	// Approval result response type (decision on a tool call approval)
+	ResponseTypeApprovalResult ResponseType = "approval_result"
+	// Sub-agent response type (event of an agent the current agent delegated to)
+	ResponseTypeSubAgent ResponseType = "sub_agent"
 )
 
 // StreamResponse stream response
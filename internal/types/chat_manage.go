@@ -1,5 +1,7 @@
 package types
 
+import "encoding/json"
+
 // ChatManage represents the configuration and state for a chat session
 // including query processing, search parameters, and model configurations
 type ChatManage struct {
@@ -7,6 +9,7 @@ type ChatManage struct {
 	Query        string     `json:"query,omitempty"`         // Original user query
 	RewriteQuery string     `json:"rewrite_query,omitempty"` // Query after rewriting for better retrieval
 	History      []*History `json:"history,omitempty"`       // Chat history for context
+	Images       []string   `json:"-"`                       // Images attached to the query (data URLs or http(s) URLs)
 
 	KnowledgeBaseIDs []string `json:"knowledge_base_ids"`      // IDs of knowledge bases to search (multi-KB support)
 	KnowledgeIDs     []string `json:"knowledge_ids,omitempty"` // IDs of specific files to search (optional)
@@ -24,16 +27,21 @@ type ChatManage struct {
 
 	MaxRounds int `json:"max_rounds"` // Maximum history rounds used for rewrite/context
 
-	ChatModelID      string           `json:"chat_model_id"`     // ID of the chat model to use
-	SummaryConfig    SummaryConfig    `json:"summary_config"`    // Configuration for summary generation
-	FallbackStrategy FallbackStrategy `json:"fallback_strategy"` // Strategy when no relevant results are found
-	FallbackResponse string           `json:"fallback_response"` // Default response when fallback occurs
-	FallbackPrompt   string           `json:"fallback_prompt"`   // Prompt for model-based fallback response
+	ChatModelID      string           `json:"chat_model_id"`      // ID of the chat model to use
+	MaxContextTokens int              `json:"max_context_tokens"` // Tokens prompt and completion may take together, 0 for unlimited
+	SummaryConfig    SummaryConfig    `json:"summary_config"`     // Configuration for summary generation
+	FallbackStrategy FallbackStrategy `json:"fallback_strategy"`  // Strategy when no relevant results are found
+	FallbackResponse string           `json:"fallback_response"`  // Default response when fallback occurs
+	FallbackPrompt   string           `json:"fallback_prompt"`    // Prompt for model-based fallback response
 
 	EnableRewrite        bool   `json:"enable_rewrite"`         // Whether to enable rewrite
 	EnableQueryExpansion bool   `json:"enable_query_expansion"` // Whether to enable query expansion with LLM
 	RewritePromptSystem  string `json:"rewrite_prompt_system"`  // Custom system prompt for rewrite stage
 	RewritePromptUser    string `json:"rewrite_prompt_user"`    // Custom user prompt for rewrite stage
+	EnableImageQuery     bool   `json:"enable_image_query"`     // Whether to describe attached images and search with the description
+
+	// JSON Schema the answer must conform to (empty for free-text answers)
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
 
 	// Internal fields for pipeline data processing
 	SearchResult    []*SearchResult   `json:"-"` // Results from search phase
@@ -44,7 +52,10 @@ type ChatManage struct {
 	EntityKnowledge map[string]string `json:"-"` // KnowledgeID -> KnowledgeBaseID mapping for graph-enabled files
 	GraphResult     *GraphData        `json:"-"` // Graph data from search phase
 	UserContent     string            `json:"-"` // Processed user content
+	ImageQuery      string            `json:"-"` // Description and text of the attached images, used as an extra search query
 	ChatResponse    *ChatResponse     `json:"-"` // Final response from chat model
+	// Parsed answer when ResponseSchema is set
+	StructuredOutput *StructuredOutput `json:"-"`
 
 	// Event system for streaming responses
 	EventBus  EventBusInterface `json:"-"` // EventBus for emitting streaming events
@@ -58,6 +69,9 @@ type ChatManage struct {
 	FAQPriorityEnabled       bool    `json:"-"` // Whether FAQ priority strategy is enabled
 	FAQDirectAnswerThreshold float64 `json:"-"` // Threshold for direct FAQ answer (similarity > this value)
 	FAQScoreBoost            float64 `json:"-"` // Score multiplier for FAQ results
+
+	// Guardrail policies for the query and the streamed answer (nil disables guardrails)
+	Guardrail *GuardrailConfig `json:"-"`
 }
 
 // Clone creates a deep copy of the ChatManage object
@@ -70,6 +84,13 @@ func (c *ChatManage) Clone() *ChatManage {
 	knowledgeIDs := make([]string, len(c.KnowledgeIDs))
 	copy(knowledgeIDs, c.KnowledgeIDs)
 
+	// Deep copy images slice
+	var images []string
+	if c.Images != nil {
+		images = make([]string, len(c.Images))
+		copy(images, c.Images)
+	}
+
 	// Deep copy search targets slice
 	searchTargets := make(SearchTargets, len(c.SearchTargets))
 	for i, t := range c.SearchTargets {
@@ -87,6 +108,8 @@ func (c *ChatManage) Clone() *ChatManage {
 	return &ChatManage{
 		Query:            c.Query,
 		RewriteQuery:     c.RewriteQuery,
+		Images:           images,
+		ImageQuery:       c.ImageQuery,
 		SessionID:        c.SessionID,
 		KnowledgeBaseIDs: knowledgeBaseIDs,
 		KnowledgeIDs:     knowledgeIDs,
@@ -100,6 +123,7 @@ func (c *ChatManage) Clone() *ChatManage {
 		RerankTopK:       c.RerankTopK,
 		RerankThreshold:  c.RerankThreshold,
 		ChatModelID:      c.ChatModelID,
+		MaxContextTokens: c.MaxContextTokens,
 		SummaryConfig: SummaryConfig{
 			MaxTokens:           c.SummaryConfig.MaxTokens,
 			RepeatPenalty:       c.SummaryConfig.RepeatPenalty,
@@ -122,11 +146,14 @@ func (c *ChatManage) Clone() *ChatManage {
 		RewritePromptUser:    c.RewritePromptUser,
 		EnableRewrite:        c.EnableRewrite,
 		EnableQueryExpansion: c.EnableQueryExpansion,
+		EnableImageQuery:     c.EnableImageQuery,
+		ResponseSchema:       c.ResponseSchema,
 		TenantID:             c.TenantID,
 		// FAQ Strategy Settings
 		FAQPriorityEnabled:       c.FAQPriorityEnabled,
 		FAQDirectAnswerThreshold: c.FAQDirectAnswerThreshold,
 		FAQScoreBoost:            c.FAQScoreBoost,
+		Guardrail:                c.Guardrail,
 	}
 }
 
@@ -136,6 +163,7 @@ type EventType string
 const (
 	LOAD_HISTORY           EventType = "load_history"           // Load conversation history without rewriting
 	REWRITE_QUERY          EventType = "rewrite_query"          // Query rewriting for better retrieval
+	IMAGE_QUERY            EventType = "image_query"            // Describe attached images as an extra retrieval query
 	CHUNK_SEARCH           EventType = "chunk_search"           // Search for relevant chunks
 	CHUNK_SEARCH_PARALLEL  EventType = "chunk_search_parallel"  // Parallel search: chunks + entities
 	ENTITY_SEARCH          EventType = "entity_search"          // Search for relevant entities
@@ -147,38 +175,80 @@ const (
 	CHAT_COMPLETION_STREAM EventType = "chat_completion_stream" // Stream chat completion
 	STREAM_FILTER          EventType = "stream_filter"          // Filter streaming output
 	FILTER_TOP_K           EventType = "filter_top_k"           // Keep only top K results
+	GUARDRAIL_INPUT        EventType = "guardrail_input"        // Check user query against guardrail policies
+	GUARDRAIL_OUTPUT       EventType = "guardrail_output"       // Check model output against guardrail policies
+	STRUCTURED_OUTPUT      EventType = "structured_output"      // Validate and repair JSON answers against a schema
 )
 
 // Pipline defines the sequence of events for different chat modes
 var Pipline = map[string][]EventType{
 	"chat": { // Simple chat without retrieval
+		GUARDRAIL_INPUT,
 		CHAT_COMPLETION,
+		GUARDRAIL_OUTPUT,
 	},
 	"chat_stream": { // Streaming chat without retrieval (no history)
+		GUARDRAIL_INPUT,
+		GUARDRAIL_OUTPUT, // Installs the output guard before streaming starts
 		CHAT_COMPLETION_STREAM,
 		STREAM_FILTER,
 	},
 	"chat_history_stream": { // Streaming chat with conversation history
+		GUARDRAIL_INPUT,
 		LOAD_HISTORY,
+		GUARDRAIL_OUTPUT,
 		CHAT_COMPLETION_STREAM,
 		STREAM_FILTER,
 	},
 	"rag": { // Retrieval Augmented Generation
+		GUARDRAIL_INPUT,
+		IMAGE_QUERY,
 		CHUNK_SEARCH,
 		CHUNK_RERANK,
 		CHUNK_MERGE,
 		INTO_CHAT_MESSAGE,
 		CHAT_COMPLETION,
+		GUARDRAIL_OUTPUT,
 	},
 	"rag_stream": { // Streaming Retrieval Augmented Generation
+		GUARDRAIL_INPUT,
 		REWRITE_QUERY,
+		IMAGE_QUERY,
 		CHUNK_SEARCH_PARALLEL, // Parallel: CHUNK_SEARCH + ENTITY_SEARCH
 		CHUNK_RERANK,
 		CHUNK_MERGE,
 		FILTER_TOP_K,
 		DATA_ANALYSIS,
 		INTO_CHAT_MESSAGE,
+		GUARDRAIL_OUTPUT,
 		CHAT_COMPLETION_STREAM,
 		STREAM_FILTER,
 	},
+	"chat_structured": { // Chat answering with a JSON object conforming to a schema (no history)
+		GUARDRAIL_INPUT,
+		CHAT_COMPLETION,
+		GUARDRAIL_OUTPUT,
+		STRUCTURED_OUTPUT,
+	},
+	"chat_history_structured": { // Chat with conversation history answering with a JSON object
+		GUARDRAIL_INPUT,
+		LOAD_HISTORY,
+		CHAT_COMPLETION,
+		GUARDRAIL_OUTPUT,
+		STRUCTURED_OUTPUT,
+	},
+	"rag_structured": { // Retrieval Augmented Generation answering with a JSON object conforming to a schema
+		GUARDRAIL_INPUT,
+		REWRITE_QUERY,
+		IMAGE_QUERY,
+		CHUNK_SEARCH_PARALLEL,
+		CHUNK_RERANK,
+		CHUNK_MERGE,
+		FILTER_TOP_K,
+		DATA_ANALYSIS,
+		INTO_CHAT_MESSAGE,
+		CHAT_COMPLETION,
+		GUARDRAIL_OUTPUT,
+		STRUCTURED_OUTPUT,
+	},
 }
@@ -87,16 +87,34 @@ type CustomAgentConfig struct {
 	AllowedTools []string `yaml:"allowed_tools" json:"allowed_tools"`
 	// Whether reflection is enabled (only for agent type)
 	ReflectionEnabled bool `yaml:"reflection_enabled" json:"reflection_enabled"`
+	// Maximum tool calls of a round executed concurrently (only for agent type, 0 or 1 executes them sequentially)
+	MaxParallelToolCalls int `yaml:"max_parallel_tool_calls" json:"max_parallel_tool_calls"`
+	// Tool timeouts, call budgets and token budget (only for agent type, nil means unlimited)
+	ToolBudget *ToolBudgetConfig `yaml:"tool_budget,omitempty" json:"tool_budget,omitempty"`
+	// Tools requiring human approval before they run (only for agent type)
+	ToolApproval *ToolApprovalConfig `yaml:"tool_approval,omitempty" json:"tool_approval,omitempty"`
+	// Custom agents this agent may delegate sub-tasks to (only for agent type)
+	SubAgents *SubAgentConfig `yaml:"sub_agents,omitempty" json:"sub_agents,omitempty"`
 	// MCP service selection mode: "all" = all enabled MCP services, "selected" = specific services, "none" = no MCP
 	MCPSelectionMode string `yaml:"mcp_selection_mode" json:"mcp_selection_mode"`
 	// Selected MCP service IDs (only used when MCPSelectionMode is "selected")
 	MCPServices []string `yaml:"mcp_services" json:"mcp_services"`
+	// Tool allowlists, denylists and argument policies by MCP service ID (only for agent type)
+	MCPToolPolicies map[string]*MCPToolPolicy `yaml:"mcp_tool_policies,omitempty" json:"mcp_tool_policies,omitempty"`
+	// MCP resources attached to the agent as context when it starts (only for agent type)
+	MCPResources []MCPResourceRef `yaml:"mcp_resources,omitempty" json:"mcp_resources,omitempty"`
+	// MCP prompt used as the system prompt, taking precedence over SystemPrompt (only for agent type)
+	MCPPrompt *MCPPromptRef `yaml:"mcp_prompt,omitempty" json:"mcp_prompt,omitempty"`
+	// OpenAPI services whose selected operations the agent may call (none when empty)
+	OpenAPIServices []string `yaml:"openapi_services" json:"openapi_services"`
 
 	// ===== Knowledge Base Settings =====
 	// Knowledge base selection mode: "all" = all KBs, "selected" = specific KBs, "none" = no KB
 	KBSelectionMode string `yaml:"kb_selection_mode" json:"kb_selection_mode"`
 	// Associated knowledge base IDs (only used when KBSelectionMode is "selected")
 	KnowledgeBases []string `yaml:"knowledge_bases" json:"knowledge_bases"`
+	// Routing of each query to the most relevant knowledge bases (only used when KBSelectionMode is "all")
+	KBRouting *KBRoutingConfig `yaml:"kb_routing,omitempty" json:"kb_routing,omitempty"`
 
 	// ===== File Type Restriction Settings =====
 	// Supported file types for this agent (e.g., ["csv", "xlsx", "xls"])
@@ -141,6 +159,8 @@ type CustomAgentConfig struct {
 	EnableQueryExpansion bool `yaml:"enable_query_expansion" json:"enable_query_expansion"`
 	// Whether to enable query rewrite for multi-turn conversations
 	EnableRewrite bool `yaml:"enable_rewrite" json:"enable_rewrite"`
+	// Whether to describe images attached to the query and search with the description
+	EnableImageQuery bool `yaml:"enable_image_query" json:"enable_image_query"`
 	// Rewrite prompt system message
 	RewritePromptSystem string `yaml:"rewrite_prompt_system" json:"rewrite_prompt_system"`
 	// Rewrite prompt user message template
@@ -151,6 +171,10 @@ type CustomAgentConfig struct {
 	FallbackResponse string `yaml:"fallback_response" json:"fallback_response"`
 	// Fallback prompt (when FallbackStrategy is "model")
 	FallbackPrompt string `yaml:"fallback_prompt" json:"fallback_prompt"`
+
+	// ===== Guardrail Settings =====
+	// Guardrail policies applied to the user query and the streamed answer
+	Guardrail *GuardrailConfig `yaml:"guardrail,omitempty" json:"guardrail,omitempty"`
 }
 
 // Value implements driver.Valuer interface for CustomAgentConfig
@@ -263,6 +287,7 @@ User question: {{query}}`,
 			// Advanced settings
 			EnableQueryExpansion: true,
 			EnableRewrite:        true,
+			EnableImageQuery:     true,
 			FallbackStrategy:     "model",
 		},
 	}
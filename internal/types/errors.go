@@ -19,6 +19,27 @@ func NewStorageQuotaExceededError() *StorageQuotaExceededError {
 	}
 }
 
+// TokenQuotaExceededError represents the monthly token quota exceeded error
+type TokenQuotaExceededError struct {
+	Message string
+	Quota   int64
+	Used    int64
+}
+
+// Error implements the error interface
+func (e *TokenQuotaExceededError) Error() string {
+	return e.Message
+}
+
+// NewTokenQuotaExceededError creates a monthly token quota exceeded error
+func NewTokenQuotaExceededError(used, quota int64) *TokenQuotaExceededError {
+	return &TokenQuotaExceededError{
+		Message: fmt.Sprintf("Monthly token quota exceeded: %d of %d tokens used", used, quota),
+		Quota:   quota,
+		Used:    used,
+	}
+}
+
 // DuplicateKnowledgeError duplicate knowledge error, contains the existing knowledge object
 type DuplicateKnowledgeError struct {
 	Message   string
@@ -39,6 +39,42 @@ type EvaluationDetail struct {
 	Task   *EvaluationTask `json:"task"`             // Evaluation task info
 	Params *ChatManage     `json:"params"`           // Evaluation parameters
 	Metric *MetricResult   `json:"metric,omitempty"` // Evaluation metrics
+
+	Agents []*AgentEvaluationResult `json:"agents,omitempty"` // Results of each evaluated agent (agent evaluation only)
+}
+
+// AgentEvaluationResult contains the evaluation results of a custom agent run on a dataset
+type AgentEvaluationResult struct {
+	AgentID   string `json:"agent_id"`   // Evaluated custom agent ID
+	AgentName string `json:"agent_name"` // Evaluated custom agent name
+	Finished  int    `json:"finished"`   // Questions answered so far
+	Failed    int    `json:"failed"`     // Questions whose execution failed
+
+	Metric     *MetricResult      `json:"metric,omitempty"`     // Final-answer and retrieval metrics
+	Trajectory *TrajectoryMetrics `json:"trajectory,omitempty"` // Averaged trajectory metrics
+
+	Trajectories []*AgentTrajectory `json:"trajectories"` // Execution of each question, in dataset order
+}
+
+// AgentTrajectory records the execution of an agent on a question of the dataset
+type AgentTrajectory struct {
+	QID      int         `json:"qid"`             // Question ID
+	Question string      `json:"question"`        // Question text
+	Answer   string      `json:"answer"`          // Final answer of the agent
+	Steps    []AgentStep `json:"steps"`           // Steps taken by the agent
+	Error    string      `json:"error,omitempty"` // Error message if the execution failed
+
+	Metric *TrajectoryMetrics `json:"metric,omitempty"` // Trajectory metrics of this execution
+}
+
+// TrajectoryMetrics contains metrics of the tool-use trajectory of agent executions
+type TrajectoryMetrics struct {
+	Iterations         float64 `json:"iterations"`           // ReAct iterations used
+	ToolCalls          float64 `json:"tool_calls"`           // Tool calls made
+	RedundantToolCalls float64 `json:"redundant_tool_calls"` // Repeated tool calls with identical arguments
+	FailedToolCalls    float64 `json:"failed_tool_calls"`    // Tool calls that returned an error
+	ExpectedRetrieved  float64 `json:"expected_retrieved"`   // 1 when every expected passage was retrieved
+	Completed          float64 `json:"completed"`            // 1 when the agent reached a final answer
 }
 
 // String returns JSON representation of EvaluationTask
@@ -10,6 +10,8 @@ const (
 	TypeIndexDelete        = "index:delete"        // Index deletion task
 	TypeKBDelete           = "kb:delete"           // Knowledge base deletion task
 	TypeDataTableSummary   = "datatable:summary"   // Data table summary task
+	TypeAgentResume        = "agent:resume"        // Interrupted agent execution resume task
+	TypeAgentScheduleRun   = "agent:schedule_run"  // Scheduled agent run task
 )
 
 // ExtractChunkPayload represents the extract chunk task payload
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PII type identifiers supported by the built-in PII detection policy
+const (
+	PIITypeEmail      = "email"
+	PIITypePhone      = "phone"
+	PIITypeIDCard     = "id_card"
+	PIITypeCreditCard = "credit_card"
+	PIITypeIPAddress  = "ip_address"
+)
+
+// DefaultGuardrailRefusalMessage is returned to the user when a guardrail policy
+// blocks a request and the agent does not configure its own refusal message
+const DefaultGuardrailRefusalMessage = "Sorry, I can't help with this request."
+
+// GuardrailConfig configures the guardrail policies applied to a custom agent.
+// Input policies run on the user query before retrieval, output policies run on
+// the streamed answer.
+type GuardrailConfig struct {
+	// Whether guardrails are enabled for this agent
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Regular expressions denied in the query (and the answer when CheckOutput is set)
+	DenyPatterns []string `yaml:"deny_patterns" json:"deny_patterns"`
+	// Keywords denied in the query (and the answer when CheckOutput is set), case-insensitive
+	DenyKeywords []string `yaml:"deny_keywords" json:"deny_keywords"`
+	// Whether personally identifiable information is refused
+	PIIDetection bool `yaml:"pii_detection" json:"pii_detection"`
+	// PII types to detect (email, phone, id_card, credit_card, ip_address), empty means all
+	PIITypes []string `yaml:"pii_types" json:"pii_types"`
+	// Allowed topics, queries classified outside these topics are refused. Empty disables the check
+	AllowedTopics []string `yaml:"allowed_topics" json:"allowed_topics"`
+	// Chat model used to classify query topics, defaults to the conversation model
+	TopicClassifierModelID string `yaml:"topic_classifier_model_id" json:"topic_classifier_model_id"`
+	// Whether the streamed answer is checked as well
+	CheckOutput bool `yaml:"check_output" json:"check_output"`
+	// Message returned to the user when a request is refused
+	RefusalMessage string `yaml:"refusal_message" json:"refusal_message"`
+}
+
+// IsActive reports whether the guardrail config has any effect
+func (c *GuardrailConfig) IsActive() bool {
+	return c != nil && c.Enabled
+}
+
+// Validate checks that deny patterns compile and PII types are supported
+func (c *GuardrailConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, pattern := range c.DenyPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+	}
+	for _, t := range c.PIITypes {
+		switch t {
+		case PIITypeEmail, PIITypePhone, PIITypeIDCard, PIITypeCreditCard, PIITypeIPAddress:
+		default:
+			return fmt.Errorf("unsupported pii type: %s", t)
+		}
+	}
+	return nil
+}
+
+// GetRefusalMessage returns the configured refusal message or the default one
+func (c *GuardrailConfig) GetRefusalMessage() string {
+	if c == nil || c.RefusalMessage == "" {
+		return DefaultGuardrailRefusalMessage
+	}
+	return c.RefusalMessage
+}
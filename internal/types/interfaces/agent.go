@@ -26,6 +26,8 @@ type AgentEngine interface {
 		sessionID, messageID, query string,
 		llmContext []chat.Message,
 	) (*types.AgentState, error)
+	// Resume continues an interrupted execution from its checkpoint
+	Resume(ctx context.Context, checkpoint *types.AgentCheckpoint) (*types.AgentState, error)
 }
 
 // AgentService defines the interface for agent-related operations
@@ -0,0 +1,21 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AgentBundleService moves custom agents between tenants or environments as portable bundles
+type AgentBundleService interface {
+	// ExportAgent builds the bundle of an agent, with the definitions of the resources it references
+	ExportAgent(ctx context.Context, id string) (*types.AgentBundle, error)
+
+	// ImportAgent validates the references of a bundle against the current tenant, then creates
+	// the agent and its tool services with new IDs
+	ImportAgent(
+		ctx context.Context,
+		bundle *types.AgentBundle,
+		options *types.AgentImportOptions,
+	) (*types.AgentImportResult, error)
+}
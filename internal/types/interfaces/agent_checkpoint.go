@@ -0,0 +1,62 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/models/chat"
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AgentCheckpointRepository defines the interface for agent checkpoint data access
+type AgentCheckpointRepository interface {
+	// Save creates or replaces the checkpoint of an execution
+	Save(ctx context.Context, checkpoint *types.AgentCheckpoint) error
+
+	// GetByMessageID retrieves the checkpoint of an execution by assistant message ID and tenant ID
+	GetByMessageID(ctx context.Context, tenantID uint64, messageID string) (*types.AgentCheckpoint, error)
+
+	// UpdateProgress stores the progress of an execution after a round
+	UpdateProgress(ctx context.Context, tenantID uint64, messageID string,
+		round int, messages, state types.JSON, tokensUsed int) error
+
+	// UpdateStatus moves an execution owned by the instance to the given status
+	UpdateStatus(ctx context.Context, tenantID uint64, messageID, owner string, status types.AgentRunStatus) error
+
+	// Heartbeat refreshes the heartbeat of an execution owned by the instance
+	Heartbeat(ctx context.Context, tenantID uint64, messageID, owner string) error
+
+	// Claim takes over an execution that failed or whose heartbeat is older than staleBefore.
+	// Returns false when the execution is not resumable or another instance claimed it first.
+	Claim(ctx context.Context, tenantID uint64, messageID, owner string, staleBefore time.Time) (bool, error)
+
+	// ListStale lists running executions of all tenants whose heartbeat is older than staleBefore
+	ListStale(ctx context.Context, staleBefore time.Time, limit int) ([]*types.AgentCheckpoint, error)
+}
+
+// AgentCheckpointService defines the interface for checkpointing and resuming agent executions
+type AgentCheckpointService interface {
+	// Begin records a new execution owned by this instance
+	Begin(ctx context.Context, checkpoint *types.AgentCheckpoint) error
+
+	// Save stores the progress of an execution after a round
+	Save(ctx context.Context, messageID string, state *types.AgentState, messages []chat.Message, tokensUsed int) error
+
+	// Finish moves an execution owned by this instance to a final status
+	Finish(ctx context.Context, messageID string, status types.AgentRunStatus) error
+
+	// KeepAlive refreshes the heartbeat of an execution until the returned function is called
+	KeepAlive(ctx context.Context, messageID string) (stop func(), err error)
+
+	// Get retrieves the checkpoint of an execution, nil when the message has none
+	Get(ctx context.Context, messageID string) (*types.AgentCheckpoint, error)
+
+	// Claim takes over an interrupted execution for this instance
+	Claim(ctx context.Context, messageID string) (bool, error)
+
+	// RecoverInterrupted enqueues a resume task for each interrupted execution
+	RecoverInterrupted(ctx context.Context) (int, error)
+
+	// RunRecoverySweep runs RecoverInterrupted periodically until ctx is done
+	RunRecoverySweep(ctx context.Context)
+}
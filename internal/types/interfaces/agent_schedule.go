@@ -0,0 +1,84 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+	"github.com/hibiken/asynq"
+)
+
+// AgentScheduleRepository defines the interface for agent schedule data access
+type AgentScheduleRepository interface {
+	// Create creates a new agent schedule
+	Create(ctx context.Context, schedule *types.AgentSchedule) error
+
+	// GetByID retrieves an agent schedule by ID and tenant ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.AgentSchedule, error)
+
+	// List retrieves all agent schedules for a tenant
+	List(ctx context.Context, tenantID uint64) ([]*types.AgentSchedule, error)
+
+	// Update updates an agent schedule
+	Update(ctx context.Context, schedule *types.AgentSchedule) error
+
+	// Delete deletes an agent schedule (soft delete)
+	Delete(ctx context.Context, tenantID uint64, id string) error
+
+	// ListDue retrieves the enabled schedules of all tenants due at or before now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*types.AgentSchedule, error)
+
+	// Advance moves a due schedule to its next run time, only if it is still due at dueAt.
+	// It reports false when another instance fired the schedule first.
+	Advance(ctx context.Context, schedule *types.AgentSchedule, dueAt time.Time, next *time.Time) (bool, error)
+
+	// UpdateLastRunStatus records the status of the last finished run of a schedule
+	UpdateLastRunStatus(ctx context.Context, tenantID uint64, id string, status types.AgentScheduleRunStatus) error
+
+	// CreateRun creates a new run of a schedule
+	CreateRun(ctx context.Context, run *types.AgentScheduleRun) error
+
+	// GetRun retrieves a run by ID and tenant ID
+	GetRun(ctx context.Context, tenantID uint64, id string) (*types.AgentScheduleRun, error)
+
+	// UpdateRun updates the progress of a run
+	UpdateRun(ctx context.Context, run *types.AgentScheduleRun) error
+
+	// ListRuns retrieves the latest runs of a schedule, newest first
+	ListRuns(ctx context.Context, tenantID uint64, scheduleID string, limit int) ([]*types.AgentScheduleRun, error)
+}
+
+// AgentScheduleService defines the interface for scheduled agent runs
+type AgentScheduleService interface {
+	// CreateSchedule validates and creates a new agent schedule
+	CreateSchedule(ctx context.Context, schedule *types.AgentSchedule) error
+
+	// GetSchedule retrieves an agent schedule by ID, with masked webhook secret
+	GetSchedule(ctx context.Context, tenantID uint64, id string) (*types.AgentSchedule, error)
+
+	// ListSchedules lists all agent schedules for a tenant, with masked webhook secrets
+	ListSchedules(ctx context.Context, tenantID uint64) ([]*types.AgentSchedule, error)
+
+	// UpdateSchedule updates the provided fields of an agent schedule and recomputes its next run time
+	UpdateSchedule(ctx context.Context, tenantID uint64, id string,
+		update *types.AgentScheduleUpdate) (*types.AgentSchedule, error)
+
+	// DeleteSchedule deletes an agent schedule
+	DeleteSchedule(ctx context.Context, tenantID uint64, id string) error
+
+	// TriggerSchedule starts a run of a schedule right away, outside its cron schedule
+	TriggerSchedule(ctx context.Context, tenantID uint64, id string) (*types.AgentScheduleRun, error)
+
+	// ListRuns lists the run history of a schedule, newest first
+	ListRuns(ctx context.Context, tenantID uint64, scheduleID string) ([]*types.AgentScheduleRun, error)
+
+	// EnqueueDue starts a run of each due schedule and moves the schedules to their next run time
+	EnqueueDue(ctx context.Context) (int, error)
+
+	// RunScheduler runs EnqueueDue periodically until ctx is done
+	RunScheduler(ctx context.Context)
+
+	// ProcessScheduleRun handles the task executing a run: runs the agent, stores the answer
+	// as a session and delivers it to the webhook. Failed attempts are retried by the task queue.
+	ProcessScheduleRun(ctx context.Context, t *asynq.Task) error
+}
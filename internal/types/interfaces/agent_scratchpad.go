@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// AgentScratchpadRepository defines the interface for agent scratchpad data access
+type AgentScratchpadRepository interface {
+	// Get retrieves the scratchpad of a session, nil when the session has none
+	Get(ctx context.Context, tenantID uint64, sessionID string) (*types.AgentScratchpad, error)
+
+	// Save creates or replaces the scratchpad of a session
+	Save(ctx context.Context, scratchpad *types.AgentScratchpad) error
+
+	// Delete deletes the scratchpad of a session
+	Delete(ctx context.Context, tenantID uint64, sessionID string) error
+}
+
+// AgentScratchpadService defines the interface for the plan and findings kept across turns of a session
+type AgentScratchpadService interface {
+	// Get retrieves the scratchpad of a session, empty when the agent has recorded nothing yet
+	Get(ctx context.Context, sessionID string) (*types.AgentScratchpad, error)
+
+	// Update records the latest plan and the new findings written with todo_write in the steps of an execution
+	Update(ctx context.Context, sessionID, messageID string, steps []types.AgentStep) error
+
+	// Clear deletes the scratchpad of a session
+	Clear(ctx context.Context, sessionID string) error
+}
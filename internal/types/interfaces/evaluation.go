@@ -12,6 +12,10 @@ type EvaluationService interface {
 	Evaluation(ctx context.Context, datasetID string, knowledgeBaseID string,
 		chatModelID string, rerankModelID string,
 	) (*types.EvaluationDetail, error)
+	// AgentEvaluation starts a new evaluation task running the dataset through custom agents
+	AgentEvaluation(ctx context.Context, datasetID string, knowledgeBaseID string,
+		agentIDs []string,
+	) (*types.EvaluationDetail, error)
 	// EvaluationResult retrieves evaluation result by task ID
 	EvaluationResult(ctx context.Context, taskID string) (*types.EvaluationDetail, error)
 }
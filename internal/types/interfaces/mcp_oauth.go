@@ -0,0 +1,47 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// MCPOAuthRepository defines the interface for MCP OAuth token and pending authorization data access
+type MCPOAuthRepository interface {
+	// GetToken retrieves the token of a service for a user (empty for the tenant), nil when there is none
+	GetToken(ctx context.Context, serviceID, userID string) (*types.MCPOAuthToken, error)
+
+	// SaveToken creates or replaces the token of a service for a user
+	SaveToken(ctx context.Context, token *types.MCPOAuthToken) error
+
+	// DeleteToken deletes the token of a service for a user
+	DeleteToken(ctx context.Context, serviceID, userID string) error
+
+	// DeleteServiceTokens deletes the tokens of all users of a service
+	DeleteServiceTokens(ctx context.Context, serviceID string) error
+
+	// CreateState stores a pending authorization
+	CreateState(ctx context.Context, state *types.MCPOAuthState) error
+
+	// ConsumeState retrieves and deletes a pending authorization, nil when unknown or expired
+	ConsumeState(ctx context.Context, state string) (*types.MCPOAuthState, error)
+}
+
+// MCPOAuthService defines the interface for the OAuth authorization of MCP services
+type MCPOAuthService interface {
+	// Authorize starts the authorization of a service for the calling user and returns the URL
+	// of the authorization server to redirect the user to, and the nonce binding the authorization
+	// to the browser, which must be presented on the callback
+	Authorize(ctx context.Context, serviceID, redirectURI, returnURL string) (authURL, nonce string, err error)
+
+	// HandleCallback exchanges the authorization code of a callback for a token and returns the
+	// URL the user asked to return to. nonce is the one returned by Authorize to the browser,
+	// authErr is the error reported by the authorization server.
+	HandleCallback(ctx context.Context, state, nonce, code, authErr string) (string, error)
+
+	// GetStatus returns the authorization status of a service for the calling user
+	GetStatus(ctx context.Context, serviceID string) (*types.MCPOAuthStatus, error)
+
+	// Revoke deletes the token of a service for the calling user
+	Revoke(ctx context.Context, serviceID string) error
+}
@@ -27,6 +27,8 @@ type ModelService interface {
 	GetRerankModel(ctx context.Context, modelId string) (rerank.Reranker, error)
 	// GetChatModel gets a chat model
 	GetChatModel(ctx context.Context, modelId string) (chat.Chat, error)
+	// ValidateModelRouting checks the models referred to by a model routing configuration
+	ValidateModelRouting(ctx context.Context, config *types.ModelRoutingConfig) error
 }
 
 // ModelRepository defines the model repository interface
@@ -0,0 +1,35 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// ModelUsageRepository defines the interface for model usage data access
+type ModelUsageRepository interface {
+	// Create records a model call
+	Create(ctx context.Context, usage *types.ModelUsage) error
+
+	// SumTokens returns the tokens used by a tenant since a time
+	SumTokens(ctx context.Context, tenantID uint64, since time.Time) (int64, error)
+
+	// Aggregate returns the usage statistics of a tenant
+	Aggregate(ctx context.Context, tenantID uint64, query *types.ModelUsageQuery) ([]*types.ModelUsageStat, error)
+}
+
+// ModelUsageService defines the interface for model usage accounting and token quotas
+type ModelUsageService interface {
+	// Record records a model call for the tenant, user and scope in the context
+	Record(ctx context.Context, usage *types.ModelUsage)
+
+	// CheckQuota returns a *types.TokenQuotaExceededError when the tenant in the context used its monthly token quota
+	CheckQuota(ctx context.Context) error
+
+	// GetQuotaStatus returns the token usage of the tenant in the context in the current month
+	GetQuotaStatus(ctx context.Context) (*types.TokenQuotaStatus, error)
+
+	// GetUsageStats returns the usage statistics of the tenant in the context
+	GetUsageStats(ctx context.Context, query *types.ModelUsageQuery) ([]*types.ModelUsageStat, error)
+}
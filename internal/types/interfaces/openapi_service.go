@@ -0,0 +1,54 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// OpenAPIServiceRepository defines the interface for OpenAPI service data access
+type OpenAPIServiceRepository interface {
+	// Create creates a new OpenAPI service
+	Create(ctx context.Context, service *types.OpenAPIService) error
+
+	// GetByID retrieves an OpenAPI service by ID and tenant ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.OpenAPIService, error)
+
+	// List retrieves all OpenAPI services for a tenant
+	List(ctx context.Context, tenantID uint64) ([]*types.OpenAPIService, error)
+
+	// ListByIDs retrieves OpenAPI services by multiple IDs for a tenant
+	ListByIDs(ctx context.Context, tenantID uint64, ids []string) ([]*types.OpenAPIService, error)
+
+	// Update updates an OpenAPI service
+	Update(ctx context.Context, service *types.OpenAPIService) error
+
+	// Delete deletes an OpenAPI service (soft delete)
+	Delete(ctx context.Context, tenantID uint64, id string) error
+}
+
+// OpenAPIServiceService defines the interface for OpenAPI service business logic
+type OpenAPIServiceService interface {
+	// CreateOpenAPIService validates the document and creates a new OpenAPI service
+	CreateOpenAPIService(ctx context.Context, service *types.OpenAPIService) error
+
+	// GetOpenAPIServiceByID retrieves an OpenAPI service by ID, with masked auth headers
+	GetOpenAPIServiceByID(ctx context.Context, tenantID uint64, id string) (*types.OpenAPIService, error)
+
+	// ListOpenAPIServices lists all OpenAPI services for a tenant, with masked auth headers
+	ListOpenAPIServices(ctx context.Context, tenantID uint64) ([]*types.OpenAPIService, error)
+
+	// ListOpenAPIServicesForAgent retrieves the enabled services among the IDs, with decrypted
+	// auth headers, to register their operations as agent tools
+	ListOpenAPIServicesForAgent(ctx context.Context, tenantID uint64, ids []string) ([]*types.OpenAPIService, error)
+
+	// UpdateOpenAPIService updates the provided fields of an OpenAPI service, with masked auth headers
+	UpdateOpenAPIService(ctx context.Context, tenantID uint64, id string,
+		update *types.OpenAPIServiceUpdate) (*types.OpenAPIService, error)
+
+	// DeleteOpenAPIService deletes an OpenAPI service
+	DeleteOpenAPIService(ctx context.Context, tenantID uint64, id string) error
+
+	// ListOpenAPIOperations lists the operations of the document of an OpenAPI service
+	ListOpenAPIOperations(ctx context.Context, tenantID uint64, id string) ([]*types.OpenAPIOperation, error)
+}
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/Tencent/WeKnora/internal/event"
 	"github.com/Tencent/WeKnora/internal/types"
@@ -29,16 +30,18 @@ type SessionService interface {
 	// modelID: optional model ID to use for title generation (if empty, uses first available KnowledgeQA model)
 	GenerateTitleAsync(ctx context.Context, session *types.Session, userQuery string, modelID string, eventBus *event.EventBus)
 	// KnowledgeQA performs knowledge-based question answering
+	// images: optional images attached to the query, answered by a vision capable model
 	// knowledgeBaseIDs: list of knowledge base IDs to search (supports multi-KB)
 	// knowledgeIDs: list of specific knowledge (file) IDs to search
 	// summaryModelID: optional summary model ID override (if empty, uses session/KB default)
 	// webSearchEnabled: whether to enable web search to supplement knowledge base results
 	// customAgent: optional custom agent for config override (multiTurnEnabled, historyTurns)
+	// responseSchema: optional JSON Schema, when set the answer is a JSON object conforming to it
 	// Events are emitted through eventBus (references, answer chunks, completion)
 	KnowledgeQA(ctx context.Context,
-		session *types.Session, query string, knowledgeBaseIDs []string, knowledgeIDs []string,
+		session *types.Session, query string, images []string, knowledgeBaseIDs []string, knowledgeIDs []string,
 		assistantMessageID string, summaryModelID string, webSearchEnabled bool, eventBus *event.EventBus,
-		customAgent *types.CustomAgent,
+		customAgent *types.CustomAgent, responseSchema json.RawMessage,
 	) error
 	// KnowledgeQAByEvent performs knowledge-based question answering by event
 	KnowledgeQAByEvent(ctx context.Context, chatManage *types.ChatManage, eventList []types.EventType) error
@@ -61,6 +64,26 @@ type SessionService interface {
 		knowledgeBaseIDs []string,
 		knowledgeIDs []string,
 	) error
+	// ResumeAgentQA resumes an interrupted agent execution from its checkpoint.
+	// The checkpoint must have been claimed by this instance.
+	ResumeAgentQA(
+		ctx context.Context,
+		session *types.Session,
+		checkpoint *types.AgentCheckpoint,
+		eventBus *event.EventBus,
+		customAgent *types.CustomAgent,
+	) error
+	// RunAgent runs a custom agent on a single query over the given knowledge bases outside any session,
+	// without conversation history, checkpoint, human approval or delegation, and returns its final state.
+	// The knowledge bases of the agent are used when none are given.
+	// Events are emitted through eventBus.
+	RunAgent(
+		ctx context.Context,
+		customAgent *types.CustomAgent,
+		query string,
+		knowledgeBaseIDs []string,
+		eventBus *event.EventBus,
+	) (*types.AgentState, error)
 	// ClearContext clears the LLM context for a session
 	ClearContext(ctx context.Context, sessionID string) error
 }
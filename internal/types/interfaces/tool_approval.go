@@ -0,0 +1,39 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/Tencent/WeKnora/internal/types"
+)
+
+// ToolApprovalRepository defines the interface for tool approval data access
+type ToolApprovalRepository interface {
+	// Create creates a new approval request
+	Create(ctx context.Context, approval *types.ToolApproval) error
+
+	// GetByID retrieves an approval request by ID and tenant ID
+	GetByID(ctx context.Context, tenantID uint64, id string) (*types.ToolApproval, error)
+
+	// ListPendingBySession retrieves the pending approval requests of a session
+	ListPendingBySession(ctx context.Context, tenantID uint64, sessionID string) ([]*types.ToolApproval, error)
+
+	// Decide moves a pending approval request to the given status.
+	// Returns false when the request is no longer pending.
+	Decide(ctx context.Context, tenantID uint64, id string, status types.ToolApprovalStatus, reason string) (bool, error)
+}
+
+// ToolApprovalService defines the interface for human approval of agent tool calls
+type ToolApprovalService interface {
+	// RequestApproval persists a pending approval request for a tool call
+	RequestApproval(ctx context.Context, approval *types.ToolApproval) error
+
+	// WaitForDecision blocks until the request is decided, expires or ctx is done,
+	// and returns the request with its final status
+	WaitForDecision(ctx context.Context, approval *types.ToolApproval) (*types.ToolApproval, error)
+
+	// Decide approves or denies a pending request of the session
+	Decide(ctx context.Context, sessionID, id string, approved bool, reason string) (*types.ToolApproval, error)
+
+	// ListPending lists the pending approval requests of the session
+	ListPending(ctx context.Context, sessionID string) ([]*types.ToolApproval, error)
+}
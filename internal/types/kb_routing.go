@@ -0,0 +1,71 @@
+package types
+
+// Default limits of knowledge base routing
+const (
+	DefaultKBRoutingTopN = 3
+)
+
+// KBRoutingConfig configures automatic knowledge base routing. When enabled for an
+// agent in "all" selection mode, only the knowledge bases most relevant to the query
+// are searched instead of every knowledge base of the tenant.
+type KBRoutingConfig struct {
+	// Whether routing is enabled
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Maximum number of knowledge bases searched per query
+	TopN int `yaml:"top_n" json:"top_n"`
+	// Knowledge bases whose similarity to the query is below this value are skipped
+	MinScore float64 `yaml:"min_score" json:"min_score"`
+	// Embedding model used to compare the query with knowledge base profiles,
+	// defaults to the embedding model of each knowledge base
+	EmbeddingModelID string `yaml:"embedding_model_id" json:"embedding_model_id"`
+	// Whether a chat model picks the final knowledge bases among the embedding candidates
+	LLMClassifier bool `yaml:"llm_classifier" json:"llm_classifier"`
+	// Chat model used by the classifier, defaults to the conversation model
+	ClassifierModelID string `yaml:"classifier_model_id" json:"classifier_model_id"`
+}
+
+// IsActive reports whether the routing config has any effect
+func (c *KBRoutingConfig) IsActive() bool {
+	return c != nil && c.Enabled
+}
+
+// GetTopN returns the configured number of routed knowledge bases or the default one
+func (c *KBRoutingConfig) GetTopN() int {
+	if c == nil || c.TopN <= 0 {
+		return DefaultKBRoutingTopN
+	}
+	return c.TopN
+}
+
+// KBRouteCandidate is a knowledge base scored by the router
+type KBRouteCandidate struct {
+	KnowledgeBaseID  string `json:"knowledge_base_id"`
+	Name             string `json:"name"`
+	EmbeddingModelID string `json:"embedding_model_id"`
+	// Cosine similarity between the query and the knowledge base profile, compared with MinScore
+	Similarity float64 `json:"similarity"`
+	// Ranking score: the similarity, scaled by the best similarity of the same embedding model
+	// when the knowledge bases use different embedding models
+	Score    float64 `json:"score"`
+	Selected bool    `json:"selected"`
+}
+
+// KBRoutingDecision records which knowledge bases were selected for a query and why
+type KBRoutingDecision struct {
+	Query string `json:"query"`
+	// Routing method: embedding, or llm when the classifier made the final choice
+	Method string `json:"method"`
+	// Selected knowledge base IDs, most relevant first
+	Selected []string `json:"selected"`
+	// All knowledge bases considered, sorted by score
+	Candidates []*KBRouteCandidate `json:"candidates"`
+	// Set when routing fell back: every knowledge base is searched when routing failed,
+	// the embedding top N when nothing was selected
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Knowledge base routing methods
+const (
+	KBRoutingMethodEmbedding = "embedding"
+	KBRoutingMethodLLM       = "llm"
+)
@@ -45,19 +45,20 @@ type MCPAuthConfig struct {
 	APIKey        string            `json:"api_key,omitempty"`
 	Token         string            `json:"token,omitempty"`
 	CustomHeaders map[string]string `json:"custom_headers,omitempty"`
+	OAuth         *MCPOAuthConfig   `json:"oauth,omitempty"` // OAuth 2.1 authorization, SSE and HTTP Streamable only
 }
 
 // MCPAdvancedConfig represents advanced configuration for MCP service
 type MCPAdvancedConfig struct {
-	Timeout    int `json:"timeout"`     // Timeout in seconds, default: 30
-	RetryCount int `json:"retry_count"` // Number of retries, default: 3
-	RetryDelay int `json:"retry_delay"` // Delay between retries in seconds, default: 1
+	Timeout    int `yaml:"timeout"     json:"timeout"`     // Timeout in seconds, default: 30
+	RetryCount int `yaml:"retry_count" json:"retry_count"` // Number of retries, default: 3
+	RetryDelay int `yaml:"retry_delay" json:"retry_delay"` // Delay between retries in seconds, default: 1
 }
 
 // MCPStdioConfig represents stdio transport configuration
 type MCPStdioConfig struct {
-	Command string   `json:"command"` // Command: "uvx" or "npx"
-	Args    []string `json:"args"`    // Command arguments array
+	Command string   `yaml:"command" json:"command"` // Command: "uvx" or "npx"
+	Args    []string `yaml:"args"    json:"args"`    // Command arguments array
 }
 
 // MCPEnvVars represents environment variables as a map
@@ -78,12 +79,48 @@ type MCPResource struct {
 	MimeType    string `json:"mimeType,omitempty"`
 }
 
+// MCPPrompt represents a prompt template exposed by an MCP service
+type MCPPrompt struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Arguments   []MCPPromptArgument `json:"arguments,omitempty"`
+}
+
+// MCPPromptArgument represents an argument of an MCP prompt
+type MCPPromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// MCPResourceRef references a resource of an MCP service attached to an agent as context
+type MCPResourceRef struct {
+	ServiceID string `yaml:"service_id" json:"service_id"`
+	URI       string `yaml:"uri" json:"uri"`
+}
+
+// MCPPromptRef references a prompt of an MCP service used as the system prompt of an agent
+type MCPPromptRef struct {
+	ServiceID string            `yaml:"service_id" json:"service_id"`
+	Name      string            `yaml:"name" json:"name"`
+	Arguments map[string]string `yaml:"arguments,omitempty" json:"arguments,omitempty"`
+}
+
+// MCPResourceContext is the content of an attached MCP resource, read when an agent starts
+type MCPResourceContext struct {
+	ServiceName string
+	URI         string
+	MimeType    string
+	Text        string
+}
+
 // MCPTestResult represents the result of testing an MCP service connection
 type MCPTestResult struct {
 	Success   bool           `json:"success"`
 	Message   string         `json:"message,omitempty"`
 	Tools     []*MCPTool     `json:"tools,omitempty"`
 	Resources []*MCPResource `json:"resources,omitempty"`
+	Prompts   []*MCPPrompt   `json:"prompts,omitempty"`
 }
 
 // BeforeCreate is a GORM hook that runs before creating a new MCP service
@@ -214,7 +251,20 @@ func (m *MCPService) MaskSensitiveData() {
 		if m.AuthConfig.Token != "" {
 			m.AuthConfig.Token = maskString(m.AuthConfig.Token)
 		}
+		if m.AuthConfig.OAuth != nil && m.AuthConfig.OAuth.ClientSecret != "" {
+			oauth := *m.AuthConfig.OAuth
+			oauth.ClientSecret = maskString(oauth.ClientSecret)
+			m.AuthConfig.OAuth = &oauth
+		}
+	}
+}
+
+// OAuthConfig returns the OAuth configuration of the service, nil when it does not use OAuth
+func (m *MCPService) OAuthConfig() *MCPOAuthConfig {
+	if m.AuthConfig == nil || m.TransportType == MCPTransportStdio {
+		return nil
 	}
+	return m.AuthConfig.OAuth
 }
 
 // maskString masks a string, showing only first 4 and last 4 characters
@@ -0,0 +1,74 @@
+package types
+
+import "time"
+
+// MCPOAuthStateTTL bounds the time between the redirect to the authorization server and its callback
+const MCPOAuthStateTTL = 10 * time.Minute
+
+// MCPOAuthConfig configures the OAuth 2.1 authorization code flow with PKCE of an MCP service
+type MCPOAuthConfig struct {
+	// Client registered with the authorization server, registered dynamically when empty
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// Scopes requested during authorization
+	Scopes []string `json:"scopes,omitempty"`
+	// Authorization server metadata URL, discovered from the service URL when empty
+	AuthServerMetadataURL string `json:"auth_server_metadata_url,omitempty"`
+	// Redirect URI registered with the authorization server, derived from the API address when empty
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	// Whether each user authorizes with their own identity instead of one authorization for the tenant
+	PerUser bool `json:"per_user,omitempty"`
+}
+
+// MCPOAuthToken is the OAuth token of an MCP service, for a user or shared by the tenant (empty UserID).
+// Tokens and client secrets are stored encrypted.
+type MCPOAuthToken struct {
+	ServiceID    string    `json:"service_id"    gorm:"type:varchar(36);primaryKey"`
+	UserID       string    `json:"user_id"       gorm:"type:varchar(36);primaryKey"`
+	TenantID     uint64    `json:"tenant_id"     gorm:"index"`
+	ClientID     string    `json:"-"             gorm:"type:varchar(255)"` // Client registered dynamically
+	ClientSecret string    `json:"-"             gorm:"type:text"`
+	AccessToken  string    `json:"-"             gorm:"type:text"`
+	RefreshToken string    `json:"-"             gorm:"type:text"`
+	TokenType    string    `json:"token_type"    gorm:"type:varchar(50)"`
+	Scope        string    `json:"scope"         gorm:"type:text"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name of MCP OAuth tokens
+func (MCPOAuthToken) TableName() string {
+	return "mcp_oauth_tokens"
+}
+
+// MCPOAuthState is a pending authorization, from the redirect to the authorization server to its callback
+type MCPOAuthState struct {
+	State        string    `gorm:"type:varchar(64);primaryKey"`
+	ServiceID    string    `gorm:"type:varchar(36)"`
+	UserID       string    `gorm:"type:varchar(36)"`
+	TenantID     uint64    `gorm:"index"`
+	CodeVerifier string    `gorm:"type:text"` // Encrypted
+	RedirectURI  string    `gorm:"type:text"`
+	ClientID     string    `gorm:"type:varchar(255)"` // Client registered dynamically
+	ClientSecret string    `gorm:"type:text"`         // Encrypted
+	ReturnURL    string    `gorm:"type:text"`
+	BrowserNonce string    `gorm:"type:varchar(64)"` // SHA-256 of the nonce cookie of the browser which started it
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+// TableName returns the table name of pending MCP OAuth authorizations
+func (MCPOAuthState) TableName() string {
+	return "mcp_oauth_states"
+}
+
+// MCPOAuthStatus is the authorization status of an MCP service for the calling user
+type MCPOAuthStatus struct {
+	Enabled    bool       `json:"enabled"`    // Whether the service uses OAuth
+	PerUser    bool       `json:"per_user"`   // Whether each user authorizes separately
+	Authorized bool       `json:"authorized"` // Whether a token is stored
+	ExpiresAt  *time.Time `json:"expires_at"` // Expiry of the access token, refreshed automatically
+	Scope      string     `json:"scope"`      // Scopes granted
+	UpdatedAt  *time.Time `json:"updated_at"` // When the token was last issued or refreshed
+}
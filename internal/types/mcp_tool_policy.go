@@ -0,0 +1,94 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MCPToolPolicyAllTools is the tool name of argument policies applying to every tool of a service
+const MCPToolPolicyAllTools = "*"
+
+// MCPToolPolicy restricts which tools of an MCP service an agent may call, and with which arguments
+type MCPToolPolicy struct {
+	// Tools the agent may call, all tools when empty
+	AllowedTools []string `yaml:"allowed_tools,omitempty" json:"allowed_tools,omitempty"`
+	// Tools the agent may not call, taking precedence over AllowedTools
+	DeniedTools []string `yaml:"denied_tools,omitempty" json:"denied_tools,omitempty"`
+	// Argument policies by tool name, then argument name. Policies of "*" apply to every tool
+	// and are overridden by the policies of the tool.
+	Arguments map[string]map[string]MCPArgumentPolicy `yaml:"arguments,omitempty" json:"arguments,omitempty"`
+}
+
+// MCPArgumentPolicy fixes or constrains the value of a tool argument
+type MCPArgumentPolicy struct {
+	// Value always passed for the argument, which is then hidden from the agent
+	Value interface{} `yaml:"value,omitempty" json:"value,omitempty"`
+	// Values the agent may pass
+	Enum []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`
+	// Regular expression string values passed by the agent must match
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+}
+
+// IsFixed returns true if the argument always takes Value
+func (p MCPArgumentPolicy) IsFixed() bool {
+	return p.Value != nil
+}
+
+// Allows returns true if the agent may call the tool
+func (p *MCPToolPolicy) Allows(toolName string) bool {
+	if p == nil {
+		return true
+	}
+	for _, name := range p.DeniedTools {
+		if name == toolName {
+			return false
+		}
+	}
+	if len(p.AllowedTools) == 0 {
+		return true
+	}
+	for _, name := range p.AllowedTools {
+		if name == toolName {
+			return true
+		}
+	}
+	return false
+}
+
+// ArgumentPolicies returns the argument policies of a tool, nil when its arguments are unrestricted
+func (p *MCPToolPolicy) ArgumentPolicies(toolName string) map[string]MCPArgumentPolicy {
+	if p == nil || len(p.Arguments) == 0 {
+		return nil
+	}
+	policies := make(map[string]MCPArgumentPolicy)
+	for name, policy := range p.Arguments[MCPToolPolicyAllTools] {
+		policies[name] = policy
+	}
+	for name, policy := range p.Arguments[toolName] {
+		policies[name] = policy
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	return policies
+}
+
+// Validate checks that the argument policies are consistent and their patterns compile
+func (p *MCPToolPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for toolName, arguments := range p.Arguments {
+		for name, policy := range arguments {
+			if policy.IsFixed() && (len(policy.Enum) > 0 || policy.Pattern != "") {
+				return fmt.Errorf("argument %s of tool %s has a fixed value and constraints", name, toolName)
+			}
+			if policy.Pattern != "" {
+				if _, err := regexp.Compile(policy.Pattern); err != nil {
+					return fmt.Errorf("invalid pattern of argument %s of tool %s: %w", name, toolName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
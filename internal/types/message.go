@@ -3,7 +3,10 @@ package types
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -53,6 +56,67 @@ func (m *MentionedItems) Scan(value interface{}) error {
 	return json.Unmarshal(b, m)
 }
 
+// Limits of the images attached to a user message
+const (
+	MaxMessageImages     = 4       // Images per message
+	MaxMessageImageBytes = 5 << 20 // Decoded size of an inline image
+)
+
+// MessageImages is a list of images attached to a message, each a base64 data URL
+// (data:image/png;base64,...) or an http(s) URL
+type MessageImages []string
+
+// Value implements the driver.Valuer interface for database serialization
+func (m MessageImages) Value() (driver.Value, error) {
+	if m == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal([]string(m))
+}
+
+// Scan implements the sql.Scanner interface for database deserialization
+func (m *MessageImages) Scan(value interface{}) error {
+	if value == nil {
+		*m = make(MessageImages, 0)
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		*m = make(MessageImages, 0)
+		return nil
+	}
+	return json.Unmarshal(b, m)
+}
+
+// Validate checks the number, format and size of the images
+func (m MessageImages) Validate() error {
+	if len(m) > MaxMessageImages {
+		return fmt.Errorf("at most %d images can be attached to a message", MaxMessageImages)
+	}
+	for i, image := range m {
+		switch {
+		case strings.HasPrefix(image, "data:"):
+			header, data, found := strings.Cut(strings.TrimPrefix(image, "data:"), ",")
+			if !found || !strings.HasPrefix(header, "image/") || !strings.HasSuffix(header, ";base64") {
+				return fmt.Errorf("image %d: data URLs must be base64 encoded images", i+1)
+			}
+			if base64.StdEncoding.DecodedLen(len(data)) > MaxMessageImageBytes {
+				return fmt.Errorf("image %d exceeds %d MB", i+1, MaxMessageImageBytes>>20)
+			}
+			if _, err := base64.StdEncoding.DecodeString(data); err != nil {
+				return fmt.Errorf("image %d: invalid base64 data", i+1)
+			}
+		case strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://"):
+			if len(image) > 2048 {
+				return fmt.Errorf("image %d: URL too long", i+1)
+			}
+		default:
+			return fmt.Errorf("image %d: expected a base64 data URL or an http(s) URL", i+1)
+		}
+	}
+	return nil
+}
+
 // Message represents a conversation message
 // Each message belongs to a conversation session and can be from either user or system
 // Messages can contain references to knowledge chunks used to generate responses
@@ -76,6 +140,8 @@ type Message struct {
 	// Mentioned knowledge bases and files (for user messages)
 	// Stores the @mentioned items when user sends a message
 	MentionedItems MentionedItems `json:"mentioned_items,omitempty" gorm:"type:jsonb,column:mentioned_items"`
+	// Images attached to the message (for user messages)
+	Images MessageImages `json:"images,omitempty" gorm:"type:jsonb,column:images"`
 	// Whether message generation is complete
 	IsCompleted bool `json:"is_completed"`
 	// Message creation timestamp
@@ -130,5 +196,8 @@ func (m *Message) BeforeCreate(tx *gorm.DB) (err error) {
 	if m.MentionedItems == nil {
 		m.MentionedItems = make(MentionedItems, 0)
 	}
+	if m.Images == nil {
+		m.Images = make(MessageImages, 0)
+	}
 	return nil
 }
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestMessageImagesValidate(t *testing.T) {
+	png := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("png"))
+	oversized := "data:image/png;base64," + base64.StdEncoding.EncodeToString(make([]byte, MaxMessageImageBytes+1))
+
+	tests := []struct {
+		name    string
+		images  MessageImages
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"data url", MessageImages{png}, false},
+		{"http url", MessageImages{"https://example.com/a.png"}, false},
+		{"too many", MessageImages{png, png, png, png, png}, true},
+		{"not an image", MessageImages{"data:text/plain;base64,aGk="}, true},
+		{"not base64", MessageImages{"data:image/png,raw"}, true},
+		{"invalid base64", MessageImages{"data:image/png;base64,!!!"}, true},
+		{"too large", MessageImages{oversized}, true},
+		{"file url", MessageImages{"file:///etc/passwd"}, true},
+		{"url too long", MessageImages{"https://example.com/" + strings.Repeat("a", 2048)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.images.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -46,6 +46,7 @@ const (
 	ModelSourceSiliconFlow ModelSource = "siliconflow" // SiliconFlow model
 	ModelSourceJina        ModelSource = "jina"        // Jina AI model
 	ModelSourceOpenRouter  ModelSource = "openrouter"  // OpenRouter model
+	ModelSourceAnthropic   ModelSource = "anthropic"   // Anthropic model
 )
 
 // EmbeddingParameters represents the embedding parameters for a model
@@ -59,9 +60,10 @@ type ModelParameters struct {
 	APIKey              string              `yaml:"api_key"              json:"api_key"`
 	InterfaceType       string              `yaml:"interface_type"       json:"interface_type"`
 	EmbeddingParameters EmbeddingParameters `yaml:"embedding_parameters" json:"embedding_parameters"`
-	ParameterSize       string              `yaml:"parameter_size"       json:"parameter_size"` // Ollama model parameter size (e.g., "7B", "13B", "70B")
-	Provider            string              `yaml:"provider"             json:"provider"`       // Provider identifier: openai, aliyun, zhipu, generic
-	ExtraConfig         map[string]string   `yaml:"extra_config"         json:"extra_config"`   // Provider-specific configuration
+	ParameterSize       string              `yaml:"parameter_size"       json:"parameter_size"`  // Ollama model parameter size (e.g., "7B", "13B", "70B")
+	Provider            string              `yaml:"provider"             json:"provider"`        // Provider identifier: openai, aliyun, zhipu, generic
+	ExtraConfig         map[string]string   `yaml:"extra_config"         json:"extra_config"`    // Provider-specific configuration
+	SupportsVision      bool                `yaml:"supports_vision"      json:"supports_vision"` // Chat model accepts images in messages
 }
 
 // Model represents the AI model
@@ -111,6 +113,11 @@ func (c *ModelParameters) Scan(value interface{}) error {
 	return json.Unmarshal(b, c)
 }
 
+// SupportsVision reports whether the model can read images in chat messages
+func (m *Model) SupportsVision() bool {
+	return m.Type == ModelTypeVLLM || m.Parameters.SupportsVision
+}
+
 // BeforeCreate is a GORM hook that runs before creating a new model record
 // Automatically generates a UUID for new models
 // Parameters:
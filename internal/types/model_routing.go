@@ -0,0 +1,156 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Defaults of the model routing policies, used for zero values
+const (
+	DefaultModelMaxRetries              = 2
+	DefaultModelInitialBackoff          = 500 * time.Millisecond
+	DefaultModelMaxBackoff              = 8 * time.Second
+	DefaultModelBreakerFailureThreshold = 5
+	DefaultModelBreakerOpenDuration     = 30 * time.Second
+)
+
+// ModelRoutingConfig configures the fallback routes, retries and circuit breakers of the models of a tenant
+type ModelRoutingConfig struct {
+	// Fallback routes, by primary model
+	Routes []ModelRoute `json:"routes"`
+	// Retries of each model before failing over to the next one
+	Retry ModelRetryPolicy `json:"retry"`
+	// Circuit breaker of each model
+	CircuitBreaker ModelCircuitBreakerPolicy `json:"circuit_breaker"`
+}
+
+// ModelRoute lists the models tried in order when the primary model fails. Every model of a route
+// has the type of the primary model, and embedding models have its dimension.
+type ModelRoute struct {
+	// Model requested by knowledge bases, agents and sessions
+	ModelID string `json:"model_id"`
+	// Models tried in order after the primary model
+	Fallbacks []string `json:"fallbacks"`
+}
+
+// ModelRetryPolicy retries retryable errors (timeouts, rate limits, server errors) with exponential backoff
+type ModelRetryPolicy struct {
+	// Retries after the first attempt, 0 for the default and -1 for no retries
+	MaxRetries int `json:"max_retries"`
+	// Wait before the first retry in milliseconds, doubled on each retry
+	InitialBackoffMs int `json:"initial_backoff_ms"`
+	// Maximum wait between retries in milliseconds
+	MaxBackoffMs int `json:"max_backoff_ms"`
+}
+
+// ModelCircuitBreakerPolicy skips a model after consecutive failures, until it has rested
+type ModelCircuitBreakerPolicy struct {
+	// Consecutive failed requests which open the circuit
+	FailureThreshold int `json:"failure_threshold"`
+	// Seconds the circuit stays open before a request is tried again
+	OpenSeconds int `json:"open_seconds"`
+}
+
+// Route returns the route of a model, nil when the model has no fallbacks
+func (c *ModelRoutingConfig) Route(modelID string) *ModelRoute {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Routes {
+		if c.Routes[i].ModelID == modelID && len(c.Routes[i].Fallbacks) > 0 {
+			return &c.Routes[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks the routes and policies, but not the models they refer to
+func (c *ModelRoutingConfig) Validate() error {
+	seen := make(map[string]bool, len(c.Routes))
+	for _, route := range c.Routes {
+		if route.ModelID == "" {
+			return fmt.Errorf("model_id of route is required")
+		}
+		if seen[route.ModelID] {
+			return fmt.Errorf("model %s has several routes", route.ModelID)
+		}
+		seen[route.ModelID] = true
+		for _, fallback := range route.Fallbacks {
+			if fallback == route.ModelID {
+				return fmt.Errorf("model %s is its own fallback", route.ModelID)
+			}
+		}
+	}
+	if c.Retry.MaxRetries < -1 || c.Retry.MaxRetries > 10 {
+		return fmt.Errorf("max_retries must be between -1 and 10")
+	}
+	if c.Retry.InitialBackoffMs < 0 || c.Retry.MaxBackoffMs < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+	if c.CircuitBreaker.FailureThreshold < 0 || c.CircuitBreaker.OpenSeconds < 0 {
+		return fmt.Errorf("circuit breaker settings must not be negative")
+	}
+	return nil
+}
+
+// Retries returns the number of retries after the first attempt
+func (p ModelRetryPolicy) Retries() int {
+	switch {
+	case p.MaxRetries < 0:
+		return 0
+	case p.MaxRetries == 0:
+		return DefaultModelMaxRetries
+	default:
+		return p.MaxRetries
+	}
+}
+
+// Backoff returns the wait before a retry, counted from 1
+func (p ModelRetryPolicy) Backoff(retry int) time.Duration {
+	backoff, maxBackoff := DefaultModelInitialBackoff, DefaultModelMaxBackoff
+	if p.InitialBackoffMs > 0 {
+		backoff = time.Duration(p.InitialBackoffMs) * time.Millisecond
+	}
+	if p.MaxBackoffMs > 0 {
+		maxBackoff = time.Duration(p.MaxBackoffMs) * time.Millisecond
+	}
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+// Threshold returns the consecutive failures which open the circuit
+func (p ModelCircuitBreakerPolicy) Threshold() int {
+	if p.FailureThreshold > 0 {
+		return p.FailureThreshold
+	}
+	return DefaultModelBreakerFailureThreshold
+}
+
+// OpenDuration returns how long the circuit stays open
+func (p ModelCircuitBreakerPolicy) OpenDuration() time.Duration {
+	if p.OpenSeconds > 0 {
+		return time.Duration(p.OpenSeconds) * time.Second
+	}
+	return DefaultModelBreakerOpenDuration
+}
+
+// Value implements driver.Valuer interface for ModelRoutingConfig
+func (c ModelRoutingConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner interface for ModelRoutingConfig
+func (c *ModelRoutingConfig) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(b, c)
+}
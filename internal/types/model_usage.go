@@ -0,0 +1,126 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// ModelUsageContextKey is the context key for the scope model calls are attributed to
+const ModelUsageContextKey ContextKey = "ModelUsage"
+
+// ModelUsageSource is the feature a model call was made for
+type ModelUsageSource string
+
+const (
+	// ModelUsageSourceQA marks calls answering a question in quick answer mode
+	ModelUsageSourceQA ModelUsageSource = "qa"
+	// ModelUsageSourceAgent marks calls of agent executions
+	ModelUsageSourceAgent ModelUsageSource = "agent"
+	// ModelUsageSourceSummary marks calls summarizing documents and data tables
+	ModelUsageSourceSummary ModelUsageSource = "summary"
+	// ModelUsageSourceQuestionGeneration marks calls generating questions for chunks
+	ModelUsageSourceQuestionGeneration ModelUsageSource = "question_generation"
+	// ModelUsageSourceTitle marks calls generating session titles
+	ModelUsageSourceTitle ModelUsageSource = "title"
+	// ModelUsageSourceExtraction marks calls extracting entities and relations from chunks
+	ModelUsageSourceExtraction ModelUsageSource = "extraction"
+	// ModelUsageSourceIndexing marks calls embedding documents
+	ModelUsageSourceIndexing ModelUsageSource = "indexing"
+	// ModelUsageSourceOther marks calls made outside any of the above, such as knowledge search
+	ModelUsageSourceOther ModelUsageSource = "other"
+)
+
+// ModelUsageScope is what the model calls made with a context are attributed to
+type ModelUsageScope struct {
+	Source    ModelUsageSource
+	SessionID string
+	AgentID   string
+}
+
+// WithModelUsageScope attributes the model calls made with the returned context to a scope
+func WithModelUsageScope(ctx context.Context, scope ModelUsageScope) context.Context {
+	return context.WithValue(ctx, ModelUsageContextKey, scope)
+}
+
+// ModelUsageScopeFromContext returns the scope model calls are attributed to, ModelUsageSourceOther when none is set
+func ModelUsageScopeFromContext(ctx context.Context) ModelUsageScope {
+	scope, _ := ctx.Value(ModelUsageContextKey).(ModelUsageScope)
+	if scope.Source == "" {
+		scope.Source = ModelUsageSourceOther
+	}
+	return scope
+}
+
+// ModelUsage records a call to a chat, embedding or rerank model
+type ModelUsage struct {
+	ID       uint64 `json:"id"        gorm:"primaryKey;autoIncrement"`
+	TenantID uint64 `json:"tenant_id" gorm:"index"`
+	// User who made the request, empty for API keys and background tasks
+	UserID    string           `json:"user_id"    gorm:"type:varchar(36)"`
+	SessionID string           `json:"session_id" gorm:"type:varchar(36)"`
+	AgentID   string           `json:"agent_id"   gorm:"type:varchar(36)"`
+	Source    ModelUsageSource `json:"source"     gorm:"type:varchar(32)"`
+	ModelID   string           `json:"model_id"   gorm:"type:varchar(64)"`
+	ModelName string           `json:"model_name" gorm:"type:varchar(255)"`
+	ModelType ModelType        `json:"model_type" gorm:"type:varchar(32)"`
+	// Tokens reported by the provider, or estimated from the text when it reports none
+	PromptTokens     int  `json:"prompt_tokens"`
+	CompletionTokens int  `json:"completion_tokens"`
+	TotalTokens      int  `json:"total_tokens"`
+	Estimated        bool `json:"estimated"`
+	// Time until the response was complete
+	LatencyMs int64     `json:"latency_ms"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModelUsageGroupBy is the dimension usage statistics are aggregated by
+type ModelUsageGroupBy string
+
+const (
+	ModelUsageGroupByDay    ModelUsageGroupBy = "day"    // By day of the call (UTC)
+	ModelUsageGroupByModel  ModelUsageGroupBy = "model"  // By model
+	ModelUsageGroupByAgent  ModelUsageGroupBy = "agent"  // By custom agent
+	ModelUsageGroupBySource ModelUsageGroupBy = "source" // By feature
+)
+
+// ModelUsageQuery selects the usage records aggregated into statistics
+type ModelUsageQuery struct {
+	GroupBy ModelUsageGroupBy
+	// Records created in [Start, End)
+	Start time.Time
+	End   time.Time
+	// Only records of this source when set
+	Source ModelUsageSource
+}
+
+// ModelUsageStat aggregates the usage records sharing a key
+type ModelUsageStat struct {
+	// Day (YYYY-MM-DD), model ID, agent ID or source, by the dimension of the query
+	Key string `json:"key"`
+	// Name of the model, when grouped by model
+	ModelName        string  `json:"model_name,omitempty"`
+	Calls            int64   `json:"calls"`
+	FailedCalls      int64   `json:"failed_calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// TokenQuotaStatus is the token usage of a tenant in the current month
+type TokenQuotaStatus struct {
+	// Monthly token quota, 0 when unlimited
+	TokenQuota int64 `json:"token_quota"`
+	// Tokens used since the start of the month
+	TokenUsed   int64     `json:"token_used"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+// TokenQuotaPeriod returns the month, in UTC, containing a time
+func TokenQuotaPeriod(now time.Time) (start, end time.Time) {
+	now = now.UTC()
+	start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
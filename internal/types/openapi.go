@@ -0,0 +1,93 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultOpenAPITimeout is the timeout in seconds of an OpenAPI operation call when none is configured
+const DefaultOpenAPITimeout = 30
+
+// OpenAPIService is an HTTP API described by an OpenAPI 3 document, whose selected operations
+// are registered as agent tools
+type OpenAPIService struct {
+	ID          string `json:"id"          gorm:"type:varchar(36);primaryKey"`
+	TenantID    uint64 `json:"tenant_id"   gorm:"index"`
+	Name        string `json:"name"        gorm:"type:varchar(255);not null"`
+	Description string `json:"description" gorm:"type:text"`
+	Enabled     bool   `json:"enabled"     gorm:"default:true;index"`
+	// Base URL of the API, defaults to the first server of the document
+	BaseURL string `json:"base_url" gorm:"type:varchar(512)"`
+	// OpenAPI 3 document (JSON or YAML)
+	Spec string `json:"spec" gorm:"type:text;not null"`
+	// IDs of the operations exposed as tools
+	Operations StringArray `json:"operations" gorm:"type:json"`
+	// Headers added to each request (e.g. Authorization), never returned unmasked
+	AuthHeaders map[string]string `json:"auth_headers,omitempty" gorm:"-"`
+	// AuthHeaders encrypted at rest
+	EncryptedAuthHeaders string `json:"-" gorm:"column:auth_headers;type:text"`
+	// Timeout of a call in seconds
+	Timeout int `json:"timeout"`
+	// Response body size returned to the agent, longer responses are truncated
+	MaxResponseBytes int            `json:"max_response_bytes"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at"  gorm:"index"`
+}
+
+// OpenAPIServiceUpdate holds the fields of an OpenAPI service update, omitted fields keep their value
+type OpenAPIServiceUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+	BaseURL     *string `json:"base_url,omitempty"`
+	Spec        *string `json:"spec,omitempty"`
+	// IDs of the operations exposed as tools, an empty list exposes none
+	Operations StringArray `json:"operations,omitempty"`
+	// Masked values keep their stored value
+	AuthHeaders      map[string]string `json:"auth_headers,omitempty"`
+	Timeout          *int              `json:"timeout,omitempty"`
+	MaxResponseBytes *int              `json:"max_response_bytes,omitempty"`
+}
+
+// OpenAPIOperation describes an operation of the document of an OpenAPI service
+type OpenAPIOperation struct {
+	OperationID string `json:"operation_id"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Summary     string `json:"summary,omitempty"`
+	// Tool name of the operation
+	ToolName string `json:"tool_name"`
+	// Whether the operation is exposed as a tool
+	Selected bool `json:"selected"`
+}
+
+// TableName returns the table name for OpenAPIService
+func (OpenAPIService) TableName() string {
+	return "openapi_services"
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new OpenAPI service
+func (s *OpenAPIService) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// GetTimeout returns the call timeout, DefaultOpenAPITimeout when not configured
+func (s *OpenAPIService) GetTimeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DefaultOpenAPITimeout * time.Second
+	}
+	return time.Duration(s.Timeout) * time.Second
+}
+
+// MaskSensitiveData masks the auth header values for display
+func (s *OpenAPIService) MaskSensitiveData() {
+	for name, value := range s.AuthHeaders {
+		s.AuthHeaders[name] = maskString(value)
+	}
+}
@@ -0,0 +1,209 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// RetrievalTraceContextKey is the context key for the retrieval trace of a debug request
+const RetrievalTraceContextKey ContextKey = "RetrievalTrace"
+
+// Retrieval stages recorded in candidate traces
+const (
+	RetrievalStageHybridSearch = "hybrid_search"
+	RetrievalStageSearch       = "search"
+	RetrievalStageRerank       = "rerank"
+	RetrievalStageMerge        = "merge"
+	RetrievalStageFilterTopK   = "filter_top_k"
+)
+
+// Reasons a candidate was dropped
+const (
+	DropReasonThreshold        = "threshold"
+	DropReasonTopK             = "top_k"
+	DropReasonDedup            = "dedup"
+	DropReasonMMR              = "mmr"
+	DropReasonNegativeQuestion = "negative_question"
+	DropReasonMerged           = "merged"
+)
+
+// Actions applied to a candidate besides scoring
+const (
+	RetrievalActionQueryExpansion = "query_expansion"   // Retrieved by an expanded query
+	RetrievalActionHistory        = "history"           // Reused from a previous answer
+	RetrievalActionDirectLoad     = "direct_load"       // Loaded directly from a selected file
+	RetrievalActionMerged         = "merged"            // Absorbed overlapping chunks
+	RetrievalActionNeighborExpand = "neighbor_expand"   // Extended with neighbor chunks
+	RetrievalActionFAQAnswer      = "faq_answer_fill"   // Content replaced with the FAQ answer
+	RetrievalActionThresholdLower = "threshold_degrade" // Passed the rerank threshold after degradation
+	RetrievalActionImageQuery     = "image_query"       // Retrieved by the description of the attached images
+)
+
+// CandidateTrace records the scores and decisions applied to a single candidate chunk
+type CandidateTrace struct {
+	ChunkID         string    `json:"chunk_id"`
+	KnowledgeID     string    `json:"knowledge_id,omitempty"`
+	KnowledgeBaseID string    `json:"knowledge_base_id,omitempty"`
+	ChunkType       string    `json:"chunk_type,omitempty"`
+	MatchType       MatchType `json:"match_type"`
+	// Query variants that retrieved the chunk
+	Queries []string `json:"queries,omitempty"`
+
+	VectorScore    *float64 `json:"vector_score,omitempty"`
+	VectorRank     int      `json:"vector_rank,omitempty"`
+	KeywordScore   *float64 `json:"keyword_score,omitempty"`
+	KeywordRank    int      `json:"keyword_rank,omitempty"`
+	FusionScore    *float64 `json:"fusion_score,omitempty"`
+	RerankScore    *float64 `json:"rerank_score,omitempty"`
+	CompositeScore *float64 `json:"composite_score,omitempty"`
+	// Multiplier applied to the composite score of FAQ chunks
+	FAQBoost float64 `json:"faq_boost,omitempty"`
+	// Whether MMR selected the chunk, and its selection order
+	MMRSelected *bool    `json:"mmr_selected,omitempty"`
+	MMRRank     int      `json:"mmr_rank,omitempty"`
+	FinalScore  *float64 `json:"final_score,omitempty"`
+
+	Actions []string `json:"actions,omitempty"`
+	// MergedInto is the chunk that absorbed this one during merge
+	MergedInto string `json:"merged_into,omitempty"`
+
+	Returned   bool   `json:"returned"`
+	DropStage  string `json:"drop_stage,omitempty"`
+	DropReason string `json:"drop_reason,omitempty"`
+	DropDetail string `json:"drop_detail,omitempty"`
+}
+
+// RetrievalTrace collects per-candidate scores and decisions of a single retrieval request.
+// All methods are safe on a nil trace, so callers do not need to check whether debugging is enabled.
+type RetrievalTrace struct {
+	Query           string            `json:"query"`
+	RewriteQuery    string            `json:"rewrite_query"`
+	ExpandedQueries []string          `json:"expanded_queries,omitempty"`
+	Candidates      []*CandidateTrace `json:"candidates"`
+
+	mu    sync.Mutex
+	index map[string]*CandidateTrace
+}
+
+// NewRetrievalTrace creates an empty trace for the query
+func NewRetrievalTrace(query string) *RetrievalTrace {
+	return &RetrievalTrace{
+		Query:      query,
+		Candidates: make([]*CandidateTrace, 0),
+		index:      make(map[string]*CandidateTrace),
+	}
+}
+
+// WithRetrievalTrace returns a context carrying the trace
+func WithRetrievalTrace(ctx context.Context, trace *RetrievalTrace) context.Context {
+	return context.WithValue(ctx, RetrievalTraceContextKey, trace)
+}
+
+// RetrievalTraceFromContext returns the trace of the request, nil when debugging is disabled
+func RetrievalTraceFromContext(ctx context.Context) *RetrievalTrace {
+	trace, _ := ctx.Value(RetrievalTraceContextKey).(*RetrievalTrace)
+	return trace
+}
+
+// Update applies fn to the trace of the chunk, creating it on first use
+func (t *RetrievalTrace) Update(chunkID string, fn func(c *CandidateTrace)) {
+	if t == nil || chunkID == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.index[chunkID]
+	if !ok {
+		c = &CandidateTrace{ChunkID: chunkID}
+		t.index[chunkID] = c
+		t.Candidates = append(t.Candidates, c)
+	}
+	fn(c)
+}
+
+// RecordResult records the identity of a search result that is still a candidate.
+// A chunk dropped by one query variant but retrieved by another is no longer dropped.
+func (t *RetrievalTrace) RecordResult(r *SearchResult) {
+	t.Update(r.ID, func(c *CandidateTrace) {
+		c.KnowledgeID = r.KnowledgeID
+		c.ChunkType = r.ChunkType
+		c.MatchType = r.MatchType
+		c.DropStage, c.DropReason, c.DropDetail = "", "", ""
+	})
+}
+
+// AddAction records an action applied to the chunk
+func (t *RetrievalTrace) AddAction(chunkID string, action string) {
+	t.Update(chunkID, func(c *CandidateTrace) {
+		for _, a := range c.Actions {
+			if a == action {
+				return
+			}
+		}
+		c.Actions = append(c.Actions, action)
+	})
+}
+
+// Drop marks the chunk as dropped. The first drop wins, later stages never see the chunk again.
+func (t *RetrievalTrace) Drop(chunkID string, stage string, reason string, detail string) {
+	t.Update(chunkID, func(c *CandidateTrace) {
+		if c.DropReason != "" {
+			return
+		}
+		c.DropStage = stage
+		c.DropReason = reason
+		c.DropDetail = detail
+	})
+}
+
+// AddExpandedQueries records query variants used for expansion retrieval
+func (t *RetrievalTrace) AddExpandedQueries(queries ...string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ExpandedQueries = append(t.ExpandedQueries, queries...)
+}
+
+// Finish records the rewritten query and marks the returned results with their final scores
+func (t *RetrievalTrace) Finish(rewriteQuery string, results []*SearchResult) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.RewriteQuery = rewriteQuery
+	t.mu.Unlock()
+	for _, r := range results {
+		score := r.Score
+		t.Update(r.ID, func(c *CandidateTrace) {
+			c.Returned = true
+			c.FinalScore = &score
+			c.DropStage, c.DropReason, c.DropDetail = "", "", ""
+		})
+	}
+}
+
+// MarshalJSON serializes the trace while holding its lock
+func (t *RetrievalTrace) MarshalJSON() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	type alias struct {
+		Query           string            `json:"query"`
+		RewriteQuery    string            `json:"rewrite_query"`
+		ExpandedQueries []string          `json:"expanded_queries,omitempty"`
+		Candidates      []*CandidateTrace `json:"candidates"`
+	}
+	return json.Marshal(alias{
+		Query:           t.Query,
+		RewriteQuery:    t.RewriteQuery,
+		ExpandedQueries: t.ExpandedQueries,
+		Candidates:      t.Candidates,
+	})
+}
+
+// Float64Ptr returns a pointer to a copy of v, used for optional trace scores
+func Float64Ptr(v float64) *float64 {
+	return &v
+}
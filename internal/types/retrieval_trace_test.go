@@ -0,0 +1,92 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRetrievalTraceNilSafe(t *testing.T) {
+	var trace *RetrievalTrace
+	trace.RecordResult(&SearchResult{ID: "c1"})
+	trace.AddAction("c1", RetrievalActionMerged)
+	trace.Drop("c1", RetrievalStageRerank, DropReasonThreshold, "")
+	trace.AddExpandedQueries("q")
+	trace.Finish("rewrite", []*SearchResult{{ID: "c1"}})
+
+	if got := RetrievalTraceFromContext(context.Background()); got != nil {
+		t.Errorf("expected no trace in empty context, got %v", got)
+	}
+}
+
+func TestRetrievalTraceDrop(t *testing.T) {
+	trace := NewRetrievalTrace("query")
+	trace.RecordResult(&SearchResult{ID: "c1", KnowledgeID: "k1", MatchType: MatchTypeEmbedding})
+	trace.Drop("c1", RetrievalStageRerank, DropReasonThreshold, "score 0.1 < 0.3")
+	trace.Drop("c1", RetrievalStageFilterTopK, DropReasonTopK, "")
+
+	c := trace.Candidates[0]
+	if c.DropStage != RetrievalStageRerank || c.DropReason != DropReasonThreshold {
+		t.Errorf("expected first drop to win, got %s/%s", c.DropStage, c.DropReason)
+	}
+	if c.KnowledgeID != "k1" {
+		t.Errorf("expected knowledge id k1, got %q", c.KnowledgeID)
+	}
+
+	// Retrieved again by another query variant
+	trace.RecordResult(&SearchResult{ID: "c1"})
+	if c.DropReason != "" || c.DropStage != "" {
+		t.Errorf("expected drop to be cleared, got %s/%s", c.DropStage, c.DropReason)
+	}
+}
+
+func TestRetrievalTraceActions(t *testing.T) {
+	trace := NewRetrievalTrace("query")
+	trace.AddAction("c1", RetrievalActionMerged)
+	trace.AddAction("c1", RetrievalActionMerged)
+	trace.AddAction("c1", RetrievalActionNeighborExpand)
+	trace.AddAction("", RetrievalActionMerged)
+
+	if len(trace.Candidates) != 1 {
+		t.Fatalf("expected 1 candidate, got %d", len(trace.Candidates))
+	}
+	if got := trace.Candidates[0].Actions; len(got) != 2 {
+		t.Errorf("expected deduplicated actions, got %v", got)
+	}
+}
+
+func TestRetrievalTraceFinish(t *testing.T) {
+	trace := NewRetrievalTrace("query")
+	trace.RecordResult(&SearchResult{ID: "c1"})
+	trace.RecordResult(&SearchResult{ID: "c2"})
+	trace.Drop("c1", RetrievalStageMerge, DropReasonMerged, "")
+	trace.Drop("c2", RetrievalStageFilterTopK, DropReasonTopK, "")
+	trace.AddExpandedQueries("variant")
+	trace.Finish("rewritten", []*SearchResult{{ID: "c1", Score: 0.8}})
+
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatalf("marshal trace: %v", err)
+	}
+	var decoded RetrievalTrace
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal trace: %v", err)
+	}
+	if decoded.RewriteQuery != "rewritten" || len(decoded.ExpandedQueries) != 1 {
+		t.Errorf("unexpected queries: %q %v", decoded.RewriteQuery, decoded.ExpandedQueries)
+	}
+	if len(decoded.Candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(decoded.Candidates))
+	}
+
+	returned, dropped := decoded.Candidates[0], decoded.Candidates[1]
+	if !returned.Returned || returned.DropReason != "" {
+		t.Errorf("expected c1 returned without drop, got %+v", returned)
+	}
+	if returned.FinalScore == nil || *returned.FinalScore != 0.8 {
+		t.Errorf("expected final score 0.8, got %v", returned.FinalScore)
+	}
+	if dropped.Returned || dropped.DropReason != DropReasonTopK {
+		t.Errorf("expected c2 dropped by top_k, got %+v", dropped)
+	}
+}
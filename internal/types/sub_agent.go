@@ -0,0 +1,73 @@
+package types
+
+import "context"
+
+const (
+	// DefaultSubAgentMaxDepth is the nesting depth allowed when the agent does not configure one
+	DefaultSubAgentMaxDepth = 2
+	// MaxSubAgentDepth bounds the nesting depth any agent may configure
+	MaxSubAgentDepth = 3
+)
+
+// SubAgentDepthContextKey is the context key for the nesting depth of a delegated agent execution
+const SubAgentDepthContextKey ContextKey = "SubAgentDepth"
+
+// SubAgentConfig lists the custom agents an agent may delegate sub-tasks to
+type SubAgentConfig struct {
+	// IDs of the custom agents that may be invoked
+	AllowedAgents []string `yaml:"allowed_agents" json:"allowed_agents"`
+	// Maximum nesting depth of delegations started by this agent (0 uses the default)
+	MaxDepth int `yaml:"max_depth" json:"max_depth"`
+}
+
+// IsActive reports whether the agent may delegate at all
+func (c *SubAgentConfig) IsActive() bool {
+	return c != nil && len(c.AllowedAgents) > 0
+}
+
+// Allows reports whether the agent may delegate to the given agent
+func (c *SubAgentConfig) Allows(agentID string) bool {
+	if c == nil {
+		return false
+	}
+	for _, id := range c.AllowedAgents {
+		if id == agentID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMaxDepth returns the configured nesting depth, bounded by MaxSubAgentDepth
+func (c *SubAgentConfig) GetMaxDepth() int {
+	if c == nil || c.MaxDepth <= 0 {
+		return DefaultSubAgentMaxDepth
+	}
+	return min(c.MaxDepth, MaxSubAgentDepth)
+}
+
+// SubAgentResult is the outcome of a delegated agent execution returned to the parent agent
+type SubAgentResult struct {
+	AgentID       string          `json:"agent_id"`
+	AgentName     string          `json:"agent_name"`
+	Answer        string          `json:"answer"`
+	KnowledgeRefs []*SearchResult `json:"knowledge_refs"`
+	Rounds        int             `json:"rounds"`
+}
+
+// SubAgentRunner runs another custom agent on a sub-query on behalf of the current agent
+type SubAgentRunner interface {
+	// RunSubAgent runs the agent and returns its final answer. The nesting depth is read from ctx.
+	RunSubAgent(ctx context.Context, agentID, query string) (*SubAgentResult, error)
+}
+
+// WithSubAgentDepth returns a context for an execution nested at the given depth
+func WithSubAgentDepth(ctx context.Context, depth int) context.Context {
+	return context.WithValue(ctx, SubAgentDepthContextKey, depth)
+}
+
+// SubAgentDepth returns the nesting depth of the execution, 0 for a top-level execution
+func SubAgentDepth(ctx context.Context) int {
+	depth, _ := ctx.Value(SubAgentDepthContextKey).(int)
+	return depth
+}
@@ -0,0 +1,21 @@
+package types
+
+import "testing"
+
+func TestSubAgentConfig(t *testing.T) {
+	var disabled *SubAgentConfig
+	if disabled.IsActive() || disabled.Allows("a") {
+		t.Error("expected a nil config to allow no delegation")
+	}
+	if got := disabled.GetMaxDepth(); got != DefaultSubAgentMaxDepth {
+		t.Errorf("expected default depth %d, got %d", DefaultSubAgentMaxDepth, got)
+	}
+
+	cfg := &SubAgentConfig{AllowedAgents: []string{"a", "b"}, MaxDepth: 10}
+	if !cfg.IsActive() || !cfg.Allows("b") || cfg.Allows("c") {
+		t.Errorf("unexpected allowlist result for %v", cfg.AllowedAgents)
+	}
+	if got := cfg.GetMaxDepth(); got != MaxSubAgentDepth {
+		t.Errorf("expected depth bounded by %d, got %d", MaxSubAgentDepth, got)
+	}
+}
@@ -69,6 +69,8 @@ type Tenant struct {
 	StorageQuota int64 `yaml:"storage_quota"       json:"storage_quota"       gorm:"default:10737418240"`
 	// Storage used (Bytes)
 	StorageUsed int64 `yaml:"storage_used"        json:"storage_used"        gorm:"default:0"`
+	// Tokens the models of this tenant may use per calendar month (UTC), 0 for unlimited
+	TokenQuota int64 `yaml:"token_quota"         json:"token_quota"         gorm:"default:0"`
 	// Deprecated: AgentConfig is deprecated, use CustomAgent (builtin-smart-reasoning) config instead.
 	// This field is kept for backward compatibility and will be removed in future versions.
 	AgentConfig *AgentConfig `yaml:"agent_config"        json:"agent_config"        gorm:"type:jsonb"`
@@ -79,6 +81,8 @@ type Tenant struct {
 	// Deprecated: ConversationConfig is deprecated, use CustomAgent (builtin-quick-answer) config instead.
 	// This field is kept for backward compatibility and will be removed in future versions.
 	ConversationConfig *ConversationConfig `yaml:"conversation_config" json:"conversation_config" gorm:"type:jsonb"`
+	// Fallback routes, retries and circuit breakers of the models of this tenant
+	ModelRoutingConfig *ModelRoutingConfig `yaml:"model_routing_config" json:"model_routing_config" gorm:"type:jsonb"`
 	// Creation time
 	CreatedAt time.Time `yaml:"created_at"          json:"created_at"`
 	// Last updated time
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultToolApprovalTimeout is how long the agent waits for a decision when the agent
+// does not configure its own timeout
+const DefaultToolApprovalTimeout = 5 * time.Minute
+
+// ToolApprovalStatus is the state of a tool approval request
+type ToolApprovalStatus string
+
+const (
+	ToolApprovalPending  ToolApprovalStatus = "pending"
+	ToolApprovalApproved ToolApprovalStatus = "approved"
+	ToolApprovalDenied   ToolApprovalStatus = "denied"
+	ToolApprovalExpired  ToolApprovalStatus = "expired"
+)
+
+// ToolApprovalConfig lists the agent tools that need a human approval before they run
+type ToolApprovalConfig struct {
+	// Tool names requiring approval. A trailing "*" matches a prefix, e.g. "mcp.jira.*"
+	RequireApproval []string `yaml:"require_approval" json:"require_approval"`
+	// How long the agent waits for a decision, in seconds. The call is denied when it expires
+	TimeoutSeconds int `yaml:"timeout_seconds" json:"timeout_seconds"`
+}
+
+// Requires reports whether calls of the tool need approval
+func (c *ToolApprovalConfig) Requires(toolName string) bool {
+	if c == nil {
+		return false
+	}
+	for _, pattern := range c.RequireApproval {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(toolName, prefix) {
+				return true
+			}
+		} else if pattern == toolName {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTimeout returns the configured approval timeout or the default one
+func (c *ToolApprovalConfig) GetTimeout() time.Duration {
+	if c == nil || c.TimeoutSeconds <= 0 {
+		return DefaultToolApprovalTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// ToolApproval is a pending or decided approval request for a single agent tool call
+type ToolApproval struct {
+	ID         string             `json:"id"           gorm:"type:varchar(36);primaryKey"`
+	TenantID   uint64             `json:"tenant_id"    gorm:"index"`
+	SessionID  string             `json:"session_id"   gorm:"type:varchar(36);index"`
+	MessageID  string             `json:"message_id"   gorm:"type:varchar(36)"`
+	ToolCallID string             `json:"tool_call_id" gorm:"type:varchar(128)"`
+	ToolName   string             `json:"tool_name"    gorm:"type:varchar(255)"`
+	Arguments  JSON               `json:"arguments"    gorm:"type:json"`
+	Status     ToolApprovalStatus `json:"status"       gorm:"type:varchar(16);index"`
+	Reason     string             `json:"reason"       gorm:"type:text"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	DecidedAt  *time.Time         `json:"decided_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// TableName returns the table name for ToolApproval
+func (ToolApproval) TableName() string {
+	return "tool_approvals"
+}
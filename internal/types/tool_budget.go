@@ -0,0 +1,46 @@
+package types
+
+import "fmt"
+
+// Budget kinds reported when an agent exhausts a budget
+const (
+	BudgetKindToolCalls        = "tool_calls"          // Total tool calls of an execution
+	BudgetKindToolCallsPerTool = "tool_calls_per_tool" // Calls of a single tool
+	BudgetKindTokens           = "tokens"              // Estimated LLM tokens of an execution
+)
+
+// ToolBudgetConfig limits how long and how often an agent may call tools, and how many
+// tokens an execution may spend. Zero values mean no limit.
+type ToolBudgetConfig struct {
+	// Timeout applied to every tool call, in seconds
+	DefaultTimeoutSeconds int `yaml:"default_timeout_seconds" json:"default_timeout_seconds"`
+	// Timeouts of individual tools by tool name, in seconds, overriding the default timeout
+	ToolTimeoutSeconds map[string]int `yaml:"tool_timeout_seconds" json:"tool_timeout_seconds"`
+	// Maximum calls of individual tools by tool name
+	MaxCallsPerTool map[string]int `yaml:"max_calls_per_tool" json:"max_calls_per_tool"`
+	// Maximum tool calls of a whole execution
+	MaxToolCalls int `yaml:"max_tool_calls" json:"max_tool_calls"`
+	// Maximum estimated tokens (prompt and completion) of a whole execution
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens"`
+}
+
+// Validate checks that limits are not negative
+func (c *ToolBudgetConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.DefaultTimeoutSeconds < 0 || c.MaxToolCalls < 0 || c.MaxTokens < 0 {
+		return fmt.Errorf("tool budget limits must not be negative")
+	}
+	for name, v := range c.ToolTimeoutSeconds {
+		if v < 0 {
+			return fmt.Errorf("invalid timeout for tool %s: %d", name, v)
+		}
+	}
+	for name, v := range c.MaxCallsPerTool {
+		if v < 0 {
+			return fmt.Errorf("invalid max calls for tool %s: %d", name, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+)
+
+// secretKey returns the AES key used to encrypt secrets at rest, shared with the tenant API keys
+func secretKey() []byte {
+	return []byte(os.Getenv("TENANT_AES_KEY"))
+}
+
+// EncryptSecret encrypts a secret with AES-GCM and returns it base64 encoded
+func EncryptSecret(plaintext string) (string, error) {
+	aesgcm, err := newSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aesgcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptSecret decrypts a secret encrypted by EncryptSecret
+func DecryptSecret(encoded string) (string, error) {
+	aesgcm, err := newSecretCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.New("invalid secret encoding")
+	}
+	if len(data) < aesgcm.NonceSize() {
+		return "", errors.New("invalid secret length")
+	}
+	nonce, ciphertext := data[:aesgcm.NonceSize()], data[aesgcm.NonceSize():]
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("secret is invalid or has been tampered with")
+	}
+	return string(plaintext), nil
+}
+
+// newSecretCipher creates the AES-GCM cipher of the secret key
+func newSecretCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secretKey())
+	if err != nil {
+		return nil, errors.New("secret encryption key (TENANT_AES_KEY) is not configured correctly")
+	}
+	return cipher.NewGCM(block)
+}
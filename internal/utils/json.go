@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	jsonschema "github.com/google/jsonschema-go/jsonschema"
 )
@@ -33,3 +34,59 @@ func GenerateSchema[T any]() json.RawMessage {
 
 	return schemaBytes
 }
+
+// CompileJSONSchema parses and resolves a JSON Schema document
+func CompileJSONSchema(schema json.RawMessage) (*jsonschema.Resolved, error) {
+	var s jsonschema.Schema
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+	resolved, err := s.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json schema: %w", err)
+	}
+	return resolved, nil
+}
+
+// ValidateJSONSchema validates a JSON document against a JSON Schema and returns the decoded document
+func ValidateJSONSchema(schema json.RawMessage, data []byte) (interface{}, error) {
+	resolved, err := CompileJSONSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance interface{}
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	if err := resolved.Validate(instance); err != nil {
+		return instance, err
+	}
+	return instance, nil
+}
+
+// ExtractJSON extracts the JSON document from a model response,
+// stripping markdown code fences and any text around the outermost object or array
+func ExtractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+		content = strings.TrimSpace(content)
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return content
+	}
+	closing := "}"
+	if content[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(content, closing)
+	if end < start {
+		return content[start:]
+	}
+	return content[start : end+1]
+}
@@ -0,0 +1,7 @@
+-- Migration: 000008_tool_approvals (rollback)
+-- Description: Remove tool approvals table
+DO $$ BEGIN RAISE NOTICE '[Migration 000008 DOWN] Dropping table: tool_approvals'; END $$;
+DROP INDEX IF EXISTS idx_tool_approvals_status;
+DROP INDEX IF EXISTS idx_tool_approvals_session_id;
+DROP INDEX IF EXISTS idx_tool_approvals_tenant_id;
+DROP TABLE IF EXISTS tool_approvals;
@@ -0,0 +1,23 @@
+-- Migration: 000008_tool_approvals
+-- Description: Add tool approvals table for human-in-the-loop approval of agent tool calls
+DO $$ BEGIN RAISE NOTICE '[Migration 000008] Creating table: tool_approvals'; END $$;
+CREATE TABLE IF NOT EXISTS tool_approvals (
+    id VARCHAR(36) PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id INTEGER NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    message_id VARCHAR(36),
+    tool_call_id VARCHAR(128),
+    tool_name VARCHAR(255) NOT NULL,
+    arguments JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    reason TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_tenant_id ON tool_approvals(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_session_id ON tool_approvals(session_id);
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_status ON tool_approvals(status);
+DO $$ BEGIN RAISE NOTICE '[Migration 000008] tool_approvals setup completed'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000009_agent_checkpoints (rollback)
+-- Description: Remove agent checkpoints table
+DO $$ BEGIN RAISE NOTICE '[Migration 000009 DOWN] Dropping table: agent_checkpoints'; END $$;
+DROP INDEX IF EXISTS idx_agent_checkpoints_status_heartbeat;
+DROP INDEX IF EXISTS idx_agent_checkpoints_session_id;
+DROP INDEX IF EXISTS idx_agent_checkpoints_tenant_id;
+DROP TABLE IF EXISTS agent_checkpoints;
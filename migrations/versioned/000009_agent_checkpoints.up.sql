@@ -0,0 +1,28 @@
+-- Migration: 000009_agent_checkpoints
+-- Description: Add agent checkpoints table so that interrupted agent executions can be resumed
+DO $$ BEGIN RAISE NOTICE '[Migration 000009] Creating table: agent_checkpoints'; END $$;
+CREATE TABLE IF NOT EXISTS agent_checkpoints (
+    message_id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    session_id VARCHAR(36) NOT NULL,
+    custom_agent_id VARCHAR(36),
+    query TEXT,
+    summary_model_id VARCHAR(64),
+    knowledge_base_ids JSONB,
+    knowledge_ids JSONB,
+    round INTEGER NOT NULL DEFAULT 0,
+    messages JSONB,
+    state JSONB,
+    tokens_used INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'running',
+    owner VARCHAR(255),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    heartbeat_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_checkpoints_tenant_id ON agent_checkpoints(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_agent_checkpoints_session_id ON agent_checkpoints(session_id);
+CREATE INDEX IF NOT EXISTS idx_agent_checkpoints_status_heartbeat ON agent_checkpoints(status, heartbeat_at);
+DO $$ BEGIN RAISE NOTICE '[Migration 000009] agent_checkpoints setup completed'; END $$;
@@ -0,0 +1,7 @@
+-- Migration: 000010_openapi_services (rollback)
+-- Description: Remove OpenAPI services table
+DO $$ BEGIN RAISE NOTICE '[Migration 000010 DOWN] Dropping table: openapi_services'; END $$;
+DROP INDEX IF EXISTS idx_openapi_services_deleted_at;
+DROP INDEX IF EXISTS idx_openapi_services_enabled;
+DROP INDEX IF EXISTS idx_openapi_services_tenant_id;
+DROP TABLE IF EXISTS openapi_services;
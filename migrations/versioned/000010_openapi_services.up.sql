@@ -0,0 +1,24 @@
+-- Migration: 000010_openapi_services
+-- Description: Add OpenAPI services table so that HTTP APIs can be registered as agent tools
+DO $$ BEGIN RAISE NOTICE '[Migration 000010] Creating table: openapi_services'; END $$;
+CREATE TABLE IF NOT EXISTS openapi_services (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    enabled BOOLEAN DEFAULT TRUE,
+    base_url VARCHAR(512),
+    spec TEXT NOT NULL,
+    operations JSONB,
+    auth_headers TEXT,
+    timeout INTEGER NOT NULL DEFAULT 0,
+    max_response_bytes INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_openapi_services_tenant_id ON openapi_services(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_openapi_services_enabled ON openapi_services(enabled);
+CREATE INDEX IF NOT EXISTS idx_openapi_services_deleted_at ON openapi_services(deleted_at);
+DO $$ BEGIN RAISE NOTICE '[Migration 000010] openapi_services setup completed'; END $$;
@@ -0,0 +1,11 @@
+-- Migration: 000011_agent_schedules (rollback)
+-- Description: Remove agent schedules and their run history
+DO $$ BEGIN RAISE NOTICE '[Migration 000011 DOWN] Dropping tables: agent_schedule_runs, agent_schedules'; END $$;
+DROP INDEX IF EXISTS idx_agent_schedule_runs_schedule_id;
+DROP INDEX IF EXISTS idx_agent_schedule_runs_tenant_id;
+DROP TABLE IF EXISTS agent_schedule_runs;
+DROP INDEX IF EXISTS idx_agent_schedules_deleted_at;
+DROP INDEX IF EXISTS idx_agent_schedules_next_run_at;
+DROP INDEX IF EXISTS idx_agent_schedules_agent_id;
+DROP INDEX IF EXISTS idx_agent_schedules_tenant_id;
+DROP TABLE IF EXISTS agent_schedules;
@@ -0,0 +1,53 @@
+-- Migration: 000011_agent_schedules
+-- Description: Add agent schedules and their run history so that agents can run on a cron schedule
+DO $$ BEGIN RAISE NOTICE '[Migration 000011] Creating table: agent_schedules'; END $$;
+CREATE TABLE IF NOT EXISTS agent_schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    enabled BOOLEAN DEFAULT TRUE,
+    agent_id VARCHAR(36) NOT NULL,
+    prompt TEXT NOT NULL,
+    knowledge_base_ids JSONB,
+    cron_expression VARCHAR(128) NOT NULL,
+    timezone VARCHAR(64),
+    webhook_url VARCHAR(512),
+    webhook_secret TEXT,
+    max_retries INTEGER,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_status VARCHAR(16),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_schedules_tenant_id ON agent_schedules(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_agent_schedules_agent_id ON agent_schedules(agent_id);
+CREATE INDEX IF NOT EXISTS idx_agent_schedules_next_run_at ON agent_schedules(next_run_at);
+CREATE INDEX IF NOT EXISTS idx_agent_schedules_deleted_at ON agent_schedules(deleted_at);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000011] Creating table: agent_schedule_runs'; END $$;
+CREATE TABLE IF NOT EXISTS agent_schedule_runs (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    schedule_id VARCHAR(36) NOT NULL,
+    triggered_by VARCHAR(16),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    session_id VARCHAR(36),
+    message_id VARCHAR(36),
+    error TEXT,
+    webhook_status_code INTEGER NOT NULL DEFAULT 0,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    scheduled_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_schedule_runs_tenant_id ON agent_schedule_runs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_agent_schedule_runs_schedule_id ON agent_schedule_runs(schedule_id, created_at);
+DO $$ BEGIN RAISE NOTICE '[Migration 000011] agent_schedules setup completed'; END $$;
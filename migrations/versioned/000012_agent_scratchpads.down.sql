@@ -0,0 +1,5 @@
+-- Migration: 000012_agent_scratchpads (rollback)
+-- Description: Remove agent scratchpads
+DO $$ BEGIN RAISE NOTICE '[Migration 000012 DOWN] Dropping table: agent_scratchpads'; END $$;
+DROP INDEX IF EXISTS idx_agent_scratchpads_tenant_id;
+DROP TABLE IF EXISTS agent_scratchpads;
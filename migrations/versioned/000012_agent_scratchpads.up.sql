@@ -0,0 +1,15 @@
+-- Migration: 000012_agent_scratchpads
+-- Description: Add agent scratchpads so that the research plan and findings of a session survive across turns
+DO $$ BEGIN RAISE NOTICE '[Migration 000012] Creating table: agent_scratchpads'; END $$;
+CREATE TABLE IF NOT EXISTS agent_scratchpads (
+    session_id VARCHAR(36) PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    task TEXT,
+    steps JSONB,
+    findings JSONB,
+    message_id VARCHAR(36),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_scratchpads_tenant_id ON agent_scratchpads(tenant_id);
@@ -0,0 +1,8 @@
+-- Migration: 000013_mcp_oauth (rollback)
+-- Description: Remove OAuth tokens and pending authorizations of MCP services
+DO $$ BEGIN RAISE NOTICE '[Migration 000013 DOWN] Dropping tables: mcp_oauth_states, mcp_oauth_tokens'; END $$;
+DROP INDEX IF EXISTS idx_mcp_oauth_states_expires_at;
+DROP INDEX IF EXISTS idx_mcp_oauth_states_tenant_id;
+DROP TABLE IF EXISTS mcp_oauth_states;
+DROP INDEX IF EXISTS idx_mcp_oauth_tokens_tenant_id;
+DROP TABLE IF EXISTS mcp_oauth_tokens;
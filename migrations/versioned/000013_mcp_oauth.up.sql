@@ -0,0 +1,39 @@
+-- Migration: 000013_mcp_oauth
+-- Description: Add OAuth tokens and pending authorizations of MCP services
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Creating table: mcp_oauth_tokens'; END $$;
+CREATE TABLE IF NOT EXISTS mcp_oauth_tokens (
+    service_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL DEFAULT '',
+    tenant_id INTEGER NOT NULL,
+    client_id VARCHAR(255),
+    client_secret TEXT,
+    access_token TEXT,
+    refresh_token TEXT,
+    token_type VARCHAR(50),
+    scope TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (service_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_tokens_tenant_id ON mcp_oauth_tokens(tenant_id);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000013] Creating table: mcp_oauth_states'; END $$;
+CREATE TABLE IF NOT EXISTS mcp_oauth_states (
+    state VARCHAR(64) PRIMARY KEY,
+    service_id VARCHAR(36) NOT NULL,
+    user_id VARCHAR(36) NOT NULL DEFAULT '',
+    tenant_id INTEGER NOT NULL,
+    code_verifier TEXT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    client_id VARCHAR(255),
+    client_secret TEXT,
+    return_url TEXT,
+    browser_nonce VARCHAR(64),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_states_tenant_id ON mcp_oauth_states(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_mcp_oauth_states_expires_at ON mcp_oauth_states(expires_at);
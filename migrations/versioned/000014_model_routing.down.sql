@@ -0,0 +1,4 @@
+-- Migration: 000014_model_routing (rollback)
+-- Description: Remove model routing configuration from tenants
+DO $$ BEGIN RAISE NOTICE '[Migration 000014 DOWN] Dropping column: tenants.model_routing_config'; END $$;
+ALTER TABLE tenants DROP COLUMN IF EXISTS model_routing_config;
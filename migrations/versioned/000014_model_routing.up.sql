@@ -0,0 +1,4 @@
+-- Migration: 000014_model_routing
+-- Description: Add model fallback routes, retries and circuit breakers to tenants
+DO $$ BEGIN RAISE NOTICE '[Migration 000014] Adding column: tenants.model_routing_config'; END $$;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS model_routing_config JSONB DEFAULT NULL;
@@ -0,0 +1,7 @@
+-- Migration: 000015_model_usage (rollback)
+-- Description: Remove model usage records and token quotas
+DO $$ BEGIN RAISE NOTICE '[Migration 000015 DOWN] Dropping column: tenants.token_quota'; END $$;
+ALTER TABLE tenants DROP COLUMN IF EXISTS token_quota;
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000015 DOWN] Dropping table: model_usages'; END $$;
+DROP TABLE IF EXISTS model_usages;
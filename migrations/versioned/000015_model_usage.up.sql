@@ -0,0 +1,26 @@
+-- Migration: 000015_model_usage
+-- Description: Record the token usage of model calls and add monthly token quotas to tenants
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Creating table: model_usages'; END $$;
+CREATE TABLE IF NOT EXISTS model_usages (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    user_id VARCHAR(36),
+    session_id VARCHAR(36),
+    agent_id VARCHAR(36),
+    source VARCHAR(32) NOT NULL,
+    model_id VARCHAR(64) NOT NULL,
+    model_name VARCHAR(255),
+    model_type VARCHAR(32),
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    estimated BOOLEAN NOT NULL DEFAULT FALSE,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    success BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_usages_tenant_created ON model_usages(tenant_id, created_at);
+
+DO $$ BEGIN RAISE NOTICE '[Migration 000015] Adding column: tenants.token_quota'; END $$;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS token_quota BIGINT DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Migration: 000016_message_images (rollback)
+-- Description: Remove the images of messages
+DO $$ BEGIN RAISE NOTICE '[Migration 000016 DOWN] Dropping column: messages.images'; END $$;
+ALTER TABLE messages DROP COLUMN IF EXISTS images;
@@ -0,0 +1,6 @@
+-- Migration: 000016_message_images
+-- Description: Store the images attached to user messages
+DO $$ BEGIN RAISE NOTICE '[Migration 000016] Adding column: messages.images'; END $$;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS images JSONB DEFAULT '[]';
+
+COMMENT ON COLUMN messages.images IS 'Images attached to the message, as base64 data URLs or http(s) URLs';
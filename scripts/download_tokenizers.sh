@@ -0,0 +1,33 @@
+#!/bin/bash
+# Download the BPE vocabularies used to count model tokens into config/tokenizers
+# (or TOKENIZER_DIR). Without them token counts are estimated.
+set -e
+
+# Get the script directory and project root
+SCRIPT_DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" && pwd )"
+PROJECT_ROOT="$( cd "$SCRIPT_DIR/.." && pwd )"
+
+TOKENIZER_DIR="${TOKENIZER_DIR:-$PROJECT_ROOT/config/tokenizers}"
+OPENAI_ENDPOINT="${OPENAI_ENDPOINT:-https://openaipublic.blob.core.windows.net}"
+HF_ENDPOINT="${HF_ENDPOINT:-https://huggingface.co}"
+
+mkdir -p "$TOKENIZER_DIR"
+
+download() {
+    local name=$1
+    local url=$2
+    local target="$TOKENIZER_DIR/$name.tiktoken"
+    if [ -s "$target" ]; then
+        echo "$name already installed"
+        return
+    fi
+    echo "Downloading $name from $url"
+    curl -fsSL --retry 3 -o "$target.tmp" "$url"
+    mv "$target.tmp" "$target"
+}
+
+download cl100k_base "$OPENAI_ENDPOINT/encodings/cl100k_base.tiktoken"
+download o200k_base "$OPENAI_ENDPOINT/encodings/o200k_base.tiktoken"
+download qwen "$HF_ENDPOINT/Qwen/Qwen-7B/resolve/main/qwen.tiktoken"
+
+echo "Tokenizers installed in $TOKENIZER_DIR"